@@ -22,10 +22,15 @@ package broker
 
 import (
 	"errors"
+	"fmt"
 	"sync/atomic"
+	"testing"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -101,3 +106,65 @@ func (m MockOutputType) WaitForClose(t time.Duration) error {
 }
 
 //------------------------------------------------------------------------------
+
+// checkOutputMetrics sends a message through a broker that is expected to
+// reach an output at an index, and checks the in flight and last error gauges
+// of the output whilst the message fails.
+func checkOutputMetrics(
+	t *testing.T,
+	stats *metrics.Local,
+	readChan chan<- types.Transaction,
+	out *MockOutputType,
+	index int,
+) {
+	t.Helper()
+
+	gauge := func(name string) int64 {
+		return stats.GetCounters()[fmt.Sprintf("broker.outputs.%v.%v", index, name)]
+	}
+
+	resChan := make(chan types.Response)
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker send")
+	}
+
+	var ts types.Transaction
+	select {
+	case ts = <-out.TChan:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker propagate")
+	}
+
+	// The send is recorded once the output has accepted the message.
+	<-time.After(time.Millisecond * 50)
+	if exp, act := int64(1), gauge("in_flight"); exp != act {
+		t.Errorf("Wrong in flight count: %v != %v", act, exp)
+	}
+	if act := gauge("last_error"); act != 0 {
+		t.Errorf("Unexpected last error: %v", act)
+	}
+
+	select {
+	case ts.ResponseChan <- response.NewError(errors.New("test")):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for response send")
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() == nil {
+			t.Error("Expected error response")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for response")
+	}
+	if exp, act := int64(0), gauge("in_flight"); exp != act {
+		t.Errorf("Wrong in flight count: %v != %v", act, exp)
+	}
+	if act := gauge("last_error"); act == 0 {
+		t.Error("Expected last error to be set")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,275 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Failover is a broker that implements types.Consumer and sends each message to
+// the first healthy output of a list, where the health of each output is
+// periodically checked from its connection status. Outputs must fail a number
+// of consecutive checks before being considered unhealthy, and pass a number of
+// consecutive checks before being considered healthy again, which prevents
+// traffic from flapping between outputs.
+type Failover struct {
+	running int32
+	active  int32
+
+	log   log.Modular
+	stats metrics.Type
+
+	checkInterval      time.Duration
+	unhealthyThreshold int
+	healthyThreshold   int
+
+	healthy []bool
+	passes  []int
+	fails   []int
+
+	mSwitched metrics.StatCounter
+	mActive   metrics.StatGauge
+	mHealthy  []metrics.StatGauge
+
+	transactions <-chan types.Transaction
+
+	outputTsChans []chan types.Transaction
+	outputs       []types.Output
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewFailover creates a new Failover type by providing consumers, where the
+// first consumer is the primary.
+func NewFailover(
+	outputs []types.Output,
+	log log.Modular,
+	stats metrics.Type,
+	options ...func(*Failover),
+) (*Failover, error) {
+	f := &Failover{
+		running:            1,
+		log:                log,
+		stats:              stats,
+		checkInterval:      time.Second,
+		unhealthyThreshold: 2,
+		healthyThreshold:   5,
+		healthy:            make([]bool, len(outputs)),
+		passes:             make([]int, len(outputs)),
+		fails:              make([]int, len(outputs)),
+		mSwitched:          stats.GetCounter("broker.failover.switched"),
+		mActive:            stats.GetGauge("broker.failover.active"),
+		transactions:       nil,
+		outputs:            outputs,
+		closedChan:         make(chan struct{}),
+		closeChan:          make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(f)
+	}
+	if f.checkInterval <= 0 {
+		return nil, fmt.Errorf("invalid health check interval: %v", f.checkInterval)
+	}
+	f.outputTsChans = make([]chan types.Transaction, len(f.outputs))
+	for i := range f.outputTsChans {
+		// Outputs are assumed healthy until proven otherwise, as they might
+		// not have connected yet.
+		f.healthy[i] = true
+		f.mHealthy = append(f.mHealthy, stats.GetGauge(fmt.Sprintf("broker.outputs.%v.healthy", i)))
+		f.mHealthy[i].Set(1)
+		f.outputTsChans[i] = make(chan types.Transaction)
+		if err := f.outputs[i].Consume(f.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OptFailoverSetCheckInterval sets the period between health checks of the
+// outputs.
+func OptFailoverSetCheckInterval(interval time.Duration) func(*Failover) {
+	return func(f *Failover) {
+		f.checkInterval = interval
+	}
+}
+
+// OptFailoverSetThresholds sets the number of consecutive failed health checks
+// before an output is considered unhealthy, and the number of consecutive
+// passed health checks before it is considered healthy again.
+func OptFailoverSetThresholds(unhealthy, healthy int) func(*Failover) {
+	return func(f *Failover) {
+		f.unhealthyThreshold = unhealthy
+		f.healthyThreshold = healthy
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new messages channel for the broker to read.
+func (f *Failover) Consume(ts <-chan types.Transaction) error {
+	if f.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	f.transactions = ts
+
+	go f.checkLoop()
+	go f.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (f *Failover) Connected() bool {
+	for _, out := range f.outputs {
+		if out.Connected() {
+			return true
+		}
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+// check updates the health of each output from its connection status and
+// selects the first healthy output as the active output. When no outputs are
+// healthy the primary is used.
+func (f *Failover) check() {
+	for i, out := range f.outputs {
+		if out.Connected() {
+			f.fails[i] = 0
+			f.passes[i]++
+			if !f.healthy[i] && f.passes[i] >= f.healthyThreshold {
+				f.healthy[i] = true
+				f.mHealthy[i].Set(1)
+				f.log.Infof("Output %v is healthy\n", i)
+			}
+		} else {
+			f.passes[i] = 0
+			f.fails[i]++
+			if f.healthy[i] && f.fails[i] >= f.unhealthyThreshold {
+				f.healthy[i] = false
+				f.mHealthy[i].Set(0)
+				f.log.Warnf("Output %v is unhealthy\n", i)
+			}
+		}
+	}
+
+	active := 0
+	for i, h := range f.healthy {
+		if h {
+			active = i
+			break
+		}
+	}
+	if prev := atomic.SwapInt32(&f.active, int32(active)); prev != int32(active) {
+		f.mSwitched.Incr(1)
+		f.mActive.Set(int64(active))
+		f.log.Infof("Switching active output from %v to %v\n", prev, active)
+	}
+}
+
+// checkLoop periodically checks the health of outputs until the broker is
+// closed.
+func (f *Failover) checkLoop() {
+	ticker := time.NewTicker(f.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.check()
+		case <-f.closeChan:
+			return
+		}
+	}
+}
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (f *Failover) loop() {
+	defer func() {
+		for _, c := range f.outputTsChans {
+			close(c)
+		}
+		close(f.closedChan)
+	}()
+
+	var (
+		mMsgsRcvd = f.stats.GetCounter("messages.received")
+		mOutputs  = make([]*outputMetrics, len(f.outputs))
+	)
+	for i := range f.outputs {
+		mOutputs[i] = newOutputMetrics(f.stats, i, false)
+	}
+
+	var open bool
+	for atomic.LoadInt32(&f.running) == 1 {
+		var ts types.Transaction
+		select {
+		case ts, open = <-f.transactions:
+			if !open {
+				return
+			}
+		case <-f.closeChan:
+			return
+		}
+		mMsgsRcvd.Incr(1)
+		i := atomic.LoadInt32(&f.active)
+		start := time.Now()
+		resChan := make(chan types.Response, 1)
+		select {
+		case f.outputTsChans[i] <- types.NewTransaction(ts.Payload, resChan):
+		case <-f.closeChan:
+			return
+		}
+		mOutputs[i].blockedSince(start)
+		mOutputs[i].sent()
+		go mOutputs[i].forward(resChan, ts.ResponseChan, f.closeChan)
+	}
+}
+
+// CloseAsync shuts down the Failover broker and stops processing requests.
+func (f *Failover) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&f.running, 1, 0) {
+		close(f.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Failover broker has closed down.
+func (f *Failover) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-f.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
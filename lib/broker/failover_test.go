@@ -0,0 +1,215 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockHealthOutput struct {
+	MockOutputType
+	connected int32
+}
+
+func (m *mockHealthOutput) Connected() bool {
+	return atomic.LoadInt32(&m.connected) == 1
+}
+
+func (m *mockHealthOutput) setConnected(c bool) {
+	if c {
+		atomic.StoreInt32(&m.connected, 1)
+	} else {
+		atomic.StoreInt32(&m.connected, 0)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestFailoverInterfaces(t *testing.T) {
+	f := &Failover{}
+	if types.Consumer(f) == nil {
+		t.Errorf("Failover: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("Failover: nil types.Closable")
+	}
+}
+
+func TestFailoverBadInterval(t *testing.T) {
+	if _, err := NewFailover(nil, log.Noop(), metrics.Noop(), OptFailoverSetCheckInterval(0)); err == nil {
+		t.Error("Expected error from zero check interval")
+	}
+}
+
+func TestFailoverHysteresis(t *testing.T) {
+	mockOutputs := []*mockHealthOutput{{connected: 1}, {connected: 1}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	// Health checks are triggered manually by the test.
+	oTM, err := NewFailover(
+		outputs, log.Noop(), metrics.Noop(),
+		OptFailoverSetCheckInterval(time.Hour),
+		OptFailoverSetThresholds(2, 3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	sendAndExpect := func(exp int) {
+		t.Helper()
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		var ts types.Transaction
+		select {
+		case ts = <-mockOutputs[exp].TChan:
+		case <-mockOutputs[1-exp].TChan:
+			t.Fatalf("Message sent to wrong output, expected %v", exp)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+		go func() {
+			ts.ResponseChan <- response.NewAck()
+		}()
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+	}
+
+	sendAndExpect(0)
+
+	// A single failed check does not trigger a failover.
+	mockOutputs[0].setConnected(false)
+	oTM.check()
+	sendAndExpect(0)
+
+	oTM.check()
+	sendAndExpect(1)
+
+	// A flapping primary does not fail back.
+	mockOutputs[0].setConnected(true)
+	oTM.check()
+	oTM.check()
+	mockOutputs[0].setConnected(false)
+	oTM.check()
+	sendAndExpect(1)
+
+	mockOutputs[0].setConnected(true)
+	oTM.check()
+	oTM.check()
+	sendAndExpect(1)
+	oTM.check()
+	sendAndExpect(0)
+
+	// With no healthy outputs the primary is used.
+	mockOutputs[0].setConnected(false)
+	mockOutputs[1].setConnected(false)
+	oTM.check()
+	oTM.check()
+	sendAndExpect(0)
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFailoverCheckLoop(t *testing.T) {
+	mockOutputs := []*mockHealthOutput{{connected: 0}, {connected: 1}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	oTM, err := NewFailover(
+		outputs, log.Noop(), metrics.Noop(),
+		OptFailoverSetCheckInterval(time.Millisecond),
+		OptFailoverSetThresholds(1, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(make(chan types.Transaction)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for atomic.LoadInt32(&oTM.active) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for failover")
+		}
+		<-time.After(time.Millisecond)
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFailoverOutputMetrics(t *testing.T) {
+	primary := &MockOutputType{}
+	readChan := make(chan types.Transaction)
+	stats := metrics.NewLocal()
+
+	oTM, err := NewFailover([]types.Output{primary}, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	checkOutputMetrics(t, stats, readChan, primary, 0)
+
+	oTM.CloseAsync()
+	if err = oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
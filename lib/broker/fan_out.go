@@ -21,6 +21,8 @@
 package broker
 
 import (
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -35,20 +37,28 @@ import (
 
 // FanOut is a broker that implements types.Consumer and broadcasts each message
 // out to an array of outputs.
+//
+// Each output is fed from its own queue, allowing an output to fall behind the
+// others by a configured number of in-flight messages before it applies back
+// pressure to the broker. Messages are only acknowledged once all outputs have
+// succeeded, or a message that repeatedly fails on an output has been sent to
+// the dead letter output instead.
 type FanOut struct {
 	running int32
 
 	logger log.Modular
 	stats  metrics.Type
 
-	throt *throttle.Type
+	maxInFlight int
+	maxRetries  uint64
 
 	transactions <-chan types.Transaction
 
-	outputTsChans  []chan types.Transaction
-	outputResChans []chan types.Response
-	outputs        []types.Output
-	outputNs       []int
+	outputTsChans []chan types.Transaction
+	outputs       []types.Output
+
+	deadLetter       types.Output
+	deadLetterTsChan chan types.Transaction
 
 	closedChan chan struct{}
 	closeChan  chan struct{}
@@ -56,33 +66,61 @@ type FanOut struct {
 
 // NewFanOut creates a new FanOut type by providing outputs.
 func NewFanOut(
-	outputs []types.Output, logger log.Modular, stats metrics.Type,
+	outputs []types.Output, logger log.Modular, stats metrics.Type, options ...func(*FanOut),
 ) (*FanOut, error) {
 	o := &FanOut{
 		running:      1,
 		stats:        stats,
 		logger:       logger,
+		maxInFlight:  1,
 		transactions: nil,
 		outputs:      outputs,
-		outputNs:     []int{},
 		closedChan:   make(chan struct{}),
 		closeChan:    make(chan struct{}),
 	}
-	o.throt = throttle.New(throttle.OptCloseChan(o.closeChan))
+	for _, opt := range options {
+		opt(o)
+	}
+	if o.maxInFlight < 1 {
+		return nil, fmt.Errorf("max in flight must be at least 1, received: %v", o.maxInFlight)
+	}
 
 	o.outputTsChans = make([]chan types.Transaction, len(o.outputs))
-	o.outputResChans = make([]chan types.Response, len(o.outputs))
 	for i := range o.outputTsChans {
-		o.outputNs = append(o.outputNs, i)
 		o.outputTsChans[i] = make(chan types.Transaction)
-		o.outputResChans[i] = make(chan types.Response)
 		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
 			return nil, err
 		}
 	}
+	if o.deadLetter != nil {
+		o.deadLetterTsChan = make(chan types.Transaction)
+		if err := o.deadLetter.Consume(o.deadLetterTsChan); err != nil {
+			return nil, err
+		}
+	}
 	return o, nil
 }
 
+// OptFanOutSetMaxInFlight sets the maximum number of messages that each output
+// can have in flight at any given time. An output that has reached this limit
+// applies back pressure to the broker. The default is 1.
+func OptFanOutSetMaxInFlight(n int) func(*FanOut) {
+	return func(o *FanOut) {
+		o.maxInFlight = n
+	}
+}
+
+// OptFanOutSetDeadLetter sets an output that receives messages which failed to
+// be sent to an output after maxRetries retries. Once a message is accepted by
+// the dead letter output it is considered delivered for the failed output. When
+// not set failed messages are retried indefinitely.
+func OptFanOutSetDeadLetter(output types.Output, maxRetries uint64) func(*FanOut) {
+	return func(o *FanOut) {
+		o.deadLetter = output
+		o.maxRetries = maxRetries
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Consume assigns a new transactions channel for the broker to read.
@@ -109,20 +147,42 @@ func (o *FanOut) Connected() bool {
 
 //------------------------------------------------------------------------------
 
+// fanOutTransaction tracks a transaction that is in flight to a number of
+// outputs.
+type fanOutTransaction struct {
+	ts      types.Transaction
+	pending int32
+	skipAck int32
+}
+
 // loop is an internal loop that brokers incoming messages to many outputs.
 func (o *FanOut) loop() {
+	var wg sync.WaitGroup
+	queues := make([]chan *fanOutTransaction, len(o.outputs))
+	mOutputs := make([]*outputMetrics, len(o.outputs))
+	for i := range queues {
+		// The output loop holds one message, the remainder are queued.
+		queues[i] = make(chan *fanOutTransaction, o.maxInFlight-1)
+		mOutputs[i] = newOutputMetrics(o.stats, i, true)
+		wg.Add(1)
+		go o.outputLoop(i, queues[i], mOutputs[i], &wg)
+	}
+
 	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+		wg.Wait()
 		for _, c := range o.outputTsChans {
 			close(c)
 		}
+		if o.deadLetterTsChan != nil {
+			close(o.deadLetterTsChan)
+		}
 		close(o.closedChan)
 	}()
 
-	var (
-		mMsgsRcvd  = o.stats.GetCounter("messages.received")
-		mOutputErr = o.stats.GetCounter("error")
-		mMsgsSnt   = o.stats.GetCounter("messages.sent")
-	)
+	mMsgsRcvd := o.stats.GetCounter("messages.received")
 
 	for atomic.LoadInt32(&o.running) == 1 {
 		var ts types.Transaction
@@ -138,45 +198,145 @@ func (o *FanOut) loop() {
 		}
 		mMsgsRcvd.Incr(1)
 
-		outputTargets := o.outputNs
-		for len(outputTargets) > 0 {
-			for _, i := range outputTargets {
-				msgCopy := ts.Payload.Copy()
-				select {
-				case o.outputTsChans[i] <- types.NewTransaction(msgCopy, o.outputResChans[i]):
-				case <-o.closeChan:
-					return
+		t := &fanOutTransaction{
+			ts:      ts,
+			pending: int32(len(queues)),
+		}
+		for i, q := range queues {
+			// Time spent waiting on a full queue is back pressure applied by
+			// the output to all other outputs.
+			start := time.Now()
+			select {
+			case q <- t:
+			case <-o.closeChan:
+				return
+			}
+			mOutputs[i].blockedSince(start)
+			mOutputs[i].queueDepth.Set(int64(len(q)))
+		}
+	}
+}
+
+// outputLoop sends each queued message to a single output, retrying until it
+// is either delivered or has been sent to the dead letter output.
+func (o *FanOut) outputLoop(
+	i int, queue <-chan *fanOutTransaction, mOutput *outputMetrics, wg *sync.WaitGroup,
+) {
+	defer wg.Done()
+
+	var (
+		mOutputErr  = o.stats.GetCounter("error")
+		mMsgsSnt    = o.stats.GetCounter("messages.sent")
+		mDeadLetter = o.stats.GetCounter(fmt.Sprintf("broker.outputs.%v.dead_letter", i))
+	)
+
+	throt := throttle.New(throttle.OptCloseChan(o.closeChan))
+	resChan := make(chan types.Response)
+
+	for {
+		var t *fanOutTransaction
+		var open bool
+		select {
+		case t, open = <-queue:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+		mOutput.queueDepth.Set(int64(len(queue)))
+
+		var retries uint64
+	sendLoop:
+		for {
+			select {
+			case o.outputTsChans[i] <- types.NewTransaction(t.ts.Payload.Copy(), resChan):
+			case <-o.closeChan:
+				return
+			}
+			mOutput.sent()
+			var res types.Response
+			select {
+			case res = <-resChan:
+			case <-o.closeChan:
+				return
+			}
+			mOutput.received(res)
+			if res.Error() == nil {
+				throt.Reset()
+				mMsgsSnt.Incr(1)
+				if res.SkipAck() {
+					atomic.StoreInt32(&t.skipAck, 1)
 				}
+				break sendLoop
 			}
-			newTargets := []int{}
-			for _, i := range outputTargets {
-				select {
-				case res := <-o.outputResChans[i]:
-					if res.Error() != nil {
-						newTargets = append(newTargets, i)
-						o.logger.Errorf("Failed to dispatch fan out message: %v\n", res.Error())
-						mOutputErr.Incr(1)
-						if !o.throt.Retry() {
-							return
-						}
-					} else {
-						o.throt.Reset()
-						mMsgsSnt.Incr(1)
-					}
-				case <-o.closeChan:
+			o.logger.Errorf("Failed to dispatch fan out message: %v\n", res.Error())
+			mOutputErr.Incr(1)
+			if o.deadLetter != nil && retries >= o.maxRetries {
+				throt.Reset()
+				if !o.sendDeadLetter(t, throt) {
 					return
 				}
+				mDeadLetter.Incr(1)
+				break sendLoop
 			}
-			outputTargets = newTargets
+			retries++
+			if !throt.Retry() {
+				return
+			}
+		}
+
+		// The last output to finish with a message responds to it.
+		if atomic.AddInt32(&t.pending, -1) > 0 {
+			continue
+		}
+		// If any output skips the acknowledgement of a message, such as when
+		// it is pending within a batch, then so must we.
+		var res types.Response = response.NewAck()
+		if atomic.LoadInt32(&t.skipAck) == 1 {
+			res = response.NewUnack()
 		}
 		select {
-		case ts.ResponseChan <- response.NewAck():
+		case t.ts.ResponseChan <- res:
 		case <-o.closeChan:
 			return
 		}
 	}
 }
 
+// sendDeadLetter sends a message to the dead letter output, retrying until it
+// succeeds. Returns false if the broker was closed before the message could be
+// sent.
+func (o *FanOut) sendDeadLetter(t *fanOutTransaction, throt *throttle.Type) bool {
+	mErr := o.stats.GetCounter("broker.dead_letter.error")
+	resChan := make(chan types.Response)
+	for {
+		select {
+		case o.deadLetterTsChan <- types.NewTransaction(t.ts.Payload.Copy(), resChan):
+		case <-o.closeChan:
+			return false
+		}
+		var res types.Response
+		select {
+		case res = <-resChan:
+		case <-o.closeChan:
+			return false
+		}
+		if res.Error() == nil {
+			throt.Reset()
+			if res.SkipAck() {
+				atomic.StoreInt32(&t.skipAck, 1)
+			}
+			return true
+		}
+		o.logger.Errorf("Failed to dispatch message to dead letter output: %v\n", res.Error())
+		mErr.Incr(1)
+		if !throt.Retry() {
+			return false
+		}
+	}
+}
+
 // CloseAsync shuts down the FanOut broker and stops processing requests.
 func (o *FanOut) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
@@ -209,6 +209,361 @@ func TestFanOutAtLeastOnce(t *testing.T) {
 	}
 }
 
+func TestFanOutSkipAck(t *testing.T) {
+	mockOne := MockOutputType{}
+	mockTwo := MockOutputType{}
+
+	outputs := []types.Output{&mockOne, &mockTwo}
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewFanOut(
+		outputs, log.New(os.Stdout, logConfig), metrics.DudType{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second output acknowledges every other message, as if batching.
+	for i := 0; i < 4; i++ {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte("hello world")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		var ts1, ts2 types.Transaction
+		select {
+		case ts1 = <-mockOne.TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for mockOne")
+		}
+		select {
+		case ts2 = <-mockTwo.TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for mockTwo")
+		}
+		select {
+		case ts1.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		var res types.Response = response.NewAck()
+		if i%2 == 0 {
+			res = response.NewUnack()
+		}
+		select {
+		case ts2.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Errorf("Fan out returned error %v", res.Error())
+			}
+			if exp, act := i%2 == 0, res.SkipAck(); exp != act {
+				t.Errorf("Wrong skip ack for message %v: %v != %v", i, act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+	}
+
+	close(readChan)
+
+	if err := oTM.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFanOutMaxInFlight(t *testing.T) {
+	fast, slow := &MockOutputType{}, &MockOutputType{}
+	readChan := make(chan types.Transaction)
+
+	oTM, err := NewFanOut(
+		[]types.Output{fast, slow}, log.Noop(), metrics.DudType{},
+		OptFanOutSetMaxInFlight(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(out *MockOutputType) types.Transaction {
+		select {
+		case ts := <-out.TChan:
+			return ts
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for msg rcv")
+		}
+		return types.Transaction{}
+	}
+	respond := func(ts types.Transaction) {
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for res send")
+		}
+	}
+
+	resChans := []chan types.Response{}
+	for i := 0; i < 3; i++ {
+		resChan := make(chan types.Response, 1)
+		resChans = append(resChans, resChan)
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(fmt.Sprintf("foo%v", i))}), resChan):
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for msg %v send", i)
+		}
+	}
+
+	// The fast output continues whilst the slow output holds its first message.
+	slowTs := receive(slow)
+	for i := 0; i < 3; i++ {
+		ts := receive(fast)
+		if exp, act := fmt.Sprintf("foo%v", i), string(ts.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		respond(ts)
+	}
+
+	// The slow output has reached its limit of two messages in flight.
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("bar")}), make(chan types.Response)):
+		t.Fatal("Expected back pressure from slow output")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-resChans[i]:
+			t.Fatalf("Received premature response %v", i)
+		default:
+		}
+		if exp, act := fmt.Sprintf("foo%v", i), string(slowTs.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		respond(slowTs)
+		select {
+		case res := <-resChans[i]:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for response %v", i)
+		}
+		if i < 2 {
+			slowTs = receive(slow)
+		}
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFanOutDeadLetter(t *testing.T) {
+	good, bad, dead := &MockOutputType{}, &MockOutputType{}, &MockOutputType{}
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	stats := metrics.NewLocal()
+
+	oTM, err := NewFanOut(
+		[]types.Output{good, bad}, log.Noop(), stats,
+		OptFanOutSetDeadLetter(dead, 1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for msg send")
+	}
+
+	select {
+	case ts := <-good.TChan:
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for res send")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for msg rcv")
+	}
+
+	// The initial attempt plus one retry.
+	for i := 0; i < 2; i++ {
+		select {
+		case ts := <-bad.TChan:
+			select {
+			case ts.ResponseChan <- response.NewError(errors.New("test")):
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for res send")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for msg rcv")
+		}
+	}
+
+	select {
+	case <-resChan:
+		t.Fatal("Received premature response")
+	case ts := <-dead.TChan:
+		if exp, act := "foo", string(ts.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for res send")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for dead letter msg rcv")
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for response")
+	}
+
+	if exp, act := int64(1), stats.GetCounters()["broker.outputs.1.dead_letter"]; exp != act {
+		t.Errorf("Wrong dead letter count: %v != %v", act, exp)
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	select {
+	case _, open := <-dead.TChan:
+		if open {
+			t.Error("dead letter output still open after closure")
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for msg rcv")
+	}
+}
+
+func TestFanOutOutputMetrics(t *testing.T) {
+	fast, slow := &MockOutputType{}, &MockOutputType{}
+	readChan := make(chan types.Transaction)
+	stats := metrics.NewLocal()
+
+	oTM, err := NewFanOut(
+		[]types.Output{fast, slow}, log.Noop(), stats,
+		OptFanOutSetMaxInFlight(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(out *MockOutputType) types.Transaction {
+		select {
+		case ts := <-out.TChan:
+			return ts
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for msg rcv")
+		}
+		return types.Transaction{}
+	}
+	respond := func(ts types.Transaction, res types.Response) {
+		select {
+		case ts.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for res send")
+		}
+	}
+	send := func(content string) error {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), make(chan types.Response, 1)):
+		case <-time.After(time.Second):
+			return fmt.Errorf("timed out waiting for msg %v send", content)
+		}
+		return nil
+	}
+	gauge := func(path string) int64 {
+		return stats.GetCounters()[path]
+	}
+
+	if err = send("foo"); err != nil {
+		t.Fatal(err)
+	}
+	respond(receive(fast), response.NewAck())
+	slowTs := receive(slow)
+
+	if err = send("bar"); err != nil {
+		t.Fatal(err)
+	}
+	respond(receive(fast), response.NewAck())
+
+	// Wait for the second message to be queued behind the first.
+	<-time.After(time.Millisecond * 50)
+	if exp, act := int64(0), gauge("broker.outputs.0.in_flight"); exp != act {
+		t.Errorf("Wrong in flight count: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), gauge("broker.outputs.1.in_flight"); exp != act {
+		t.Errorf("Wrong in flight count: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), gauge("broker.outputs.1.queue_depth"); exp != act {
+		t.Errorf("Wrong queue depth: %v != %v", act, exp)
+	}
+	if act := gauge("broker.outputs.1.last_error"); act != 0 {
+		t.Errorf("Unexpected last error: %v", act)
+	}
+
+	// The slow output is at its limit and blocks the next message.
+	sendErrChan := make(chan error, 1)
+	go func() {
+		sendErrChan <- send("baz")
+	}()
+	respond(receive(fast), response.NewAck())
+	<-time.After(time.Millisecond * 50)
+
+	respond(slowTs, response.NewError(errors.New("test")))
+	respond(receive(slow), response.NewAck())
+	for i := 0; i < 2; i++ {
+		respond(receive(slow), response.NewAck())
+	}
+	if err = <-sendErrChan; err != nil {
+		t.Error(err)
+	}
+	<-time.After(time.Millisecond * 50)
+
+	if act := gauge("broker.outputs.1.last_error"); act == 0 {
+		t.Error("Expected last error to be set")
+	}
+	if act := gauge("broker.outputs.0.last_error"); act != 0 {
+		t.Errorf("Unexpected last error: %v", act)
+	}
+	if act := time.Duration(gauge("broker.outputs.1.time_blocked")); act < time.Millisecond*50 {
+		t.Errorf("Expected at least 50ms blocked, received: %v", act)
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestFanOutShutDownFromErrorResponse(t *testing.T) {
 	outputs := []types.Output{}
 	mockOutput := &MockOutputType{}
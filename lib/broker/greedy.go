@@ -21,18 +21,45 @@
 package broker
 
 import (
+	"errors"
+	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
 //------------------------------------------------------------------------------
 
+// ErrNoDynamicOutputs is returned when attempting to remove an output from a
+// Greedy broker that has no outputs added at runtime.
+var ErrNoDynamicOutputs = errors.New("broker has no dynamically added outputs")
+
 // Greedy is a broker that implements types.Consumer and sends each message
 // out to a single consumer chosen from an array in round-robin fashion.
 // Consumers that apply backpressure will block all consumers.
 type Greedy struct {
+	mut sync.Mutex
+
+	transactions <-chan types.Transaction
+
 	outputs []types.Output
+	dynamic []*greedyDynamicOutput
+}
+
+// greedyDynamicOutput is an output added at runtime, which is fed from a
+// dedicated channel so that it can be removed gracefully by closing it.
+type greedyDynamicOutput struct {
+	output   types.Output
+	tsChan   chan types.Transaction
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func (d *greedyDynamicOutput) stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopped)
+	})
 }
 
 // NewGreedy creates a new Greedy type by providing consumers.
@@ -46,30 +73,137 @@ func NewGreedy(outputs []types.Output) (*Greedy, error) {
 
 // Consume assigns a new messages channel for the broker to read.
 func (g *Greedy) Consume(ts <-chan types.Transaction) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	g.transactions = ts
+
 	for _, out := range g.outputs {
 		if err := out.Consume(ts); err != nil {
 			return err
 		}
 	}
+	for _, d := range g.dynamic {
+		go g.feed(d)
+	}
 	return nil
 }
 
+// feed forwards messages to a dynamically added output until it is removed,
+// at which point its transaction channel is closed.
+func (g *Greedy) feed(d *greedyDynamicOutput) {
+	defer close(d.tsChan)
+	for {
+		select {
+		case ts, open := <-g.transactions:
+			if !open {
+				return
+			}
+			select {
+			case d.tsChan <- ts:
+			case <-d.stopped:
+				// The output was removed before accepting the message, which
+				// is rejected so that it can be sent again by the input.
+				go func() {
+					ts.ResponseChan <- response.NewError(types.ErrTypeClosed)
+				}()
+				return
+			}
+		case <-d.stopped:
+			return
+		}
+	}
+}
+
+// AddOutput adds an output to the broker at runtime, which begins consuming
+// messages immediately if the broker has already started.
+func (g *Greedy) AddOutput(out types.Output) error {
+	d := &greedyDynamicOutput{
+		output:  out,
+		tsChan:  make(chan types.Transaction),
+		stopped: make(chan struct{}),
+	}
+	if err := out.Consume(d.tsChan); err != nil {
+		return err
+	}
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	g.dynamic = append(g.dynamic, d)
+	if g.transactions != nil {
+		go g.feed(d)
+	}
+	return nil
+}
+
+// RemoveOutput removes the most recently added output that was added at
+// runtime. A message claimed for the output that it has not yet accepted is
+// rejected with an error, and this call blocks until the output is closed or
+// the timeout elapses.
+func (g *Greedy) RemoveOutput(timeout time.Duration) error {
+	g.mut.Lock()
+	if len(g.dynamic) == 0 {
+		g.mut.Unlock()
+		return ErrNoDynamicOutputs
+	}
+	d := g.dynamic[len(g.dynamic)-1]
+	g.dynamic = g.dynamic[:len(g.dynamic)-1]
+	started := g.transactions != nil
+	g.mut.Unlock()
+
+	d.stop()
+	if !started {
+		close(d.tsChan)
+	}
+	return d.output.WaitForClose(timeout)
+}
+
 // Connected returns a boolean indicating whether this output is currently
 // connected to its target.
 func (g *Greedy) Connected() bool {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
 	for _, out := range g.outputs {
 		if !out.Connected() {
 			return false
 		}
 	}
+	for _, d := range g.dynamic {
+		if !d.output.Connected() {
+			return false
+		}
+	}
 	return true
 }
 
 //------------------------------------------------------------------------------
 
+// allOutputs returns both the static and dynamically added outputs.
+func (g *Greedy) allOutputs() []types.Output {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	outputs := make([]types.Output, 0, len(g.outputs)+len(g.dynamic))
+	outputs = append(outputs, g.outputs...)
+	for _, d := range g.dynamic {
+		outputs = append(outputs, d.output)
+	}
+	return outputs
+}
+
 // CloseAsync shuts down the Greedy broker and stops processing requests.
 func (g *Greedy) CloseAsync() {
-	for _, out := range g.outputs {
+	g.mut.Lock()
+	for _, d := range g.dynamic {
+		d.stop()
+	}
+	g.mut.Unlock()
+	for _, out := range g.allOutputs() {
 		out.CloseAsync()
 	}
 }
@@ -78,7 +212,7 @@ func (g *Greedy) CloseAsync() {
 func (g *Greedy) WaitForClose(timeout time.Duration) error {
 	tStarted := time.Now()
 	remaining := timeout
-	for _, out := range g.outputs {
+	for _, out := range g.allOutputs() {
 		if err := out.WaitForClose(remaining); err != nil {
 			return err
 		}
@@ -130,4 +130,126 @@ func TestBasicGreedy(t *testing.T) {
 	}
 }
 
+func TestGreedyDynamicOutputs(t *testing.T) {
+	dynOne, dynTwo := &MockOutputType{}, &MockOutputType{}
+
+	oTM, err := NewGreedy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.RemoveOutput(time.Second); err != ErrNoDynamicOutputs {
+		t.Errorf("Wrong error returned: %v != %v", err, ErrNoDynamicOutputs)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	sendAndExpect := func(content string, out *MockOutputType) {
+		t.Helper()
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		var ts types.Transaction
+		select {
+		case ts = <-out.TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+		if act := string(ts.Payload.Get(0).Get()); act != content {
+			t.Errorf("Wrong content: %v != %v", act, content)
+		}
+		go func() {
+			ts.ResponseChan <- response.NewAck()
+		}()
+		select {
+		case <-resChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for response")
+		}
+	}
+
+	// Outputs can be added both before and after the broker starts.
+	if err = oTM.AddOutput(dynOne); err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+	sendAndExpect("foo", dynOne)
+
+	if err = oTM.RemoveOutput(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case _, open := <-dynOne.TChan:
+		if open {
+			t.Error("Expected removed output channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for removed output to close")
+	}
+
+	if err = oTM.AddOutput(dynTwo); err != nil {
+		t.Fatal(err)
+	}
+	sendAndExpect("bar", dynTwo)
+
+	oTM.CloseAsync()
+	if err = oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGreedyRemoveOutputPendingSend(t *testing.T) {
+	dyn := &MockOutputType{}
+
+	oTM, err := NewGreedy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.AddOutput(dyn); err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// The output never reads the message, leaving the send pending.
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker send")
+	}
+
+	if err = oTM.RemoveOutput(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() != types.ErrTypeClosed {
+			t.Errorf("Wrong response error: %v != %v", res.Error(), types.ErrTypeClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for response")
+	}
+	select {
+	case _, open := <-dyn.TChan:
+		if open {
+			t.Error("Expected removed output channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for removed output to close")
+	}
+
+	oTM.CloseAsync()
+	if err = oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,257 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Hash is a broker that implements types.Consumer and routes each message part
+// to a single output chosen by a consistent hash of a key extracted from the
+// part, guaranteeing that all parts of the same key reach the same output.
+// Consumers that apply backpressure will block all consumers.
+type Hash struct {
+	running int32
+
+	stats metrics.Type
+	key   func(msg types.Message, index int) []byte
+
+	transactions <-chan types.Transaction
+
+	outputTsChans []chan types.Transaction
+	outputs       []types.Output
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewHash creates a new Hash type by providing consumers and a function that
+// extracts the routing key of a message part.
+func NewHash(
+	outputs []types.Output,
+	key func(msg types.Message, index int) []byte,
+	stats metrics.Type,
+) (*Hash, error) {
+	o := &Hash{
+		running:      1,
+		stats:        stats,
+		key:          key,
+		transactions: nil,
+		outputs:      outputs,
+		closedChan:   make(chan struct{}),
+		closeChan:    make(chan struct{}),
+	}
+	o.outputTsChans = make([]chan types.Transaction, len(o.outputs))
+	for i := range o.outputTsChans {
+		o.outputTsChans[i] = make(chan types.Transaction)
+		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new messages channel for the broker to read.
+func (o *Hash) Consume(ts <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = ts
+
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (o *Hash) Connected() bool {
+	for _, out := range o.outputs {
+		if !out.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// jumpHash maps a key to one of n buckets using the jump consistent hash
+// algorithm, which moves a minimal number of keys when n changes.
+func jumpHash(key uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// hashKey maps a routing key to one of n outputs.
+func hashKey(key []byte, n int) int {
+	h := fnv.New64a()
+	h.Write(key)
+	return jumpHash(h.Sum64(), n)
+}
+
+// awaitResponses waits for the responses of a batch split across multiple
+// outputs and forwards a single response to the origin of the batch.
+func (o *Hash) awaitResponses(
+	resChans []chan types.Response, mOutputs []*outputMetrics, resChan chan<- types.Response,
+) {
+	var err error
+	skipAck := false
+	for i, c := range resChans {
+		select {
+		case res := <-c:
+			mOutputs[i].received(res)
+			if res.Error() != nil {
+				err = res.Error()
+			} else if res.SkipAck() {
+				skipAck = true
+			}
+		case <-o.closeChan:
+			return
+		}
+	}
+	var res types.Response = response.NewAck()
+	if err != nil {
+		res = response.NewError(err)
+	} else if skipAck {
+		res = response.NewUnack()
+	}
+	select {
+	case resChan <- res:
+	case <-o.closeChan:
+	}
+}
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (o *Hash) loop() {
+	defer func() {
+		for _, c := range o.outputTsChans {
+			close(c)
+		}
+		close(o.closedChan)
+	}()
+
+	var (
+		mMsgsRcvd = o.stats.GetCounter("messages.received")
+		mSplit    = o.stats.GetCounter("batch.split")
+		mOutputs  = make([]*outputMetrics, len(o.outputs))
+	)
+	for i := range o.outputs {
+		mOutputs[i] = newOutputMetrics(o.stats, i, false)
+	}
+
+	var open bool
+	for atomic.LoadInt32(&o.running) == 1 {
+		var ts types.Transaction
+		select {
+		case ts, open = <-o.transactions:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+		mMsgsRcvd.Incr(1)
+
+		var targets []int
+		batches := map[int]types.Message{}
+		ts.Payload.Iter(func(i int, p types.Part) error {
+			t := hashKey(o.key(ts.Payload, i), len(o.outputTsChans))
+			b, exists := batches[t]
+			if !exists {
+				b = message.New(nil)
+				batches[t] = b
+				targets = append(targets, t)
+			}
+			b.Append(p)
+			return nil
+		})
+
+		if len(targets) <= 1 {
+			t := 0
+			if len(targets) == 1 {
+				t = targets[0]
+			}
+			start := time.Now()
+			resChan := make(chan types.Response, 1)
+			select {
+			case o.outputTsChans[t] <- types.NewTransaction(ts.Payload, resChan):
+			case <-o.closeChan:
+				return
+			}
+			mOutputs[t].blockedSince(start)
+			mOutputs[t].sent()
+			go mOutputs[t].forward(resChan, ts.ResponseChan, o.closeChan)
+			continue
+		}
+
+		mSplit.Incr(1)
+		resChans := make([]chan types.Response, len(targets))
+		mTargets := make([]*outputMetrics, len(targets))
+		for i, t := range targets {
+			resChans[i] = make(chan types.Response, 1)
+			mTargets[i] = mOutputs[t]
+			start := time.Now()
+			select {
+			case o.outputTsChans[t] <- types.NewTransaction(batches[t], resChans[i]):
+			case <-o.closeChan:
+				return
+			}
+			mTargets[i].blockedSince(start)
+			mTargets[i].sent()
+		}
+		go o.awaitResponses(resChans, mTargets, ts.ResponseChan)
+	}
+}
+
+// CloseAsync shuts down the Hash broker and stops processing requests.
+func (o *Hash) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
+		close(o.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Hash broker has closed down.
+func (o *Hash) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,260 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestHashInterfaces(t *testing.T) {
+	f := &Hash{}
+	if types.Consumer(f) == nil {
+		t.Errorf("Hash: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("Hash: nil types.Closable")
+	}
+}
+
+func contentKey(msg types.Message, index int) []byte {
+	return msg.Get(index).Get()
+}
+
+func TestJumpHashConsistency(t *testing.T) {
+	moved := 0
+	for i := uint64(0); i < 1000; i++ {
+		a, b := jumpHash(i*7919, 10), jumpHash(i*7919, 11)
+		if a < 0 || a >= 10 {
+			t.Fatalf("Bucket out of range: %v", a)
+		}
+		if a != b {
+			if b != 10 {
+				t.Errorf("Key moved between existing buckets: %v -> %v", a, b)
+			}
+			moved++
+		}
+	}
+	if moved == 0 || moved > 200 {
+		t.Errorf("Unexpected count of moved keys: %v", moved)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestHashRouting(t *testing.T) {
+	nKeys, nMsgs := 20, 500
+
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response, 1)
+
+	oTM, err := NewHash(outputs, contentKey, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	keyOutputs := map[string]int{}
+	for i := 0; i < nMsgs; i++ {
+		key := fmt.Sprintf("key%v", i%nKeys)
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(key)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+
+		var ts types.Transaction
+		var index int
+		select {
+		case ts = <-mockOutputs[0].TChan:
+			index = 0
+		case ts = <-mockOutputs[1].TChan:
+			index = 1
+		case ts = <-mockOutputs[2].TChan:
+			index = 2
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+		if act := string(ts.Payload.Get(0).Get()); act != key {
+			t.Errorf("Wrong content: %v != %v", act, key)
+		}
+		if prev, exists := keyOutputs[key]; exists && prev != index {
+			t.Errorf("Key %v routed to output %v and %v", key, prev, index)
+		}
+		keyOutputs[key] = index
+
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Errorf("Received unexpected errors from broker: %v", res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+	}
+
+	used := map[int]struct{}{}
+	for _, i := range keyOutputs {
+		used[i] = struct{}{}
+	}
+	if len(used) < 2 {
+		t.Errorf("Expected keys to be spread across outputs: %v", keyOutputs)
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHashSplitBatch(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewHash(outputs, contentKey, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// Find a key for each output.
+	keys := make([]string, 2)
+	for i := 0; len(keys[0]) == 0 || len(keys[1]) == 0; i++ {
+		k := fmt.Sprintf("key%v", i)
+		keys[hashKey([]byte(k), 2)] = k
+	}
+
+	for _, expErr := range []error{nil, errors.New("nope")} {
+		msg := message.New([][]byte{
+			[]byte(keys[0]), []byte(keys[1]), []byte(keys[0]),
+		})
+		select {
+		case readChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+
+		for i, o := range mockOutputs {
+			var ts types.Transaction
+			select {
+			case ts = <-o.TChan:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for broker propagate")
+			}
+			expLen := 1
+			if i == 0 {
+				expLen = 2
+			}
+			if act := ts.Payload.Len(); act != expLen {
+				t.Errorf("Wrong batch size for output %v: %v != %v", i, act, expLen)
+			}
+			ts.Payload.Iter(func(_ int, p types.Part) error {
+				if act := string(p.Get()); act != keys[i] {
+					t.Errorf("Wrong key for output %v: %v != %v", i, act, keys[i])
+				}
+				return nil
+			})
+			var res types.Response = response.NewAck()
+			if i == 1 && expErr != nil {
+				res = response.NewError(expErr)
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out responding to broker")
+			}
+		}
+
+		select {
+		case res := <-resChan:
+			if res.Error() != expErr {
+				t.Errorf("Wrong response: %v != %v", res.Error(), expErr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHashOutputMetrics(t *testing.T) {
+	outputs := []types.Output{}
+	mockOutputs := []*MockOutputType{{}, {}}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+	readChan := make(chan types.Transaction)
+	stats := metrics.NewLocal()
+
+	oTM, err := NewHash(outputs, func(msg types.Message, index int) []byte {
+		return msg.Get(index).Get()
+	}, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	target := hashKey([]byte("foo"), len(mockOutputs))
+	checkOutputMetrics(t, stats, readChan, mockOutputs[target], target)
+
+	oTM.CloseAsync()
+	if err = oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
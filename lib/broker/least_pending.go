@@ -0,0 +1,195 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// LeastPending is a broker that implements types.Consumer and sends each
+// message to the output with the fewest messages pending a response, where
+// ties are broken in round-robin fashion.
+type LeastPending struct {
+	running int32
+
+	stats metrics.Type
+
+	pending  []int64
+	mPending []metrics.StatGauge
+	mOutputs []*outputMetrics
+
+	transactions <-chan types.Transaction
+
+	outputTsChans []chan types.Transaction
+	outputs       []types.Output
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewLeastPending creates a new LeastPending type by providing consumers.
+func NewLeastPending(outputs []types.Output, stats metrics.Type) (*LeastPending, error) {
+	o := &LeastPending{
+		running:      1,
+		stats:        stats,
+		pending:      make([]int64, len(outputs)),
+		transactions: nil,
+		outputs:      outputs,
+		closedChan:   make(chan struct{}),
+		closeChan:    make(chan struct{}),
+	}
+	o.outputTsChans = make([]chan types.Transaction, len(o.outputs))
+	for i := range o.outputTsChans {
+		o.mPending = append(o.mPending, stats.GetGauge(fmt.Sprintf("broker.outputs.%v.pending", i)))
+		o.mOutputs = append(o.mOutputs, newOutputMetrics(stats, i, false))
+		o.outputTsChans[i] = make(chan types.Transaction)
+		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new messages channel for the broker to read.
+func (o *LeastPending) Consume(ts <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = ts
+
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (o *LeastPending) Connected() bool {
+	for _, out := range o.outputs {
+		if !out.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// leastPending returns the index of the output with the fewest pending
+// messages, checking outputs in order from a starting index.
+func (o *LeastPending) leastPending(start int) int {
+	target, least := start, atomic.LoadInt64(&o.pending[start])
+	for j := 1; j < len(o.pending) && least > 0; j++ {
+		i := (start + j) % len(o.pending)
+		if p := atomic.LoadInt64(&o.pending[i]); p < least {
+			target, least = i, p
+		}
+	}
+	return target
+}
+
+// forward waits for the response of a message sent to an output and forwards
+// it to the origin of the message.
+func (o *LeastPending) forward(index int, resChan <-chan types.Response, ogResChan chan<- types.Response) {
+	select {
+	case res := <-resChan:
+		o.mPending[index].Set(atomic.AddInt64(&o.pending[index], -1))
+		o.mOutputs[index].received(res)
+		select {
+		case ogResChan <- res:
+		case <-o.closeChan:
+		}
+	case <-o.closeChan:
+	}
+}
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (o *LeastPending) loop() {
+	defer func() {
+		for _, c := range o.outputTsChans {
+			close(c)
+		}
+		close(o.closedChan)
+	}()
+
+	var (
+		mMsgsRcvd = o.stats.GetCounter("messages.received")
+	)
+
+	start := 0
+	var open bool
+	for atomic.LoadInt32(&o.running) == 1 {
+		var ts types.Transaction
+		select {
+		case ts, open = <-o.transactions:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+		mMsgsRcvd.Incr(1)
+
+		i := o.leastPending(start)
+		if start++; start >= len(o.outputTsChans) {
+			start = 0
+		}
+
+		o.mPending[i].Set(atomic.AddInt64(&o.pending[i], 1))
+		start := time.Now()
+		resChan := make(chan types.Response, 1)
+		select {
+		case o.outputTsChans[i] <- types.NewTransaction(ts.Payload, resChan):
+		case <-o.closeChan:
+			return
+		}
+		o.mOutputs[i].blockedSince(start)
+		o.mOutputs[i].sent()
+		go o.forward(i, resChan, ts.ResponseChan)
+	}
+}
+
+// CloseAsync shuts down the LeastPending broker and stops processing requests.
+func (o *LeastPending) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
+		close(o.closeChan)
+	}
+}
+
+// WaitForClose blocks until the LeastPending broker has closed down.
+func (o *LeastPending) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
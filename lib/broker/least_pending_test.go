@@ -0,0 +1,220 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestLeastPendingInterfaces(t *testing.T) {
+	f := &LeastPending{}
+	if types.Consumer(f) == nil {
+		t.Errorf("LeastPending: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("LeastPending: nil types.Closable")
+	}
+}
+
+func TestLeastPendingDoubleClose(t *testing.T) {
+	oTM, err := NewLeastPending([]types.Output{}, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// This shouldn't cause a panic
+	oTM.CloseAsync()
+	oTM.CloseAsync()
+}
+
+//------------------------------------------------------------------------------
+
+func TestLeastPendingRouting(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewLeastPending(outputs, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := map[string]types.Transaction{}
+	sendAndExpect := func(content string, exp int) {
+		t.Helper()
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		var ts types.Transaction
+		var index int
+		select {
+		case ts = <-mockOutputs[0].TChan:
+			index = 0
+		case ts = <-mockOutputs[1].TChan:
+			index = 1
+		case ts = <-mockOutputs[2].TChan:
+			index = 2
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+		if index != exp {
+			t.Errorf("Message %v sent to wrong output: %v != %v", content, index, exp)
+		}
+		pending[content] = ts
+	}
+	respond := func(content string) {
+		t.Helper()
+		select {
+		case pending[content].ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+		delete(pending, content)
+	}
+
+	sendAndExpect("a", 0)
+	sendAndExpect("b", 1)
+	sendAndExpect("c", 2)
+
+	respond("b")
+	sendAndExpect("d", 1)
+
+	respond("a")
+	respond("c")
+	sendAndExpect("e", 2)
+	sendAndExpect("f", 0)
+
+	// Every output has a single pending message, and so ties are broken in
+	// order from the next output in the rotation.
+	sendAndExpect("g", 0)
+
+	for k := range pending {
+		respond(k)
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLeastPendingSlowOutput(t *testing.T) {
+	nMsgs := 100
+
+	mockOutputs := []*MockOutputType{{}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response, nMsgs)
+
+	oTM, err := NewLeastPending(outputs, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first output never responds whilst the second responds immediately.
+	var slow []types.Transaction
+	fastCount := 0
+	for i := 0; i < nMsgs; i++ {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(fmt.Sprintf("%v", i))}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		select {
+		case ts := <-mockOutputs[0].TChan:
+			slow = append(slow, ts)
+		case ts := <-mockOutputs[1].TChan:
+			fastCount++
+			ts.ResponseChan <- response.NewAck()
+			<-resChan
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+	}
+	if len(slow) != 1 {
+		t.Errorf("Expected a single message to the slow output, got %v", len(slow))
+	}
+	if exp := nMsgs - len(slow); fastCount != exp {
+		t.Errorf("Wrong count of messages to fast output: %v != %v", fastCount, exp)
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLeastPendingOutputMetrics(t *testing.T) {
+	out := &MockOutputType{}
+	readChan := make(chan types.Transaction)
+	stats := metrics.NewLocal()
+
+	oTM, err := NewLeastPending([]types.Output{out}, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	checkOutputMetrics(t, stats, readChan, out, 0)
+
+	oTM.CloseAsync()
+	if err = oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,97 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// outputMetrics contains gauges describing the state of a child output of a
+// broker under the namespace broker.outputs.N, which make it possible to
+// identify which child output is applying back pressure.
+type outputMetrics struct {
+	queueDepth  metrics.StatGauge
+	inFlight    metrics.StatGauge
+	lastError   metrics.StatGauge
+	timeBlocked metrics.StatGauge
+}
+
+// newOutputMetrics creates the gauges of a child output at an index. The queue
+// depth gauge is only registered when queued is true, as not all brokers queue
+// messages for their outputs.
+func newOutputMetrics(stats metrics.Type, index int, queued bool) *outputMetrics {
+	prefix := fmt.Sprintf("broker.outputs.%v.", index)
+	m := &outputMetrics{
+		queueDepth:  metrics.DudStat{},
+		inFlight:    stats.GetGauge(prefix + "in_flight"),
+		lastError:   stats.GetGauge(prefix + "last_error"),
+		timeBlocked: stats.GetGauge(prefix + "time_blocked"),
+	}
+	if queued {
+		m.queueDepth = stats.GetGauge(prefix + "queue_depth")
+	}
+	return m
+}
+
+// blockedSince adds the time elapsed since the broker began waiting on the
+// output to the total nanoseconds spent blocked.
+func (m *outputMetrics) blockedSince(start time.Time) {
+	m.timeBlocked.Incr(int64(time.Since(start)))
+}
+
+// sent records a transaction that was accepted by the output.
+func (m *outputMetrics) sent() {
+	m.inFlight.Incr(1)
+}
+
+// received records the response of a transaction, setting the timestamp of the
+// latest error when it has failed.
+func (m *outputMetrics) received(res types.Response) {
+	m.inFlight.Decr(1)
+	if res.Error() != nil {
+		m.lastError.Set(time.Now().Unix())
+	}
+}
+
+// forward waits for the response of a transaction sent to the output, records
+// it, and then forwards it to the origin of the transaction. Waiting stops when
+// done is closed, a nil done channel waits indefinitely.
+func (m *outputMetrics) forward(
+	resChan <-chan types.Response, ogResChan chan<- types.Response, done <-chan struct{},
+) {
+	select {
+	case res := <-resChan:
+		m.received(res)
+		select {
+		case ogResChan <- res:
+		case <-done:
+		}
+	case <-done:
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,267 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Priority is a broker that implements types.Producer, takes an array of inputs
+// ordered from highest to lowest priority and routes them through a single
+// message channel. Messages from an input are only read when all inputs of a
+// higher priority are idle, meaning they have no messages in flight and have
+// not produced a message within an idle timeout.
+type Priority struct {
+	stats metrics.Type
+
+	idleTimeout time.Duration
+
+	transactions chan types.Transaction
+
+	closables       []types.Closable
+	inputClosedChan chan int
+	incomingChan    chan priorityTransaction
+	doneChan        chan int
+	nInputs         int
+
+	closedChan chan struct{}
+}
+
+// priorityTransaction is a transaction read from an input, along with the
+// index of that input and the transaction to be sent downstream in its place.
+type priorityTransaction struct {
+	index   int
+	ts      types.Transaction
+	wrapped types.Transaction
+	resChan chan types.Response
+}
+
+// NewPriority creates a new Priority type by providing inputs in order of
+// priority, where the first input has the highest priority.
+func NewPriority(inputs []types.Producer, stats metrics.Type, options ...func(*Priority)) (*Priority, error) {
+	p := &Priority{
+		stats: stats,
+
+		idleTimeout: time.Second,
+
+		transactions: make(chan types.Transaction),
+
+		inputClosedChan: make(chan int),
+		incomingChan:    make(chan priorityTransaction),
+		doneChan:        make(chan int),
+		nInputs:         len(inputs),
+
+		closables:  []types.Closable{},
+		closedChan: make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(p)
+	}
+
+	for n, input := range inputs {
+		if closable, ok := input.(types.Closable); ok {
+			p.closables = append(p.closables, closable)
+		}
+
+		// Launch goroutine that async writes input into the broker loop
+		go func(index int) {
+			defer func() {
+				// If the input closes we need to signal to the broker
+				p.inputClosedChan <- index
+			}()
+			for {
+				in, open := <-inputs[index].TransactionChan()
+				if !open {
+					return
+				}
+				resChan := make(chan types.Response, 1)
+				p.incomingChan <- priorityTransaction{
+					index:   index,
+					ts:      in,
+					wrapped: types.NewTransaction(in.Payload, resChan),
+					resChan: resChan,
+				}
+			}
+		}(n)
+	}
+
+	go p.loop()
+	return p, nil
+}
+
+// OptPrioritySetIdleTimeout sets the period of time that an input must go
+// without producing a message, after its last message was acknowledged, before
+// inputs of a lower priority are read. The default is one second.
+func OptPrioritySetIdleTimeout(timeout time.Duration) func(*Priority) {
+	return func(p *Priority) {
+		p.idleTimeout = timeout
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// TransactionChan returns the channel used for consuming transactions from this
+// broker.
+func (p *Priority) TransactionChan() <-chan types.Transaction {
+	return p.transactions
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (p *Priority) Connected() bool {
+	type connector interface {
+		Connected() bool
+	}
+	for _, in := range p.closables {
+		if c, ok := in.(connector); ok {
+			if !c.Connected() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// forward waits for the response of a transaction that was sent downstream
+// and returns it to the input that the transaction was read from.
+func (p *Priority) forward(t priorityTransaction) {
+	var res types.Response
+	select {
+	case res = <-t.resChan:
+	case <-p.closedChan:
+		return
+	}
+	select {
+	case t.ts.ResponseChan <- res:
+	case <-p.closedChan:
+		return
+	}
+	select {
+	case p.doneChan <- t.index:
+	case <-p.closedChan:
+	}
+}
+
+// loop is an internal loop that brokers incoming messages from many inputs in
+// order of priority.
+func (p *Priority) loop() {
+	defer func() {
+		close(p.transactions)
+		close(p.closedChan)
+	}()
+
+	var (
+		mActive = p.stats.GetGauge("broker.priority.active")
+	)
+
+	pending := make([]*priorityTransaction, p.nInputs)
+	inFlight := make([]int, p.nInputs)
+	lastActive := make([]time.Time, p.nInputs)
+	closed := make([]bool, p.nInputs)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for nOpen := p.nInputs; nOpen > 0; {
+		// Find the highest priority pending transaction that is not blocked by
+		// a busy input of a higher priority.
+		pick, wait := -1, time.Duration(0)
+		for i := range pending {
+			if pending[i] != nil {
+				pick = i
+				break
+			}
+			if closed[i] {
+				continue
+			}
+			if inFlight[i] > 0 {
+				break
+			}
+			if since := time.Since(lastActive[i]); since < p.idleTimeout {
+				wait = p.idleTimeout - since
+				break
+			}
+		}
+
+		var outChan chan types.Transaction
+		var outTs types.Transaction
+		if pick >= 0 {
+			outChan, outTs = p.transactions, pending[pick].wrapped
+		}
+
+		var timerChan <-chan time.Time
+		if wait > 0 {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+			timerChan = timer.C
+		}
+
+		select {
+		case outChan <- outTs:
+			inFlight[pick]++
+			mActive.Set(int64(pick))
+			go p.forward(*pending[pick])
+			pending[pick] = nil
+		case t := <-p.incomingChan:
+			pending[t.index] = &t
+			lastActive[t.index] = time.Now()
+		case index := <-p.doneChan:
+			inFlight[index]--
+			lastActive[index] = time.Now()
+		case index := <-p.inputClosedChan:
+			closed[index] = true
+			pending[index] = nil
+			nOpen--
+		case <-timerChan:
+		}
+	}
+}
+
+// CloseAsync shuts down the Priority broker and stops processing requests.
+func (p *Priority) CloseAsync() {
+	for _, closable := range p.closables {
+		closable.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the Priority broker has closed down.
+func (p *Priority) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-p.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
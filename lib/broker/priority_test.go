@@ -0,0 +1,178 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestPriorityInterfaces(t *testing.T) {
+	p := &Priority{}
+	if types.Producer(p) == nil {
+		t.Errorf("Priority: nil types.Producer")
+	}
+	if types.Closable(p) == nil {
+		t.Errorf("Priority: nil types.Closable")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestPriorityDrainsHigherFirst(t *testing.T) {
+	high := &MockInputType{TChan: make(chan types.Transaction)}
+	low := &MockInputType{TChan: make(chan types.Transaction)}
+
+	p, err := NewPriority(
+		[]types.Producer{high, low}, metrics.DudType{},
+		OptPrioritySetIdleTimeout(time.Millisecond*200),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(in *MockInputType, content string) chan types.Response {
+		resChan := make(chan types.Response)
+		select {
+		case in.TChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for input send: %v", content)
+		}
+		return resChan
+	}
+	receive := func(exp string) {
+		var ts types.Transaction
+		select {
+		case ts = <-p.TransactionChan():
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for broker propagate: %v", exp)
+		}
+		if act := string(ts.Payload.Get(0).Get()); act != exp {
+			t.Errorf("Wrong content returned: %v != %v", act, exp)
+		}
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for response to broker: %v", exp)
+		}
+	}
+	ack := func(resChan chan types.Response) {
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for response to input")
+		}
+	}
+
+	lowRes := send(low, "low0")
+	highRes := send(high, "high0")
+	// Allow both messages to reach the broker loop before reading.
+	<-time.After(time.Millisecond * 50)
+	receive("high0")
+	ack(highRes)
+
+	// The high priority input is still active, so the low priority message
+	// must wait.
+	highRes = send(high, "high1")
+	receive("high1")
+	ack(highRes)
+
+	select {
+	case <-p.TransactionChan():
+		t.Fatal("Received low priority message whilst high priority input active")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// Once the high priority input is idle the low priority message is read.
+	receive("low0")
+	ack(lowRes)
+
+	p.CloseAsync()
+	if err := p.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPriorityClosedInput(t *testing.T) {
+	high := &MockInputType{TChan: make(chan types.Transaction)}
+	low := &MockInputType{TChan: make(chan types.Transaction)}
+
+	p, err := NewPriority(
+		[]types.Producer{high, low}, metrics.DudType{},
+		OptPrioritySetIdleTimeout(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resChan := make(chan types.Response)
+	select {
+	case high.TChan <- types.NewTransaction(message.New([][]byte{[]byte("high")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for input send")
+	}
+	select {
+	case ts := <-p.TransactionChan():
+		ts.ResponseChan <- response.NewAck()
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker propagate")
+	}
+	<-resChan
+
+	// A closed input no longer blocks inputs of a lower priority.
+	high.CloseAsync()
+
+	select {
+	case low.TChan <- types.NewTransaction(message.New([][]byte{[]byte("low")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for input send")
+	}
+	select {
+	case ts := <-p.TransactionChan():
+		if act := string(ts.Payload.Get(0).Get()); act != "low" {
+			t.Errorf("Wrong content returned: %v != low", act)
+		}
+		ts.ResponseChan <- response.NewAck()
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker propagate")
+	}
+	<-resChan
+
+	low.CloseAsync()
+	if err := p.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+	if _, open := <-p.TransactionChan(); open {
+		t.Error("Broker channel still open after closure")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -104,7 +104,11 @@ func (o *RoundRobin) loop() {
 
 	var (
 		mMsgsRcvd = o.stats.GetCounter("messages.received")
+		mOutputs  = make([]*outputMetrics, len(o.outputs))
 	)
+	for i := range o.outputs {
+		mOutputs[i] = newOutputMetrics(o.stats, i, false)
+	}
 
 	i := 0
 	var open bool
@@ -119,11 +123,16 @@ func (o *RoundRobin) loop() {
 			return
 		}
 		mMsgsRcvd.Incr(1)
+		start := time.Now()
+		resChan := make(chan types.Response, 1)
 		select {
-		case o.outputTsChans[i] <- ts:
+		case o.outputTsChans[i] <- types.NewTransaction(ts.Payload, resChan):
 		case <-o.closeChan:
 			return
 		}
+		mOutputs[i].blockedSince(start)
+		mOutputs[i].sent()
+		go mOutputs[i].forward(resChan, ts.ResponseChan, o.closeChan)
 
 		i++
 		if i >= len(o.outputTsChans) {
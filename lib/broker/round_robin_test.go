@@ -179,4 +179,26 @@ func BenchmarkBasicRoundRobin(b *testing.B) {
 	b.StopTimer()
 }
 
+func TestRoundRobinOutputMetrics(t *testing.T) {
+	first, second := &MockOutputType{}, &MockOutputType{}
+	readChan := make(chan types.Transaction)
+	stats := metrics.NewLocal()
+
+	oTM, err := NewRoundRobin([]types.Output{first, second}, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	checkOutputMetrics(t, stats, readChan, first, 0)
+	checkOutputMetrics(t, stats, readChan, second, 1)
+
+	oTM.CloseAsync()
+	if err = oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,232 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Shadow is a broker that implements types.Consumer and sends every message to
+// a primary output, and a sample of messages to any number of shadow outputs.
+// Only the primary output determines the response of a message, failures of
+// shadow outputs are ignored. Shadow outputs never apply back pressure, when a
+// shadow output is busy the sampled message is dropped for that output.
+type Shadow struct {
+	running int32
+
+	log   log.Modular
+	stats metrics.Type
+
+	sampleRate float64
+	rand       *rand.Rand
+
+	transactions <-chan types.Transaction
+
+	outputTsChans  []chan types.Transaction
+	outputResChans []chan types.Response
+	outputs        []types.Output
+	mOutputs       []*outputMetrics
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewShadow creates a new Shadow type by providing consumers, where the first
+// consumer is the primary and the remaining consumers are shadows.
+func NewShadow(
+	outputs []types.Output,
+	log log.Modular,
+	stats metrics.Type,
+	options ...func(*Shadow),
+) (*Shadow, error) {
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("a primary output is required")
+	}
+	o := &Shadow{
+		running:      1,
+		log:          log,
+		stats:        stats,
+		sampleRate:   1,
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		transactions: nil,
+		outputs:      outputs,
+		closedChan:   make(chan struct{}),
+		closeChan:    make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(o)
+	}
+	if o.sampleRate < 0 || o.sampleRate > 1 {
+		return nil, fmt.Errorf("sample rate must be between 0 and 1, received: %v", o.sampleRate)
+	}
+	o.outputTsChans = make([]chan types.Transaction, len(o.outputs))
+	o.outputResChans = make([]chan types.Response, len(o.outputs))
+	for i := range o.outputTsChans {
+		o.outputTsChans[i] = make(chan types.Transaction)
+		o.outputResChans[i] = make(chan types.Response)
+		o.mOutputs = append(o.mOutputs, newOutputMetrics(stats, i, false))
+		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OptShadowSetSampleRate sets the ratio, between 0 and 1, of messages that are
+// sent to shadow outputs.
+func OptShadowSetSampleRate(rate float64) func(*Shadow) {
+	return func(o *Shadow) {
+		o.sampleRate = rate
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new messages channel for the broker to read.
+func (o *Shadow) Consume(ts <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = ts
+
+	for i := 1; i < len(o.outputs); i++ {
+		go o.shadowResponses(i)
+	}
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether the primary output is
+// currently connected to its target.
+func (o *Shadow) Connected() bool {
+	return o.outputs[0].Connected()
+}
+
+//------------------------------------------------------------------------------
+
+// shadowResponses consumes the responses of a shadow output, logging any
+// failures.
+func (o *Shadow) shadowResponses(index int) {
+	var (
+		mSent   = o.stats.GetCounter(fmt.Sprintf("broker.outputs.%v.sent", index))
+		mFailed = o.stats.GetCounter(fmt.Sprintf("broker.outputs.%v.failed", index))
+	)
+	for {
+		select {
+		case res := <-o.outputResChans[index]:
+			o.mOutputs[index].received(res)
+			if err := res.Error(); err != nil {
+				mFailed.Incr(1)
+				o.log.Debugf("Shadow output %v failed to send message: %v\n", index, err)
+			} else {
+				mSent.Incr(1)
+			}
+		case <-o.closeChan:
+			return
+		}
+	}
+}
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (o *Shadow) loop() {
+	defer func() {
+		for _, c := range o.outputTsChans {
+			close(c)
+		}
+		close(o.closedChan)
+	}()
+
+	var (
+		mMsgsRcvd = o.stats.GetCounter("messages.received")
+		mSampled  = o.stats.GetCounter("broker.shadow.sampled")
+		mDropped  = []metrics.StatCounter{nil}
+	)
+	for i := 1; i < len(o.outputs); i++ {
+		mDropped = append(mDropped, o.stats.GetCounter(fmt.Sprintf("broker.outputs.%v.dropped", i)))
+	}
+
+	var open bool
+	for atomic.LoadInt32(&o.running) == 1 {
+		var ts types.Transaction
+		select {
+		case ts, open = <-o.transactions:
+			if !open {
+				return
+			}
+		case <-o.closeChan:
+			return
+		}
+		mMsgsRcvd.Incr(1)
+
+		if len(o.outputs) > 1 && o.rand.Float64() < o.sampleRate {
+			mSampled.Incr(1)
+			for i := 1; i < len(o.outputs); i++ {
+				select {
+				case o.outputTsChans[i] <- types.NewTransaction(ts.Payload.Copy(), o.outputResChans[i]):
+					o.mOutputs[i].sent()
+				default:
+					mDropped[i].Incr(1)
+				}
+			}
+		}
+
+		start := time.Now()
+		resChan := make(chan types.Response, 1)
+		select {
+		case o.outputTsChans[0] <- types.NewTransaction(ts.Payload, resChan):
+		case <-o.closeChan:
+			return
+		}
+		o.mOutputs[0].blockedSince(start)
+		o.mOutputs[0].sent()
+		go o.mOutputs[0].forward(resChan, ts.ResponseChan, o.closeChan)
+	}
+}
+
+// CloseAsync shuts down the Shadow broker and stops processing requests.
+func (o *Shadow) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&o.running, 1, 0) {
+		close(o.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Shadow broker has closed down.
+func (o *Shadow) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
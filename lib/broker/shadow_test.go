@@ -0,0 +1,170 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package broker
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestShadowInterfaces(t *testing.T) {
+	f := &Shadow{}
+	if types.Consumer(f) == nil {
+		t.Errorf("Shadow: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("Shadow: nil types.Closable")
+	}
+}
+
+func TestShadowBadConfig(t *testing.T) {
+	if _, err := NewShadow(nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing primary")
+	}
+	outputs := []types.Output{&MockOutputType{}}
+	if _, err := NewShadow(outputs, log.Noop(), metrics.Noop(), OptShadowSetSampleRate(1.5)); err == nil {
+		t.Error("Expected error from bad sample rate")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func testShadow(t *testing.T, nMsgs int, rate float64) (shadowRcvd int64, stats *metrics.Local) {
+	t.Helper()
+
+	primary, shadow := &MockOutputType{}, &MockOutputType{}
+
+	stats = metrics.NewLocal()
+	oTM, err := NewShadow(
+		[]types.Output{primary, shadow}, log.Noop(), stats,
+		OptShadowSetSampleRate(rate),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shadow fails every message it receives.
+	shadowDone := make(chan struct{})
+	go func() {
+		defer close(shadowDone)
+		for ts := range shadow.TChan {
+			atomic.AddInt64(&shadowRcvd, 1)
+			ts.ResponseChan <- response.NewError(errors.New("shadow failed"))
+		}
+	}()
+
+	for i := 0; i < nMsgs; i++ {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		var ts types.Transaction
+		select {
+		case ts = <-primary.TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for primary")
+		}
+		go func() {
+			ts.ResponseChan <- response.NewAck()
+		}()
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Errorf("Unexpected error: %v", res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+	}
+
+	close(readChan)
+	if err = oTM.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+	<-shadowDone
+	return atomic.LoadInt64(&shadowRcvd), stats
+}
+
+func TestShadowSampleAll(t *testing.T) {
+	nMsgs := 100
+	rcvd, stats := testShadow(t, nMsgs, 1)
+
+	counters := stats.GetCounters()
+	if exp, act := int64(nMsgs), counters["broker.shadow.sampled"]; exp != act {
+		t.Errorf("Wrong count of sampled messages: %v != %v", act, exp)
+	}
+	if rcvd == 0 {
+		t.Error("Expected shadow to receive messages")
+	}
+	if exp, act := int64(nMsgs), rcvd+counters["broker.outputs.1.dropped"]; exp != act {
+		t.Errorf("Wrong count of received and dropped messages: %v != %v", act, exp)
+	}
+}
+
+func TestShadowSampleNone(t *testing.T) {
+	rcvd, stats := testShadow(t, 100, 0)
+	if rcvd != 0 {
+		t.Errorf("Expected no shadow messages, received %v", rcvd)
+	}
+	if act := stats.GetCounters()["broker.shadow.sampled"]; act != 0 {
+		t.Errorf("Wrong count of sampled messages: %v", act)
+	}
+}
+
+func TestShadowOutputMetrics(t *testing.T) {
+	primary := &MockOutputType{}
+	readChan := make(chan types.Transaction)
+	stats := metrics.NewLocal()
+
+	oTM, err := NewShadow([]types.Output{primary}, log.Noop(), stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	checkOutputMetrics(t, stats, readChan, primary, 0)
+
+	oTM.CloseAsync()
+	if err = oTM.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
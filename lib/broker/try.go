@@ -27,6 +27,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/cenkalti/backoff"
 )
 
 //------------------------------------------------------------------------------
@@ -37,7 +38,9 @@ import (
 type Try struct {
 	running int32
 
-	stats metrics.Type
+	stats     metrics.Type
+	onFailure func(msg types.Message, attempts int, err error) types.Message
+	backoff   func() backoff.BackOff
 
 	transactions <-chan types.Transaction
 
@@ -49,7 +52,7 @@ type Try struct {
 }
 
 // NewTry creates a new Try type by providing consumers.
-func NewTry(outputs []types.Output, stats metrics.Type) (*Try, error) {
+func NewTry(outputs []types.Output, stats metrics.Type, options ...func(*Try)) (*Try, error) {
 	t := &Try{
 		running:      1,
 		stats:        stats,
@@ -58,6 +61,9 @@ func NewTry(outputs []types.Output, stats metrics.Type) (*Try, error) {
 		closedChan:   make(chan struct{}),
 		closeChan:    make(chan struct{}),
 	}
+	for _, opt := range options {
+		opt(t)
+	}
 	t.outputTsChans = make([]chan types.Transaction, len(t.outputs))
 	for i := range t.outputTsChans {
 		t.outputTsChans[i] = make(chan types.Transaction)
@@ -70,6 +76,28 @@ func NewTry(outputs []types.Output, stats metrics.Type) (*Try, error) {
 
 //------------------------------------------------------------------------------
 
+// OptTrySetOnFailure sets a function that is called whenever an output fails
+// to send a message and there are remaining outputs to attempt. The function
+// is given the message, the number of failed attempts so far and the error of
+// the latest attempt, and returns the message to send to the next output.
+func OptTrySetOnFailure(onFailure func(msg types.Message, attempts int, err error) types.Message) func(*Try) {
+	return func(t *Try) {
+		t.onFailure = onFailure
+	}
+}
+
+// OptTrySetBackoff sets a constructor for a backoff that determines how many
+// times, and how often, a failed message is retried against the same output
+// before failing over to the next. A new backoff is created for each output
+// attempted. When not set messages fail over immediately.
+func OptTrySetBackoff(ctor func() backoff.BackOff) func(*Try) {
+	return func(t *Try) {
+		t.backoff = ctor
+	}
+}
+
+//------------------------------------------------------------------------------
+
 // Consume assigns a new messages channel for the broker to read.
 func (t *Try) Consume(ts <-chan types.Transaction) error {
 	if t.transactions != nil {
@@ -104,11 +132,17 @@ func (t *Try) loop() {
 	}()
 
 	var (
-		mMsgsRcvd = t.stats.GetCounter("messages.received")
-		mErrs     = []metrics.StatCounter{}
+		mMsgsRcvd  = t.stats.GetCounter("messages.received")
+		mErrs      = []metrics.StatCounter{}
+		mRetries   = []metrics.StatCounter{}
+		mFailovers = []metrics.StatCounter{}
+		mOutputs   = []*outputMetrics{}
 	)
 	for i := range t.outputs {
+		mOutputs = append(mOutputs, newOutputMetrics(t.stats, i, false))
 		mErrs = append(mErrs, t.stats.GetCounter(fmt.Sprintf("broker.outputs.%v.failed", i)))
+		mRetries = append(mRetries, t.stats.GetCounter(fmt.Sprintf("broker.outputs.%v.retry", i)))
+		mFailovers = append(mFailovers, t.stats.GetCounter(fmt.Sprintf("broker.outputs.%v.failover", i)))
 	}
 
 	var open bool
@@ -126,25 +160,55 @@ func (t *Try) loop() {
 		}
 		mMsgsRcvd.Incr(1)
 
+		payload := ts.Payload
+
 	triesLoop:
 		for i, ot := range t.outputTsChans {
-			select {
-			case ot <- types.NewTransaction(ts.Payload, resChan):
-			case <-t.closeChan:
-				return
+			if i > 0 {
+				mFailovers[i-1].Incr(1)
+				if t.onFailure != nil {
+					payload = t.onFailure(ts.Payload, i, res.Error())
+				}
+			}
+			var boff backoff.BackOff
+			if t.backoff != nil {
+				boff = t.backoff()
 			}
-			select {
-			case res, open = <-resChan:
-				if !open {
+			for {
+				start := time.Now()
+				select {
+				case ot <- types.NewTransaction(payload, resChan):
+				case <-t.closeChan:
+					return
+				}
+				mOutputs[i].blockedSince(start)
+				mOutputs[i].sent()
+				select {
+				case res, open = <-resChan:
+					if !open {
+						return
+					}
+				case <-t.closeChan:
 					return
 				}
-				if res.Error() != nil {
-					mErrs[i].Incr(1)
-				} else {
+				mOutputs[i].received(res)
+				if res.Error() == nil {
 					break triesLoop
 				}
-			case <-t.closeChan:
-				return
+				mErrs[i].Incr(1)
+				if boff == nil {
+					break
+				}
+				wait := boff.NextBackOff()
+				if wait == backoff.Stop {
+					break
+				}
+				mRetries[i].Incr(1)
+				select {
+				case <-time.After(wait):
+				case <-t.closeChan:
+					return
+				}
 			}
 		}
 		select {
@@ -23,6 +23,7 @@ package broker
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -30,6 +31,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/cenkalti/backoff"
 )
 
 //------------------------------------------------------------------------------
@@ -315,4 +317,169 @@ func TestTryAllFail(t *testing.T) {
 	}
 }
 
+func TestTryOnFailure(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	type failure struct {
+		attempts int
+		err      string
+	}
+	var failures []failure
+
+	oTM, err := NewTry(outputs, metrics.DudType{}, OptTrySetOnFailure(
+		func(msg types.Message, attempts int, err error) types.Message {
+			failures = append(failures, failure{attempts, err.Error()})
+			newMsg := msg.Copy()
+			newMsg.Get(0).Set([]byte(fmt.Sprintf("%s attempt %v", msg.Get(0).Get(), attempts)))
+			return newMsg
+		},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker send")
+	}
+
+	expContents := []string{"foo", "foo attempt 1", "foo attempt 2"}
+	for i, exp := range expContents {
+		var ts types.Transaction
+		select {
+		case ts = <-mockOutputs[i].TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+		if act := string(ts.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong content for output %v: %v != %v", i, act, exp)
+		}
+		var res types.Response = response.NewAck()
+		if i < 2 {
+			res = response.NewError(fmt.Errorf("err %v", i))
+		}
+		select {
+		case ts.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Error(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to broker")
+	}
+
+	expFailures := []failure{{1, "err 0"}, {2, "err 1"}}
+	if !reflect.DeepEqual(expFailures, failures) {
+		t.Errorf("Wrong failures: %v != %v", failures, expFailures)
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTryBackoff(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	stats := metrics.NewLocal()
+	oTM, err := NewTry(outputs, stats, OptTrySetBackoff(func() backoff.BackOff {
+		return backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), 2)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each test case lists the output that receives each attempt and whether
+	// the attempt fails.
+	type attempt struct {
+		output int
+		fail   bool
+	}
+	tests := [][]attempt{
+		{{0, true}, {0, true}, {0, false}},
+		{{0, true}, {0, true}, {0, true}, {1, false}},
+	}
+
+	for _, attempts := range tests {
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		for _, a := range attempts {
+			var ts types.Transaction
+			select {
+			case ts = <-mockOutputs[a.output].TChan:
+			case ts = <-mockOutputs[1-a.output].TChan:
+				t.Fatalf("Attempt sent to wrong output, expected %v", a.output)
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for broker propagate")
+			}
+			var res types.Response = response.NewAck()
+			if a.fail {
+				res = response.NewError(errors.New("nope"))
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out responding to broker")
+			}
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Error(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+
+	counters := stats.GetCounters()
+	for k, v := range map[string]int64{
+		"broker.outputs.0.failed":   5,
+		"broker.outputs.0.retry":    4,
+		"broker.outputs.0.failover": 1,
+		"broker.outputs.1.failed":   0,
+		"broker.outputs.1.failover": 0,
+	} {
+		if act := counters[k]; act != v {
+			t.Errorf("Wrong count for %v: %v != %v", k, act, v)
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -21,6 +21,7 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
@@ -52,6 +53,9 @@ value is stored as a binary value using the ` + "`data_key`" + ` field name.
 A prefix can be specified to allow multiple cache types to share a single
 DynamoDB table. An optional TTL duration (` + "`ttl`" + `) and field
 (` + "`ttl_key`" + `) can be specified if the backing table has TTL enabled.
+The ` + "`ttl_key`" + ` field is required in order to write TTLs. A
+` + "`ttl`" + ` set without one is ignored with a warning, and a custom TTL set
+by a ` + "`cache`" + ` output against a cache without one results in an error.
 
 Strong read consistency can be enabled using the ` + "`consistent_read`" + `
 configuration field.`,
@@ -99,6 +103,10 @@ func NewDynamoDBConfig() DynamoDBConfig {
 
 //------------------------------------------------------------------------------
 
+// errDynamoDBNoTTLKey is returned when a TTL is used with a DynamoDB cache that
+// has no field to write it to.
+var errDynamoDBNoTTLKey = errors.New("a ttl_key must be set in order to write TTLs")
+
 // DynamoDB is a DynamoDB based cache implementation.
 type DynamoDB struct {
 	client      dynamodbiface.DynamoDBAPI
@@ -180,12 +188,8 @@ func NewDynamoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.
 		mDelLatency:      stats.GetTimer("delete.latency"),
 	}
 
-	if d.conf.TTL != "" {
-		ttl, err := time.ParseDuration(d.conf.TTL)
-		if err != nil {
-			return nil, err
-		}
-		d.ttl = ttl
+	if err := d.parseTTL(); err != nil {
+		return nil, err
 	}
 
 	sess, err := d.conf.GetSession()
@@ -215,6 +219,24 @@ func NewDynamoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.
 	return &d, nil
 }
 
+// parseTTL parses the default TTL of the cache. Without a ttl_key there is no
+// field to write it to, and so it is ignored with a warning.
+func (d *DynamoDB) parseTTL() error {
+	if d.conf.TTL == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(d.conf.TTL)
+	if err != nil {
+		return err
+	}
+	if d.conf.TTLKey == "" {
+		d.log.Warnf("Ignoring ttl '%v' as no ttl_key is set\n", d.conf.TTL)
+		return nil
+	}
+	d.ttl = ttl
+	return nil
+}
+
 //------------------------------------------------------------------------------
 
 // Get attempts to locate and return a cached value by its key, returns an error
@@ -275,12 +297,22 @@ func (d *DynamoDB) get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (d *DynamoDB) Set(key string, value []byte) error {
+	return d.SetWithTTL(key, value, d.ttl)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL that overrides the
+// default TTL of the cache. An error is returned when a TTL is provided and the
+// cache has no ttl_key.
+func (d *DynamoDB) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	if ttl != 0 && d.conf.TTLKey == "" {
+		return errDynamoDBNoTTLKey
+	}
 	d.mSetCount.Incr(1)
 
 	tStarted := time.Now()
 	boff := d.boffPool.Get().(backoff.BackOff)
 
-	_, err := d.client.PutItem(d.putItemInput(key, value))
+	_, err := d.client.PutItem(d.putItemInput(key, value, ttl))
 	for err != nil {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -288,7 +320,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 		}
 		time.Sleep(wait)
 		d.mSetRetry.Incr(1)
-		_, err = d.client.PutItem(d.putItemInput(key, value))
+		_, err = d.client.PutItem(d.putItemInput(key, value, ttl))
 	}
 	if err == nil {
 		d.mSetSuccess.Incr(1)
@@ -317,7 +349,7 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 	for k, v := range items {
 		writeReqs = append(writeReqs, &dynamodb.WriteRequest{
 			PutRequest: &dynamodb.PutRequest{
-				Item: d.putItemInput(k, v).Item,
+				Item: d.putItemInput(k, v, d.ttl).Item,
 			},
 		})
 	}
@@ -400,7 +432,7 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 }
 
 func (d *DynamoDB) add(key string, value []byte) error {
-	input := d.putItemInput(key, value)
+	input := d.putItemInput(key, value, d.ttl)
 
 	expr, err := expression.NewBuilder().
 		WithCondition(expression.AttributeNotExists(expression.Name(d.conf.HashKey))).
@@ -468,7 +500,7 @@ func (d *DynamoDB) delete(key string) error {
 }
 
 // putItemInput creates a generic put item input for use in Set and Add operations
-func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput {
+func (d *DynamoDB) putItemInput(key string, value []byte, ttl time.Duration) *dynamodb.PutItemInput {
 	input := dynamodb.PutItemInput{
 		Item: map[string]*dynamodb.AttributeValue{
 			d.conf.HashKey: {
@@ -481,9 +513,9 @@ func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput
 		TableName: d.table,
 	}
 
-	if d.ttl != 0 && d.conf.TTLKey != "" {
+	if ttl != 0 && d.conf.TTLKey != "" {
 		input.Item[d.conf.TTLKey] = &dynamodb.AttributeValue{
-			N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10)),
+			N: aws.String(strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)),
 		}
 	}
 
@@ -1,9 +1,12 @@
 package cache
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -16,6 +19,32 @@ import (
 	"github.com/ory/dockertest"
 )
 
+func TestDynamoDBTTLWithoutKey(t *testing.T) {
+	conf := NewConfig()
+	conf.DynamoDB.Table = "foo"
+	conf.DynamoDB.TTL = "60s"
+
+	logBuf := &bytes.Buffer{}
+	d := &DynamoDB{
+		conf: conf.DynamoDB,
+		log:  log.New(logBuf, log.Config{LogLevel: "WARN"}),
+	}
+	if err := d.parseTTL(); err != nil {
+		t.Fatal(err)
+	}
+	if d.ttl != 0 {
+		t.Errorf("Expected ttl to be ignored: %v", d.ttl)
+	}
+	if !strings.Contains(logBuf.String(), "no ttl_key") {
+		t.Errorf("Expected warning to be logged: %s", logBuf.String())
+	}
+
+	d = &DynamoDB{conf: NewDynamoDBConfig()}
+	if err := d.SetWithTTL("foo", []byte("bar"), time.Minute); err != errDynamoDBNoTTLKey {
+		t.Errorf("Wrong error returned: %v != %v", err, errDynamoDBNoTTLKey)
+	}
+}
+
 func TestDynamoDBIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
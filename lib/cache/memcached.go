@@ -155,12 +155,16 @@ func NewMemcached(
 
 //------------------------------------------------------------------------------
 
+// memcachedMaxRelativeTTL is the largest expiration in seconds that memcached
+// treats as relative to the current time.
+const memcachedMaxRelativeTTL = 60 * 60 * 24 * 30
+
 // getItemFor returns a memcache.Item object ready to be stored in memcache
-func (m *Memcached) getItemFor(key string, value []byte) *memcache.Item {
+func (m *Memcached) getItemFor(key string, value []byte, ttl int32) *memcache.Item {
 	return &memcache.Item{
 		Key:        m.conf.Memcached.Prefix + key,
 		Value:      value,
-		Expiration: m.conf.Memcached.TTL,
+		Expiration: ttl,
 	}
 }
 
@@ -193,15 +197,31 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (m *Memcached) Set(key string, value []byte) error {
+	return m.set(key, value, m.conf.Memcached.TTL)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL that overrides the
+// default TTL of the cache. The TTL is rounded up to the nearest second.
+func (m *Memcached) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	secs := int64((ttl + time.Second - 1) / time.Second)
+	if secs > memcachedMaxRelativeTTL {
+		// Memcached interprets expirations larger than 30 days as absolute
+		// unix timestamps.
+		secs = time.Now().Add(ttl).Unix()
+	}
+	return m.set(key, value, int32(secs))
+}
+
+func (m *Memcached) set(key string, value []byte, ttl int32) error {
 	m.mSetCount.Incr(1)
 	tStarted := time.Now()
 
-	err := m.mc.Set(m.getItemFor(key, value))
+	err := m.mc.Set(m.getItemFor(key, value, ttl))
 	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
 		m.log.Errorf("Set command failed: %v\n", err)
 		<-time.After(m.retryPeriod)
 		m.mSetRetry.Incr(1)
-		err = m.mc.Set(m.getItemFor(key, value))
+		err = m.mc.Set(m.getItemFor(key, value, ttl))
 	}
 	if err != nil {
 		m.mSetFailed.Incr(1)
@@ -234,7 +254,7 @@ func (m *Memcached) Add(key string, value []byte) error {
 	m.mAddCount.Incr(1)
 	tStarted := time.Now()
 
-	err := m.mc.Add(m.getItemFor(key, value))
+	err := m.mc.Add(m.getItemFor(key, value, m.conf.Memcached.TTL))
 	if memcache.ErrNotStored == err {
 		m.mAddFailedDupe.Incr(1)
 
@@ -248,7 +268,7 @@ func (m *Memcached) Add(key string, value []byte) error {
 		m.log.Errorf("Add command failed: %v\n", err)
 		<-time.After(m.retryPeriod)
 		m.mAddRetry.Incr(1)
-		if err := m.mc.Add(m.getItemFor(key, value)); memcache.ErrNotStored == err {
+		if err := m.mc.Add(m.getItemFor(key, value, m.conf.Memcached.TTL)); memcache.ErrNotStored == err {
 			m.mAddFailedDupe.Incr(1)
 
 			latency := int64(time.Since(tStarted))
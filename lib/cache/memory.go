@@ -68,6 +68,7 @@ func NewMemoryConfig() MemoryConfig {
 type item struct {
 	value []byte
 	ts    time.Time
+	ttl   time.Duration
 }
 
 // Memory is a memory based cache implementation.
@@ -103,7 +104,11 @@ func (m *Memory) compaction() {
 		return
 	}
 	for k, v := range m.items {
-		if time.Since(v.ts) >= m.ttl {
+		ttl := m.ttl
+		if v.ttl > 0 {
+			ttl = v.ttl
+		}
+		if time.Since(v.ts) >= ttl {
 			delete(m.items, k)
 		}
 	}
@@ -130,6 +135,16 @@ func (m *Memory) Set(key string, value []byte) error {
 	return nil
 }
 
+// SetWithTTL attempts to set the value of a key with a TTL that overrides the
+// default TTL of the cache.
+func (m *Memory) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	m.Lock()
+	m.compaction()
+	m.items[key] = item{value: value, ts: time.Now(), ttl: ttl}
+	m.Unlock()
+	return nil
+}
+
 // SetMulti attempts to set the value of multiple keys, returns an error if any
 // keys fail.
 func (m *Memory) SetMulti(items map[string][]byte) error {
@@ -23,6 +23,7 @@ package cache
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -141,4 +142,49 @@ func TestMemoryCacheCompaction(t *testing.T) {
 	}
 }
 
+func TestMemoryCacheCompactionTTL(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.TTL = 300
+	conf.Memory.CompactionInterval = ""
+
+	c, err := New(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ttlCache, ok := c.(types.CacheWithTTL)
+	if !ok {
+		t.Fatal("Memory cache does not support custom TTLs")
+	}
+
+	if err = c.Set("foo", []byte("1")); err != nil {
+		t.Error(err)
+	}
+	if err = ttlCache.SetWithTTL("bar", []byte("2"), time.Nanosecond); err != nil {
+		t.Error(err)
+	}
+	<-time.After(time.Millisecond)
+
+	// This should trigger compaction.
+	if err = c.Set("baz", []byte("3")); err != nil {
+		t.Error(err)
+	}
+
+	// This key should have been removed from compaction.
+	expErr := types.ErrKeyNotFound
+	if _, act := c.Get("bar"); act != expErr {
+		t.Errorf("Wrong error returned: %v != %v", act, expErr)
+	}
+
+	exp := "1"
+	if act, err := c.Get("foo"); err != nil {
+		t.Error(err)
+	} else if string(act) != exp {
+		t.Errorf("Wrong result: %v != %v", string(act), exp)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -219,17 +219,23 @@ func (r *Redis) Get(key string) ([]byte, error) {
 
 // Set attempts to set the value of a key.
 func (r *Redis) Set(key string, value []byte) error {
+	return r.SetWithTTL(key, value, r.ttl)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL that overrides the
+// default expiration of the cache.
+func (r *Redis) SetWithTTL(key string, value []byte, ttl time.Duration) error {
 	r.mSetCount.Incr(1)
 	tStarted := time.Now()
 
 	key = r.prefix + key
 
-	err := r.client.Set(key, value, r.ttl).Err()
+	err := r.client.Set(key, value, ttl).Err()
 	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
 		r.log.Errorf("Set command failed: %v\n", err)
 		<-time.After(r.retryPeriod)
 		r.mSetRetry.Incr(1)
-		err = r.client.Set(key, value, r.ttl).Err()
+		err = r.client.Set(key, value, ttl).Err()
 	}
 	if err != nil {
 		r.mSetFailed.Incr(1)
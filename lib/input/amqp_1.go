@@ -0,0 +1,95 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAMQP1] = TypeSpec{
+		constructor: NewAMQP1,
+		description: `
+Reads messages from an AMQP (1.0) server such as Azure Service Bus, ActiveMQ or
+Apache Qpid by attaching a receiver link to the node at ` + "`source_address`" + `.
+
+Credentials are taken from the user info of the ` + "`url`" + ` and are sent
+with SASL PLAIN, special characters (common within Azure shared access keys)
+must therefore be URL encoded. Without credentials SASL ANONYMOUS is used. TLS is automatic when connecting to an
+` + "`amqps`" + ` URL, but custom settings can be enabled in the ` + "`tls`" + `
+section.
+
+The field ` + "`link_credit`" + ` sets the maximum number of messages that the
+server may deliver ahead of them being consumed.
+
+The ` + "`settle_mode`" + ` field determines the delivery guarantees of the
+input. When ` + "`unsettled`" + ` (the default) messages are accepted only once
+they have been successfully propagated to the output, and messages that fail
+are modified as failed so that the server redelivers them. When
+` + "`settled`" + ` the server settles messages as it sends them, which is
+faster but means messages in flight are lost if Benthos crashes.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- amqp_message_id
+- amqp_correlation_id
+- amqp_content_type
+- amqp_content_encoding
+- amqp_subject
+- amqp_to
+- amqp_reply_to
+- amqp_group_id
+- amqp_creation_time_unix
+- amqp_delivery_count
+- All message annotations
+- All application properties
+` + "```" + `
+
+Hyphens within the names of annotations and application properties are
+replaced with underscores. You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAMQP1 creates a new AMQP1 input type.
+func NewAMQP1(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	a, err := reader.NewAMQP1(conf.AMQP1, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	if conf.AMQP1.SettleMode == "settled" {
+		// Settled messages cannot be redelivered by the server.
+		return NewReader("amqp_1", reader.NewPreserver(a), log, stats)
+	}
+	return NewReader("amqp_1", a, log, stats)
+}
+
+//------------------------------------------------------------------------------
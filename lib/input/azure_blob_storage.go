@@ -0,0 +1,96 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAzureBlobStorage] = TypeSpec{
+		constructor: NewAzureBlobStorage,
+		description: `
+Downloads blobs within an Azure Blob Storage container, optionally filtered by a
+prefix. The blobs found when this input connects are downloaded in order, after
+which the input closes.
+
+The field ` + "`decompress`" + ` can be set to ` + "`gzip`" + ` in order to
+decompress all blobs, or ` + "`auto`" + ` in order to only decompress blobs that
+have a ` + "`.gz`" + ` suffix. The decompressed contents of a blob are then
+decoded according to ` + "`codec`" + `, which can be one of the following:
+
+- ` + "`all-bytes`" + `: The entire blob becomes a single message.
+- ` + "`lines`" + `: Each line (separated by ` + "`delimiter`" + `, which
+  defaults to a newline) becomes a part of a message batch.
+- ` + "`tar`" + `: Each file of a tar archive becomes a part of a message batch.
+
+Batches can be broken into individual messages with the ` + "`split`" + `
+processor.
+
+### Checkpointing
+
+Once a blob has been sent onwards successfully its name and last modified time
+are recorded within a checkpoint blob, which is stored in
+` + "`checkpoint_container`" + ` under the name ` + "`checkpoint_blob`" + `
+(defaulting to ` + "`azure_blob_storage/<container>/<prefix>/checkpoint.json`" + `).
+When the input is restarted any blobs recorded in the checkpoint are skipped
+unless they have been modified since. Checkpointing can be disabled by setting
+` + "`checkpoint_container`" + ` to an empty string.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- azure_blob_name
+- azure_blob_container
+- azure_blob_size
+- azure_blob_last_modified (RFC3339)
+- azure_blob_last_modified_unix
+- azure_blob_tar_entry (when using the tar codec)
+- All user defined metadata
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).
+
+` + storage.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAzureBlobStorage creates a new Azure Blob Storage input type.
+func NewAzureBlobStorage(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	r, err := reader.NewAzureBlobStorage(conf.AzureBlobStorage, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("azure_blob_storage", reader.NewPreserver(r), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAzureEventHubs] = TypeSpec{
+		constructor: NewAzureEventHubs,
+		description: `
+Consumes the partitions of an Azure Event Hub. Connections authenticate with
+the ` + "`connection_string`" + ` of a shared access policy when set, and
+otherwise with the default Azure credential chain against the fully qualified
+` + "`namespace`" + `, e.g. ` + "`foo.servicebus.windows.net`" + `.
+
+Partitions are divided between all Benthos instances consuming the same event
+hub and ` + "`consumer_group`" + `. Instances record ownership of partitions
+within the ` + "`checkpoint_store`" + ` container, and every
+` + "`balance_period`" + ` they renew ownership and claim partitions in order to
+spread them evenly. Partitions of instances that stop are claimed by the
+remaining instances once their ownership expires after
+` + "`lease_duration`" + `.
+
+The offsets of acknowledged messages are checkpointed to the container at most
+once every ` + "`checkpoint_period`" + `, and consumption of a partition resumes
+from its last checkpoint when it is claimed. When no checkpoint exists
+consumption starts from either the oldest or newest message depending on
+` + "`start_from_oldest`" + `.
+
+The field ` + "`instance_id`" + ` identifies this instance as the owner of
+partitions and is randomly generated when left empty.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- azure_event_hubs_key
+- azure_event_hubs_partition
+- azure_event_hubs_offset
+- azure_event_hubs_sequence_number
+- azure_event_hubs_timestamp_unix
+- All event properties
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAzureEventHubs creates a new AzureEventHubs input type.
+func NewAzureEventHubs(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	a, err := reader.NewAzureEventHubs(conf.AzureEventHubs, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("azure_event_hubs", reader.NewPreserver(a), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,91 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAzureServiceBus] = TypeSpec{
+		constructor: NewAzureServiceBus,
+		description: `
+Receives messages from an Azure Service Bus queue or topic subscription using
+peek-lock semantics. Either a ` + "`queue`" + ` or both a ` + "`topic`" + ` and
+` + "`subscription`" + ` must be specified.
+
+The client authenticates with the ` + "`connection_string`" + ` of a shared
+access policy when set. Otherwise the fully qualified ` + "`namespace`" + `
+(e.g. ` + "`benthos.servicebus.windows.net`" + `) must be set, and credentials
+are obtained from the default Azure credential chain, which includes
+environment variables and managed identities.
+
+Messages remain locked until they have been propagated, at which point they are
+completed. The lock of a message is renewed every ` + "`lock_renewal_period`" + `
+until it is either completed or abandoned, this period should be shorter than
+the lock duration of the entity. Set the period to an empty string in order to
+disable lock renewal.
+
+Messages that fail to be propagated are abandoned, which makes them available
+for redelivery and increments their delivery count. Once the delivery count of
+a message exceeds the max delivery count of the entity it is moved to the dead
+letter queue by Service Bus.
+
+The field ` + "`timeout`" + ` specifies how long each receive request waits for
+a message before trying again.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- azure_service_bus_message_id
+- azure_service_bus_sequence_number
+- azure_service_bus_delivery_count
+- azure_service_bus_enqueued_time
+- azure_service_bus_correlation_id
+- azure_service_bus_session_id
+- azure_service_bus_label
+- azure_service_bus_content_type
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAzureServiceBus creates a new AzureServiceBus input type.
+func NewAzureServiceBus(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	a, err := reader.NewAzureServiceBus(conf.AzureServiceBus, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("azure_service_bus", a, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -84,6 +85,48 @@ If the number of copies is greater than zero the list will be copied that number
 of times. For example, if your inputs were of type foo and bar, with 'copies'
 set to '2', you would end up with two 'foo' inputs and two 'bar' inputs.
 
+### Patterns
+
+The broker pattern determines the way in which messages are read from the
+inputs and can be chosen from the following:
+
+#### ` + "`fan_in`" + `
+
+With the fan in pattern, which is the default, all inputs are read in parallel
+and their messages are merged into a single stream as soon as they arrive.
+
+#### ` + "`priority`" + `
+
+With the priority pattern the inputs are ordered from the highest priority to
+the lowest, where the first input has the highest priority. Messages from an
+input are only read when all inputs of a higher priority are idle, meaning that
+they have no messages awaiting acknowledgement and have not produced a message
+for at least ` + "`priority.idle_timeout`" + `. This allows a higher priority
+input to starve the inputs below it for as long as it has data.
+
+For example, a live Kafka feed can take precedence over an S3 backfill, where
+the backfill is only read during lulls in the live feed:
+
+` + "``` yaml" + `
+type: broker
+broker:
+  pattern: priority
+  priority:
+    idle_timeout: 1s
+  inputs:
+  - type: kafka
+    kafka:
+      addresses:
+      - localhost:9092
+      topic: live_stream
+  - type: s3
+    s3:
+      bucket: backfill
+` + "```" + `
+
+The gauge ` + "`broker.priority.active`" + ` exposes the index of the input
+that the latest message was read from.
+
 ### Processors
 
 It is possible to configure [processors](../processors/README.md) at the broker
@@ -102,8 +145,10 @@ nodes processors.`,
 				inSlice = append(inSlice, sanInput)
 			}
 			return map[string]interface{}{
-				"copies": conf.Broker.Copies,
-				"inputs": inSlice,
+				"copies":   conf.Broker.Copies,
+				"pattern":  conf.Broker.Pattern,
+				"priority": conf.Broker.Priority,
+				"inputs":   inSlice,
 			}, nil
 		},
 	}
@@ -111,16 +156,28 @@ nodes processors.`,
 
 //------------------------------------------------------------------------------
 
+// BrokerPriorityConfig contains configuration fields for the priority pattern
+// of the Broker input type.
+type BrokerPriorityConfig struct {
+	IdleTimeout string `json:"idle_timeout" yaml:"idle_timeout"`
+}
+
 // BrokerConfig contains configuration fields for the Broker input type.
 type BrokerConfig struct {
-	Copies int             `json:"copies" yaml:"copies"`
-	Inputs brokerInputList `json:"inputs" yaml:"inputs"`
+	Copies   int                  `json:"copies" yaml:"copies"`
+	Pattern  string               `json:"pattern" yaml:"pattern"`
+	Priority BrokerPriorityConfig `json:"priority" yaml:"priority"`
+	Inputs   brokerInputList      `json:"inputs" yaml:"inputs"`
 }
 
 // NewBrokerConfig creates a new BrokerConfig with default values.
 func NewBrokerConfig() BrokerConfig {
 	return BrokerConfig{
-		Copies: 1,
+		Copies:  1,
+		Pattern: "fan_in",
+		Priority: BrokerPriorityConfig{
+			IdleTimeout: "1s",
+		},
 		Inputs: brokerInputList{},
 	}
 }
@@ -286,7 +343,18 @@ func NewBroker(
 		}
 	}
 
-	return broker.NewFanIn(inputs, stats)
+	switch conf.Broker.Pattern {
+	case "fan_in":
+		return broker.NewFanIn(inputs, stats)
+	case "priority":
+		idleTimeout, err := time.ParseDuration(conf.Broker.Priority.IdleTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse priority idle timeout: %v", err)
+		}
+		return broker.NewPriority(inputs, stats, broker.OptPrioritySetIdleTimeout(idleTimeout))
+	}
+
+	return nil, fmt.Errorf("broker pattern was not recognised: %v", conf.Broker.Pattern)
 }
 
 //------------------------------------------------------------------------------
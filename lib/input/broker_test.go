@@ -23,6 +23,12 @@ package input
 import (
 	"encoding/json"
 	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/broker"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestBrokerConfigDefaults(t *testing.T) {
@@ -56,6 +62,10 @@ func TestBrokerConfigDefaults(t *testing.T) {
 
 	inputConfs := conf.Broker.Inputs
 
+	if exp, actual := "fan_in", conf.Broker.Pattern; exp != actual {
+		t.Errorf("Unexpected value from config: %v != %v", exp, actual)
+	}
+
 	if exp, actual := 2, len(inputConfs); exp != actual {
 		t.Errorf("unexpected number of input configs: %v != %v", exp, actual)
 		return
@@ -90,6 +100,40 @@ func TestBrokerConfigDefaults(t *testing.T) {
 	}
 }
 
+func TestBrokerPatterns(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	for i := 0; i < 2; i++ {
+		iConf := NewConfig()
+		iConf.Type = TypeHTTPServer
+		conf.Broker.Inputs = append(conf.Broker.Inputs, iConf)
+	}
+
+	conf.Broker.Pattern = "priority"
+	in, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := in.(*broker.Priority); !ok {
+		t.Errorf("Unexpected broker type: %T", in)
+	}
+	in.CloseAsync()
+	if err = in.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+
+	conf.Broker.Pattern = "nope"
+	if _, err = NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unrecognised pattern")
+	}
+
+	conf.Broker.Pattern = "priority"
+	conf.Broker.Priority.IdleTimeout = "nope"
+	if _, err = NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad idle timeout")
+	}
+}
+
 func TestBrokerConfigDitto(t *testing.T) {
 	testConf := []byte(`{
 		"type": "broker",
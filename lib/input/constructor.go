@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/pipeline"
 	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/processor/condition"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/config"
 	yaml "gopkg.in/yaml.v2"
@@ -61,104 +62,184 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each input type.
 const (
-	TypeAMQP          = "amqp"
-	TypeBroker        = "broker"
-	TypeDynamic       = "dynamic"
-	TypeFile          = "file"
-	TypeFiles         = "files"
-	TypeGCPPubSub     = "gcp_pubsub"
-	TypeHDFS          = "hdfs"
-	TypeHTTPClient    = "http_client"
-	TypeHTTPServer    = "http_server"
-	TypeInproc        = "inproc"
-	TypeKafka         = "kafka"
-	TypeKafkaBalanced = "kafka_balanced"
-	TypeKinesis       = "kinesis"
-	TypeMQTT          = "mqtt"
-	TypeNanomsg       = "nanomsg"
-	TypeNATS          = "nats"
-	TypeNATSStream    = "nats_stream"
-	TypeNSQ           = "nsq"
-	TypeReadUntil     = "read_until"
-	TypeRedisList     = "redis_list"
-	TypeRedisPubSub   = "redis_pubsub"
-	TypeRedisStreams  = "redis_streams"
-	TypeS3            = "s3"
-	TypeSQS           = "sqs"
-	TypeSTDIN         = "stdin"
-	TypeWebsocket     = "websocket"
-	TypeZMQ4          = "zmq4"
+	TypeAMQP             = "amqp"
+	TypeAMQP1            = "amqp_1"
+	TypeAzureBlobStorage = "azure_blob_storage"
+	TypeAzureEventHubs   = "azure_event_hubs"
+	TypeAzureServiceBus  = "azure_service_bus"
+	TypeBroker           = "broker"
+	TypeCron             = "cron"
+	TypeCSV              = "csv"
+	TypeDockerLogs       = "docker_logs"
+	TypeDynamic          = "dynamic"
+	TypeFile             = "file"
+	TypeFiles            = "files"
+	TypeFTP              = "ftp"
+	TypeGCPCloudStorage  = "gcp_cloud_storage"
+	TypeGenerate         = "generate"
+	TypeGCPPubSub        = "gcp_pubsub"
+	TypeHDFS             = "hdfs"
+	TypeHTTPClient       = "http_client"
+	TypeHTTPServer       = "http_server"
+	TypeIMAP             = "imap"
+	TypeInproc           = "inproc"
+	TypeJournald         = "journald"
+	TypeKafka            = "kafka"
+	TypeKafkaBalanced    = "kafka_balanced"
+	TypeKinesis          = "kinesis"
+	TypeKinesisBalanced  = "kinesis_balanced"
+	TypeMQTT             = "mqtt"
+	TypeMySQLBinlog      = "mysql_binlog"
+	TypeNanomsg          = "nanomsg"
+	TypeNATS             = "nats"
+	TypeNATSJetStream    = "nats_jetstream"
+	TypeNATSStream       = "nats_stream"
+	TypeNSQ              = "nsq"
+	TypeParquet          = "parquet"
+	TypePostgresCDC      = "postgres_cdc"
+	TypePulsar           = "pulsar"
+	TypeReadUntil        = "read_until"
+	TypeRedisList        = "redis_list"
+	TypeRedisPubSub      = "redis_pubsub"
+	TypeRedisStreams     = "redis_streams"
+	TypeS3               = "s3"
+	TypeSequence         = "sequence"
+	TypeSFTP             = "sftp"
+	TypeSocket           = "socket"
+	TypeSQLSelect        = "sql_select"
+	TypeSQS              = "sqs"
+	TypeSTDIN            = "stdin"
+	TypeSTOMP            = "stomp"
+	TypeSubprocess       = "subprocess"
+	TypeSyslog           = "syslog"
+	TypeUDP              = "udp"
+	TypeWebsocket        = "websocket"
+	TypeZMQ4             = "zmq4"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all input types.
 type Config struct {
-	Type          string                     `json:"type" yaml:"type"`
-	AMQP          reader.AMQPConfig          `json:"amqp" yaml:"amqp"`
-	Broker        BrokerConfig               `json:"broker" yaml:"broker"`
-	Dynamic       DynamicConfig              `json:"dynamic" yaml:"dynamic"`
-	File          FileConfig                 `json:"file" yaml:"file"`
-	Files         reader.FilesConfig         `json:"files" yaml:"files"`
-	GCPPubSub     reader.GCPPubSubConfig     `json:"gcp_pubsub" yaml:"gcp_pubsub"`
-	HDFS          reader.HDFSConfig          `json:"hdfs" yaml:"hdfs"`
-	HTTPClient    HTTPClientConfig           `json:"http_client" yaml:"http_client"`
-	HTTPServer    HTTPServerConfig           `json:"http_server" yaml:"http_server"`
-	Inproc        InprocConfig               `json:"inproc" yaml:"inproc"`
-	Kafka         reader.KafkaConfig         `json:"kafka" yaml:"kafka"`
-	KafkaBalanced reader.KafkaBalancedConfig `json:"kafka_balanced" yaml:"kafka_balanced"`
-	Kinesis       reader.KinesisConfig       `json:"kinesis" yaml:"kinesis"`
-	MQTT          reader.MQTTConfig          `json:"mqtt" yaml:"mqtt"`
-	Nanomsg       reader.ScaleProtoConfig    `json:"nanomsg" yaml:"nanomsg"`
-	NATS          reader.NATSConfig          `json:"nats" yaml:"nats"`
-	NATSStream    reader.NATSStreamConfig    `json:"nats_stream" yaml:"nats_stream"`
-	NSQ           reader.NSQConfig           `json:"nsq" yaml:"nsq"`
-	Plugin        interface{}                `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	ReadUntil     ReadUntilConfig            `json:"read_until" yaml:"read_until"`
-	RedisList     reader.RedisListConfig     `json:"redis_list" yaml:"redis_list"`
-	RedisPubSub   reader.RedisPubSubConfig   `json:"redis_pubsub" yaml:"redis_pubsub"`
-	RedisStreams  reader.RedisStreamsConfig  `json:"redis_streams" yaml:"redis_streams"`
-	S3            reader.AmazonS3Config      `json:"s3" yaml:"s3"`
-	SQS           reader.AmazonSQSConfig     `json:"sqs" yaml:"sqs"`
-	STDIN         STDINConfig                `json:"stdin" yaml:"stdin"`
-	Websocket     reader.WebsocketConfig     `json:"websocket" yaml:"websocket"`
-	ZMQ4          *reader.ZMQ4Config         `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
-	Processors    []processor.Config         `json:"processors" yaml:"processors"`
+	Type             string                        `json:"type" yaml:"type"`
+	AMQP             reader.AMQPConfig             `json:"amqp" yaml:"amqp"`
+	AMQP1            reader.AMQP1Config            `json:"amqp_1" yaml:"amqp_1"`
+	AzureBlobStorage reader.AzureBlobStorageConfig `json:"azure_blob_storage" yaml:"azure_blob_storage"`
+	AzureEventHubs   reader.AzureEventHubsConfig   `json:"azure_event_hubs" yaml:"azure_event_hubs"`
+	AzureServiceBus  reader.AzureServiceBusConfig  `json:"azure_service_bus" yaml:"azure_service_bus"`
+	Broker           BrokerConfig                  `json:"broker" yaml:"broker"`
+	Cron             reader.CronConfig             `json:"cron" yaml:"cron"`
+	CSV              reader.CSVConfig              `json:"csv" yaml:"csv"`
+	DockerLogs       reader.DockerLogsConfig       `json:"docker_logs" yaml:"docker_logs"`
+	Dynamic          DynamicConfig                 `json:"dynamic" yaml:"dynamic"`
+	File             FileConfig                    `json:"file" yaml:"file"`
+	Files            reader.FilesConfig            `json:"files" yaml:"files"`
+	FTP              reader.FTPConfig              `json:"ftp" yaml:"ftp"`
+	GCPCloudStorage  reader.GCPCloudStorageConfig  `json:"gcp_cloud_storage" yaml:"gcp_cloud_storage"`
+	GCPPubSub        reader.GCPPubSubConfig        `json:"gcp_pubsub" yaml:"gcp_pubsub"`
+	Generate         reader.GenerateConfig         `json:"generate" yaml:"generate"`
+	HDFS             reader.HDFSConfig             `json:"hdfs" yaml:"hdfs"`
+	HTTPClient       HTTPClientConfig              `json:"http_client" yaml:"http_client"`
+	HTTPServer       HTTPServerConfig              `json:"http_server" yaml:"http_server"`
+	IMAP             reader.IMAPConfig             `json:"imap" yaml:"imap"`
+	Inproc           InprocConfig                  `json:"inproc" yaml:"inproc"`
+	Journald         reader.JournaldConfig         `json:"journald" yaml:"journald"`
+	Kafka            reader.KafkaConfig            `json:"kafka" yaml:"kafka"`
+	KafkaBalanced    reader.KafkaBalancedConfig    `json:"kafka_balanced" yaml:"kafka_balanced"`
+	Kinesis          reader.KinesisConfig          `json:"kinesis" yaml:"kinesis"`
+	KinesisBalanced  reader.KinesisBalancedConfig  `json:"kinesis_balanced" yaml:"kinesis_balanced"`
+	MQTT             reader.MQTTConfig             `json:"mqtt" yaml:"mqtt"`
+	MySQLBinlog      reader.MySQLBinlogConfig      `json:"mysql_binlog" yaml:"mysql_binlog"`
+	Nanomsg          reader.ScaleProtoConfig       `json:"nanomsg" yaml:"nanomsg"`
+	NATS             reader.NATSConfig             `json:"nats" yaml:"nats"`
+	NATSJetStream    reader.NATSJetStreamConfig    `json:"nats_jetstream" yaml:"nats_jetstream"`
+	NATSStream       reader.NATSStreamConfig       `json:"nats_stream" yaml:"nats_stream"`
+	NSQ              reader.NSQConfig              `json:"nsq" yaml:"nsq"`
+	Parquet          reader.ParquetConfig          `json:"parquet" yaml:"parquet"`
+	PostgresCDC      reader.PostgresCDCConfig      `json:"postgres_cdc" yaml:"postgres_cdc"`
+	Pulsar           reader.PulsarConfig           `json:"pulsar" yaml:"pulsar"`
+	Plugin           interface{}                   `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	ReadUntil        ReadUntilConfig               `json:"read_until" yaml:"read_until"`
+	RedisList        reader.RedisListConfig        `json:"redis_list" yaml:"redis_list"`
+	RedisPubSub      reader.RedisPubSubConfig      `json:"redis_pubsub" yaml:"redis_pubsub"`
+	RedisStreams     reader.RedisStreamsConfig     `json:"redis_streams" yaml:"redis_streams"`
+	S3               reader.AmazonS3Config         `json:"s3" yaml:"s3"`
+	Sequence         SequenceConfig                `json:"sequence" yaml:"sequence"`
+	SFTP             reader.SFTPConfig             `json:"sftp" yaml:"sftp"`
+	Socket           reader.SocketConfig           `json:"socket" yaml:"socket"`
+	SQLSelect        reader.SQLSelectConfig        `json:"sql_select" yaml:"sql_select"`
+	SQS              reader.AmazonSQSConfig        `json:"sqs" yaml:"sqs"`
+	STDIN            STDINConfig                   `json:"stdin" yaml:"stdin"`
+	STOMP            reader.STOMPConfig            `json:"stomp" yaml:"stomp"`
+	Subprocess       reader.SubprocessConfig       `json:"subprocess" yaml:"subprocess"`
+	Syslog           reader.SyslogConfig           `json:"syslog" yaml:"syslog"`
+	UDP              reader.UDPConfig              `json:"udp" yaml:"udp"`
+	Websocket        reader.WebsocketConfig        `json:"websocket" yaml:"websocket"`
+	ZMQ4             *reader.ZMQ4Config            `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
+	Batching         processor.BatchConfig         `json:"batching" yaml:"batching"`
+	Processors       []processor.Config            `json:"processors" yaml:"processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:          "stdin",
-		AMQP:          reader.NewAMQPConfig(),
-		Broker:        NewBrokerConfig(),
-		Dynamic:       NewDynamicConfig(),
-		File:          NewFileConfig(),
-		Files:         reader.NewFilesConfig(),
-		GCPPubSub:     reader.NewGCPPubSubConfig(),
-		HDFS:          reader.NewHDFSConfig(),
-		HTTPClient:    NewHTTPClientConfig(),
-		HTTPServer:    NewHTTPServerConfig(),
-		Inproc:        NewInprocConfig(),
-		Kafka:         reader.NewKafkaConfig(),
-		KafkaBalanced: reader.NewKafkaBalancedConfig(),
-		Kinesis:       reader.NewKinesisConfig(),
-		MQTT:          reader.NewMQTTConfig(),
-		Nanomsg:       reader.NewScaleProtoConfig(),
-		NATS:          reader.NewNATSConfig(),
-		NATSStream:    reader.NewNATSStreamConfig(),
-		NSQ:           reader.NewNSQConfig(),
-		Plugin:        nil,
-		ReadUntil:     NewReadUntilConfig(),
-		RedisList:     reader.NewRedisListConfig(),
-		RedisPubSub:   reader.NewRedisPubSubConfig(),
-		RedisStreams:  reader.NewRedisStreamsConfig(),
-		S3:            reader.NewAmazonS3Config(),
-		SQS:           reader.NewAmazonSQSConfig(),
-		STDIN:         NewSTDINConfig(),
-		Websocket:     reader.NewWebsocketConfig(),
-		ZMQ4:          reader.NewZMQ4Config(),
-		Processors:    []processor.Config{},
+		Type:             "stdin",
+		AMQP:             reader.NewAMQPConfig(),
+		AMQP1:            reader.NewAMQP1Config(),
+		AzureBlobStorage: reader.NewAzureBlobStorageConfig(),
+		AzureEventHubs:   reader.NewAzureEventHubsConfig(),
+		AzureServiceBus:  reader.NewAzureServiceBusConfig(),
+		Broker:           NewBrokerConfig(),
+		Cron:             reader.NewCronConfig(),
+		CSV:              reader.NewCSVConfig(),
+		DockerLogs:       reader.NewDockerLogsConfig(),
+		Dynamic:          NewDynamicConfig(),
+		File:             NewFileConfig(),
+		Files:            reader.NewFilesConfig(),
+		FTP:              reader.NewFTPConfig(),
+		GCPCloudStorage:  reader.NewGCPCloudStorageConfig(),
+		GCPPubSub:        reader.NewGCPPubSubConfig(),
+		Generate:         reader.NewGenerateConfig(),
+		HDFS:             reader.NewHDFSConfig(),
+		HTTPClient:       NewHTTPClientConfig(),
+		HTTPServer:       NewHTTPServerConfig(),
+		IMAP:             reader.NewIMAPConfig(),
+		Inproc:           NewInprocConfig(),
+		Journald:         reader.NewJournaldConfig(),
+		Kafka:            reader.NewKafkaConfig(),
+		KafkaBalanced:    reader.NewKafkaBalancedConfig(),
+		Kinesis:          reader.NewKinesisConfig(),
+		KinesisBalanced:  reader.NewKinesisBalancedConfig(),
+		MQTT:             reader.NewMQTTConfig(),
+		MySQLBinlog:      reader.NewMySQLBinlogConfig(),
+		Nanomsg:          reader.NewScaleProtoConfig(),
+		NATS:             reader.NewNATSConfig(),
+		NATSJetStream:    reader.NewNATSJetStreamConfig(),
+		NATSStream:       reader.NewNATSStreamConfig(),
+		NSQ:              reader.NewNSQConfig(),
+		Plugin:           nil,
+		Parquet:          reader.NewParquetConfig(),
+		PostgresCDC:      reader.NewPostgresCDCConfig(),
+		Pulsar:           reader.NewPulsarConfig(),
+		ReadUntil:        NewReadUntilConfig(),
+		RedisList:        reader.NewRedisListConfig(),
+		RedisPubSub:      reader.NewRedisPubSubConfig(),
+		RedisStreams:     reader.NewRedisStreamsConfig(),
+		S3:               reader.NewAmazonS3Config(),
+		Sequence:         NewSequenceConfig(),
+		SFTP:             reader.NewSFTPConfig(),
+		Socket:           reader.NewSocketConfig(),
+		SQLSelect:        reader.NewSQLSelectConfig(),
+		SQS:              reader.NewAmazonSQSConfig(),
+		STDIN:            NewSTDINConfig(),
+		STOMP:            reader.NewSTOMPConfig(),
+		Subprocess:       reader.NewSubprocessConfig(),
+		Syslog:           reader.NewSyslogConfig(),
+		UDP:              reader.NewUDPConfig(),
+		Websocket:        reader.NewWebsocketConfig(),
+		ZMQ4:             reader.NewZMQ4Config(),
+		Batching:         processor.NewBatchConfig(),
+		Processors:       []processor.Config{},
 	}
 }
 
@@ -196,6 +277,19 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 		}
 	}
 
+	if batchingEnabled(conf.Batching) {
+		var condSanit interface{}
+		if condSanit, err = condition.SanitiseConfig(conf.Batching.Condition); err != nil {
+			return nil, err
+		}
+		outputMap["batching"] = map[string]interface{}{
+			"byte_size": conf.Batching.ByteSize,
+			"count":     conf.Batching.Count,
+			"condition": condSanit,
+			"period":    conf.Batching.Period,
+		}
+	}
+
 	if len(conf.Processors) == 0 {
 		return outputMap, nil
 	}
@@ -306,7 +400,43 @@ input:
 
 Note that in this example we have specified a processor at the broker level
 which will be applied to _all_ inputs, and we also have a processor at the baz
-level which is only applied to messages from the baz input.`
+level which is only applied to messages from the baz input.
+
+### Batching
+
+Every input supports a ` + "`batching`" + ` block, which combines messages
+consumed from the input into batches before they are sent through any
+processors. The fields are the same as those of the
+[` + "`batch`" + ` processor](../processors/README.md#batch), where a batch is
+flushed once it reaches a ` + "`count`" + ` or ` + "`byte_size`" + `, once the
+` + "`condition`" + ` resolves ` + "`true`" + ` for an added message, or once the
+` + "`period`" + ` has passed since the last batch:
+
+` + "``` yaml" + `
+input:
+  type: sqs
+  sqs:
+    url: https://sqs.us-east-2.amazonaws.com/123456789012/MyQueue
+  batching:
+    count: 10
+    period: 1s
+` + "```" + `
+
+Messages within a batch are only acknowledged once the whole batch has reached
+its destination. Batching is disabled when none of the fields are set.
+
+A pending batch is flushed once its ` + "`period`" + ` has passed even if the
+input stops receiving messages, and is acknowledged as soon as it reaches its
+destination. There are a few exceptions:
+
+- The ` + "`http_server`" + `, ` + "`inproc`" + `, ` + "`read_until`" + ` and
+  ` + "`sequence`" + ` inputs, as well as batching configured on a broker, retry
+  a flushed batch until it is delivered and acknowledge its messages along with
+  the next batch.
+- Inputs that block on a stream without a timeout, which are ` + "`stdin`" + `,
+  ` + "`subprocess`" + `, ` + "`journald`" + `, ` + "`websocket`" + `,
+  ` + "`pulsar`" + ` and ` + "`mysql_binlog`" + `, only flush an expired batch
+  once the next message arrives.`
 
 // Descriptions returns a formatted string of descriptions for each type.
 func Descriptions() string {
@@ -357,6 +487,15 @@ func Descriptions() string {
 	return buf.String()
 }
 
+// batchingEnabled returns whether a batching config has any flush trigger set.
+func batchingEnabled(conf processor.BatchConfig) bool {
+	return conf.ByteSize > 0 ||
+		conf.Count > 0 ||
+		len(conf.Period) > 0 ||
+		conf.Condition.Type != "static" ||
+		conf.Condition.Static
+}
+
 // New creates an input type based on an input configuration.
 func New(
 	conf Config,
@@ -386,22 +525,73 @@ func New(
 	}
 	if c, ok := Constructors[conf.Type]; ok {
 		if c.brokerConstructor != nil {
+			if batchingEnabled(conf.Batching) {
+				pipelines = append([]types.PipelineConstructorFunc{
+					batchingPipeline(conf.Batching, mgr, log, stats),
+				}, pipelines...)
+			}
 			return c.brokerConstructor(conf, mgr, log, stats, pipelines...)
 		}
 		input, err := c.constructor(conf, mgr, log, stats)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create input '%v': %v", conf.Type, err)
 		}
-		return WrapWithPipelines(input, pipelines...)
+		return wrapWithBatching(input, conf.Batching, mgr, log, stats, pipelines...)
 	}
 	if c, ok := pluginSpecs[conf.Type]; ok {
 		input, err := c.constructor(conf.Plugin, mgr, log, stats)
 		if err != nil {
 			return nil, err
 		}
-		return WrapWithPipelines(input, pipelines...)
+		return wrapWithBatching(input, conf.Batching, mgr, log, stats, pipelines...)
 	}
 	return nil, types.ErrInvalidInputType
 }
 
+// wrapWithBatching applies a batching config to an input followed by its
+// pipelines. Inputs that consume a reader.Type have the reader itself batched,
+// allowing pending batches to be flushed and acknowledged whilst the source is
+// idle. Other inputs are batched with a pipeline.
+func wrapWithBatching(
+	input Type,
+	conf processor.BatchConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+	pipelines ...types.PipelineConstructorFunc,
+) (Type, error) {
+	if batchingEnabled(conf) {
+		if rdr, ok := input.(*Reader); ok {
+			if err := rdr.batch(conf, mgr, log.NewModule(".batching"), metrics.Namespaced(stats, "batching")); err != nil {
+				return nil, fmt.Errorf("failed to create batching policy: %v", err)
+			}
+		} else {
+			pipelines = append([]types.PipelineConstructorFunc{
+				batchingPipeline(conf, mgr, log, stats),
+			}, pipelines...)
+		}
+	}
+	return WrapWithPipelines(input, pipelines...)
+}
+
+// batchingPipeline returns a pipeline constructor that combines messages into
+// batches according to a batching config.
+func batchingPipeline(
+	conf processor.BatchConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) types.PipelineConstructorFunc {
+	return func(i *int) (types.Pipeline, error) {
+		procConf := processor.NewConfig()
+		procConf.Type = processor.TypeBatch
+		procConf.Batch = conf
+		batcher, err := processor.NewBatch(procConf, mgr, log.NewModule(".batching"), metrics.Namespaced(stats, "batching"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create batching policy: %v", err)
+		}
+		return pipeline.NewBatcher(log, stats, batcher.(*processor.Batch)), nil
+	}
+}
+
 //------------------------------------------------------------------------------
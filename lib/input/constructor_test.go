@@ -0,0 +1,204 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/config"
+)
+
+func TestConstructorBatching(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "benthos_batching_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err = tmpfile.Write([]byte("foo\nbar\nbaz\nqux")); err != nil {
+		t.Fatal(err)
+	}
+	if err = tmpfile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeFile
+	conf.File.Path = tmpfile.Name()
+	conf.Batching.Count = 2
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range [][][]byte{
+		{[]byte("foo"), []byte("bar")},
+		{[]byte("baz"), []byte("qux")},
+	} {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-in.TransactionChan():
+			if !open {
+				t.Fatal("transaction chan closed")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		if act := message.GetAllBytes(tran.Payload); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong batch contents: %s != %s", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	in.CloseAsync()
+	if err = in.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+type mockIdleReader struct {
+	msgs      chan types.Message
+	acks      chan error
+	closeChan chan struct{}
+}
+
+func (r *mockIdleReader) Connect() error {
+	return nil
+}
+func (r *mockIdleReader) Read() (types.Message, error) {
+	return r.ReadWithDeadline(time.Time{})
+}
+func (r *mockIdleReader) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	var timeoutChan <-chan time.Time
+	if !deadline.IsZero() {
+		timeoutChan = time.After(time.Until(deadline))
+	}
+	select {
+	case msg := <-r.msgs:
+		return msg, nil
+	case <-timeoutChan:
+	case <-r.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+	return nil, types.ErrTimeout
+}
+func (r *mockIdleReader) Acknowledge(err error) error {
+	r.acks <- err
+	return nil
+}
+func (r *mockIdleReader) CloseAsync() {
+	close(r.closeChan)
+}
+func (r *mockIdleReader) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestConstructorBatchingPeriod(t *testing.T) {
+	rdr := &mockIdleReader{
+		msgs:      make(chan types.Message, 10),
+		acks:      make(chan error, 10),
+		closeChan: make(chan struct{}),
+	}
+	rdrInput, err := NewReader("foo", rdr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.Batching.Count = 10
+	conf.Batching.Period = "50ms"
+
+	in, err := wrapWithBatching(rdrInput, conf.Batching, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{[]byte("foo"), []byte("bar")}
+	for _, p := range exp {
+		rdr.msgs <- message.New([][]byte{p})
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-in.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	if act := message.GetAllBytes(tran.Payload); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong batch contents: %s != %s", act, exp)
+	}
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	// The batch should be acknowledged even though the reader is idle.
+	select {
+	case err = <-rdr.acks:
+		if err != nil {
+			t.Errorf("Unexpected ack error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	in.CloseAsync()
+	if err = in.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConstructorBatchingSanitise(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSTDIN
+
+	sanit, err := SanitiseConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := sanit.(config.Sanitised)["batching"]; exists {
+		t.Error("Expected batching to be omitted when disabled")
+	}
+
+	conf.Batching.Period = "1s"
+	if sanit, err = SanitiseConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := sanit.(config.Sanitised)["batching"]; !exists {
+		t.Error("Expected batching to be present when enabled")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCron] = TypeSpec{
+		constructor: NewCron,
+		description: `
+Emits a message each time a cron schedule activates, which can be used to
+trigger periodic work such as querying an HTTP endpoint with the ` + "`http`" + `
+processor every night at 2am:
+
+` + "``` yaml" + `
+input:
+  type: cron
+  cron:
+    schedule: 0 2 * * *
+    timezone: Europe/London
+    message: '{"requested_at":"${!timestamp}"}'
+` + "```" + `
+
+The schedule can be a standard five field cron expression (minute, hour, day of
+month, month, day of week), or a six field expression with a leading seconds
+field. Fields support wildcards, ranges (` + "`1-5`" + `), lists
+(` + "`1,3,5`" + `), steps (` + "`*/15`" + `) and names for months and days of
+the week (` + "`JAN`, `MON`" + `). The descriptors ` + "`@yearly`" + `,
+` + "`@monthly`, `@weekly`, `@daily`, `@hourly`" + ` and
+` + "`@every <duration>`" + ` are also supported.
+
+The ` + "`timezone`" + ` field is an IANA timezone name used when evaluating
+the schedule. The ` + "`message`" + ` field supports
+[function interpolations](../config_interpolation.md#functions).
+
+If a message is still being processed when the schedule next activates that
+activation is skipped.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- cron_schedule
+- cron_time
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewCron creates a new cron input type.
+func NewCron(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	c, err := reader.NewCron(conf.Cron, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"cron",
+		reader.NewPreserver(c),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCSV] = TypeSpec{
+		constructor: NewCSV,
+		description: `
+Reads one or more CSV files in order and emits each row as a JSON document. A
+path of ` + "`-`" + ` reads CSV data from stdin. Once all files have been
+consumed the input closes.
+
+When ` + "`parse_header_row`" + ` is true the first row of each file is used
+as the field names of an object, where each subsequent row is emitted as an
+object of those fields, e.g. ` + "`{\"id\":\"1\",\"name\":\"foo\"}`" + `. Rows
+that do not have the same number of fields as the header are logged and
+skipped. When ` + "`parse_header_row`" + ` is false each row is emitted as an
+array of strings.
+
+The ` + "`delimiter`" + ` field sets the single character used to separate
+fields. Setting ` + "`lazy_quotes`" + ` to true allows quotes to appear within
+unquoted fields and non-doubled quotes to appear within quoted fields.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewCSV creates a new CSV input type.
+func NewCSV(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	c, err := reader.NewCSV(conf.CSV, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("csv", reader.NewPreserver(c), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,87 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDockerLogs] = TypeSpec{
+		constructor: NewDockerLogs,
+		description: `
+Connects to the Docker Engine API and tails the logs of running containers,
+emitting each log line as a message.
+
+Containers are selected with ` + "`labels`" + ` filters, which are either a
+label key (` + "`app`" + `) or a key and value (` + "`app=foo`" + `), and a
+container must match all filters in order to be tailed. If no filters are
+specified then all running containers are tailed. The list of containers is
+refreshed every ` + "`refresh_interval`" + ` in order to discover new
+containers.
+
+The ` + "`host`" + ` can either be a unix socket
+(` + "`unix:///var/run/docker.sock`" + `) or a TCP address
+(` + "`tcp://localhost:2375`" + `).
+
+By default only logs written after the input starts are consumed, set
+` + "`start_from_oldest`" + ` to true in order to consume the full logs of each
+container.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- docker_container_id
+- docker_container_name
+- docker_container_image
+- docker_stream
+- docker_timestamp
+- docker_label_<key> (for each label of the container)
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewDockerLogs creates a new docker_logs input type.
+func NewDockerLogs(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	d, err := reader.NewDockerLogs(conf.DockerLogs, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"docker_logs",
+		reader.NewPreserver(d),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
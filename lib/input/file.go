@@ -21,8 +21,10 @@
 package input
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/input/reader"
 	"github.com/Jeffail/benthos/lib/log"
@@ -40,7 +42,38 @@ The file type reads input from a file. If multipart is set to false each line
 is read as a separate message. If multipart is set to true each line is read as
 a message part, and an empty line indicates the end of a message.
 
-If the delimiter field is left empty then line feed (\n) is used.`,
+If the delimiter field is left empty then line feed (\n) is used.
+
+### Tail Mode
+
+When ` + "`tail`" + ` is set to ` + "`true`" + ` the path is treated as a glob
+pattern (e.g. ` + "`/var/log/*.log`" + `) and all matching files are followed
+indefinitely, with each line being read as a separate message as it is
+appended. Multipart messages are not supported in this mode.
+
+Every ` + "`poll_interval`" + ` the glob is re-evaluated in order to find new
+files, and followed files are checked for truncation and rotation. A truncated
+file is read again from the beginning. When a file is rotated (renamed or
+removed) the remaining lines of the old file are read before it is closed, and a
+new file created under the same path is followed from the beginning.
+
+If ` + "`offsets_path`" + ` is set then the offsets of acknowledged lines are
+periodically written to a file at that path, allowing the input to resume where
+it left off after a restart. A persisted offset is only used if the start of the
+file still matches what was previously read. Files found on start up that do not
+have a persisted offset are read from the beginning unless
+` + "`start_from_beginning`" + ` is set to ` + "`false`" + `, in which case only
+new lines are read.
+
+In tail mode the following metadata fields are added to each message:
+
+` + "```" + `
+- file_path
+- file_offset
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
 	}
 }
 
@@ -48,19 +81,27 @@ If the delimiter field is left empty then line feed (\n) is used.`,
 
 // FileConfig contains configuration values for the File input type.
 type FileConfig struct {
-	Path      string `json:"path" yaml:"path"`
-	Multipart bool   `json:"multipart" yaml:"multipart"`
-	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
-	Delim     string `json:"delimiter" yaml:"delimiter"`
+	Path               string `json:"path" yaml:"path"`
+	Multipart          bool   `json:"multipart" yaml:"multipart"`
+	MaxBuffer          int    `json:"max_buffer" yaml:"max_buffer"`
+	Delim              string `json:"delimiter" yaml:"delimiter"`
+	Tail               bool   `json:"tail" yaml:"tail"`
+	PollInterval       string `json:"poll_interval" yaml:"poll_interval"`
+	OffsetsPath        string `json:"offsets_path" yaml:"offsets_path"`
+	StartFromBeginning bool   `json:"start_from_beginning" yaml:"start_from_beginning"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
 func NewFileConfig() FileConfig {
 	return FileConfig{
-		Path:      "",
-		Multipart: false,
-		MaxBuffer: 1000000,
-		Delim:     "",
+		Path:               "",
+		Multipart:          false,
+		MaxBuffer:          1000000,
+		Delim:              "",
+		Tail:               false,
+		PollInterval:       "1s",
+		OffsetsPath:        "",
+		StartFromBeginning: true,
 	}
 }
 
@@ -68,14 +109,18 @@ func NewFileConfig() FileConfig {
 
 // NewFile creates a new File input type.
 func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	file, err := os.Open(conf.File.Path)
-	if err != nil {
-		return nil, err
-	}
 	delim := conf.File.Delim
 	if len(delim) == 0 {
 		delim = "\n"
 	}
+	if conf.File.Tail {
+		return newFileTail(conf.File, delim, log, stats)
+	}
+
+	file, err := os.Open(conf.File.Path)
+	if err != nil {
+		return nil, err
+	}
 
 	rdr, err := reader.NewLines(
 		func() (io.Reader, error) {
@@ -103,4 +148,27 @@ func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	)
 }
 
+func newFileTail(conf FileConfig, delim string, log log.Modular, stats metrics.Type) (Type, error) {
+	pollInterval, err := time.ParseDuration(conf.PollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse poll interval: %v", err)
+	}
+	rdr, err := reader.NewFileTail(
+		conf.Path, log, stats,
+		reader.OptFileTailSetDelimiter(delim),
+		reader.OptFileTailSetMaxBuffer(conf.MaxBuffer),
+		reader.OptFileTailSetPollInterval(pollInterval),
+		reader.OptFileTailSetOffsetsPath(conf.OffsetsPath),
+		reader.OptFileTailSetStartFromBeginning(conf.StartFromBeginning),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"file",
+		reader.NewPreserver(rdr),
+		log, stats,
+	)
+}
+
 //------------------------------------------------------------------------------
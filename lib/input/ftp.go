@@ -0,0 +1,53 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFTP] = TypeSpec{
+		constructor: NewFTP,
+		description: `
+Polls a directory of an FTP server every ` + "`poll_interval`" + ` for new files
+and reads them as messages. Data is transferred in passive mode.
+` + remoteFilesDescription,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewFTP creates a new FTP input type.
+func NewFTP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	f, err := reader.NewFTP(conf.FTP, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("ftp", reader.NewPreserver(f), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGCPCloudStorage] = TypeSpec{
+		constructor: NewGCPCloudStorage,
+		description: `
+Downloads objects within a GCP Cloud Storage bucket, optionally filtered by a
+prefix. The objects found when this input connects are downloaded in order,
+after which the input closes. Credentials are resolved using the standard
+[application default credentials](https://cloud.google.com/docs/authentication/production).
+
+The field ` + "`decompress`" + ` can be set to ` + "`gzip`" + ` in order to
+decompress all objects, or ` + "`auto`" + ` in order to only decompress objects
+that have a ` + "`.gz`" + ` suffix. Objects stored with a
+` + "`Content-Encoding`" + ` of ` + "`gzip`" + ` are decompressed by the service
+itself.
+
+When ` + "`split_lines`" + ` is set to ` + "`true`" + ` each line of an object
+(separated by ` + "`delimiter`" + `, which defaults to a newline) becomes a part
+of a single message batch, which can be broken into individual messages with the
+` + "`split`" + ` processor.
+
+Once an object has been sent onwards successfully the ` + "`after_read`" + `
+action is performed, which can be ` + "`none`" + `, ` + "`delete`" + ` or
+` + "`move`" + `. Moving an object copies it to ` + "`move_to_bucket`" + `
+(which defaults to the source bucket) with ` + "`move_to_prefix`" + ` prepended
+to its name, and then deletes the original.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- gcs_key
+- gcs_bucket
+- gcs_size
+- gcs_last_modified (RFC3339)
+- gcs_last_modified_unix
+- gcs_content_type
+- gcs_content_encoding
+- All user defined metadata
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGCPCloudStorage creates a new GCP Cloud Storage input type.
+func NewGCPCloudStorage(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	r, err := reader.NewGCPCloudStorage(conf.GCPCloudStorage, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("gcp_cloud_storage", reader.NewPreserver(r), log, stats)
+}
+
+//------------------------------------------------------------------------------
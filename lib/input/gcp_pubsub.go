@@ -33,8 +33,31 @@ func init() {
 	Constructors[TypeGCPPubSub] = TypeSpec{
 		constructor: NewGCPPubSub,
 		description: `
-Consumes messages from a GCP Cloud Pub/Sub subscription. Attributes from each
-message are added as metadata, which can be accessed using
+Consumes messages from a GCP Cloud Pub/Sub subscription. Messages are only
+acknowledged once they have been successfully propagated to the output, and are
+negatively acknowledged (resulting in redelivery) otherwise.
+
+The fields ` + "`max_outstanding_messages`" + ` and
+` + "`max_outstanding_bytes`" + ` control flow by limiting the number and total
+size of messages that are received but not yet acknowledged. When
+` + "`synchronous`" + ` is set to ` + "`true`" + ` no more than
+` + "`max_outstanding_messages`" + ` are held in memory at any time.
+
+The ack deadline of outstanding messages is automatically extended until they
+are acknowledged, up to a maximum duration of ` + "`max_extension`" + `. A
+negative duration disables deadline extension.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- gcp_pubsub_message_id
+- gcp_pubsub_publish_time_unix
+- All message attributes
+` + "```" + `
+
+You can access these metadata fields using
 [function interpolation](../config_interpolation.md#metadata).`,
 	}
 }
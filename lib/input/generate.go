@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGenerate] = TypeSpec{
+		constructor: NewGenerate,
+		description: `
+Generates messages from a template at a given interval, which is useful for
+testing pipelines and producing synthetic data. The template supports
+[function interpolations](../config_interpolation.md#functions), which are
+resolved each time a message is generated. For example, the template:
+
+` + "``` yaml" + `
+template: '{"id":"${!uuid_v4}","score":${!random_int:0,100},"at":${!timestamp_unix}}'
+` + "```" + `
+
+Produces a unique JSON document for each message.
+
+The ` + "`interval`" + ` field is a duration string (e.g. ` + "`500ms`" + `)
+specifying how long to wait between messages. If left empty messages are
+generated as fast as the pipeline will consume them.
+
+If ` + "`count`" + ` is greater than zero the input will close once that many
+messages have been generated, otherwise messages are generated indefinitely.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGenerate creates a new generate input type.
+func NewGenerate(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g, err := reader.NewGenerate(conf.Generate, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"generate",
+		reader.NewPreserver(g),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
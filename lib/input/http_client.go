@@ -22,8 +22,15 @@ package input
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -35,6 +42,9 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/client"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+	"github.com/Jeffail/gabs"
+	"github.com/OneOfOne/xxhash"
 )
 
 //------------------------------------------------------------------------------
@@ -57,20 +67,84 @@ interpolations described [here](../config_interpolation.md#functions).
 If you enable streaming then Benthos will consume the body of the response as a
 line delimited list of message parts. Each part is read as an individual message
 unless multipart is set to true, in which case an empty line indicates the end
-of a message.`,
+of a message.
+
+Failed stream requests are reattempted according to the class of the failure.
+Connection errors are backed off by ` + "`network_backoff`" + `, responses
+with a status code within ` + "`rate_limit_codes`" + ` are backed off by
+` + "`rate_limit_backoff`" + `, and any other unexpected response is backed off
+by ` + "`http_backoff`" + `. Each backoff begins at its ` + "`initial_period`" + `
+and doubles with each consecutive failure up to its ` + "`max_period`" + `, with
+a random duration of up to ` + "`jitter`" + ` added to each wait. All backoffs
+are reset once a stream is successfully opened.
+
+Responses with a status code within ` + "`fatal_codes`" + ` are not retried,
+and instead the input is closed. The fields ` + "`retries`" + `,
+` + "`backoff_on`" + ` and ` + "`drop_on`" + ` are ignored when streaming.
+
+### Polling
+
+If you enable polling then Benthos will treat the target as a paginated JSON
+API. Each poll requests the configured URL and then follows subsequent pages
+until no next page is found, emitting each item of each page as an individual
+message. Once the last page is reached the next poll is scheduled after
+` + "`interval`" + ` plus a random duration of up to ` + "`jitter`" + `.
+
+Items are extracted from each page body at the dot path ` + "`items_path`" + `,
+which should point to an array. If left empty the body itself is used, where a
+JSON array is split into items and anything else is emitted as a single item.
+
+The next page is found either from a ` + "`Link`" + ` header with the relation
+` + "`next`" + ` when ` + "`link_header`" + ` is true, or from the body at the
+dot path ` + "`next_page_path`" + `. When ` + "`next_page_param`" + ` is set the
+value found in the body is treated as a token and set as that query parameter of
+the original URL, otherwise it is treated as a (possibly relative) URL.
+
+When ` + "`cache`" + ` is set to the name of a [cache resource](../caches),
+items that have already been delivered are skipped. Items are identified by the
+value at the dot path ` + "`id_path`" + `, or by a hash of their contents if it
+is left empty.`,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// StreamBackoffConfig contains fields for specifying how long to wait before
+// reattempting a failed stream request.
+type StreamBackoffConfig struct {
+	InitialPeriod string `json:"initial_period" yaml:"initial_period"`
+	MaxPeriod     string `json:"max_period" yaml:"max_period"`
+	Jitter        string `json:"jitter" yaml:"jitter"`
+}
+
 // StreamConfig contains fields for specifying consumption behaviour when the
 // body of a request is a constant stream of bytes.
 type StreamConfig struct {
-	Enabled   bool   `json:"enabled" yaml:"enabled"`
-	Reconnect bool   `json:"reconnect" yaml:"reconnect"`
-	Multipart bool   `json:"multipart" yaml:"multipart"`
-	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
-	Delim     string `json:"delimiter" yaml:"delimiter"`
+	Enabled          bool                `json:"enabled" yaml:"enabled"`
+	Reconnect        bool                `json:"reconnect" yaml:"reconnect"`
+	Multipart        bool                `json:"multipart" yaml:"multipart"`
+	MaxBuffer        int                 `json:"max_buffer" yaml:"max_buffer"`
+	Delim            string              `json:"delimiter" yaml:"delimiter"`
+	FatalCodes       []int               `json:"fatal_codes" yaml:"fatal_codes"`
+	RateLimitCodes   []int               `json:"rate_limit_codes" yaml:"rate_limit_codes"`
+	NetworkBackoff   StreamBackoffConfig `json:"network_backoff" yaml:"network_backoff"`
+	HTTPBackoff      StreamBackoffConfig `json:"http_backoff" yaml:"http_backoff"`
+	RateLimitBackoff StreamBackoffConfig `json:"rate_limit_backoff" yaml:"rate_limit_backoff"`
+}
+
+// PollConfig contains fields for specifying consumption behaviour when the
+// target is a paginated API that should be polled periodically.
+type PollConfig struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	Interval      string `json:"interval" yaml:"interval"`
+	Jitter        string `json:"jitter" yaml:"jitter"`
+	ItemsPath     string `json:"items_path" yaml:"items_path"`
+	NextPagePath  string `json:"next_page_path" yaml:"next_page_path"`
+	NextPageParam string `json:"next_page_param" yaml:"next_page_param"`
+	LinkHeader    bool   `json:"link_header" yaml:"link_header"`
+	MaxPages      int    `json:"max_pages" yaml:"max_pages"`
+	Cache         string `json:"cache" yaml:"cache"`
+	IDPath        string `json:"id_path" yaml:"id_path"`
 }
 
 // HTTPClientConfig contains configuration for the HTTPClient output type.
@@ -78,6 +152,7 @@ type HTTPClientConfig struct {
 	client.Config `json:",inline" yaml:",inline"`
 	Payload       string       `json:"payload" yaml:"payload"`
 	Stream        StreamConfig `json:"stream" yaml:"stream"`
+	Poll          PollConfig   `json:"poll" yaml:"poll"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
@@ -89,11 +164,40 @@ func NewHTTPClientConfig() HTTPClientConfig {
 		Config:  cConf,
 		Payload: "",
 		Stream: StreamConfig{
-			Enabled:   false,
-			Reconnect: true,
-			Multipart: false,
-			MaxBuffer: 1000000,
-			Delim:     "",
+			Enabled:        false,
+			Reconnect:      true,
+			Multipart:      false,
+			MaxBuffer:      1000000,
+			Delim:          "",
+			FatalCodes:     []int{401, 403},
+			RateLimitCodes: []int{420, 429},
+			NetworkBackoff: StreamBackoffConfig{
+				InitialPeriod: "250ms",
+				MaxPeriod:     "16s",
+				Jitter:        "250ms",
+			},
+			HTTPBackoff: StreamBackoffConfig{
+				InitialPeriod: "5s",
+				MaxPeriod:     "320s",
+				Jitter:        "1s",
+			},
+			RateLimitBackoff: StreamBackoffConfig{
+				InitialPeriod: "60s",
+				MaxPeriod:     "960s",
+				Jitter:        "5s",
+			},
+		},
+		Poll: PollConfig{
+			Enabled:       false,
+			Interval:      "60s",
+			Jitter:        "5s",
+			ItemsPath:     "",
+			NextPagePath:  "",
+			NextPageParam: "",
+			LinkHeader:    false,
+			MaxPages:      0,
+			Cache:         "",
+			IDPath:        "",
 		},
 	}
 }
@@ -115,6 +219,11 @@ type HTTPClient struct {
 
 	payload types.Message
 
+	pollInterval time.Duration
+	pollJitter   time.Duration
+	pollCache    types.Cache
+	pollRand     *rand.Rand
+
 	transactions chan types.Transaction
 
 	closeChan  chan struct{}
@@ -135,8 +244,10 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 	}
 
 	if h.conf.HTTPClient.Stream.Enabled {
-		// Timeout should be left at zero if we are streaming.
+		// Timeout should be left at zero if we are streaming, and failed
+		// requests are retried according to the stream backoff policies.
 		h.conf.HTTPClient.Timeout = ""
+		h.conf.HTTPClient.NumRetries = 0
 	}
 	if len(h.conf.HTTPClient.Payload) > 0 {
 		h.payload = message.New([][]byte{[]byte(h.conf.HTTPClient.Payload)})
@@ -153,6 +264,30 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 		return nil, err
 	}
 
+	if pConf := h.conf.HTTPClient.Poll; pConf.Enabled {
+		if h.conf.HTTPClient.Stream.Enabled {
+			return nil, errors.New("polling and streaming cannot both be enabled")
+		}
+		if tout := pConf.Interval; len(tout) > 0 {
+			if h.pollInterval, err = time.ParseDuration(tout); err != nil {
+				return nil, fmt.Errorf("failed to parse poll interval string: %v", err)
+			}
+		}
+		if tout := pConf.Jitter; len(tout) > 0 {
+			if h.pollJitter, err = time.ParseDuration(tout); err != nil {
+				return nil, fmt.Errorf("failed to parse poll jitter string: %v", err)
+			}
+		}
+		if len(pConf.Cache) > 0 {
+			if h.pollCache, err = mgr.GetCache(pConf.Cache); err != nil {
+				return nil, fmt.Errorf("failed to obtain cache '%v': %v", pConf.Cache, err)
+			}
+		}
+		h.pollRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		go h.pollLoop()
+		return &h, nil
+	}
+
 	if !h.conf.HTTPClient.Stream.Enabled {
 		go h.loop()
 		return &h, nil
@@ -168,10 +303,17 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 	var res *http.Response
 
 	conn := false
+	streamCloseChan := make(chan struct{})
+
+	backoff, err := newStreamBackoff(conf.HTTPClient.Stream, streamCloseChan)
+	if err != nil {
+		return nil, err
+	}
 
 	var (
 		mStrmConstructor = h.stats.GetCounter("stream.constructor")
 		mStrmReqErr      = h.stats.GetCounter("stream.request.error")
+		mStrmReqFatal    = h.stats.GetCounter("stream.request.fatal")
 		mStrnOnClose     = h.stats.GetCounter("stream.on_close")
 	)
 
@@ -193,11 +335,16 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 			var err error
 			res, err = h.doRequest()
 			for err != nil && !closed {
+				if backoff.isFatal(err) {
+					h.log.Errorf("HTTP stream request failed with a fatal status: %v\n", err)
+					mStrmReqFatal.Incr(1)
+					return nil, io.EOF
+				}
 				h.log.Errorf("HTTP stream request failed: %v\n", err)
 				mStrmReqErr.Incr(1)
 
 				resMux.Unlock()
-				<-time.After(time.Second)
+				backoff.wait(err)
 				resMux.Lock()
 
 				res, err = h.doRequest()
@@ -207,6 +354,7 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 				return nil, io.EOF
 			}
 
+			backoff.reset()
 			conn = true
 			return res.Body, nil
 		},
@@ -216,6 +364,9 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 			resMux.Lock()
 			defer resMux.Unlock()
 
+			if !closed {
+				close(streamCloseChan)
+			}
 			closed = true
 
 			// On shutdown we close the response body, this should end any
@@ -242,6 +393,99 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 
 //------------------------------------------------------------------------------
 
+// streamBackoff determines how long to wait before reattempting a failed
+// stream request, where network errors, rate limited responses and other
+// unexpected responses are each backed off independently.
+type streamBackoff struct {
+	fatalCodes     map[int]struct{}
+	rateLimitCodes map[int]struct{}
+
+	network   *throttle.Type
+	http      *throttle.Type
+	rateLimit *throttle.Type
+}
+
+func newStreamBackoff(conf StreamConfig, closeChan <-chan struct{}) (*streamBackoff, error) {
+	b := streamBackoff{
+		fatalCodes:     map[int]struct{}{},
+		rateLimitCodes: map[int]struct{}{},
+	}
+	for _, c := range conf.FatalCodes {
+		b.fatalCodes[c] = struct{}{}
+	}
+	for _, c := range conf.RateLimitCodes {
+		b.rateLimitCodes[c] = struct{}{}
+	}
+
+	var err error
+	if b.network, err = newStreamThrottle(conf.NetworkBackoff, closeChan); err != nil {
+		return nil, fmt.Errorf("failed to parse network_backoff: %v", err)
+	}
+	if b.http, err = newStreamThrottle(conf.HTTPBackoff, closeChan); err != nil {
+		return nil, fmt.Errorf("failed to parse http_backoff: %v", err)
+	}
+	if b.rateLimit, err = newStreamThrottle(conf.RateLimitBackoff, closeChan); err != nil {
+		return nil, fmt.Errorf("failed to parse rate_limit_backoff: %v", err)
+	}
+	return &b, nil
+}
+
+func newStreamThrottle(conf StreamBackoffConfig, closeChan <-chan struct{}) (*throttle.Type, error) {
+	var initial, max, jitter time.Duration
+	var err error
+	if tout := conf.InitialPeriod; len(tout) > 0 {
+		if initial, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse initial period string: %v", err)
+		}
+	}
+	if tout := conf.MaxPeriod; len(tout) > 0 {
+		if max, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse max period string: %v", err)
+		}
+	}
+	if tout := conf.Jitter; len(tout) > 0 {
+		if jitter, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse jitter string: %v", err)
+		}
+	}
+	return throttle.New(
+		throttle.OptMaxUnthrottledRetries(0),
+		throttle.OptThrottlePeriod(initial),
+		throttle.OptMaxExponentPeriod(max),
+		throttle.OptJitter(jitter),
+		throttle.OptCloseChan(closeChan),
+	), nil
+}
+
+// isFatal returns true if a request error is an unexpected response with a
+// status code that should not be retried.
+func (b *streamBackoff) isFatal(err error) bool {
+	if resErr, ok := err.(types.ErrUnexpectedHTTPRes); ok {
+		_, exists := b.fatalCodes[resErr.Code]
+		return exists
+	}
+	return false
+}
+
+// wait blocks for the backoff period of the class of a request error, returning
+// false if the stream was closed during the wait.
+func (b *streamBackoff) wait(err error) bool {
+	if resErr, ok := err.(types.ErrUnexpectedHTTPRes); ok {
+		if _, exists := b.rateLimitCodes[resErr.Code]; exists {
+			return b.rateLimit.ExponentialRetry()
+		}
+		return b.http.ExponentialRetry()
+	}
+	return b.network.ExponentialRetry()
+}
+
+// reset clears the backoff of all classes after a successful request.
+func (b *streamBackoff) reset() {
+	b.network.Reset()
+	b.http.Reset()
+	b.rateLimit.Reset()
+}
+
 func (h *HTTPClient) doRequest() (*http.Response, error) {
 	return h.client.Do(h.payload)
 }
@@ -342,6 +586,265 @@ func (h *HTTPClient) loop() {
 	}
 }
 
+//------------------------------------------------------------------------------
+
+// parseLinkNext returns the target of the first link with the relation "next"
+// from Link header values, or an empty string if there isn't one.
+func parseLinkNext(values []string) string {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			segments := strings.Split(link, ";")
+			target := strings.TrimSpace(segments[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range segments[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || strings.ToLower(strings.TrimSpace(kv[0])) != "rel" {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(kv[1], `"`)) {
+					if strings.ToLower(rel) == "next" {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// fetchPage requests a page from a URL, or the configured URL if empty, and
+// returns the items of the page along with the URL of the next page, which is
+// empty if this is the last page.
+func (h *HTTPClient) fetchPage(pageURL string) ([][]byte, string, error) {
+	var res *http.Response
+	var err error
+	if len(pageURL) == 0 {
+		res, err = h.client.Do(h.payload)
+	} else {
+		res, err = h.client.DoURL(pageURL, h.payload)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+
+	pConf := h.conf.HTTPClient.Poll
+
+	gBody, jerr := gabs.ParseJSON(body)
+	if jerr != nil && (len(pConf.ItemsPath) > 0 || len(pConf.NextPagePath) > 0) {
+		return nil, "", fmt.Errorf("failed to parse response body as JSON: %v", jerr)
+	}
+
+	var items [][]byte
+	if jerr != nil {
+		if len(body) > 0 {
+			items = append(items, body)
+		}
+	} else {
+		gItems := gBody
+		if len(pConf.ItemsPath) > 0 {
+			gItems = gBody.Path(pConf.ItemsPath)
+		}
+		switch t := gItems.Data().(type) {
+		case []interface{}:
+			for _, v := range t {
+				item, merr := json.Marshal(v)
+				if merr != nil {
+					return nil, "", fmt.Errorf("failed to serialise item: %v", merr)
+				}
+				items = append(items, item)
+			}
+		case nil:
+		default:
+			items = append(items, gItems.Bytes())
+		}
+	}
+
+	var next string
+	if pConf.LinkHeader {
+		next = parseLinkNext(res.Header["Link"])
+	} else if len(pConf.NextPagePath) > 0 {
+		switch t := gBody.Path(pConf.NextPagePath).Data().(type) {
+		case string:
+			next = t
+		case float64:
+			next = strconv.FormatFloat(t, 'f', -1, 64)
+		}
+	}
+	if len(next) == 0 {
+		return items, "", nil
+	}
+
+	current := res.Request.URL
+	if len(pConf.NextPageParam) > 0 {
+		nextURL := *current
+		query := nextURL.Query()
+		query.Set(pConf.NextPageParam, next)
+		nextURL.RawQuery = query.Encode()
+		return items, nextURL.String(), nil
+	}
+	nextURL, err := url.Parse(next)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse next page URL: %v", err)
+	}
+	return items, current.ResolveReference(nextURL).String(), nil
+}
+
+// itemKey returns the key used to deduplicate an item.
+func (h *HTTPClient) itemKey(item []byte) string {
+	if idPath := h.conf.HTTPClient.Poll.IDPath; len(idPath) > 0 {
+		if gItem, err := gabs.ParseJSON(item); err == nil {
+			switch t := gItem.Path(idPath).Data().(type) {
+			case string:
+				return t
+			case float64:
+				return strconv.FormatFloat(t, 'f', -1, 64)
+			case nil:
+			default:
+				return gItem.Path(idPath).String()
+			}
+		}
+	}
+	return strconv.FormatUint(xxhash.Checksum64(item), 16)
+}
+
+// pollLoop periodically walks the pages of the target API and emits each item
+// as a message.
+func (h *HTTPClient) pollLoop() {
+	var (
+		mRunning     = h.stats.GetGauge("running")
+		mRcvd        = h.stats.GetCounter("batch.received")
+		mPartsRcvd   = h.stats.GetCounter("received")
+		mPollSucc    = h.stats.GetCounter("poll.success")
+		mPollErr     = h.stats.GetCounter("poll.error")
+		mPages       = h.stats.GetCounter("poll.pages")
+		mDuplicate   = h.stats.GetCounter("poll.duplicate")
+		mCacheErr    = h.stats.GetCounter("poll.cache.error")
+		mCount       = h.stats.GetCounter("count")
+		mPartsCount  = h.stats.GetCounter("parts.count")
+		mSendErr     = h.stats.GetCounter("send.error")
+		mSendSucc    = h.stats.GetCounter("send.success")
+		pConf        = h.conf.HTTPClient.Poll
+		resOut       = make(chan types.Response)
+		maxPagesWarn = false
+	)
+
+	defer func() {
+		atomic.StoreInt32(&h.running, 0)
+		mRunning.Decr(1)
+
+		close(h.transactions)
+		close(h.closedChan)
+	}()
+
+	mRunning.Incr(1)
+	h.log.Infof("Polling for paginated HTTP messages from: %s\n", h.conf.HTTPClient.URL)
+
+	// Blocks until the message is successfully delivered, returns false if the
+	// input is closing.
+	send := func(msg types.Message) bool {
+		mCount.Incr(1)
+		mPartsCount.Incr(int64(msg.Len()))
+		mRcvd.Incr(1)
+		mPartsRcvd.Incr(int64(msg.Len()))
+		for {
+			select {
+			case h.transactions <- types.NewTransaction(msg, resOut):
+			case <-h.closeChan:
+				return false
+			}
+			select {
+			case res, open := <-resOut:
+				if !open {
+					return false
+				}
+				if res.Error() == nil {
+					mSendSucc.Incr(1)
+					return true
+				}
+				mSendErr.Incr(1)
+			case <-h.closeChan:
+				return false
+			}
+		}
+	}
+
+	for atomic.LoadInt32(&h.running) == 1 {
+		var pageURL string
+		visited := map[string]struct{}{}
+	pages:
+		for page := 0; pConf.MaxPages <= 0 || page < pConf.MaxPages; page++ {
+			items, next, err := h.fetchPage(pageURL)
+			if err != nil {
+				if err == types.ErrTypeClosed {
+					return
+				}
+				mPollErr.Incr(1)
+				h.log.Errorf("Failed to poll page: %v\n", err)
+				break pages
+			}
+			mPages.Incr(1)
+
+			for _, item := range items {
+				var key string
+				if h.pollCache != nil {
+					key = h.itemKey(item)
+					if _, cerr := h.pollCache.Get(key); cerr == nil {
+						mDuplicate.Incr(1)
+						continue
+					} else if cerr != types.ErrKeyNotFound {
+						mCacheErr.Incr(1)
+						h.log.Errorf("Failed to check item key '%v': %v\n", key, cerr)
+					}
+				}
+				if !send(message.New([][]byte{item})) {
+					return
+				}
+				if h.pollCache != nil {
+					if cerr := h.pollCache.Set(key, []byte("t")); cerr != nil {
+						mCacheErr.Incr(1)
+						h.log.Errorf("Failed to store item key '%v': %v\n", key, cerr)
+					}
+				}
+			}
+
+			if len(next) == 0 {
+				mPollSucc.Incr(1)
+				break pages
+			}
+			if _, seen := visited[next]; seen {
+				h.log.Warnf("Next page '%v' was already visited during this poll, stopping\n", next)
+				break pages
+			}
+			visited[next] = struct{}{}
+			pageURL = next
+
+			if pConf.MaxPages > 0 && page+1 >= pConf.MaxPages && !maxPagesWarn {
+				maxPagesWarn = true
+				h.log.Warnf("Reached the maximum of %v pages for a single poll\n", pConf.MaxPages)
+			}
+		}
+
+		wait := h.pollInterval
+		if h.pollJitter > 0 {
+			wait += time.Duration(h.pollRand.Int63n(int64(h.pollJitter)))
+		}
+		select {
+		case <-time.After(wait):
+		case <-h.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
 // TransactionChan returns a transactions channel for consuming messages from
 // this input type.
 func (h *HTTPClient) TransactionChan() <-chan types.Transaction {
@@ -8,12 +8,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/textproto"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/lib/cache"
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
@@ -746,6 +749,95 @@ func TestHTTPClientStreamGETRecover(t *testing.T) {
 	}
 }
 
+func TestHTTPClientStreamBackoffClasses(t *testing.T) {
+	var reqCount int32
+	codes := []int{http.StatusTooManyRequests, http.StatusInternalServerError}
+
+	tserve := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if i := int(atomic.AddInt32(&reqCount, 1)) - 1; i < len(codes) {
+			w.WriteHeader(codes[i])
+			return
+		}
+		w.Write([]byte("foo\n"))
+	}))
+	defer tserve.Close()
+
+	conf := NewConfig()
+	conf.HTTPClient.URL = tserve.URL + "/testpost"
+	conf.HTTPClient.Stream.Enabled = true
+	conf.HTTPClient.Stream.Reconnect = false
+	for _, b := range []*StreamBackoffConfig{
+		&conf.HTTPClient.Stream.NetworkBackoff,
+		&conf.HTTPClient.Stream.HTTPBackoff,
+		&conf.HTTPClient.Stream.RateLimitBackoff,
+	} {
+		b.InitialPeriod = "1ms"
+		b.MaxPeriod = "10ms"
+		b.Jitter = "1ms"
+	}
+
+	h, err := NewHTTPClient(conf, nil, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ts, open := <-h.TransactionChan():
+		if !open {
+			t.Fatal("Chan not open")
+		}
+		if exp, act := "foo", string(ts.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong part: %v != %v", act, exp)
+		}
+		ts.ResponseChan <- response.NewAck()
+	case <-time.After(time.Second):
+		t.Fatal("Action timed out")
+	}
+	if exp, act := int32(3), atomic.LoadInt32(&reqCount); exp != act {
+		t.Errorf("Wrong count of requests: %v != %v", act, exp)
+	}
+
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPClientStreamFatalCode(t *testing.T) {
+	var reqCount int32
+	tserve := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqCount, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer tserve.Close()
+
+	conf := NewConfig()
+	conf.HTTPClient.URL = tserve.URL + "/testpost"
+	conf.HTTPClient.Stream.Enabled = true
+
+	h, err := NewHTTPClient(conf, nil, log.Noop(), metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case _, open := <-h.TransactionChan():
+		if open {
+			t.Error("Expected input to close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Action timed out")
+	}
+	if exp, act := int32(1), atomic.LoadInt32(&reqCount); exp != act {
+		t.Errorf("Wrong count of requests: %v != %v", act, exp)
+	}
+
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
 func BenchmarkHTTPClientGETMultipart(b *testing.B) {
 	parts := []string{
 		"Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat.",
@@ -816,3 +908,164 @@ func BenchmarkHTTPClientGETMultipart(b *testing.B) {
 		b.Error(err)
 	}
 }
+
+func readHTTPClientItems(t *testing.T, h Type, n int) []string {
+	t.Helper()
+	var items []string
+	for i := 0; i < n; i++ {
+		var tr types.Transaction
+		var open bool
+		select {
+		case tr, open = <-h.TransactionChan():
+			if !open {
+				t.Fatal("Chan not open")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		items = append(items, string(tr.Payload.Get(0).Get()))
+		select {
+		case tr.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+	return items
+}
+
+func TestHTTPClientPollLinkHeader(t *testing.T) {
+	t.Parallel()
+
+	var pagesMut sync.Mutex
+	pages := map[string]string{
+		"":  `{"data":[{"id":1},{"id":2}]}`,
+		"2": `{"data":[{"id":3}]}`,
+		"3": `{"data":[{"id":4},{"id":5}]}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pagesMut.Lock()
+		defer pagesMut.Unlock()
+
+		page := r.URL.Query().Get("page")
+		switch page {
+		case "":
+			w.Header().Add("Link", `</items?page=2>; rel="next", </items?page=3>; rel="last"`)
+		case "2":
+			w.Header().Add("Link", `<?page=3>; rel="next"`)
+		}
+		w.Write([]byte(pages[page]))
+	}))
+	defer ts.Close()
+
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["seen"] = cache.NewConfig()
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.HTTPClient.URL = ts.URL + "/items"
+	conf.HTTPClient.Verb = "GET"
+	conf.HTTPClient.Poll.Enabled = true
+	conf.HTTPClient.Poll.Interval = "1ms"
+	conf.HTTPClient.Poll.Jitter = ""
+	conf.HTTPClient.Poll.LinkHeader = true
+	conf.HTTPClient.Poll.ItemsPath = "data"
+	conf.HTTPClient.Poll.Cache = "seen"
+	conf.HTTPClient.Poll.IDPath = "id"
+
+	h, err := NewHTTPClient(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{`{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`, `{"id":5}`}
+	if act := readHTTPClientItems(t, h, 5); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong items: %v != %v", act, exp)
+	}
+
+	// Subsequent polls should only emit new items.
+	pagesMut.Lock()
+	pages["3"] = `{"data":[{"id":4},{"id":5},{"id":6}]}`
+	pagesMut.Unlock()
+
+	exp = []string{`{"id":6}`}
+	if act := readHTTPClientItems(t, h, 1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong items: %v != %v", act, exp)
+	}
+
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPClientPollBodyToken(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exp, act := "bar", r.URL.Query().Get("foo"); exp != act {
+			t.Errorf("Wrong static query param: %v != %v", act, exp)
+		}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"results":["a","b"],"meta":{"next":"abc"}}`))
+		case "abc":
+			w.Write([]byte(`{"results":["c"],"meta":{"next":null}}`))
+		default:
+			t.Errorf("Unexpected cursor: %v", r.URL.Query().Get("cursor"))
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.HTTPClient.URL = ts.URL + "/items?foo=bar"
+	conf.HTTPClient.Verb = "GET"
+	conf.HTTPClient.Poll.Enabled = true
+	conf.HTTPClient.Poll.Interval = "1h"
+	conf.HTTPClient.Poll.ItemsPath = "results"
+	conf.HTTPClient.Poll.NextPagePath = "meta.next"
+	conf.HTTPClient.Poll.NextPageParam = "cursor"
+
+	h, err := NewHTTPClient(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{`"a"`, `"b"`, `"c"`}
+	if act := readHTTPClientItems(t, h, 3); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong items: %v != %v", act, exp)
+	}
+
+	// The next poll is an hour away so closing should be immediate.
+	h.CloseAsync()
+	if err := h.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPClientPollStreamConflict(t *testing.T) {
+	conf := NewConfig()
+	conf.HTTPClient.Poll.Enabled = true
+	conf.HTTPClient.Stream.Enabled = true
+	if _, err := NewHTTPClient(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from enabling both polling and streaming")
+	}
+}
+
+func TestHTTPClientParseLinkNext(t *testing.T) {
+	tests := map[string][]string{
+		"":                    nil,
+		"http://foo/2":        {`<http://foo/2>; rel="next"`},
+		"/bar?page=3":         {`</bar?page=1>; rel="prev", </bar?page=3>; rel="next"`},
+		"http://foo/baz":      {`<http://foo/first>; rel=first`, `<http://foo/baz>; rel="last next"`},
+		"https://example.com": {`<https://example.com>;rel=next`},
+	}
+	for exp, values := range tests {
+		if act := parseLinkNext(values); exp != act {
+			t.Errorf("Wrong next link from %v: %v != %v", values, act, exp)
+		}
+	}
+}
@@ -21,6 +21,8 @@
 package input
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -28,6 +30,8 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -35,6 +39,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/message/metadata"
+	"github.com/Jeffail/benthos/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/throttle"
@@ -53,6 +58,41 @@ which is enabled when key and cert files are specified.
 You can leave the 'address' config field blank in order to use the instance wide
 HTTP server.
 
+### Streaming
+
+As well as single requests to ` + "`path`" + `, messages can be streamed over
+long-lived connections. Clients can open a websocket connection at
+` + "`ws_path`" + ` where each websocket message becomes a Benthos message, or
+send a POST request of any length to ` + "`stream_path`" + ` (typically with a
+chunked transfer encoding), where each line of the body becomes a message.
+
+Messages from a streaming connection are sent one at a time, and the next
+message is not read from the connection until the previous one has been
+successfully delivered, which applies back pressure to the client. Failed
+messages are retried with an increasing back off. Once the body of a streamed
+POST request ends the server responds with a 200 status code.
+
+### Synchronous Responses
+
+By default a request to ` + "`path`" + ` receives an empty 200 response once
+its message has been delivered. When ` + "`sync_response`" + ` is set to
+` + "`true`" + ` the response is instead made of any results passed back by
+outputs, such as the responses captured by an ` + "`http_client`" + ` output
+with ` + "`propagate_response`" + ` enabled.
+
+A single result part is written as the response body, and multiple parts are
+written as a ` + "`multipart/mixed`" + ` body. The status code and content type
+of the response are taken from the metadata fields ` + "`http_status_code`" + `
+and ` + "`Content-Type`" + ` of the first part. If no results are passed back
+then an empty 200 response is returned.
+
+Results are tracked by the message parts themselves rather than their metadata,
+and are therefore not passed back for parts that processors replace with new
+parts, such as those created by the ` + "`archive`" + ` processor. Results are
+only passed back when the message is delivered to the output before the request
+is acknowledged, meaning pipelines with buffers do not support synchronous
+responses.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -72,23 +112,27 @@ You can access these metadata fields using
 
 // HTTPServerConfig contains configuration for the HTTPServer input type.
 type HTTPServerConfig struct {
-	Address  string `json:"address" yaml:"address"`
-	Path     string `json:"path" yaml:"path"`
-	WSPath   string `json:"ws_path" yaml:"ws_path"`
-	Timeout  string `json:"timeout" yaml:"timeout"`
-	CertFile string `json:"cert_file" yaml:"cert_file"`
-	KeyFile  string `json:"key_file" yaml:"key_file"`
+	Address      string `json:"address" yaml:"address"`
+	Path         string `json:"path" yaml:"path"`
+	WSPath       string `json:"ws_path" yaml:"ws_path"`
+	StreamPath   string `json:"stream_path" yaml:"stream_path"`
+	Timeout      string `json:"timeout" yaml:"timeout"`
+	CertFile     string `json:"cert_file" yaml:"cert_file"`
+	KeyFile      string `json:"key_file" yaml:"key_file"`
+	SyncResponse bool   `json:"sync_response" yaml:"sync_response"`
 }
 
 // NewHTTPServerConfig creates a new HTTPServerConfig with default values.
 func NewHTTPServerConfig() HTTPServerConfig {
 	return HTTPServerConfig{
-		Address:  "",
-		Path:     "/post",
-		WSPath:   "/post/ws",
-		Timeout:  "5s",
-		CertFile: "",
-		KeyFile:  "",
+		Address:      "",
+		Path:         "/post",
+		WSPath:       "/post/ws",
+		StreamPath:   "/post/stream",
+		Timeout:      "5s",
+		CertFile:     "",
+		KeyFile:      "",
+		SyncResponse: false,
 	}
 }
 
@@ -115,18 +159,21 @@ type HTTPServer struct {
 	closeChan  chan struct{}
 	closedChan chan struct{}
 
-	mCount      metrics.StatCounter
-	mPartsCount metrics.StatCounter
-	mRcvd       metrics.StatCounter
-	mPartsRcvd  metrics.StatCounter
-	mWSCount    metrics.StatCounter
-	mTimeout    metrics.StatCounter
-	mErr        metrics.StatCounter
-	mWSErr      metrics.StatCounter
-	mSucc       metrics.StatCounter
-	mWSSucc     metrics.StatCounter
-	mAsyncErr   metrics.StatCounter
-	mAsyncSucc  metrics.StatCounter
+	mCount       metrics.StatCounter
+	mPartsCount  metrics.StatCounter
+	mRcvd        metrics.StatCounter
+	mPartsRcvd   metrics.StatCounter
+	mWSCount     metrics.StatCounter
+	mStreamCount metrics.StatCounter
+	mTimeout     metrics.StatCounter
+	mErr         metrics.StatCounter
+	mWSErr       metrics.StatCounter
+	mSucc        metrics.StatCounter
+	mWSSucc      metrics.StatCounter
+	mStreamErr   metrics.StatCounter
+	mStreamSucc  metrics.StatCounter
+	mAsyncErr    metrics.StatCounter
+	mAsyncSucc   metrics.StatCounter
 }
 
 // NewHTTPServer creates a new HTTPServer input type.
@@ -159,23 +206,29 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		closeChan:    make(chan struct{}),
 		closedChan:   make(chan struct{}),
 
-		mCount:      stats.GetCounter("count"),
-		mPartsCount: stats.GetCounter("parts.count"),
-		mRcvd:       stats.GetCounter("batch.received"),
-		mPartsRcvd:  stats.GetCounter("received"),
-		mWSCount:    stats.GetCounter("ws.count"),
-		mTimeout:    stats.GetCounter("send.timeout"),
-		mErr:        stats.GetCounter("send.error"),
-		mWSErr:      stats.GetCounter("ws.send.error"),
-		mSucc:       stats.GetCounter("send.success"),
-		mWSSucc:     stats.GetCounter("ws.send.success"),
-		mAsyncErr:   stats.GetCounter("send.async_error"),
-		mAsyncSucc:  stats.GetCounter("send.async_success"),
+		mCount:       stats.GetCounter("count"),
+		mPartsCount:  stats.GetCounter("parts.count"),
+		mRcvd:        stats.GetCounter("batch.received"),
+		mPartsRcvd:   stats.GetCounter("received"),
+		mWSCount:     stats.GetCounter("ws.count"),
+		mStreamCount: stats.GetCounter("stream.count"),
+		mTimeout:     stats.GetCounter("send.timeout"),
+		mErr:         stats.GetCounter("send.error"),
+		mWSErr:       stats.GetCounter("ws.send.error"),
+		mSucc:        stats.GetCounter("send.success"),
+		mWSSucc:      stats.GetCounter("ws.send.success"),
+		mStreamErr:   stats.GetCounter("stream.send.error"),
+		mStreamSucc:  stats.GetCounter("stream.send.success"),
+		mAsyncErr:    stats.GetCounter("send.async_error"),
+		mAsyncSucc:   stats.GetCounter("send.async_success"),
 	}
 
 	if mux != nil {
 		mux.HandleFunc(h.conf.HTTPServer.Path, h.postHandler)
 		mux.HandleFunc(h.conf.HTTPServer.WSPath, h.wsHandler)
+		if len(h.conf.HTTPServer.StreamPath) > 0 {
+			mux.HandleFunc(h.conf.HTTPServer.StreamPath, h.streamHandler)
+		}
 	} else {
 		mgr.RegisterEndpoint(
 			h.conf.HTTPServer.Path, "Post a message into Benthos.", h.postHandler,
@@ -183,6 +236,11 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 		mgr.RegisterEndpoint(
 			h.conf.HTTPServer.WSPath, "Post messages via websocket into Benthos.", h.wsHandler,
 		)
+		if len(h.conf.HTTPServer.StreamPath) > 0 {
+			mgr.RegisterEndpoint(
+				h.conf.HTTPServer.StreamPath, "Stream line delimited messages into Benthos.", h.streamHandler,
+			)
+		}
 	}
 
 	go h.loop()
@@ -191,6 +249,75 @@ func NewHTTPServer(conf Config, mgr types.Manager, log log.Modular, stats metric
 
 //------------------------------------------------------------------------------
 
+// setRequestMetadata adds the user agent, headers and cookies of a request to
+// metadata.
+func setRequestMetadata(meta types.Metadata, r *http.Request) {
+	meta.Set("http_server_user_agent", r.UserAgent())
+	for k, v := range r.Header {
+		if len(v) > 0 {
+			meta.Set(k, v[0])
+		}
+	}
+	for _, c := range r.Cookies() {
+		meta.Set(c.Name, c.Value)
+	}
+}
+
+// writeSyncResponse writes the results of a request as its response. A single
+// result part is written as the body, whereas multiple parts are written as a
+// multipart body. The status code and content type are taken from the
+// metadata of the first part.
+func writeSyncResponse(w http.ResponseWriter, results []types.Message) error {
+	var parts []types.Part
+	for _, msg := range results {
+		msg.Iter(func(i int, p types.Part) error {
+			parts = append(parts, p)
+			return nil
+		})
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	status := http.StatusOK
+	if code, err := strconv.Atoi(parts[0].Metadata().Get("http_status_code")); err == nil {
+		status = code
+	}
+
+	if len(parts) == 1 {
+		if contentType := parts[0].Metadata().Get("Content-Type"); len(contentType) > 0 {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.WriteHeader(status)
+		_, err := w.Write(parts[0].Get())
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	writer := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		if contentType := p.Metadata().Get("Content-Type"); len(contentType) > 0 {
+			header.Set("Content-Type", contentType)
+		}
+		pw, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err = pw.Write(p.Get()); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
 func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -247,15 +374,7 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	meta := metadata.New(nil)
-	meta.Set("http_server_user_agent", r.UserAgent())
-	for k, v := range r.Header {
-		if len(v) > 0 {
-			meta.Set(k, v[0])
-		}
-	}
-	for _, c := range r.Cookies() {
-		meta.Set(c.Name, c.Value)
-	}
+	setRequestMetadata(meta, r)
 	message.SetAllMetadata(msg, meta)
 
 	h.mCount.Incr(1)
@@ -264,6 +383,12 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 	h.mPartsRcvd.Incr(int64(msg.Len()))
 	h.mRcvd.Incr(1)
 
+	var store roundtrip.ResultStore
+	if h.conf.HTTPServer.SyncResponse {
+		store = roundtrip.NewResultStore()
+		roundtrip.SetResultStore(msg, store)
+	}
+
 	resChan := make(chan types.Response)
 	select {
 	case h.transactions <- types.NewTransaction(msg, resChan):
@@ -287,6 +412,11 @@ func (h *HTTPServer) postHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.mSucc.Incr(1)
+		if store != nil {
+			if err := writeSyncResponse(w, store.Get()); err != nil {
+				h.log.Errorf("Failed to write sync response: %v\n", err)
+			}
+		}
 	case <-time.After(h.timeout):
 		h.mTimeout.Incr(1)
 		http.Error(w, "Request timed out", http.StatusRequestTimeout)
@@ -337,17 +467,7 @@ func (h *HTTPServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		msg := message.New([][]byte{msgBytes})
-
-		meta := msg.Get(0).Metadata()
-		meta.Set("http_server_user_agent", r.UserAgent())
-		for k, v := range r.Header {
-			if len(v) > 0 {
-				meta.Set(k, v[0])
-			}
-		}
-		for _, c := range r.Cookies() {
-			meta.Set(c.Name, c.Value)
-		}
+		setRequestMetadata(msg.Get(0).Metadata(), r)
 
 		select {
 		case h.transactions <- types.NewTransaction(msg, resChan):
@@ -375,6 +495,85 @@ func (h *HTTPServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *HTTPServer) streamHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if atomic.LoadInt32(&h.running) != 1 {
+		http.Error(w, "Server closing", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 1024*1024*64)
+
+	resChan := make(chan types.Response)
+	throt := throttle.New(throttle.OptCloseChan(h.closeChan))
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		h.mStreamCount.Incr(1)
+		h.mCount.Incr(1)
+		h.mPartsCount.Incr(1)
+		h.mRcvd.Incr(1)
+		h.mPartsRcvd.Incr(1)
+
+		msgBytes := append([]byte(nil), scanner.Bytes()...)
+		for {
+			if atomic.LoadInt32(&h.running) != 1 {
+				http.Error(w, "Server closing", http.StatusServiceUnavailable)
+				return
+			}
+
+			msg := message.New([][]byte{msgBytes})
+			setRequestMetadata(msg.Get(0).Metadata(), r)
+
+			select {
+			case h.transactions <- types.NewTransaction(msg, resChan):
+			case <-h.closeChan:
+				http.Error(w, "Server closing", http.StatusServiceUnavailable)
+				return
+			}
+
+			var res types.Response
+			var open bool
+			select {
+			case res, open = <-resChan:
+				if !open {
+					http.Error(w, "Server closing", http.StatusServiceUnavailable)
+					return
+				}
+			case <-h.closeChan:
+				http.Error(w, "Server closing", http.StatusServiceUnavailable)
+				return
+			}
+			if res.Error() == nil {
+				h.mStreamSucc.Incr(1)
+				h.mSucc.Incr(1)
+				throt.Reset()
+				break
+			}
+			h.mStreamErr.Incr(1)
+			h.mErr.Incr(1)
+			if !throt.Retry() {
+				http.Error(w, "Server closing", http.StatusServiceUnavailable)
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		h.log.Warnf("Stream request read failed: %v\n", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
 //------------------------------------------------------------------------------
 
 func (h *HTTPServer) loop() {
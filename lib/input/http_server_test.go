@@ -22,12 +22,19 @@ package input
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"testing"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/response"
 	"github.com/Jeffail/benthos/lib/types"
@@ -209,3 +216,233 @@ func TestHTTPTimeout(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHTTPStream(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1244"
+	conf.HTTPServer.StreamPath = "/teststream"
+
+	h, err := NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.CloseAsync()
+		if err := h.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	<-time.After(time.Millisecond * 500)
+
+	bodyReader, bodyWriter := io.Pipe()
+	resChan := make(chan *http.Response)
+	go func() {
+		res, err := http.Post("http://localhost:1244/teststream", "text/plain", bodyReader)
+		if err != nil {
+			t.Error(err)
+			close(resChan)
+			return
+		}
+		resChan <- res
+	}()
+
+	readTran := func() types.Transaction {
+		select {
+		case ts := <-h.TransactionChan():
+			return ts
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for message")
+		}
+		return types.Transaction{}
+	}
+	respond := func(ts types.Transaction, res types.Response) {
+		select {
+		case ts.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for response")
+		}
+	}
+
+	go func() {
+		bodyWriter.Write([]byte("first\nsec"))
+		bodyWriter.Write([]byte("ond\n\nthird"))
+		bodyWriter.Close()
+	}()
+
+	ts := readTran()
+	if exp, act := "first", string(ts.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	respond(ts, response.NewAck())
+
+	ts = readTran()
+	if exp, act := "second", string(ts.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	// A failed message should be resent before the next is read.
+	respond(ts, response.NewError(errors.New("nope")))
+	ts = readTran()
+	if exp, act := "second", string(ts.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	respond(ts, response.NewAck())
+
+	ts = readTran()
+	if exp, act := "third", string(ts.Payload.Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	respond(ts, response.NewAck())
+
+	select {
+	case res, open := <-resChan:
+		if !open {
+			return
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("Wrong status code: %v", res.StatusCode)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for stream response")
+	}
+}
+
+func TestHTTPSyncResponse(t *testing.T) {
+	t.Parallel()
+
+	conf := NewConfig()
+	conf.HTTPServer.Address = "localhost:1245"
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.SyncResponse = true
+
+	h, err := NewHTTPServer(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		h.CloseAsync()
+		if err := h.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	<-time.After(time.Millisecond * 500)
+
+	type result struct {
+		res  *http.Response
+		body []byte
+		err  error
+	}
+	post := func(body string) <-chan result {
+		resChan := make(chan result, 1)
+		go func() {
+			res, err := http.Post(
+				"http://localhost:1245/testpost",
+				"application/octet-stream",
+				bytes.NewBuffer([]byte(body)),
+			)
+			if err != nil {
+				resChan <- result{err: err}
+				return
+			}
+			defer res.Body.Close()
+			resBody, err := ioutil.ReadAll(res.Body)
+			resChan <- result{res: res, body: resBody, err: err}
+		}()
+		return resChan
+	}
+
+	// Single part result.
+	resChan := post("foo")
+	var ts types.Transaction
+	select {
+	case ts = <-h.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	resPart := message.NewPart([]byte("foo result"))
+	resPart.Metadata().Set("http_status_code", "201").Set("Content-Type", "text/plain")
+	resMsg := message.New(nil)
+	resMsg.Append(resPart)
+	if exp, act := 1, roundtrip.AddResults(ts.Payload, resMsg); exp != act {
+		t.Errorf("Wrong count of result stores: %v != %v", act, exp)
+	}
+	ts.ResponseChan <- response.NewAck()
+
+	res := <-resChan
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if exp, act := 201, res.res.StatusCode; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	if exp, act := "text/plain", res.res.Header.Get("Content-Type"); exp != act {
+		t.Errorf("Wrong content type: %v != %v", act, exp)
+	}
+	if exp, act := "foo result", string(res.body); exp != act {
+		t.Errorf("Wrong body: %v != %v", act, exp)
+	}
+
+	// Multiple part result.
+	resChan = post("bar")
+	select {
+	case ts = <-h.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	roundtrip.AddResults(ts.Payload, message.New([][]byte{[]byte("first")}))
+	roundtrip.AddResults(ts.Payload, message.New([][]byte{[]byte("second")}))
+	ts.ResponseChan <- response.NewAck()
+
+	if res = <-resChan; res.err != nil {
+		t.Fatal(res.err)
+	}
+	if exp, act := 200, res.res.StatusCode; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	mediaType, params, err := mime.ParseMediaType(res.res.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "multipart/mixed", mediaType; exp != act {
+		t.Errorf("Wrong media type: %v != %v", act, exp)
+	}
+	var parts []string
+	mr := multipart.NewReader(bytes.NewReader(res.body), params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(p)
+		parts = append(parts, string(b))
+	}
+	if exp, act := "[first second]", fmt.Sprintf("%v", parts); exp != act {
+		t.Errorf("Wrong parts: %v != %v", act, exp)
+	}
+
+	// No results.
+	resChan = post("baz")
+	select {
+	case ts = <-h.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	ts.ResponseChan <- response.NewAck()
+
+	if res = <-resChan; res.err != nil {
+		t.Fatal(res.err)
+	}
+	if exp, act := 200, res.res.StatusCode; exp != act {
+		t.Errorf("Wrong status code: %v != %v", act, exp)
+	}
+	if exp, act := "", string(res.body); exp != act {
+		t.Errorf("Wrong body: %v != %v", act, exp)
+	}
+}
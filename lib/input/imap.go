@@ -0,0 +1,88 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeIMAP] = TypeSpec{
+		constructor: NewIMAP,
+		description: `
+Polls an IMAP mailbox for emails matching a search criteria and emits each
+email as a message. The body of an email and each of its attachments are
+emitted as individual message parts, nested multipart bodies are flattened.
+
+The ` + "`search`" + ` field is an IMAP search criteria such as
+` + "`UNSEEN`" + `, ` + "`ALL`" + ` or ` + "`FROM \"foo@example.com\" UNSEEN`" + `.
+The mailbox is searched again every ` + "`poll_interval`" + ` once all
+previously found emails have been consumed.
+
+Once an email has been successfully delivered it is flagged as seen when
+` + "`mark_seen`" + ` is true, and moved to the mailbox ` + "`move_to`" + `
+when set. If neither is configured then emails matching the search criteria will
+be consumed repeatedly.
+
+` + tls.Documentation + `
+
+### Metadata
+
+This input adds the following metadata fields to each message part:
+
+` + "```" + `
+- imap_uid
+- imap_mailbox
+- imap_header_<name> (for each header, e.g. imap_header_subject)
+- imap_part_content_type
+- imap_part_filename (attachments only)
+` + "```" + `
+
+Header names are lower cased with hyphens replaced by underscores, and encoded
+words are decoded.
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewIMAP creates a new IMAP input type.
+func NewIMAP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	i, err := reader.NewIMAP(conf.IMAP, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"imap",
+		reader.NewPreserver(i),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,82 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJournald] = TypeSpec{
+		constructor: NewJournald,
+		description: `
+Reads entries from the systemd journal by following the JSON output of
+` + "`journalctl`" + `, which must be installed on the host. Each entry is
+emitted as a JSON object containing all of its fields.
+
+Entries can be filtered by ` + "`units`" + `, where an entry matching any of
+the listed units is consumed, and by ` + "`priority`" + `, which is either a
+single level (e.g. ` + "`warning`" + ` or ` + "`4`" + `) that also includes
+more severe levels, or a range (e.g. ` + "`err..info`" + `).
+
+When ` + "`cursor_path`" + ` is set the cursor of the last acknowledged entry is
+periodically written to that file, and consumption resumes after it when the
+input restarts. Without a cursor only new entries are consumed unless
+` + "`start_from_oldest`" + ` is true.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- journald_cursor
+- journald_unit
+- journald_priority
+- journald_hostname
+- journald_timestamp
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewJournald creates a new journald input type.
+func NewJournald(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	j, err := reader.NewJournald(conf.Journald, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"journald",
+		reader.NewPreserver(j),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -43,6 +43,16 @@ The field ` + "`max_batch_count`" + ` specifies the maximum number of prefetched
 messages to be batched together. When more than one message is batched they can
 be split into individual messages with the ` + "`split`" + ` processor.
 
+By default consumption begins from the offset committed for the consumer group,
+falling back to the oldest or newest offset according to
+` + "`start_from_oldest`" + `. An explicit start position can instead be set
+with either ` + "`start_offset`" + `, which is an offset of the partition, or
+` + "`start_timestamp`" + `, an RFC 3339 timestamp from which the earliest
+message at or after it is consumed (version 0.10.1+). An explicit start position
+is only used when the input first connects, after which offsets are committed
+and resumed from as usual. Set ` + "`start_offset`" + ` to -1 in order to
+disable it.
+
 The target version by default will be the oldest supported, as it is expected
 that the server will be backwards compatible. In order to support newer client
 features you should increase this version up to the known version of the target
@@ -34,9 +34,21 @@ func init() {
 	Constructors[TypeKafkaBalanced] = TypeSpec{
 		constructor: NewKafkaBalanced,
 		description: `
-Connects to a kafka (0.9+) server. Offsets are managed within kafka as per the
+Connects to a kafka (0.10.2+) server, and requires a ` + "`target_version`" + `
+of at least ` + "`0.10.2.0`" + `. Offsets are managed within kafka as per the
 consumer group (set via config), and partitions are automatically balanced
-across any members of the consumer group.
+across any members of the consumer group. Scaling the number of consumers up or
+down results in the group being rebalanced automatically.
+
+Offsets are only marked for commit once a message has been acknowledged by the
+output. When a rebalance revokes a partition from this consumer any pending
+offsets of that partition are dropped, which means unacknowledged messages will
+be redelivered to whichever consumer claims the partition next.
+
+The ` + "`group`" + ` fields control the session timeout and heartbeat interval
+of the consumer group membership, as well as the strategy used to assign
+partitions to members, which can be either ` + "`range`" + ` or
+` + "`roundrobin`" + `.
 
 The field ` + "`max_batch_count`" + ` specifies the maximum number of prefetched
 messages to be batched together. When more than one message is batched they can
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeKinesisBalanced] = TypeSpec{
+		constructor: NewKinesisBalanced,
+		description: `
+Receives messages from all shards of a Kinesis stream, where shards are
+automatically balanced across any number of clients sharing the same
+` + "`client_id`" + `, similar to the Kinesis Client Library.
+
+Clients coordinate by holding leases on shards within a DynamoDB table, which
+should be created with ` + "`namespace`" + ` as the primary key and
+` + "`shard_id`" + ` as a sort key (both strings). This is the same table layout
+as the ` + "`kinesis`" + ` input, and therefore checkpoints are shared between
+the two.
+
+Every ` + "`rebalance_period`" + ` the shards of the stream are listed, owned
+leases are renewed and further leases are claimed until this client owns its
+share of the shards. Leases that are not renewed within the
+` + "`lease_period`" + ` expire and are claimed by other clients, and when no
+free shards remain a client takes a lease from the client that owns the most.
+
+When a stream is resharded the child shards are only consumed once all records
+of their parents have been consumed, and are always read from their oldest
+record.
+
+Sequence numbers are only checkpointed once messages have been acknowledged by
+the output, and are written at most once per ` + "`commit_period`" + `. When
+a lease moves to another client unacknowledged messages are consumed again by
+the new owner.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- kinesis_shard
+- kinesis_stream
+- kinesis_partition_key
+- kinesis_sequence_number
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewKinesisBalanced creates a new AWS Kinesis input type that balances shards
+// across clients.
+func NewKinesisBalanced(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	k, err := reader.NewKinesisBalanced(conf.KinesisBalanced, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"kinesis_balanced",
+		reader.NewPreserver(k),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
 )
 
 //------------------------------------------------------------------------------
@@ -33,7 +34,26 @@ func init() {
 	Constructors[TypeMQTT] = TypeSpec{
 		constructor: NewMQTT,
 		description: `
-Subscribe to topics on MQTT brokers.
+Subscribe to topics on MQTT brokers. Topics can contain wildcard filters, and
+all topics are subscribed to with the configured QoS level.
+
+Messages are consumed one at a time, and the input does not take delivery of
+the next message until the current one has been propagated, therefore back
+pressure is applied to the broker. Messages that fail to be delivered are
+retried until successful.
+
+Messages received with QoS 1 or 2 are only acknowledged with the broker once
+they have been successfully propagated to the output. Messages that are not yet
+acknowledged when the connection is lost are redelivered by the broker once it
+is reestablished, provided that the session is persistent.
+
+When ` + "`clean_session`" + ` is set to ` + "`false`" + ` a persistent session
+is established with the broker using the configured ` + "`client_id`" + `, and
+messages published with QoS 1 or 2 during a disconnect are delivered once the
+connection is reestablished. The connection is reestablished automatically and
+topic subscriptions are renewed each time.
+
+` + tls.Documentation + `
 
 ### Metadata
 
@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMySQLBinlog] = TypeSpec{
+		constructor: NewMySQLBinlog,
+		description: `
+Connects to a MySQL (5.6+) server as a replica and streams row changes from its
+binary log, which must use the ` + "`ROW`" + ` format. Each changed row is
+emitted as a JSON document of the form:
+
+` + "``` json" + `
+{
+  "operation": "update",
+  "schema": "shop",
+  "table": "users",
+  "gtid": "3e11fa47-71ca-11e1-9e33-c80aa9429562:23",
+  "timestamp": 1550000000,
+  "before": {"id": 1, "name": "foo"},
+  "after": {"id": 1, "name": "bar"}
+}
+` + "```" + `
+
+Inserts only have an ` + "`after`" + ` image and deletes only have a
+` + "`before`" + ` image. Column names are read from
+` + "`information_schema`" + `, and so the user requires the privileges
+` + "`REPLICATION SLAVE`" + `, ` + "`REPLICATION CLIENT`" + ` and
+` + "`SELECT`" + ` on the tables captured. The ` + "`server_id`" + ` must be
+unique amongst all replicas of the server.
+
+The field ` + "`tables`" + ` can be used to restrict changes to a list of tables
+of the form ` + "`schema.table`" + `, when empty all tables are captured.
+
+### Checkpointing
+
+When ` + "`use_gtid`" + ` is true the position of the input is tracked as a set
+of global transaction identifiers, which requires GTIDs to be enabled on the
+server, otherwise the binary log file and position are used.
+
+The position only advances once all changes of a transaction have been
+acknowledged by the output, and if ` + "`checkpoint_path`" + ` is set it is
+persisted to that file so that streaming resumes from it after a restart. A
+transaction that was partially delivered before a restart is delivered again
+in full. Without a checkpoint streaming begins from the current position of the
+server.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- mysql_schema
+- mysql_table
+- mysql_operation
+- mysql_gtid
+- mysql_binlog_file
+- mysql_binlog_pos
+- mysql_timestamp_unix
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewMySQLBinlog creates a new MySQLBinlog input type.
+func NewMySQLBinlog(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	m, err := reader.NewMySQLBinlog(conf.MySQLBinlog, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("mysql_binlog", reader.NewPreserver(m), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeNATSJetStream] = TypeSpec{
+		constructor: NewNATSJetStream,
+		description: `
+Consumes messages from a NATS JetStream stream through a durable pull consumer,
+which is at-least-once. The consumer is created if it does not already exist,
+and since its progress is tracked by the server a restarted Benthos instance
+resumes from the last acknowledged message.
+
+Messages are acknowledged explicitly once they have been successfully
+propagated to the output. Messages that fail to be delivered are negatively
+acknowledged and are redelivered by the server, if not acknowledged within
+` + "`ack_wait`" + ` they are also redelivered.
+
+The field ` + "`backoff`" + ` optionally specifies a list of durations to wait
+between each redelivery of a message, when set ` + "`max_deliver`" + ` must be
+greater than the number of backoff durations. A ` + "`max_deliver`" + ` of -1
+means messages are redelivered indefinitely.
+
+The field ` + "`subject`" + ` optionally filters the subjects of the stream
+that are consumed. If the durable consumer already exists with a different
+configuration the input fails to connect.
+
+Each pull request waits for up to ` + "`pull_timeout`" + ` for a message, and
+expires on the server slightly before that so that a pending request is never
+left behind to consume a message after the input has given up waiting on it.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- nats_jetstream_subject
+- nats_jetstream_stream
+- nats_jetstream_consumer
+- nats_jetstream_num_delivered
+- nats_jetstream_sequence_stream
+- nats_jetstream_sequence_consumer
+- nats_jetstream_timestamp_unix_nano
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewNATSJetStream creates a new NATSJetStream input type.
+func NewNATSJetStream(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	n, err := reader.NewNATSJetStream(conf.NATSJetStream, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("nats_jetstream", n, log, stats)
+}
+
+//------------------------------------------------------------------------------
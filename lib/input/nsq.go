@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
 )
 
 //------------------------------------------------------------------------------
@@ -33,7 +34,19 @@ func init() {
 	Constructors[TypeNSQ] = TypeSpec{
 		constructor: NewNSQ,
 		description: `
-Subscribe to an NSQ instance topic and channel.`,
+Subscribe to an NSQ instance topic and channel.
+
+The field ` + "`max_in_flight`" + ` sets the maximum number of messages that
+may be outstanding with the consumer at any time. Setting
+` + "`sample_rate`" + ` to a value between 1 and 99 instructs nsqd to deliver
+only that percentage of messages to this consumer, and setting
+` + "`channel_ephemeral`" + ` to true causes the channel to be removed once
+all consumers have disconnected.
+
+If the nsqd instances require authorisation an ` + "`auth_secret`" + ` can be
+provided, and TLS can be enabled with the ` + "`tls`" + ` fields.
+
+` + tls.Documentation,
 	}
 }
 
@@ -0,0 +1,62 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeParquet] = TypeSpec{
+		constructor: NewParquet,
+		description: `
+Reads the rows of Parquet files from the local filesystem and emits each row as
+a JSON document, where the keys are column names. Each path can be a glob
+pattern (e.g. ` + "`/data/events/*.parquet`" + `), and files are read in order,
+one row group at a time. Once all files have been consumed the input closes.
+
+Only flat schemas are supported, files containing nested or repeated columns
+are skipped with an error. Columns may use the PLAIN or dictionary encodings
+and be uncompressed or compressed with snappy or gzip.
+
+String columns are emitted as strings, other byte array columns are emitted as
+base64 encoded strings and INT96 timestamps are emitted as RFC 3339 strings.
+Null values of optional columns are emitted as ` + "`null`" + `.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewParquet creates a new Parquet input type.
+func NewParquet(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	p, err := reader.NewParquet(conf.Parquet, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("parquet", reader.NewPreserver(p), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,100 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypePostgresCDC] = TypeSpec{
+		constructor: NewPostgresCDC,
+		description: `
+Streams row changes from a PostgreSQL (10+) logical replication slot. Each
+insert, update, delete and truncate is emitted as a JSON document of the form:
+
+` + "``` json" + `
+{
+  "operation": "update",
+  "schema": "public",
+  "table": "users",
+  "xid": 571,
+  "lsn": "0/16B3748",
+  "data": {"id": 1, "name": "foo"},
+  "old": {"id": 1}
+}
+` + "```" + `
+
+The ` + "`old`" + ` field is only populated for updates and deletes, and its
+contents depend on the replica identity of the table.
+
+The ` + "`plugin`" + ` field selects the logical decoding output plugin, which
+can be either ` + "`pgoutput`" + ` (built into Postgres) or
+` + "`wal2json`" + `. When using ` + "`pgoutput`" + ` a publication must be
+created for the tables to be captured, e.g.
+` + "`CREATE PUBLICATION benthos FOR ALL TABLES`" + `, and its name set in the
+field ` + "`publication`" + `.
+
+When ` + "`create_slot`" + ` is true the replication slot is created if it
+does not already exist. The user of the connection requires the
+` + "`REPLICATION`" + ` attribute.
+
+The position of the slot is only advanced to the end of a transaction once all
+of its changes have been acknowledged by the output, and is reported to the
+server periodically at the interval ` + "`status_interval`" + `. If the service
+restarts, or the delivery of a change fails, the stream resumes from the last
+confirmed position, which may result in a partially delivered transaction being
+delivered again.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- postgres_lsn
+- postgres_schema
+- postgres_table
+- postgres_operation
+- postgres_xid
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewPostgresCDC creates a new PostgresCDC input type.
+func NewPostgresCDC(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	p, err := reader.NewPostgresCDC(conf.PostgresCDC, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("postgres_cdc", reader.NewPreserver(p), log, stats)
+}
+
+//------------------------------------------------------------------------------
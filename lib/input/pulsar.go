@@ -0,0 +1,82 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypePulsar] = TypeSpec{
+		constructor: NewPulsar,
+		description: `
+Consumes messages from an Apache Pulsar topic through the WebSocket API of a
+Pulsar broker or proxy, where ` + "`url`" + ` is the WebSocket service URL
+(use a ` + "`wss://`" + ` scheme along with the ` + "`tls`" + ` fields for
+TLS connections).
+
+The field ` + "`subscription_type`" + ` can be one of ` + "`exclusive`" + `,
+` + "`shared`" + `, ` + "`failover`" + ` or ` + "`key_shared`" + `. When
+` + "`auth_token`" + ` is set it is sent as a bearer token in order to
+authenticate with the broker.
+
+Messages are acknowledged once they have been successfully propagated to the
+output. Messages that fail to be delivered are negatively acknowledged and are
+redelivered by the broker after ` + "`negative_ack_redelivery_delay`" + `.
+
+` + tls.Documentation + `
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- pulsar_message_id
+- pulsar_key
+- pulsar_publish_time
+- pulsar_redelivery_count
+- pulsar_topic
+- All message properties
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewPulsar creates a new Pulsar input type.
+func NewPulsar(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	p, err := reader.NewPulsar(conf.Pulsar, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("pulsar", p, log, stats)
+}
+
+//------------------------------------------------------------------------------
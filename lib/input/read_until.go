@@ -50,6 +50,14 @@ type reaches the end of a file it will shut down. By default this type will also
 shut down. If you wish for the input type to be restarted every time it shuts
 down until the condition is met then set ` + "`restart_input` to `true`." + `
 
+In order to stop after a fixed number of messages use a
+[` + "`count`" + ` condition](../conditions/README.md#count).
+
+If ` + "`idle_timeout`" + ` is set to a duration string (e.g. ` + "`30s`" + `)
+then the input will also shut down when no message has been received from the
+child input for that length of time. This is useful for batch style invocations
+of Benthos, where a source should be drained before the process exits.
+
 ### Metadata
 
 A metadata key ` + "`benthos_read_until` containing the value `final`" + ` is
@@ -69,6 +77,7 @@ added to the first part of the message that triggers to input to stop.`,
 				"input":         inputSanit,
 				"restart_input": conf.ReadUntil.Restart,
 				"condition":     condSanit,
+				"idle_timeout":  conf.ReadUntil.IdleTimeout,
 			}, nil
 		},
 	}
@@ -78,34 +87,38 @@ added to the first part of the message that triggers to input to stop.`,
 
 // ReadUntilConfig contains configuration values for the ReadUntil input type.
 type ReadUntilConfig struct {
-	Input     *Config          `json:"input" yaml:"input"`
-	Restart   bool             `json:"restart_input" yaml:"restart_input"`
-	Condition condition.Config `json:"condition" yaml:"condition"`
+	Input       *Config          `json:"input" yaml:"input"`
+	Restart     bool             `json:"restart_input" yaml:"restart_input"`
+	Condition   condition.Config `json:"condition" yaml:"condition"`
+	IdleTimeout string           `json:"idle_timeout" yaml:"idle_timeout"`
 }
 
 // NewReadUntilConfig creates a new ReadUntilConfig with default values.
 func NewReadUntilConfig() ReadUntilConfig {
 	return ReadUntilConfig{
-		Input:     nil,
-		Restart:   false,
-		Condition: condition.NewConfig(),
+		Input:       nil,
+		Restart:     false,
+		Condition:   condition.NewConfig(),
+		IdleTimeout: "",
 	}
 }
 
 //------------------------------------------------------------------------------
 
 type dummyReadUntilConfig struct {
-	Input     interface{}      `json:"input" yaml:"input"`
-	Restart   bool             `json:"restart_input" yaml:"restart_input"`
-	Condition condition.Config `json:"condition" yaml:"condition"`
+	Input       interface{}      `json:"input" yaml:"input"`
+	Restart     bool             `json:"restart_input" yaml:"restart_input"`
+	Condition   condition.Config `json:"condition" yaml:"condition"`
+	IdleTimeout string           `json:"idle_timeout" yaml:"idle_timeout"`
 }
 
 // MarshalJSON prints an empty object instead of nil.
 func (r ReadUntilConfig) MarshalJSON() ([]byte, error) {
 	dummy := dummyReadUntilConfig{
-		Input:     r.Input,
-		Restart:   r.Restart,
-		Condition: r.Condition,
+		Input:       r.Input,
+		Restart:     r.Restart,
+		Condition:   r.Condition,
+		IdleTimeout: r.IdleTimeout,
 	}
 	if r.Input == nil {
 		dummy.Input = struct{}{}
@@ -116,9 +129,10 @@ func (r ReadUntilConfig) MarshalJSON() ([]byte, error) {
 // MarshalYAML prints an empty object instead of nil.
 func (r ReadUntilConfig) MarshalYAML() (interface{}, error) {
 	dummy := dummyReadUntilConfig{
-		Input:     r.Input,
-		Restart:   r.Restart,
-		Condition: r.Condition,
+		Input:       r.Input,
+		Restart:     r.Restart,
+		Condition:   r.Condition,
+		IdleTimeout: r.IdleTimeout,
 	}
 	if r.Input == nil {
 		dummy.Input = struct{}{}
@@ -134,8 +148,9 @@ type ReadUntil struct {
 	running int32
 	conf    ReadUntilConfig
 
-	wrapped Type
-	cond    condition.Type
+	wrapped     Type
+	cond        condition.Type
+	idleTimeout time.Duration
 
 	wrapperMgr   types.Manager
 	wrapperLog   log.Modular
@@ -161,6 +176,14 @@ func NewReadUntil(
 		return nil, errors.New("cannot create read_until input without a child")
 	}
 
+	var idleTimeout time.Duration
+	if tout := conf.ReadUntil.IdleTimeout; len(tout) > 0 {
+		var err error
+		if idleTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse idle timeout string: %v", err)
+		}
+	}
+
 	wrapped, err := New(
 		*conf.ReadUntil.Input, mgr, log, stats,
 	)
@@ -189,6 +212,7 @@ func NewReadUntil(
 		stats:        metrics.Namespaced(stats, "read_until"),
 		wrapped:      wrapped,
 		cond:         cond,
+		idleTimeout:  idleTimeout,
 		transactions: make(chan types.Transaction),
 		closeChan:    make(chan struct{}),
 		closedChan:   make(chan struct{}),
@@ -206,6 +230,7 @@ func (r *ReadUntil) loop() {
 		mRestartErr      = r.stats.GetCounter("restart.error")
 		mRestartSucc     = r.stats.GetCounter("restart.success")
 		mInputClosed     = r.stats.GetCounter("input.closed")
+		mIdleTimeout     = r.stats.GetCounter("idle_timeout")
 		mCount           = r.stats.GetCounter("count")
 		mPropagated      = r.stats.GetCounter("propagated")
 		mFinalPropagated = r.stats.GetCounter("final.propagated")
@@ -248,15 +273,32 @@ runLoop:
 			}
 		}
 
+		var idleChan <-chan time.Time
+		var idleTimer *time.Timer
+		if r.idleTimeout > 0 {
+			idleTimer = time.NewTimer(r.idleTimeout)
+			idleChan = idleTimer.C
+		}
+
 		var tran types.Transaction
 		select {
 		case tran, open = <-r.wrapped.TransactionChan():
+			if idleTimer != nil {
+				idleTimer.Stop()
+			}
 			if !open {
 				mInputClosed.Incr(1)
 				r.wrapped = nil
 				continue runLoop
 			}
+		case <-idleChan:
+			mIdleTimeout.Incr(1)
+			r.log.Infof("No messages received for %v, shutting down.\n", r.idleTimeout)
+			return
 		case <-r.closeChan:
+			if idleTimer != nil {
+				idleTimer.Stop()
+			}
 			return
 		}
 		mCount.Incr(1)
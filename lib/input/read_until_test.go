@@ -379,3 +379,46 @@ func testReadUntilInputCloseRestart(inConf Config, t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestReadUntilIdleTimeout(t *testing.T) {
+	inConf := NewConfig()
+	inConf.Type = TypeGenerate
+	inConf.Generate.Interval = "1h"
+
+	rConf := NewConfig()
+	rConf.Type = TypeReadUntil
+	rConf.ReadUntil.Input = &inConf
+	rConf.ReadUntil.IdleTimeout = "100ms"
+
+	in, err := New(rConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case _, open := <-in.TransactionChan():
+		if open {
+			t.Fatal("transaction chan not closed")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	if err = in.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadUntilBadIdleTimeout(t *testing.T) {
+	inConf := NewConfig()
+	inConf.Type = TypeGenerate
+
+	rConf := NewConfig()
+	rConf.Type = TypeReadUntil
+	rConf.ReadUntil.Input = &inConf
+	rConf.ReadUntil.IdleTimeout = "nope"
+
+	if _, err := New(rConf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad idle timeout")
+	}
+}
@@ -21,12 +21,14 @@
 package input
 
 import (
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/input/reader"
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/throttle"
 )
@@ -40,6 +42,7 @@ type Reader struct {
 
 	typeStr string
 	reader  reader.Type
+	batcher *reader.Batcher
 
 	stats metrics.Type
 	log   log.Modular
@@ -49,11 +52,15 @@ type Reader struct {
 	transactions chan types.Transaction
 	responses    chan types.Response
 
+	startOnce  sync.Once
+	startChan  chan struct{}
 	closeChan  chan struct{}
 	closedChan chan struct{}
 }
 
-// NewReader creates a new Reader input type.
+// NewReader creates a new Reader input type. The reader is connected to
+// immediately, but messages are only read once the transaction channel of the
+// input has been requested.
 func NewReader(
 	typeStr string,
 	r reader.Type,
@@ -68,6 +75,7 @@ func NewReader(
 		stats:        stats,
 		transactions: make(chan types.Transaction),
 		responses:    make(chan types.Response),
+		startChan:    make(chan struct{}),
 		closeChan:    make(chan struct{}),
 		closedChan:   make(chan struct{}),
 	}
@@ -78,6 +86,25 @@ func NewReader(
 	return rdr, nil
 }
 
+// batch wraps the reader with a reader.Batcher, which combines messages into
+// batches before they are sent and flushes pending batches once their period
+// has passed, even when the source is idle. Messages are not read until the
+// transaction channel is requested, and therefore this must be called before
+// then.
+func (r *Reader) batch(
+	conf processor.BatchConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) error {
+	batcher, err := reader.NewBatcher(conf, r.reader, mgr, log, stats)
+	if err != nil {
+		return err
+	}
+	r.batcher = batcher
+	return nil
+}
+
 //------------------------------------------------------------------------------
 
 func (r *Reader) loop() {
@@ -130,8 +157,21 @@ func (r *Reader) loop() {
 	mConn.Incr(1)
 	atomic.StoreInt32(&r.connected, 1)
 
+	// Wait until our transactions are being consumed, at which point the
+	// reader can no longer be modified.
+	select {
+	case <-r.startChan:
+	case <-r.closeChan:
+		return
+	}
+
+	var rdr reader.Type = r.reader
+	if r.batcher != nil {
+		rdr = r.batcher
+	}
+
 	for atomic.LoadInt32(&r.running) == 1 {
-		msg, err := r.reader.Read()
+		msg, err := rdr.Read()
 
 		// If our reader says it is not connected.
 		if err == types.ErrNotConnected {
@@ -140,7 +180,7 @@ func (r *Reader) loop() {
 
 			// Continue to try to reconnect while still active.
 			for atomic.LoadInt32(&r.running) == 1 {
-				if err = r.reader.Connect(); err != nil {
+				if err = rdr.Connect(); err != nil {
 					// Close immediately if our reader is closed.
 					if err == types.ErrTypeClosed {
 						return
@@ -152,7 +192,7 @@ func (r *Reader) loop() {
 					if !r.connThrot.Retry() {
 						return
 					}
-				} else if msg, err = r.reader.Read(); err != types.ErrNotConnected {
+				} else if msg, err = rdr.Read(); err != types.ErrNotConnected {
 					mConn.Incr(1)
 					atomic.StoreInt32(&r.connected, 1)
 					r.connThrot.Reset()
@@ -201,7 +241,7 @@ func (r *Reader) loop() {
 				mSendSuccess.Incr(1)
 			}
 			if res.Error() != nil || !res.SkipAck() {
-				if err = r.reader.Acknowledge(res.Error()); err != nil {
+				if err = rdr.Acknowledge(res.Error()); err != nil {
 					mAckError.Incr(1)
 				} else {
 					tTaken := time.Since(msg.CreatedAt()).Nanoseconds()
@@ -218,6 +258,9 @@ func (r *Reader) loop() {
 // TransactionChan returns a transactions channel for consuming messages from
 // this input type.
 func (r *Reader) TransactionChan() <-chan types.Transaction {
+	r.startOnce.Do(func() {
+		close(r.startChan)
+	})
 	return r.transactions
 }
 
@@ -21,8 +21,13 @@
 package reader
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -35,8 +40,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 )
 
 //------------------------------------------------------------------------------
@@ -50,8 +56,12 @@ type AmazonS3Config struct {
 	DeleteObjects   bool   `json:"delete_objects" yaml:"delete_objects"`
 	SQSURL          string `json:"sqs_url" yaml:"sqs_url"`
 	SQSBodyPath     string `json:"sqs_body_path" yaml:"sqs_body_path"`
+	SQSBucketPath   string `json:"sqs_bucket_path" yaml:"sqs_bucket_path"`
 	SQSEnvelopePath string `json:"sqs_envelope_path" yaml:"sqs_envelope_path"`
 	SQSMaxMessages  int64  `json:"sqs_max_messages" yaml:"sqs_max_messages"`
+	Decompress      string `json:"decompress" yaml:"decompress"`
+	SplitLines      bool   `json:"split_lines" yaml:"split_lines"`
+	Delim           string `json:"delimiter" yaml:"delimiter"`
 	Timeout         string `json:"timeout" yaml:"timeout"`
 }
 
@@ -65,8 +75,12 @@ func NewAmazonS3Config() AmazonS3Config {
 		DeleteObjects:   false,
 		SQSURL:          "",
 		SQSBodyPath:     "Records.s3.object.key",
+		SQSBucketPath:   "",
 		SQSEnvelopePath: "",
 		SQSMaxMessages:  10,
+		Decompress:      "none",
+		SplitLines:      false,
+		Delim:           "",
 		Timeout:         "5s",
 	}
 }
@@ -75,6 +89,7 @@ func NewAmazonS3Config() AmazonS3Config {
 
 type objKey struct {
 	s3Key     string
+	s3Bucket  string
 	attempts  int
 	sqsHandle *sqs.DeleteMessageBatchRequestEntry
 }
@@ -84,17 +99,18 @@ type objKey struct {
 type AmazonS3 struct {
 	conf AmazonS3Config
 
-	sqsBodyPath []string
-	sqsEnvPath  []string
+	sqsBodyPath   []string
+	sqsBucketPath []string
+	sqsEnvPath    []string
+	delim         []byte
 
 	readKeys   []objKey
 	targetKeys []objKey
 
-	session    *session.Session
-	s3         *s3.S3
-	downloader *s3manager.Downloader
-	sqs        *sqs.SQS
-	timeout    time.Duration
+	session *session.Session
+	s3      s3iface.S3API
+	sqs     sqsiface.SQSAPI
+	timeout time.Duration
 
 	log   log.Modular
 	stats metrics.Type
@@ -110,10 +126,23 @@ func NewAmazonS3(
 	if len(conf.SQSBodyPath) > 0 {
 		path = strings.Split(conf.SQSBodyPath, ".")
 	}
+	var bucketPath []string
+	if len(conf.SQSBucketPath) > 0 {
+		bucketPath = strings.Split(conf.SQSBucketPath, ".")
+	}
 	var envPath []string
 	if len(conf.SQSEnvelopePath) > 0 {
 		envPath = strings.Split(conf.SQSEnvelopePath, ".")
 	}
+	switch conf.Decompress {
+	case "none", "gzip", "auto":
+	default:
+		return nil, fmt.Errorf("unrecognised decompress algorithm: %v", conf.Decompress)
+	}
+	delim := []byte("\n")
+	if len(conf.Delim) > 0 {
+		delim = []byte(conf.Delim)
+	}
 	var timeout time.Duration
 	if tout := conf.Timeout; len(tout) > 0 {
 		var err error
@@ -122,12 +151,14 @@ func NewAmazonS3(
 		}
 	}
 	return &AmazonS3{
-		conf:        conf,
-		sqsBodyPath: path,
-		sqsEnvPath:  envPath,
-		log:         log,
-		stats:       stats,
-		timeout:     timeout,
+		conf:          conf,
+		sqsBodyPath:   path,
+		sqsBucketPath: bucketPath,
+		sqsEnvPath:    envPath,
+		delim:         delim,
+		log:           log,
+		stats:         stats,
+		timeout:       timeout,
 	}, nil
 }
 
@@ -144,7 +175,6 @@ func (a *AmazonS3) Connect() error {
 	}
 
 	sThree := s3.New(sess)
-	dler := s3manager.NewDownloader(sess)
 
 	if len(a.conf.SQSURL) == 0 {
 		listInput := &s3.ListObjectsInput{
@@ -158,6 +188,7 @@ func (a *AmazonS3) Connect() error {
 				for _, obj := range page.Contents {
 					a.targetKeys = append(a.targetKeys, objKey{
 						s3Key:    *obj.Key,
+						s3Bucket: a.conf.Bucket,
 						attempts: a.conf.Retries,
 					})
 				}
@@ -171,10 +202,13 @@ func (a *AmazonS3) Connect() error {
 		a.sqs = sqs.New(sess)
 	}
 
-	a.log.Infof("Receiving Amazon S3 objects from bucket: %s\n", a.conf.Bucket)
+	if len(a.conf.SQSURL) > 0 {
+		a.log.Infof("Receiving Amazon S3 objects from SQS: %s\n", a.conf.SQSURL)
+	} else {
+		a.log.Infof("Receiving Amazon S3 objects from bucket: %s\n", a.conf.Bucket)
+	}
 
 	a.session = sess
-	a.downloader = dler
 	a.s3 = sThree
 	return nil
 }
@@ -253,43 +287,59 @@ messageLoop:
 			}
 		}
 
+		var keys, buckets []string
 		switch t := gObj.S(a.sqsBodyPath...).Data().(type) {
 		case string:
-			if strings.HasPrefix(t, a.conf.Prefix) {
-				a.targetKeys = append(a.targetKeys, objKey{
-					s3Key:     t,
-					attempts:  a.conf.Retries,
-					sqsHandle: msgHandle,
-				})
-			}
+			keys = []string{t}
 		case []interface{}:
-			newTargets := []string{}
-			strs := digStrsFromSlices(t)
-			for _, p := range strs {
-				if strings.HasPrefix(p, a.conf.Prefix) {
-					newTargets = append(newTargets, p)
-				}
+			keys = digStrsFromSlices(t)
+		}
+		if len(a.sqsBucketPath) > 0 {
+			switch t := gObj.S(a.sqsBucketPath...).Data().(type) {
+			case string:
+				buckets = []string{t}
+			case []interface{}:
+				buckets = digStrsFromSlices(t)
 			}
-			if len(newTargets) == 0 {
-				dudMessageHandles = append(dudMessageHandles, msgHandle)
-			} else {
-				for _, target := range newTargets {
-					a.targetKeys = append(a.targetKeys, objKey{
-						s3Key:    target,
-						attempts: a.conf.Retries,
-					})
-				}
-				a.targetKeys[len(a.targetKeys)-1].sqsHandle = msgHandle
+		}
+
+		var newTargets []objKey
+		for i, key := range keys {
+			// Object keys within event notifications are URL encoded.
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+			if !strings.HasPrefix(key, a.conf.Prefix) {
+				continue
 			}
+			bucket := a.conf.Bucket
+			if len(buckets) == len(keys) {
+				bucket = buckets[i]
+			} else if len(buckets) == 1 {
+				bucket = buckets[0]
+			}
+			newTargets = append(newTargets, objKey{
+				s3Key:    key,
+				s3Bucket: bucket,
+				attempts: a.conf.Retries,
+			})
 		}
+		if len(newTargets) == 0 {
+			dudMessageHandles = append(dudMessageHandles, msgHandle)
+			continue messageLoop
+		}
+		newTargets[len(newTargets)-1].sqsHandle = msgHandle
+		a.targetKeys = append(a.targetKeys, newTargets...)
 	}
 
 	// Discard any SQS messages not associated with a target file.
-	a.sqs.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
-		QueueUrl: aws.String(a.conf.SQSURL),
-		Entries:  dudMessageHandles,
-	})
-	return types.ErrTimeout
+	if len(dudMessageHandles) > 0 {
+		a.sqs.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(a.conf.SQSURL),
+			Entries:  dudMessageHandles,
+		})
+	}
+	return nil
 }
 
 func (a *AmazonS3) popTargetKey() {
@@ -327,13 +377,15 @@ func (a *AmazonS3) Read() (types.Message, error) {
 
 	target := a.targetKeys[0]
 
-	buff := &aws.WriteAtBuffer{}
-
-	// Write the contents of S3 Object to the file
-	if _, err := a.downloader.Download(buff, &s3.GetObjectInput{
-		Bucket: aws.String(a.conf.Bucket),
+	obj, err := a.s3.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(target.s3Bucket),
 		Key:    aws.String(target.s3Key),
-	}); err != nil {
+	})
+	var objBytes []byte
+	if err == nil {
+		objBytes, err = a.readObject(target.s3Key, obj)
+	}
+	if err != nil {
 		target.attempts--
 		if target.attempts == 0 {
 			a.popTargetKey()
@@ -345,12 +397,73 @@ func (a *AmazonS3) Read() (types.Message, error) {
 
 	a.popTargetKey()
 
-	msg := message.New([][]byte{buff.Bytes()})
-	msg.Get(0).Metadata().Set("s3_key", target.s3Key)
+	var parts [][]byte
+	if a.conf.SplitLines {
+		for _, line := range bytes.Split(objBytes, a.delim) {
+			if len(line) > 0 {
+				parts = append(parts, line)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		parts = [][]byte{objBytes}
+	}
+
+	msg := message.New(parts)
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		meta.Set("s3_key", target.s3Key)
+		meta.Set("s3_bucket", target.s3Bucket)
+		if obj.LastModified != nil {
+			meta.Set("s3_last_modified", obj.LastModified.Format(time.RFC3339))
+			meta.Set("s3_last_modified_unix", strconv.FormatInt(obj.LastModified.Unix(), 10))
+		}
+		if obj.ContentType != nil {
+			meta.Set("s3_content_type", *obj.ContentType)
+		}
+		if obj.ContentEncoding != nil {
+			meta.Set("s3_content_encoding", *obj.ContentEncoding)
+		}
+		for k, v := range obj.Metadata {
+			if v != nil {
+				meta.Set(k, *v)
+			}
+		}
+		return nil
+	})
 
 	return msg, nil
 }
 
+// readObject reads the body of an object, decompressing it when configured.
+func (a *AmazonS3) readObject(key string, obj *s3.GetObjectOutput) ([]byte, error) {
+	defer obj.Body.Close()
+
+	objBytes, err := ioutil.ReadAll(obj.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	gzipped := a.conf.Decompress == "gzip"
+	if a.conf.Decompress == "auto" {
+		gzipped = strings.HasSuffix(key, ".gz") ||
+			(obj.ContentEncoding != nil && *obj.ContentEncoding == "gzip")
+	}
+	if !gzipped {
+		return objBytes, nil
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(objBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress object: %v", err)
+	}
+	defer gr.Close()
+	if objBytes, err = ioutil.ReadAll(gr); err != nil {
+		return nil, fmt.Errorf("failed to decompress object: %v", err)
+	}
+	return objBytes, nil
+}
+
 // Acknowledge confirms whether or not our unacknowledged messages have been
 // successfully propagated or not.
 func (a *AmazonS3) Acknowledge(err error) error {
@@ -360,7 +473,7 @@ func (a *AmazonS3) Acknowledge(err error) error {
 		for _, key := range a.readKeys {
 			if a.conf.DeleteObjects {
 				if _, serr = a.s3.DeleteObject(&s3.DeleteObjectInput{
-					Bucket: aws.String(a.conf.Bucket),
+					Bucket: aws.String(key.s3Bucket),
 					Key:    aws.String(key.s3Key),
 				}); serr != nil {
 					a.log.Errorf("Failed to delete consumed object: %v\n", serr)
@@ -377,7 +490,7 @@ func (a *AmazonS3) Acknowledge(err error) error {
 				Entries:  deleteHandles,
 			}); serr != nil {
 				a.log.Errorf("Failed to delete consumed SQS message: %v\n", serr)
-			} else {
+			} else if len(res.Failed) > 0 {
 				serr = fmt.Errorf("failed to delete %v consumed SQS messages", len(res.Failed))
 				for _, f := range res.Failed {
 					a.log.Errorf("Failed to delete consumed SQS message '%v', response code: %v\n", f.Id, f.Code)
@@ -0,0 +1,224 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+//------------------------------------------------------------------------------
+
+type mockS3Object struct {
+	body     []byte
+	encoding string
+}
+
+type mockS3 struct {
+	s3iface.S3API
+	objects map[string]mockS3Object
+	deleted []string
+}
+
+func (m *mockS3) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	obj, exists := m.objects[*in.Bucket+"/"+*in.Key]
+	if !exists {
+		return nil, errS3NotFound{}
+	}
+	out := &s3.GetObjectOutput{
+		Body:         ioutil.NopCloser(bytes.NewReader(obj.body)),
+		ContentType:  aws.String("text/plain"),
+		LastModified: aws.Time(time.Unix(1500000000, 0)),
+		Metadata:     map[string]*string{"foo": aws.String("bar")},
+	}
+	if len(obj.encoding) > 0 {
+		out.ContentEncoding = aws.String(obj.encoding)
+	}
+	return out, nil
+}
+
+func (m *mockS3) DeleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	m.deleted = append(m.deleted, *in.Bucket+"/"+*in.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+type mockS3SQS struct {
+	sqsiface.SQSAPI
+	bodies  []string
+	deleted []string
+}
+
+func (m *mockS3SQS) ReceiveMessage(*sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	out := &sqs.ReceiveMessageOutput{}
+	for i, b := range m.bodies {
+		out.Messages = append(out.Messages, &sqs.Message{
+			MessageId:     aws.String(string('a' + rune(i))),
+			ReceiptHandle: aws.String(string('a' + rune(i))),
+			Body:          aws.String(b),
+		})
+	}
+	m.bodies = nil
+	return out, nil
+}
+
+func (m *mockS3SQS) DeleteMessageBatch(in *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	for _, e := range in.Entries {
+		m.deleted = append(m.deleted, *e.ReceiptHandle)
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+type errS3NotFound struct{}
+
+func (errS3NotFound) Error() string { return "not found" }
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+//------------------------------------------------------------------------------
+
+func TestAmazonS3SQSEvents(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.SQSURL = "http://localhost/queue"
+	conf.SQSBucketPath = "Records.s3.bucket.name"
+	conf.DeleteObjects = true
+	conf.Decompress = "auto"
+	conf.SplitLines = true
+
+	r, err := NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mS3 := &mockS3{objects: map[string]mockS3Object{
+		"foo/a b.txt": {body: []byte("first\nsecond\n")},
+		"bar/c.gz":    {body: gzipBytes(t, []byte("third"))},
+		"baz/d":       {body: gzipBytes(t, []byte("fourth")), encoding: "gzip"},
+	}}
+	mSQS := &mockS3SQS{bodies: []string{
+		`{"Records":[{"s3":{"bucket":{"name":"foo"},"object":{"key":"a+b.txt"}}},{"s3":{"bucket":{"name":"bar"},"object":{"key":"c.gz"}}}]}`,
+		`not json`,
+		`{"Records":[{"s3":{"bucket":{"name":"baz"},"object":{"key":"d"}}}]}`,
+	}}
+	r.session = &session.Session{}
+	r.s3 = mS3
+	r.sqs = mSQS
+
+	expParts := [][]string{{"first", "second"}, {"third"}, {"fourth"}}
+	expBuckets := []string{"foo", "bar", "baz"}
+	expKeys := []string{"a b.txt", "c.gz", "d"}
+	for i, exp := range expParts {
+		msg, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := message.GetAllBytes(msg); !reflect.DeepEqual(toByteSlices(exp), act) {
+			t.Errorf("Wrong result at %v: %s != %s", i, act, exp)
+		}
+		meta := msg.Get(0).Metadata()
+		if act := meta.Get("s3_bucket"); act != expBuckets[i] {
+			t.Errorf("Wrong bucket at %v: %v != %v", i, act, expBuckets[i])
+		}
+		if act := meta.Get("s3_key"); act != expKeys[i] {
+			t.Errorf("Wrong key at %v: %v != %v", i, act, expKeys[i])
+		}
+		if act, exp := meta.Get("s3_last_modified_unix"), "1500000000"; act != exp {
+			t.Errorf("Wrong last modified: %v != %v", act, exp)
+		}
+		if act, exp := meta.Get("foo"), "bar"; act != exp {
+			t.Errorf("Wrong user metadata: %v != %v", act, exp)
+		}
+		if i == 0 {
+			// The dud message should have been discarded immediately.
+			if exp, act := []string{"b"}, mSQS.deleted; !reflect.DeepEqual(exp, act) {
+				t.Errorf("Wrong deleted SQS messages: %v != %v", act, exp)
+			}
+		}
+		if i == 1 {
+			// Fail the first attempt and expect a redelivery.
+			if err = r.Acknowledge(errS3NotFound{}); err != nil {
+				t.Fatal(err)
+			}
+			if msg, err = r.Read(); err != nil {
+				t.Fatal(err)
+			}
+			if act := message.GetAllBytes(msg); !reflect.DeepEqual(toByteSlices(exp), act) {
+				t.Errorf("Wrong redelivered result: %s != %s", act, exp)
+			}
+		}
+		if err = r.Acknowledge(nil); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if _, err = r.Read(); err == nil {
+		t.Error("Expected timeout error")
+	}
+
+	if exp, act := []string{"foo/a b.txt", "bar/c.gz", "baz/d"}, mS3.deleted; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong deleted objects: %v != %v", act, exp)
+	}
+	if exp, act := []string{"b", "a", "c"}, mSQS.deleted; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong deleted SQS messages: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3BadDecompress(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Decompress = "nope"
+	if _, err := NewAmazonS3(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad decompress")
+	}
+}
+
+func toByteSlices(strs []string) [][]byte {
+	var b [][]byte
+	for _, s := range strs {
+		b = append(b, []byte(s))
+	}
+	return b
+}
+
+//------------------------------------------------------------------------------
@@ -21,7 +21,9 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -32,23 +34,30 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 )
 
 //------------------------------------------------------------------------------
 
 // AmazonSQSConfig contains configuration values for the input type.
 type AmazonSQSConfig struct {
-	sess.Config `json:",inline" yaml:",inline"`
-	URL         string `json:"url" yaml:"url"`
-	Timeout     string `json:"timeout" yaml:"timeout"`
+	sess.Config         `json:",inline" yaml:",inline"`
+	URL                 string `json:"url" yaml:"url"`
+	Timeout             string `json:"timeout" yaml:"timeout"`
+	MaxNumberOfMessages int64  `json:"max_number_of_messages" yaml:"max_number_of_messages"`
+	VisibilityTimeout   string `json:"visibility_timeout" yaml:"visibility_timeout"`
+	ExtendVisibility    bool   `json:"extend_visibility" yaml:"extend_visibility"`
 }
 
 // NewAmazonSQSConfig creates a new Config with default values.
 func NewAmazonSQSConfig() AmazonSQSConfig {
 	return AmazonSQSConfig{
-		Config:  sess.NewConfig(),
-		URL:     "",
-		Timeout: "5s",
+		Config:              sess.NewConfig(),
+		URL:                 "",
+		Timeout:             "5s",
+		MaxNumberOfMessages: 1,
+		VisibilityTimeout:   "30s",
+		ExtendVisibility:    false,
 	}
 }
 
@@ -60,13 +69,23 @@ type AmazonSQS struct {
 	conf AmazonSQSConfig
 
 	pendingHandles []*sqs.DeleteMessageBatchRequestEntry
+	pendingMut     sync.Mutex
 
 	session *session.Session
-	sqs     *sqs.SQS
+	sqs     sqsiface.SQSAPI
 	timeout time.Duration
 
+	visibilityTimeout time.Duration
+
 	log   log.Modular
 	stats metrics.Type
+
+	mVisibilityExtended metrics.StatCounter
+	mVisibilityErr      metrics.StatCounter
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
 }
 
 // NewAmazonSQS creates a new Amazon SQS reader.Type.
@@ -82,16 +101,44 @@ func NewAmazonSQS(
 			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
 		}
 	}
-	return &AmazonSQS{
-		conf:    conf,
-		log:     log,
-		stats:   stats,
-		timeout: timeout,
-	}, nil
+	var visibilityTimeout time.Duration
+	if tout := conf.VisibilityTimeout; len(tout) > 0 {
+		var err error
+		if visibilityTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse visibility timeout string: %v", err)
+		}
+	}
+	if conf.MaxNumberOfMessages < 1 || conf.MaxNumberOfMessages > 10 {
+		return nil, fmt.Errorf("max_number_of_messages must be between 1 and 10, got: %v", conf.MaxNumberOfMessages)
+	}
+	if conf.ExtendVisibility && visibilityTimeout < time.Second {
+		return nil, errors.New("a visibility timeout of at least one second is required in order to extend visibility")
+	}
+	a := &AmazonSQS{
+		conf:              conf,
+		log:               log,
+		stats:             stats,
+		timeout:           timeout,
+		visibilityTimeout: visibilityTimeout,
+		closeChan:         make(chan struct{}),
+		closedChan:        make(chan struct{}),
+
+		mVisibilityExtended: stats.GetCounter("visibility.extended"),
+		mVisibilityErr:      stats.GetCounter("visibility.error"),
+	}
+	if conf.ExtendVisibility {
+		go a.visibilityLoop()
+	} else {
+		close(a.closedChan)
+	}
+	return a, nil
 }
 
 // Connect attempts to establish a connection to the target SQS queue.
 func (a *AmazonSQS) Connect() error {
+	a.pendingMut.Lock()
+	defer a.pendingMut.Unlock()
+
 	if a.session != nil {
 		return nil
 	}
@@ -108,17 +155,85 @@ func (a *AmazonSQS) Connect() error {
 	return nil
 }
 
+// visibilityLoop periodically extends the visibility timeout of messages that
+// are pending acknowledgement, which prevents them from being redelivered to
+// other consumers whilst they are still being processed.
+func (a *AmazonSQS) visibilityLoop() {
+	defer close(a.closedChan)
+
+	ticker := time.NewTicker(a.visibilityTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.closeChan:
+			return
+		}
+
+		a.pendingMut.Lock()
+		if a.sqs == nil || len(a.pendingHandles) == 0 {
+			a.pendingMut.Unlock()
+			continue
+		}
+		entries := make([]*sqs.ChangeMessageVisibilityBatchRequestEntry, 0, len(a.pendingHandles))
+		for _, h := range a.pendingHandles {
+			entries = append(entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                h.Id,
+				ReceiptHandle:     h.ReceiptHandle,
+				VisibilityTimeout: aws.Int64(int64(a.visibilityTimeout.Seconds())),
+			})
+		}
+		client := a.sqs
+		a.pendingMut.Unlock()
+
+		for len(entries) > 0 {
+			batch := entries
+			if len(batch) > 10 {
+				batch = entries[:10]
+			}
+			entries = entries[len(batch):]
+
+			res, err := client.ChangeMessageVisibilityBatch(&sqs.ChangeMessageVisibilityBatchInput{
+				QueueUrl: aws.String(a.conf.URL),
+				Entries:  batch,
+			})
+			if err != nil {
+				a.mVisibilityErr.Incr(1)
+				a.log.Errorf("Failed to extend message visibility: %v\n", err)
+				continue
+			}
+			if len(res.Failed) > 0 {
+				a.mVisibilityErr.Incr(int64(len(res.Failed)))
+				a.log.Errorf("Failed to extend visibility of %v messages: %v\n", len(res.Failed), res.Failed[0])
+			}
+			a.mVisibilityExtended.Incr(int64(len(res.Successful)))
+		}
+	}
+}
+
 // Read attempts to read a new message from the target SQS.
 func (a *AmazonSQS) Read() (types.Message, error) {
-	if a.session == nil {
+	a.pendingMut.Lock()
+	client := a.sqs
+	a.pendingMut.Unlock()
+
+	if client == nil {
 		return nil, types.ErrNotConnected
 	}
 
-	output, err := a.sqs.ReceiveMessage(&sqs.ReceiveMessageInput{
-		QueueUrl:            aws.String(a.conf.URL),
-		MaxNumberOfMessages: aws.Int64(1),
-		WaitTimeSeconds:     aws.Int64(int64(a.timeout.Seconds())),
-	})
+	input := &sqs.ReceiveMessageInput{
+		QueueUrl:              aws.String(a.conf.URL),
+		MaxNumberOfMessages:   aws.Int64(a.conf.MaxNumberOfMessages),
+		WaitTimeSeconds:       aws.Int64(int64(a.timeout.Seconds())),
+		AttributeNames:        []*string{aws.String("All")},
+		MessageAttributeNames: []*string{aws.String("All")},
+	}
+	if a.visibilityTimeout > 0 {
+		input.VisibilityTimeout = aws.Int64(int64(a.visibilityTimeout.Seconds()))
+	}
+
+	output, err := client.ReceiveMessage(input)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +244,7 @@ func (a *AmazonSQS) Read() (types.Message, error) {
 		return nil, types.ErrTimeout
 	}
 
+	a.pendingMut.Lock()
 	for _, sqsMsg := range output.Messages {
 		if sqsMsg.ReceiptHandle != nil {
 			a.pendingHandles = append(a.pendingHandles, &sqs.DeleteMessageBatchRequestEntry{
@@ -138,9 +254,12 @@ func (a *AmazonSQS) Read() (types.Message, error) {
 		}
 
 		if sqsMsg.Body != nil {
-			msg.Append(message.NewPart([]byte(*sqsMsg.Body)))
+			part := message.NewPart([]byte(*sqsMsg.Body))
+			addSQSMetadata(part.Metadata(), sqsMsg)
+			msg.Append(part)
 		}
 	}
+	a.pendingMut.Unlock()
 
 	if msg.Len() == 0 {
 		return nil, types.ErrTimeout
@@ -149,27 +268,122 @@ func (a *AmazonSQS) Read() (types.Message, error) {
 	return msg, nil
 }
 
+func addSQSMetadata(meta types.Metadata, sqsMsg *sqs.Message) {
+	if sqsMsg.MessageId != nil {
+		meta.Set("sqs_message_id", *sqsMsg.MessageId)
+	}
+	if sqsMsg.ReceiptHandle != nil {
+		meta.Set("sqs_receipt_handle", *sqsMsg.ReceiptHandle)
+	}
+	if rCountStr := sqsMsg.Attributes["ApproximateReceiveCount"]; rCountStr != nil {
+		meta.Set("sqs_approximate_receive_count", *rCountStr)
+	}
+	for k, v := range sqsMsg.MessageAttributes {
+		if v.StringValue != nil {
+			meta.Set(k, *v.StringValue)
+		}
+	}
+}
+
+// resetVisibility sets the visibility timeout of a slice of pending messages
+// to zero, making them immediately available for redelivery.
+func (a *AmazonSQS) resetVisibility(client sqsiface.SQSAPI, handles []*sqs.DeleteMessageBatchRequestEntry) error {
+	for len(handles) > 0 {
+		batch := handles
+		if len(batch) > 10 {
+			batch = handles[:10]
+		}
+		handles = handles[len(batch):]
+
+		entries := make([]*sqs.ChangeMessageVisibilityBatchRequestEntry, 0, len(batch))
+		for _, h := range batch {
+			entries = append(entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                h.Id,
+				ReceiptHandle:     h.ReceiptHandle,
+				VisibilityTimeout: aws.Int64(0),
+			})
+		}
+		res, err := client.ChangeMessageVisibilityBatch(&sqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(a.conf.URL),
+			Entries:  entries,
+		})
+		if err != nil {
+			a.mVisibilityErr.Incr(1)
+			return err
+		}
+		if len(res.Failed) > 0 {
+			a.mVisibilityErr.Incr(int64(len(res.Failed)))
+			return fmt.Errorf("failed to reset visibility of %v messages: %v", len(res.Failed), res.Failed[0])
+		}
+	}
+	return nil
+}
+
 // Acknowledge confirms whether or not our unacknowledged messages have been
-// successfully propagated or not.
+// successfully propagated or not. Messages are only deleted from the queue
+// once they have been successfully propagated, messages that failed are made
+// immediately visible again so that they can be redelivered.
 func (a *AmazonSQS) Acknowledge(err error) error {
-	if _, err := a.sqs.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
-		QueueUrl: aws.String(a.conf.URL),
-		Entries:  a.pendingHandles,
-	}); err != nil {
-		return err
+	if err != nil {
+		a.pendingMut.Lock()
+		handles, client := a.pendingHandles, a.sqs
+		a.pendingHandles = nil
+		a.pendingMut.Unlock()
+
+		if client == nil || len(handles) == 0 {
+			return nil
+		}
+		// Failing to reset the visibility is not fatal as the messages will
+		// become visible again once their visibility timeout expires.
+		if rerr := a.resetVisibility(client, handles); rerr != nil {
+			a.log.Errorf("Failed to reset visibility of unacknowledged messages: %v\n", rerr)
+		}
+		return nil
 	}
 
-	a.pendingHandles = nil
+	a.pendingMut.Lock()
+	defer a.pendingMut.Unlock()
+
+	if a.sqs == nil {
+		return types.ErrNotConnected
+	}
+
+	for len(a.pendingHandles) > 0 {
+		batch := a.pendingHandles
+		if len(batch) > 10 {
+			batch = a.pendingHandles[:10]
+		}
+		res, err := a.sqs.DeleteMessageBatch(&sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(a.conf.URL),
+			Entries:  batch,
+		})
+		if err != nil {
+			return err
+		}
+		if len(res.Failed) > 0 {
+			a.pendingHandles = a.pendingHandles[len(batch):]
+			return fmt.Errorf("failed to delete %v messages: %v", len(res.Failed), res.Failed[0])
+		}
+		a.pendingHandles = a.pendingHandles[len(batch):]
+	}
 	return nil
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonSQS) CloseAsync() {
+	a.closeOnce.Do(func() {
+		close(a.closeChan)
+	})
 }
 
 // WaitForClose will block until either the reader is closed or a specified
 // timeout occurs.
-func (a *AmazonSQS) WaitForClose(time.Duration) error {
+func (a *AmazonSQS) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-a.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
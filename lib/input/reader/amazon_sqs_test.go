@@ -0,0 +1,230 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+
+	sync.Mutex
+	received   []*sqs.Message
+	deleted    []string
+	visibility []string
+}
+
+func (m *mockSQS) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	n := int(*input.MaxNumberOfMessages)
+	if n > len(m.received) {
+		n = len(m.received)
+	}
+	msgs := m.received[:n]
+	m.received = m.received[n:]
+	return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (m *mockSQS) DeleteMessageBatch(input *sqs.DeleteMessageBatchInput) (*sqs.DeleteMessageBatchOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	for _, e := range input.Entries {
+		m.deleted = append(m.deleted, *e.ReceiptHandle)
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (m *mockSQS) ChangeMessageVisibilityBatch(input *sqs.ChangeMessageVisibilityBatchInput) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	m.Lock()
+	defer m.Unlock()
+	res := &sqs.ChangeMessageVisibilityBatchOutput{}
+	for _, e := range input.Entries {
+		m.visibility = append(m.visibility, *e.ReceiptHandle)
+		res.Successful = append(res.Successful, &sqs.ChangeMessageVisibilityBatchResultEntry{Id: e.Id})
+	}
+	return res, nil
+}
+
+func newMockSQSMsg(id, body string) *sqs.Message {
+	return &sqs.Message{
+		MessageId:     aws.String(id),
+		ReceiptHandle: aws.String("handle_" + id),
+		Body:          aws.String(body),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			"foo": {StringValue: aws.String("bar_" + id)},
+		},
+	}
+}
+
+func TestAmazonSQSBatchedReceiveAndAck(t *testing.T) {
+	conf := NewAmazonSQSConfig()
+	conf.MaxNumberOfMessages = 2
+
+	r, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockSQS{
+		received: []*sqs.Message{
+			newMockSQSMsg("1", "first"),
+			newMockSQSMsg("2", "second"),
+			newMockSQSMsg("3", "third"),
+		},
+	}
+	r.session = session.Must(session.NewSession())
+	r.sqs = mock
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("first"), []byte("second")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "bar_2", msg.Get(1).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := "1", msg.Get(0).Metadata().Get("sqs_message_id"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+
+	if err = r.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"handle_1", "handle_2"}, mock.deleted; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong deleted handles: %v != %v", act, exp)
+	}
+
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAmazonSQSFailedAckThenSuccess(t *testing.T) {
+	conf := NewAmazonSQSConfig()
+	conf.MaxNumberOfMessages = 2
+
+	r, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockSQS{
+		received: []*sqs.Message{
+			newMockSQSMsg("1", "first"),
+			newMockSQSMsg("2", "second"),
+			newMockSQSMsg("3", "third"),
+		},
+	}
+	r.session = session.Must(session.NewSession())
+	r.sqs = mock
+
+	if _, err = r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.deleted) > 0 {
+		t.Errorf("Messages deleted after failed ack: %v", mock.deleted)
+	}
+	if exp, act := []string{"handle_1", "handle_2"}, mock.visibility; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong reset handles: %v != %v", act, exp)
+	}
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("third")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if err = r.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"handle_3"}, mock.deleted; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong deleted handles: %v != %v", act, exp)
+	}
+
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAmazonSQSExtendVisibility(t *testing.T) {
+	conf := NewAmazonSQSConfig()
+	conf.VisibilityTimeout = "1s"
+	conf.ExtendVisibility = true
+
+	r, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock := &mockSQS{
+		received: []*sqs.Message{newMockSQSMsg("1", "first")},
+	}
+	r.pendingMut.Lock()
+	r.session = session.Must(session.NewSession())
+	r.sqs = mock
+	r.pendingMut.Unlock()
+
+	if _, err = r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	<-time.After(time.Millisecond * 1200)
+
+	mock.Lock()
+	if len(mock.visibility) == 0 {
+		t.Error("Expected visibility of pending message to be extended")
+	} else if exp, act := "handle_1", mock.visibility[0]; exp != act {
+		t.Errorf("Wrong extended handle: %v != %v", act, exp)
+	}
+	mock.Unlock()
+
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAmazonSQSBadConfig(t *testing.T) {
+	conf := NewAmazonSQSConfig()
+	conf.MaxNumberOfMessages = 11
+	if _, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from max messages")
+	}
+}
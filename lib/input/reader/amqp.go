@@ -275,6 +275,12 @@ func setMetadata(p types.Part, k string, v interface{}) {
 
 // Read a new AMQP message.
 func (a *AMQP) Read() (types.Message, error) {
+	return a.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline reads a new AMQP message, returning types.ErrTimeout if no
+// message arrives before the deadline.
+func (a *AMQP) ReadWithDeadline(deadline time.Time) (types.Message, error) {
 	var c <-chan amqp.Delivery
 
 	a.m.RLock()
@@ -327,7 +333,16 @@ func (a *AMQP) Read() (types.Message, error) {
 		msg.Append(part)
 	}
 
-	data, open := <-c
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	var data amqp.Delivery
+	var open bool
+	select {
+	case data, open = <-c:
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
+	}
 	if !open {
 		a.disconnect()
 		return nil, types.ErrNotConnected
@@ -0,0 +1,382 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+)
+
+//------------------------------------------------------------------------------
+
+// AzureBlobStorageConfig contains configuration fields for the
+// AzureBlobStorage input type.
+type AzureBlobStorageConfig struct {
+	storage.Config      `json:",inline" yaml:",inline"`
+	Container           string `json:"container" yaml:"container"`
+	Prefix              string `json:"prefix" yaml:"prefix"`
+	Decompress          string `json:"decompress" yaml:"decompress"`
+	Codec               string `json:"codec" yaml:"codec"`
+	Delim               string `json:"delimiter" yaml:"delimiter"`
+	CheckpointContainer string `json:"checkpoint_container" yaml:"checkpoint_container"`
+	CheckpointBlob      string `json:"checkpoint_blob" yaml:"checkpoint_blob"`
+}
+
+// NewAzureBlobStorageConfig creates a new AzureBlobStorageConfig with default
+// values.
+func NewAzureBlobStorageConfig() AzureBlobStorageConfig {
+	return AzureBlobStorageConfig{
+		Config:              storage.NewConfig(),
+		Container:           "",
+		Prefix:              "",
+		Decompress:          "none",
+		Codec:               "all-bytes",
+		Delim:               "",
+		CheckpointContainer: "benthos-checkpoints",
+		CheckpointBlob:      "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// blobStore is the subset of blob storage operations used by the input.
+type blobStore interface {
+	CreateContainer(container string) error
+	ListBlobs(container, prefix string) ([]storage.Blob, error)
+	GetBlob(container, blob string) ([]byte, *storage.Blob, error)
+	PutBlob(container, blob string, data []byte, contentType string) error
+}
+
+// blobCheckpoint is the JSON document stored in the checkpoint blob, mapping
+// the names of processed blobs to their last modified time.
+type blobCheckpoint struct {
+	Blobs map[string]int64 `json:"blobs"`
+}
+
+//------------------------------------------------------------------------------
+
+// AzureBlobStorage is a benthos reader.Type implementation that reads blobs
+// from an Azure Blob Storage container.
+type AzureBlobStorage struct {
+	conf AzureBlobStorageConfig
+
+	delim          []byte
+	checkpointBlob string
+
+	cMut      sync.Mutex
+	blobs     blobStore
+	newClient func() (blobStore, error)
+	closed    bool
+
+	checkpoint blobCheckpoint
+	targets    []storage.Blob
+	pending    []storage.Blob
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCheckpoint  metrics.StatCounter
+	mCheckpointE metrics.StatCounter
+}
+
+// NewAzureBlobStorage creates a new Azure Blob Storage reader.Type.
+func NewAzureBlobStorage(
+	conf AzureBlobStorageConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*AzureBlobStorage, error) {
+	if len(conf.Container) == 0 {
+		return nil, errors.New("a container must be specified")
+	}
+	switch conf.Decompress {
+	case "none", "gzip", "auto":
+	default:
+		return nil, fmt.Errorf("unrecognised decompress algorithm: %v", conf.Decompress)
+	}
+	switch conf.Codec {
+	case "all-bytes", "lines", "tar":
+	default:
+		return nil, fmt.Errorf("unrecognised codec: %v", conf.Codec)
+	}
+	a := &AzureBlobStorage{
+		conf:           conf,
+		delim:          []byte("\n"),
+		checkpointBlob: conf.CheckpointBlob,
+		checkpoint:     blobCheckpoint{Blobs: map[string]int64{}},
+		log:            log,
+		stats:          stats,
+		mCheckpoint:    stats.GetCounter("checkpoint.success"),
+		mCheckpointE:   stats.GetCounter("checkpoint.error"),
+	}
+	if len(conf.Delim) > 0 {
+		a.delim = []byte(conf.Delim)
+	}
+	if len(a.checkpointBlob) == 0 {
+		a.checkpointBlob = path.Join("azure_blob_storage", conf.Container, conf.Prefix, "checkpoint.json")
+	}
+	a.newClient = func() (blobStore, error) {
+		return conf.NewClient()
+	}
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target container, reads
+// the checkpoint of processed blobs and lists the blobs to be consumed.
+func (a *AzureBlobStorage) Connect() error {
+	a.cMut.Lock()
+	defer a.cMut.Unlock()
+	if a.closed {
+		return types.ErrTypeClosed
+	}
+	if a.blobs != nil {
+		return nil
+	}
+
+	client, err := a.newClient()
+	if err != nil {
+		return err
+	}
+
+	if len(a.conf.CheckpointContainer) > 0 {
+		if err = client.CreateContainer(a.conf.CheckpointContainer); err != nil {
+			return fmt.Errorf("failed to create checkpoint container: %v", err)
+		}
+		data, _, err := client.GetBlob(a.conf.CheckpointContainer, a.checkpointBlob)
+		if err == nil {
+			if err = json.Unmarshal(data, &a.checkpoint); err != nil {
+				return fmt.Errorf("failed to parse checkpoint: %v", err)
+			}
+			if a.checkpoint.Blobs == nil {
+				a.checkpoint.Blobs = map[string]int64{}
+			}
+		} else if err != storage.ErrBlobNotFound {
+			return fmt.Errorf("failed to read checkpoint: %v", err)
+		}
+	}
+
+	blobs, err := client.ListBlobs(a.conf.Container, a.conf.Prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list blobs: %v", err)
+	}
+
+	listed := map[string]struct{}{}
+	for _, b := range blobs {
+		listed[b.Name] = struct{}{}
+		if a.conf.CheckpointContainer == a.conf.Container && b.Name == a.checkpointBlob {
+			continue
+		}
+		if mod, exists := a.checkpoint.Blobs[b.Name]; exists && mod == b.LastModified.Unix() {
+			continue
+		}
+		a.targets = append(a.targets, b)
+	}
+
+	// Forget blobs that no longer exist so that the checkpoint doesn't grow
+	// indefinitely.
+	for name := range a.checkpoint.Blobs {
+		if _, exists := listed[name]; !exists {
+			delete(a.checkpoint.Blobs, name)
+		}
+	}
+
+	a.log.Infof(
+		"Receiving Azure Blob Storage blobs from container '%v', %v blobs already processed\n",
+		a.conf.Container, len(blobs)-len(a.targets),
+	)
+	a.blobs = client
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// decodeBlob decompresses the contents of a blob and splits it into message
+// parts according to the configured codec.
+func (a *AzureBlobStorage) decodeBlob(name string, data []byte) ([][]byte, []string, error) {
+	if a.conf.Decompress == "gzip" || (a.conf.Decompress == "auto" && strings.HasSuffix(name, ".gz")) {
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress blob: %v", err)
+		}
+		if data, err = ioutil.ReadAll(gr); err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress blob: %v", err)
+		}
+	}
+
+	var parts [][]byte
+	var names []string
+	switch a.conf.Codec {
+	case "lines":
+		for _, line := range bytes.Split(data, a.delim) {
+			if len(line) > 0 {
+				parts = append(parts, line)
+			}
+		}
+	case "tar":
+		tr := tar.NewReader(bytes.NewReader(data))
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read tar archive: %v", err)
+			}
+			if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+				continue
+			}
+			b, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read tar archive: %v", err)
+			}
+			parts = append(parts, b)
+			names = append(names, hdr.Name)
+		}
+	}
+	if len(parts) == 0 {
+		parts = [][]byte{data}
+		names = nil
+	}
+	return parts, names, nil
+}
+
+// Read attempts to read a new message from the target container.
+func (a *AzureBlobStorage) Read() (types.Message, error) {
+	a.cMut.Lock()
+	defer a.cMut.Unlock()
+	if a.blobs == nil {
+		return nil, types.ErrNotConnected
+	}
+	if len(a.targets) == 0 {
+		return nil, types.ErrTypeClosed
+	}
+
+	target := a.targets[0]
+	data, blob, err := a.blobs.GetBlob(a.conf.Container, target.Name)
+	if err == storage.ErrBlobNotFound {
+		a.log.Warnf("Blob '%v' was removed before it could be read\n", target.Name)
+		a.targets = a.targets[1:]
+		return nil, types.ErrTimeout
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob: %v", err)
+	}
+	if !blob.LastModified.IsZero() {
+		target.LastModified = blob.LastModified
+	}
+
+	parts, names, err := a.decodeBlob(target.Name, data)
+	if err != nil {
+		a.log.Errorf("Skipping blob '%v': %v\n", target.Name, err)
+		a.targets = a.targets[1:]
+		return nil, types.ErrTimeout
+	}
+	a.targets = a.targets[1:]
+	a.pending = append(a.pending, target)
+
+	msg := message.New(parts)
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		for k, v := range blob.Metadata {
+			meta.Set(k, v)
+		}
+		meta.Set("azure_blob_name", target.Name)
+		meta.Set("azure_blob_container", a.conf.Container)
+		meta.Set("azure_blob_size", strconv.Itoa(len(data)))
+		meta.Set("azure_blob_last_modified", target.LastModified.Format(time.RFC3339))
+		meta.Set("azure_blob_last_modified_unix", strconv.FormatInt(target.LastModified.Unix(), 10))
+		if i < len(names) {
+			meta.Set("azure_blob_tar_entry", names[i])
+		}
+		return nil
+	})
+	return msg, nil
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not, and checkpoints the blobs that were.
+func (a *AzureBlobStorage) Acknowledge(err error) error {
+	a.cMut.Lock()
+	defer a.cMut.Unlock()
+
+	if err != nil {
+		a.targets = append(a.pending, a.targets...)
+		a.pending = nil
+		return nil
+	}
+	if len(a.pending) == 0 {
+		return nil
+	}
+
+	for _, b := range a.pending {
+		a.checkpoint.Blobs[b.Name] = b.LastModified.Unix()
+	}
+	a.pending = nil
+
+	if len(a.conf.CheckpointContainer) == 0 || a.blobs == nil {
+		return nil
+	}
+	data, err := json.Marshal(a.checkpoint)
+	if err != nil {
+		return err
+	}
+	if err = a.blobs.PutBlob(a.conf.CheckpointContainer, a.checkpointBlob, data, "application/json"); err != nil {
+		a.mCheckpointE.Incr(1)
+		a.log.Errorf("Failed to write checkpoint: %v\n", err)
+		return err
+	}
+	a.mCheckpoint.Incr(1)
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (a *AzureBlobStorage) CloseAsync() {
+	a.cMut.Lock()
+	a.blobs = nil
+	a.closed = true
+	a.cMut.Unlock()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (a *AzureBlobStorage) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
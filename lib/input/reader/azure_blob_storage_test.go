@@ -0,0 +1,240 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+)
+
+//------------------------------------------------------------------------------
+
+type mockBlobStore struct {
+	containers map[string]map[string][]byte
+	modified   map[string]time.Time
+}
+
+func newMockBlobStore() *mockBlobStore {
+	return &mockBlobStore{
+		containers: map[string]map[string][]byte{},
+		modified:   map[string]time.Time{},
+	}
+}
+
+func (m *mockBlobStore) CreateContainer(container string) error {
+	if _, exists := m.containers[container]; !exists {
+		m.containers[container] = map[string][]byte{}
+	}
+	return nil
+}
+
+func (m *mockBlobStore) ListBlobs(container, prefix string) ([]storage.Blob, error) {
+	var blobs []storage.Blob
+	for k, v := range m.containers[container] {
+		if !bytes.HasPrefix([]byte(k), []byte(prefix)) {
+			continue
+		}
+		blobs = append(blobs, storage.Blob{
+			Name:         k,
+			Size:         int64(len(v)),
+			LastModified: m.modified[container+"/"+k],
+		})
+	}
+	sort.Slice(blobs, func(i, j int) bool {
+		return blobs[i].Name < blobs[j].Name
+	})
+	return blobs, nil
+}
+
+func (m *mockBlobStore) GetBlob(container, blob string) ([]byte, *storage.Blob, error) {
+	data, exists := m.containers[container][blob]
+	if !exists {
+		return nil, nil, storage.ErrBlobNotFound
+	}
+	return data, &storage.Blob{
+		Name:         blob,
+		Size:         int64(len(data)),
+		LastModified: m.modified[container+"/"+blob],
+		Metadata:     map[string]string{"foo": "bar"},
+	}, nil
+}
+
+func (m *mockBlobStore) PutBlob(container, blob string, data []byte, contentType string) error {
+	if _, exists := m.containers[container]; !exists {
+		return errors.New("container does not exist")
+	}
+	m.containers[container][blob] = data
+	m.modified[container+"/"+blob] = time.Unix(1600000000, 0)
+	return nil
+}
+
+func (m *mockBlobStore) put(container, blob string, data []byte, modified time.Time) {
+	m.CreateContainer(container)
+	m.containers[container][blob] = data
+	m.modified[container+"/"+blob] = modified
+}
+
+//------------------------------------------------------------------------------
+
+func readAllBlobs(t *testing.T, conf AzureBlobStorageConfig, store *mockBlobStore) []types.Message {
+	t.Helper()
+
+	a, err := NewAzureBlobStorage(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.newClient = func() (blobStore, error) {
+		return store, nil
+	}
+	if err = a.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []types.Message
+	for {
+		msg, err := a.Read()
+		if err == types.ErrTypeClosed {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = a.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	a.CloseAsync()
+	return msgs
+}
+
+func TestAzureBlobStorageCheckpoint(t *testing.T) {
+	store := newMockBlobStore()
+	store.put("foo", "in/a", []byte("first\nsecond"), time.Unix(1500000000, 0))
+	store.put("foo", "in/b", []byte("third"), time.Unix(1500000000, 0))
+	store.put("foo", "other/c", []byte("ignored"), time.Unix(1500000000, 0))
+
+	conf := NewAzureBlobStorageConfig()
+	conf.Container = "foo"
+	conf.Prefix = "in/"
+	conf.Codec = "lines"
+
+	msgs := readAllBlobs(t, conf, store)
+	if exp, act := 2, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("first"), []byte("second")}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	meta := msgs[0].Get(0).Metadata()
+	if exp, act := "in/a", meta.Get("azure_blob_name"); exp != act {
+		t.Errorf("Wrong blob name: %v != %v", act, exp)
+	}
+	if exp, act := "1500000000", meta.Get("azure_blob_last_modified_unix"); exp != act {
+		t.Errorf("Wrong last modified: %v != %v", act, exp)
+	}
+	if exp, act := "bar", meta.Get("foo"); exp != act {
+		t.Errorf("Wrong user metadata: %v != %v", act, exp)
+	}
+
+	if _, exists := store.containers["benthos-checkpoints"]["azure_blob_storage/foo/in/checkpoint.json"]; !exists {
+		t.Fatal("Expected checkpoint blob to be written")
+	}
+
+	// After a restart only new and modified blobs are read.
+	store.put("foo", "in/b", []byte("third again"), time.Unix(1500000001, 0))
+	store.put("foo", "in/d", []byte("fourth"), time.Unix(1500000000, 0))
+
+	msgs = readAllBlobs(t, conf, store)
+	if exp, act := 2, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("third again")}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("fourth")}, message.GetAllBytes(msgs[1]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+
+	if msgs = readAllBlobs(t, conf, store); len(msgs) != 0 {
+		t.Errorf("Expected no messages, received %v", len(msgs))
+	}
+}
+
+func TestAzureBlobStorageTarGzip(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct{ name, body string }{
+		{"a.txt", "first"},
+		{"b.txt", "second"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     f.name,
+			Mode:     0600,
+			Size:     int64(len(f.body)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newMockBlobStore()
+	store.put("foo", "archive.tar.gz", gzipBytes(t, buf.Bytes()), time.Unix(1500000000, 0))
+
+	conf := NewAzureBlobStorageConfig()
+	conf.Container = "foo"
+	conf.Decompress = "auto"
+	conf.Codec = "tar"
+	conf.CheckpointContainer = ""
+
+	msgs := readAllBlobs(t, conf, store)
+	if exp, act := 1, len(msgs); exp != act {
+		t.Fatalf("Wrong count of messages: %v != %v", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("first"), []byte("second")}, message.GetAllBytes(msgs[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := "b.txt", msgs[0].Get(1).Metadata().Get("azure_blob_tar_entry"); exp != act {
+		t.Errorf("Wrong tar entry: %v != %v", act, exp)
+	}
+	if len(store.containers) != 1 {
+		t.Error("Expected no checkpoint container to be created")
+	}
+}
+
+//------------------------------------------------------------------------------
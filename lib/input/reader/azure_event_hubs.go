@@ -0,0 +1,518 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs/checkpoints"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+	"github.com/gofrs/uuid"
+)
+
+//------------------------------------------------------------------------------
+
+// AzureEventHubsCheckpointConfig contains configuration fields for the blob
+// storage container used to checkpoint Event Hubs offsets.
+type AzureEventHubsCheckpointConfig struct {
+	storage.Config `json:",inline" yaml:",inline"`
+	Container      string `json:"container" yaml:"container"`
+}
+
+// AzureEventHubsConfig contains configuration fields for the AzureEventHubs
+// input type.
+type AzureEventHubsConfig struct {
+	Namespace        string                         `json:"namespace" yaml:"namespace"`
+	EventHub         string                         `json:"event_hub" yaml:"event_hub"`
+	ConnectionString string                         `json:"connection_string" yaml:"connection_string"`
+	ConsumerGroup    string                         `json:"consumer_group" yaml:"consumer_group"`
+	InstanceID       string                         `json:"instance_id" yaml:"instance_id"`
+	StartFromOldest  bool                           `json:"start_from_oldest" yaml:"start_from_oldest"`
+	MaxBatchCount    int                            `json:"max_batch_count" yaml:"max_batch_count"`
+	CheckpointStore  AzureEventHubsCheckpointConfig `json:"checkpoint_store" yaml:"checkpoint_store"`
+	CheckpointPeriod string                         `json:"checkpoint_period" yaml:"checkpoint_period"`
+	LeaseDuration    string                         `json:"lease_duration" yaml:"lease_duration"`
+	BalancePeriod    string                         `json:"balance_period" yaml:"balance_period"`
+}
+
+// NewAzureEventHubsConfig creates a new AzureEventHubsConfig with default
+// values.
+func NewAzureEventHubsConfig() AzureEventHubsConfig {
+	return AzureEventHubsConfig{
+		Namespace:        "",
+		EventHub:         "",
+		ConnectionString: "",
+		ConsumerGroup:    "$Default",
+		InstanceID:       "",
+		StartFromOldest:  true,
+		MaxBatchCount:    1,
+		CheckpointStore: AzureEventHubsCheckpointConfig{
+			Config:    storage.NewConfig(),
+			Container: "benthos-checkpoints",
+		},
+		CheckpointPeriod: "1s",
+		LeaseDuration:    "30s",
+		BalancePeriod:    "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type eventHubsPartition struct {
+	client *azeventhubs.ProcessorPartitionClient
+
+	// Latest event acknowledged and the sequence number of the latest event
+	// written to the checkpoint store.
+	acked        *azeventhubs.ReceivedEventData
+	checkpointed int64
+}
+
+type eventHubsMsg struct {
+	partition *eventHubsPartition
+	event     *azeventhubs.ReceivedEventData
+}
+
+// eventHubsPart creates a message part from an event received from a
+// partition, adding its properties as metadata.
+func eventHubsPart(partitionID string, e *azeventhubs.ReceivedEventData) types.Part {
+	part := message.NewPart(e.Body)
+
+	meta := part.Metadata()
+	for k, v := range e.Properties {
+		meta.Set(k, fmt.Sprintf("%v", v))
+	}
+	if e.PartitionKey != nil {
+		meta.Set("azure_event_hubs_key", *e.PartitionKey)
+	}
+	meta.Set("azure_event_hubs_partition", partitionID)
+	meta.Set("azure_event_hubs_offset", strconv.FormatInt(e.Offset, 10))
+	meta.Set("azure_event_hubs_sequence_number", strconv.FormatInt(e.SequenceNumber, 10))
+	if e.EnqueuedTime != nil {
+		meta.Set("azure_event_hubs_timestamp_unix", strconv.FormatInt(e.EnqueuedTime.Unix(), 10))
+	}
+	return part
+}
+
+//------------------------------------------------------------------------------
+
+// AzureEventHubs is an input type that consumes the partitions of an Azure
+// Event Hub, dividing partitions across instances and checkpointing offsets to
+// blob storage.
+type AzureEventHubs struct {
+	conf  AzureEventHubsConfig
+	stats metrics.Type
+	log   log.Modular
+
+	checkpointPeriod time.Duration
+	leaseDuration    time.Duration
+	balancePeriod    time.Duration
+
+	blobs *storage.Client
+
+	cMut       sync.Mutex
+	consumer   *azeventhubs.ConsumerClient
+	cancel     context.CancelFunc
+	runDone    chan struct{}
+	partitions map[string]*eventHubsPartition
+
+	msgChan     chan eventHubsMsg
+	pending     map[*eventHubsPartition]*azeventhubs.ReceivedEventData
+	lastFlushed time.Time
+
+	mPartAcquired metrics.StatCounter
+	mPartReleased metrics.StatCounter
+	mPartLost     metrics.StatCounter
+	mCheckpoint   metrics.StatCounter
+	mCheckpointE  metrics.StatCounter
+	mRcvErr       metrics.StatCounter
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewAzureEventHubs creates a new AzureEventHubs input type.
+func NewAzureEventHubs(conf AzureEventHubsConfig, log log.Modular, stats metrics.Type) (*AzureEventHubs, error) {
+	if len(conf.EventHub) == 0 {
+		return nil, errors.New("an event hub must be specified")
+	}
+	if len(conf.ConsumerGroup) == 0 {
+		return nil, errors.New("a consumer group must be specified")
+	}
+	if len(conf.ConnectionString) == 0 && len(conf.Namespace) == 0 {
+		return nil, errors.New("either a connection string or a namespace must be specified")
+	}
+	if len(conf.InstanceID) == 0 {
+		u4, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		conf.InstanceID = u4.String()
+	}
+	a := AzureEventHubs{
+		conf:       conf,
+		stats:      stats,
+		log:        log,
+		partitions: map[string]*eventHubsPartition{},
+		msgChan:    make(chan eventHubsMsg),
+		pending:    map[*eventHubsPartition]*azeventhubs.ReceivedEventData{},
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+
+		mPartAcquired: stats.GetCounter("partition.acquired"),
+		mPartReleased: stats.GetCounter("partition.released"),
+		mPartLost:     stats.GetCounter("partition.lost"),
+		mCheckpoint:   stats.GetCounter("checkpoint.success"),
+		mCheckpointE:  stats.GetCounter("checkpoint.error"),
+		mRcvErr:       stats.GetCounter("recv.error"),
+	}
+
+	var err error
+	if a.checkpointPeriod, err = time.ParseDuration(conf.CheckpointPeriod); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint period string: %v", err)
+	}
+	if a.leaseDuration, err = time.ParseDuration(conf.LeaseDuration); err != nil {
+		return nil, fmt.Errorf("failed to parse lease duration string: %v", err)
+	}
+	if a.balancePeriod, err = time.ParseDuration(conf.BalancePeriod); err != nil {
+		return nil, fmt.Errorf("failed to parse balance period string: %v", err)
+	}
+	if a.balancePeriod >= a.leaseDuration {
+		return nil, errors.New("balance period must be shorter than the lease duration")
+	}
+	if a.blobs, err = conf.CheckpointStore.NewClient(); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (a *AzureEventHubs) dialConsumer() (*azeventhubs.ConsumerClient, error) {
+	opts := &azeventhubs.ConsumerClientOptions{
+		InstanceID: a.conf.InstanceID,
+	}
+	if len(a.conf.ConnectionString) > 0 {
+		return azeventhubs.NewConsumerClientFromConnectionString(
+			a.conf.ConnectionString, a.conf.EventHub, a.conf.ConsumerGroup, opts,
+		)
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	return azeventhubs.NewConsumerClient(
+		a.conf.Namespace, a.conf.EventHub, a.conf.ConsumerGroup, cred, opts,
+	)
+}
+
+// Connect establishes a connection to the Event Hubs namespace and begins
+// claiming partitions.
+func (a *AzureEventHubs) Connect() error {
+	a.cMut.Lock()
+	defer a.cMut.Unlock()
+
+	if a.consumer != nil {
+		return nil
+	}
+	select {
+	case <-a.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	container := a.conf.CheckpointStore.Container
+	if err := a.blobs.CreateContainer(container); err != nil {
+		return fmt.Errorf("failed to create checkpoint container: %v", err)
+	}
+	store, err := checkpoints.NewBlobStore(a.blobs.Container(container), nil)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := a.dialConsumer()
+	if err != nil {
+		return err
+	}
+
+	enabled := true
+	startPos := azeventhubs.StartPosition{Latest: &enabled}
+	if a.conf.StartFromOldest {
+		startPos = azeventhubs.StartPosition{Earliest: &enabled}
+	}
+	processor, err := azeventhubs.NewProcessor(consumer, store, &azeventhubs.ProcessorOptions{
+		LoadBalancingStrategy:       azeventhubs.ProcessorStrategyBalanced,
+		UpdateInterval:              a.balancePeriod,
+		PartitionExpirationDuration: a.leaseDuration,
+		StartPositions: azeventhubs.StartPositions{
+			Default: startPos,
+		},
+	})
+	if err != nil {
+		consumer.Close(context.Background())
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.consumer = consumer
+	a.cancel = cancel
+	a.runDone = make(chan struct{})
+	go a.runProcessor(ctx, cancel, consumer, processor, a.runDone)
+
+	a.log.Infof("Receiving Azure Event Hubs messages from event hub '%v' as instance '%v'\n", a.conf.EventHub, a.conf.InstanceID)
+	return nil
+}
+
+// runProcessor runs the processor until it fails or the input is closed,
+// consuming each partition it claims.
+func (a *AzureEventHubs) runProcessor(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	consumer *azeventhubs.ConsumerClient,
+	processor *azeventhubs.Processor,
+	done chan struct{},
+) {
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+		consumer.Close(context.Background())
+
+		a.cMut.Lock()
+		a.consumer = nil
+		a.cancel = nil
+		a.cMut.Unlock()
+		close(done)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			pc := processor.NextPartitionClient(ctx)
+			if pc == nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				a.consumePartition(ctx, pc)
+			}()
+		}
+	}()
+
+	if err := processor.Run(ctx); err != nil && ctx.Err() == nil {
+		a.log.Errorf("Event Hubs processor failed: %v\n", err)
+	}
+}
+
+func (a *AzureEventHubs) consumePartition(ctx context.Context, pc *azeventhubs.ProcessorPartitionClient) {
+	id := pc.PartitionID()
+	p := &eventHubsPartition{client: pc, checkpointed: -1}
+
+	a.cMut.Lock()
+	a.partitions[id] = p
+	a.cMut.Unlock()
+
+	a.mPartAcquired.Incr(1)
+	a.log.Infof("Acquired partition %v\n", id)
+
+	defer func() {
+		a.cMut.Lock()
+		a.flushPartition(id, p)
+		if a.partitions[id] == p {
+			delete(a.partitions, id)
+		}
+		a.cMut.Unlock()
+
+		pc.Close(context.Background())
+		a.mPartReleased.Incr(1)
+	}()
+
+	for {
+		rctx, done := context.WithTimeout(ctx, time.Second*10)
+		events, err := pc.ReceiveEvents(rctx, a.conf.MaxBatchCount, nil)
+		done()
+
+		for _, e := range events {
+			select {
+			case a.msgChan <- eventHubsMsg{partition: p, event: e}:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			var ehErr *azeventhubs.Error
+			if errors.As(err, &ehErr) && ehErr.Code == azeventhubs.ErrorCodeOwnershipLost {
+				a.mPartLost.Incr(1)
+				a.log.Warnf("Lost ownership of partition %v\n", id)
+				return
+			}
+			a.mRcvErr.Incr(1)
+			a.log.Errorf("Partition %v recv error: %v\n", id, err)
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// flushPartition writes the latest acknowledged event of a partition to the
+// checkpoint store. Must be called with cMut held.
+func (a *AzureEventHubs) flushPartition(id string, p *eventHubsPartition) {
+	if p.acked == nil || p.acked.SequenceNumber <= p.checkpointed {
+		return
+	}
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+	if err := p.client.UpdateCheckpoint(ctx, p.acked, nil); err != nil {
+		a.mCheckpointE.Incr(1)
+		a.log.Errorf("Failed to checkpoint partition %v: %v\n", id, err)
+		return
+	}
+	a.mCheckpoint.Incr(1)
+	p.checkpointed = p.acked.SequenceNumber
+}
+
+// flushCheckpoints writes the latest acknowledged events of all owned
+// partitions to the checkpoint store. Must be called with cMut held.
+func (a *AzureEventHubs) flushCheckpoints() {
+	for id, p := range a.partitions {
+		a.flushPartition(id, p)
+	}
+	a.lastFlushed = time.Now()
+}
+
+//------------------------------------------------------------------------------
+
+// Read attempts to read a new message from any owned partition.
+func (a *AzureEventHubs) Read() (types.Message, error) {
+	a.cMut.Lock()
+	connected := a.consumer != nil
+	a.cMut.Unlock()
+	if !connected {
+		return nil, types.ErrNotConnected
+	}
+
+	msg := message.New(nil)
+	addPart := func(data eventHubsMsg) {
+		msg.Append(eventHubsPart(data.partition.client.PartitionID(), data.event))
+		if current, exists := a.pending[data.partition]; !exists || current.SequenceNumber < data.event.SequenceNumber {
+			a.pending[data.partition] = data.event
+		}
+	}
+
+	select {
+	case data := <-a.msgChan:
+		addPart(data)
+	case <-time.After(time.Second):
+		return nil, types.ErrTimeout
+	case <-a.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+
+batchLoop:
+	for i := 1; i < a.conf.MaxBatchCount; i++ {
+		select {
+		case data := <-a.msgChan:
+			addPart(data)
+		default:
+			break batchLoop
+		}
+	}
+	return msg, nil
+}
+
+// Acknowledge marks the events of read messages as ready to be checkpointed.
+func (a *AzureEventHubs) Acknowledge(err error) error {
+	if err != nil {
+		return nil
+	}
+
+	a.cMut.Lock()
+	defer a.cMut.Unlock()
+
+	for p, e := range a.pending {
+		// Events of partitions we no longer own are dropped, the new owner
+		// will redeliver them.
+		if a.partitions[p.client.PartitionID()] != p {
+			continue
+		}
+		if p.acked == nil || p.acked.SequenceNumber < e.SequenceNumber {
+			p.acked = e
+		}
+	}
+	a.pending = map[*eventHubsPartition]*azeventhubs.ReceivedEventData{}
+
+	if time.Since(a.lastFlushed) >= a.checkpointPeriod {
+		a.flushCheckpoints()
+	}
+	return nil
+}
+
+// CloseAsync shuts down the AzureEventHubs input and stops processing requests.
+func (a *AzureEventHubs) CloseAsync() {
+	a.closeOnce.Do(func() {
+		close(a.closeChan)
+		a.cMut.Lock()
+		if a.cancel != nil {
+			a.cancel()
+		}
+		runDone := a.runDone
+		a.cMut.Unlock()
+		go func() {
+			if runDone != nil {
+				<-runDone
+			}
+			close(a.closedChan)
+		}()
+	})
+}
+
+// WaitForClose blocks until the AzureEventHubs input has closed down.
+func (a *AzureEventHubs) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-a.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
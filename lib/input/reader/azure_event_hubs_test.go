@@ -0,0 +1,91 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azeventhubs"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestAzureEventHubsPart(t *testing.T) {
+	key := "foo"
+	enqueued := time.Unix(1500000000, 0)
+	part := eventHubsPart("3", &azeventhubs.ReceivedEventData{
+		EventData: azeventhubs.EventData{
+			Body:       []byte("hello world"),
+			Properties: map[string]interface{}{"bar": "baz", "qux": 10},
+		},
+		EnqueuedTime:   &enqueued,
+		PartitionKey:   &key,
+		Offset:         1024,
+		SequenceNumber: 5,
+	})
+
+	if exp, act := "hello world", string(part.Get()); exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+	exp := map[string]string{
+		"bar":                              "baz",
+		"qux":                              "10",
+		"azure_event_hubs_key":             "foo",
+		"azure_event_hubs_partition":       "3",
+		"azure_event_hubs_offset":          "1024",
+		"azure_event_hubs_sequence_number": "5",
+		"azure_event_hubs_timestamp_unix":  "1500000000",
+	}
+	act := map[string]string{}
+	part.Metadata().Iter(func(k, v string) error {
+		act[k] = v
+		return nil
+	})
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+func TestAzureEventHubsBadConfig(t *testing.T) {
+	conf := NewAzureEventHubsConfig()
+	conf.CheckpointStore.Account = "foo"
+	if _, err := NewAzureEventHubs(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing event hub")
+	}
+
+	conf.EventHub = "bar"
+	if _, err := NewAzureEventHubs(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing namespace")
+	}
+
+	conf.Namespace = "foo.servicebus.windows.net"
+	conf.LeaseDuration = "5s"
+	if _, err := NewAzureEventHubs(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from short lease duration")
+	}
+
+	conf.LeaseDuration = "30s"
+	if _, err := NewAzureEventHubs(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,397 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// AzureServiceBusConfig contains configuration fields for the AzureServiceBus
+// input type.
+type AzureServiceBusConfig struct {
+	ConnectionString  string `json:"connection_string" yaml:"connection_string"`
+	Namespace         string `json:"namespace" yaml:"namespace"`
+	Queue             string `json:"queue" yaml:"queue"`
+	Topic             string `json:"topic" yaml:"topic"`
+	Subscription      string `json:"subscription" yaml:"subscription"`
+	Timeout           string `json:"timeout" yaml:"timeout"`
+	LockRenewalPeriod string `json:"lock_renewal_period" yaml:"lock_renewal_period"`
+}
+
+// NewAzureServiceBusConfig creates a new AzureServiceBusConfig with default
+// values.
+func NewAzureServiceBusConfig() AzureServiceBusConfig {
+	return AzureServiceBusConfig{
+		ConnectionString:  "",
+		Namespace:         "",
+		Queue:             "",
+		Topic:             "",
+		Subscription:      "",
+		Timeout:           "30s",
+		LockRenewalPeriod: "20s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// serviceBusReceiver is the subset of the Service Bus receiver used by the
+// input.
+type serviceBusReceiver interface {
+	ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error)
+	CompleteMessage(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	RenewMessageLock(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.RenewMessageLockOptions) error
+	Close(ctx context.Context) error
+}
+
+// AzureServiceBus is an input type that receives messages from an Azure
+// Service Bus queue or topic subscription with peek-lock semantics.
+type AzureServiceBus struct {
+	conf  AzureServiceBusConfig
+	stats metrics.Type
+	log   log.Modular
+
+	entity      string
+	timeout     time.Duration
+	renewPeriod time.Duration
+	dial        func() (*azservicebus.Client, serviceBusReceiver, error)
+
+	rMut     sync.Mutex
+	client   *azservicebus.Client
+	receiver serviceBusReceiver
+
+	pending    []*azservicebus.ReceivedMessage
+	pendingMut sync.Mutex
+
+	mLockRenewed  metrics.StatCounter
+	mLockRenewErr metrics.StatCounter
+	mAbandoned    metrics.StatCounter
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewAzureServiceBus creates a new AzureServiceBus input type.
+func NewAzureServiceBus(conf AzureServiceBusConfig, log log.Modular, stats metrics.Type) (*AzureServiceBus, error) {
+	a := AzureServiceBus{
+		conf:       conf,
+		stats:      stats,
+		log:        log,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+
+		mLockRenewed:  stats.GetCounter("lock.renewed"),
+		mLockRenewErr: stats.GetCounter("lock.error"),
+		mAbandoned:    stats.GetCounter("abandoned"),
+	}
+
+	if len(conf.Queue) > 0 {
+		if len(conf.Topic) > 0 || len(conf.Subscription) > 0 {
+			return nil, errors.New("a queue cannot be specified alongside a topic or subscription")
+		}
+		a.entity = conf.Queue
+	} else if len(conf.Topic) > 0 && len(conf.Subscription) > 0 {
+		a.entity = conf.Topic + "/subscriptions/" + conf.Subscription
+	} else {
+		return nil, errors.New("either a queue or a topic and subscription must be specified")
+	}
+	if len(conf.ConnectionString) == 0 && len(conf.Namespace) == 0 {
+		return nil, errors.New("either a connection string or a namespace must be specified")
+	}
+
+	var err error
+	if tout := conf.Timeout; len(tout) > 0 {
+		if a.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if tout := conf.LockRenewalPeriod; len(tout) > 0 {
+		if a.renewPeriod, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse lock renewal period string: %v", err)
+		}
+	}
+	if a.timeout <= 0 {
+		a.timeout = time.Second * 30
+	}
+	a.dial = a.dialClient
+
+	if a.renewPeriod > 0 {
+		go a.renewLoop()
+	} else {
+		close(a.closedChan)
+	}
+	return &a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// dialClient creates a Service Bus client authenticated with either the
+// connection string or, when absent, the default Azure credential chain.
+func (a *AzureServiceBus) dialClient() (*azservicebus.Client, serviceBusReceiver, error) {
+	var client *azservicebus.Client
+	var err error
+	if len(a.conf.ConnectionString) > 0 {
+		client, err = azservicebus.NewClientFromConnectionString(a.conf.ConnectionString, nil)
+	} else {
+		var cred *azidentity.DefaultAzureCredential
+		if cred, err = azidentity.NewDefaultAzureCredential(nil); err != nil {
+			return nil, nil, err
+		}
+		client, err = azservicebus.NewClient(a.conf.Namespace, cred, nil)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var receiver *azservicebus.Receiver
+	opts := &azservicebus.ReceiverOptions{
+		ReceiveMode: azservicebus.ReceiveModePeekLock,
+	}
+	if len(a.conf.Queue) > 0 {
+		receiver, err = client.NewReceiverForQueue(a.conf.Queue, opts)
+	} else {
+		receiver, err = client.NewReceiverForSubscription(a.conf.Topic, a.conf.Subscription, opts)
+	}
+	if err != nil {
+		client.Close(context.Background())
+		return nil, nil, err
+	}
+	return client, receiver, nil
+}
+
+func (a *AzureServiceBus) renewLoop() {
+	defer close(a.closedChan)
+
+	ticker := time.NewTicker(a.renewPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-a.closeChan:
+			return
+		}
+
+		a.rMut.Lock()
+		receiver := a.receiver
+		a.rMut.Unlock()
+		if receiver == nil {
+			continue
+		}
+
+		a.pendingMut.Lock()
+		msgs := make([]*azservicebus.ReceivedMessage, len(a.pending))
+		copy(msgs, a.pending)
+		a.pendingMut.Unlock()
+
+		for _, m := range msgs {
+			ctx, done := context.WithTimeout(context.Background(), a.timeout)
+			err := receiver.RenewMessageLock(ctx, m, nil)
+			done()
+			if err != nil {
+				a.mLockRenewErr.Incr(1)
+				a.log.Errorf("Failed to renew message lock: %v\n", err)
+			} else {
+				a.mLockRenewed.Incr(1)
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a receiver for the queue or topic subscription.
+func (a *AzureServiceBus) Connect() error {
+	a.rMut.Lock()
+	defer a.rMut.Unlock()
+
+	if a.receiver != nil {
+		return nil
+	}
+	select {
+	case <-a.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	client, receiver, err := a.dial()
+	if err != nil {
+		return err
+	}
+	a.client = client
+	a.receiver = receiver
+
+	a.log.Infof("Receiving Azure Service Bus messages from: %v\n", a.entity)
+	return nil
+}
+
+// serviceBusMessage converts a received Service Bus message into a message
+// with its properties added as metadata.
+func serviceBusMessage(m *azservicebus.ReceivedMessage) types.Message {
+	msg := message.New([][]byte{m.Body})
+	meta := msg.Get(0).Metadata()
+	for k, v := range m.ApplicationProperties {
+		meta.Set(k, fmt.Sprintf("%v", v))
+	}
+	meta.Set("azure_service_bus_message_id", m.MessageID)
+	meta.Set("azure_service_bus_delivery_count", strconv.FormatUint(uint64(m.DeliveryCount), 10))
+	if m.SequenceNumber != nil {
+		meta.Set("azure_service_bus_sequence_number", strconv.FormatInt(*m.SequenceNumber, 10))
+	}
+	if m.EnqueuedTime != nil {
+		meta.Set("azure_service_bus_enqueued_time", m.EnqueuedTime.Format(time.RFC3339))
+	}
+	if m.CorrelationID != nil {
+		meta.Set("azure_service_bus_correlation_id", *m.CorrelationID)
+	}
+	if m.SessionID != nil {
+		meta.Set("azure_service_bus_session_id", *m.SessionID)
+	}
+	if m.Subject != nil {
+		meta.Set("azure_service_bus_label", *m.Subject)
+	}
+	if m.ContentType != nil {
+		meta.Set("azure_service_bus_content_type", *m.ContentType)
+	}
+	return msg
+}
+
+// Read attempts to receive and lock a message from the queue or subscription.
+func (a *AzureServiceBus) Read() (types.Message, error) {
+	a.rMut.Lock()
+	receiver := a.receiver
+	a.rMut.Unlock()
+
+	if receiver == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), a.timeout)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-a.closeChan:
+			done()
+		}
+	}()
+	msgs, err := receiver.ReceiveMessages(ctx, 1, nil)
+	done()
+
+	select {
+	case <-a.closeChan:
+		return nil, types.ErrTypeClosed
+	default:
+	}
+	if err != nil && err != context.DeadlineExceeded {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, types.ErrTimeout
+	}
+
+	a.pendingMut.Lock()
+	a.pending = append(a.pending, msgs[0])
+	a.pendingMut.Unlock()
+
+	return serviceBusMessage(msgs[0]), nil
+}
+
+// Acknowledge completes locked messages if they were successfully propagated,
+// otherwise they are abandoned, which makes them available for redelivery.
+func (a *AzureServiceBus) Acknowledge(err error) error {
+	a.rMut.Lock()
+	receiver := a.receiver
+	a.rMut.Unlock()
+
+	a.pendingMut.Lock()
+	msgs := a.pending
+	a.pending = nil
+	a.pendingMut.Unlock()
+
+	if receiver == nil {
+		return types.ErrNotConnected
+	}
+	if err != nil {
+		a.mAbandoned.Incr(int64(len(msgs)))
+	}
+
+	var lErr error
+	for _, m := range msgs {
+		ctx, done := context.WithTimeout(context.Background(), a.timeout)
+		var sErr error
+		if err == nil {
+			sErr = receiver.CompleteMessage(ctx, m, nil)
+		} else {
+			sErr = receiver.AbandonMessage(ctx, m, nil)
+		}
+		done()
+		if sErr != nil {
+			lErr = sErr
+		}
+	}
+	return lErr
+}
+
+// CloseAsync shuts down the AzureServiceBus input and stops processing
+// requests.
+func (a *AzureServiceBus) CloseAsync() {
+	a.closeOnce.Do(func() {
+		close(a.closeChan)
+
+		a.rMut.Lock()
+		if a.receiver != nil {
+			a.receiver.Close(context.Background())
+			a.receiver = nil
+		}
+		if a.client != nil {
+			a.client.Close(context.Background())
+			a.client = nil
+		}
+		a.rMut.Unlock()
+	})
+}
+
+// WaitForClose blocks until the AzureServiceBus input has closed down.
+func (a *AzureServiceBus) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-a.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
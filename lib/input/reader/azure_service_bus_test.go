@@ -0,0 +1,188 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockServiceBusReceiver struct {
+	mut   sync.Mutex
+	queue []*azservicebus.ReceivedMessage
+	ops   []string
+}
+
+func (m *mockServiceBusReceiver) ReceiveMessages(ctx context.Context, maxMessages int, options *azservicebus.ReceiveMessagesOptions) ([]*azservicebus.ReceivedMessage, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.ops = append(m.ops, "receive")
+	if len(m.queue) == 0 {
+		return nil, context.DeadlineExceeded
+	}
+	msg := m.queue[0]
+	m.queue = m.queue[1:]
+	return []*azservicebus.ReceivedMessage{msg}, nil
+}
+
+func (m *mockServiceBusReceiver) CompleteMessage(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error {
+	m.mut.Lock()
+	m.ops = append(m.ops, "complete "+msg.MessageID)
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *mockServiceBusReceiver) AbandonMessage(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error {
+	m.mut.Lock()
+	m.ops = append(m.ops, "abandon "+msg.MessageID)
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *mockServiceBusReceiver) RenewMessageLock(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.RenewMessageLockOptions) error {
+	m.mut.Lock()
+	m.ops = append(m.ops, "renew "+msg.MessageID)
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *mockServiceBusReceiver) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func TestAzureServiceBusReceive(t *testing.T) {
+	seq := int64(5)
+	label := "baz"
+	receiver := &mockServiceBusReceiver{
+		queue: []*azservicebus.ReceivedMessage{
+			{
+				MessageID:             "foo_id",
+				Body:                  []byte("foo"),
+				DeliveryCount:         2,
+				SequenceNumber:        &seq,
+				Subject:               &label,
+				ApplicationProperties: map[string]interface{}{"qux": 10},
+			},
+			{MessageID: "bar_id", Body: []byte("bar")},
+		},
+	}
+
+	conf := NewAzureServiceBusConfig()
+	conf.ConnectionString = "foo"
+	conf.Queue = "foo_queue"
+	conf.LockRenewalPeriod = ""
+
+	a, err := NewAzureServiceBus(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.dial = func() (*azservicebus.Client, serviceBusReceiver, error) {
+		return nil, receiver, nil
+	}
+	if err = a.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := a.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+	meta := msg.Get(0).Metadata()
+	for k, exp := range map[string]string{
+		"azure_service_bus_message_id":      "foo_id",
+		"azure_service_bus_delivery_count":  "2",
+		"azure_service_bus_sequence_number": "5",
+		"azure_service_bus_label":           "baz",
+		"qux":                               "10",
+	} {
+		if act := meta.Get(k); exp != act {
+			t.Errorf("Wrong metadata %v: %v != %v", k, act, exp)
+		}
+	}
+	if err = a.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = a.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTimeout)
+	}
+
+	exp := []string{
+		"receive",
+		"abandon foo_id",
+		"receive",
+		"complete bar_id",
+		"receive",
+	}
+	receiver.mut.Lock()
+	if !reflect.DeepEqual(exp, receiver.ops) {
+		t.Errorf("Wrong operations: %v != %v", receiver.ops, exp)
+	}
+	receiver.mut.Unlock()
+
+	a.CloseAsync()
+	if err = a.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAzureServiceBusBadConfig(t *testing.T) {
+	conf := NewAzureServiceBusConfig()
+	conf.ConnectionString = "foo"
+	if _, err := NewAzureServiceBus(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing entity")
+	}
+
+	conf.Queue = "foo"
+	conf.Topic = "bar"
+	if _, err := NewAzureServiceBus(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from queue and topic")
+	}
+
+	conf = NewAzureServiceBusConfig()
+	conf.Queue = "foo"
+	if _, err := NewAzureServiceBus(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing credentials")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Batcher is a wrapper for reader.Type implementations that combines messages
+// into batches according to a batch policy. Once the period of a pending batch
+// has passed it is flushed even if no more messages arrive, provided that the
+// wrapped reader either implements DeadlineReader or returns types.ErrTimeout
+// when idle. Batcher implements reader.Type.
+type Batcher struct {
+	batcher *processor.Batch
+
+	r Type
+}
+
+// NewBatcher returns a new Batcher wrapper around a reader.Type.
+func NewBatcher(
+	conf processor.BatchConfig,
+	r Type,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (*Batcher, error) {
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeBatch
+	procConf.Batch = conf
+	proc, err := processor.NewBatch(procConf, mgr, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return &Batcher{
+		batcher: proc.(*processor.Batch),
+		r:       r,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the source, if unsuccessful
+// returns an error. If the attempt is successful (or not necessary) returns
+// nil.
+func (b *Batcher) Connect() error {
+	return b.r.Connect()
+}
+
+// Acknowledge instructs whether messages read since the last Acknowledge call
+// were successfully propagated, which is forwarded to the wrapped reader.
+func (b *Batcher) Acknowledge(err error) error {
+	return b.r.Acknowledge(err)
+}
+
+// Read attempts to read a batch of messages from the source. If the pending
+// batch expires whilst waiting for more messages then it is returned as it is.
+func (b *Batcher) Read() (types.Message, error) {
+	for {
+		var msg types.Message
+		var err error
+
+		if until := b.batcher.UntilNext(); until == 0 {
+			err = types.ErrTimeout
+		} else if dr, ok := b.r.(DeadlineReader); ok && until > 0 {
+			msg, err = dr.ReadWithDeadline(time.Now().Add(until))
+		} else {
+			msg, err = b.r.Read()
+		}
+
+		if err != nil {
+			if err == types.ErrTimeout {
+				if batch := b.batcher.FlushExpired(); batch != nil {
+					return batch, nil
+				}
+			}
+			return nil, err
+		}
+		if msgs, _ := b.batcher.ProcessMessage(msg); len(msgs) > 0 {
+			return msgs[0], nil
+		}
+	}
+}
+
+// CloseAsync triggers the asynchronous closing of the reader.
+func (b *Batcher) CloseAsync() {
+	b.r.CloseAsync()
+}
+
+// WaitForClose blocks until either the reader is finished closing or a timeout
+// occurs.
+func (b *Batcher) WaitForClose(tout time.Duration) error {
+	return b.r.WaitForClose(tout)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,172 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockChanReader struct {
+	msgs chan types.Message
+	acks []error
+}
+
+func (r *mockChanReader) Connect() error {
+	return nil
+}
+func (r *mockChanReader) Read() (types.Message, error) {
+	select {
+	case msg := <-r.msgs:
+		return msg, nil
+	case <-time.After(time.Millisecond * 10):
+	}
+	return nil, types.ErrTimeout
+}
+func (r *mockChanReader) Acknowledge(err error) error {
+	r.acks = append(r.acks, err)
+	return nil
+}
+func (r *mockChanReader) CloseAsync() {}
+func (r *mockChanReader) WaitForClose(time.Duration) error {
+	return nil
+}
+
+type mockDeadlineReader struct {
+	*mockChanReader
+}
+
+func (r mockDeadlineReader) Read() (types.Message, error) {
+	return r.ReadWithDeadline(time.Time{})
+}
+func (r mockDeadlineReader) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+	select {
+	case msg := <-r.msgs:
+		return msg, nil
+	case <-timeoutChan:
+	}
+	return nil, types.ErrTimeout
+}
+
+//------------------------------------------------------------------------------
+
+func TestBatcherCount(t *testing.T) {
+	rdr := &mockChanReader{msgs: make(chan types.Message, 10)}
+
+	conf := processor.NewBatchConfig()
+	conf.Count = 3
+
+	b, err := NewBatcher(conf, rdr, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}
+	for _, p := range exp {
+		rdr.msgs <- message.New([][]byte{p})
+	}
+
+	msg, err := b.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := message.GetAllBytes(msg); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong batch: %s != %s", act, exp)
+	}
+
+	if _, err = b.Read(); err != types.ErrTimeout {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err = b.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+	if exp, act := []error{nil}, rdr.acks; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong acks: %v != %v", act, exp)
+	}
+}
+
+func TestBatcherPeriodTimeout(t *testing.T) {
+	rdr := &mockChanReader{msgs: make(chan types.Message, 10)}
+
+	conf := processor.NewBatchConfig()
+	conf.Count = 10
+	conf.Period = "50ms"
+
+	b, err := NewBatcher(conf, rdr, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{[]byte("foo"), []byte("bar")}
+	for _, p := range exp {
+		rdr.msgs <- message.New([][]byte{p})
+	}
+
+	var msg types.Message
+	for msg == nil {
+		if msg, err = b.Read(); err != nil && err != types.ErrTimeout {
+			t.Fatal(err)
+		}
+	}
+	if act := message.GetAllBytes(msg); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong batch: %s != %s", act, exp)
+	}
+}
+
+func TestBatcherPeriodDeadline(t *testing.T) {
+	rdr := &mockChanReader{msgs: make(chan types.Message, 10)}
+
+	conf := processor.NewBatchConfig()
+	conf.Count = 10
+	conf.Period = "50ms"
+
+	b, err := NewBatcher(conf, mockDeadlineReader{rdr}, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]byte{[]byte("foo"), []byte("bar")}
+	for _, p := range exp {
+		rdr.msgs <- message.New([][]byte{p})
+	}
+
+	msg, err := b.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := message.GetAllBytes(msg); !reflect.DeepEqual(act, exp) {
+		t.Errorf("Wrong batch: %s != %s", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,172 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/cron"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// CronConfig contains configuration fields for the Cron input type.
+type CronConfig struct {
+	Schedule string `json:"schedule" yaml:"schedule"`
+	Timezone string `json:"timezone" yaml:"timezone"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// NewCronConfig creates a new CronConfig with default values.
+func NewCronConfig() CronConfig {
+	return CronConfig{
+		Schedule: "",
+		Timezone: "UTC",
+		Message:  "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Cron is a reader that emits a message each time a cron schedule activates.
+type Cron struct {
+	conf     CronConfig
+	schedule *cron.Schedule
+	message  []byte
+	dynamic  bool
+
+	lastTrigger time.Time
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewCron creates a new Cron reader type.
+func NewCron(
+	conf CronConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Cron, error) {
+	location := time.UTC
+	if len(conf.Timezone) > 0 {
+		var err error
+		if location, err = time.LoadLocation(conf.Timezone); err != nil {
+			return nil, fmt.Errorf("failed to load timezone: %v", err)
+		}
+	}
+	schedule, err := cron.Parse(conf.Schedule, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schedule: %v", err)
+	}
+	msg := []byte(conf.Message)
+	return &Cron{
+		conf:       conf,
+		schedule:   schedule,
+		message:    msg,
+		dynamic:    text.ContainsFunctionVariables(msg),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log,
+		stats:      stats,
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect is a noop since messages are generated locally.
+func (c *Cron) Connect() error {
+	c.log.Infof("Emitting messages on cron schedule: %v (%v)\n", c.conf.Schedule, c.conf.Timezone)
+	return nil
+}
+
+// Read blocks until the next activation of the schedule and then returns a
+// new message. Activations missed whilst a previous message was being
+// processed are skipped.
+func (c *Cron) Read() (types.Message, error) {
+	from := time.Now()
+	if from.Before(c.lastTrigger) {
+		from = c.lastTrigger
+	}
+	next := c.schedule.Next(from)
+	if next.IsZero() {
+		c.log.Errorf("Cron schedule '%v' has no future activations\n", c.conf.Schedule)
+		return nil, types.ErrTypeClosed
+	}
+
+	timer := time.NewTimer(time.Until(next))
+	select {
+	case <-timer.C:
+	case <-c.closeChan:
+		timer.Stop()
+		return nil, types.ErrTypeClosed
+	}
+	c.lastTrigger = next
+
+	msg := message.New(nil)
+	var content []byte
+	if c.dynamic {
+		content = text.ReplaceFunctionVariables(msg, c.message)
+	} else {
+		content = append([]byte(nil), c.message...)
+	}
+	part := message.NewPart(content)
+	part.Metadata().Set("cron_schedule", c.conf.Schedule)
+	part.Metadata().Set("cron_time", next.Format(time.RFC3339))
+	msg.Append(part)
+	return msg, nil
+}
+
+// Acknowledge is a noop since triggered messages cannot be redelivered.
+func (c *Cron) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (c *Cron) CloseAsync() {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+		close(c.closedChan)
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (c *Cron) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-c.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,117 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestCronRead(t *testing.T) {
+	conf := NewCronConfig()
+	conf.Schedule = "* * * * * *"
+	conf.Message = `{"trigger":"${!metadata:nope}foo"}`
+
+	c, err := NewCron(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		c.CloseAsync()
+		if err := c.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	var lastTime string
+	for i := 0; i < 2; i++ {
+		msg, err := c.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := `{"trigger":"foo"}`, string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong content: %v != %v", act, exp)
+		}
+		if exp, act := "* * * * * *", msg.Get(0).Metadata().Get("cron_schedule"); exp != act {
+			t.Errorf("Wrong schedule metadata: %v != %v", act, exp)
+		}
+		cronTime := msg.Get(0).Metadata().Get("cron_time")
+		if _, err = time.Parse(time.RFC3339, cronTime); err != nil {
+			t.Errorf("Bad cron_time metadata: %v", err)
+		}
+		if cronTime == lastTime {
+			t.Errorf("Duplicate trigger time: %v", cronTime)
+		}
+		lastTime = cronTime
+	}
+}
+
+func TestCronCloseWhileWaiting(t *testing.T) {
+	conf := NewCronConfig()
+	conf.Schedule = "@yearly"
+
+	c, err := NewCron(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		c.CloseAsync()
+	}()
+
+	if _, err = c.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Expected ErrTypeClosed, received: %v", err)
+	}
+	if err = c.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCronBadConfig(t *testing.T) {
+	conf := NewCronConfig()
+	conf.Schedule = "not a schedule"
+	if _, err := NewCron(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad schedule")
+	}
+
+	conf = NewCronConfig()
+	conf.Schedule = "@daily"
+	conf.Timezone = "Nowhere/Special"
+	if _, err := NewCron(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad timezone")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,232 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// CSVConfig contains configuration fields for the CSV input type.
+type CSVConfig struct {
+	Paths          []string `json:"paths" yaml:"paths"`
+	ParseHeaderRow bool     `json:"parse_header_row" yaml:"parse_header_row"`
+	Delim          string   `json:"delimiter" yaml:"delimiter"`
+	LazyQuotes     bool     `json:"lazy_quotes" yaml:"lazy_quotes"`
+}
+
+// NewCSVConfig creates a new CSVConfig with default values.
+func NewCSVConfig() CSVConfig {
+	return CSVConfig{
+		Paths:          []string{},
+		ParseHeaderRow: true,
+		Delim:          ",",
+		LazyQuotes:     false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CSV is a reader type that reads rows from CSV files or stdin, emitting each
+// row as a JSON document.
+type CSV struct {
+	conf  CSVConfig
+	delim rune
+
+	handleMut sync.Mutex
+	remaining []string
+	path      string
+	handle    io.ReadCloser
+	scanner   *csv.Reader
+	headers   []string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRows    metrics.StatCounter
+	mRowsErr metrics.StatCounter
+}
+
+// NewCSV creates a new CSV reader type.
+func NewCSV(conf CSVConfig, log log.Modular, stats metrics.Type) (*CSV, error) {
+	if len(conf.Paths) == 0 {
+		return nil, errors.New("at least one path must be specified")
+	}
+	if utf8.RuneCountInString(conf.Delim) != 1 {
+		return nil, fmt.Errorf("delimiter must be a single character, got '%v'", conf.Delim)
+	}
+	delim, _ := utf8.DecodeRuneInString(conf.Delim)
+	if delim == '"' || delim == '\r' || delim == '\n' || delim == utf8.RuneError {
+		return nil, fmt.Errorf("invalid delimiter: '%v'", conf.Delim)
+	}
+	return &CSV{
+		conf:      conf,
+		delim:     delim,
+		remaining: append([]string{}, conf.Paths...),
+		log:       log,
+		stats:     stats,
+		mRows:     stats.GetCounter("rows"),
+		mRowsErr:  stats.GetCounter("rows.error"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *CSV) closeHandle() {
+	if c.handle != nil {
+		if c.handle != os.Stdin {
+			c.handle.Close()
+		}
+		c.handle = nil
+	}
+	c.scanner = nil
+	c.headers = nil
+}
+
+// Connect opens the next file in the list of paths and, if configured, parses
+// its header row.
+func (c *CSV) Connect() error {
+	c.handleMut.Lock()
+	defer c.handleMut.Unlock()
+
+	if c.scanner != nil {
+		return nil
+	}
+
+	for len(c.remaining) > 0 {
+		c.path = c.remaining[0]
+		c.remaining = c.remaining[1:]
+
+		if c.path == "-" {
+			c.handle = os.Stdin
+		} else {
+			file, err := os.Open(c.path)
+			if err != nil {
+				return err
+			}
+			c.handle = file
+		}
+
+		c.scanner = csv.NewReader(c.handle)
+		c.scanner.Comma = c.delim
+		c.scanner.LazyQuotes = c.conf.LazyQuotes
+		c.scanner.FieldsPerRecord = -1
+
+		if !c.conf.ParseHeaderRow {
+			break
+		}
+
+		headers, err := c.scanner.Read()
+		if err == nil {
+			c.headers = headers
+			break
+		}
+		c.closeHandle()
+		if err != io.EOF {
+			return fmt.Errorf("failed to parse header row of '%v': %v", c.path, err)
+		}
+		c.log.Warnf("CSV file '%v' is empty\n", c.path)
+	}
+	if c.scanner == nil {
+		return types.ErrTypeClosed
+	}
+
+	c.log.Infof("Reading CSV rows from: %v\n", c.path)
+	return nil
+}
+
+// Read attempts to read a new row from the current CSV file.
+func (c *CSV) Read() (types.Message, error) {
+	c.handleMut.Lock()
+	defer c.handleMut.Unlock()
+
+	if c.scanner == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	record, err := c.scanner.Read()
+	if err != nil {
+		if err == io.EOF {
+			c.closeHandle()
+			return nil, types.ErrNotConnected
+		}
+		c.mRowsErr.Incr(1)
+		return nil, err
+	}
+
+	var rowBytes []byte
+	if c.headers != nil {
+		if len(record) != len(c.headers) {
+			c.mRowsErr.Incr(1)
+			return nil, fmt.Errorf(
+				"row of '%v' has %v fields, expected %v",
+				c.path, len(record), len(c.headers),
+			)
+		}
+		obj := make(map[string]interface{}, len(record))
+		for i, v := range record {
+			obj[c.headers[i]] = v
+		}
+		rowBytes, err = json.Marshal(obj)
+	} else {
+		rowBytes, err = json.Marshal(record)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mRows.Incr(1)
+	return message.New([][]byte{rowBytes}), nil
+}
+
+// Acknowledge instructs whether the pending messages were propagated
+// successfully.
+func (c *CSV) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the CSV input and stops processing requests.
+func (c *CSV) CloseAsync() {
+}
+
+// WaitForClose blocks until the CSV input has closed down.
+func (c *CSV) WaitForClose(timeout time.Duration) error {
+	c.handleMut.Lock()
+	c.closeHandle()
+	c.handleMut.Unlock()
+	return nil
+}
+
+//------------------------------------------------------------------------------
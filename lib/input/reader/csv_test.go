@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func writeCSVTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "benthos_csv_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readAllCSV(t *testing.T, c *CSV) []string {
+	t.Helper()
+	var rows []string
+	for {
+		if err := c.Connect(); err != nil {
+			if err == types.ErrTypeClosed {
+				return rows
+			}
+			t.Fatal(err)
+		}
+		msg, err := c.Read()
+		if err == types.ErrNotConnected {
+			continue
+		}
+		if err != nil {
+			rows = append(rows, "error")
+			continue
+		}
+		rows = append(rows, string(msg.Get(0).Get()))
+		if err = c.Acknowledge(nil); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCSVWithHeaders(t *testing.T) {
+	dir := writeCSVTestFiles(t, map[string]string{
+		"a.csv": "id,name\n1,foo\n2,\"bar, baz\"\n3\n",
+		"b.csv": "",
+		"c.csv": "id,name\n4,qux",
+	})
+	defer os.RemoveAll(dir)
+
+	conf := NewCSVConfig()
+	conf.Paths = []string{
+		filepath.Join(dir, "a.csv"),
+		filepath.Join(dir, "b.csv"),
+		filepath.Join(dir, "c.csv"),
+	}
+
+	c, err := NewCSV(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		`{"id":"1","name":"foo"}`,
+		`{"id":"2","name":"bar, baz"}`,
+		"error",
+		`{"id":"4","name":"qux"}`,
+	}
+	act := readAllCSV(t, c)
+	if len(exp) != len(act) {
+		t.Fatalf("Wrong count of rows: %v != %v", act, exp)
+	}
+	for i := range exp {
+		if exp[i] != act[i] {
+			t.Errorf("Wrong row %v: %v != %v", i, act[i], exp[i])
+		}
+	}
+
+	c.CloseAsync()
+	if err = c.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCSVNoHeadersCustomDelim(t *testing.T) {
+	dir := writeCSVTestFiles(t, map[string]string{
+		"a.csv": "1;fo\"o\n2;bar;baz\n",
+	})
+	defer os.RemoveAll(dir)
+
+	conf := NewCSVConfig()
+	conf.Paths = []string{filepath.Join(dir, "a.csv")}
+	conf.ParseHeaderRow = false
+	conf.Delim = ";"
+	conf.LazyQuotes = true
+
+	c, err := NewCSV(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		`["1","fo\"o"]`,
+		`["2","bar","baz"]`,
+	}
+	act := readAllCSV(t, c)
+	if len(exp) != len(act) {
+		t.Fatalf("Wrong count of rows: %v != %v", act, exp)
+	}
+	for i := range exp {
+		if exp[i] != act[i] {
+			t.Errorf("Wrong row %v: %v != %v", i, act[i], exp[i])
+		}
+	}
+}
+
+func TestCSVBadConfig(t *testing.T) {
+	conf := NewCSVConfig()
+	if _, err := NewCSV(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing paths")
+	}
+
+	conf.Paths = []string{"foo.csv"}
+	for _, delim := range []string{"", "ab", "\"", "\n"} {
+		conf.Delim = delim
+		if _, err := NewCSV(conf, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error from delimiter '%v'", delim)
+		}
+	}
+}
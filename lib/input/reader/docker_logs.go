@@ -0,0 +1,357 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/docker"
+)
+
+//------------------------------------------------------------------------------
+
+// DockerLogsConfig contains configuration fields for the DockerLogs input
+// type.
+type DockerLogsConfig struct {
+	Host            string   `json:"host" yaml:"host"`
+	Labels          []string `json:"labels" yaml:"labels"`
+	Stdout          bool     `json:"stdout" yaml:"stdout"`
+	Stderr          bool     `json:"stderr" yaml:"stderr"`
+	StartFromOldest bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	RefreshInterval string   `json:"refresh_interval" yaml:"refresh_interval"`
+}
+
+// NewDockerLogsConfig creates a new DockerLogsConfig with default values.
+func NewDockerLogsConfig() DockerLogsConfig {
+	return DockerLogsConfig{
+		Host:            "unix:///var/run/docker.sock",
+		Labels:          []string{},
+		Stdout:          true,
+		Stderr:          true,
+		StartFromOldest: false,
+		RefreshInterval: "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DockerLogs is a reader that tails the logs of Docker containers selected by
+// label filters.
+type DockerLogs struct {
+	conf            DockerLogsConfig
+	refreshInterval time.Duration
+	filters         map[string][]string
+
+	client    *docker.Client
+	startedAt time.Time
+
+	tailsMut sync.Mutex
+	tails    map[string]struct{}
+	lastSeen map[string]time.Time
+
+	msgChan chan types.Message
+
+	connectMut sync.Mutex
+	connected  bool
+	ctx        context.Context
+	cancel     func()
+	wg         sync.WaitGroup
+
+	closeOnce  sync.Once
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mTailStarted metrics.StatCounter
+	mTailEnded   metrics.StatCounter
+	mTailErr     metrics.StatCounter
+	mRefreshErr  metrics.StatCounter
+}
+
+// NewDockerLogs creates a new DockerLogs reader type.
+func NewDockerLogs(
+	conf DockerLogsConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*DockerLogs, error) {
+	refreshInterval := time.Second * 10
+	if tout := conf.RefreshInterval; len(tout) > 0 {
+		var err error
+		if refreshInterval, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse refresh interval string: %v", err)
+		}
+	}
+	if !conf.Stdout && !conf.Stderr {
+		return nil, fmt.Errorf("at least one of stdout or stderr must be enabled")
+	}
+	client, err := docker.NewClient(conf.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := map[string][]string{
+		"status": {"running"},
+	}
+	if len(conf.Labels) > 0 {
+		filters["label"] = conf.Labels
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DockerLogs{
+		conf:            conf,
+		refreshInterval: refreshInterval,
+		filters:         filters,
+		client:          client,
+		tails:           map[string]struct{}{},
+		lastSeen:        map[string]time.Time{},
+		msgChan:         make(chan types.Message),
+		ctx:             ctx,
+		cancel:          cancel,
+		closedChan:      make(chan struct{}),
+		log:             log,
+		stats:           stats,
+
+		mTailStarted: stats.GetCounter("docker_logs.tail.started"),
+		mTailEnded:   stats.GetCounter("docker_logs.tail.ended"),
+		mTailErr:     stats.GetCounter("docker_logs.tail.error"),
+		mRefreshErr:  stats.GetCounter("docker_logs.refresh.error"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect lists the containers that match the label filters and begins tailing
+// their logs, containers started later are discovered periodically.
+func (d *DockerLogs) Connect() error {
+	d.connectMut.Lock()
+	defer d.connectMut.Unlock()
+
+	if d.connected {
+		return nil
+	}
+	if d.ctx.Err() != nil {
+		return types.ErrTypeClosed
+	}
+
+	containers, err := d.client.ListContainers(d.ctx, d.filters)
+	if err != nil {
+		return err
+	}
+
+	if !d.conf.StartFromOldest {
+		d.startedAt = time.Now()
+	}
+	d.startTails(containers)
+
+	d.wg.Add(1)
+	go d.refreshLoop()
+
+	d.connected = true
+	d.log.Infof("Tailing logs of %v Docker containers from: %v\n", len(containers), d.conf.Host)
+	return nil
+}
+
+func (d *DockerLogs) refreshLoop() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-time.After(d.refreshInterval):
+		case <-d.ctx.Done():
+			return
+		}
+		containers, err := d.client.ListContainers(d.ctx, d.filters)
+		if err != nil {
+			if d.ctx.Err() != nil {
+				return
+			}
+			d.mRefreshErr.Incr(1)
+			d.log.Errorf("Failed to list containers: %v\n", err)
+			continue
+		}
+		d.startTails(containers)
+	}
+}
+
+func (d *DockerLogs) startTails(containers []docker.Container) {
+	d.tailsMut.Lock()
+	defer d.tailsMut.Unlock()
+
+	for _, c := range containers {
+		if _, exists := d.tails[c.ID]; exists {
+			continue
+		}
+		since := d.startedAt
+		if last, exists := d.lastSeen[c.ID]; exists {
+			since = last.Add(time.Nanosecond)
+		}
+		d.tails[c.ID] = struct{}{}
+		d.wg.Add(1)
+		go d.tail(c, since)
+	}
+}
+
+func (d *DockerLogs) tail(c docker.Container, since time.Time) {
+	defer func() {
+		d.tailsMut.Lock()
+		delete(d.tails, c.ID)
+		d.tailsMut.Unlock()
+		d.mTailEnded.Incr(1)
+		d.wg.Done()
+	}()
+
+	d.mTailStarted.Incr(1)
+	d.log.Debugf("Tailing logs of container %v (%v)\n", c.Name(), c.ID)
+
+	tty, err := d.client.ContainerTTY(d.ctx, c.ID)
+	if err != nil {
+		if d.ctx.Err() == nil {
+			d.mTailErr.Incr(1)
+			d.log.Errorf("Failed to inspect container %v: %v\n", c.ID, err)
+		}
+		return
+	}
+
+	logs, err := d.client.Logs(d.ctx, c.ID, docker.LogsOptions{
+		Follow:     true,
+		Stdout:     d.conf.Stdout,
+		Stderr:     d.conf.Stderr,
+		Timestamps: true,
+		Since:      since,
+	})
+	if err != nil {
+		if d.ctx.Err() == nil {
+			d.mTailErr.Incr(1)
+			d.log.Errorf("Failed to open logs of container %v: %v\n", c.ID, err)
+		}
+		return
+	}
+	defer logs.Close()
+
+	rdr := docker.NewLogReader(logs, !tty, true)
+	for {
+		line, err := rdr.Next()
+		if err != nil {
+			if err != io.EOF && d.ctx.Err() == nil {
+				d.mTailErr.Incr(1)
+				d.log.Errorf("Failed to read logs of container %v: %v\n", c.ID, err)
+			}
+			return
+		}
+
+		part := message.NewPart(line.Content)
+		meta := part.Metadata()
+		meta.Set("docker_container_id", c.ID)
+		meta.Set("docker_container_name", c.Name())
+		meta.Set("docker_container_image", c.Image)
+		meta.Set("docker_stream", string(line.Stream))
+		if !line.Timestamp.IsZero() {
+			meta.Set("docker_timestamp", line.Timestamp.Format(time.RFC3339Nano))
+		}
+		for k, v := range c.Labels {
+			meta.Set("docker_label_"+k, v)
+		}
+		msg := message.New(nil)
+		msg.Append(part)
+
+		select {
+		case d.msgChan <- msg:
+		case <-d.ctx.Done():
+			return
+		}
+
+		if !line.Timestamp.IsZero() {
+			d.tailsMut.Lock()
+			d.lastSeen[c.ID] = line.Timestamp
+			d.tailsMut.Unlock()
+		}
+	}
+}
+
+// Read attempts to read a log line from any of the tailed containers.
+func (d *DockerLogs) Read() (types.Message, error) {
+	return d.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a log line from any of the tailed
+// containers, returning types.ErrTimeout if none arrives before the deadline.
+func (d *DockerLogs) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	d.connectMut.Lock()
+	connected := d.connected
+	d.connectMut.Unlock()
+
+	if !connected {
+		return nil, types.ErrNotConnected
+	}
+
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	select {
+	case msg := <-d.msgChan:
+		return msg, nil
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
+	case <-d.ctx.Done():
+	}
+	return nil, types.ErrTypeClosed
+}
+
+// Acknowledge is a noop since logs are not replayed.
+func (d *DockerLogs) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (d *DockerLogs) CloseAsync() {
+	d.closeOnce.Do(func() {
+		d.cancel()
+		go func() {
+			// Wait for any in progress connection attempt before waiting on
+			// tails, as it may still be adding to the wait group.
+			d.connectMut.Lock()
+			d.connectMut.Unlock()
+			d.wg.Wait()
+			close(d.closedChan)
+		}()
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (d *DockerLogs) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
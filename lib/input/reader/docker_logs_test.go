@@ -0,0 +1,182 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func dockerFrame(stream byte, data string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestDockerLogsTail(t *testing.T) {
+	var containersMut sync.Mutex
+	containers := `[{"Id":"aaa","Names":["/foo_1"],"Image":"foo","Labels":{"app":"foo"}}]`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			if exp, act := `{"label":["app=foo"],"status":["running"]}`, r.URL.Query().Get("filters"); exp != act {
+				t.Errorf("Wrong filters: %v != %v", act, exp)
+			}
+			containersMut.Lock()
+			w.Write([]byte(containers))
+			containersMut.Unlock()
+		case "/containers/aaa/json":
+			w.Write([]byte(`{"Config":{"Tty":false}}`))
+		case "/containers/bbb/json":
+			w.Write([]byte(`{"Config":{"Tty":true}}`))
+		case "/containers/aaa/logs":
+			if r.URL.Query().Get("since") == "" {
+				t.Error("Expected since parameter")
+			}
+			w.Write(dockerFrame(1, "2019-01-01T00:00:00Z hello world\n"))
+			w.Write(dockerFrame(2, "2019-01-01T00:00:01Z uh oh\n"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/containers/bbb/logs":
+			w.Write([]byte("2019-01-01T00:00:02Z from tty\n"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewDockerLogsConfig()
+	conf.Host = "tcp://" + strings.TrimPrefix(ts.URL, "http://")
+	conf.Labels = []string{"app=foo"}
+	conf.RefreshInterval = "10ms"
+
+	d, err := NewDockerLogs(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	type expLine struct {
+		content, id, name, stream, timestamp string
+	}
+	readLine := func(exp expLine) {
+		t.Helper()
+		msg, err := d.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := msg.Get(0)
+		if act := string(p.Get()); act != exp.content {
+			t.Errorf("Wrong content: %v != %v", act, exp.content)
+		}
+		meta := p.Metadata()
+		if act := meta.Get("docker_container_id"); act != exp.id {
+			t.Errorf("Wrong id: %v != %v", act, exp.id)
+		}
+		if act := meta.Get("docker_container_name"); act != exp.name {
+			t.Errorf("Wrong name: %v != %v", act, exp.name)
+		}
+		if act := meta.Get("docker_stream"); act != exp.stream {
+			t.Errorf("Wrong stream: %v != %v", act, exp.stream)
+		}
+		if act := meta.Get("docker_timestamp"); act != exp.timestamp {
+			t.Errorf("Wrong timestamp: %v != %v", act, exp.timestamp)
+		}
+		if act := meta.Get("docker_label_app"); act != "foo" {
+			t.Errorf("Wrong label: %v", act)
+		}
+	}
+
+	readLine(expLine{"hello world", "aaa", "foo_1", "stdout", "2019-01-01T00:00:00Z"})
+	readLine(expLine{"uh oh", "aaa", "foo_1", "stderr", "2019-01-01T00:00:01Z"})
+
+	// A new container should be discovered on refresh.
+	containersMut.Lock()
+	containers = `[{"Id":"aaa","Names":["/foo_1"],"Labels":{"app":"foo"}},{"Id":"bbb","Names":["/foo_2"],"Labels":{"app":"foo"}}]`
+	containersMut.Unlock()
+
+	readLine(expLine{"from tty", "bbb", "foo_2", "stdout", "2019-01-01T00:00:02Z"})
+
+	d.CloseAsync()
+	if _, err = d.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Expected ErrTypeClosed, received: %v", err)
+	}
+	if err = d.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDockerLogsConnectError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"nope"}`, http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	conf := NewDockerLogsConfig()
+	conf.Host = ts.URL
+
+	d, err := NewDockerLogs(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.CloseAsync()
+
+	if err = d.Connect(); err == nil {
+		t.Error("Expected error from connect")
+	}
+	if _, err = d.Read(); err != types.ErrNotConnected {
+		t.Errorf("Expected ErrNotConnected, received: %v", err)
+	}
+}
+
+func TestDockerLogsBadConfig(t *testing.T) {
+	conf := NewDockerLogsConfig()
+	conf.Host = "ftp://nope"
+	if _, err := NewDockerLogs(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad host")
+	}
+
+	conf = NewDockerLogsConfig()
+	conf.Stdout = false
+	conf.Stderr = false
+	if _, err := NewDockerLogs(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from no streams")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,539 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// fileTailFingerprintLen is the maximum number of bytes from the start of a
+// file used to identify it when resuming from a persisted offset.
+const fileTailFingerprintLen = 1024
+
+// fileTailOffset is the persisted state of a tailed file.
+type fileTailOffset struct {
+	Offset         int64  `json:"offset"`
+	Fingerprint    string `json:"fingerprint"`
+	FingerprintLen int64  `json:"fingerprint_len"`
+}
+
+// tailedFile is a file currently being followed.
+type tailedFile struct {
+	path string
+	file *os.File
+	info os.FileInfo
+
+	// Offset of the first byte of buf within the file, and the unconsumed
+	// bytes read from the file.
+	offset int64
+	buf    []byte
+
+	// Offset of the end of the last acknowledged line.
+	acked int64
+
+	// Set when the path no longer refers to this file, once the file has been
+	// drained it is closed.
+	rotated bool
+}
+
+func (t *tailedFile) fingerprint() fileTailOffset {
+	o := fileTailOffset{Offset: t.acked}
+	fpLen := t.acked
+	if fpLen > fileTailFingerprintLen {
+		fpLen = fileTailFingerprintLen
+	}
+	if h, err := fingerprintFile(t.file, fpLen); err == nil {
+		o.Fingerprint = h
+		o.FingerprintLen = fpLen
+	}
+	return o
+}
+
+func fingerprintFile(f *os.File, n int64) (string, error) {
+	b := make([]byte, n)
+	if _, err := f.ReadAt(b, 0); err != nil && err != io.EOF {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+//------------------------------------------------------------------------------
+
+// FileTail is a reader implementation that follows files matching a glob
+// pattern, reading line delimited messages as they are appended. Files are
+// tracked across truncation and rotation, and offsets can be persisted to disk
+// in order to resume after a restart.
+type FileTail struct {
+	glob string
+
+	delimiter          []byte
+	maxBuffer          int
+	pollInterval       time.Duration
+	offsetsPath        string
+	startFromBeginning bool
+	flushPeriod        time.Duration
+
+	mut       sync.Mutex
+	connected bool
+	files     map[string]*tailedFile
+	order     []string
+	nextIndex int
+	lastPoll  time.Time
+
+	offsets     map[string]fileTailOffset
+	lastFlushed time.Time
+	pending     map[*tailedFile]int64
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRotated   metrics.StatCounter
+	mTruncated metrics.StatCounter
+	mFlushErr  metrics.StatCounter
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFileTail creates a new FileTail reader type that follows files matching
+// a glob pattern.
+func NewFileTail(
+	glob string,
+	log log.Modular,
+	stats metrics.Type,
+	options ...func(r *FileTail),
+) (*FileTail, error) {
+	r := &FileTail{
+		glob:               glob,
+		delimiter:          []byte("\n"),
+		maxBuffer:          1000000,
+		pollInterval:       time.Second,
+		startFromBeginning: true,
+		flushPeriod:        time.Second,
+		files:              map[string]*tailedFile{},
+		offsets:            map[string]fileTailOffset{},
+		pending:            map[*tailedFile]int64{},
+		log:                log,
+		stats:              stats,
+		mRotated:           stats.GetCounter("tail.rotated"),
+		mTruncated:         stats.GetCounter("tail.truncated"),
+		mFlushErr:          stats.GetCounter("tail.offsets.error"),
+		closeChan:          make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	if _, err := filepath.Match(glob, ""); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// OptFileTailSetDelimiter is a option func that sets the delimiter (default
+// '\n') used to divide lines in the followed files.
+func OptFileTailSetDelimiter(delimiter string) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.delimiter = []byte(delimiter)
+	}
+}
+
+// OptFileTailSetMaxBuffer is a option func that sets the maximum size of a
+// line, longer lines are split.
+func OptFileTailSetMaxBuffer(maxBuffer int) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.maxBuffer = maxBuffer
+	}
+}
+
+// OptFileTailSetPollInterval is a option func that sets the interval at which
+// the glob is re-evaluated and files are checked for rotation and truncation.
+func OptFileTailSetPollInterval(interval time.Duration) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.pollInterval = interval
+	}
+}
+
+// OptFileTailSetOffsetsPath is a option func that sets the path of a file
+// used to persist the offsets of acknowledged lines.
+func OptFileTailSetOffsetsPath(path string) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.offsetsPath = path
+	}
+}
+
+// OptFileTailSetStartFromBeginning is a option func that sets whether files
+// found on start up without a persisted offset are read from the beginning
+// rather than the end.
+func OptFileTailSetStartFromBeginning(b bool) func(r *FileTail) {
+	return func(r *FileTail) {
+		r.startFromBeginning = b
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Connect reads any persisted offsets and opens the files matching the glob.
+func (r *FileTail) Connect() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	select {
+	case <-r.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+	if r.connected {
+		return nil
+	}
+
+	if len(r.offsetsPath) > 0 {
+		data, err := ioutil.ReadFile(r.offsetsPath)
+		if err == nil {
+			if err = json.Unmarshal(data, &r.offsets); err != nil {
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := r.poll(true); err != nil {
+		return err
+	}
+	r.connected = true
+	r.log.Infof("Tailing files matching: %v\n", r.glob)
+	return nil
+}
+
+// open begins following a file, resuming from a persisted offset when its
+// fingerprint matches.
+func (r *FileTail) open(path string, info os.FileInfo, initial bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		r.log.Errorf("Failed to open file '%v': %v\n", path, err)
+		return
+	}
+	t := &tailedFile{path: path, file: f, info: info}
+
+	if o, exists := r.offsets[path]; exists && o.Offset <= info.Size() {
+		if h, err := fingerprintFile(f, o.FingerprintLen); err == nil && h == o.Fingerprint {
+			t.offset, t.acked = o.Offset, o.Offset
+		}
+	} else if initial && !r.startFromBeginning {
+		t.offset, t.acked = info.Size(), info.Size()
+	}
+	if t.offset > 0 {
+		if _, err = f.Seek(t.offset, io.SeekStart); err != nil {
+			r.log.Errorf("Failed to seek file '%v': %v\n", path, err)
+			f.Close()
+			return
+		}
+	}
+
+	r.files[path] = t
+	r.order = append(r.order, path)
+	sort.Strings(r.order)
+	r.log.Debugf("Following file '%v' from offset %v\n", path, t.offset)
+}
+
+// poll re-evaluates the glob, detecting new, rotated and truncated files.
+// Must be called with mut held.
+func (r *FileTail) poll(initial bool) error {
+	r.lastPoll = time.Now()
+
+	matches, err := filepath.Glob(r.glob)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range r.files {
+		if t.rotated {
+			continue
+		}
+		info, err := os.Stat(t.path)
+		if err != nil || !os.SameFile(t.info, info) {
+			// The file has been removed or renamed, we continue reading it
+			// until it is drained.
+			t.rotated = true
+			r.mRotated.Incr(1)
+			continue
+		}
+		if info.Size() < t.offset+int64(len(t.buf)) {
+			r.log.Warnf("File '%v' was truncated, reading from the beginning\n", t.path)
+			r.mTruncated.Incr(1)
+			if _, err = t.file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			delete(r.pending, t)
+			t.offset, t.acked, t.buf = 0, 0, nil
+		}
+		t.info = info
+	}
+
+	for _, path := range matches {
+		if existing, exists := r.files[path]; exists && !existing.rotated {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		// A matching path might be a file we're already following that was
+		// renamed, in which case we continue following it under the new name.
+		var renamed *tailedFile
+		for _, t := range r.files {
+			if t.rotated && os.SameFile(t.info, info) {
+				renamed = t
+				break
+			}
+		}
+		if renamed != nil {
+			r.forget(renamed.path)
+			renamed.path, renamed.info, renamed.rotated = path, info, false
+			r.files[path] = renamed
+			r.order = append(r.order, path)
+			sort.Strings(r.order)
+			continue
+		}
+		if _, exists := r.files[path]; exists {
+			// The rotated file under this path has yet to be drained.
+			continue
+		}
+		r.open(path, info, initial)
+	}
+	return nil
+}
+
+func (r *FileTail) forget(path string) {
+	delete(r.files, path)
+	for i, p := range r.order {
+		if p == path {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// readLine attempts to read a line from a file, returning nil if a complete
+// line is not yet available.
+func (r *FileTail) readLine(t *tailedFile) ([]byte, error) {
+	chunk := make([]byte, 32*1024)
+	for {
+		if i := bytes.Index(t.buf, r.delimiter); i >= 0 {
+			line := t.buf[:i]
+			t.buf = t.buf[i+len(r.delimiter):]
+			t.offset += int64(i + len(r.delimiter))
+			return line, nil
+		}
+		if len(t.buf) >= r.maxBuffer {
+			line := t.buf[:r.maxBuffer]
+			t.buf = t.buf[r.maxBuffer:]
+			t.offset += int64(r.maxBuffer)
+			return line, nil
+		}
+		n, err := t.file.Read(chunk)
+		if n > 0 {
+			t.buf = append(t.buf, chunk[:n]...)
+			continue
+		}
+		if err == io.EOF || err == nil {
+			if t.rotated && len(t.buf) > 0 {
+				// A rotated file won't be appended to, so flush the final
+				// non-terminated line.
+				line := t.buf
+				t.offset += int64(len(t.buf))
+				t.buf = nil
+				return line, nil
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+}
+
+// Read attempts to read a new line from any of the followed files.
+func (r *FileTail) Read() (types.Message, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if !r.connected {
+		return nil, types.ErrNotConnected
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		for i := 0; i < len(r.order); i++ {
+			index := (r.nextIndex + i) % len(r.order)
+			t := r.files[r.order[index]]
+
+			line, err := r.readLine(t)
+			if err != nil {
+				r.log.Errorf("Failed to read file '%v': %v\n", t.path, err)
+				continue
+			}
+			if line == nil {
+				if t.rotated {
+					r.pending[t] = t.offset
+					t.file.Close()
+					r.forget(t.path)
+					i--
+				}
+				continue
+			}
+			if len(line) == 0 {
+				// Skip empty lines but mark them as consumed.
+				r.pending[t] = t.offset
+				i--
+				continue
+			}
+
+			r.nextIndex = index + 1
+			r.pending[t] = t.offset
+
+			msg := message.New([][]byte{append([]byte(nil), line...)})
+			meta := msg.Get(0).Metadata()
+			meta.Set("file_path", t.path)
+			meta.Set("file_offset", strconv.FormatInt(t.offset, 10))
+			return msg, nil
+		}
+
+		if time.Since(r.lastPoll) < r.pollInterval {
+			break
+		}
+		if err := r.poll(false); err != nil {
+			r.log.Errorf("Failed to poll files: %v\n", err)
+		}
+	}
+
+	wait := r.pollInterval - time.Since(r.lastPoll)
+	if wait > r.pollInterval {
+		wait = r.pollInterval
+	}
+	if wait > 0 {
+		r.mut.Unlock()
+		select {
+		case <-time.After(wait):
+		case <-r.closeChan:
+		}
+		r.mut.Lock()
+	}
+	return nil, types.ErrTimeout
+}
+
+// flush writes the offsets of acknowledged lines to disk. Must be called with
+// mut held.
+func (r *FileTail) flush() error {
+	r.lastFlushed = time.Now()
+	r.offsets = map[string]fileTailOffset{}
+	for path, t := range r.files {
+		r.offsets[path] = t.fingerprint()
+	}
+	data, err := json.Marshal(r.offsets)
+	if err != nil {
+		return err
+	}
+	tmpPath := r.offsetsPath + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, r.offsetsPath)
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not.
+func (r *FileTail) Acknowledge(err error) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if err != nil {
+		// The preserver resends failed messages, so a negative acknowledgement
+		// does not need to rewind the files.
+		return nil
+	}
+	for t, offset := range r.pending {
+		if offset > t.acked {
+			t.acked = offset
+		}
+	}
+	r.pending = map[*tailedFile]int64{}
+
+	if r.connected && len(r.offsetsPath) > 0 && time.Since(r.lastFlushed) >= r.flushPeriod {
+		if err = r.flush(); err != nil {
+			r.mFlushErr.Incr(1)
+			r.log.Errorf("Failed to persist file offsets: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// CloseAsync shuts down the reader and stops processing requests.
+func (r *FileTail) CloseAsync() {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if !r.connected {
+		return
+	}
+	if len(r.offsetsPath) > 0 {
+		if err := r.flush(); err != nil {
+			r.mFlushErr.Incr(1)
+			r.log.Errorf("Failed to persist file offsets: %v\n", err)
+		}
+	}
+	for _, t := range r.files {
+		t.file.Close()
+	}
+	r.files = map[string]*tailedFile{}
+	r.order = nil
+	r.connected = false
+}
+
+// WaitForClose blocks until the reader has closed down or the specified
+// timeout elapses.
+func (r *FileTail) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
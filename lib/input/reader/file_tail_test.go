@@ -0,0 +1,198 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func readTailLines(t *testing.T, r *FileTail, n int) []string {
+	t.Helper()
+	var lines []string
+	deadline := time.Now().Add(time.Second * 5)
+	for len(lines) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for lines, received: %v", lines)
+		}
+		msg, err := r.Read()
+		if err == types.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(msg.Get(0).Get()))
+		if err = r.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return lines
+}
+
+func newTestFileTail(t *testing.T, glob string, opts ...func(*FileTail)) *FileTail {
+	t.Helper()
+	opts = append([]func(*FileTail){OptFileTailSetPollInterval(time.Millisecond * 10)}, opts...)
+	r, err := NewFileTail(glob, log.Noop(), metrics.Noop(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+//------------------------------------------------------------------------------
+
+func TestFileTailRotateAndTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "a.log")
+	appendFile(t, logPath, "first\nsecond\n")
+	appendFile(t, filepath.Join(dir, "b.txt"), "ignored\n")
+
+	r := newTestFileTail(t, filepath.Join(dir, "*.log"))
+	defer r.CloseAsync()
+
+	if exp, act := []string{"first", "second"}, readTailLines(t, r, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lines: %v != %v", act, exp)
+	}
+
+	appendFile(t, logPath, "third\n")
+	appendFile(t, filepath.Join(dir, "c.log"), "fourth\n")
+	lines := readTailLines(t, r, 2)
+	sort.Strings(lines)
+	if exp, act := []string{"fourth", "third"}, lines; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lines: %v != %v", act, exp)
+	}
+
+	// Rotate by renaming the file to a name that still matches the glob, the
+	// old file must not be read again.
+	appendFile(t, logPath, "fifth\n")
+	if err = os.Rename(logPath, logPath+".1.log"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, logPath, "sixth\n")
+
+	lines = readTailLines(t, r, 2)
+	if exp := []string{"fifth", "sixth"}; !reflect.DeepEqual(exp, lines) {
+		t.Errorf("Wrong lines: %v != %v", lines, exp)
+	}
+
+	// Truncate the new file in place.
+	if err = ioutil.WriteFile(logPath, []byte("7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"7"}, readTailLines(t, r, 1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lines: %v != %v", act, exp)
+	}
+}
+
+func TestFileTailResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "a.log")
+	offsetsPath := filepath.Join(dir, "offsets.json")
+	appendFile(t, logPath, "first\nsecond\n")
+
+	r := newTestFileTail(t, logPath, OptFileTailSetOffsetsPath(offsetsPath))
+	if exp, act := []string{"first", "second"}, readTailLines(t, r, 2); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lines: %v != %v", act, exp)
+	}
+	r.CloseAsync()
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+
+	appendFile(t, logPath, "third\n")
+
+	r = newTestFileTail(t, logPath, OptFileTailSetOffsetsPath(offsetsPath))
+	if exp, act := []string{"third"}, readTailLines(t, r, 1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lines: %v != %v", act, exp)
+	}
+	r.CloseAsync()
+
+	// Replacing the file with different content invalidates the offset.
+	if err = ioutil.WriteFile(logPath, []byte("other\ncontent\nhere\nnow\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r = newTestFileTail(t, logPath, OptFileTailSetOffsetsPath(offsetsPath))
+	if exp, act := []string{"other", "content", "here", "now"}, readTailLines(t, r, 4); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lines: %v != %v", act, exp)
+	}
+	r.CloseAsync()
+}
+
+func TestFileTailStartFromEnd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_tail_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logPath := filepath.Join(dir, "a.log")
+	appendFile(t, logPath, "first\nsecond\n")
+
+	r := newTestFileTail(t, logPath, OptFileTailSetStartFromBeginning(false))
+	defer r.CloseAsync()
+
+	appendFile(t, logPath, "third\n")
+	if exp, act := []string{"third"}, readTailLines(t, r, 1); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong lines: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
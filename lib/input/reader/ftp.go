@@ -0,0 +1,109 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/ftp"
+)
+
+//------------------------------------------------------------------------------
+
+// FTPConfig contains configuration for the FTP input type.
+type FTPConfig struct {
+	Address           string `json:"address" yaml:"address"`
+	User              string `json:"user" yaml:"user"`
+	Password          string `json:"password" yaml:"password"`
+	Timeout           string `json:"timeout" yaml:"timeout"`
+	RemoteFilesConfig `json:",inline" yaml:",inline"`
+}
+
+// NewFTPConfig creates a new FTPConfig with default values.
+func NewFTPConfig() FTPConfig {
+	return FTPConfig{
+		Address:           "localhost:21",
+		User:              "anonymous",
+		Password:          "anonymous",
+		Timeout:           "30s",
+		RemoteFilesConfig: NewRemoteFilesConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type ftpFS struct {
+	*ftp.Client
+}
+
+func (f ftpFS) ReadDir(dir string) ([]remoteFile, error) {
+	infos, err := f.Client.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]remoteFile, 0, len(infos))
+	for _, info := range infos {
+		files = append(files, remoteFile{
+			Name:    info.Name,
+			Size:    info.Size,
+			ModTime: info.ModTime,
+			IsDir:   info.IsDir(),
+		})
+	}
+	return files, nil
+}
+
+func (f ftpFS) IsNotExist(err error) bool {
+	return ftp.IsNotExist(err)
+}
+
+func (f ftpFS) Close() error {
+	return f.Client.Quit()
+}
+
+//------------------------------------------------------------------------------
+
+// NewFTP creates a new FTP input type.
+func NewFTP(conf FTPConfig, log log.Modular, stats metrics.Type) (*RemoteFiles, error) {
+	var timeout time.Duration
+	if len(conf.Timeout) > 0 {
+		var err error
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	return newRemoteFiles("ftp", conf.RemoteFilesConfig, func() (remoteFS, error) {
+		client, err := ftp.Dial(conf.Address, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if err = client.Login(conf.User, conf.Password); err != nil {
+			client.Quit()
+			return nil, err
+		}
+		return ftpFS{Client: client}, nil
+	}, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,436 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"google.golang.org/api/iterator"
+)
+
+//------------------------------------------------------------------------------
+
+// GCPCloudStorageConfig contains configuration values for the input type.
+type GCPCloudStorageConfig struct {
+	Bucket       string `json:"bucket" yaml:"bucket"`
+	Prefix       string `json:"prefix" yaml:"prefix"`
+	Decompress   string `json:"decompress" yaml:"decompress"`
+	SplitLines   bool   `json:"split_lines" yaml:"split_lines"`
+	Delim        string `json:"delimiter" yaml:"delimiter"`
+	AfterRead    string `json:"after_read" yaml:"after_read"`
+	MoveToBucket string `json:"move_to_bucket" yaml:"move_to_bucket"`
+	MoveToPrefix string `json:"move_to_prefix" yaml:"move_to_prefix"`
+	Retries      int    `json:"retries" yaml:"retries"`
+	Timeout      string `json:"timeout" yaml:"timeout"`
+}
+
+// NewGCPCloudStorageConfig creates a new Config with default values.
+func NewGCPCloudStorageConfig() GCPCloudStorageConfig {
+	return GCPCloudStorageConfig{
+		Bucket:       "",
+		Prefix:       "",
+		Decompress:   "none",
+		SplitLines:   false,
+		Delim:        "",
+		AfterRead:    "none",
+		MoveToBucket: "",
+		MoveToPrefix: "",
+		Retries:      3,
+		Timeout:      "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// gcsObject describes an object listed within a bucket.
+type gcsObject struct {
+	Name            string
+	Size            int64
+	ContentType     string
+	ContentEncoding string
+	Updated         time.Time
+	Metadata        map[string]string
+}
+
+// gcsBucket is the subset of cloud storage operations used by the input.
+type gcsBucket interface {
+	List(ctx context.Context, prefix string) ([]gcsObject, error)
+	NewReader(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+	Copy(ctx context.Context, name, dstBucket, dstName string) error
+	Close() error
+}
+
+type gcsClientBucket struct {
+	client *storage.Client
+	bucket string
+}
+
+func (g *gcsClientBucket) List(ctx context.Context, prefix string) ([]gcsObject, error) {
+	var objs []gcsObject
+	iter := g.client.Bucket(g.bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, gcsObject{
+			Name:            attrs.Name,
+			Size:            attrs.Size,
+			ContentType:     attrs.ContentType,
+			ContentEncoding: attrs.ContentEncoding,
+			Updated:         attrs.Updated,
+			Metadata:        attrs.Metadata,
+		})
+	}
+}
+
+func (g *gcsClientBucket) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	return g.client.Bucket(g.bucket).Object(name).NewReader(ctx)
+}
+
+func (g *gcsClientBucket) Delete(ctx context.Context, name string) error {
+	return g.client.Bucket(g.bucket).Object(name).Delete(ctx)
+}
+
+func (g *gcsClientBucket) Copy(ctx context.Context, name, dstBucket, dstName string) error {
+	src := g.client.Bucket(g.bucket).Object(name)
+	_, err := g.client.Bucket(dstBucket).Object(dstName).CopierFrom(src).Run(ctx)
+	return err
+}
+
+func (g *gcsClientBucket) Close() error {
+	return g.client.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type gcsTarget struct {
+	obj      gcsObject
+	attempts int
+}
+
+// GCPCloudStorage is a benthos reader.Type implementation that reads objects
+// from a GCP Cloud Storage bucket.
+type GCPCloudStorage struct {
+	conf GCPCloudStorageConfig
+
+	delim   []byte
+	timeout time.Duration
+
+	bucketMut sync.Mutex
+	bucket    gcsBucket
+	newBucket func(ctx context.Context) (gcsBucket, error)
+	closed    bool
+
+	targets []gcsTarget
+	pending []gcsTarget
+
+	log   log.Modular
+	stats metrics.Type
+
+	mAfterErr metrics.StatCounter
+}
+
+// NewGCPCloudStorage creates a new GCP Cloud Storage bucket reader.Type.
+func NewGCPCloudStorage(
+	conf GCPCloudStorageConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*GCPCloudStorage, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("a bucket must be specified")
+	}
+	switch conf.Decompress {
+	case "none", "gzip", "auto":
+	default:
+		return nil, fmt.Errorf("unrecognised decompress algorithm: %v", conf.Decompress)
+	}
+	switch conf.AfterRead {
+	case "none", "delete":
+	case "move":
+		if len(conf.MoveToBucket) == 0 && len(conf.MoveToPrefix) == 0 {
+			return nil, errors.New("a move_to_bucket or move_to_prefix must be specified when after_read is move")
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised after_read action: %v", conf.AfterRead)
+	}
+	var timeout time.Duration
+	if len(conf.Timeout) > 0 {
+		var err error
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	g := &GCPCloudStorage{
+		conf:      conf,
+		delim:     []byte("\n"),
+		timeout:   timeout,
+		log:       log,
+		stats:     stats,
+		mAfterErr: stats.GetCounter("after_read.error"),
+	}
+	if len(conf.Delim) > 0 {
+		g.delim = []byte(conf.Delim)
+	}
+	g.newBucket = func(ctx context.Context) (gcsBucket, error) {
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &gcsClientBucket{client: client, bucket: conf.Bucket}, nil
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GCPCloudStorage) ctx() (context.Context, context.CancelFunc) {
+	if g.timeout > 0 {
+		return context.WithTimeout(context.Background(), g.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Connect attempts to establish a connection to the target bucket and lists
+// the objects to be consumed.
+func (g *GCPCloudStorage) Connect() error {
+	g.bucketMut.Lock()
+	defer g.bucketMut.Unlock()
+	if g.closed {
+		return types.ErrTypeClosed
+	}
+	if g.bucket != nil {
+		return nil
+	}
+
+	ctx, done := g.ctx()
+	defer done()
+
+	bucket, err := g.newBucket(ctx)
+	if err != nil {
+		return err
+	}
+	objs, err := bucket.List(ctx, g.conf.Prefix)
+	if err != nil {
+		bucket.Close()
+		return fmt.Errorf("failed to list objects: %v", err)
+	}
+	for _, obj := range objs {
+		g.targets = append(g.targets, gcsTarget{
+			obj:      obj,
+			attempts: g.conf.Retries,
+		})
+	}
+
+	g.log.Infof("Receiving GCP Cloud Storage objects from bucket: %s\n", g.conf.Bucket)
+	g.bucket = bucket
+	return nil
+}
+
+// readObject streams the contents of an object into message parts.
+func (g *GCPCloudStorage) readObject(obj gcsObject) ([][]byte, error) {
+	ctx, done := g.ctx()
+	defer done()
+
+	rdr, err := g.bucket.NewReader(ctx, obj.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Close()
+
+	var body io.Reader = rdr
+	if g.conf.Decompress == "gzip" || (g.conf.Decompress == "auto" && strings.HasSuffix(obj.Name, ".gz")) {
+		gr, err := gzip.NewReader(rdr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress object: %v", err)
+		}
+		defer gr.Close()
+		body = gr
+	}
+
+	if !g.conf.SplitLines {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{b}, nil
+	}
+
+	var parts [][]byte
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, 1024*1024*64)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.Index(data, g.delim); i >= 0 {
+			return i + len(g.delim), data[0:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			parts = append(parts, append([]byte(nil), scanner.Bytes()...))
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		parts = [][]byte{{}}
+	}
+	return parts, nil
+}
+
+// Read attempts to read a new message from the target bucket.
+func (g *GCPCloudStorage) Read() (types.Message, error) {
+	g.bucketMut.Lock()
+	defer g.bucketMut.Unlock()
+	if g.bucket == nil {
+		return nil, types.ErrNotConnected
+	}
+	if len(g.targets) == 0 {
+		return nil, types.ErrTypeClosed
+	}
+
+	target := g.targets[0]
+	parts, err := g.readObject(target.obj)
+	if err != nil {
+		target.attempts--
+		if target.attempts <= 0 {
+			g.targets = g.targets[1:]
+		} else {
+			g.targets[0] = target
+		}
+		return nil, fmt.Errorf("failed to download object: %v", err)
+	}
+	g.targets = g.targets[1:]
+	g.pending = append(g.pending, target)
+
+	msg := message.New(parts)
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		for k, v := range target.obj.Metadata {
+			meta.Set(k, v)
+		}
+		meta.Set("gcs_key", target.obj.Name)
+		meta.Set("gcs_bucket", g.conf.Bucket)
+		meta.Set("gcs_size", strconv.FormatInt(target.obj.Size, 10))
+		meta.Set("gcs_last_modified", target.obj.Updated.Format(time.RFC3339))
+		meta.Set("gcs_last_modified_unix", strconv.FormatInt(target.obj.Updated.Unix(), 10))
+		if len(target.obj.ContentType) > 0 {
+			meta.Set("gcs_content_type", target.obj.ContentType)
+		}
+		if len(target.obj.ContentEncoding) > 0 {
+			meta.Set("gcs_content_encoding", target.obj.ContentEncoding)
+		}
+		return nil
+	})
+	return msg, nil
+}
+
+// afterRead performs the configured after_read action on an object.
+func (g *GCPCloudStorage) afterRead(name string) error {
+	ctx, done := g.ctx()
+	defer done()
+
+	switch g.conf.AfterRead {
+	case "delete":
+		return g.bucket.Delete(ctx, name)
+	case "move":
+		dstBucket := g.conf.MoveToBucket
+		if len(dstBucket) == 0 {
+			dstBucket = g.conf.Bucket
+		}
+		if err := g.bucket.Copy(ctx, name, dstBucket, g.conf.MoveToPrefix+name); err != nil {
+			return err
+		}
+		return g.bucket.Delete(ctx, name)
+	}
+	return nil
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not.
+func (g *GCPCloudStorage) Acknowledge(err error) error {
+	g.bucketMut.Lock()
+	defer g.bucketMut.Unlock()
+
+	if err != nil {
+		g.targets = append(g.pending, g.targets...)
+		g.pending = nil
+		return nil
+	}
+
+	var aerr error
+	if g.bucket != nil {
+		for _, target := range g.pending {
+			if aerr = g.afterRead(target.obj.Name); aerr != nil {
+				g.mAfterErr.Incr(1)
+				g.log.Errorf("Failed to %v consumed object '%v': %v\n", g.conf.AfterRead, target.obj.Name, aerr)
+			}
+		}
+	}
+	g.pending = nil
+	return aerr
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (g *GCPCloudStorage) CloseAsync() {
+	g.bucketMut.Lock()
+	if g.bucket != nil {
+		g.bucket.Close()
+		g.bucket = nil
+	}
+	g.closed = true
+	g.bucketMut.Unlock()
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (g *GCPCloudStorage) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
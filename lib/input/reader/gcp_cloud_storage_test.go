@@ -0,0 +1,205 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockGCSBucket struct {
+	objects map[string][]byte
+	order   []string
+	copied  []string
+	deleted []string
+}
+
+func (m *mockGCSBucket) List(ctx context.Context, prefix string) ([]gcsObject, error) {
+	var objs []gcsObject
+	for _, name := range m.order {
+		if len(prefix) > 0 && !bytes.HasPrefix([]byte(name), []byte(prefix)) {
+			continue
+		}
+		objs = append(objs, gcsObject{
+			Name:     name,
+			Size:     int64(len(m.objects[name])),
+			Updated:  time.Unix(1500000000, 0),
+			Metadata: map[string]string{"foo": "bar"},
+		})
+	}
+	return objs, nil
+}
+
+func (m *mockGCSBucket) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	b, exists := m.objects[name]
+	if !exists {
+		return nil, errors.New("object not found")
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *mockGCSBucket) Delete(ctx context.Context, name string) error {
+	m.deleted = append(m.deleted, name)
+	return nil
+}
+
+func (m *mockGCSBucket) Copy(ctx context.Context, name, dstBucket, dstName string) error {
+	m.copied = append(m.copied, dstBucket+"/"+dstName)
+	return nil
+}
+
+func (m *mockGCSBucket) Close() error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func TestGCPCloudStorageRead(t *testing.T) {
+	conf := NewGCPCloudStorageConfig()
+	conf.Bucket = "foo"
+	conf.Prefix = "in/"
+	conf.Decompress = "auto"
+	conf.SplitLines = true
+	conf.AfterRead = "move"
+	conf.MoveToPrefix = "done/"
+
+	g, err := NewGCPCloudStorage(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucket := &mockGCSBucket{
+		objects: map[string][]byte{
+			"in/a.txt": []byte("first\nsecond\n"),
+			"in/b.gz":  gzipBytes(t, []byte("third")),
+			"other/c":  []byte("ignored"),
+		},
+		order: []string{"in/a.txt", "in/b.gz", "other/c"},
+	}
+	g.newBucket = func(context.Context) (gcsBucket, error) {
+		return bucket, nil
+	}
+
+	if _, err = g.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := g.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("first"), []byte("second")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	meta := msg.Get(1).Metadata()
+	if exp, act := "in/a.txt", meta.Get("gcs_key"); exp != act {
+		t.Errorf("Wrong key: %v != %v", act, exp)
+	}
+	if exp, act := "foo", meta.Get("gcs_bucket"); exp != act {
+		t.Errorf("Wrong bucket: %v != %v", act, exp)
+	}
+	if exp, act := "1500000000", meta.Get("gcs_last_modified_unix"); exp != act {
+		t.Errorf("Wrong last modified: %v != %v", act, exp)
+	}
+	if exp, act := "bar", meta.Get("foo"); exp != act {
+		t.Errorf("Wrong user metadata: %v != %v", act, exp)
+	}
+
+	// Rejected objects should be read again.
+	if err = g.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+	if len(bucket.copied) > 0 || len(bucket.deleted) > 0 {
+		t.Error("Expected no after read actions")
+	}
+	if msg, err = g.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "in/a.txt", msg.Get(0).Metadata().Get("gcs_key"); exp != act {
+		t.Errorf("Wrong key: %v != %v", act, exp)
+	}
+	if err = g.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg, err = g.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("third")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if err = g.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = g.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+
+	if exp, act := []string{"foo/done/in/a.txt", "foo/done/in/b.gz"}, bucket.copied; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong copied objects: %v != %v", act, exp)
+	}
+	if exp, act := []string{"in/a.txt", "in/b.gz"}, bucket.deleted; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong deleted objects: %v != %v", act, exp)
+	}
+
+	g.CloseAsync()
+	if err = g.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestGCPCloudStorageBadConfig(t *testing.T) {
+	conf := NewGCPCloudStorageConfig()
+	if _, err := NewGCPCloudStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty bucket")
+	}
+
+	conf.Bucket = "foo"
+	conf.AfterRead = "move"
+	if _, err := NewGCPCloudStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from move without destination")
+	}
+
+	conf.AfterRead = "none"
+	conf.Decompress = "nope"
+	if _, err := NewGCPCloudStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad decompress")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -23,6 +23,7 @@ package reader
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -42,6 +43,8 @@ type GCPPubSubConfig struct {
 	SubscriptionID         string `json:"subscription" yaml:"subscription"`
 	MaxOutstandingMessages int    `json:"max_outstanding_messages" yaml:"max_outstanding_messages"`
 	MaxOutstandingBytes    int    `json:"max_outstanding_bytes" yaml:"max_outstanding_bytes"`
+	MaxExtension           string `json:"max_extension" yaml:"max_extension"`
+	Synchronous            bool   `json:"synchronous" yaml:"synchronous"`
 }
 
 // NewGCPPubSubConfig creates a new Config with default values.
@@ -51,6 +54,8 @@ func NewGCPPubSubConfig() GCPPubSubConfig {
 		SubscriptionID:         "",
 		MaxOutstandingMessages: pubsub.DefaultReceiveSettings.MaxOutstandingMessages,
 		MaxOutstandingBytes:    pubsub.DefaultReceiveSettings.MaxOutstandingBytes,
+		MaxExtension:           pubsub.DefaultReceiveSettings.MaxExtension.String(),
+		Synchronous:            false,
 	}
 }
 
@@ -66,8 +71,10 @@ type GCPPubSub struct {
 	closeFunc    context.CancelFunc
 	subMut       sync.Mutex
 
-	client      *pubsub.Client
-	pendingMsgs []*pubsub.Message
+	client       *pubsub.Client
+	pendingMsgs  []*pubsub.Message
+	maxExtension time.Duration
+	closed       bool
 
 	log   log.Modular
 	stats metrics.Type
@@ -79,6 +86,13 @@ func NewGCPPubSub(
 	log log.Modular,
 	stats metrics.Type,
 ) (*GCPPubSub, error) {
+	maxExtension := pubsub.DefaultReceiveSettings.MaxExtension
+	if len(conf.MaxExtension) > 0 {
+		var err error
+		if maxExtension, err = time.ParseDuration(conf.MaxExtension); err != nil {
+			return nil, fmt.Errorf("failed to parse max extension string: %v", err)
+		}
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 	client, err := pubsub.NewClient(ctx, conf.ProjectID)
@@ -86,10 +100,11 @@ func NewGCPPubSub(
 		return nil, err
 	}
 	return &GCPPubSub{
-		conf:   conf,
-		log:    log,
-		stats:  stats,
-		client: client,
+		conf:         conf,
+		log:          log,
+		stats:        stats,
+		client:       client,
+		maxExtension: maxExtension,
 	}, nil
 }
 
@@ -97,6 +112,9 @@ func NewGCPPubSub(
 func (c *GCPPubSub) Connect() error {
 	c.subMut.Lock()
 	defer c.subMut.Unlock()
+	if c.closed {
+		return types.ErrTypeClosed
+	}
 	if c.subscription != nil {
 		return nil
 	}
@@ -104,6 +122,8 @@ func (c *GCPPubSub) Connect() error {
 	sub := c.client.Subscription(c.conf.SubscriptionID)
 	sub.ReceiveSettings.MaxOutstandingMessages = c.conf.MaxOutstandingMessages
 	sub.ReceiveSettings.MaxOutstandingBytes = c.conf.MaxOutstandingBytes
+	sub.ReceiveSettings.MaxExtension = c.maxExtension
+	sub.ReceiveSettings.Synchronous = c.conf.Synchronous
 
 	existsCtx, existsCancel := context.WithTimeout(context.Background(), time.Second*5)
 	exists, err := sub.Exists(existsCtx)
@@ -146,6 +166,13 @@ func (c *GCPPubSub) Connect() error {
 
 // Read attempts to read a new message from the target subscription.
 func (c *GCPPubSub) Read() (types.Message, error) {
+	return c.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new message from the target
+// subscription, returning types.ErrTimeout if no message arrives before the
+// deadline.
+func (c *GCPPubSub) ReadWithDeadline(deadline time.Time) (types.Message, error) {
 	c.subMut.Lock()
 	msgsChan := c.msgsChan
 	c.subMut.Unlock()
@@ -153,14 +180,26 @@ func (c *GCPPubSub) Read() (types.Message, error) {
 		return nil, types.ErrNotConnected
 	}
 
-	gmsg, open := <-msgsChan
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	var gmsg *pubsub.Message
+	var open bool
+	select {
+	case gmsg, open = <-msgsChan:
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
+	}
 	if !open {
 		return nil, types.ErrNotConnected
 	}
 	c.pendingMsgs = append(c.pendingMsgs, gmsg)
 
 	msg := message.New([][]byte{gmsg.Data})
-	msg.Get(0).SetMetadata(metadata.New(gmsg.Attributes))
+	meta := metadata.New(gmsg.Attributes)
+	meta.Set("gcp_pubsub_message_id", gmsg.ID)
+	meta.Set("gcp_pubsub_publish_time_unix", strconv.FormatInt(gmsg.PublishTime.Unix(), 10))
+	msg.Get(0).SetMetadata(meta)
 	return msg, nil
 }
 
@@ -181,6 +220,7 @@ func (c *GCPPubSub) Acknowledge(err error) error {
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (c *GCPPubSub) CloseAsync() {
 	c.subMut.Lock()
+	c.closed = true
 	if c.closeFunc != nil {
 		c.closeFunc()
 		c.closeFunc = nil
@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// GenerateConfig contains configuration fields for the Generate input type.
+type GenerateConfig struct {
+	Template string `json:"template" yaml:"template"`
+	Interval string `json:"interval" yaml:"interval"`
+	Count    int    `json:"count" yaml:"count"`
+}
+
+// NewGenerateConfig creates a new GenerateConfig with default values.
+func NewGenerateConfig() GenerateConfig {
+	return GenerateConfig{
+		Template: "",
+		Interval: "1s",
+		Count:    0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Generate is a reader that creates messages from a template, resolving any
+// function interpolations each time a message is produced.
+type Generate struct {
+	conf     GenerateConfig
+	template []byte
+	dynamic  bool
+
+	ticker    *time.Ticker
+	remaining int
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewGenerate creates a new Generate reader type.
+func NewGenerate(
+	conf GenerateConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Generate, error) {
+	var interval time.Duration
+	if tout := conf.Interval; len(tout) > 0 {
+		var err error
+		if interval, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse interval string: %v", err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval must be greater than zero: %v", tout)
+		}
+	}
+	if conf.Count < 0 {
+		return nil, fmt.Errorf("count must not be negative: %v", conf.Count)
+	}
+	template := []byte(conf.Template)
+	g := &Generate{
+		conf:       conf,
+		template:   template,
+		dynamic:    text.ContainsFunctionVariables(template),
+		remaining:  conf.Count,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log,
+		stats:      stats,
+	}
+	if interval > 0 {
+		g.ticker = time.NewTicker(interval)
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect is a noop since messages are generated locally.
+func (g *Generate) Connect() error {
+	if g.conf.Count > 0 {
+		g.log.Infof("Generating %v messages from template\n", g.conf.Count)
+	} else {
+		g.log.Infoln("Generating messages from template")
+	}
+	return nil
+}
+
+// Read generates a new message from the template, waiting for the next
+// interval tick if one is configured.
+func (g *Generate) Read() (types.Message, error) {
+	if g.conf.Count > 0 && g.remaining <= 0 {
+		return nil, types.ErrTypeClosed
+	}
+
+	if g.ticker != nil {
+		select {
+		case <-g.ticker.C:
+		case <-g.closeChan:
+			return nil, types.ErrTypeClosed
+		}
+	} else {
+		select {
+		case <-g.closeChan:
+			return nil, types.ErrTypeClosed
+		default:
+		}
+	}
+
+	msg := message.New(nil)
+	var content []byte
+	if g.dynamic {
+		content = text.ReplaceFunctionVariables(msg, g.template)
+	} else {
+		content = append([]byte(nil), g.template...)
+	}
+	msg.Append(message.NewPart(content))
+
+	if g.conf.Count > 0 {
+		g.remaining--
+	}
+	return msg, nil
+}
+
+// Acknowledge is a noop since generated messages cannot be redelivered.
+func (g *Generate) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (g *Generate) CloseAsync() {
+	g.closeOnce.Do(func() {
+		if g.ticker != nil {
+			g.ticker.Stop()
+		}
+		close(g.closeChan)
+		close(g.closedChan)
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (g *Generate) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-g.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,144 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGenerateCount(t *testing.T) {
+	conf := NewGenerateConfig()
+	conf.Template = "hello world"
+	conf.Interval = ""
+	conf.Count = 3
+
+	g, err := NewGenerate(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		g.CloseAsync()
+		if err := g.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		msg, err := g.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := "hello world", string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong content: %v != %v", act, exp)
+		}
+		if err = g.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err = g.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Expected ErrTypeClosed, received: %v", err)
+	}
+}
+
+func TestGenerateInterpolation(t *testing.T) {
+	conf := NewGenerateConfig()
+	conf.Template = "${!random_int:10,20}"
+	conf.Interval = "1ms"
+
+	g, err := NewGenerate(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.CloseAsync()
+
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		msg, err := g.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := strconv.Atoi(string(msg.Get(0).Get()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v < 10 || v > 20 {
+			t.Errorf("Value out of range: %v", v)
+		}
+	}
+}
+
+func TestGenerateCloseWhileWaiting(t *testing.T) {
+	conf := NewGenerateConfig()
+	conf.Template = "foo"
+	conf.Interval = "1h"
+
+	g, err := NewGenerate(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		g.CloseAsync()
+	}()
+
+	if _, err = g.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Expected ErrTypeClosed, received: %v", err)
+	}
+	if err = g.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGenerateBadConfig(t *testing.T) {
+	conf := NewGenerateConfig()
+	conf.Interval = "nope"
+	if _, err := NewGenerate(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad interval")
+	}
+
+	conf = NewGenerateConfig()
+	conf.Count = -1
+	if _, err := NewGenerate(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from negative count")
+	}
+}
+
+//------------------------------------------------------------------------------
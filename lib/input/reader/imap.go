@@ -0,0 +1,495 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+//------------------------------------------------------------------------------
+
+// IMAPConfig contains configuration fields for the IMAP input type.
+type IMAPConfig struct {
+	Address      string      `json:"address" yaml:"address"`
+	TLS          btls.Config `json:"tls" yaml:"tls"`
+	Username     string      `json:"username" yaml:"username"`
+	Password     string      `json:"password" yaml:"password"`
+	Mailbox      string      `json:"mailbox" yaml:"mailbox"`
+	Search       string      `json:"search" yaml:"search"`
+	MarkSeen     bool        `json:"mark_seen" yaml:"mark_seen"`
+	MoveTo       string      `json:"move_to" yaml:"move_to"`
+	PollInterval string      `json:"poll_interval" yaml:"poll_interval"`
+	Timeout      string      `json:"timeout" yaml:"timeout"`
+}
+
+// NewIMAPConfig creates a new IMAPConfig with default values.
+func NewIMAPConfig() IMAPConfig {
+	tlsConf := btls.NewConfig()
+	tlsConf.Enabled = true
+	return IMAPConfig{
+		Address:      "localhost:993",
+		TLS:          tlsConf,
+		Username:     "",
+		Password:     "",
+		Mailbox:      "INBOX",
+		Search:       "UNSEEN",
+		MarkSeen:     true,
+		MoveTo:       "",
+		PollInterval: "30s",
+		Timeout:      "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// IMAP is a reader type that polls an IMAP mailbox for emails.
+type IMAP struct {
+	conf         IMAPConfig
+	tlsConf      *tls.Config
+	criteria     *imap.SearchCriteria
+	pollInterval time.Duration
+	timeout      time.Duration
+
+	clientMut sync.Mutex
+	client    *client.Client
+	queue     []uint32
+	pending   []uint32
+
+	log   log.Modular
+	stats metrics.Type
+
+	mFetched  metrics.StatCounter
+	mFetchErr metrics.StatCounter
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewIMAP creates a new IMAP reader type.
+func NewIMAP(conf IMAPConfig, log log.Modular, stats metrics.Type) (*IMAP, error) {
+	i := &IMAP{
+		conf:      conf,
+		log:       log,
+		stats:     stats,
+		mFetched:  stats.GetCounter("fetched"),
+		mFetchErr: stats.GetCounter("fetch.error"),
+		closeChan: make(chan struct{}),
+	}
+	var err error
+	if i.pollInterval, err = time.ParseDuration(conf.PollInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse poll interval: %v", err)
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		if i.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	if len(conf.Search) == 0 {
+		return nil, errors.New("search criteria must not be empty")
+	}
+	if i.criteria, err = parseIMAPSearch(conf.Search); err != nil {
+		return nil, fmt.Errorf("failed to parse search criteria: %v", err)
+	}
+	if conf.TLS.Enabled {
+		if i.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	if !conf.MarkSeen && len(conf.MoveTo) == 0 {
+		log.Warnln(
+			"IMAP input configured without mark_seen or move_to, messages" +
+				" matching the search criteria will be read repeatedly.",
+		)
+	}
+	return i, nil
+}
+
+// parseIMAPSearch parses search criteria written in the syntax of the IMAP
+// SEARCH command (RFC 3501 section 6.4.4).
+func parseIMAPSearch(search string) (*imap.SearchCriteria, error) {
+	fields, err := imap.NewReader(
+		bufio.NewReader(strings.NewReader(search + "\r\n")),
+	).ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	criteria := imap.NewSearchCriteria()
+	if err = criteria.ParseWithCharset(fields, nil); err != nil {
+		return nil, err
+	}
+	return criteria, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (i *IMAP) disconnect() {
+	if i.client != nil {
+		i.client.Terminate()
+		i.client = nil
+	}
+	i.queue = nil
+}
+
+// connLost returns true if the connection to the server has been lost, which
+// distinguishes a failed connection from an error returned by the server.
+func (i *IMAP) connLost() bool {
+	select {
+	case <-i.client.LoggedOut():
+		return true
+	default:
+	}
+	return i.client.State() == imap.LogoutState
+}
+
+// Connect establishes a session with the IMAP server and selects the mailbox.
+func (i *IMAP) Connect() error {
+	i.clientMut.Lock()
+	defer i.clientMut.Unlock()
+
+	if i.client != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: i.timeout}
+
+	var c *client.Client
+	var err error
+	if i.tlsConf != nil {
+		c, err = client.DialWithDialerTLS(dialer, i.conf.Address, i.tlsConf)
+	} else {
+		c, err = client.DialWithDialer(dialer, i.conf.Address)
+	}
+	if err != nil {
+		return err
+	}
+	c.Timeout = i.timeout
+
+	if err = c.Login(i.conf.Username, i.conf.Password); err != nil {
+		c.Terminate()
+		return err
+	}
+	if _, err = c.Select(i.conf.Mailbox, false); err != nil {
+		c.Logout()
+		return err
+	}
+
+	i.client = c
+	i.log.Infof("Polling IMAP mailbox '%v' at: %v\n", i.conf.Mailbox, i.conf.Address)
+	return nil
+}
+
+// Read attempts to read a new email from the mailbox.
+func (i *IMAP) Read() (types.Message, error) {
+	i.clientMut.Lock()
+	defer i.clientMut.Unlock()
+
+	if i.client == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	if len(i.queue) == 0 {
+		uids, err := i.client.UidSearch(i.criteria)
+		if err != nil {
+			if i.connLost() {
+				i.disconnect()
+				return nil, types.ErrNotConnected
+			}
+			return nil, err
+		}
+		for _, uid := range uids {
+			if !i.isPending(uid) {
+				i.queue = append(i.queue, uid)
+			}
+		}
+		if len(i.queue) == 0 {
+			select {
+			case <-time.After(i.pollInterval):
+			case <-i.closeChan:
+				return nil, types.ErrTypeClosed
+			}
+			return nil, types.ErrTimeout
+		}
+	}
+
+	uid := i.queue[0]
+	raw, err := i.fetch(uid)
+	if err != nil {
+		i.mFetchErr.Incr(1)
+		if i.connLost() {
+			i.disconnect()
+			return nil, types.ErrNotConnected
+		}
+		i.queue = i.queue[1:]
+		return nil, fmt.Errorf("failed to fetch message %v: %v", uid, err)
+	}
+	i.queue = i.queue[1:]
+
+	msg, err := parseEmail(raw)
+	if err != nil {
+		i.mFetchErr.Incr(1)
+		return nil, fmt.Errorf("failed to parse message %v: %v", uid, err)
+	}
+	msg.Iter(func(_ int, p types.Part) error {
+		p.Metadata().
+			Set("imap_uid", strconv.FormatUint(uint64(uid), 10)).
+			Set("imap_mailbox", i.conf.Mailbox)
+		return nil
+	})
+
+	i.mFetched.Incr(1)
+	i.pending = append(i.pending, uid)
+	return msg, nil
+}
+
+// fetch reads the full raw contents of an email without setting the \Seen
+// flag, which is left to Acknowledge.
+func (i *IMAP) fetch(uid uint32) ([]byte, error) {
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+
+	section := &imap.BodySectionName{Peek: true}
+	msgChan := make(chan *imap.Message, 1)
+	if err := i.client.UidFetch(seqSet, []imap.FetchItem{section.FetchItem()}, msgChan); err != nil {
+		return nil, err
+	}
+
+	m, open := <-msgChan
+	if !open || m == nil {
+		return nil, errors.New("message not found")
+	}
+	body := m.GetBody(section)
+	if body == nil {
+		return nil, errors.New("server did not return a message body")
+	}
+	return ioutil.ReadAll(body)
+}
+
+func (i *IMAP) isPending(uid uint32) bool {
+	for _, p := range i.pending {
+		if p == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// Acknowledge marks or moves emails that have been successfully propagated.
+func (i *IMAP) Acknowledge(err error) error {
+	if err != nil {
+		return nil
+	}
+
+	i.clientMut.Lock()
+	defer i.clientMut.Unlock()
+
+	if i.client == nil {
+		return types.ErrNotConnected
+	}
+	if len(i.pending) == 0 {
+		return nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(i.pending...)
+
+	if i.conf.MarkSeen {
+		flagOp := imap.FormatFlagsOp(imap.AddFlags, true)
+		if err = i.client.UidStore(seqSet, flagOp, []interface{}{imap.SeenFlag}, nil); err != nil {
+			return err
+		}
+	}
+	if len(i.conf.MoveTo) > 0 {
+		if err = i.client.UidMove(seqSet, i.conf.MoveTo); err != nil {
+			return err
+		}
+	}
+	i.pending = nil
+	return nil
+}
+
+// CloseAsync shuts down the IMAP input and stops processing requests.
+func (i *IMAP) CloseAsync() {
+	i.closeOnce.Do(func() {
+		close(i.closeChan)
+	})
+}
+
+// WaitForClose blocks until the IMAP input has closed down.
+func (i *IMAP) WaitForClose(timeout time.Duration) error {
+	i.clientMut.Lock()
+	if i.client != nil {
+		i.client.Logout()
+		i.client = nil
+	}
+	i.clientMut.Unlock()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+var headerDecoder = &mime.WordDecoder{}
+
+// parseEmail parses a raw email into a message, where each body part and
+// attachment is a message part. The headers of the email are added as metadata
+// to each part.
+func parseEmail(raw []byte) (types.Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]string{}
+	keys := make([]string, 0, len(m.Header))
+	for k := range m.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		values := make([]string, 0, len(m.Header[k]))
+		for _, v := range m.Header[k] {
+			if decoded, derr := headerDecoder.DecodeHeader(v); derr == nil {
+				v = decoded
+			}
+			values = append(values, v)
+		}
+		meta["imap_header_"+strings.Replace(strings.ToLower(k), "-", "_", -1)] = strings.Join(values, ", ")
+	}
+
+	msg := message.New(nil)
+	addPart := func(body []byte, contentType, filename string) {
+		part := message.NewPart(body)
+		for k, v := range meta {
+			part.Metadata().Set(k, v)
+		}
+		part.Metadata().Set("imap_part_content_type", contentType)
+		if len(filename) > 0 {
+			part.Metadata().Set("imap_part_filename", filename)
+		}
+		msg.Append(part)
+	}
+
+	contentType := m.Header.Get("Content-Type")
+	mediaType, params, perr := mime.ParseMediaType(contentType)
+	if perr != nil {
+		mediaType, contentType = "text/plain", "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if err = walkMultipart(multipart.NewReader(m.Body, params["boundary"]), addPart); err != nil {
+			return nil, err
+		}
+	} else {
+		body, err := decodeTransferEncoding(m.Body, m.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return nil, err
+		}
+		addPart(body, contentType, "")
+	}
+	if msg.Len() == 0 {
+		addPart(nil, contentType, "")
+	}
+	return msg, nil
+}
+
+func walkMultipart(r *multipart.Reader, addPart func(body []byte, contentType, filename string)) error {
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		contentType := p.Header.Get("Content-Type")
+		if len(contentType) == 0 {
+			contentType = "text/plain"
+		}
+		if mediaType, params, perr := mime.ParseMediaType(contentType); perr == nil && strings.HasPrefix(mediaType, "multipart/") {
+			if err = walkMultipart(multipart.NewReader(p, params["boundary"]), addPart); err != nil {
+				return err
+			}
+			continue
+		}
+		// Quoted-printable parts are decoded by the multipart reader.
+		body, err := decodeTransferEncoding(p, p.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return err
+		}
+		addPart(body, contentType, p.FileName())
+	}
+}
+
+func decodeTransferEncoding(r io.Reader, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: r})
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	return ioutil.ReadAll(r)
+}
+
+// newlineStripper removes line breaks from base64 encoded content.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	for {
+		read, err := n.r.Read(p)
+		out := 0
+		for _, b := range p[:read] {
+			if b != '\r' && b != '\n' {
+				p[out] = b
+				out++
+			}
+		}
+		if out > 0 || err != nil {
+			return out, err
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
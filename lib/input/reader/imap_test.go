@@ -0,0 +1,164 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestIMAPBadConfig(t *testing.T) {
+	conf := NewIMAPConfig()
+	conf.PollInterval = "nope"
+	if _, err := NewIMAP(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad poll interval")
+	}
+
+	conf = NewIMAPConfig()
+	conf.Search = ""
+	if _, err := NewIMAP(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty search")
+	}
+
+	conf = NewIMAPConfig()
+	conf.Search = "NOTAKEY foo"
+	if _, err := NewIMAP(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad search")
+	}
+}
+
+func TestIMAPParseSearch(t *testing.T) {
+	criteria, err := parseIMAPSearch(`UNSEEN FROM "foo@example.com" SINCE 1-Feb-2019`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(criteria.WithoutFlags); exp != act || criteria.WithoutFlags[0] != `\Seen` {
+		t.Errorf("Wrong flags: %v", criteria.WithoutFlags)
+	}
+	if exp, act := "foo@example.com", criteria.Header.Get("From"); exp != act {
+		t.Errorf("Wrong from: %v != %v", act, exp)
+	}
+	if criteria.Since.IsZero() {
+		t.Error("Expected since to be set")
+	}
+}
+
+func TestIMAPParsePlain(t *testing.T) {
+	raw := strings.Join([]string{
+		"From: Foo <foo@example.com>",
+		"To: bar@example.com",
+		"Subject: =?utf-8?q?hello_world?=",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"hello =3D world",
+	}, "\r\n")
+
+	msg, err := parseEmail([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, msg.Len(); exp != act {
+		t.Fatalf("Wrong count of parts: %v != %v", act, exp)
+	}
+	if exp, act := "hello = world", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong body: %v != %v", act, exp)
+	}
+	meta := msg.Get(0).Metadata()
+	if exp, act := "hello world", meta.Get("imap_header_subject"); exp != act {
+		t.Errorf("Wrong subject: %v != %v", act, exp)
+	}
+	if exp, act := "Foo <foo@example.com>", meta.Get("imap_header_from"); exp != act {
+		t.Errorf("Wrong from: %v != %v", act, exp)
+	}
+	if exp, act := "quoted-printable", meta.Get("imap_header_content_transfer_encoding"); exp != act {
+		t.Errorf("Wrong header: %v != %v", act, exp)
+	}
+}
+
+func TestIMAPParseMultipart(t *testing.T) {
+	raw := strings.Join([]string{
+		"From: foo@example.com",
+		"Subject: attached",
+		`Content-Type: multipart/mixed; boundary="outer"`,
+		"",
+		"--outer",
+		`Content-Type: multipart/alternative; boundary="inner"`,
+		"",
+		"--inner",
+		"Content-Type: text/plain",
+		"",
+		"plain body",
+		"--inner",
+		"Content-Type: text/html",
+		"",
+		"<p>html body</p>",
+		"--inner--",
+		"--outer",
+		"Content-Type: application/octet-stream",
+		`Content-Disposition: attachment; filename="data.bin"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		"aGVsbG8g",
+		"d29ybGQ=",
+		"--outer--",
+	}, "\r\n")
+
+	msg, err := parseEmail([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 3, msg.Len(); exp != act {
+		t.Fatalf("Wrong count of parts: %v != %v", act, exp)
+	}
+
+	exp := []struct {
+		body        string
+		contentType string
+		filename    string
+	}{
+		{"plain body", "text/plain", ""},
+		{"<p>html body</p>", "text/html", ""},
+		{"hello world", "application/octet-stream", "data.bin"},
+	}
+	for i, e := range exp {
+		part := msg.Get(i)
+		if act := string(part.Get()); act != e.body {
+			t.Errorf("Wrong body %v: %v != %v", i, act, e.body)
+		}
+		if act := part.Metadata().Get("imap_part_content_type"); act != e.contentType {
+			t.Errorf("Wrong content type %v: %v != %v", i, act, e.contentType)
+		}
+		if act := part.Metadata().Get("imap_part_filename"); act != e.filename {
+			t.Errorf("Wrong filename %v: %v != %v", i, act, e.filename)
+		}
+		if act := part.Metadata().Get("imap_header_subject"); act != "attached" {
+			t.Errorf("Wrong subject %v: %v", i, act)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -21,6 +21,8 @@
 package reader
 
 import (
+	"time"
+
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -47,3 +49,25 @@ type Type interface {
 
 	types.Closable
 }
+
+// DeadlineReader is an optional interface for reader.Type implementations that
+// are able to abandon a blocking read once a deadline is reached. This allows
+// wrappers such as Batcher to act on pending messages whilst the source is
+// idle.
+type DeadlineReader interface {
+	// ReadWithDeadline attempts to read a new message from the source. If no
+	// message is available before the deadline then types.ErrTimeout is
+	// returned and no message is consumed.
+	ReadWithDeadline(deadline time.Time) (types.Message, error)
+}
+
+// deadlineChan returns a channel that fires once the deadline is reached along
+// with a func for releasing its resources. A zero deadline returns a nil
+// channel that blocks forever.
+func deadlineChan(deadline time.Time) (<-chan time.Time, func()) {
+	if deadline.IsZero() {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	return timer.C, func() { timer.Stop() }
+}
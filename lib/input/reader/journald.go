@@ -0,0 +1,399 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// JournaldConfig contains configuration fields for the Journald input type.
+type JournaldConfig struct {
+	Units           []string `json:"units" yaml:"units"`
+	Priority        string   `json:"priority" yaml:"priority"`
+	Directory       string   `json:"directory" yaml:"directory"`
+	CursorPath      string   `json:"cursor_path" yaml:"cursor_path"`
+	StartFromOldest bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	JournalctlPath  string   `json:"journalctl_path" yaml:"journalctl_path"`
+	MaxBuffer       int      `json:"max_buffer" yaml:"max_buffer"`
+}
+
+// NewJournaldConfig creates a new JournaldConfig with default values.
+func NewJournaldConfig() JournaldConfig {
+	return JournaldConfig{
+		Units:           []string{},
+		Priority:        "",
+		Directory:       "",
+		CursorPath:      "",
+		StartFromOldest: false,
+		JournalctlPath:  "journalctl",
+		MaxBuffer:       1048576,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// journaldStream is a stream of JSON journal entries, one per line.
+type journaldStream interface {
+	io.Reader
+	Close() error
+}
+
+type journalctlProcess struct {
+	io.Reader
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+func (j *journalctlProcess) Close() error {
+	j.cmd.Process.Kill()
+	err := j.cmd.Wait()
+	if err != nil && j.stderr.Len() > 0 {
+		err = fmt.Errorf("%v: %s", err, bytes.TrimSpace(j.stderr.Bytes()))
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// Journald is a reader that consumes entries from the systemd journal by
+// following the JSON output of journalctl.
+type Journald struct {
+	conf JournaldConfig
+
+	startStream func(args []string) (journaldStream, error)
+
+	streamMut sync.Mutex
+	stream    journaldStream
+	scanner   *bufio.Scanner
+
+	cursorMut     sync.Mutex
+	cursorLoaded  bool
+	cursor        string
+	pendingCursor string
+	flushed       time.Time
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mEntries  metrics.StatCounter
+	mParseErr metrics.StatCounter
+	mFlushErr metrics.StatCounter
+}
+
+// NewJournald creates a new Journald reader type.
+func NewJournald(
+	conf JournaldConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Journald, error) {
+	if len(conf.JournalctlPath) == 0 {
+		return nil, fmt.Errorf("journalctl path must not be empty")
+	}
+	j := &Journald{
+		conf:      conf,
+		closeChan: make(chan struct{}),
+		log:       log,
+		stats:     stats,
+		mEntries:  stats.GetCounter("journald.entries"),
+		mParseErr: stats.GetCounter("journald.parse.error"),
+		mFlushErr: stats.GetCounter("journald.cursor.error"),
+	}
+	j.startStream = j.startJournalctl
+	return j, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (j *Journald) startJournalctl(args []string) (journaldStream, error) {
+	cmd := exec.Command(j.conf.JournalctlPath, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start journalctl: %v", err)
+	}
+	return &journalctlProcess{Reader: stdout, cmd: cmd, stderr: stderr}, nil
+}
+
+// args returns the journalctl arguments for following the journal from the
+// committed cursor. Must be called with cursorMut locked.
+func (j *Journald) args() []string {
+	args := []string{"--output=json", "--follow", "--no-pager", "--all"}
+	if len(j.cursor) > 0 {
+		args = append(args, "--after-cursor="+j.cursor)
+	} else if j.conf.StartFromOldest {
+		args = append(args, "--no-tail")
+	} else {
+		args = append(args, "--lines=0")
+	}
+	for _, unit := range j.conf.Units {
+		args = append(args, "--unit="+unit)
+	}
+	if len(j.conf.Priority) > 0 {
+		args = append(args, "--priority="+j.conf.Priority)
+	}
+	if len(j.conf.Directory) > 0 {
+		args = append(args, "--directory="+j.conf.Directory)
+	}
+	return args
+}
+
+func (j *Journald) loadCursor() error {
+	if len(j.conf.CursorPath) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(j.conf.CursorPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read cursor: %v", err)
+	}
+	j.cursor = string(bytes.TrimSpace(data))
+	return nil
+}
+
+// flush writes the committed cursor to disk. Must be called with cursorMut
+// locked.
+func (j *Journald) flush() error {
+	if len(j.conf.CursorPath) == 0 || len(j.cursor) == 0 {
+		return nil
+	}
+	tmpPath := j.conf.CursorPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, []byte(j.cursor), 0644); err != nil {
+		return err
+	}
+	j.flushed = time.Now()
+	return os.Rename(tmpPath, j.conf.CursorPath)
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to start following the journal from the committed cursor.
+func (j *Journald) Connect() error {
+	j.streamMut.Lock()
+	defer j.streamMut.Unlock()
+
+	if j.stream != nil {
+		return nil
+	}
+	select {
+	case <-j.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	j.cursorMut.Lock()
+	if !j.cursorLoaded {
+		if err := j.loadCursor(); err != nil {
+			j.cursorMut.Unlock()
+			return err
+		}
+		j.cursorLoaded = true
+	}
+	args := j.args()
+	cursor := j.cursor
+	j.cursorMut.Unlock()
+
+	stream, err := j.startStream(args)
+	if err != nil {
+		return err
+	}
+	j.stream = stream
+	j.scanner = bufio.NewScanner(stream)
+	j.scanner.Buffer(make([]byte, 0, 4096), j.conf.MaxBuffer)
+
+	if len(cursor) > 0 {
+		j.log.Infof("Reading journal entries after cursor: %v\n", cursor)
+	} else {
+		j.log.Infoln("Reading journal entries")
+	}
+	return nil
+}
+
+func (j *Journald) disconnect() error {
+	j.streamMut.Lock()
+	defer j.streamMut.Unlock()
+
+	var err error
+	if j.stream != nil {
+		err = j.stream.Close()
+		j.stream = nil
+		j.scanner = nil
+	}
+	return err
+}
+
+// journalValue converts a field value from the JSON output of journalctl,
+// where non-printable values are represented as arrays of bytes.
+func journalValue(v interface{}) interface{} {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return v
+	}
+	bytesValue := make([]byte, 0, len(arr))
+	for _, e := range arr {
+		n, isNum := e.(float64)
+		if !isNum || n < 0 || n > 255 {
+			// Fields that appear multiple times are also arrays.
+			for i, e := range arr {
+				arr[i] = journalValue(e)
+			}
+			return arr
+		}
+		bytesValue = append(bytesValue, byte(n))
+	}
+	return string(bytesValue)
+}
+
+// Read attempts to read a new entry from the journal.
+func (j *Journald) Read() (types.Message, error) {
+	j.streamMut.Lock()
+	scanner := j.scanner
+	j.streamMut.Unlock()
+
+	if scanner == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			j.mParseErr.Incr(1)
+			j.log.Errorf("Failed to parse journal entry: %v\n", err)
+			continue
+		}
+		for k, v := range entry {
+			entry[k] = journalValue(v)
+		}
+		entryBytes, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		j.mEntries.Incr(1)
+
+		part := message.NewPart(entryBytes)
+		meta := part.Metadata()
+		if cursor, ok := entry["__CURSOR"].(string); ok {
+			meta.Set("journald_cursor", cursor)
+			j.pendingCursor = cursor
+		}
+		if unit, ok := entry["_SYSTEMD_UNIT"].(string); ok {
+			meta.Set("journald_unit", unit)
+		}
+		if priority, ok := entry["PRIORITY"].(string); ok {
+			meta.Set("journald_priority", priority)
+		}
+		if hostname, ok := entry["_HOSTNAME"].(string); ok {
+			meta.Set("journald_hostname", hostname)
+		}
+		if tsStr, ok := entry["__REALTIME_TIMESTAMP"].(string); ok {
+			if ts, err := strconv.ParseInt(tsStr, 10, 64); err == nil {
+				meta.Set("journald_timestamp", time.Unix(0, ts*int64(time.Microsecond)).UTC().Format(time.RFC3339Nano))
+			}
+		}
+
+		msg := message.New(nil)
+		msg.Append(part)
+		return msg, nil
+	}
+
+	err := scanner.Err()
+	closeErr := j.disconnect()
+	select {
+	case <-j.closeChan:
+		return nil, types.ErrTypeClosed
+	default:
+	}
+	if err != nil {
+		j.log.Errorf("Failed to read journal: %v\n", err)
+	} else if closeErr != nil {
+		j.log.Errorf("Journal stream ended: %v\n", closeErr)
+	} else {
+		j.log.Warnln("Journal stream ended unexpectedly")
+	}
+	return nil, types.ErrNotConnected
+}
+
+// Acknowledge commits the cursor of the last read entry once it has been
+// successfully propagated.
+func (j *Journald) Acknowledge(err error) error {
+	if err != nil || len(j.pendingCursor) == 0 {
+		return nil
+	}
+
+	j.cursorMut.Lock()
+	defer j.cursorMut.Unlock()
+
+	j.cursor = j.pendingCursor
+	j.pendingCursor = ""
+	if time.Since(j.flushed) >= time.Second {
+		if ferr := j.flush(); ferr != nil {
+			j.mFlushErr.Incr(1)
+			j.log.Errorf("Failed to persist journal cursor: %v\n", ferr)
+		}
+	}
+	return nil
+}
+
+// CloseAsync shuts down the Journald input and stops processing requests.
+func (j *Journald) CloseAsync() {
+	j.closeOnce.Do(func() {
+		close(j.closeChan)
+		j.disconnect()
+
+		j.cursorMut.Lock()
+		if err := j.flush(); err != nil {
+			j.log.Errorf("Failed to persist journal cursor: %v\n", err)
+		}
+		j.cursorMut.Unlock()
+	})
+}
+
+// WaitForClose blocks until the Journald input has closed down.
+func (j *Journald) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
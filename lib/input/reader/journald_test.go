@@ -0,0 +1,177 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type fakeJournalStream struct {
+	io.Reader
+	closed bool
+}
+
+func (f *fakeJournalStream) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestJournaldRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_journald_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cursorPath := filepath.Join(dir, "cursor")
+	if err = ioutil.WriteFile(cursorPath, []byte("s=start\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewJournaldConfig()
+	conf.Units = []string{"foo.service", "bar.service"}
+	conf.Priority = "warning"
+	conf.CursorPath = cursorPath
+
+	j, err := NewJournald(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var streams [][]string
+	entries := []string{
+		`{"__CURSOR":"s=1","__REALTIME_TIMESTAMP":"1546300800000001","_SYSTEMD_UNIT":"foo.service","PRIORITY":"4","_HOSTNAME":"host1","MESSAGE":"hello world"}`,
+		`not json`,
+		`{"__CURSOR":"s=2","_SYSTEMD_UNIT":"bar.service","PRIORITY":"3","MESSAGE":[104,105,0],"TAG":["a","b"]}`,
+	}
+	j.startStream = func(args []string) (journaldStream, error) {
+		streams = append(streams, args)
+		if len(streams) == 1 {
+			return &fakeJournalStream{Reader: strings.NewReader(strings.Join(entries, "\n") + "\n")}, nil
+		}
+		return &fakeJournalStream{Reader: strings.NewReader("")}, nil
+	}
+
+	if err = j.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	expArgs := []string{
+		"--output=json", "--follow", "--no-pager", "--all",
+		"--after-cursor=s=start",
+		"--unit=foo.service", "--unit=bar.service",
+		"--priority=warning",
+	}
+	if !reflect.DeepEqual(expArgs, streams[0]) {
+		t.Errorf("Wrong args: %v != %v", streams[0], expArgs)
+	}
+
+	msg, err := j.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"MESSAGE":"hello world","PRIORITY":"4","_HOSTNAME":"host1","_SYSTEMD_UNIT":"foo.service","__CURSOR":"s=1","__REALTIME_TIMESTAMP":"1546300800000001"}`, string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong content: %v != %v", act, exp)
+	}
+	expMeta := map[string]string{
+		"journald_cursor":    "s=1",
+		"journald_unit":      "foo.service",
+		"journald_priority":  "4",
+		"journald_hostname":  "host1",
+		"journald_timestamp": "2019-01-01T00:00:00.000001Z",
+	}
+	for k, v := range expMeta {
+		if act := msg.Get(0).Metadata().Get(k); act != v {
+			t.Errorf("Wrong metadata %v: %v != %v", k, act, v)
+		}
+	}
+	if err = j.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg, err = j.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"MESSAGE":"hi\u0000","PRIORITY":"3","TAG":["a","b"],"_SYSTEMD_UNIT":"bar.service","__CURSOR":"s=2"}`, string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong content: %v != %v", act, exp)
+	}
+
+	// The stream ending should result in a reconnect from the acknowledged
+	// cursor, as the second entry was not acknowledged.
+	if _, err = j.Read(); err != types.ErrNotConnected {
+		t.Fatalf("Expected ErrNotConnected, received: %v", err)
+	}
+	if err = j.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "--after-cursor=s=1", streams[1][4]; exp != act {
+		t.Errorf("Wrong reconnect cursor: %v != %v", act, exp)
+	}
+
+	j.CloseAsync()
+	if err = j.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	cursor, err := ioutil.ReadFile(cursorPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "s=1", string(cursor); exp != act {
+		t.Errorf("Wrong persisted cursor: %v != %v", act, exp)
+	}
+}
+
+func TestJournaldArgsWithoutCursor(t *testing.T) {
+	conf := NewJournaldConfig()
+	j, err := NewJournald(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "--lines=0", j.args()[4]; exp != act {
+		t.Errorf("Wrong arg: %v != %v", act, exp)
+	}
+
+	conf.StartFromOldest = true
+	conf.Directory = "/var/log/journal"
+	if j, err = NewJournald(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{"--output=json", "--follow", "--no-pager", "--all", "--no-tail", "--directory=/var/log/journal"}
+	if act := j.args(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong args: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -22,6 +22,7 @@ package reader
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -47,6 +48,8 @@ type KafkaConfig struct {
 	Topic           string      `json:"topic" yaml:"topic"`
 	Partition       int32       `json:"partition" yaml:"partition"`
 	StartFromOldest bool        `json:"start_from_oldest" yaml:"start_from_oldest"`
+	StartOffset     int64       `json:"start_offset" yaml:"start_offset"`
+	StartTimestamp  string      `json:"start_timestamp" yaml:"start_timestamp"`
 	TargetVersion   string      `json:"target_version" yaml:"target_version"`
 	MaxBatchCount   int         `json:"max_batch_count" yaml:"max_batch_count"`
 	TLS             btls.Config `json:"tls" yaml:"tls"`
@@ -62,6 +65,8 @@ func NewKafkaConfig() KafkaConfig {
 		Topic:           "benthos_stream",
 		Partition:       0,
 		StartFromOldest: true,
+		StartOffset:     -1,
+		StartTimestamp:  "",
 		TargetVersion:   sarama.V1_0_0_0.String(),
 		MaxBatchCount:   1,
 		TLS:             btls.NewConfig(),
@@ -90,6 +95,13 @@ type Kafka struct {
 	offsetCommit    int64
 	offset          int64
 
+	// startTime is the explicit start position as unix milliseconds, or zero.
+	startTime int64
+
+	// startResolved is set once an explicit start position has been consumed
+	// from, after which reconnects resume from the committed offset.
+	startResolved bool
+
 	addresses []string
 	conf      KafkaConfig
 	stats     metrics.Type
@@ -134,6 +146,21 @@ func NewKafka(
 			return nil, fmt.Errorf("failed to parse commit period string: %v", err)
 		}
 	}
+
+	if len(conf.StartTimestamp) > 0 {
+		if conf.StartOffset >= 0 {
+			return nil, errors.New("a start_offset and start_timestamp cannot both be specified")
+		}
+		startTime, err := time.Parse(time.RFC3339, conf.StartTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse start timestamp: %v", err)
+		}
+		k.startTime = startTime.UnixNano() / int64(time.Millisecond)
+	} else if conf.StartOffset < -1 {
+		return nil, fmt.Errorf("invalid start offset: %v", conf.StartOffset)
+	} else if conf.StartOffset == -1 {
+		k.startResolved = true
+	}
 	return &k, nil
 }
 
@@ -214,15 +241,27 @@ func (k *Kafka) Connect() error {
 		return err
 	}
 
-	offsetReq := sarama.OffsetFetchRequest{}
-	offsetReq.ConsumerGroup = k.conf.ConsumerGroup
-	offsetReq.AddPartition(k.conf.Topic, k.conf.Partition)
+	if k.startResolved {
+		offsetReq := sarama.OffsetFetchRequest{}
+		offsetReq.ConsumerGroup = k.conf.ConsumerGroup
+		offsetReq.AddPartition(k.conf.Topic, k.conf.Partition)
 
-	if offsetRes, err := k.coordinator.FetchOffset(&offsetReq); err == nil {
-		offsetBlock := offsetRes.Blocks[k.conf.Topic][k.conf.Partition]
-		if offsetBlock.Err == sarama.ErrNoError {
-			k.offset = offsetBlock.Offset
+		if offsetRes, err := k.coordinator.FetchOffset(&offsetReq); err == nil {
+			offsetBlock := offsetRes.Blocks[k.conf.Topic][k.conf.Partition]
+			if offsetBlock.Err == sarama.ErrNoError {
+				k.offset = offsetBlock.Offset
+			}
 		}
+	} else if k.startTime > 0 {
+		if k.offset, err = k.client.GetOffset(
+			k.conf.Topic, k.conf.Partition, k.startTime,
+		); err != nil {
+			return err
+		}
+		k.log.Infof("Starting from offset %v at timestamp %v\n", k.offset, k.conf.StartTimestamp)
+	} else {
+		k.offset = k.conf.StartOffset
+		k.log.Infof("Starting from offset %v\n", k.offset)
 	}
 
 	var partConsumer sarama.PartitionConsumer
@@ -257,6 +296,7 @@ func (k *Kafka) Connect() error {
 	}
 
 	k.partConsumer = partConsumer
+	k.startResolved = true
 	k.log.Infof("Receiving Kafka messages from addresses: %s\n", k.addresses)
 
 	go func() {
@@ -273,6 +313,12 @@ func (k *Kafka) Connect() error {
 
 // Read attempts to read a message from a Kafka topic.
 func (k *Kafka) Read() (types.Message, error) {
+	return k.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a message from a Kafka topic, returning
+// types.ErrTimeout if no message arrives before the deadline.
+func (k *Kafka) ReadWithDeadline(deadline time.Time) (types.Message, error) {
 	var partConsumer sarama.PartitionConsumer
 
 	k.sMut.Lock()
@@ -301,7 +347,16 @@ func (k *Kafka) Read() (types.Message, error) {
 		msg.Append(part)
 	}
 
-	data, open := <-partConsumer.Messages()
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	var data *sarama.ConsumerMessage
+	var open bool
+	select {
+	case data, open = <-partConsumer.Messages():
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
+	}
 	if !open {
 		return nil, types.ErrTypeClosed
 	}
@@ -357,7 +412,7 @@ func (k *Kafka) commit() error {
 
 	commitReq := sarama.OffsetCommitRequest{}
 	commitReq.ConsumerGroup = k.conf.ConsumerGroup
-	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, 0, "")
+	commitReq.AddBlock(k.conf.Topic, k.conf.Partition, k.offset, -1, 0, "")
 
 	commitRes, err := coordinator.CommitOffset(&commitReq)
 	if err == nil {
@@ -21,6 +21,7 @@
 package reader
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"strconv"
@@ -34,22 +35,39 @@ import (
 	"github.com/Jeffail/benthos/lib/types"
 	btls "github.com/Jeffail/benthos/lib/util/tls"
 	"github.com/Shopify/sarama"
-	cluster "github.com/bsm/sarama-cluster"
 )
 
 //------------------------------------------------------------------------------
 
+// KafkaBalancedGroupConfig contains config fields for Kafka consumer groups.
+type KafkaBalancedGroupConfig struct {
+	SessionTimeout    string `json:"session_timeout" yaml:"session_timeout"`
+	HeartbeatInterval string `json:"heartbeat_interval" yaml:"heartbeat_interval"`
+	PartitionStrategy string `json:"partition_strategy" yaml:"partition_strategy"`
+}
+
+// NewKafkaBalancedGroupConfig returns a KafkaBalancedGroupConfig with default
+// values.
+func NewKafkaBalancedGroupConfig() KafkaBalancedGroupConfig {
+	return KafkaBalancedGroupConfig{
+		SessionTimeout:    "30s",
+		HeartbeatInterval: "3s",
+		PartitionStrategy: sarama.BalanceStrategyRange.Name(),
+	}
+}
+
 // KafkaBalancedConfig contains configuration for the KafkaBalanced input type.
 type KafkaBalancedConfig struct {
-	Addresses       []string    `json:"addresses" yaml:"addresses"`
-	ClientID        string      `json:"client_id" yaml:"client_id"`
-	ConsumerGroup   string      `json:"consumer_group" yaml:"consumer_group"`
-	CommitPeriod    string      `json:"commit_period" yaml:"commit_period"`
-	Topics          []string    `json:"topics" yaml:"topics"`
-	StartFromOldest bool        `json:"start_from_oldest" yaml:"start_from_oldest"`
-	TargetVersion   string      `json:"target_version" yaml:"target_version"`
-	MaxBatchCount   int         `json:"max_batch_count" yaml:"max_batch_count"`
-	TLS             btls.Config `json:"tls" yaml:"tls"`
+	Addresses       []string                 `json:"addresses" yaml:"addresses"`
+	ClientID        string                   `json:"client_id" yaml:"client_id"`
+	ConsumerGroup   string                   `json:"consumer_group" yaml:"consumer_group"`
+	Group           KafkaBalancedGroupConfig `json:"group" yaml:"group"`
+	CommitPeriod    string                   `json:"commit_period" yaml:"commit_period"`
+	Topics          []string                 `json:"topics" yaml:"topics"`
+	StartFromOldest bool                     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	TargetVersion   string                   `json:"target_version" yaml:"target_version"`
+	MaxBatchCount   int                      `json:"max_batch_count" yaml:"max_batch_count"`
+	TLS             btls.Config              `json:"tls" yaml:"tls"`
 }
 
 // NewKafkaBalancedConfig creates a new KafkaBalancedConfig with default values.
@@ -58,6 +76,7 @@ func NewKafkaBalancedConfig() KafkaBalancedConfig {
 		Addresses:       []string{"localhost:9092"},
 		ClientID:        "benthos_kafka_input",
 		ConsumerGroup:   "benthos_consumer_group",
+		Group:           NewKafkaBalancedGroupConfig(),
 		CommitPeriod:    "1s",
 		Topics:          []string{"benthos_stream"},
 		StartFromOldest: true,
@@ -72,18 +91,30 @@ func NewKafkaBalancedConfig() KafkaBalancedConfig {
 // KafkaBalanced is an input type that reads from a Kafka cluster by balancing
 // partitions across other consumers of the same consumer group.
 type KafkaBalanced struct {
-	consumer *cluster.Consumer
-	version  sarama.KafkaVersion
-	cMut     sync.Mutex
+	group       sarama.ConsumerGroup
+	groupCtx    context.Context
+	groupCancel func()
+	session     sarama.ConsumerGroupSession
+	version     sarama.KafkaVersion
+	cMut        sync.Mutex
+
+	msgChan chan *sarama.ConsumerMessage
 
 	tlsConf *tls.Config
 
 	offsetLastCommitted time.Time
 	offsets             map[string]map[int32]int64
+	offsetsMut          sync.Mutex
 	commitPeriod        time.Duration
 
-	mRcvErr     metrics.StatCounter
-	mRebalanced metrics.StatCounter
+	sessionTimeout    time.Duration
+	heartbeatInterval time.Duration
+	partitionStrategy sarama.BalanceStrategy
+
+	mRcvErr      metrics.StatCounter
+	mRebalanced  metrics.StatCounter
+	mPartClaimed metrics.StatCounter
+	mPartRelease metrics.StatCounter
 
 	addresses []string
 	topics    []string
@@ -97,12 +128,15 @@ func NewKafkaBalanced(
 	conf KafkaBalancedConfig, log log.Modular, stats metrics.Type,
 ) (*KafkaBalanced, error) {
 	k := KafkaBalanced{
-		conf:        conf,
-		stats:       stats,
-		mRcvErr:     stats.GetCounter("recv.error"),
-		mRebalanced: stats.GetCounter("rebalanced"),
-		offsets:     map[string]map[int32]int64{},
-		log:         log,
+		conf:         conf,
+		stats:        stats,
+		msgChan:      make(chan *sarama.ConsumerMessage),
+		mRcvErr:      stats.GetCounter("recv.error"),
+		mRebalanced:  stats.GetCounter("rebalanced"),
+		mPartClaimed: stats.GetCounter("rebalanced.partitions.claimed"),
+		mPartRelease: stats.GetCounter("rebalanced.partitions.released"),
+		offsets:      map[string]map[int32]int64{},
+		log:          log,
 	}
 	if conf.TLS.Enabled {
 		var err error
@@ -130,10 +164,33 @@ func NewKafkaBalanced(
 			return nil, fmt.Errorf("failed to parse commit period string: %v", err)
 		}
 	}
+	if tout := conf.Group.SessionTimeout; len(tout) > 0 {
+		var err error
+		if k.sessionTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse session timeout string: %v", err)
+		}
+	}
+	if tout := conf.Group.HeartbeatInterval; len(tout) > 0 {
+		var err error
+		if k.heartbeatInterval, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse heartbeat interval string: %v", err)
+		}
+	}
+	switch s := conf.Group.PartitionStrategy; s {
+	case sarama.BalanceStrategyRange.Name(), "":
+		k.partitionStrategy = sarama.BalanceStrategyRange
+	case sarama.BalanceStrategyRoundRobin.Name():
+		k.partitionStrategy = sarama.BalanceStrategyRoundRobin
+	default:
+		return nil, fmt.Errorf("partition strategy not recognised: %v", s)
+	}
 	var err error
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if !k.version.IsAtLeast(sarama.V0_10_2_0) {
+		return nil, fmt.Errorf("consumer groups require a target version of at least %v", sarama.V0_10_2_0)
+	}
 	return &k, nil
 }
 
@@ -143,19 +200,98 @@ func NewKafkaBalanced(
 func (k *KafkaBalanced) closeClients() {
 	k.cMut.Lock()
 	defer k.cMut.Unlock()
-	if k.consumer != nil {
-		k.consumer.CommitOffsets()
-		k.consumer.Close()
+	if k.group != nil {
+		// Closing the group ends the current session, which commits any marked
+		// offsets during cleanup.
+		k.groupCancel()
+		k.group.Close()
+		k.group = nil
+	}
+}
 
-		// Drain all channels
-		for range k.consumer.Messages() {
-		}
-		for range k.consumer.Notifications() {
+//------------------------------------------------------------------------------
+
+// kafkaBalancedHandler implements sarama.ConsumerGroupHandler for a
+// KafkaBalanced input, feeding the messages of each claim into the input.
+type kafkaBalancedHandler struct {
+	k *KafkaBalanced
+}
+
+// Setup drops any pending offsets of partitions that are no longer claimed by
+// this consumer, as marking them would overwrite the progress of whichever
+// member now owns them.
+func (h kafkaBalancedHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	k := h.k
+	k.mRebalanced.Incr(1)
+
+	claims := sess.Claims()
+	k.offsetsMut.Lock()
+	for topic, topicMap := range k.offsets {
+		for part := range topicMap {
+			claimed := false
+			for _, p := range claims[topic] {
+				if p == part {
+					claimed = true
+					break
+				}
+			}
+			if !claimed {
+				delete(topicMap, part)
+			}
 		}
-		for range k.consumer.Errors() {
+		if len(topicMap) == 0 {
+			delete(k.offsets, topic)
 		}
+	}
+	k.offsetsMut.Unlock()
+
+	for _, parts := range claims {
+		k.mPartClaimed.Incr(int64(len(parts)))
+	}
+
+	k.cMut.Lock()
+	k.session = sess
+	k.cMut.Unlock()
+
+	k.log.Infof("Consumer group rebalanced, now consuming: %v\n", claims)
+	return nil
+}
+
+// Cleanup commits the offsets marked during the session before its partitions
+// are released.
+func (h kafkaBalancedHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	k := h.k
+
+	k.cMut.Lock()
+	if k.session == sess {
+		k.session = nil
+	}
+	k.cMut.Unlock()
 
-		k.consumer = nil
+	for _, parts := range sess.Claims() {
+		k.mPartRelease.Incr(int64(len(parts)))
+	}
+	sess.Commit()
+	return nil
+}
+
+// ConsumeClaim forwards the messages of a claim to the input until the session
+// ends.
+func (h kafkaBalancedHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case data, open := <-claim.Messages():
+			if !open {
+				return nil
+			}
+			select {
+			case h.k.msgChan <- data:
+			case <-sess.Context().Done():
+				return nil
+			}
+		case <-sess.Context().Done():
+			return nil
+		}
 	}
 }
 
@@ -166,16 +302,23 @@ func (k *KafkaBalanced) Connect() error {
 	k.cMut.Lock()
 	defer k.cMut.Unlock()
 
-	if k.consumer != nil {
+	if k.group != nil {
 		return nil
 	}
 
-	config := cluster.NewConfig()
+	config := sarama.NewConfig()
 	config.ClientID = k.conf.ClientID
 	config.Net.DialTimeout = time.Second
 	config.Version = k.version
 	config.Consumer.Return.Errors = true
-	config.Group.Return.Notifications = true
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{k.partitionStrategy}
+	if k.sessionTimeout > 0 {
+		config.Consumer.Group.Session.Timeout = k.sessionTimeout
+	}
+	if k.heartbeatInterval > 0 {
+		config.Consumer.Group.Heartbeat.Interval = k.heartbeatInterval
+	}
 	config.Net.TLS.Enable = k.conf.TLS.Enabled
 	if k.conf.TLS.Enabled {
 		config.Net.TLS.Config = k.tlsConf
@@ -185,44 +328,54 @@ func (k *KafkaBalanced) Connect() error {
 		config.Consumer.Offsets.Initial = sarama.OffsetOldest
 	}
 
-	var consumer *cluster.Consumer
-	var err error
-
-	if consumer, err = cluster.NewConsumer(
-		k.addresses,
-		k.conf.ConsumerGroup,
-		k.topics,
-		config,
-	); err != nil {
+	group, err := sarama.NewConsumerGroup(k.addresses, k.conf.ConsumerGroup, config)
+	if err != nil {
 		return err
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		for err := range group.Errors() {
+			if err != nil {
+				k.log.Errorf("KafkaBalanced message recv error: %v\n", err)
+				k.mRcvErr.Incr(1)
+			}
+		}
+	}()
 
 	go func() {
+		handler := kafkaBalancedHandler{k: k}
 		for {
-			select {
-			case err, open := <-consumer.Errors():
-				if !open {
+			// Consume blocks for the lifetime of a session, and is called again
+			// in order to join the group after each rebalance.
+			if err := group.Consume(ctx, k.topics, handler); err != nil {
+				if err == sarama.ErrClosedConsumerGroup {
 					return
 				}
-				if err != nil {
-					k.log.Errorf("KafkaBalanced message recv error: %v\n", err)
-					k.mRcvErr.Incr(1)
-				}
-			case _, open := <-consumer.Notifications():
-				if !open {
-					return
+				k.log.Errorf("KafkaBalanced consumer group error: %v\n", err)
+				k.mRcvErr.Incr(1)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
 				}
-				k.mRebalanced.Incr(1)
+			}
+			if ctx.Err() != nil {
+				return
 			}
 		}
 	}()
 
-	k.consumer = consumer
+	k.group = group
+	k.groupCtx = ctx
+	k.groupCancel = cancel
 	k.log.Infof("Receiving KafkaBalanced messages from addresses: %s\n", k.addresses)
 	return nil
 }
 
 func (k *KafkaBalanced) setOffset(topic string, partition int32, offset int64) {
+	k.offsetsMut.Lock()
+	defer k.offsetsMut.Unlock()
+
 	var topicMap map[int32]int64
 	var exists bool
 	if topicMap, exists = k.offsets[topic]; !exists {
@@ -234,15 +387,21 @@ func (k *KafkaBalanced) setOffset(topic string, partition int32, offset int64) {
 
 // Read attempts to read a message from a KafkaBalanced topic.
 func (k *KafkaBalanced) Read() (types.Message, error) {
-	var consumer *cluster.Consumer
+	return k.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a message from a KafkaBalanced topic,
+// returning types.ErrTimeout if no message arrives before the deadline.
+func (k *KafkaBalanced) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	var groupCtx context.Context
 
 	k.cMut.Lock()
-	if k.consumer != nil {
-		consumer = k.consumer
+	if k.group != nil {
+		groupCtx = k.groupCtx
 	}
 	k.cMut.Unlock()
 
-	if consumer == nil {
+	if groupCtx == nil {
 		return nil, types.ErrNotConnected
 	}
 
@@ -265,66 +424,66 @@ func (k *KafkaBalanced) Read() (types.Message, error) {
 		k.setOffset(data.Topic, data.Partition, data.Offset)
 	}
 
-	data, open := <-consumer.Messages()
-	if !open {
-		k.closeClients()
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	select {
+	case data := <-k.msgChan:
+		addPart(data)
+	case <-groupCtx.Done():
 		return nil, types.ErrTypeClosed
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
 	}
-	addPart(data)
 
 batchLoop:
 	for i := 1; i < k.conf.MaxBatchCount; i++ {
 		select {
-		case data, open = <-consumer.Messages():
-			if !open {
-				k.closeClients()
-				return nil, types.ErrTypeClosed
-			}
+		case data := <-k.msgChan:
 			addPart(data)
 		default:
 			// Drained the buffer
 			break batchLoop
 		}
 	}
-
-	if msg.Len() == 0 {
-		return nil, types.ErrTimeout
-	}
 	return msg, nil
 }
 
 // Acknowledge instructs whether the current offset should be committed.
 func (k *KafkaBalanced) Acknowledge(err error) error {
+	k.cMut.Lock()
+	session := k.session
+	connected := k.group != nil
+	k.cMut.Unlock()
+
+	if !connected {
+		return types.ErrNotConnected
+	}
+	if session == nil {
+		// Pending offsets are marked once the next session begins, unless their
+		// partitions are no longer claimed by then.
+		return nil
+	}
+
 	if err == nil {
-		k.cMut.Lock()
-		if k.consumer != nil {
-			for topic, v := range k.offsets {
-				for part, offset := range v {
-					k.consumer.MarkPartitionOffset(topic, part, offset, "")
-				}
+		k.offsetsMut.Lock()
+		for topic, v := range k.offsets {
+			for part, offset := range v {
+				// The offset to commit is that of the next message to consume.
+				session.MarkOffset(topic, part, offset+1, "")
 			}
 		}
-		k.cMut.Unlock()
+		k.offsets = map[string]map[int32]int64{}
+		k.offsetsMut.Unlock()
 	}
 
 	if time.Since(k.offsetLastCommitted) < k.commitPeriod {
 		return nil
 	}
 
-	var commitErr error
-	k.cMut.Lock()
-	if k.consumer != nil {
-		commitErr = k.consumer.CommitOffsets()
-	} else {
-		commitErr = types.ErrNotConnected
-	}
-	k.cMut.Unlock()
-
-	if commitErr == nil {
-		k.offsetLastCommitted = time.Now()
-	}
-
-	return commitErr
+	session.Commit()
+	k.offsetLastCommitted = time.Now()
+	return nil
 }
 
 // CloseAsync shuts down the KafkaBalanced input and stops processing requests.
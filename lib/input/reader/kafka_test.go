@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaStartPosition(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.StartTimestamp = "2019-01-02T15:04:05Z"
+	k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := int64(1546441445000), k.startTime; exp != act {
+		t.Errorf("Wrong start time: %v != %v", act, exp)
+	}
+	if k.startResolved {
+		t.Error("Expected start position to be unresolved")
+	}
+
+	conf = NewKafkaConfig()
+	conf.StartOffset = 10
+	if k, err = NewKafka(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if k.startResolved {
+		t.Error("Expected start position to be unresolved")
+	}
+
+	conf = NewKafkaConfig()
+	if k, err = NewKafka(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if !k.startResolved {
+		t.Error("Expected start position to be resolved")
+	}
+}
+
+func TestKafkaBadStartPosition(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.StartOffset = 10
+	conf.StartTimestamp = "2019-01-02T15:04:05Z"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from both start positions")
+	}
+
+	conf = NewKafkaConfig()
+	conf.StartTimestamp = "not a timestamp"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad timestamp")
+	}
+
+	conf = NewKafkaConfig()
+	conf.StartOffset = -2
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad offset")
+	}
+}
+
+//------------------------------------------------------------------------------
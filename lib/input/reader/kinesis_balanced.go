@@ -0,0 +1,876 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+)
+
+//------------------------------------------------------------------------------
+
+// KinesisBalancedConfig is configuration values for the input type.
+type KinesisBalancedConfig struct {
+	sess.Config     `json:",inline" yaml:",inline"`
+	Stream          string `json:"stream" yaml:"stream"`
+	DynamoDBTable   string `json:"dynamodb_table" yaml:"dynamodb_table"`
+	ClientID        string `json:"client_id" yaml:"client_id"`
+	Limit           int64  `json:"limit" yaml:"limit"`
+	StartFromOldest bool   `json:"start_from_oldest" yaml:"start_from_oldest"`
+	CommitPeriod    string `json:"commit_period" yaml:"commit_period"`
+	LeasePeriod     string `json:"lease_period" yaml:"lease_period"`
+	RebalancePeriod string `json:"rebalance_period" yaml:"rebalance_period"`
+	Timeout         string `json:"timeout" yaml:"timeout"`
+}
+
+// NewKinesisBalancedConfig creates a new Config with default values.
+func NewKinesisBalancedConfig() KinesisBalancedConfig {
+	return KinesisBalancedConfig{
+		Config:          sess.NewConfig(),
+		Stream:          "",
+		DynamoDBTable:   "",
+		ClientID:        "benthos_consumer",
+		Limit:           100,
+		StartFromOldest: true,
+		CommitPeriod:    "1s",
+		LeasePeriod:     "30s",
+		RebalancePeriod: "10s",
+		Timeout:         "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var errKinesisLeaseLost = errors.New("shard lease is owned by another client")
+
+// kinesisLease is the state of a shard as stored within DynamoDB.
+type kinesisLease struct {
+	Owner    string
+	Expires  time.Time
+	Sequence string
+	Finished bool
+}
+
+// kinesisLeaseStore persists the leases and checkpoints of shards. All
+// mutations are conditional on the ownership of the lease.
+type kinesisLeaseStore interface {
+	// Leases returns the current state of all shards with a lease.
+	Leases() (map[string]kinesisLease, error)
+
+	// Claim takes ownership of a shard lease if it is free or expired, or
+	// when prevOwner is set, if it is still owned by prevOwner.
+	Claim(shardID, prevOwner string, expires time.Time) (kinesisLease, error)
+
+	// Update renews an owned lease and writes its checkpoint.
+	Update(shardID string, expires time.Time, sequence string, finished bool) error
+
+	// Release gives up ownership of a lease.
+	Release(shardID string) error
+}
+
+//------------------------------------------------------------------------------
+
+// dynamoLeaseStore is a kinesisLeaseStore backed by a DynamoDB table with the
+// hash key namespace and the range key shard_id.
+type dynamoLeaseStore struct {
+	table     string
+	namespace string
+	owner     string
+	timeout   time.Duration
+	client    dynamodbiface.DynamoDBAPI
+}
+
+func (d *dynamoLeaseStore) key(shardID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"namespace": {S: aws.String(d.namespace)},
+		"shard_id":  {S: aws.String(shardID)},
+	}
+}
+
+func unixMillis(t time.Time) *string {
+	return aws.String(strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+}
+
+func parseKinesisLease(item map[string]*dynamodb.AttributeValue) kinesisLease {
+	var l kinesisLease
+	if v := item["lease_owner"]; v != nil && v.S != nil {
+		l.Owner = *v.S
+	}
+	if v := item["lease_timeout"]; v != nil && v.N != nil {
+		if ms, err := strconv.ParseInt(*v.N, 10, 64); err == nil {
+			l.Expires = time.Unix(0, ms*int64(time.Millisecond))
+		}
+	}
+	if v := item["sequence"]; v != nil && v.S != nil {
+		l.Sequence = *v.S
+	}
+	if v := item["finished"]; v != nil && v.BOOL != nil {
+		l.Finished = *v.BOOL
+	}
+	return l
+}
+
+func isConditionFailed(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+func (d *dynamoLeaseStore) Leases() (map[string]kinesisLease, error) {
+	leases := map[string]kinesisLease{}
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		ConsistentRead:         aws.Bool(true),
+		KeyConditionExpression: aws.String("namespace = :ns"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":ns": {S: aws.String(d.namespace)},
+		},
+	}
+	for {
+		res, err := d.client.QueryWithContext(
+			aws.BackgroundContext(), input,
+			request.WithResponseReadTimeout(d.timeout),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range res.Items {
+			if v := item["shard_id"]; v != nil && v.S != nil {
+				leases[*v.S] = parseKinesisLease(item)
+			}
+		}
+		if len(res.LastEvaluatedKey) == 0 {
+			return leases, nil
+		}
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
+}
+
+func (d *dynamoLeaseStore) Claim(shardID, prevOwner string, expires time.Time) (kinesisLease, error) {
+	values := map[string]*dynamodb.AttributeValue{
+		":owner":   {S: aws.String(d.owner)},
+		":expires": {N: unixMillis(expires)},
+	}
+	condition := "attribute_not_exists(lease_owner) OR lease_timeout < :now"
+	if len(prevOwner) > 0 {
+		condition = "lease_owner = :prev"
+		values[":prev"] = &dynamodb.AttributeValue{S: aws.String(prevOwner)}
+	} else {
+		values[":now"] = &dynamodb.AttributeValue{N: unixMillis(time.Now())}
+	}
+	res, err := d.client.UpdateItemWithContext(
+		aws.BackgroundContext(),
+		&dynamodb.UpdateItemInput{
+			TableName:                 aws.String(d.table),
+			Key:                       d.key(shardID),
+			UpdateExpression:          aws.String("SET lease_owner = :owner, lease_timeout = :expires"),
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeValues: values,
+			ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+		},
+		request.WithResponseReadTimeout(d.timeout),
+	)
+	if err != nil {
+		if isConditionFailed(err) {
+			return kinesisLease{}, errKinesisLeaseLost
+		}
+		return kinesisLease{}, err
+	}
+	return parseKinesisLease(res.Attributes), nil
+}
+
+func (d *dynamoLeaseStore) Update(shardID string, expires time.Time, sequence string, finished bool) error {
+	expr := "SET lease_timeout = :expires, finished = :finished"
+	values := map[string]*dynamodb.AttributeValue{
+		":owner":    {S: aws.String(d.owner)},
+		":expires":  {N: unixMillis(expires)},
+		":finished": {BOOL: aws.Bool(finished)},
+	}
+	if len(sequence) > 0 {
+		expr += ", #seq = :seq"
+		values[":seq"] = &dynamodb.AttributeValue{S: aws.String(sequence)}
+	}
+	input := &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(d.table),
+		Key:                       d.key(shardID),
+		UpdateExpression:          aws.String(expr),
+		ConditionExpression:       aws.String("lease_owner = :owner"),
+		ExpressionAttributeValues: values,
+	}
+	if len(sequence) > 0 {
+		input.ExpressionAttributeNames = map[string]*string{
+			"#seq": aws.String("sequence"),
+		}
+	}
+	_, err := d.client.UpdateItemWithContext(
+		aws.BackgroundContext(), input,
+		request.WithResponseReadTimeout(d.timeout),
+	)
+	if isConditionFailed(err) {
+		return errKinesisLeaseLost
+	}
+	return err
+}
+
+func (d *dynamoLeaseStore) Release(shardID string) error {
+	_, err := d.client.UpdateItemWithContext(
+		aws.BackgroundContext(),
+		&dynamodb.UpdateItemInput{
+			TableName:           aws.String(d.table),
+			Key:                 d.key(shardID),
+			UpdateExpression:    aws.String("REMOVE lease_owner, lease_timeout"),
+			ConditionExpression: aws.String("lease_owner = :owner"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":owner": {S: aws.String(d.owner)},
+			},
+		},
+		request.WithResponseReadTimeout(d.timeout),
+	)
+	if isConditionFailed(err) {
+		return errKinesisLeaseLost
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// kinesisShardState tracks a shard owned by this client.
+type kinesisShardState struct {
+	id        string
+	sequence  string
+	dirty     bool
+	finished  bool
+	closeChan chan struct{}
+}
+
+// kinesisRecords is a batch of records read from a shard, a nil message marks
+// the end of a closed shard.
+type kinesisRecords struct {
+	shardID  string
+	msg      types.Message
+	sequence string
+}
+
+// KinesisBalanced is a benthos reader.Type implementation that reads messages
+// from all shards of an Amazon Kinesis stream, balancing shards across clients
+// by coordinating leases within a DynamoDB table.
+type KinesisBalanced struct {
+	conf  KinesisBalancedConfig
+	owner string
+
+	commitPeriod    time.Duration
+	leasePeriod     time.Duration
+	rebalancePeriod time.Duration
+	timeout         time.Duration
+
+	kinesis kinesisiface.KinesisAPI
+	store   kinesisLeaseStore
+
+	cMut          sync.Mutex
+	connected     bool
+	shards        map[string]*kinesisShardState
+	lastCommitted time.Time
+
+	recordsChan  chan kinesisRecords
+	pendingShard string
+	pendingSeq   string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRebalance    metrics.StatCounter
+	mRebalanceErr metrics.StatCounter
+	mLeaseClaimed metrics.StatCounter
+	mLeaseStolen  metrics.StatCounter
+	mLeaseLost    metrics.StatCounter
+	mCommitErr    metrics.StatCounter
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewKinesisBalanced creates a new Amazon Kinesis stream reader.Type that
+// balances shards across clients.
+func NewKinesisBalanced(
+	conf KinesisBalancedConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*KinesisBalanced, error) {
+	if len(conf.Stream) == 0 {
+		return nil, errors.New("a stream must be specified")
+	}
+	if len(conf.DynamoDBTable) == 0 {
+		return nil, errors.New("a dynamodb table must be specified")
+	}
+
+	k := &KinesisBalanced{
+		conf:          conf,
+		shards:        map[string]*kinesisShardState{},
+		recordsChan:   make(chan kinesisRecords),
+		log:           log,
+		stats:         stats,
+		mRebalance:    stats.GetCounter("rebalance.success"),
+		mRebalanceErr: stats.GetCounter("rebalance.error"),
+		mLeaseClaimed: stats.GetCounter("lease.claimed"),
+		mLeaseStolen:  stats.GetCounter("lease.stolen"),
+		mLeaseLost:    stats.GetCounter("lease.lost"),
+		mCommitErr:    stats.GetCounter("commit.error"),
+		closeChan:     make(chan struct{}),
+		closedChan:    make(chan struct{}),
+	}
+
+	for _, d := range []struct {
+		name string
+		str  string
+		dur  *time.Duration
+	}{
+		{"commit period", conf.CommitPeriod, &k.commitPeriod},
+		{"lease period", conf.LeasePeriod, &k.leasePeriod},
+		{"rebalance period", conf.RebalancePeriod, &k.rebalancePeriod},
+		{"timeout", conf.Timeout, &k.timeout},
+	} {
+		if len(d.str) == 0 {
+			continue
+		}
+		var err error
+		if *d.dur, err = time.ParseDuration(d.str); err != nil {
+			return nil, fmt.Errorf("failed to parse %v string: %v", d.name, err)
+		}
+	}
+	if k.rebalancePeriod <= 0 {
+		return nil, errors.New("rebalance period must be greater than zero")
+	}
+	if k.leasePeriod <= k.rebalancePeriod {
+		return nil, errors.New("lease period must be greater than the rebalance period")
+	}
+
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	k.owner = fmt.Sprintf("%v-%v-%v", conf.ClientID, hostname, hex.EncodeToString(idBytes))
+	return k, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the target Kinesis stream and
+// claims an initial set of shard leases.
+func (k *KinesisBalanced) Connect() error {
+	k.cMut.Lock()
+	if k.connected {
+		k.cMut.Unlock()
+		return nil
+	}
+	if k.kinesis == nil || k.store == nil {
+		sess, err := k.conf.GetSession()
+		if err != nil {
+			k.cMut.Unlock()
+			return err
+		}
+		k.kinesis = kinesis.New(sess)
+		k.store = &dynamoLeaseStore{
+			table:     k.conf.DynamoDBTable,
+			namespace: fmt.Sprintf("%v-%v", k.conf.ClientID, k.conf.Stream),
+			owner:     k.owner,
+			timeout:   k.timeout,
+			client:    dynamodb.New(sess),
+		}
+	}
+	k.cMut.Unlock()
+
+	if err := k.rebalance(); err != nil {
+		return err
+	}
+
+	k.cMut.Lock()
+	select {
+	case <-k.closeChan:
+		// Closed whilst connecting, the close signal has already been sent.
+		k.cMut.Unlock()
+		k.shutdown()
+		return types.ErrTypeClosed
+	default:
+	}
+	k.connected = true
+	k.cMut.Unlock()
+
+	go k.rebalanceLoop()
+
+	k.log.Infof("Receiving Amazon Kinesis messages from stream %v as client: %v\n", k.conf.Stream, k.owner)
+	return nil
+}
+
+func (k *KinesisBalanced) rebalanceLoop() {
+	defer close(k.closedChan)
+
+	ticker := time.NewTicker(k.rebalancePeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := k.rebalance(); err != nil {
+				k.log.Errorf("Failed to rebalance shards: %v\n", err)
+			}
+		case <-k.closeChan:
+			k.shutdown()
+			return
+		}
+	}
+}
+
+func (k *KinesisBalanced) listShards() ([]*kinesis.Shard, error) {
+	var shards []*kinesis.Shard
+	input := &kinesis.ListShardsInput{
+		StreamName: aws.String(k.conf.Stream),
+	}
+	for {
+		res, err := k.kinesis.ListShardsWithContext(
+			aws.BackgroundContext(), input,
+			request.WithResponseReadTimeout(k.timeout),
+		)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, res.Shards...)
+		if res.NextToken == nil {
+			return shards, nil
+		}
+		input = &kinesis.ListShardsInput{
+			NextToken: res.NextToken,
+		}
+	}
+}
+
+// rebalance discovers the shards of the stream, renews the leases owned by
+// this client and claims further leases until this client owns its share.
+func (k *KinesisBalanced) rebalance() error {
+	shards, err := k.listShards()
+	if err != nil {
+		k.mRebalanceErr.Incr(1)
+		return fmt.Errorf("failed to list shards: %v", err)
+	}
+
+	k.cMut.Lock()
+	defer k.cMut.Unlock()
+
+	k.commit(true)
+
+	leases, err := k.store.Leases()
+	if err != nil {
+		k.mRebalanceErr.Incr(1)
+		return fmt.Errorf("failed to read leases: %v", err)
+	}
+
+	now := time.Now()
+	inStream := map[string]*kinesis.Shard{}
+	for _, s := range shards {
+		if s.ShardId != nil {
+			inStream[*s.ShardId] = s
+		}
+	}
+
+	// Shards that are no longer listed have expired from the stream.
+	for id := range k.shards {
+		if _, exists := inStream[id]; !exists {
+			k.stopShard(id)
+		}
+	}
+
+	// Child shards of a reshard only become eligible once their parents have
+	// been consumed, or have expired from the stream.
+	parentDone := func(id *string) bool {
+		if id == nil {
+			return true
+		}
+		if _, exists := inStream[*id]; !exists {
+			return true
+		}
+		return leases[*id].Finished
+	}
+
+	var eligible []*kinesis.Shard
+	owners := map[string][]string{k.owner: nil}
+	for _, s := range shards {
+		id := *s.ShardId
+		lease := leases[id]
+		if lease.Finished || !parentDone(s.ParentShardId) || !parentDone(s.AdjacentParentShardId) {
+			continue
+		}
+		eligible = append(eligible, s)
+		if len(lease.Owner) > 0 && lease.Expires.After(now) {
+			owners[lease.Owner] = append(owners[lease.Owner], id)
+		}
+	}
+	if len(eligible) == 0 {
+		k.mRebalance.Incr(1)
+		return nil
+	}
+
+	target := (len(eligible) + len(owners) - 1) / len(owners)
+
+	for _, s := range eligible {
+		if len(k.shards) >= target {
+			break
+		}
+		id := *s.ShardId
+		if _, owned := k.shards[id]; owned {
+			continue
+		}
+		if lease := leases[id]; len(lease.Owner) > 0 && lease.Expires.After(now) {
+			continue
+		}
+		k.claimShard(s, "")
+	}
+
+	// When there are no free shards left take one from the client with the
+	// most leases, provided it owns more than its share.
+	if len(k.shards) < target {
+		var victim string
+		for owner, ids := range owners {
+			if owner != k.owner && len(ids) > target && (len(victim) == 0 || len(ids) > len(owners[victim])) {
+				victim = owner
+			}
+		}
+		if len(victim) > 0 {
+			for _, s := range eligible {
+				if *s.ShardId == owners[victim][0] {
+					if k.claimShard(s, victim) {
+						k.mLeaseStolen.Incr(1)
+					}
+					break
+				}
+			}
+		}
+	}
+
+	k.mRebalance.Incr(1)
+	return nil
+}
+
+// claimShard attempts to claim the lease of a shard and begins consuming it.
+// Must be called with cMut locked.
+func (k *KinesisBalanced) claimShard(s *kinesis.Shard, prevOwner string) bool {
+	id := *s.ShardId
+	lease, err := k.store.Claim(id, prevOwner, time.Now().Add(k.leasePeriod))
+	if err != nil {
+		if err != errKinesisLeaseLost {
+			k.log.Errorf("Failed to claim lease of shard %v: %v\n", id, err)
+		}
+		return false
+	}
+	k.mLeaseClaimed.Incr(1)
+
+	state := &kinesisShardState{
+		id:        id,
+		sequence:  lease.Sequence,
+		closeChan: make(chan struct{}),
+	}
+	k.shards[id] = state
+
+	// Children of a reshard must be read from the beginning, otherwise
+	// records written before their first read would be skipped.
+	fromOldest := k.conf.StartFromOldest || s.ParentShardId != nil || s.AdjacentParentShardId != nil
+	go k.consumeShard(state, lease.Sequence, fromOldest)
+
+	k.log.Infof("Claimed lease of shard: %v\n", id)
+	return true
+}
+
+// stopShard stops consuming a shard. Must be called with cMut locked.
+func (k *KinesisBalanced) stopShard(id string) {
+	if state, exists := k.shards[id]; exists {
+		close(state.closeChan)
+		delete(k.shards, id)
+	}
+}
+
+// commit writes the checkpoints of owned shards and renews their leases. When
+// all is false only shards with new checkpoints are written. Must be called
+// with cMut locked.
+func (k *KinesisBalanced) commit(all bool) {
+	expires := time.Now().Add(k.leasePeriod)
+	for id, state := range k.shards {
+		if !all && !state.dirty {
+			continue
+		}
+		if err := k.store.Update(id, expires, state.sequence, state.finished); err != nil {
+			if err == errKinesisLeaseLost {
+				k.mLeaseLost.Incr(1)
+				k.log.Warnf("Lost lease of shard: %v\n", id)
+				k.stopShard(id)
+			} else {
+				k.mCommitErr.Incr(1)
+				k.log.Errorf("Failed to commit checkpoint of shard %v: %v\n", id, err)
+			}
+			continue
+		}
+		state.dirty = false
+		if state.finished {
+			k.log.Infof("Finished consuming closed shard: %v\n", id)
+			k.stopShard(id)
+		}
+	}
+	k.lastCommitted = time.Now()
+}
+
+//------------------------------------------------------------------------------
+
+func (k *KinesisBalanced) getIterator(shardID, sequence string, fromOldest bool) (string, error) {
+	input := &kinesis.GetShardIteratorInput{
+		ShardId:    aws.String(shardID),
+		StreamName: aws.String(k.conf.Stream),
+	}
+	if len(sequence) > 0 {
+		input.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+		input.StartingSequenceNumber = aws.String(sequence)
+	} else if fromOldest {
+		input.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeTrimHorizon)
+	} else {
+		input.ShardIteratorType = aws.String(kinesis.ShardIteratorTypeLatest)
+	}
+	res, err := k.kinesis.GetShardIteratorWithContext(
+		aws.BackgroundContext(), input,
+		request.WithResponseReadTimeout(k.timeout),
+	)
+	if err != nil {
+		return "", err
+	}
+	if res.ShardIterator == nil {
+		return "", errors.New("failed to obtain shard iterator")
+	}
+	return *res.ShardIterator, nil
+}
+
+// consumeShard reads records from a shard until it is closed or the lease is
+// lost.
+func (k *KinesisBalanced) consumeShard(state *kinesisShardState, sequence string, fromOldest bool) {
+	wait := func(d time.Duration) bool {
+		select {
+		case <-time.After(d):
+			return true
+		case <-state.closeChan:
+		case <-k.closeChan:
+		}
+		return false
+	}
+
+	var iter string
+	for {
+		if len(iter) == 0 {
+			var err error
+			if iter, err = k.getIterator(state.id, sequence, fromOldest); err != nil {
+				k.log.Errorf("Failed to obtain iterator of shard %v: %v\n", state.id, err)
+				if !wait(time.Second) {
+					return
+				}
+				continue
+			}
+		}
+
+		res, err := k.kinesis.GetRecordsWithContext(
+			aws.BackgroundContext(),
+			&kinesis.GetRecordsInput{
+				Limit:         aws.Int64(k.conf.Limit),
+				ShardIterator: aws.String(iter),
+			},
+			request.WithResponseReadTimeout(k.timeout),
+		)
+		if err != nil {
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == kinesis.ErrCodeExpiredIteratorException {
+				k.log.Warnf("Iterator of shard %v expired, attempting to refresh\n", state.id)
+				iter = ""
+				continue
+			}
+			k.log.Errorf("Failed to read records of shard %v: %v\n", state.id, err)
+			if !wait(time.Second) {
+				return
+			}
+			continue
+		}
+
+		msg := message.New(nil)
+		var lastSeq string
+		for _, rec := range res.Records {
+			if rec.Data == nil {
+				continue
+			}
+			part := message.NewPart(rec.Data)
+			meta := part.Metadata()
+			meta.Set("kinesis_shard", state.id)
+			meta.Set("kinesis_stream", k.conf.Stream)
+			if rec.PartitionKey != nil {
+				meta.Set("kinesis_partition_key", *rec.PartitionKey)
+			}
+			if rec.SequenceNumber != nil {
+				meta.Set("kinesis_sequence_number", *rec.SequenceNumber)
+				lastSeq = *rec.SequenceNumber
+			}
+			msg.Append(part)
+		}
+
+		if msg.Len() > 0 {
+			select {
+			case k.recordsChan <- kinesisRecords{shardID: state.id, msg: msg, sequence: lastSeq}:
+			case <-state.closeChan:
+				return
+			case <-k.closeChan:
+				return
+			}
+			sequence = lastSeq
+		}
+
+		if res.NextShardIterator == nil {
+			select {
+			case k.recordsChan <- kinesisRecords{shardID: state.id}:
+			case <-state.closeChan:
+			case <-k.closeChan:
+			}
+			return
+		}
+		iter = *res.NextShardIterator
+
+		// Stay below the read limits of a shard, backing off further when
+		// the shard is idle.
+		backoff := time.Millisecond * 200
+		if len(res.Records) == 0 {
+			backoff = time.Second
+		}
+		if !wait(backoff) {
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Read attempts to read a new message from any of the owned shards.
+func (k *KinesisBalanced) Read() (types.Message, error) {
+	k.cMut.Lock()
+	connected := k.connected
+	k.cMut.Unlock()
+	if !connected {
+		return nil, types.ErrNotConnected
+	}
+
+	select {
+	case recs := <-k.recordsChan:
+		if recs.msg == nil {
+			// All records of the closed shard have been read and, as reads
+			// are sequential, acknowledged.
+			k.cMut.Lock()
+			if state, exists := k.shards[recs.shardID]; exists {
+				state.finished = true
+				state.dirty = true
+			}
+			k.cMut.Unlock()
+			return nil, types.ErrTimeout
+		}
+		k.pendingShard, k.pendingSeq = recs.shardID, recs.sequence
+		return recs.msg, nil
+	case <-time.After(k.timeout):
+	case <-k.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+	return nil, types.ErrTimeout
+}
+
+// Acknowledge confirms whether or not our unacknowledged messages have been
+// successfully propagated or not.
+func (k *KinesisBalanced) Acknowledge(err error) error {
+	k.cMut.Lock()
+	defer k.cMut.Unlock()
+
+	if err == nil && len(k.pendingShard) > 0 {
+		if state, exists := k.shards[k.pendingShard]; exists {
+			state.sequence = k.pendingSeq
+			state.dirty = true
+		}
+		k.pendingShard, k.pendingSeq = "", ""
+	}
+
+	if time.Since(k.lastCommitted) >= k.commitPeriod {
+		k.commit(false)
+	}
+	return nil
+}
+
+// shutdown commits the checkpoints of all owned shards and releases their
+// leases so that other clients can claim them immediately.
+func (k *KinesisBalanced) shutdown() {
+	k.cMut.Lock()
+	defer k.cMut.Unlock()
+
+	k.commit(false)
+	for id := range k.shards {
+		if err := k.store.Release(id); err != nil && err != errKinesisLeaseLost {
+			k.log.Errorf("Failed to release lease of shard %v: %v\n", id, err)
+		}
+		k.stopShard(id)
+	}
+	k.connected = false
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (k *KinesisBalanced) CloseAsync() {
+	k.closeOnce.Do(func() {
+		close(k.closeChan)
+		k.cMut.Lock()
+		connected := k.connected
+		k.cMut.Unlock()
+		if !connected {
+			close(k.closedChan)
+		}
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (k *KinesisBalanced) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-k.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,354 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+)
+
+//------------------------------------------------------------------------------
+
+type mockKinesisShard struct {
+	parent  string
+	records []string
+	closed  bool
+}
+
+type mockKinesis struct {
+	kinesisiface.KinesisAPI
+
+	mut    sync.Mutex
+	order  []string
+	shards map[string]*mockKinesisShard
+}
+
+func (m *mockKinesis) ListShardsWithContext(ctx aws.Context, in *kinesis.ListShardsInput, opts ...request.Option) (*kinesis.ListShardsOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	out := &kinesis.ListShardsOutput{}
+	for _, id := range m.order {
+		s := &kinesis.Shard{ShardId: aws.String(id)}
+		if p := m.shards[id].parent; len(p) > 0 {
+			s.ParentShardId = aws.String(p)
+		}
+		out.Shards = append(out.Shards, s)
+	}
+	return out, nil
+}
+
+func (m *mockKinesis) GetShardIteratorWithContext(ctx aws.Context, in *kinesis.GetShardIteratorInput, opts ...request.Option) (*kinesis.GetShardIteratorOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	shard := m.shards[*in.ShardId]
+	pos := 0
+	switch *in.ShardIteratorType {
+	case kinesis.ShardIteratorTypeLatest:
+		pos = len(shard.records)
+	case kinesis.ShardIteratorTypeAfterSequenceNumber:
+		seq, _ := strconv.Atoi(strings.TrimPrefix(*in.StartingSequenceNumber, *in.ShardId+"-"))
+		pos = seq + 1
+	}
+	return &kinesis.GetShardIteratorOutput{
+		ShardIterator: aws.String(fmt.Sprintf("%v:%v", *in.ShardId, pos)),
+	}, nil
+}
+
+func (m *mockKinesis) GetRecordsWithContext(ctx aws.Context, in *kinesis.GetRecordsInput, opts ...request.Option) (*kinesis.GetRecordsOutput, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	parts := strings.Split(*in.ShardIterator, ":")
+	id := parts[0]
+	pos, _ := strconv.Atoi(parts[1])
+	shard := m.shards[id]
+
+	out := &kinesis.GetRecordsOutput{}
+	for ; pos < len(shard.records) && int64(len(out.Records)) < *in.Limit; pos++ {
+		out.Records = append(out.Records, &kinesis.Record{
+			Data:           []byte(shard.records[pos]),
+			PartitionKey:   aws.String("key"),
+			SequenceNumber: aws.String(fmt.Sprintf("%v-%v", id, pos)),
+		})
+	}
+	if !shard.closed || pos < len(shard.records) {
+		out.NextShardIterator = aws.String(fmt.Sprintf("%v:%v", id, pos))
+	}
+	return out, nil
+}
+
+//------------------------------------------------------------------------------
+
+type memLeaseStore struct {
+	mut    *sync.Mutex
+	leases map[string]kinesisLease
+	owner  string
+}
+
+func newMemLeaseStores(owners ...string) []*memLeaseStore {
+	mut := &sync.Mutex{}
+	leases := map[string]kinesisLease{}
+	var stores []*memLeaseStore
+	for _, o := range owners {
+		stores = append(stores, &memLeaseStore{mut: mut, leases: leases, owner: o})
+	}
+	return stores
+}
+
+func (m *memLeaseStore) Leases() (map[string]kinesisLease, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	leases := map[string]kinesisLease{}
+	for k, v := range m.leases {
+		leases[k] = v
+	}
+	return leases, nil
+}
+
+func (m *memLeaseStore) Claim(shardID, prevOwner string, expires time.Time) (kinesisLease, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	l := m.leases[shardID]
+	if len(prevOwner) > 0 {
+		if l.Owner != prevOwner {
+			return l, errKinesisLeaseLost
+		}
+	} else if len(l.Owner) > 0 && l.Expires.After(time.Now()) {
+		return l, errKinesisLeaseLost
+	}
+	l.Owner, l.Expires = m.owner, expires
+	m.leases[shardID] = l
+	return l, nil
+}
+
+func (m *memLeaseStore) Update(shardID string, expires time.Time, sequence string, finished bool) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	l := m.leases[shardID]
+	if l.Owner != m.owner {
+		return errKinesisLeaseLost
+	}
+	l.Expires, l.Finished = expires, finished
+	if len(sequence) > 0 {
+		l.Sequence = sequence
+	}
+	m.leases[shardID] = l
+	return nil
+}
+
+func (m *memLeaseStore) Release(shardID string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	l := m.leases[shardID]
+	if l.Owner != m.owner {
+		return errKinesisLeaseLost
+	}
+	l.Owner, l.Expires = "", time.Time{}
+	m.leases[shardID] = l
+	return nil
+}
+
+func (m *memLeaseStore) get(shardID string) kinesisLease {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.leases[shardID]
+}
+
+//------------------------------------------------------------------------------
+
+func newTestKinesisBalanced(t *testing.T, client *mockKinesis, store *memLeaseStore) *KinesisBalanced {
+	conf := NewKinesisBalancedConfig()
+	conf.Stream = "foo"
+	conf.DynamoDBTable = "bar"
+	conf.CommitPeriod = "0s"
+	conf.RebalancePeriod = "1h"
+	conf.LeasePeriod = "2h"
+	conf.Timeout = "100ms"
+
+	k, err := NewKinesisBalanced(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.owner = store.owner
+	k.kinesis = client
+	k.store = store
+	return k
+}
+
+func (k *KinesisBalanced) ownedShards() int {
+	k.cMut.Lock()
+	defer k.cMut.Unlock()
+	return len(k.shards)
+}
+
+func TestKinesisBalancedBadConfig(t *testing.T) {
+	conf := NewKinesisBalancedConfig()
+	conf.DynamoDBTable = "bar"
+	if _, err := NewKinesisBalanced(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing stream")
+	}
+
+	conf = NewKinesisBalancedConfig()
+	conf.Stream = "foo"
+	if _, err := NewKinesisBalanced(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing table")
+	}
+
+	conf = NewKinesisBalancedConfig()
+	conf.Stream, conf.DynamoDBTable = "foo", "bar"
+	conf.LeasePeriod = "5s"
+	if _, err := NewKinesisBalanced(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from lease period below rebalance period")
+	}
+}
+
+func TestKinesisBalancedBalancing(t *testing.T) {
+	client := &mockKinesis{
+		order:  []string{"a", "b", "c", "d"},
+		shards: map[string]*mockKinesisShard{},
+	}
+	for _, id := range client.order {
+		client.shards[id] = &mockKinesisShard{}
+	}
+	stores := newMemLeaseStores("first", "second")
+
+	first := newTestKinesisBalanced(t, client, stores[0])
+	if err := first.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer first.CloseAsync()
+	if exp, act := 4, first.ownedShards(); exp != act {
+		t.Errorf("Wrong count of shards: %v != %v", act, exp)
+	}
+
+	second := newTestKinesisBalanced(t, client, stores[1])
+	if err := second.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer second.CloseAsync()
+	if err := second.rebalance(); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.rebalance(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, first.ownedShards(); exp != act {
+		t.Errorf("Wrong count of shards: %v != %v", act, exp)
+	}
+	if exp, act := 2, second.ownedShards(); exp != act {
+		t.Errorf("Wrong count of shards: %v != %v", act, exp)
+	}
+
+	// Closing releases leases for the remaining client to claim.
+	second.CloseAsync()
+	if err := second.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.rebalance(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 4, first.ownedShards(); exp != act {
+		t.Errorf("Wrong count of shards: %v != %v", act, exp)
+	}
+}
+
+func TestKinesisBalancedReshard(t *testing.T) {
+	client := &mockKinesis{
+		order: []string{"parent", "child"},
+		shards: map[string]*mockKinesisShard{
+			"parent": {records: []string{"foo", "bar"}, closed: true},
+			"child":  {parent: "parent", records: []string{"baz"}},
+		},
+	}
+	store := newMemLeaseStores("first")[0]
+
+	k := newTestKinesisBalanced(t, client, store)
+	if err := k.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer k.CloseAsync()
+
+	if exp, act := 1, k.ownedShards(); exp != act {
+		t.Errorf("Wrong count of shards: %v != %v", act, exp)
+	}
+
+	msg, err := k.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %s != %s", act, exp)
+	}
+	if exp, act := "parent", msg.Get(0).Metadata().Get("kinesis_shard"); exp != act {
+		t.Errorf("Wrong shard metadata: %v != %v", act, exp)
+	}
+	if exp, act := "parent-1", msg.Get(1).Metadata().Get("kinesis_sequence_number"); exp != act {
+		t.Errorf("Wrong sequence metadata: %v != %v", act, exp)
+	}
+	if err = k.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+	if exp, act := "parent-1", store.get("parent").Sequence; exp != act {
+		t.Errorf("Wrong checkpoint: %v != %v", act, exp)
+	}
+
+	// The end of the closed parent shard.
+	if _, err = k.Read(); err != types.ErrTimeout {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTimeout)
+	}
+	if err = k.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+	if !store.get("parent").Finished {
+		t.Error("Expected parent shard to be finished")
+	}
+
+	if err = k.rebalance(); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err = k.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := [][]byte{[]byte("baz")}, message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %s != %s", act, exp)
+	}
+	if err = k.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+	if exp, act := "child-0", store.get("child").Sequence; exp != act {
+		t.Errorf("Wrong checkpoint: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
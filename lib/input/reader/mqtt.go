@@ -21,6 +21,8 @@
 package reader
 
 import (
+	"crypto/tls"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -37,19 +40,27 @@ import (
 
 // MQTTConfig contains configuration fields for the MQTT input type.
 type MQTTConfig struct {
-	URLs     []string `json:"urls" yaml:"urls"`
-	QoS      uint8    `json:"qos" yaml:"qos"`
-	Topics   []string `json:"topics" yaml:"topics"`
-	ClientID string   `json:"client_id" yaml:"client_id"`
+	URLs         []string    `json:"urls" yaml:"urls"`
+	QoS          uint8       `json:"qos" yaml:"qos"`
+	Topics       []string    `json:"topics" yaml:"topics"`
+	ClientID     string      `json:"client_id" yaml:"client_id"`
+	CleanSession bool        `json:"clean_session" yaml:"clean_session"`
+	User         string      `json:"user" yaml:"user"`
+	Password     string      `json:"password" yaml:"password"`
+	TLS          btls.Config `json:"tls" yaml:"tls"`
 }
 
 // NewMQTTConfig creates a new MQTTConfig with default values.
 func NewMQTTConfig() MQTTConfig {
 	return MQTTConfig{
-		URLs:     []string{"tcp://localhost:1883"},
-		QoS:      1,
-		Topics:   []string{"benthos_topic"},
-		ClientID: "benthos_input",
+		URLs:         []string{"tcp://localhost:1883"},
+		QoS:          1,
+		Topics:       []string{"benthos_topic"},
+		ClientID:     "benthos_input",
+		CleanSession: true,
+		User:         "",
+		Password:     "",
+		TLS:          btls.NewConfig(),
 	}
 }
 
@@ -63,9 +74,13 @@ type MQTT struct {
 	conf MQTTConfig
 
 	msgChan       chan mqtt.Message
+	unAckMsgs     []mqtt.Message
 	interruptChan chan struct{}
+	closeOnce     sync.Once
 
-	urls []string
+	tlsConf *tls.Config
+	urls    []string
+	topics  map[string]byte
 
 	stats metrics.Type
 	log   log.Modular
@@ -81,6 +96,31 @@ func NewMQTT(
 		interruptChan: make(chan struct{}),
 		stats:         stats,
 		log:           log,
+		topics:        map[string]byte{},
+	}
+
+	if conf.QoS > 2 {
+		return nil, fmt.Errorf("qos level not supported: %v", conf.QoS)
+	}
+	if !conf.CleanSession && len(conf.ClientID) == 0 {
+		return nil, fmt.Errorf("a client id must be specified when clean_session is false")
+	}
+	if conf.TLS.Enabled {
+		var err error
+		if m.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range conf.Topics {
+		for _, splitTopic := range strings.Split(t, ",") {
+			if len(splitTopic) > 0 {
+				m.topics[splitTopic] = byte(conf.QoS)
+			}
+		}
+	}
+	if len(m.topics) == 0 {
+		return nil, fmt.Errorf("at least one topic must be specified")
 	}
 
 	for _, u := range conf.URLs {
@@ -105,19 +145,38 @@ func (m *MQTT) Connect() error {
 		return nil
 	}
 
+	select {
+	case <-m.interruptChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
 	conf := mqtt.NewClientOptions().
 		SetAutoReconnect(true).
+		SetAutoAckDisabled(true).
 		SetClientID(m.conf.ClientID).
+		SetCleanSession(m.conf.CleanSession).
+		SetConnectionLostHandler(func(c mqtt.Client, err error) {
+			m.log.Errorf("Connection lost due to: %v\n", err)
+		}).
 		SetOnConnectHandler(func(c mqtt.Client) {
-			for _, topic := range m.conf.Topics {
-				tok := c.Subscribe(topic, byte(m.conf.QoS), m.msgHandler)
-				tok.Wait()
-				if err := tok.Error(); err != nil {
-					m.log.Errorf("Failed to subscribe to topic '%v': %v\n", topic, err)
-				}
+			tok := c.SubscribeMultiple(m.topics, m.msgHandler)
+			tok.Wait()
+			if err := tok.Error(); err != nil {
+				m.log.Errorf("Failed to subscribe to topics '%v': %v\n", m.conf.Topics, err)
 			}
 		})
 
+	if m.tlsConf != nil {
+		conf = conf.SetTLSConfig(m.tlsConf)
+	}
+	if len(m.conf.User) > 0 {
+		conf = conf.SetUsername(m.conf.User)
+	}
+	if len(m.conf.Password) > 0 {
+		conf = conf.SetPassword(m.conf.Password)
+	}
+
 	for _, u := range m.urls {
 		conf = conf.AddBroker(u)
 	}
@@ -131,6 +190,7 @@ func (m *MQTT) Connect() error {
 	}
 
 	m.client = client
+	m.log.Infof("Receiving MQTT messages from topics: %v\n", m.conf.Topics)
 	return nil
 }
 
@@ -143,6 +203,15 @@ func (m *MQTT) msgHandler(c mqtt.Client, msg mqtt.Message) {
 
 // Read attempts to read a new message from an MQTT broker.
 func (m *MQTT) Read() (types.Message, error) {
+	return m.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new message from an MQTT broker,
+// returning types.ErrTimeout if no message arrives before the deadline.
+func (m *MQTT) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
 	select {
 	case msg := <-m.msgChan:
 		message := message.New([][]byte{[]byte(msg.Payload())})
@@ -154,24 +223,38 @@ func (m *MQTT) Read() (types.Message, error) {
 		meta.Set("mqtt_topic", string(msg.Topic()))
 		meta.Set("mqtt_message_id", strconv.Itoa(int(msg.MessageID())))
 
+		m.unAckMsgs = append(m.unAckMsgs, msg)
 		return message, nil
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
 	case <-m.interruptChan:
 	}
 	return nil, types.ErrTypeClosed
 }
 
 // Acknowledge instructs whether messages have been successfully propagated.
+// Messages are only acknowledged with the broker once successfully propagated,
+// otherwise they remain in flight until redelivered by the broker.
 func (m *MQTT) Acknowledge(err error) error {
+	if err != nil {
+		return nil
+	}
+	for _, msg := range m.unAckMsgs {
+		msg.Ack()
+	}
+	m.unAckMsgs = nil
 	return nil
 }
 
 // CloseAsync shuts down the MQTT input and stops processing requests.
 func (m *MQTT) CloseAsync() {
+	m.closeOnce.Do(func() {
+		close(m.interruptChan)
+	})
 	m.cMut.Lock()
 	if m.client != nil {
 		m.client.Disconnect(0)
 		m.client = nil
-		close(m.interruptChan)
 	}
 	m.cMut.Unlock()
 }
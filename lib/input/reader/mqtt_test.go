@@ -21,6 +21,7 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"sync"
@@ -196,3 +197,58 @@ func testMQTTDisconnect(urls []string, t *testing.T) {
 
 	wg.Wait()
 }
+
+type mockMQTTMsg struct {
+	mqtt.Message
+
+	payload string
+	acked   int
+}
+
+func (m *mockMQTTMsg) Duplicate() bool   { return false }
+func (m *mockMQTTMsg) Qos() byte         { return 1 }
+func (m *mockMQTTMsg) Retained() bool    { return false }
+func (m *mockMQTTMsg) Topic() string     { return "foo" }
+func (m *mockMQTTMsg) MessageID() uint16 { return 1 }
+func (m *mockMQTTMsg) Payload() []byte   { return []byte(m.payload) }
+func (m *mockMQTTMsg) Ack()              { m.acked++ }
+
+func TestMQTTAcknowledge(t *testing.T) {
+	m, err := NewMQTT(NewMQTTConfig(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.CloseAsync()
+
+	mqttMsg := &mockMQTTMsg{payload: "hello world"}
+	go m.msgHandler(nil, mqttMsg)
+
+	msg, err := m.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+
+	if err = m.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 0, mqttMsg.acked; exp != act {
+		t.Errorf("Message acked after failed propagation: %v != %v", act, exp)
+	}
+
+	if err = m.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, mqttMsg.acked; exp != act {
+		t.Errorf("Wrong count of acks: %v != %v", act, exp)
+	}
+
+	if err = m.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, mqttMsg.acked; exp != act {
+		t.Errorf("Wrong count of acks: %v != %v", act, exp)
+	}
+}
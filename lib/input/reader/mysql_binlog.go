@@ -0,0 +1,593 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/mysql"
+)
+
+//------------------------------------------------------------------------------
+
+// MySQLBinlogConfig contains configuration fields for the MySQLBinlog input
+// type.
+type MySQLBinlogConfig struct {
+	Address        string   `json:"address" yaml:"address"`
+	User           string   `json:"user" yaml:"user"`
+	Password       string   `json:"password" yaml:"password"`
+	ServerID       uint32   `json:"server_id" yaml:"server_id"`
+	UseGTID        bool     `json:"use_gtid" yaml:"use_gtid"`
+	Tables         []string `json:"tables" yaml:"tables"`
+	CheckpointPath string   `json:"checkpoint_path" yaml:"checkpoint_path"`
+	Timeout        string   `json:"timeout" yaml:"timeout"`
+}
+
+// NewMySQLBinlogConfig creates a new MySQLBinlogConfig with default values.
+func NewMySQLBinlogConfig() MySQLBinlogConfig {
+	return MySQLBinlogConfig{
+		Address:        "localhost:3306",
+		User:           "root",
+		Password:       "",
+		ServerID:       1001,
+		UseGTID:        true,
+		Tables:         []string{},
+		CheckpointPath: "",
+		Timeout:        "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// mysqlBinlogStream is the subset of a replication connection used by the
+// MySQLBinlog reader.
+type mysqlBinlogStream interface {
+	ReadEvent() ([]byte, error)
+	Close() error
+}
+
+// mysqlQuerier is the subset of a connection used for reading the schema and
+// position of the server.
+type mysqlQuerier interface {
+	Query(sql string) ([]string, [][]*string, error)
+	Close() error
+}
+
+type mysqlColumn struct {
+	name     string
+	unsigned bool
+}
+
+// mysqlBinlogCheckpoint is the persisted position of the reader.
+type mysqlBinlogCheckpoint struct {
+	GTIDSet    string `json:"gtid_set"`
+	BinlogFile string `json:"binlog_file"`
+	BinlogPos  uint32 `json:"binlog_pos"`
+}
+
+// mysqlRowChange is a single row change waiting to be read.
+type mysqlRowChange struct {
+	op        mysql.RowsEventType
+	table     *mysql.TableMapEvent
+	before    []interface{}
+	after     []interface{}
+	timestamp uint32
+	logPos    uint32
+}
+
+// MySQLBinlog is an input type that streams row changes from the binary log of
+// a MySQL server by connecting as a replica.
+type MySQLBinlog struct {
+	conf    MySQLBinlogConfig
+	myConf  mysql.Config
+	tables  map[string]struct{}
+	flushed time.Time
+
+	dialStream func(pos mysqlBinlogCheckpoint, gtids mysql.GTIDSet) (mysqlBinlogStream, error)
+	dialQuery  func() (mysqlQuerier, error)
+
+	cMut    sync.Mutex
+	stream  mysqlBinlogStream
+	querier mysqlQuerier
+
+	parser  *mysql.Parser
+	columns map[string][]mysqlColumn
+
+	// The committed position, which only advances once every change prior to
+	// it has been acknowledged.
+	sMut     sync.Mutex
+	gtids    mysql.GTIDSet
+	file     string
+	pos      uint32
+	hasState bool
+
+	curGTID *mysql.GTID
+	pending []mysqlRowChange
+	curFile string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mFlushErr  metrics.StatCounter
+	mSchemaErr metrics.StatCounter
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewMySQLBinlog creates a new MySQLBinlog input type.
+func NewMySQLBinlog(conf MySQLBinlogConfig, log log.Modular, stats metrics.Type) (*MySQLBinlog, error) {
+	m := &MySQLBinlog{
+		conf: conf,
+		myConf: mysql.Config{
+			Address:  conf.Address,
+			User:     conf.User,
+			Password: conf.Password,
+		},
+		tables:     map[string]struct{}{},
+		columns:    map[string][]mysqlColumn{},
+		gtids:      mysql.GTIDSet{},
+		log:        log,
+		stats:      stats,
+		mFlushErr:  stats.GetCounter("checkpoint.error"),
+		mSchemaErr: stats.GetCounter("schema.error"),
+		closeChan:  make(chan struct{}),
+	}
+	if len(conf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+	if conf.ServerID == 0 {
+		return nil, errors.New("server id must be non-zero")
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if m.myConf.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	for _, t := range conf.Tables {
+		if !strings.Contains(t, ".") {
+			return nil, fmt.Errorf("table '%v' must be of the form schema.table", t)
+		}
+		m.tables[t] = struct{}{}
+	}
+	if err := m.loadCheckpoint(); err != nil {
+		return nil, err
+	}
+	m.dialStream = m.dialReplication
+	m.dialQuery = func() (mysqlQuerier, error) {
+		return mysql.Dial(m.myConf)
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (m *MySQLBinlog) loadCheckpoint() error {
+	if len(m.conf.CheckpointPath) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(m.conf.CheckpointPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	var cp mysqlBinlogCheckpoint
+	if err = json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	if m.gtids, err = mysql.ParseGTIDSet(cp.GTIDSet); err != nil {
+		return fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	m.file, m.pos = cp.BinlogFile, cp.BinlogPos
+	m.hasState = true
+	return nil
+}
+
+// flush writes the committed position to disk. Must be called with sMut
+// locked.
+func (m *MySQLBinlog) flush() error {
+	if len(m.conf.CheckpointPath) == 0 || !m.hasState {
+		return nil
+	}
+	data, err := json.Marshal(mysqlBinlogCheckpoint{
+		GTIDSet:    m.gtids.String(),
+		BinlogFile: m.file,
+		BinlogPos:  m.pos,
+	})
+	if err != nil {
+		return err
+	}
+	tmpPath := m.conf.CheckpointPath + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	m.flushed = time.Now()
+	return os.Rename(tmpPath, m.conf.CheckpointPath)
+}
+
+//------------------------------------------------------------------------------
+
+// readMasterStatus obtains the current position of the server.
+func (m *MySQLBinlog) readMasterStatus(q mysqlQuerier) error {
+	cols, rows, err := q.Query("SHOW MASTER STATUS")
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errors.New("binary logging is not enabled on the server")
+	}
+	gtids := mysql.GTIDSet{}
+	for i, col := range cols {
+		v := rows[0][i]
+		if v == nil {
+			continue
+		}
+		switch col {
+		case "File":
+			m.file = *v
+		case "Position":
+			pos, err := strconv.ParseUint(*v, 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse binlog position: %v", err)
+			}
+			m.pos = uint32(pos)
+		case "Executed_Gtid_Set":
+			if gtids, err = mysql.ParseGTIDSet(*v); err != nil {
+				return err
+			}
+		}
+	}
+	m.gtids = gtids
+	m.hasState = true
+	return nil
+}
+
+func (m *MySQLBinlog) dialReplication(pos mysqlBinlogCheckpoint, gtids mysql.GTIDSet) (mysqlBinlogStream, error) {
+	conn, err := mysql.Dial(m.myConf)
+	if err != nil {
+		return nil, err
+	}
+	// Announce that checksums are understood, otherwise servers that
+	// checksum events refuse to stream them.
+	if err = conn.Exec("SET @master_binlog_checksum = @@global.binlog_checksum"); err != nil {
+		m.log.Debugf("Failed to set binlog checksum: %v\n", err)
+	}
+	if err = conn.RegisterReplica(m.conf.ServerID); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to register as replica: %v", err)
+	}
+	if m.conf.UseGTID {
+		err = conn.StartBinlogDumpGTID(m.conf.ServerID, gtids)
+	} else {
+		err = conn.StartBinlogDump(m.conf.ServerID, pos.BinlogFile, pos.BinlogPos)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start binlog dump: %v", err)
+	}
+	return conn, nil
+}
+
+// Connect establishes a replication connection and begins streaming events.
+func (m *MySQLBinlog) Connect() error {
+	m.cMut.Lock()
+	defer m.cMut.Unlock()
+
+	if m.stream != nil {
+		return nil
+	}
+
+	m.sMut.Lock()
+	defer m.sMut.Unlock()
+
+	querier, err := m.dialQuery()
+	if err != nil {
+		return err
+	}
+	if !m.hasState {
+		if err = m.readMasterStatus(querier); err != nil {
+			querier.Close()
+			return err
+		}
+	}
+
+	stream, err := m.dialStream(mysqlBinlogCheckpoint{
+		BinlogFile: m.file,
+		BinlogPos:  m.pos,
+	}, m.gtids.Clone())
+	if err != nil {
+		querier.Close()
+		return err
+	}
+
+	m.stream = stream
+	m.querier = querier
+	m.parser = mysql.NewParser()
+	m.pending = nil
+	m.curGTID = nil
+	m.curFile = m.file
+
+	if m.conf.UseGTID {
+		m.log.Infof("Receiving MySQL binlog events from %v after GTID set: %v\n", m.conf.Address, m.gtids)
+	} else {
+		m.log.Infof("Receiving MySQL binlog events from %v at %v:%v\n", m.conf.Address, m.file, m.pos)
+	}
+	return nil
+}
+
+func (m *MySQLBinlog) disconnect() {
+	m.cMut.Lock()
+	if m.stream != nil {
+		m.stream.Close()
+		m.stream = nil
+	}
+	if m.querier != nil {
+		m.querier.Close()
+		m.querier = nil
+	}
+	m.cMut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// tableColumns returns the columns of a table, querying the server when they
+// are not cached or no longer match the table map.
+func (m *MySQLBinlog) tableColumns(t *mysql.TableMapEvent) []mysqlColumn {
+	key := t.Schema + "." + t.Table
+	if cols, exists := m.columns[key]; exists && len(cols) == len(t.ColumnTypes) {
+		return cols
+	}
+
+	var cols []mysqlColumn
+	_, rows, err := m.querier.Query(fmt.Sprintf(
+		"SELECT COLUMN_NAME, COLUMN_TYPE FROM information_schema.COLUMNS "+
+			"WHERE TABLE_SCHEMA = %v AND TABLE_NAME = %v ORDER BY ORDINAL_POSITION",
+		mysql.QuoteString(t.Schema), mysql.QuoteString(t.Table),
+	))
+	if err == nil {
+		for _, row := range rows {
+			if len(row) < 2 || row[0] == nil {
+				continue
+			}
+			col := mysqlColumn{name: *row[0]}
+			if row[1] != nil {
+				col.unsigned = strings.Contains(*row[1], "unsigned")
+			}
+			cols = append(cols, col)
+		}
+		if len(cols) != len(t.ColumnTypes) {
+			err = fmt.Errorf("table has %v columns but binlog has %v", len(cols), len(t.ColumnTypes))
+		}
+	}
+	if err != nil {
+		m.mSchemaErr.Incr(1)
+		m.log.Warnf("Failed to read columns of table %v, using column indexes as names: %v\n", key, err)
+		cols = make([]mysqlColumn, len(t.ColumnTypes))
+		for i := range cols {
+			cols[i].name = strconv.Itoa(i)
+		}
+		return cols
+	}
+	m.columns[key] = cols
+	return cols
+}
+
+func (m *MySQLBinlog) rowToMap(t *mysql.TableMapEvent, row []interface{}) map[string]interface{} {
+	if row == nil {
+		return nil
+	}
+	cols := m.tableColumns(t)
+	obj := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		if cols[i].unsigned {
+			if iv, ok := v.(int64); ok {
+				v = mysql.ToUnsigned(t.ColumnTypes[i], iv)
+			}
+		}
+		obj[cols[i].name] = v
+	}
+	return obj
+}
+
+// commit advances the committed position to the end of a transaction. Reads
+// and acknowledgements are sequential, therefore all changes of the
+// transaction have already been delivered.
+func (m *MySQLBinlog) commit(logPos uint32) {
+	m.sMut.Lock()
+	defer m.sMut.Unlock()
+
+	if m.curGTID != nil {
+		m.gtids.Add(*m.curGTID)
+		m.curGTID = nil
+	}
+	m.file = m.curFile
+	if logPos > 0 {
+		m.pos = logPos
+	}
+	if time.Since(m.flushed) >= time.Second {
+		if err := m.flush(); err != nil {
+			m.mFlushErr.Incr(1)
+			m.log.Errorf("Failed to persist binlog checkpoint: %v\n", err)
+		}
+	}
+}
+
+// handleEvent processes a parsed event, queueing any row changes.
+func (m *MySQLBinlog) handleEvent(h mysql.EventHeader, e interface{}) {
+	switch t := e.(type) {
+	case *mysql.RotateEvent:
+		m.curFile = t.File
+		if m.curGTID == nil {
+			m.sMut.Lock()
+			m.file, m.pos = t.File, uint32(t.Position)
+			m.sMut.Unlock()
+		}
+	case *mysql.GTIDEvent:
+		g := t.GTID
+		m.curGTID = &g
+	case *mysql.QueryEvent:
+		switch strings.ToUpper(strings.TrimSpace(t.Query)) {
+		case "BEGIN":
+		default:
+			// Statements other than BEGIN either commit a transaction of a
+			// non-transactional engine or are DDL, which might change the
+			// columns of a table.
+			if !strings.EqualFold(strings.TrimSpace(t.Query), "COMMIT") {
+				m.columns = map[string][]mysqlColumn{}
+			}
+			m.commit(h.LogPos)
+		}
+	case *mysql.XIDEvent:
+		m.commit(h.LogPos)
+	case *mysql.RowsEvent:
+		if len(m.tables) > 0 {
+			if _, exists := m.tables[t.Table.Schema+"."+t.Table.Table]; !exists {
+				return
+			}
+		}
+		n := len(t.After)
+		if len(t.Before) > n {
+			n = len(t.Before)
+		}
+		for i := 0; i < n; i++ {
+			change := mysqlRowChange{
+				op:        t.Type,
+				table:     t.Table,
+				timestamp: h.Timestamp,
+				logPos:    h.LogPos,
+			}
+			if i < len(t.Before) {
+				change.before = t.Before[i]
+			}
+			if i < len(t.After) {
+				change.after = t.After[i]
+			}
+			m.pending = append(m.pending, change)
+		}
+	}
+}
+
+func (m *MySQLBinlog) changeToMsg(c mysqlRowChange) (types.Message, error) {
+	gtid := ""
+	if m.curGTID != nil {
+		gtid = m.curGTID.String()
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"operation": string(c.op),
+		"schema":    c.table.Schema,
+		"table":     c.table.Table,
+		"gtid":      gtid,
+		"timestamp": c.timestamp,
+		"before":    m.rowToMap(c.table, c.before),
+		"after":     m.rowToMap(c.table, c.after),
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg := message.New([][]byte{body})
+	msg.Get(0).Metadata().
+		Set("mysql_schema", c.table.Schema).
+		Set("mysql_table", c.table.Table).
+		Set("mysql_operation", string(c.op)).
+		Set("mysql_gtid", gtid).
+		Set("mysql_binlog_file", m.curFile).
+		Set("mysql_binlog_pos", strconv.FormatUint(uint64(c.logPos), 10)).
+		Set("mysql_timestamp_unix", strconv.FormatUint(uint64(c.timestamp), 10))
+	return msg, nil
+}
+
+// Read attempts to read a new row change from the binary log.
+func (m *MySQLBinlog) Read() (types.Message, error) {
+	m.cMut.Lock()
+	stream := m.stream
+	m.cMut.Unlock()
+
+	if stream == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	for len(m.pending) == 0 {
+		data, err := stream.ReadEvent()
+		if err != nil {
+			select {
+			case <-m.closeChan:
+				return nil, types.ErrTypeClosed
+			default:
+			}
+			m.log.Errorf("Lost binlog connection: %v\n", err)
+			m.disconnect()
+			return nil, types.ErrNotConnected
+		}
+		h, e, err := m.parser.Parse(data)
+		if err != nil {
+			m.log.Errorf("Failed to parse binlog event: %v\n", err)
+			m.disconnect()
+			return nil, types.ErrNotConnected
+		}
+		m.handleEvent(h, e)
+	}
+
+	c := m.pending[0]
+	m.pending = m.pending[1:]
+	return m.changeToMsg(c)
+}
+
+// Acknowledge is a noop as the position is only committed at the end of each
+// transaction, once all of its changes have been read and acknowledged.
+func (m *MySQLBinlog) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the MySQLBinlog input and stops processing requests.
+func (m *MySQLBinlog) CloseAsync() {
+	m.closeOnce.Do(func() {
+		close(m.closeChan)
+		m.disconnect()
+
+		m.sMut.Lock()
+		if err := m.flush(); err != nil {
+			m.log.Errorf("Failed to persist binlog checkpoint: %v\n", err)
+		}
+		m.sMut.Unlock()
+	})
+}
+
+// WaitForClose blocks until the MySQLBinlog input has closed down.
+func (m *MySQLBinlog) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,281 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/mysql"
+)
+
+//------------------------------------------------------------------------------
+
+type mockMySQLStream struct {
+	events [][]byte
+}
+
+func (m *mockMySQLStream) ReadEvent() ([]byte, error) {
+	if len(m.events) == 0 {
+		return nil, errors.New("connection lost")
+	}
+	e := m.events[0]
+	m.events = m.events[1:]
+	return e, nil
+}
+
+func (m *mockMySQLStream) Close() error {
+	return nil
+}
+
+type mockMySQLQuerier struct {
+	queries []string
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func (m *mockMySQLQuerier) Query(sql string) ([]string, [][]*string, error) {
+	m.queries = append(m.queries, sql)
+	if sql == "SHOW MASTER STATUS" {
+		return []string{"File", "Position", "Binlog_Do_DB", "Binlog_Ignore_DB", "Executed_Gtid_Set"},
+			[][]*string{{
+				strPtr("mysql-bin.000001"), strPtr("4"), strPtr(""), strPtr(""),
+				strPtr("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-10"),
+			}}, nil
+	}
+	if strings.Contains(sql, "information_schema.COLUMNS") && strings.Contains(sql, "'users'") {
+		return []string{"COLUMN_NAME", "COLUMN_TYPE"}, [][]*string{
+			{strPtr("id"), strPtr("int(10) unsigned")},
+			{strPtr("name"), strPtr("varchar(20)")},
+		}, nil
+	}
+	return nil, nil, nil
+}
+
+func (m *mockMySQLQuerier) Close() error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func mysqlTestEvent(t byte, logPos uint32, body []byte) []byte {
+	b := make([]byte, 19)
+	binary.LittleEndian.PutUint32(b, 1550000000)
+	b[4] = t
+	binary.LittleEndian.PutUint32(b[9:], uint32(19+len(body)))
+	binary.LittleEndian.PutUint32(b[13:], logPos)
+	return append(b, body...)
+}
+
+func mysqlTestTransaction(t *testing.T, gno byte, table string, id int32, name string, logPos uint32) [][]byte {
+	sid, err := mysql.ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gtid := append([]byte{1}, sid[:]...)
+	gtid = append(gtid, gno, 0, 0, 0, 0, 0, 0, 0)
+
+	begin := []byte{0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 'd', 'b', 0}
+	begin = append(begin, "BEGIN"...)
+
+	tableMap := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 'd', 'b', 0, byte(len(table))}
+	tableMap = append(append(tableMap, table...), 0)
+	tableMap = append(tableMap, 2, mysql.TypeLong, mysql.TypeVarchar, 2, 20, 0, 0)
+
+	rows := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 2, 0x03, 0x00, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(rows[13:], uint32(id))
+	rows = append(append(rows, byte(len(name))), name...)
+
+	xid := []byte{9, 0, 0, 0, 0, 0, 0, 0}
+
+	return [][]byte{
+		mysqlTestEvent(0x21, logPos-400, gtid),
+		mysqlTestEvent(0x02, logPos-300, begin),
+		mysqlTestEvent(0x13, logPos-200, tableMap),
+		mysqlTestEvent(0x1e, logPos-100, rows),
+		mysqlTestEvent(0x10, logPos, xid),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestMySQLBinlogBadConfig(t *testing.T) {
+	conf := NewMySQLBinlogConfig()
+	conf.ServerID = 0
+	if _, err := NewMySQLBinlog(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero server id")
+	}
+
+	conf = NewMySQLBinlogConfig()
+	conf.Tables = []string{"nope"}
+	if _, err := NewMySQLBinlog(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad table")
+	}
+
+	conf = NewMySQLBinlogConfig()
+	conf.Timeout = "nope"
+	if _, err := NewMySQLBinlog(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad timeout")
+	}
+}
+
+func TestMySQLBinlogRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_mysql_binlog_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewMySQLBinlogConfig()
+	conf.CheckpointPath = filepath.Join(dir, "checkpoint.json")
+	conf.Tables = []string{"db.users"}
+
+	m, err := NewMySQLBinlog(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events [][]byte
+	events = append(events, mysqlTestTransaction(t, 11, "users", -1, "foo", 1000)...)
+	events = append(events, mysqlTestTransaction(t, 12, "other", 5, "bar", 2000)...)
+
+	var dialedGTIDs string
+	querier := &mockMySQLQuerier{}
+	m.dialQuery = func() (mysqlQuerier, error) {
+		return querier, nil
+	}
+	m.dialStream = func(pos mysqlBinlogCheckpoint, gtids mysql.GTIDSet) (mysqlBinlogStream, error) {
+		dialedGTIDs = gtids.String()
+		return &mockMySQLStream{events: events}, nil
+	}
+
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.CloseAsync()
+
+	if exp, act := "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-10", dialedGTIDs; exp != act {
+		t.Errorf("Wrong dialed GTID set: %v != %v", act, exp)
+	}
+
+	msg, err := m.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var act interface{}
+	if err = json.Unmarshal(msg.Get(0).Get(), &act); err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{
+		"operation": "insert",
+		"schema":    "db",
+		"table":     "users",
+		"gtid":      "3e11fa47-71ca-11e1-9e33-c80aa9429562:11",
+		"timestamp": float64(1550000000),
+		"before":    nil,
+		"after": map[string]interface{}{
+			"id":   float64(4294967295),
+			"name": "foo",
+		},
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+	meta := msg.Get(0).Metadata()
+	for k, v := range map[string]string{
+		"mysql_schema":         "db",
+		"mysql_table":          "users",
+		"mysql_operation":      "insert",
+		"mysql_gtid":           "3e11fa47-71ca-11e1-9e33-c80aa9429562:11",
+		"mysql_binlog_file":    "mysql-bin.000001",
+		"mysql_binlog_pos":     "900",
+		"mysql_timestamp_unix": "1550000000",
+	} {
+		if act := meta.Get(k); act != v {
+			t.Errorf("Wrong metadata %v: %v != %v", k, act, v)
+		}
+	}
+	if err = m.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+
+	// The second transaction is filtered out, and so the stream runs dry.
+	if _, err = m.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+
+	m.CloseAsync()
+
+	data, err := ioutil.ReadFile(conf.CheckpointPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp mysqlBinlogCheckpoint
+	if err = json.Unmarshal(data, &cp); err != nil {
+		t.Fatal(err)
+	}
+	expCP := mysqlBinlogCheckpoint{
+		GTIDSet:    "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-12",
+		BinlogFile: "mysql-bin.000001",
+		BinlogPos:  2000,
+	}
+	if cp != expCP {
+		t.Errorf("Wrong checkpoint: %+v != %+v", cp, expCP)
+	}
+
+	// A new reader resumes from the checkpoint without querying the server
+	// position.
+	if m, err = NewMySQLBinlog(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	querier = &mockMySQLQuerier{}
+	m.dialQuery = func() (mysqlQuerier, error) {
+		return querier, nil
+	}
+	m.dialStream = func(pos mysqlBinlogCheckpoint, gtids mysql.GTIDSet) (mysqlBinlogStream, error) {
+		dialedGTIDs = gtids.String()
+		return &mockMySQLStream{}, nil
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.CloseAsync()
+	if exp, act := "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-12", dialedGTIDs; exp != act {
+		t.Errorf("Wrong dialed GTID set: %v != %v", act, exp)
+	}
+	if len(querier.queries) > 0 {
+		t.Errorf("Unexpected queries: %v", querier.queries)
+	}
+}
+
+//------------------------------------------------------------------------------
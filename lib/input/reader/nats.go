@@ -30,7 +30,7 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
-	nats "github.com/nats-io/go-nats"
+	"github.com/nats-io/nats.go"
 )
 
 //------------------------------------------------------------------------------
@@ -141,14 +141,25 @@ func (n *NATS) disconnect() {
 
 // Read attempts to read a new message from the NATS subject.
 func (n *NATS) Read() (types.Message, error) {
+	return n.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new message from the NATS subject,
+// returning types.ErrTimeout if no message arrives before the deadline.
+func (n *NATS) ReadWithDeadline(deadline time.Time) (types.Message, error) {
 	n.cMut.Lock()
 	natsChan := n.natsChan
 	n.cMut.Unlock()
 
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
 	var msg *nats.Msg
 	var open bool
 	select {
 	case msg, open = <-natsChan:
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
 	case _, open = <-n.interruptChan:
 	}
 	if !open {
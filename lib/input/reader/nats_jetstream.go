@@ -0,0 +1,303 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/nats-io/nats.go"
+)
+
+//------------------------------------------------------------------------------
+
+// NATSJetStreamConfig contains configuration fields for the NATSJetStream
+// input type.
+type NATSJetStreamConfig struct {
+	URLs            []string `json:"urls" yaml:"urls"`
+	Stream          string   `json:"stream" yaml:"stream"`
+	Subject         string   `json:"subject" yaml:"subject"`
+	DurableName     string   `json:"durable_name" yaml:"durable_name"`
+	StartFromOldest bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	AckWait         string   `json:"ack_wait" yaml:"ack_wait"`
+	Backoff         []string `json:"backoff" yaml:"backoff"`
+	MaxDeliver      int      `json:"max_deliver" yaml:"max_deliver"`
+	MaxAckPending   int      `json:"max_ack_pending" yaml:"max_ack_pending"`
+	PullTimeout     string   `json:"pull_timeout" yaml:"pull_timeout"`
+}
+
+// NewNATSJetStreamConfig creates a new NATSJetStreamConfig with default values.
+func NewNATSJetStreamConfig() NATSJetStreamConfig {
+	return NATSJetStreamConfig{
+		URLs:            []string{nats.DefaultURL},
+		Stream:          "benthos_stream",
+		Subject:         "",
+		DurableName:     "benthos_consumer",
+		StartFromOldest: true,
+		AckWait:         "30s",
+		Backoff:         []string{},
+		MaxDeliver:      -1,
+		MaxAckPending:   1024,
+		PullTimeout:     "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NATSJetStream is an input type that consumes messages from a NATS JetStream
+// stream through a durable pull consumer.
+type NATSJetStream struct {
+	urls  string
+	conf  NATSJetStreamConfig
+	stats metrics.Type
+	log   log.Modular
+
+	subOpts     []nats.SubOpt
+	pullTimeout time.Duration
+
+	unAckMsgs []*nats.Msg
+
+	natsConn *nats.Conn
+	natsSub  *nats.Subscription
+	cMut     sync.Mutex
+
+	closedChan    chan struct{}
+	interruptChan chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewNATSJetStream creates a new NATSJetStream input type.
+func NewNATSJetStream(conf NATSJetStreamConfig, log log.Modular, stats metrics.Type) (*NATSJetStream, error) {
+	if len(conf.Stream) == 0 {
+		return nil, errors.New("a stream name must be specified")
+	}
+	if len(conf.DurableName) == 0 {
+		return nil, errors.New("a durable name must be specified")
+	}
+	n := NATSJetStream{
+		conf:          conf,
+		stats:         stats,
+		log:           log,
+		closedChan:    make(chan struct{}),
+		interruptChan: make(chan struct{}),
+	}
+	n.urls = strings.Join(conf.URLs, ",")
+
+	n.subOpts = []nats.SubOpt{
+		nats.BindStream(conf.Stream),
+		nats.AckExplicit(),
+		nats.DeliverNew(),
+		nats.MaxDeliver(conf.MaxDeliver),
+		nats.MaxAckPending(conf.MaxAckPending),
+	}
+	if conf.StartFromOldest {
+		n.subOpts[2] = nats.DeliverAll()
+	}
+	if len(conf.AckWait) > 0 {
+		tout, err := time.ParseDuration(conf.AckWait)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ack wait duration string: %v", err)
+		}
+		n.subOpts = append(n.subOpts, nats.AckWait(tout))
+	}
+	var backoff []time.Duration
+	for _, b := range conf.Backoff {
+		tout, err := time.ParseDuration(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse backoff duration string: %v", err)
+		}
+		backoff = append(backoff, tout)
+	}
+	if len(backoff) > 0 {
+		if conf.MaxDeliver > 0 && conf.MaxDeliver <= len(backoff) {
+			return nil, errors.New("max deliver must be greater than the number of backoff durations")
+		}
+		n.subOpts = append(n.subOpts, nats.BackOff(backoff))
+	}
+	n.pullTimeout = time.Second * 5
+	if len(conf.PullTimeout) > 0 {
+		var err error
+		if n.pullTimeout, err = time.ParseDuration(conf.PullTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse pull timeout duration string: %v", err)
+		}
+	}
+	return &n, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (n *NATSJetStream) disconnect() {
+	n.cMut.Lock()
+	defer n.cMut.Unlock()
+
+	if n.natsConn != nil {
+		n.natsConn.Close()
+		n.natsConn = nil
+		n.natsSub = nil
+	}
+}
+
+// Connect establishes a connection to a NATS server and binds a pull
+// subscription to the durable consumer, creating it if it does not exist.
+func (n *NATSJetStream) Connect() error {
+	n.cMut.Lock()
+	defer n.cMut.Unlock()
+
+	if n.natsConn != nil {
+		return nil
+	}
+	select {
+	case <-n.interruptChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	natsConn, err := nats.Connect(n.urls)
+	if err != nil {
+		return err
+	}
+
+	js, err := natsConn.JetStream(nats.MaxWait(n.pullTimeout))
+	if err != nil {
+		natsConn.Close()
+		return err
+	}
+
+	natsSub, err := js.PullSubscribe(n.conf.Subject, n.conf.DurableName, n.subOpts...)
+	if err != nil {
+		natsConn.Close()
+		return fmt.Errorf("failed to create durable consumer: %v", err)
+	}
+
+	n.natsConn = natsConn
+	n.natsSub = natsSub
+	n.log.Infof("Receiving NATS JetStream messages from stream '%v' with durable consumer '%v'\n", n.conf.Stream, n.conf.DurableName)
+	return nil
+}
+
+// Read attempts to pull a new message from the JetStream consumer.
+func (n *NATSJetStream) Read() (types.Message, error) {
+	n.cMut.Lock()
+	natsSub := n.natsSub
+	n.cMut.Unlock()
+
+	if natsSub == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	msgs, err := natsSub.Fetch(1, nats.MaxWait(n.pullTimeout))
+	if err != nil {
+		select {
+		case <-n.interruptChan:
+			return nil, types.ErrTypeClosed
+		default:
+		}
+		if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, types.ErrTimeout
+		}
+		if errors.Is(err, nats.ErrConnectionClosed) || errors.Is(err, nats.ErrBadSubscription) {
+			n.disconnect()
+			return nil, types.ErrNotConnected
+		}
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, types.ErrTimeout
+	}
+	msg := msgs[0]
+
+	info, err := msg.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	n.unAckMsgs = append(n.unAckMsgs, msg)
+
+	bmsg := message.New([][]byte{msg.Data})
+	meta := bmsg.Get(0).Metadata()
+	meta.Set("nats_jetstream_subject", msg.Subject)
+	meta.Set("nats_jetstream_stream", info.Stream)
+	meta.Set("nats_jetstream_consumer", info.Consumer)
+	meta.Set("nats_jetstream_num_delivered", strconv.FormatUint(info.NumDelivered, 10))
+	meta.Set("nats_jetstream_sequence_stream", strconv.FormatUint(info.Sequence.Stream, 10))
+	meta.Set("nats_jetstream_sequence_consumer", strconv.FormatUint(info.Sequence.Consumer, 10))
+	meta.Set("nats_jetstream_timestamp_unix_nano", strconv.FormatInt(info.Timestamp.UnixNano(), 10))
+
+	return bmsg, nil
+}
+
+// Acknowledge instructs whether unacknowledged messages have been successfully
+// propagated. Failed messages are negatively acknowledged so that they are
+// redelivered according to the backoff of the consumer.
+func (n *NATSJetStream) Acknowledge(err error) error {
+	n.cMut.Lock()
+	natsConn := n.natsConn
+	n.cMut.Unlock()
+
+	if natsConn == nil {
+		n.unAckMsgs = nil
+		return types.ErrNotConnected
+	}
+
+	var ackErr error
+	for _, m := range n.unAckMsgs {
+		var aErr error
+		if err != nil {
+			aErr = m.Nak()
+		} else {
+			aErr = m.Ack()
+		}
+		if aErr != nil {
+			ackErr = aErr
+		}
+	}
+	n.unAckMsgs = nil
+	return ackErr
+}
+
+// CloseAsync shuts down the NATSJetStream input and stops processing requests.
+func (n *NATSJetStream) CloseAsync() {
+	n.closeOnce.Do(func() {
+		close(n.interruptChan)
+		n.disconnect()
+		close(n.closedChan)
+	})
+}
+
+// WaitForClose blocks until the NATSJetStream input has closed down.
+func (n *NATSJetStream) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-n.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
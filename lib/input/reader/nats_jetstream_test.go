@@ -0,0 +1,215 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// fakeNATSServer speaks enough of the NATS protocol to serve request/reply
+// calls from a single client. The handler result is delivered to the reply
+// subject of a publish with the returned subject as its own reply, and nothing
+// is delivered when the result is nil.
+func fakeNATSServer(t *testing.T, handler func(subject string, data []byte) (res []byte, replyTo string)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		conn, err := ln.Accept()
+		ln.Close()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var writeMut sync.Mutex
+		write := func(s string) {
+			writeMut.Lock()
+			conn.Write([]byte(s))
+			writeMut.Unlock()
+		}
+		write(`INFO {"server_id":"fake","version":"2.10.0","headers":true,"max_payload":1048576}` + "\r\n")
+
+		subs := map[string]string{}
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args := strings.Fields(line)
+			if len(args) == 0 {
+				continue
+			}
+			switch strings.ToUpper(args[0]) {
+			case "PING":
+				write("PONG\r\n")
+			case "SUB":
+				subs[strings.TrimSuffix(args[1], "*")] = args[len(args)-1]
+			case "PUB":
+				size, _ := strconv.Atoi(args[len(args)-1])
+				data := make([]byte, size+2)
+				if _, err = io.ReadFull(r, data); err != nil {
+					return
+				}
+				var reply string
+				if len(args) > 3 {
+					reply = args[2]
+				}
+				res, replyTo := handler(args[1], data[:size])
+				if res == nil || len(reply) == 0 {
+					continue
+				}
+				for prefix, sid := range subs {
+					if strings.HasPrefix(reply, prefix) {
+						write(fmt.Sprintf("MSG %v %v %v %v\r\n%s\r\n", reply, sid, replyTo, len(res), res))
+					}
+				}
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String()
+}
+
+//------------------------------------------------------------------------------
+
+func TestNATSJetStreamBadConfig(t *testing.T) {
+	conf := NewNATSJetStreamConfig()
+	conf.Backoff = []string{"1s", "5s"}
+	conf.MaxDeliver = 2
+	if _, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from max deliver lower than backoff count")
+	}
+
+	conf = NewNATSJetStreamConfig()
+	conf.AckWait = "nope"
+	if _, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad ack wait")
+	}
+
+	conf = NewNATSJetStreamConfig()
+	conf.Stream = ""
+	if _, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty stream")
+	}
+}
+
+func TestNATSJetStreamReadAck(t *testing.T) {
+	var resMut sync.Mutex
+	var expires []time.Duration
+	var acks []string
+
+	url := fakeNATSServer(t, func(subject string, data []byte) ([]byte, string) {
+		resMut.Lock()
+		defer resMut.Unlock()
+		switch {
+		case strings.HasPrefix(subject, "$JS.API.CONSUMER.INFO."):
+			return []byte(`{"error":{"code":404,"err_code":10014,"description":"consumer not found"}}`), ""
+		case strings.HasPrefix(subject, "$JS.API.CONSUMER.CREATE."):
+			return []byte(`{"stream_name":"foo","name":"bar","config":{"durable_name":"bar","ack_policy":"explicit"}}`), ""
+		case strings.HasPrefix(subject, "$JS.API.CONSUMER.MSG.NEXT."):
+			var req struct {
+				Expires time.Duration `json:"expires"`
+			}
+			if err := json.Unmarshal(data, &req); err != nil {
+				t.Error(err)
+			}
+			expires = append(expires, req.Expires)
+			return []byte("hello world"), fmt.Sprintf("$JS.ACK.foo.bar.1.%v.%v.1547000000000000000.0", len(expires), len(expires))
+		case strings.HasPrefix(subject, "$JS.ACK."):
+			acks = append(acks, string(data))
+		}
+		return nil, ""
+	})
+
+	conf := NewNATSJetStreamConfig()
+	conf.URLs = []string{url}
+	conf.Stream = "foo"
+	conf.DurableName = "bar"
+	conf.PullTimeout = "2s"
+
+	r, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.CloseAsync()
+
+	msg, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+	meta := msg.Get(0).Metadata()
+	if exp, act := "1", meta.Get("nats_jetstream_sequence_stream"); exp != act {
+		t.Errorf("Wrong stream sequence: %v != %v", act, exp)
+	}
+	if exp, act := "1547000000000000000", meta.Get("nats_jetstream_timestamp_unix_nano"); exp != act {
+		t.Errorf("Wrong timestamp: %v != %v", act, exp)
+	}
+	if err = r.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.natsConn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	resMut.Lock()
+	defer resMut.Unlock()
+	if exp := []string{"+ACK", "-NAK"}; !reflect.DeepEqual(exp, acks) {
+		t.Errorf("Wrong acks: %v != %v", acks, exp)
+	}
+	for _, e := range expires {
+		if e <= 0 || e >= time.Second*2 {
+			t.Errorf("Pull request expiry should be below the pull timeout: %v", e)
+		}
+	}
+}
@@ -185,10 +185,21 @@ func (n *NATSStream) Connect() error {
 
 // Read attempts to read a new message from the NATS streaming server.
 func (n *NATSStream) Read() (types.Message, error) {
+	return n.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new message from the NATS streaming
+// server, returning types.ErrTimeout if no message arrives before the deadline.
+func (n *NATSStream) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
 	var msg *stan.Msg
 	select {
 	case msg = <-n.msgChan:
 		n.unAckMsgs = append(n.unAckMsgs, msg)
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
 	case <-n.interruptChan:
 		n.unAckMsgs = nil
 		n.disconnect()
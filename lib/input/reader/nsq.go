@@ -21,6 +21,8 @@
 package reader
 
 import (
+	"crypto/tls"
+	"fmt"
 	"io/ioutil"
 	llog "log"
 	"strings"
@@ -31,6 +33,7 @@ import (
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
 	nsq "github.com/nsqio/go-nsq"
 )
 
@@ -38,23 +41,31 @@ import (
 
 // NSQConfig contains configuration fields for the NSQ input type.
 type NSQConfig struct {
-	Addresses       []string `json:"nsqd_tcp_addresses" yaml:"nsqd_tcp_addresses"`
-	LookupAddresses []string `json:"lookupd_http_addresses" yaml:"lookupd_http_addresses"`
-	Topic           string   `json:"topic" yaml:"topic"`
-	Channel         string   `json:"channel" yaml:"channel"`
-	UserAgent       string   `json:"user_agent" yaml:"user_agent"`
-	MaxInFlight     int      `json:"max_in_flight" yaml:"max_in_flight"`
+	Addresses        []string    `json:"nsqd_tcp_addresses" yaml:"nsqd_tcp_addresses"`
+	LookupAddresses  []string    `json:"lookupd_http_addresses" yaml:"lookupd_http_addresses"`
+	Topic            string      `json:"topic" yaml:"topic"`
+	Channel          string      `json:"channel" yaml:"channel"`
+	ChannelEphemeral bool        `json:"channel_ephemeral" yaml:"channel_ephemeral"`
+	UserAgent        string      `json:"user_agent" yaml:"user_agent"`
+	MaxInFlight      int         `json:"max_in_flight" yaml:"max_in_flight"`
+	SampleRate       int         `json:"sample_rate" yaml:"sample_rate"`
+	AuthSecret       string      `json:"auth_secret" yaml:"auth_secret"`
+	TLS              btls.Config `json:"tls" yaml:"tls"`
 }
 
 // NewNSQConfig creates a new NSQConfig with default values.
 func NewNSQConfig() NSQConfig {
 	return NSQConfig{
-		Addresses:       []string{"localhost:4150"},
-		LookupAddresses: []string{"localhost:4161"},
-		Topic:           "benthos_messages",
-		Channel:         "benthos_stream",
-		UserAgent:       "benthos_consumer",
-		MaxInFlight:     100,
+		Addresses:        []string{"localhost:4150"},
+		LookupAddresses:  []string{"localhost:4161"},
+		Topic:            "benthos_messages",
+		Channel:          "benthos_stream",
+		ChannelEphemeral: false,
+		UserAgent:        "benthos_consumer",
+		MaxInFlight:      100,
+		SampleRate:       0,
+		AuthSecret:       "",
+		TLS:              btls.NewConfig(),
 	}
 }
 
@@ -69,6 +80,8 @@ type NSQ struct {
 
 	addresses       []string
 	lookupAddresses []string
+	channel         string
+	tlsConf         *tls.Config
 	conf            NSQConfig
 	stats           metrics.Type
 	log             log.Modular
@@ -101,6 +114,22 @@ func NewNSQ(conf NSQConfig, log log.Modular, stats metrics.Type) (Type, error) {
 		}
 	}
 
+	if conf.SampleRate < 0 || conf.SampleRate > 99 {
+		return nil, fmt.Errorf("sample_rate must be between 0 and 99, got %v", conf.SampleRate)
+	}
+
+	n.channel = conf.Channel
+	if conf.ChannelEphemeral && !strings.HasSuffix(n.channel, "#ephemeral") {
+		n.channel = n.channel + "#ephemeral"
+	}
+
+	if conf.TLS.Enabled {
+		var err error
+		if n.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &n, nil
 }
 
@@ -132,9 +161,15 @@ func (n *NSQ) Connect() (err error) {
 	cfg := nsq.NewConfig()
 	cfg.UserAgent = n.conf.UserAgent
 	cfg.MaxInFlight = n.conf.MaxInFlight
+	cfg.SampleRate = int32(n.conf.SampleRate)
+	cfg.AuthSecret = n.conf.AuthSecret
+	if n.tlsConf != nil {
+		cfg.TlsV1 = true
+		cfg.TlsConfig = n.tlsConf
+	}
 
 	var consumer *nsq.Consumer
-	if consumer, err = nsq.NewConsumer(n.conf.Topic, n.conf.Channel, cfg); err != nil {
+	if consumer, err = nsq.NewConsumer(n.conf.Topic, n.channel, cfg); err != nil {
 		return
 	}
 
@@ -171,10 +206,21 @@ func (n *NSQ) disconnect() error {
 
 // Read attempts to read a new message from NSQ.
 func (n *NSQ) Read() (types.Message, error) {
+	return n.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new message from NSQ, returning
+// types.ErrTimeout if no message arrives before the deadline.
+func (n *NSQ) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
 	var msg *nsq.Message
 	select {
 	case msg = <-n.internalMessages:
 		n.unAckMsgs = append(n.unAckMsgs, msg)
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
 	case <-n.interruptChan:
 		for _, m := range n.unAckMsgs {
 			m.Requeue(-1)
@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/parquet"
+)
+
+//------------------------------------------------------------------------------
+
+// ParquetConfig contains configuration fields for the Parquet input type.
+type ParquetConfig struct {
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+// NewParquetConfig creates a new ParquetConfig with default values.
+func NewParquetConfig() ParquetConfig {
+	return ParquetConfig{
+		Paths: []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Parquet is a reader type that reads the rows of Parquet files, emitting each
+// row as a JSON document.
+type Parquet struct {
+	conf ParquetConfig
+
+	fileMut   sync.Mutex
+	expanded  bool
+	remaining []string
+	path      string
+	handle    *os.File
+	file      *parquet.File
+	rowGroup  int
+	rows      []map[string]interface{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRows      metrics.StatCounter
+	mRowGroups metrics.StatCounter
+}
+
+// NewParquet creates a new Parquet reader type.
+func NewParquet(conf ParquetConfig, log log.Modular, stats metrics.Type) (*Parquet, error) {
+	if len(conf.Paths) == 0 {
+		return nil, errors.New("at least one path must be specified")
+	}
+	for _, p := range conf.Paths {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern '%v': %v", p, err)
+		}
+	}
+	return &Parquet{
+		conf:       conf,
+		log:        log,
+		stats:      stats,
+		mRows:      stats.GetCounter("rows"),
+		mRowGroups: stats.GetCounter("row_groups"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *Parquet) closeFile() {
+	if p.handle != nil {
+		p.handle.Close()
+		p.handle = nil
+	}
+	p.file = nil
+	p.rows = nil
+	p.rowGroup = 0
+}
+
+// Connect opens the next Parquet file matching the configured paths.
+func (p *Parquet) Connect() error {
+	p.fileMut.Lock()
+	defer p.fileMut.Unlock()
+
+	if p.file != nil {
+		return nil
+	}
+
+	if !p.expanded {
+		for _, pattern := range p.conf.Paths {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return err
+			}
+			if len(matches) == 0 {
+				p.log.Warnf("Path '%v' did not match any files\n", pattern)
+			}
+			p.remaining = append(p.remaining, matches...)
+		}
+		p.expanded = true
+	}
+
+	if len(p.remaining) == 0 {
+		return types.ErrTypeClosed
+	}
+	p.path = p.remaining[0]
+	p.remaining = p.remaining[1:]
+
+	handle, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	info, err := handle.Stat()
+	if err != nil {
+		handle.Close()
+		return err
+	}
+	file, err := parquet.Open(handle, info.Size())
+	if err != nil {
+		handle.Close()
+		return fmt.Errorf("failed to open '%v': %v", p.path, err)
+	}
+
+	p.handle = handle
+	p.file = file
+	p.log.Infof("Reading %v rows from Parquet file: %v\n", file.NumRows(), p.path)
+	return nil
+}
+
+// Read attempts to read a new row from the current Parquet file.
+func (p *Parquet) Read() (types.Message, error) {
+	p.fileMut.Lock()
+	defer p.fileMut.Unlock()
+
+	if p.file == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	for len(p.rows) == 0 {
+		if p.rowGroup >= p.file.NumRowGroups() {
+			p.closeFile()
+			return nil, types.ErrNotConnected
+		}
+		rows, err := p.file.ReadRowGroup(p.rowGroup)
+		if err != nil {
+			path := p.path
+			p.closeFile()
+			return nil, fmt.Errorf("failed to read row group of '%v': %v", path, err)
+		}
+		p.mRowGroups.Incr(1)
+		p.rows = rows
+		p.rowGroup++
+	}
+
+	row := p.rows[0]
+	p.rows[0] = nil
+	p.rows = p.rows[1:]
+
+	rowBytes, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mRows.Incr(1)
+	return message.New([][]byte{rowBytes}), nil
+}
+
+// Acknowledge instructs whether the pending messages were propagated
+// successfully.
+func (p *Parquet) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the Parquet input and stops processing requests.
+func (p *Parquet) CloseAsync() {
+}
+
+// WaitForClose blocks until the Parquet input has closed down.
+func (p *Parquet) WaitForClose(timeout time.Duration) error {
+	p.fileMut.Lock()
+	p.closeFile()
+	p.fileMut.Unlock()
+	return nil
+}
+
+//------------------------------------------------------------------------------
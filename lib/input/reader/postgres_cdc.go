@@ -0,0 +1,452 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/postgres"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+//------------------------------------------------------------------------------
+
+// PostgresCDCConfig contains configuration fields for the PostgresCDC input
+// type.
+type PostgresCDCConfig struct {
+	URL            string `json:"url" yaml:"url"`
+	SlotName       string `json:"slot_name" yaml:"slot_name"`
+	CreateSlot     bool   `json:"create_slot" yaml:"create_slot"`
+	Plugin         string `json:"plugin" yaml:"plugin"`
+	Publication    string `json:"publication" yaml:"publication"`
+	StatusInterval string `json:"status_interval" yaml:"status_interval"`
+}
+
+// NewPostgresCDCConfig creates a new PostgresCDCConfig with default values.
+func NewPostgresCDCConfig() PostgresCDCConfig {
+	return PostgresCDCConfig{
+		URL:            "postgres://postgres@localhost:5432/postgres?sslmode=disable",
+		SlotName:       "benthos",
+		CreateSlot:     true,
+		Plugin:         "pgoutput",
+		Publication:    "benthos",
+		StatusInterval: "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// postgresReplConn is the subset of a replication connection used by the
+// PostgresCDC reader.
+type postgresReplConn interface {
+	ReceiveReplication(ctx context.Context) (interface{}, error)
+	SendStandbyStatus(lsn postgres.LSN) error
+	Close(ctx context.Context) error
+}
+
+var slotNameRegexp = regexp.MustCompile("^[a-z0-9_]+$")
+
+// PostgresCDC is an input type that streams row changes from a PostgreSQL
+// logical replication slot.
+type PostgresCDC struct {
+	conf           PostgresCDCConfig
+	pgConf         *pgconn.Config
+	statusInterval time.Duration
+
+	dial       func() (postgresReplConn, error)
+	newDecoder func() postgres.Decoder
+
+	cMut     sync.Mutex
+	stopLoop func()
+	loopWG   sync.WaitGroup
+	dataChan chan *postgres.XLogData
+	decoder  postgres.Decoder
+
+	// ackedLSN is the position up to which all changes have been delivered
+	// and is reported back to the server.
+	ackedLSN   uint64
+	pending    []postgres.Event
+	pendingLSN postgres.LSN
+
+	// unackedLSN is the end of the last transaction that was fully read, it
+	// becomes the acked position once all read changes are acknowledged.
+	unackedLSN postgres.LSN
+	unacked    bool
+
+	log   log.Modular
+	stats metrics.Type
+
+	mStatusErr metrics.StatCounter
+	mDecodeErr metrics.StatCounter
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewPostgresCDC creates a new PostgresCDC input type.
+func NewPostgresCDC(conf PostgresCDCConfig, log log.Modular, stats metrics.Type) (*PostgresCDC, error) {
+	p := &PostgresCDC{
+		conf:       conf,
+		log:        log,
+		stats:      stats,
+		mStatusErr: stats.GetCounter("status.error"),
+		mDecodeErr: stats.GetCounter("decode.error"),
+		closeChan:  make(chan struct{}),
+	}
+
+	if !slotNameRegexp.MatchString(conf.SlotName) {
+		return nil, fmt.Errorf("invalid slot name '%v', must only contain lower case letters, numbers and underscores", conf.SlotName)
+	}
+
+	var err error
+	if p.statusInterval, err = time.ParseDuration(conf.StatusInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse status interval: %v", err)
+	}
+	if p.statusInterval <= 0 {
+		return nil, errors.New("status interval must be greater than zero")
+	}
+	if p.pgConf, err = postgres.ParseConfig(conf.URL); err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+
+	switch conf.Plugin {
+	case "pgoutput":
+		if len(conf.Publication) == 0 {
+			return nil, errors.New("a publication must be specified when using the pgoutput plugin")
+		}
+		p.newDecoder = func() postgres.Decoder {
+			return postgres.NewPGOutputDecoder()
+		}
+	case "wal2json":
+		p.newDecoder = func() postgres.Decoder {
+			return postgres.NewWal2JSONDecoder()
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised plugin: %v", conf.Plugin)
+	}
+
+	p.dial = p.dialReplication
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *PostgresCDC) dialReplication() (postgresReplConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	conn, err := postgres.Dial(ctx, p.pgConf)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.conf.CreateSlot {
+		var rows [][][]byte
+		if rows, err = conn.Query(
+			ctx, "SELECT 1 FROM pg_replication_slots WHERE slot_name = "+postgres.QuoteLiteral(p.conf.SlotName),
+		); err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("failed to query replication slots: %v", err)
+		}
+		if len(rows) == 0 {
+			if _, err = conn.Query(ctx, fmt.Sprintf(
+				"CREATE_REPLICATION_SLOT %v LOGICAL %v NOEXPORT_SNAPSHOT",
+				postgres.QuoteIdentifier(p.conf.SlotName), p.conf.Plugin,
+			)); err != nil {
+				conn.Close(ctx)
+				return nil, fmt.Errorf("failed to create replication slot: %v", err)
+			}
+			p.log.Infof("Created replication slot '%v'\n", p.conf.SlotName)
+		}
+	}
+
+	var opts map[string]string
+	if p.conf.Plugin == "pgoutput" {
+		opts = map[string]string{
+			"proto_version":     "1",
+			"publication_names": p.conf.Publication,
+		}
+	} else {
+		opts = map[string]string{
+			"format-version": "2",
+			"include-xids":   "1",
+			"include-lsn":    "1",
+		}
+	}
+	startLSN := postgres.LSN(atomic.LoadUint64(&p.ackedLSN))
+	if err = conn.StartReplication(ctx, p.conf.SlotName, startLSN, opts); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("failed to start replication: %v", err)
+	}
+	return conn, nil
+}
+
+// Connect establishes a replication connection and begins streaming changes.
+func (p *PostgresCDC) Connect() error {
+	p.cMut.Lock()
+	defer p.cMut.Unlock()
+
+	if p.dataChan != nil {
+		return nil
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stopLoop = cancel
+	p.decoder = p.newDecoder()
+	p.pending = nil
+	p.unackedLSN, p.unacked = 0, false
+	p.dataChan = make(chan *postgres.XLogData)
+
+	p.loopWG.Add(1)
+	go p.receiveLoop(ctx, conn, p.dataChan)
+
+	p.log.Infof("Receiving changes from Postgres replication slot: %v\n", p.conf.SlotName)
+	return nil
+}
+
+func (p *PostgresCDC) sendStatus(conn postgresReplConn) {
+	if err := conn.SendStandbyStatus(postgres.LSN(atomic.LoadUint64(&p.ackedLSN))); err != nil {
+		p.mStatusErr.Incr(1)
+		p.log.Errorf("Failed to send standby status: %v\n", err)
+	}
+}
+
+// receiveLoop owns a replication connection, forwarding the change data it
+// receives and reporting the acked position at the status interval, until the
+// context is cancelled or the connection is lost.
+func (p *PostgresCDC) receiveLoop(ctx context.Context, conn postgresReplConn, dataChan chan<- *postgres.XLogData) {
+	defer func() {
+		if ctx.Err() != nil {
+			// Report our final position when closing down gracefully.
+			p.sendStatus(conn)
+		}
+		closeCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		conn.Close(closeCtx)
+		cancel()
+		close(dataChan)
+		p.loopWG.Done()
+	}()
+
+	nextStatus := time.Now().Add(p.statusInterval)
+	for {
+		if !time.Now().Before(nextStatus) {
+			p.sendStatus(conn)
+			nextStatus = time.Now().Add(p.statusInterval)
+		}
+
+		recvCtx, cancel := context.WithDeadline(ctx, nextStatus)
+		msg, err := conn.ReceiveReplication(recvCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			p.log.Errorf("Lost replication connection: %v\n", err)
+			return
+		}
+		switch t := msg.(type) {
+		case *postgres.Keepalive:
+			if t.ReplyRequested {
+				p.sendStatus(conn)
+			}
+		case *postgres.XLogData:
+			select {
+			case dataChan <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (p *PostgresCDC) disconnect() {
+	p.cMut.Lock()
+	if p.stopLoop != nil {
+		p.stopLoop()
+		p.stopLoop = nil
+	}
+	p.dataChan = nil
+	p.cMut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+func (p *PostgresCDC) setAcked(lsn postgres.LSN) {
+	for {
+		current := atomic.LoadUint64(&p.ackedLSN)
+		if uint64(lsn) <= current || atomic.CompareAndSwapUint64(&p.ackedLSN, current, uint64(lsn)) {
+			return
+		}
+	}
+}
+
+func postgresEventToMsg(e postgres.Event, lsn postgres.LSN) (types.Message, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"operation": string(e.Type),
+		"schema":    e.Schema,
+		"table":     e.Table,
+		"xid":       e.XID,
+		"lsn":       lsn.String(),
+		"data":      e.Data,
+		"old":       e.Old,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg := message.New([][]byte{body})
+	msg.Get(0).Metadata().
+		Set("postgres_lsn", lsn.String()).
+		Set("postgres_schema", e.Schema).
+		Set("postgres_table", e.Table).
+		Set("postgres_operation", string(e.Type)).
+		Set("postgres_xid", strconv.FormatUint(uint64(e.XID), 10))
+	return msg, nil
+}
+
+// Read attempts to read a new change event from the replication stream.
+func (p *PostgresCDC) Read() (types.Message, error) {
+	return p.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new change event from the replication
+// stream, returning types.ErrTimeout if none arrives before the deadline.
+func (p *PostgresCDC) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	p.cMut.Lock()
+	dataChan := p.dataChan
+	p.cMut.Unlock()
+
+	if dataChan == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	for len(p.pending) == 0 {
+		var data *postgres.XLogData
+		var open bool
+		select {
+		case data, open = <-dataChan:
+			if !open {
+				p.disconnect()
+				return nil, types.ErrNotConnected
+			}
+		case <-timeoutChan:
+			return nil, types.ErrTimeout
+		case <-p.closeChan:
+			return nil, types.ErrTypeClosed
+		}
+
+		events, err := p.decoder.Decode(data.Data)
+		if err != nil {
+			p.mDecodeErr.Incr(1)
+			p.log.Errorf("Failed to decode replication message at %v: %v\n", data.WALStart, err)
+			p.disconnect()
+			return nil, types.ErrNotConnected
+		}
+		for _, e := range events {
+			switch e.Type {
+			case postgres.EventInsert, postgres.EventUpdate, postgres.EventDelete, postgres.EventTruncate:
+				p.pending = append(p.pending, e)
+				p.pendingLSN = data.WALStart
+			case postgres.EventCommit:
+				// All changes of this transaction have been read, but they
+				// can only be confirmed once they are acknowledged.
+				if p.unacked {
+					p.unackedLSN = e.CommitLSN
+				} else {
+					p.setAcked(e.CommitLSN)
+				}
+			}
+		}
+	}
+
+	e := p.pending[0]
+	p.pending = p.pending[1:]
+
+	msg, err := postgresEventToMsg(e, p.pendingLSN)
+	if err != nil {
+		return nil, err
+	}
+	p.unacked = true
+	return msg, nil
+}
+
+// Acknowledge confirms the end LSN of the last fully read transaction once all
+// changes read so far have been successfully delivered. When delivery fails the
+// replication stream is restarted from the last confirmed LSN.
+func (p *PostgresCDC) Acknowledge(err error) error {
+	if err != nil {
+		p.log.Warnf("Restarting replication from %v after failed delivery: %v\n", postgres.LSN(atomic.LoadUint64(&p.ackedLSN)), err)
+		p.disconnect()
+		return nil
+	}
+	if p.unacked {
+		p.setAcked(p.unackedLSN)
+		p.unacked = false
+	}
+	return nil
+}
+
+// CloseAsync shuts down the PostgresCDC input and stops processing requests.
+func (p *PostgresCDC) CloseAsync() {
+	p.closeOnce.Do(func() {
+		close(p.closeChan)
+		p.disconnect()
+	})
+}
+
+// WaitForClose blocks until the PostgresCDC input has closed down.
+func (p *PostgresCDC) WaitForClose(timeout time.Duration) error {
+	closed := make(chan struct{})
+	go func() {
+		p.loopWG.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
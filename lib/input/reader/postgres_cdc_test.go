@@ -0,0 +1,390 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/postgres"
+)
+
+//------------------------------------------------------------------------------
+
+type mockPostgresReplConn struct {
+	msgs chan interface{}
+
+	mut      sync.Mutex
+	statuses []postgres.LSN
+	closed   bool
+}
+
+func (m *mockPostgresReplConn) ReceiveReplication(ctx context.Context) (interface{}, error) {
+	select {
+	case msg, open := <-m.msgs:
+		if !open {
+			return nil, errors.New("connection closed")
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *mockPostgresReplConn) SendStandbyStatus(lsn postgres.LSN) error {
+	m.mut.Lock()
+	m.statuses = append(m.statuses, lsn)
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *mockPostgresReplConn) Close(ctx context.Context) error {
+	m.mut.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.msgs)
+	}
+	m.mut.Unlock()
+	return nil
+}
+
+func (m *mockPostgresReplConn) lastStatus() postgres.LSN {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	if len(m.statuses) == 0 {
+		return 0
+	}
+	return m.statuses[len(m.statuses)-1]
+}
+
+//------------------------------------------------------------------------------
+
+func TestPostgresCDCBadConfig(t *testing.T) {
+	conf := NewPostgresCDCConfig()
+	conf.SlotName = "Nope-Slot"
+	if _, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad slot name")
+	}
+
+	conf = NewPostgresCDCConfig()
+	conf.Plugin = "nope"
+	if _, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad plugin")
+	}
+
+	conf = NewPostgresCDCConfig()
+	conf.URL = "mysql://foo@localhost/bar"
+	if _, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad url")
+	}
+
+	conf = NewPostgresCDCConfig()
+	conf.Publication = ""
+	if _, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty publication")
+	}
+}
+
+func TestPostgresCDCWal2JSON(t *testing.T) {
+	conf := NewPostgresCDCConfig()
+	conf.Plugin = "wal2json"
+	conf.StatusInterval = "1h"
+
+	p, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mConn := &mockPostgresReplConn{msgs: make(chan interface{}, 10)}
+	p.dial = func() (postgresReplConn, error) {
+		return mConn, nil
+	}
+
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		p.CloseAsync()
+		if err := p.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x10, Data: []byte(`{"action":"B","xid":5}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x20, Data: []byte(
+		`{"action":"I","schema":"public","table":"foo","columns":[{"name":"id","value":1}]}`,
+	)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x30, Data: []byte(
+		`{"action":"D","schema":"public","table":"foo","identity":[{"name":"id","value":1}]}`,
+	)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x40, Data: []byte(`{"action":"C","nextlsn":"0/50"}`)}
+
+	msg, err := p.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var act interface{}
+	if err = json.Unmarshal(msg.Get(0).Get(), &act); err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{
+		"operation": "insert",
+		"schema":    "public",
+		"table":     "foo",
+		"xid":       float64(5),
+		"lsn":       "0/20",
+		"data":      map[string]interface{}{"id": float64(1)},
+		"old":       nil,
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+	meta := msg.Get(0).Metadata()
+	for k, v := range map[string]string{
+		"postgres_lsn":       "0/20",
+		"postgres_schema":    "public",
+		"postgres_table":     "foo",
+		"postgres_operation": "insert",
+		"postgres_xid":       "5",
+	} {
+		if act := meta.Get(k); act != v {
+			t.Errorf("Wrong metadata %v: %v != %v", k, act, v)
+		}
+	}
+	if err = p.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+
+	if msg, err = p.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "delete", msg.Get(0).Metadata().Get("postgres_operation"); exp != act {
+		t.Errorf("Wrong operation: %v != %v", act, exp)
+	}
+
+	// Changes of an uncommitted transaction must not be confirmed.
+	if exp, act := postgres.LSN(0), postgres.LSN(atomic.LoadUint64(&p.ackedLSN)); exp != act {
+		t.Errorf("Wrong acked LSN: %v != %v", act, exp)
+	}
+	if err = p.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+
+	// Reading past the commit confirms the whole transaction.
+	readErr := make(chan error)
+	go func() {
+		_, err := p.Read()
+		readErr <- err
+	}()
+	mConn.msgs <- &postgres.Keepalive{WALEnd: 0x50, ReplyRequested: true}
+	for i := 0; i < 100 && mConn.lastStatus() != 0x50; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if exp, act := postgres.LSN(0x50), mConn.lastStatus(); exp != act {
+		t.Errorf("Wrong confirmed LSN: %v != %v", act, exp)
+	}
+
+	mConn.Close(context.Background())
+	if err = <-readErr; err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+}
+
+func TestPostgresCDCUnackedCommit(t *testing.T) {
+	conf := NewPostgresCDCConfig()
+	conf.Plugin = "wal2json"
+	conf.StatusInterval = "1h"
+
+	p, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dials := 0
+	var mConn *mockPostgresReplConn
+	p.dial = func() (postgresReplConn, error) {
+		dials++
+		mConn = &mockPostgresReplConn{msgs: make(chan interface{}, 10)}
+		return mConn, nil
+	}
+	defer p.CloseAsync()
+
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x10, Data: []byte(`{"action":"B","xid":5}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x20, Data: []byte(
+		`{"action":"I","schema":"public","table":"foo","columns":[{"name":"id","value":1}]}`,
+	)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x30, Data: []byte(`{"action":"C","nextlsn":"0/40"}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x40, Data: []byte(`{"action":"B","xid":6}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x50, Data: []byte(
+		`{"action":"I","schema":"public","table":"foo","columns":[{"name":"id","value":2}]}`,
+	)}
+
+	// Reading past the commit without an acknowledgement, e.g. when the
+	// message is held by a batching policy, must not confirm the transaction.
+	if _, err = p.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := postgres.LSN(0), postgres.LSN(atomic.LoadUint64(&p.ackedLSN)); exp != act {
+		t.Errorf("Wrong acked LSN: %v != %v", act, exp)
+	}
+
+	// A failed delivery restarts replication from the last confirmed LSN.
+	if err = p.Acknowledge(errors.New("nope")); err != nil {
+		t.Error(err)
+	}
+	if exp, act := postgres.LSN(0), postgres.LSN(atomic.LoadUint64(&p.ackedLSN)); exp != act {
+		t.Errorf("Wrong acked LSN: %v != %v", act, exp)
+	}
+	if _, err = p.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, dials; exp != act {
+		t.Errorf("Wrong count of dials: %v != %v", act, exp)
+	}
+
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x10, Data: []byte(`{"action":"B","xid":5}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x20, Data: []byte(
+		`{"action":"I","schema":"public","table":"foo","columns":[{"name":"id","value":1}]}`,
+	)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x30, Data: []byte(`{"action":"C","nextlsn":"0/40"}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x40, Data: []byte(`{"action":"B","xid":6}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x50, Data: []byte(
+		`{"action":"I","schema":"public","table":"foo","columns":[{"name":"id","value":2}]}`,
+	)}
+	if _, err = p.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Acknowledging both changes confirms the first transaction only.
+	if err = p.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+	if exp, act := postgres.LSN(0x40), postgres.LSN(atomic.LoadUint64(&p.ackedLSN)); exp != act {
+		t.Errorf("Wrong acked LSN: %v != %v", act, exp)
+	}
+}
+
+func TestPostgresCDCStatusInterval(t *testing.T) {
+	conf := NewPostgresCDCConfig()
+	conf.Plugin = "wal2json"
+	conf.StatusInterval = "10ms"
+
+	p, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mConn := &mockPostgresReplConn{msgs: make(chan interface{}, 10)}
+	p.dial = func() (postgresReplConn, error) {
+		return mConn, nil
+	}
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x10, Data: []byte(`{"action":"B","xid":5}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x20, Data: []byte(`{"action":"C","nextlsn":"0/30"}`)}
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x30, Data: []byte(
+		`{"action":"I","schema":"public","table":"foo","columns":[{"name":"id","value":1}]}`,
+	)}
+	if _, err = p.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The acked position is reported without the server requesting it.
+	for i := 0; i < 100 && mConn.lastStatus() != 0x30; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if exp, act := postgres.LSN(0x30), mConn.lastStatus(); exp != act {
+		t.Errorf("Wrong confirmed LSN: %v != %v", act, exp)
+	}
+
+	p.CloseAsync()
+	if err = p.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+	mConn.mut.Lock()
+	if !mConn.closed {
+		t.Error("Expected connection to be closed")
+	}
+	mConn.mut.Unlock()
+}
+
+func TestPostgresCDCPGOutputDecodeError(t *testing.T) {
+	conf := NewPostgresCDCConfig()
+	p, err := NewPostgresCDC(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dials := 0
+	var mConn *mockPostgresReplConn
+	p.dial = func() (postgresReplConn, error) {
+		dials++
+		mConn = &mockPostgresReplConn{msgs: make(chan interface{}, 10)}
+		return mConn, nil
+	}
+	defer p.CloseAsync()
+
+	if _, err = p.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An insert for an unknown relation cannot be decoded.
+	mConn.msgs <- &postgres.XLogData{WALStart: 0x10, Data: []byte{'I', 0, 0, 0, 1, 'N'}}
+	if _, err = p.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, dials; exp != act {
+		t.Errorf("Wrong count of dials: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
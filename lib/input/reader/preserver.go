@@ -85,6 +85,13 @@ func (p *Preserver) Acknowledge(err error) error {
 
 // Read attempts to read a new message from the source.
 func (p *Preserver) Read() (types.Message, error) {
+	return p.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new message from the source, returning
+// types.ErrTimeout if none is available before the deadline. The deadline is
+// only honoured when the wrapped reader implements DeadlineReader.
+func (p *Preserver) ReadWithDeadline(deadline time.Time) (types.Message, error) {
 	// If we have messages queued to be resent we prioritise them over reading
 	// new messages.
 	if lMsgs := len(p.resendMessages); lMsgs > 0 {
@@ -97,7 +104,13 @@ func (p *Preserver) Read() (types.Message, error) {
 		p.unAckMessages = append(p.unAckMessages, msg)
 		return msg, nil
 	}
-	msg, err := p.r.Read()
+	var msg types.Message
+	var err error
+	if dr, ok := p.r.(DeadlineReader); ok && !deadline.IsZero() {
+		msg, err = dr.ReadWithDeadline(deadline)
+	} else {
+		msg, err = p.r.Read()
+	}
 	if err == nil {
 		p.unAckMessages = append(p.unAckMessages, msg)
 	}
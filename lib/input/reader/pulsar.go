@@ -0,0 +1,330 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/pulsar"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/gorilla/websocket"
+)
+
+//------------------------------------------------------------------------------
+
+// PulsarConfig contains configuration fields for the Pulsar input type.
+type PulsarConfig struct {
+	URL                        string      `json:"url" yaml:"url"`
+	Topic                      string      `json:"topic" yaml:"topic"`
+	SubscriptionName           string      `json:"subscription_name" yaml:"subscription_name"`
+	SubscriptionType           string      `json:"subscription_type" yaml:"subscription_type"`
+	ReceiverQueueSize          int         `json:"receiver_queue_size" yaml:"receiver_queue_size"`
+	NegativeAckRedeliveryDelay string      `json:"negative_ack_redelivery_delay" yaml:"negative_ack_redelivery_delay"`
+	AuthToken                  string      `json:"auth_token" yaml:"auth_token"`
+	TLS                        btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewPulsarConfig creates a new PulsarConfig with default values.
+func NewPulsarConfig() PulsarConfig {
+	return PulsarConfig{
+		URL:                        "ws://localhost:8080",
+		Topic:                      "persistent://public/default/benthos",
+		SubscriptionName:           "benthos",
+		SubscriptionType:           "shared",
+		ReceiverQueueSize:          1000,
+		NegativeAckRedeliveryDelay: "1m",
+		AuthToken:                  "",
+		TLS:                        btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// pulsarMessage is a message delivered by the Pulsar WebSocket consumer API.
+type pulsarMessage struct {
+	MessageID       string            `json:"messageId"`
+	Payload         string            `json:"payload"`
+	Properties      map[string]string `json:"properties"`
+	PublishTime     string            `json:"publishTime"`
+	RedeliveryCount int               `json:"redeliveryCount"`
+	Key             string            `json:"key"`
+}
+
+// pulsarAck is sent over the WebSocket consumer API in order to acknowledge or
+// negatively acknowledge a message.
+type pulsarAck struct {
+	Type      string `json:"type,omitempty"`
+	MessageID string `json:"messageId"`
+}
+
+var pulsarSubscriptionTypes = map[string]string{
+	"exclusive":  "Exclusive",
+	"shared":     "Shared",
+	"failover":   "Failover",
+	"key_shared": "Key_Shared",
+}
+
+//------------------------------------------------------------------------------
+
+// Pulsar is an input type that consumes messages from an Apache Pulsar topic
+// through the WebSocket consumer API.
+type Pulsar struct {
+	conf  PulsarConfig
+	stats metrics.Type
+	log   log.Modular
+
+	consumerURL string
+	tlsConf     *tls.Config
+
+	unAckIDs []string
+
+	conn *websocket.Conn
+	cMut sync.Mutex
+	wMut sync.Mutex
+
+	mAckErr metrics.StatCounter
+
+	closedChan    chan struct{}
+	interruptChan chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewPulsar creates a new Pulsar input type.
+func NewPulsar(conf PulsarConfig, log log.Modular, stats metrics.Type) (*Pulsar, error) {
+	if len(conf.SubscriptionName) == 0 {
+		return nil, errors.New("a subscription name must be specified")
+	}
+	subType, exists := pulsarSubscriptionTypes[conf.SubscriptionType]
+	if !exists {
+		return nil, fmt.Errorf("unrecognised subscription type: %v", conf.SubscriptionType)
+	}
+	topicPath, err := pulsar.TopicPath(conf.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	p := Pulsar{
+		conf:          conf,
+		stats:         stats,
+		log:           log,
+		mAckErr:       stats.GetCounter("ack.error"),
+		closedChan:    make(chan struct{}),
+		interruptChan: make(chan struct{}),
+	}
+
+	params := url.Values{}
+	params.Set("subscriptionType", subType)
+	if conf.ReceiverQueueSize > 0 {
+		params.Set("receiverQueueSize", strconv.Itoa(conf.ReceiverQueueSize))
+	}
+	if len(conf.NegativeAckRedeliveryDelay) > 0 {
+		delay, err := time.ParseDuration(conf.NegativeAckRedeliveryDelay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse negative ack redelivery delay string: %v", err)
+		}
+		params.Set("negativeAckRedeliveryDelay", strconv.FormatInt(int64(delay/time.Millisecond), 10))
+	}
+	p.consumerURL = fmt.Sprintf(
+		"%v/ws/v2/consumer/%v/%v?%v",
+		strings.TrimSuffix(conf.URL, "/"), topicPath,
+		url.PathEscape(conf.SubscriptionName), params.Encode(),
+	)
+
+	if conf.TLS.Enabled {
+		if p.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *Pulsar) disconnect() {
+	p.cMut.Lock()
+	defer p.cMut.Unlock()
+
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+// Connect establishes a consumer connection to a Pulsar broker.
+func (p *Pulsar) Connect() error {
+	p.cMut.Lock()
+	defer p.cMut.Unlock()
+
+	if p.conn != nil {
+		return nil
+	}
+	select {
+	case <-p.interruptChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	headers := http.Header{}
+	if len(p.conf.AuthToken) > 0 {
+		headers.Set("Authorization", "Bearer "+p.conf.AuthToken)
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = p.tlsConf
+
+	conn, res, err := dialer.Dial(p.consumerURL, headers)
+	if err != nil {
+		if res != nil {
+			return fmt.Errorf("failed to subscribe: %v (%v)", err, res.Status)
+		}
+		return err
+	}
+
+	p.conn = conn
+	p.unAckIDs = nil
+	p.log.Infof("Receiving Pulsar messages from topic '%v' with subscription '%v'\n", p.conf.Topic, p.conf.SubscriptionName)
+	return nil
+}
+
+// Read attempts to read a new message from the Pulsar subscription.
+func (p *Pulsar) Read() (types.Message, error) {
+	p.cMut.Lock()
+	conn := p.conn
+	p.cMut.Unlock()
+
+	if conn == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	var pMsg pulsarMessage
+	if err := conn.ReadJSON(&pMsg); err != nil {
+		select {
+		case <-p.interruptChan:
+			return nil, types.ErrTypeClosed
+		default:
+		}
+		if _, isJSONErr := err.(*json.SyntaxError); isJSONErr {
+			return nil, err
+		}
+		p.disconnect()
+		p.log.Errorf("Lost connection to Pulsar: %v\n", err)
+		return nil, types.ErrNotConnected
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(pMsg.Payload)
+	if err != nil {
+		// Without acknowledging the message it would be redelivered forever.
+		p.sendAck(conn, pulsarAck{MessageID: pMsg.MessageID})
+		return nil, fmt.Errorf("failed to decode message payload: %v", err)
+	}
+	p.unAckIDs = append(p.unAckIDs, pMsg.MessageID)
+
+	msg := message.New([][]byte{payload})
+	meta := msg.Get(0).Metadata()
+	for k, v := range pMsg.Properties {
+		meta.Set(k, v)
+	}
+	meta.Set("pulsar_message_id", pMsg.MessageID)
+	meta.Set("pulsar_key", pMsg.Key)
+	meta.Set("pulsar_publish_time", pMsg.PublishTime)
+	meta.Set("pulsar_redelivery_count", strconv.Itoa(pMsg.RedeliveryCount))
+	meta.Set("pulsar_topic", p.conf.Topic)
+
+	return msg, nil
+}
+
+func (p *Pulsar) sendAck(conn *websocket.Conn, ack pulsarAck) error {
+	p.wMut.Lock()
+	err := conn.WriteJSON(ack)
+	p.wMut.Unlock()
+	if err != nil {
+		p.mAckErr.Incr(1)
+	}
+	return err
+}
+
+// Acknowledge instructs whether unacknowledged messages have been successfully
+// propagated. Failed messages are negatively acknowledged so that the broker
+// redelivers them after the negative ack redelivery delay.
+func (p *Pulsar) Acknowledge(err error) error {
+	p.cMut.Lock()
+	conn := p.conn
+	p.cMut.Unlock()
+
+	if conn == nil {
+		// Unacknowledged messages are redelivered by the broker when the
+		// consumer reconnects.
+		p.unAckIDs = nil
+		return types.ErrNotConnected
+	}
+
+	ackType := ""
+	if err != nil {
+		ackType = "negativeAcknowledge"
+	}
+
+	var ackErr error
+	for _, id := range p.unAckIDs {
+		if sErr := p.sendAck(conn, pulsarAck{
+			Type:      ackType,
+			MessageID: id,
+		}); sErr != nil {
+			ackErr = sErr
+		}
+	}
+	p.unAckIDs = nil
+	return ackErr
+}
+
+// CloseAsync shuts down the Pulsar input and stops processing requests.
+func (p *Pulsar) CloseAsync() {
+	p.closeOnce.Do(func() {
+		close(p.interruptChan)
+		p.disconnect()
+		close(p.closedChan)
+	})
+}
+
+// WaitForClose blocks until the Pulsar input has closed down.
+func (p *Pulsar) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-p.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,163 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/gorilla/websocket"
+)
+
+func TestPulsarBadConfig(t *testing.T) {
+	conf := NewPulsarConfig()
+	conf.SubscriptionType = "nope"
+	if _, err := NewPulsar(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad subscription type")
+	}
+
+	conf = NewPulsarConfig()
+	conf.SubscriptionName = ""
+	if _, err := NewPulsar(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty subscription name")
+	}
+
+	conf = NewPulsarConfig()
+	conf.NegativeAckRedeliveryDelay = "nope"
+	if _, err := NewPulsar(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad redelivery delay")
+	}
+}
+
+func TestPulsarBasic(t *testing.T) {
+	acks := make(chan pulsarAck, 2)
+	reqs := make(chan *http.Request, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs <- r
+		upgrader := websocket.Upgrader{}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for i, body := range []string{"foo", "bar"} {
+			if err = ws.WriteJSON(pulsarMessage{
+				MessageID:       string('a' + rune(i)),
+				Payload:         base64.StdEncoding.EncodeToString([]byte(body)),
+				Properties:      map[string]string{"prop": body},
+				PublishTime:     "2019-01-01T00:00:00.000Z",
+				RedeliveryCount: i,
+				Key:             "key",
+			}); err != nil {
+				t.Error(err)
+				return
+			}
+			var ack pulsarAck
+			if err = ws.ReadJSON(&ack); err != nil {
+				return
+			}
+			acks <- ack
+		}
+	}))
+	defer server.Close()
+
+	conf := NewPulsarConfig()
+	wsURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsURL.Scheme = "ws"
+	conf.URL = wsURL.String()
+	conf.Topic = "foo"
+	conf.SubscriptionName = "bar"
+	conf.SubscriptionType = "failover"
+	conf.AuthToken = "baz"
+
+	p, err := NewPulsar(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-reqs
+	if exp, act := "/ws/v2/consumer/persistent/public/default/foo/bar", req.URL.Path; exp != act {
+		t.Errorf("Wrong path: %v != %v", act, exp)
+	}
+	if exp, act := "Failover", req.URL.Query().Get("subscriptionType"); exp != act {
+		t.Errorf("Wrong subscription type: %v != %v", act, exp)
+	}
+	if exp, act := "60000", req.URL.Query().Get("negativeAckRedeliveryDelay"); exp != act {
+		t.Errorf("Wrong redelivery delay: %v != %v", act, exp)
+	}
+	if exp, act := "Bearer baz", req.Header.Get("Authorization"); exp != act {
+		t.Errorf("Wrong auth header: %v != %v", act, exp)
+	}
+
+	msg, err := p.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+	if exp, act := "foo", msg.Get(0).Metadata().Get("prop"); exp != act {
+		t.Errorf("Wrong property: %v != %v", act, exp)
+	}
+	if exp, act := "a", msg.Get(0).Metadata().Get("pulsar_message_id"); exp != act {
+		t.Errorf("Wrong message id: %v != %v", act, exp)
+	}
+	if err = p.Acknowledge(nil); err != nil {
+		t.Error(err)
+	}
+	if exp, act := (pulsarAck{MessageID: "a"}), <-acks; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong ack: %+v != %+v", act, exp)
+	}
+
+	if msg, err = p.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "1", msg.Get(0).Metadata().Get("pulsar_redelivery_count"); exp != act {
+		t.Errorf("Wrong redelivery count: %v != %v", act, exp)
+	}
+	if err = p.Acknowledge(errors.New("nope")); err != nil {
+		t.Error(err)
+	}
+	if exp, act := (pulsarAck{Type: "negativeAcknowledge", MessageID: "b"}), <-acks; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong ack: %+v != %+v", act, exp)
+	}
+
+	p.CloseAsync()
+	if err = p.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
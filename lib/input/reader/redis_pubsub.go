@@ -117,6 +117,12 @@ func (r *RedisPubSub) Connect() error {
 
 // Read attempts to pop a message from a redis pubsub channel.
 func (r *RedisPubSub) Read() (types.Message, error) {
+	return r.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to pop a message from a redis pubsub channel,
+// returning types.ErrTimeout if no message arrives before the deadline.
+func (r *RedisPubSub) ReadWithDeadline(deadline time.Time) (types.Message, error) {
 	var pubsub *redis.PubSub
 
 	r.cMut.Lock()
@@ -127,7 +133,16 @@ func (r *RedisPubSub) Read() (types.Message, error) {
 		return nil, types.ErrNotConnected
 	}
 
-	rMsg, open := <-pubsub.Channel()
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	var rMsg *redis.Message
+	var open bool
+	select {
+	case rMsg, open = <-pubsub.Channel():
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
+	}
 	if !open {
 		r.disconnect()
 		return nil, types.ErrTypeClosed
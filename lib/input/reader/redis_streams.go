@@ -48,6 +48,7 @@ type RedisStreamsConfig struct {
 	StartFromOldest bool     `json:"start_from_oldest" yaml:"start_from_oldest"`
 	CommitPeriod    string   `json:"commit_period" yaml:"commit_period"`
 	Timeout         string   `json:"timeout" yaml:"timeout"`
+	ClaimMinIdle    string   `json:"claim_min_idle" yaml:"claim_min_idle"`
 }
 
 // NewRedisStreamsConfig creates a new RedisStreamsConfig with default values.
@@ -62,6 +63,7 @@ func NewRedisStreamsConfig() RedisStreamsConfig {
 		StartFromOldest: true,
 		CommitPeriod:    "1s",
 		Timeout:         "5s",
+		ClaimMinIdle:    "1m",
 	}
 }
 
@@ -74,6 +76,8 @@ type RedisStreams struct {
 
 	timeout      time.Duration
 	commitPeriod time.Duration
+	claimMinIdle time.Duration
+	lastClaim    time.Time
 
 	url  *url.URL
 	conf RedisStreamsConfig
@@ -85,6 +89,8 @@ type RedisStreams struct {
 	ackPending  map[string][]string // Acks that are pending
 	ackLastSent time.Time
 
+	mClaimed metrics.StatCounter
+
 	stats metrics.Type
 	log   log.Modular
 }
@@ -100,6 +106,7 @@ func NewRedisStreams(
 		backlogs:   make(map[string]string, len(conf.Streams)),
 		ackSend:    make(map[string][]string, len(conf.Streams)),
 		ackPending: make(map[string][]string, len(conf.Streams)),
+		mClaimed:   stats.GetCounter("claimed"),
 	}
 
 	for _, str := range conf.Streams {
@@ -126,6 +133,13 @@ func NewRedisStreams(
 		}
 	}
 
+	if tout := conf.ClaimMinIdle; len(tout) > 0 {
+		var err error
+		if r.claimMinIdle, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse claim min idle string: %v", err)
+		}
+	}
+
 	return r, nil
 }
 
@@ -152,6 +166,7 @@ func (r *RedisStreams) scheduleAcks() {
 			r.ackSend[k] = v
 		}
 	}
+	r.ackPending = make(map[string][]string, len(r.conf.Streams))
 	r.aMut.Unlock()
 }
 
@@ -223,6 +238,88 @@ func (r *RedisStreams) Connect() error {
 	return nil
 }
 
+func (r *RedisStreams) appendParts(msg types.Message, xmsgs []redis.XMessage) {
+	for _, xmsg := range xmsgs {
+		body, exists := xmsg.Values[r.conf.BodyKey]
+		if !exists {
+			continue
+		}
+
+		var bodyBytes []byte
+		switch t := body.(type) {
+		case string:
+			bodyBytes = []byte(t)
+		case []byte:
+			bodyBytes = t
+		}
+		if bodyBytes == nil {
+			continue
+		}
+
+		part := message.NewPart(bodyBytes)
+		part.Metadata().Set("redis_stream", xmsg.ID)
+		for k, v := range xmsg.Values {
+			part.Metadata().Set(k, fmt.Sprintf("%v", v))
+		}
+
+		msg.Append(part)
+	}
+}
+
+// claimIdle attempts to claim entries that have remained pending for other
+// consumers of the group for longer than the claim min idle period, which
+// recovers entries that were delivered to consumers that have since died.
+func (r *RedisStreams) claimIdle(client *redis.Client) (types.Message, error) {
+	msg := message.New(nil)
+	for _, str := range r.conf.Streams {
+		pending, err := client.XPendingExt(&redis.XPendingExtArgs{
+			Stream: str,
+			Group:  r.conf.ConsumerGroup,
+			Start:  "-",
+			End:    "+",
+			Count:  r.conf.Limit,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+
+		var ids []string
+		for _, p := range pending {
+			if p.Consumer != r.conf.ClientID && p.Idle >= r.claimMinIdle {
+				ids = append(ids, p.Id)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		xmsgs, err := client.XClaim(&redis.XClaimArgs{
+			Stream:   str,
+			Group:    r.conf.ConsumerGroup,
+			Consumer: r.conf.ClientID,
+			MinIdle:  r.claimMinIdle,
+			Messages: ids,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		if len(xmsgs) == 0 {
+			continue
+		}
+
+		r.log.Debugf("Claimed %v pending entries of stream %v\n", len(xmsgs), str)
+		r.mClaimed.Incr(int64(len(xmsgs)))
+
+		claimedIDs := make([]string, 0, len(xmsgs))
+		for _, xmsg := range xmsgs {
+			claimedIDs = append(claimedIDs, xmsg.ID)
+		}
+		r.appendParts(msg, xmsgs)
+		r.addPendingAcks(str, claimedIDs...)
+	}
+	return msg, nil
+}
+
 // Read attempts to pop a message from a Redis list.
 func (r *RedisStreams) Read() (types.Message, error) {
 	var client *redis.Client
@@ -235,10 +332,20 @@ func (r *RedisStreams) Read() (types.Message, error) {
 		return nil, types.ErrNotConnected
 	}
 
+	if r.claimMinIdle > 0 && time.Since(r.lastClaim) >= r.claimMinIdle {
+		r.lastClaim = time.Now()
+		msg, err := r.claimIdle(client)
+		if err != nil {
+			r.log.Errorf("Failed to claim idle pending entries: %v\n", err)
+		} else if msg.Len() > 0 {
+			return msg, nil
+		}
+	}
+
 	strs := make([]string, len(r.conf.Streams)*2)
 	for i, str := range r.conf.Streams {
 		strs[i] = str
-		if bl := r.backlogs[str]; bl == "" {
+		if bl, exists := r.backlogs[str]; exists {
 			strs[len(r.conf.Streams)+i] = bl
 		} else {
 			strs[len(r.conf.Streams)+i] = ">"
@@ -274,31 +381,8 @@ func (r *RedisStreams) Read() (types.Message, error) {
 		ids := make([]string, 0, len(strRes.Messages))
 		for _, xmsg := range strRes.Messages {
 			ids = append(ids, xmsg.ID)
-
-			body, exists := xmsg.Values[r.conf.BodyKey]
-			if !exists {
-				continue
-			}
-
-			var bodyBytes []byte
-			switch t := body.(type) {
-			case string:
-				bodyBytes = []byte(t)
-			case []byte:
-				bodyBytes = t
-			}
-			if bodyBytes == nil {
-				continue
-			}
-
-			part := message.NewPart(bodyBytes)
-			part.Metadata().Set("redis_stream", xmsg.ID)
-			for k, v := range xmsg.Values {
-				part.Metadata().Set(k, fmt.Sprintf("%v", v))
-			}
-
-			msg.Append(part)
 		}
+		r.appendParts(msg, strRes.Messages)
 		r.addPendingAcks(strRes.Stream, ids...)
 	}
 
@@ -309,7 +393,8 @@ func (r *RedisStreams) Read() (types.Message, error) {
 	return msg, nil
 }
 
-// Acknowledge is a noop since Redis Lists do not support acknowledgements.
+// Acknowledge schedules the IDs of successfully propagated entries to be
+// acknowledged, acknowledgements are sent at most once per commit period.
 func (r *RedisStreams) Acknowledge(err error) error {
 	if err == nil {
 		r.scheduleAcks()
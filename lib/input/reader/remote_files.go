@@ -0,0 +1,359 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// RemoteFilesConfig contains configuration fields shared by inputs that poll a
+// directory of a remote file server.
+type RemoteFilesConfig struct {
+	Path         string `json:"path" yaml:"path"`
+	PollInterval string `json:"poll_interval" yaml:"poll_interval"`
+	SplitLines   bool   `json:"split_lines" yaml:"split_lines"`
+	Delim        string `json:"delimiter" yaml:"delimiter"`
+	AfterRead    string `json:"after_read" yaml:"after_read"`
+	MoveTo       string `json:"move_to" yaml:"move_to"`
+}
+
+// NewRemoteFilesConfig creates a new RemoteFilesConfig with default values.
+func NewRemoteFilesConfig() RemoteFilesConfig {
+	return RemoteFilesConfig{
+		Path:         "/",
+		PollInterval: "10s",
+		SplitLines:   false,
+		Delim:        "",
+		AfterRead:    "none",
+		MoveTo:       "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// remoteFile describes a file listed on a remote file server.
+type remoteFile struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// remoteFS is a connection to a remote file server.
+type remoteFS interface {
+	ReadDir(dir string) ([]remoteFile, error)
+	ReadFile(path string) ([]byte, error)
+	Remove(path string) error
+	Rename(from, to string) error
+	IsNotExist(err error) bool
+	Close() error
+}
+
+//------------------------------------------------------------------------------
+
+// RemoteFiles is an input type that polls a directory of a remote file server
+// and reads each new file as messages.
+type RemoteFiles struct {
+	typeStr string
+	conf    RemoteFilesConfig
+	dial    func() (remoteFS, error)
+
+	pollInterval time.Duration
+	delim        []byte
+
+	fsMut sync.Mutex
+	fs    remoteFS
+
+	lastPoll  time.Time
+	queue     []remoteFile
+	seen      map[string]string
+	current   *remoteFile
+	parts     [][]byte
+	finishing bool
+
+	log   log.Modular
+	stats metrics.Type
+
+	mFiles     metrics.StatCounter
+	mAfterErr  metrics.StatCounter
+	closeChan  chan struct{}
+	closeOnce  sync.Once
+	closedChan chan struct{}
+}
+
+func newRemoteFiles(
+	typeStr string,
+	conf RemoteFilesConfig,
+	dial func() (remoteFS, error),
+	log log.Modular,
+	stats metrics.Type,
+) (*RemoteFiles, error) {
+	r := &RemoteFiles{
+		typeStr:    typeStr,
+		conf:       conf,
+		dial:       dial,
+		delim:      []byte("\n"),
+		seen:       map[string]string{},
+		log:        log,
+		stats:      stats,
+		mFiles:     stats.GetCounter("files.read"),
+		mAfterErr:  stats.GetCounter("files.after_read.error"),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	switch conf.AfterRead {
+	case "none", "delete":
+	case "move":
+		if len(conf.MoveTo) == 0 {
+			return nil, errors.New("a move_to directory must be specified when after_read is move")
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised after_read action: %v", conf.AfterRead)
+	}
+	if len(conf.Delim) > 0 {
+		r.delim = []byte(conf.Delim)
+	}
+	if len(conf.PollInterval) > 0 {
+		var err error
+		if r.pollInterval, err = time.ParseDuration(conf.PollInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse poll interval string: %v", err)
+		}
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a connection to the remote file server.
+func (r *RemoteFiles) Connect() error {
+	r.fsMut.Lock()
+	defer r.fsMut.Unlock()
+
+	if r.fs != nil {
+		return nil
+	}
+	select {
+	case <-r.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	fs, err := r.dial()
+	if err != nil {
+		return err
+	}
+	r.fs = fs
+	r.log.Infof("Polling %v directory: %v\n", r.typeStr, r.conf.Path)
+	return nil
+}
+
+func (r *RemoteFiles) getFS() remoteFS {
+	r.fsMut.Lock()
+	fs := r.fs
+	r.fsMut.Unlock()
+	return fs
+}
+
+func (r *RemoteFiles) disconnect() {
+	r.fsMut.Lock()
+	if r.fs != nil {
+		r.fs.Close()
+		r.fs = nil
+	}
+	r.fsMut.Unlock()
+}
+
+func fileVersion(f remoteFile) string {
+	return strconv.FormatInt(f.ModTime.UnixNano(), 10) + ":" + strconv.FormatInt(f.Size, 10)
+}
+
+// poll lists the target directory and queues files that have not yet been
+// read, oldest first.
+func (r *RemoteFiles) poll(fs remoteFS) error {
+	if wait := r.pollInterval - time.Since(r.lastPoll); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-r.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+	r.lastPoll = time.Now()
+
+	files, err := fs.ReadDir(r.conf.Path)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir {
+			continue
+		}
+		if v, exists := r.seen[f.Name]; exists && v == fileVersion(f) {
+			continue
+		}
+		r.queue = append(r.queue, f)
+	}
+	sort.SliceStable(r.queue, func(i, j int) bool {
+		return r.queue[i].ModTime.Before(r.queue[j].ModTime)
+	})
+	return nil
+}
+
+// finish performs the after read action of the current file.
+func (r *RemoteFiles) finish(fs remoteFS) {
+	f := r.current
+	r.current = nil
+	r.finishing = false
+	if f == nil {
+		return
+	}
+
+	filePath := path.Join(r.conf.Path, f.Name)
+	var err error
+	switch r.conf.AfterRead {
+	case "delete":
+		err = fs.Remove(filePath)
+	case "move":
+		err = fs.Rename(filePath, path.Join(r.conf.MoveTo, f.Name))
+	}
+	if err != nil {
+		r.mAfterErr.Incr(1)
+		r.log.Errorf("Failed to %v file '%v' after reading: %v\n", r.conf.AfterRead, filePath, err)
+	}
+	r.seen[f.Name] = fileVersion(*f)
+}
+
+// Read attempts to read a message from the current file, or the next new file
+// of the target directory.
+func (r *RemoteFiles) Read() (types.Message, error) {
+	fs := r.getFS()
+	if fs == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	for len(r.parts) == 0 {
+		if len(r.queue) == 0 {
+			if err := r.poll(fs); err != nil {
+				if err == types.ErrTypeClosed {
+					return nil, err
+				}
+				r.log.Errorf("Failed to list %v directory: %v\n", r.typeStr, err)
+				r.disconnect()
+				return nil, types.ErrNotConnected
+			}
+			if len(r.queue) == 0 {
+				return nil, types.ErrTimeout
+			}
+		}
+
+		f := r.queue[0]
+		r.queue = r.queue[1:]
+
+		filePath := path.Join(r.conf.Path, f.Name)
+		data, err := fs.ReadFile(filePath)
+		if err != nil {
+			if fs.IsNotExist(err) {
+				continue
+			}
+			r.log.Errorf("Failed to read file '%v': %v\n", filePath, err)
+			r.disconnect()
+			return nil, types.ErrNotConnected
+		}
+		r.mFiles.Incr(1)
+		r.current = &f
+
+		if r.conf.SplitLines {
+			for _, line := range bytes.Split(data, r.delim) {
+				if len(line) > 0 {
+					r.parts = append(r.parts, line)
+				}
+			}
+		} else {
+			r.parts = [][]byte{data}
+		}
+		if len(r.parts) == 0 {
+			// Files without content are finished immediately.
+			r.finish(fs)
+		}
+	}
+
+	msg := message.New([][]byte{r.parts[0]})
+	r.parts = r.parts[1:]
+	r.finishing = len(r.parts) == 0
+
+	meta := msg.Get(0).Metadata()
+	meta.Set("remote_file_path", path.Join(r.conf.Path, r.current.Name))
+	meta.Set("remote_file_name", r.current.Name)
+	meta.Set("remote_file_size", strconv.FormatInt(r.current.Size, 10))
+	if !r.current.ModTime.IsZero() {
+		meta.Set("remote_file_mod_time_unix", strconv.FormatInt(r.current.ModTime.Unix(), 10))
+	}
+	return msg, nil
+}
+
+// Acknowledge instructs whether the pending messages were propagated
+// successfully, once the last message of a file is acknowledged the after read
+// action of the file is performed.
+func (r *RemoteFiles) Acknowledge(err error) error {
+	if err != nil || !r.finishing {
+		return nil
+	}
+	fs := r.getFS()
+	if fs == nil {
+		return types.ErrNotConnected
+	}
+	r.finish(fs)
+	return nil
+}
+
+// CloseAsync shuts down the input and stops processing requests.
+func (r *RemoteFiles) CloseAsync() {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+		r.disconnect()
+		close(r.closedChan)
+	})
+}
+
+// WaitForClose blocks until the input has closed down.
+func (r *RemoteFiles) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
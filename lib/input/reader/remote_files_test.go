@@ -0,0 +1,257 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+	"path"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+var errMockNotExist = errors.New("file does not exist")
+
+type mockRemoteFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+type mockRemoteFS struct {
+	sync.Mutex
+	files map[string]mockRemoteFile
+}
+
+func (m *mockRemoteFS) ReadDir(dir string) ([]remoteFile, error) {
+	m.Lock()
+	defer m.Unlock()
+	var files []remoteFile
+	for p, f := range m.files {
+		if path.Dir(p) == dir {
+			files = append(files, remoteFile{
+				Name:    path.Base(p),
+				Size:    int64(len(f.data)),
+				ModTime: f.modTime,
+			})
+		}
+	}
+	return files, nil
+}
+
+func (m *mockRemoteFS) ReadFile(p string) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+	f, exists := m.files[p]
+	if !exists {
+		return nil, errMockNotExist
+	}
+	return f.data, nil
+}
+
+func (m *mockRemoteFS) Remove(p string) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, exists := m.files[p]; !exists {
+		return errMockNotExist
+	}
+	delete(m.files, p)
+	return nil
+}
+
+func (m *mockRemoteFS) Rename(from, to string) error {
+	m.Lock()
+	defer m.Unlock()
+	f, exists := m.files[from]
+	if !exists {
+		return errMockNotExist
+	}
+	delete(m.files, from)
+	m.files[to] = f
+	return nil
+}
+
+func (m *mockRemoteFS) IsNotExist(err error) bool {
+	return err == errMockNotExist
+}
+
+func (m *mockRemoteFS) Close() error {
+	return nil
+}
+
+func (m *mockRemoteFS) paths() []string {
+	m.Lock()
+	defer m.Unlock()
+	var paths []string
+	for p := range m.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func newTestRemoteFiles(t *testing.T, conf RemoteFilesConfig, fs *mockRemoteFS) *RemoteFiles {
+	t.Helper()
+	r, err := newRemoteFiles("mock", conf, func() (remoteFS, error) {
+		return fs, nil
+	}, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func readAllRemote(t *testing.T, r *RemoteFiles) []string {
+	t.Helper()
+	var results []string
+	for {
+		msg, err := r.Read()
+		if err == types.ErrTimeout {
+			return results
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, string(msg.Get(0).Get())+"@"+msg.Get(0).Metadata().Get("remote_file_path"))
+		if err = r.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRemoteFilesSplitLinesDelete(t *testing.T) {
+	now := time.Now()
+	fs := &mockRemoteFS{files: map[string]mockRemoteFile{
+		"/in/b.txt":    {data: []byte("baz\n"), modTime: now},
+		"/in/a.txt":    {data: []byte("foo\nbar\n"), modTime: now.Add(-time.Minute)},
+		"/in/empty":    {data: []byte{}, modTime: now.Add(-time.Hour)},
+		"/other/c.txt": {data: []byte("nope"), modTime: now},
+	}}
+
+	conf := NewRemoteFilesConfig()
+	conf.Path = "/in"
+	conf.PollInterval = "1ms"
+	conf.SplitLines = true
+	conf.AfterRead = "delete"
+	r := newTestRemoteFiles(t, conf, fs)
+
+	exp := []string{"foo@/in/a.txt", "bar@/in/a.txt", "baz@/in/b.txt"}
+	if act := readAllRemote(t, r); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %v != %v", act, exp)
+	}
+	if exp, act := []string{"/other/c.txt"}, fs.paths(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong remaining files: %v != %v", act, exp)
+	}
+}
+
+func TestRemoteFilesMoveAfterAck(t *testing.T) {
+	fs := &mockRemoteFS{files: map[string]mockRemoteFile{
+		"/in/a.txt": {data: []byte("foo\nbar"), modTime: time.Now()},
+	}}
+
+	conf := NewRemoteFilesConfig()
+	conf.Path = "/in"
+	conf.PollInterval = "1ms"
+	conf.SplitLines = true
+	conf.AfterRead = "move"
+	conf.MoveTo = "/done"
+	r := newTestRemoteFiles(t, conf, fs)
+
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed messages do not complete the file.
+	if err := r.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"/in/a.txt"}, fs.paths(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+
+	if err := r.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"/done/a.txt"}, fs.paths(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong files: %v != %v", act, exp)
+	}
+}
+
+func TestRemoteFilesNoneTracksSeen(t *testing.T) {
+	modTime := time.Now()
+	fs := &mockRemoteFS{files: map[string]mockRemoteFile{
+		"/a.txt": {data: []byte("foo"), modTime: modTime},
+	}}
+
+	conf := NewRemoteFilesConfig()
+	conf.PollInterval = "1ms"
+	r := newTestRemoteFiles(t, conf, fs)
+
+	if exp, act := []string{"foo@/a.txt"}, readAllRemote(t, r); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %v != %v", act, exp)
+	}
+	if act := readAllRemote(t, r); len(act) > 0 {
+		t.Errorf("Unexpected results: %v", act)
+	}
+
+	fs.Lock()
+	fs.files["/a.txt"] = mockRemoteFile{data: []byte("foo bar"), modTime: modTime.Add(time.Second)}
+	fs.Unlock()
+	if exp, act := []string{"foo bar@/a.txt"}, readAllRemote(t, r); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong results: %v != %v", act, exp)
+	}
+
+	r.CloseAsync()
+	if err := r.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+	if err := r.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestRemoteFilesBadConfig(t *testing.T) {
+	conf := NewRemoteFilesConfig()
+	conf.AfterRead = "nope"
+	if _, err := newRemoteFiles("mock", conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad after read action")
+	}
+
+	conf = NewRemoteFilesConfig()
+	conf.AfterRead = "move"
+	if _, err := newRemoteFiles("mock", conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty move to")
+	}
+}
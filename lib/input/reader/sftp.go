@@ -0,0 +1,111 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/sftp"
+)
+
+//------------------------------------------------------------------------------
+
+// SFTPConfig contains configuration for the SFTP input type.
+type SFTPConfig struct {
+	Address           string `json:"address" yaml:"address"`
+	User              string `json:"user" yaml:"user"`
+	Password          string `json:"password" yaml:"password"`
+	PrivateKeyFile    string `json:"private_key_file" yaml:"private_key_file"`
+	KnownHostsFile    string `json:"known_hosts_file" yaml:"known_hosts_file"`
+	Timeout           string `json:"timeout" yaml:"timeout"`
+	RemoteFilesConfig `json:",inline" yaml:",inline"`
+}
+
+// NewSFTPConfig creates a new SFTPConfig with default values.
+func NewSFTPConfig() SFTPConfig {
+	return SFTPConfig{
+		Address:           "localhost:22",
+		User:              "",
+		Password:          "",
+		PrivateKeyFile:    "",
+		KnownHostsFile:    "",
+		Timeout:           "30s",
+		RemoteFilesConfig: NewRemoteFilesConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type sftpFS struct {
+	*sftp.Client
+}
+
+func (s sftpFS) ReadDir(dir string) ([]remoteFile, error) {
+	infos, err := s.Client.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]remoteFile, 0, len(infos))
+	for _, info := range infos {
+		files = append(files, remoteFile{
+			Name:    info.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   !info.Mode().IsRegular(),
+		})
+	}
+	return files, nil
+}
+
+func (s sftpFS) IsNotExist(err error) bool {
+	return sftp.IsNotExist(err)
+}
+
+//------------------------------------------------------------------------------
+
+// NewSFTP creates a new SFTP input type.
+func NewSFTP(conf SFTPConfig, log log.Modular, stats metrics.Type) (*RemoteFiles, error) {
+	sshConf, err := sftp.SSHConfig(conf.User, conf.Password, conf.PrivateKeyFile, conf.KnownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.KnownHostsFile) == 0 {
+		log.Warnln("No known hosts file specified, the host key of the SFTP server will not be verified")
+	}
+	if len(conf.Timeout) > 0 {
+		if sshConf.Timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	return newRemoteFiles("sftp", conf.RemoteFilesConfig, func() (remoteFS, error) {
+		client, err := sftp.Dial(conf.Address, sshConf)
+		if err != nil {
+			return nil, err
+		}
+		return sftpFS{Client: client}, nil
+	}, log, stats)
+}
+
+//------------------------------------------------------------------------------
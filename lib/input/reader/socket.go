@@ -0,0 +1,277 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// SocketConfig contains configuration fields for the Socket input type.
+type SocketConfig struct {
+	Network   string `json:"network" yaml:"network"`
+	Address   string `json:"address" yaml:"address"`
+	Multipart bool   `json:"multipart" yaml:"multipart"`
+	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
+	Delim     string `json:"delimiter" yaml:"delimiter"`
+}
+
+// NewSocketConfig creates a new SocketConfig with default values.
+func NewSocketConfig() SocketConfig {
+	return SocketConfig{
+		Network:   "unix",
+		Address:   "/tmp/benthos.sock",
+		Multipart: false,
+		MaxBuffer: 1000000,
+		Delim:     "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Socket is an input type that listens on a unix domain socket or TCP
+// address and reads delimited messages from each connection.
+type Socket struct {
+	conf SocketConfig
+
+	cMut     sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	addr     net.Addr
+
+	msgs chan types.Message
+
+	log   log.Modular
+	stats metrics.Type
+
+	mConns   metrics.StatCounter
+	mConnErr metrics.StatCounter
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewSocket creates a new Socket input type.
+func NewSocket(conf SocketConfig, log log.Modular, stats metrics.Type) (*Socket, error) {
+	switch conf.Network {
+	case "unix", "tcp":
+	default:
+		return nil, fmt.Errorf("unrecognised network: %v", conf.Network)
+	}
+	if len(conf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+	return &Socket{
+		conf:       conf,
+		conns:      map[net.Conn]struct{}{},
+		msgs:       make(chan types.Message),
+		log:        log,
+		stats:      stats,
+		mConns:     stats.GetCounter("connections"),
+		mConnErr:   stats.GetCounter("connection.error"),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect begins listening for connections.
+func (s *Socket) Connect() error {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+
+	select {
+	case <-s.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+	if s.listener != nil {
+		return nil
+	}
+
+	if s.conf.Network == "unix" {
+		// Remove a stale socket file left behind by a previous run.
+		if info, err := os.Stat(s.conf.Address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if conn, err := net.Dial("unix", s.conf.Address); err != nil {
+				os.Remove(s.conf.Address)
+			} else {
+				conn.Close()
+			}
+		}
+	}
+
+	listener, err := net.Listen(s.conf.Network, s.conf.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.addr = listener.Addr()
+	go s.acceptLoop(listener)
+
+	s.log.Infof("Receiving %v socket messages at: %v\n", s.conf.Network, s.addr)
+	return nil
+}
+
+func (s *Socket) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closeChan:
+			default:
+				s.log.Errorf("Failed to accept connection: %v\n", err)
+			}
+			return
+		}
+		s.cMut.Lock()
+		s.conns[conn] = struct{}{}
+		s.cMut.Unlock()
+		s.mConns.Incr(1)
+		go s.connLoop(conn)
+	}
+}
+
+// connLoop reads messages from a connection until it is closed.
+func (s *Socket) connLoop(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.cMut.Lock()
+		delete(s.conns, conn)
+		s.cMut.Unlock()
+	}()
+
+	delim := s.conf.Delim
+	if len(delim) == 0 {
+		delim = "\n"
+	}
+
+	var handle io.Reader = conn
+	lines, err := NewLines(
+		func() (io.Reader, error) {
+			if handle == nil {
+				return nil, io.EOF
+			}
+			h := handle
+			handle = nil
+			return h, nil
+		},
+		func() {},
+		OptLinesSetDelimiter(delim),
+		OptLinesSetMaxBuffer(s.conf.MaxBuffer),
+		OptLinesSetMultipart(s.conf.Multipart),
+	)
+	if err != nil {
+		s.log.Errorf("Failed to create connection reader: %v\n", err)
+		return
+	}
+	if err = lines.Connect(); err != nil {
+		return
+	}
+
+	for {
+		msg, err := lines.Read()
+		if err != nil {
+			if err != types.ErrNotConnected {
+				select {
+				case <-s.closeChan:
+				default:
+					s.mConnErr.Incr(1)
+					s.log.Errorf("Failed to read from connection: %v\n", err)
+				}
+			}
+			return
+		}
+
+		// Parts reference the buffer of the lines reader, which is reset once
+		// acknowledged.
+		msg = msg.DeepCopy()
+		lines.Acknowledge(nil)
+
+		select {
+		case s.msgs <- msg:
+		case <-s.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Read attempts to read a new message from any of the connections.
+func (s *Socket) Read() (types.Message, error) {
+	select {
+	case msg := <-s.msgs:
+		return msg, nil
+	case <-time.After(time.Second):
+		return nil, types.ErrTimeout
+	case <-s.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+}
+
+// Acknowledge is a noop as messages read from sockets cannot be acknowledged.
+func (s *Socket) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the Socket input and stops processing requests.
+func (s *Socket) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+
+		s.cMut.Lock()
+		if s.listener != nil {
+			// Closing a unix listener also removes its socket file.
+			s.listener.Close()
+		}
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.cMut.Unlock()
+
+		close(s.closedChan)
+	})
+}
+
+// WaitForClose blocks until the Socket input has closed down.
+func (s *Socket) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-s.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,163 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func readSocket(t *testing.T, s *Socket) types.Message {
+	t.Helper()
+	for i := 0; i < 5; i++ {
+		msg, err := s.Read()
+		if err == types.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = s.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	t.Fatal("Timed out waiting for message")
+	return nil
+}
+
+func TestSocketUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_socket_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewSocketConfig()
+	conf.Address = filepath.Join(dir, "benthos.sock")
+
+	// Leave a stale socket file behind.
+	stale, err := net.Listen("unix", conf.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	s, err := NewSocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	var conns []net.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("unix", conf.Address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+	if _, err = conns[0].Write([]byte("foo\nbar\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conns[1].Write([]byte("baz")); err != nil {
+		t.Fatal(err)
+	}
+	conns[1].Close()
+
+	var results []string
+	for i := 0; i < 3; i++ {
+		results = append(results, string(readSocket(t, s).Get(0).Get()))
+	}
+	sort.Strings(results)
+	if exp := []string{"bar", "baz", "foo"}; !reflect.DeepEqual(exp, results) {
+		t.Errorf("Wrong results: %v != %v", results, exp)
+	}
+
+	conns[0].Close()
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+	if _, err = os.Stat(conf.Address); !os.IsNotExist(err) {
+		t.Errorf("Expected socket file to be removed: %v", err)
+	}
+}
+
+func TestSocketTCPMultipart(t *testing.T) {
+	conf := NewSocketConfig()
+	conf.Network = "tcp"
+	conf.Address = "127.0.0.1:0"
+	conf.Multipart = true
+	conf.Delim = "|"
+
+	s, err := NewSocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	conn, err := net.Dial("tcp", s.addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("foo|bar||baz||")); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(readSocket(t, s)); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if exp, act := [][]byte{[]byte("baz")}, message.GetAllBytes(readSocket(t, s)); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+}
+
+func TestSocketBadConfig(t *testing.T) {
+	conf := NewSocketConfig()
+	conf.Network = "udp"
+	if _, err := NewSocket(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad network")
+	}
+}
+
+//------------------------------------------------------------------------------
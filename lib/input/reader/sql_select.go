@@ -0,0 +1,361 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+
+	// SQL drivers supported by the sql_select input.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLSelectConfig contains configuration fields for the SQLSelect input type.
+type SQLSelectConfig struct {
+	Driver        string `json:"driver" yaml:"driver"`
+	DSN           string `json:"dsn" yaml:"dsn"`
+	Query         string `json:"query" yaml:"query"`
+	CursorColumn  string `json:"cursor_column" yaml:"cursor_column"`
+	InitialCursor string `json:"initial_cursor" yaml:"initial_cursor"`
+	Cache         string `json:"cache" yaml:"cache"`
+	CacheKey      string `json:"cache_key" yaml:"cache_key"`
+	Interval      string `json:"interval" yaml:"interval"`
+}
+
+// NewSQLSelectConfig creates a new SQLSelectConfig with default values.
+func NewSQLSelectConfig() SQLSelectConfig {
+	return SQLSelectConfig{
+		Driver:        "postgres",
+		DSN:           "",
+		Query:         "",
+		CursorColumn:  "id",
+		InitialCursor: "0",
+		Cache:         "",
+		CacheKey:      "sql_select_cursor",
+		Interval:      "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SQLSelect is a reader that periodically runs a SELECT query against a SQL
+// database, using the value of a monotonically increasing column from the last
+// row it emitted as a parameter in order to only receive new rows.
+type SQLSelect struct {
+	conf     SQLSelectConfig
+	interval time.Duration
+	cache    types.Cache
+
+	dbMut sync.Mutex
+	db    *sql.DB
+
+	cursor        string
+	cursorLoaded  bool
+	pendingCursor string
+	pending       []map[string]interface{}
+	pendingCurs   []string
+	nextPoll      time.Time
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mPolls       metrics.StatCounter
+	mPollErr     metrics.StatCounter
+	mRows        metrics.StatCounter
+	mCommitErr   metrics.StatCounter
+	mCursorReset metrics.StatCounter
+}
+
+// NewSQLSelect creates a new SQLSelect reader type.
+func NewSQLSelect(
+	conf SQLSelectConfig,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (*SQLSelect, error) {
+	if conf.Driver != "postgres" && conf.Driver != "mysql" {
+		return nil, fmt.Errorf("unsupported driver: %v", conf.Driver)
+	}
+	if len(conf.Query) == 0 {
+		return nil, errors.New("a query must be specified")
+	}
+	if len(conf.CursorColumn) == 0 {
+		return nil, errors.New("a cursor column must be specified")
+	}
+
+	var interval time.Duration
+	if tout := conf.Interval; len(tout) > 0 {
+		var err error
+		if interval, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse interval string: %v", err)
+		}
+	}
+
+	s := &SQLSelect{
+		conf:       conf,
+		interval:   interval,
+		cursor:     conf.InitialCursor,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log,
+		stats:      stats,
+
+		mPolls:       stats.GetCounter("sql_select.polls"),
+		mPollErr:     stats.GetCounter("sql_select.polls.error"),
+		mRows:        stats.GetCounter("sql_select.rows"),
+		mCommitErr:   stats.GetCounter("sql_select.cursor.commit.error"),
+		mCursorReset: stats.GetCounter("sql_select.cursor.initial"),
+	}
+
+	if len(conf.Cache) > 0 {
+		var err error
+		if s.cache, err = mgr.GetCache(conf.Cache); err != nil {
+			return nil, fmt.Errorf("failed to obtain cache '%v': %v", conf.Cache, err)
+		}
+	} else {
+		s.cursorLoaded = true
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to open a connection pool to the target database and load
+// the last committed cursor.
+func (s *SQLSelect) Connect() error {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+	select {
+	case <-s.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	if !s.cursorLoaded {
+		cursor, err := s.cache.Get(s.conf.CacheKey)
+		if err == nil {
+			s.cursor = string(cursor)
+		} else if err == types.ErrKeyNotFound {
+			s.mCursorReset.Incr(1)
+		} else {
+			return fmt.Errorf("failed to load cursor from cache: %v", err)
+		}
+		s.cursorLoaded = true
+	}
+
+	db, err := sql.Open(s.conf.Driver, s.conf.DSN)
+	if err != nil {
+		return err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+	s.db = db
+
+	s.log.Infof("Polling %v database from cursor '%v'\n", s.conf.Driver, s.cursor)
+	return nil
+}
+
+func (s *SQLSelect) formatCursor(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(t)
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		if s.conf.Driver == "mysql" {
+			return t.Format("2006-01-02 15:04:05.999999")
+		}
+		return t.Format("2006-01-02 15:04:05.999999-07:00")
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func (s *SQLSelect) poll(db *sql.DB) error {
+	rows, err := db.Query(s.conf.Query, s.cursor)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	cursorIndex := -1
+	for i, c := range cols {
+		if c == s.conf.CursorColumn {
+			cursorIndex = i
+		}
+	}
+	if cursorIndex < 0 {
+		return fmt.Errorf("cursor column '%v' not found in query results", s.conf.CursorColumn)
+	}
+
+	values := make([]interface{}, len(cols))
+	valuePtrs := make([]interface{}, len(cols))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(valuePtrs...); err != nil {
+			return err
+		}
+		row := make(map[string]interface{}, len(cols))
+		for i, c := range cols {
+			if b, ok := values[i].([]byte); ok {
+				row[c] = string(b)
+			} else {
+				row[c] = values[i]
+			}
+		}
+		s.pending = append(s.pending, row)
+		s.pendingCurs = append(s.pendingCurs, s.formatCursor(values[cursorIndex]))
+	}
+	return rows.Err()
+}
+
+// Read attempts to read a row from the database as a JSON message, polling for
+// new rows if the previous results are exhausted.
+func (s *SQLSelect) Read() (types.Message, error) {
+	s.dbMut.Lock()
+	db := s.db
+	s.dbMut.Unlock()
+
+	if db == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	if len(s.pending) == 0 {
+		if wait := time.Until(s.nextPoll); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-s.closeChan:
+				timer.Stop()
+				return nil, types.ErrTypeClosed
+			}
+		}
+
+		s.mPolls.Incr(1)
+		if err := s.poll(db); err != nil {
+			s.mPollErr.Incr(1)
+			s.pending, s.pendingCurs = nil, nil
+			s.nextPoll = time.Now().Add(s.interval)
+			return nil, fmt.Errorf("failed to poll database: %v", err)
+		}
+		if len(s.pending) == 0 {
+			s.nextPoll = time.Now().Add(s.interval)
+			return nil, types.ErrTimeout
+		}
+	}
+
+	row, cursor := s.pending[0], s.pendingCurs[0]
+	s.pending, s.pendingCurs = s.pending[1:], s.pendingCurs[1:]
+
+	rowBytes, err := json.Marshal(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialise row: %v", err)
+	}
+	s.mRows.Incr(1)
+	s.pendingCursor = cursor
+
+	part := message.NewPart(rowBytes)
+	part.Metadata().Set("sql_cursor", cursor)
+	msg := message.New(nil)
+	msg.Append(part)
+	return msg, nil
+}
+
+// Acknowledge commits the cursor of the last read row once it has been
+// successfully propagated.
+func (s *SQLSelect) Acknowledge(err error) error {
+	if err != nil || len(s.pendingCursor) == 0 {
+		return nil
+	}
+	s.cursor = s.pendingCursor
+	s.pendingCursor = ""
+	if s.cache != nil {
+		if cerr := s.cache.Set(s.conf.CacheKey, []byte(s.cursor)); cerr != nil {
+			s.mCommitErr.Incr(1)
+			return fmt.Errorf("failed to commit cursor: %v", cerr)
+		}
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (s *SQLSelect) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+		go func() {
+			s.dbMut.Lock()
+			if s.db != nil {
+				s.db.Close()
+				s.db = nil
+			}
+			s.dbMut.Unlock()
+			close(s.closedChan)
+		}()
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (s *SQLSelect) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-s.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,304 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/cache"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/manager"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// fakeSQLDriver serves an in-memory table for any query, returning rows with
+// an id greater than the first query argument.
+type fakeSQLDriver struct {
+	mut  sync.Mutex
+	rows [][]driver.Value
+	args []string
+}
+
+var fakeSQL = &fakeSQLDriver{}
+
+func init() {
+	sql.Register("sql_select_fake", fakeSQL)
+}
+
+func (d *fakeSQLDriver) set(rows [][]driver.Value) {
+	d.mut.Lock()
+	d.rows = rows
+	d.args = nil
+	d.mut.Unlock()
+}
+
+func (d *fakeSQLDriver) Open(name string) (driver.Conn, error) {
+	return &fakeSQLConn{d: d}, nil
+}
+
+type fakeSQLConn struct {
+	d *fakeSQLDriver
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{d: c.d}, nil
+}
+
+func (c *fakeSQLConn) Close() error {
+	return nil
+}
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeSQLStmt struct {
+	d *fakeSQLDriver
+}
+
+func (s *fakeSQLStmt) Close() error {
+	return nil
+}
+
+func (s *fakeSQLStmt) NumInput() int {
+	return 1
+}
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	cursor, err := strconv.ParseInt(args[0].(string), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	s.d.mut.Lock()
+	defer s.d.mut.Unlock()
+
+	s.d.args = append(s.d.args, args[0].(string))
+	res := &fakeSQLRows{}
+	for _, r := range s.d.rows {
+		if r[0].(int64) > cursor {
+			res.rows = append(res.rows, r)
+		}
+	}
+	return res, nil
+}
+
+type fakeSQLRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string {
+	return []string{"id", "name", "score"}
+}
+
+func (r *fakeSQLRows) Close() error {
+	return nil
+}
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func newSQLSelectTestMgr(t *testing.T) types.Manager {
+	t.Helper()
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["cursors"] = cache.NewConfig()
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mgr
+}
+
+func TestSQLSelectCursor(t *testing.T) {
+	fakeSQL.set([][]driver.Value{
+		{int64(1), []byte("foo"), 1.5},
+		{int64(2), []byte("bar"), nil},
+	})
+
+	mgr := newSQLSelectTestMgr(t)
+
+	conf := NewSQLSelectConfig()
+	conf.Driver = "postgres"
+	conf.Query = "SELECT id, name, score FROM things WHERE id > $1 ORDER BY id"
+	conf.Cache = "cursors"
+	conf.Interval = "10ms"
+
+	s, err := NewSQLSelect(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.conf.Driver = "sql_select_fake"
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	readRow := func() string {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			msg, err := s.Read()
+			if err == types.ErrTimeout {
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = s.Acknowledge(nil); err != nil {
+				t.Fatal(err)
+			}
+			return string(msg.Get(0).Get())
+		}
+		t.Fatal("Timed out waiting for row")
+		return ""
+	}
+
+	if exp, act := `{"id":1,"name":"foo","score":1.5}`, readRow(); exp != act {
+		t.Errorf("Wrong row: %v != %v", act, exp)
+	}
+	if exp, act := `{"id":2,"name":"bar","score":null}`, readRow(); exp != act {
+		t.Errorf("Wrong row: %v != %v", act, exp)
+	}
+
+	c, _ := mgr.GetCache("cursors")
+	if cursor, err := c.Get("sql_select_cursor"); err != nil {
+		t.Error(err)
+	} else if exp, act := "2", string(cursor); exp != act {
+		t.Errorf("Wrong committed cursor: %v != %v", act, exp)
+	}
+
+	fakeSQL.mut.Lock()
+	fakeSQL.rows = append(fakeSQL.rows, []driver.Value{int64(3), []byte("baz"), 3.0})
+	fakeSQL.mut.Unlock()
+
+	if exp, act := `{"id":3,"name":"baz","score":3}`, readRow(); exp != act {
+		t.Errorf("Wrong row: %v != %v", act, exp)
+	}
+
+	fakeSQL.mut.Lock()
+	args := fakeSQL.args
+	fakeSQL.mut.Unlock()
+	if len(args) < 2 || args[0] != "0" || args[len(args)-1] != "2" {
+		t.Errorf("Unexpected query cursors: %v", args)
+	}
+}
+
+func TestSQLSelectResumeFromCache(t *testing.T) {
+	fakeSQL.set([][]driver.Value{
+		{int64(5), []byte("foo"), nil},
+		{int64(6), []byte("bar"), nil},
+	})
+
+	mgr := newSQLSelectTestMgr(t)
+	c, _ := mgr.GetCache("cursors")
+	if err := c.Set("sql_select_cursor", []byte("5")); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewSQLSelectConfig()
+	conf.Query = "SELECT id, name, score FROM things WHERE id > $1 ORDER BY id"
+	conf.Cache = "cursors"
+
+	s, err := NewSQLSelect(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.conf.Driver = "sql_select_fake"
+	defer s.CloseAsync()
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"id":6,"name":"bar","score":null}`, string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong row: %v != %v", act, exp)
+	}
+	if exp, act := "6", msg.Get(0).Metadata().Get("sql_cursor"); exp != act {
+		t.Errorf("Wrong cursor metadata: %v != %v", act, exp)
+	}
+
+	// Without an acknowledgement the cursor is not committed.
+	if cursor, _ := c.Get("sql_select_cursor"); string(cursor) != "5" {
+		t.Errorf("Cursor committed before acknowledgement: %s", cursor)
+	}
+	if err = s.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+	if cursor, _ := c.Get("sql_select_cursor"); string(cursor) != "5" {
+		t.Errorf("Cursor committed after failed acknowledgement: %s", cursor)
+	}
+}
+
+func TestSQLSelectBadConfig(t *testing.T) {
+	mgr := newSQLSelectTestMgr(t)
+
+	conf := NewSQLSelectConfig()
+	conf.Driver = "oracle"
+	conf.Query = "SELECT 1"
+	if _, err := NewSQLSelect(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unsupported driver")
+	}
+
+	conf = NewSQLSelectConfig()
+	if _, err := NewSQLSelect(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing query")
+	}
+
+	conf = NewSQLSelectConfig()
+	conf.Query = "SELECT 1"
+	conf.Cache = "nope"
+	if _, err := NewSQLSelect(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing cache")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,300 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/go-stomp/stomp/v3"
+	"github.com/go-stomp/stomp/v3/frame"
+)
+
+//------------------------------------------------------------------------------
+
+// STOMPConfig contains configuration fields for the STOMP input type.
+type STOMPConfig struct {
+	Address     string            `json:"address" yaml:"address"`
+	Host        string            `json:"host" yaml:"host"`
+	Login       string            `json:"login" yaml:"login"`
+	Passcode    string            `json:"passcode" yaml:"passcode"`
+	Destination string            `json:"destination" yaml:"destination"`
+	Headers     map[string]string `json:"headers" yaml:"headers"`
+	TLS         btls.Config       `json:"tls" yaml:"tls"`
+	Timeout     string            `json:"timeout" yaml:"timeout"`
+}
+
+// NewSTOMPConfig creates a new STOMPConfig with default values.
+func NewSTOMPConfig() STOMPConfig {
+	return STOMPConfig{
+		Address:     "localhost:61613",
+		Host:        "",
+		Login:       "",
+		Passcode:    "",
+		Destination: "/queue/benthos",
+		Headers:     map[string]string{},
+		TLS:         btls.NewConfig(),
+		Timeout:     "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// STOMP is an input type that subscribes to a destination of a STOMP server
+// using the client-individual ack mode.
+type STOMP struct {
+	conf    STOMPConfig
+	tlsConf *tls.Config
+	timeout time.Duration
+
+	cMut sync.Mutex
+	conn *stomp.Conn
+	sub  *stomp.Subscription
+
+	pendingMut sync.Mutex
+	pending    []*stomp.Message
+
+	log   log.Modular
+	stats metrics.Type
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewSTOMP creates a new STOMP input type.
+func NewSTOMP(conf STOMPConfig, log log.Modular, stats metrics.Type) (*STOMP, error) {
+	s := &STOMP{
+		conf:      conf,
+		log:       log,
+		stats:     stats,
+		closeChan: make(chan struct{}),
+	}
+	if len(conf.Destination) == 0 {
+		return nil, errors.New("a destination must be specified")
+	}
+	var err error
+	if tout := conf.Timeout; len(tout) > 0 {
+		if s.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		if s.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// dial opens a network connection to the STOMP server, using TLS when
+// configured.
+func (s *STOMP) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.timeout}
+	if s.tlsConf == nil {
+		return dialer.Dial("tcp", s.conf.Address)
+	}
+	tlsConf := s.tlsConf
+	if len(tlsConf.ServerName) == 0 {
+		host, _, err := net.SplitHostPort(s.conf.Address)
+		if err != nil {
+			return nil, err
+		}
+		tlsConf = tlsConf.Clone()
+		tlsConf.ServerName = host
+	}
+	return tls.DialWithDialer(dialer, "tcp", s.conf.Address, tlsConf)
+}
+
+// Connect establishes a connection to the STOMP server and subscribes to the
+// destination.
+func (s *STOMP) Connect() error {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+
+	if s.conn != nil {
+		return nil
+	}
+
+	netConn, err := s.dial()
+	if err != nil {
+		return err
+	}
+
+	opts := []func(*stomp.Conn) error{
+		stomp.ConnOpt.AcceptVersion(stomp.V11, stomp.V12),
+		stomp.ConnOpt.HeartBeat(0, 0),
+	}
+	if len(s.conf.Host) > 0 {
+		opts = append(opts, stomp.ConnOpt.Host(s.conf.Host))
+	}
+	if len(s.conf.Login) > 0 {
+		opts = append(opts, stomp.ConnOpt.Login(s.conf.Login, s.conf.Passcode))
+	}
+
+	if s.timeout > 0 {
+		netConn.SetDeadline(time.Now().Add(s.timeout))
+	}
+	conn, err := stomp.Connect(netConn, opts...)
+	if err != nil {
+		netConn.Close()
+		return err
+	}
+	netConn.SetDeadline(time.Time{})
+
+	var subOpts []func(*frame.Frame) error
+	for k, v := range s.conf.Headers {
+		subOpts = append(subOpts, stomp.SubscribeOpt.Header(k, v))
+	}
+	sub, err := conn.Subscribe(s.conf.Destination, stomp.AckClientIndividual, subOpts...)
+	if err != nil {
+		conn.MustDisconnect()
+		return err
+	}
+
+	s.conn = conn
+	s.sub = sub
+	s.log.Infof("Receiving STOMP messages from destination: %v\n", s.conf.Destination)
+	return nil
+}
+
+func (s *STOMP) disconnect() {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+
+	if s.conn != nil {
+		s.conn.MustDisconnect()
+		s.conn = nil
+		s.sub = nil
+	}
+}
+
+// Read attempts to read a new message from the STOMP server.
+func (s *STOMP) Read() (types.Message, error) {
+	return s.ReadWithDeadline(time.Time{})
+}
+
+// ReadWithDeadline attempts to read a new message from the STOMP server,
+// returning types.ErrTimeout if no message arrives before the deadline.
+func (s *STOMP) ReadWithDeadline(deadline time.Time) (types.Message, error) {
+	s.cMut.Lock()
+	sub := s.sub
+	s.cMut.Unlock()
+
+	if sub == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	timeoutChan, done := deadlineChan(deadline)
+	defer done()
+
+	var m *stomp.Message
+	var open bool
+	select {
+	case m, open = <-sub.C:
+	case <-timeoutChan:
+		return nil, types.ErrTimeout
+	case <-s.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+	if !open || m.Err != nil {
+		select {
+		case <-s.closeChan:
+			return nil, types.ErrTypeClosed
+		default:
+		}
+		if open {
+			s.log.Errorf("Lost connection to STOMP server: %v\n", m.Err)
+		}
+		s.disconnect()
+		return nil, types.ErrNotConnected
+	}
+
+	part := message.NewPart(m.Body)
+	meta := part.Metadata()
+	for i := 0; i < m.Header.Len(); i++ {
+		k, v := m.Header.GetAt(i)
+		switch k {
+		case "destination", "message-id", "subscription", "content-type":
+			meta.Set("stomp_"+strings.Replace(k, "-", "_", -1), v)
+		case "ack", "content-length":
+		default:
+			meta.Set(k, v)
+		}
+	}
+
+	s.pendingMut.Lock()
+	s.pending = append(s.pending, m)
+	s.pendingMut.Unlock()
+
+	msg := message.New(nil)
+	msg.Append(part)
+	return msg, nil
+}
+
+// Acknowledge sends ACK frames for messages that were successfully propagated,
+// otherwise NACK frames are sent in order for the server to redeliver them.
+func (s *STOMP) Acknowledge(err error) error {
+	s.pendingMut.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.pendingMut.Unlock()
+
+	var ackErr error
+	for _, m := range pending {
+		var pErr error
+		if err == nil {
+			pErr = m.Conn.Ack(m)
+		} else {
+			pErr = m.Conn.Nack(m)
+		}
+		if pErr != nil && ackErr == nil {
+			ackErr = pErr
+		}
+	}
+	return ackErr
+}
+
+// CloseAsync shuts down the STOMP input and stops processing requests.
+func (s *STOMP) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+		s.disconnect()
+	})
+}
+
+// WaitForClose blocks until the STOMP input has closed down.
+func (s *STOMP) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
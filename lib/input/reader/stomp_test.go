@@ -0,0 +1,168 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestSTOMPBadConfig(t *testing.T) {
+	conf := NewSTOMPConfig()
+	conf.Destination = ""
+	if _, err := NewSTOMP(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty destination")
+	}
+}
+
+// stompTestFrame is a frame read by a fake STOMP server.
+type stompTestFrame struct {
+	command string
+	header  map[string]string
+}
+
+func TestSTOMPAcks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	frames := make(chan stompTestFrame, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		readFrame := func() (f stompTestFrame) {
+			raw, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			lines := strings.Split(strings.SplitN(strings.TrimLeft(raw, "\n"), "\n\n", 2)[0], "\n")
+			f.command = lines[0]
+			f.header = map[string]string{}
+			for _, l := range lines[1:] {
+				if kv := strings.SplitN(l, ":", 2); len(kv) == 2 {
+					f.header[kv[0]] = kv[1]
+				}
+			}
+			return
+		}
+
+		readFrame()
+		conn.Write([]byte("CONNECTED\nversion:1.2\n\n\x00"))
+		sub := readFrame()
+		frames <- sub
+		id := sub.header["id"]
+		conn.Write([]byte("MESSAGE\ndestination:/queue/foo\nmessage-id:1\nsubscription:" + id + "\nack:a1\nfoo:bar\n\nhello world\x00"))
+		conn.Write([]byte("MESSAGE\ndestination:/queue/foo\nmessage-id:2\nsubscription:" + id + "\nack:a2\n\nhello again\x00"))
+		for f := readFrame(); len(f.command) > 0; f = readFrame() {
+			frames <- f
+		}
+	}()
+
+	conf := NewSTOMPConfig()
+	conf.Address = ln.Addr().String()
+	conf.Destination = "/queue/foo"
+
+	s, err := NewSTOMP(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	f := <-frames
+	if exp, act := "SUBSCRIBE", f.command; exp != act {
+		t.Errorf("Wrong subscribe frame: %v != %v", act, exp)
+	}
+	if exp, act := "client-individual", f.header["ack"]; exp != act {
+		t.Errorf("Wrong ack mode: %v != %v", act, exp)
+	}
+	if exp, act := "/queue/foo", f.header["destination"]; exp != act {
+		t.Errorf("Wrong destination: %v != %v", act, exp)
+	}
+
+	msg, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+	if exp, act := "/queue/foo", msg.Get(0).Metadata().Get("stomp_destination"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := "1", msg.Get(0).Metadata().Get("stomp_message_id"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := "bar", msg.Get(0).Metadata().Get("foo"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if err = s.Acknowledge(nil); err != nil {
+		t.Fatal(err)
+	}
+	f = <-frames
+	if exp, act := "ACK", f.command; exp != act {
+		t.Errorf("Wrong ack frame: %v != %v", act, exp)
+	}
+	if exp, act := "a1", f.header["id"]; exp != act {
+		t.Errorf("Wrong ack id: %v != %v", act, exp)
+	}
+
+	if msg, err = s.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello again", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+	if err = s.Acknowledge(errors.New("nope")); err != nil {
+		t.Fatal(err)
+	}
+	f = <-frames
+	if exp, act := "NACK", f.command; exp != act {
+		t.Errorf("Wrong nack frame: %v != %v", act, exp)
+	}
+	if exp, act := "a2", f.header["id"]; exp != act {
+		t.Errorf("Wrong nack id: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,333 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// SubprocessConfig contains configuration fields for the Subprocess input
+// type.
+type SubprocessConfig struct {
+	Name              string   `json:"name" yaml:"name"`
+	Args              []string `json:"args" yaml:"args"`
+	Codec             string   `json:"codec" yaml:"codec"`
+	MaxBuffer         int      `json:"max_buffer" yaml:"max_buffer"`
+	RestartOnExit     bool     `json:"restart_on_exit" yaml:"restart_on_exit"`
+	RestartBackoff    string   `json:"restart_backoff" yaml:"restart_backoff"`
+	MaxRestartBackoff string   `json:"max_restart_backoff" yaml:"max_restart_backoff"`
+}
+
+// NewSubprocessConfig creates a new SubprocessConfig with default values.
+func NewSubprocessConfig() SubprocessConfig {
+	return SubprocessConfig{
+		Name:              "",
+		Args:              []string{},
+		Codec:             "lines",
+		MaxBuffer:         65536,
+		RestartOnExit:     true,
+		RestartBackoff:    "1s",
+		MaxRestartBackoff: "60s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Subprocess is a reader that executes a command and reads messages from its
+// stdout, restarting the command if it exits.
+type Subprocess struct {
+	conf SubprocessConfig
+
+	restartThrot *throttle.Type
+
+	cmdMut   sync.Mutex
+	cmd      *exec.Cmd
+	next     func() ([]byte, error)
+	started  bool
+	produced bool
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mStarted metrics.StatCounter
+	mExited  metrics.StatCounter
+	mStderr  metrics.StatCounter
+}
+
+// NewSubprocess creates a new Subprocess reader type.
+func NewSubprocess(
+	conf SubprocessConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Subprocess, error) {
+	if len(conf.Name) == 0 {
+		return nil, errors.New("a command name must be specified")
+	}
+	if conf.Codec != "lines" && conf.Codec != "length_prefixed" {
+		return nil, fmt.Errorf("codec not recognised: %v", conf.Codec)
+	}
+
+	backoff, maxBackoff := time.Second, time.Minute
+	if tout := conf.RestartBackoff; len(tout) > 0 {
+		var err error
+		if backoff, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse restart backoff string: %v", err)
+		}
+	}
+	if tout := conf.MaxRestartBackoff; len(tout) > 0 {
+		var err error
+		if maxBackoff, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse max restart backoff string: %v", err)
+		}
+	}
+
+	s := &Subprocess{
+		conf:       conf,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log,
+		stats:      stats,
+		mStarted:   stats.GetCounter("subprocess.started"),
+		mExited:    stats.GetCounter("subprocess.exited"),
+		mStderr:    stats.GetCounter("subprocess.stderr"),
+	}
+	s.restartThrot = throttle.New(
+		throttle.OptMaxUnthrottledRetries(0),
+		throttle.OptThrottlePeriod(backoff),
+		throttle.OptMaxExponentPeriod(maxBackoff),
+		throttle.OptCloseChan(s.closeChan),
+	)
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *Subprocess) linesCodec(r io.Reader) func() ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), s.conf.MaxBuffer)
+	return func() ([]byte, error) {
+		for scanner.Scan() {
+			if line := scanner.Bytes(); len(line) > 0 {
+				return append([]byte(nil), line...), nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
+
+func (s *Subprocess) lengthPrefixedCodec(r io.Reader) func() ([]byte, error) {
+	buf := bufio.NewReader(r)
+	return func() ([]byte, error) {
+		var lenBytes [4]byte
+		if _, err := io.ReadFull(buf, lenBytes[:]); err != nil {
+			return nil, err
+		}
+		size := binary.BigEndian.Uint32(lenBytes[:])
+		if s.conf.MaxBuffer > 0 && uint64(size) > uint64(s.conf.MaxBuffer) {
+			return nil, fmt.Errorf("message size %v exceeds max buffer %v", size, s.conf.MaxBuffer)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(buf, data); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+func (s *Subprocess) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s.mStderr.Incr(1)
+		s.log.Warnf("Subprocess stderr: %s\n", scanner.Bytes())
+	}
+}
+
+// stopProcess kills a running command and reaps it, returning the result of
+// the command.
+func (s *Subprocess) stopProcess(cmd *exec.Cmd) error {
+	s.cmdMut.Lock()
+	defer s.cmdMut.Unlock()
+
+	if s.cmd != cmd {
+		return nil
+	}
+	s.cmd = nil
+	s.next = nil
+	cmd.Process.Kill()
+	s.mExited.Incr(1)
+	return cmd.Wait()
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to start the command, waiting for a restart backoff if the
+// command has previously exited.
+func (s *Subprocess) Connect() error {
+	s.cmdMut.Lock()
+	defer s.cmdMut.Unlock()
+
+	if s.cmd != nil {
+		return nil
+	}
+
+	if s.started {
+		// Backoff is only reset for commands that managed to produce data,
+		// this prevents busy looping over a command that fails immediately.
+		if s.produced {
+			s.restartThrot.Reset()
+		}
+		s.cmdMut.Unlock()
+		ok := s.restartThrot.ExponentialRetry()
+		s.cmdMut.Lock()
+		if !ok {
+			return types.ErrTypeClosed
+		}
+	}
+
+	select {
+	case <-s.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	cmd := exec.Command(s.conf.Name, s.conf.Args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	s.started = true
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start command: %v", err)
+	}
+	go s.logStderr(stderr)
+
+	if s.conf.Codec == "length_prefixed" {
+		s.next = s.lengthPrefixedCodec(stdout)
+	} else {
+		s.next = s.linesCodec(stdout)
+	}
+	s.cmd = cmd
+	s.produced = false
+	s.mStarted.Incr(1)
+
+	s.log.Infof("Reading messages from the stdout of command: %v\n", s.conf.Name)
+	return nil
+}
+
+// Read attempts to read a new message from the stdout of the command.
+func (s *Subprocess) Read() (types.Message, error) {
+	s.cmdMut.Lock()
+	cmd, next := s.cmd, s.next
+	s.cmdMut.Unlock()
+
+	if cmd == nil {
+		return nil, types.ErrNotConnected
+	}
+
+	data, err := next()
+	if err != nil {
+		waitErr := s.stopProcess(cmd)
+		select {
+		case <-s.closeChan:
+			return nil, types.ErrTypeClosed
+		default:
+		}
+		if err != io.EOF {
+			s.log.Errorf("Failed to read from command stdout: %v\n", err)
+		} else if waitErr != nil {
+			s.log.Warnf("Command exited: %v\n", waitErr)
+		} else {
+			s.log.Infoln("Command exited")
+		}
+		if !s.conf.RestartOnExit {
+			return nil, types.ErrTypeClosed
+		}
+		return nil, types.ErrNotConnected
+	}
+	s.produced = true
+
+	msg := message.New(nil)
+	msg.Append(message.NewPart(data))
+	return msg, nil
+}
+
+// Acknowledge is a noop since the stdout of a command cannot be replayed.
+func (s *Subprocess) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this reader asynchronously.
+func (s *Subprocess) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+		go func() {
+			s.cmdMut.Lock()
+			cmd := s.cmd
+			s.cmdMut.Unlock()
+			if cmd != nil {
+				s.stopProcess(cmd)
+			}
+			close(s.closedChan)
+		}()
+	})
+}
+
+// WaitForClose will block until either the reader is closed or a specified
+// timeout occurs.
+func (s *Subprocess) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-s.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
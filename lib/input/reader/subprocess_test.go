@@ -0,0 +1,168 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func readSubprocess(t *testing.T, s *Subprocess) (string, error) {
+	t.Helper()
+	for {
+		msg, err := s.Read()
+		if err == types.ErrNotConnected {
+			if err = s.Connect(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(msg.Get(0).Get()), nil
+	}
+}
+
+func TestSubprocessLinesRestart(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	conf := NewSubprocessConfig()
+	conf.Name = "sh"
+	conf.Args = []string{"-c", "echo foo; echo; echo bar; echo baz >&2"}
+	conf.RestartBackoff = "1ms"
+
+	s, err := NewSubprocess(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"foo", "bar", "foo", "bar"} {
+		act, err := readSubprocess(t, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestSubprocessLengthPrefixedNoRestart(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	conf := NewSubprocessConfig()
+	conf.Name = "sh"
+	conf.Args = []string{"-c", `printf '\000\000\000\003abc\000\000\000\005hello'`}
+	conf.Codec = "length_prefixed"
+	conf.RestartOnExit = false
+
+	s, err := NewSubprocess(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"abc", "hello"} {
+		act, err := readSubprocess(t, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+	}
+
+	if _, err = s.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Expected ErrTypeClosed, received: %v", err)
+	}
+}
+
+func TestSubprocessCloseWhileReading(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	conf := NewSubprocessConfig()
+	conf.Name = "sleep"
+	conf.Args = []string{"60"}
+
+	s, err := NewSubprocess(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		s.CloseAsync()
+	}()
+
+	if _, err = s.Read(); err != types.ErrTypeClosed {
+		t.Errorf("Expected ErrTypeClosed, received: %v", err)
+	}
+	if err = s.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubprocessBadConfig(t *testing.T) {
+	conf := NewSubprocessConfig()
+	if _, err := NewSubprocess(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing name")
+	}
+
+	conf = NewSubprocessConfig()
+	conf.Name = "foo"
+	conf.Codec = "nope"
+	if _, err := NewSubprocess(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad codec")
+	}
+}
+
+//------------------------------------------------------------------------------
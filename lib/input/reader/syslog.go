@@ -0,0 +1,390 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/syslog"
+)
+
+//------------------------------------------------------------------------------
+
+// SyslogConfig contains configuration fields for the Syslog input type.
+type SyslogConfig struct {
+	Address   string `json:"address" yaml:"address"`
+	Protocol  string `json:"protocol" yaml:"protocol"`
+	Format    string `json:"format" yaml:"format"`
+	MaxBuffer int    `json:"max_buffer" yaml:"max_buffer"`
+	CertFile  string `json:"cert_file" yaml:"cert_file"`
+	KeyFile   string `json:"key_file" yaml:"key_file"`
+}
+
+// NewSyslogConfig creates a new SyslogConfig with default values.
+func NewSyslogConfig() SyslogConfig {
+	return SyslogConfig{
+		Address:   "0.0.0.0:514",
+		Protocol:  "udp",
+		Format:    "auto",
+		MaxBuffer: 65536,
+		CertFile:  "",
+		KeyFile:   "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type syslogFrame struct {
+	data       []byte
+	remoteAddr string
+}
+
+// Syslog is an input type that listens for syslog messages over UDP, TCP or
+// TLS and parses them into structured JSON documents.
+type Syslog struct {
+	conf   SyslogConfig
+	format syslog.Format
+
+	cMut     sync.Mutex
+	listener net.Listener
+	packConn net.PacketConn
+	conns    map[net.Conn]struct{}
+	addr     net.Addr
+
+	frames chan syslogFrame
+
+	log   log.Modular
+	stats metrics.Type
+
+	mParseErr metrics.StatCounter
+	mConnErr  metrics.StatCounter
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewSyslog creates a new Syslog input type.
+func NewSyslog(conf SyslogConfig, log log.Modular, stats metrics.Type) (*Syslog, error) {
+	switch conf.Protocol {
+	case "udp", "tcp":
+	case "tls":
+		if len(conf.CertFile) == 0 || len(conf.KeyFile) == 0 {
+			return nil, errors.New("a cert_file and key_file must be specified when the protocol is tls")
+		}
+	default:
+		return nil, fmt.Errorf("unrecognised protocol: %v", conf.Protocol)
+	}
+	format := syslog.Format(conf.Format)
+	switch format {
+	case syslog.FormatAuto, syslog.FormatRFC3164, syslog.FormatRFC5424:
+	default:
+		return nil, fmt.Errorf("unrecognised format: %v", conf.Format)
+	}
+	return &Syslog{
+		conf:       conf,
+		format:     format,
+		conns:      map[net.Conn]struct{}{},
+		frames:     make(chan syslogFrame),
+		log:        log,
+		stats:      stats,
+		mParseErr:  stats.GetCounter("parse.error"),
+		mConnErr:   stats.GetCounter("connection.error"),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect begins listening for syslog messages.
+func (s *Syslog) Connect() error {
+	s.cMut.Lock()
+	defer s.cMut.Unlock()
+
+	select {
+	case <-s.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+	if s.listener != nil || s.packConn != nil {
+		return nil
+	}
+
+	var err error
+	switch s.conf.Protocol {
+	case "udp":
+		if s.packConn, err = net.ListenPacket("udp", s.conf.Address); err != nil {
+			return err
+		}
+		s.addr = s.packConn.LocalAddr()
+		go s.udpLoop(s.packConn)
+	case "tcp", "tls":
+		if s.listener, err = net.Listen("tcp", s.conf.Address); err != nil {
+			return err
+		}
+		if s.conf.Protocol == "tls" {
+			cert, err := tls.LoadX509KeyPair(s.conf.CertFile, s.conf.KeyFile)
+			if err != nil {
+				s.listener.Close()
+				s.listener = nil
+				return err
+			}
+			s.listener = tls.NewListener(s.listener, &tls.Config{
+				Certificates: []tls.Certificate{cert},
+			})
+		}
+		s.addr = s.listener.Addr()
+		go s.acceptLoop(s.listener)
+	}
+
+	s.log.Infof("Receiving syslog messages over %v at: %v\n", s.conf.Protocol, s.addr)
+	return nil
+}
+
+func (s *Syslog) sendFrame(data []byte, remoteAddr string) bool {
+	select {
+	case s.frames <- syslogFrame{data: data, remoteAddr: remoteAddr}:
+		return true
+	case <-s.closeChan:
+		return false
+	}
+}
+
+func (s *Syslog) udpLoop(conn net.PacketConn) {
+	buf := make([]byte, s.conf.MaxBuffer)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.closeChan:
+			default:
+				s.log.Errorf("Failed to read UDP packet: %v\n", err)
+			}
+			return
+		}
+		if n == 0 {
+			continue
+		}
+		if !s.sendFrame(append([]byte(nil), buf[:n]...), addr.String()) {
+			return
+		}
+	}
+}
+
+func (s *Syslog) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closeChan:
+			default:
+				s.log.Errorf("Failed to accept connection: %v\n", err)
+			}
+			return
+		}
+		s.cMut.Lock()
+		s.conns[conn] = struct{}{}
+		s.cMut.Unlock()
+		go s.connLoop(conn)
+	}
+}
+
+// connLoop reads frames from a stream connection, which are either prefixed
+// with their length in bytes (octet counting) or terminated with a newline as
+// per RFC 6587.
+func (s *Syslog) connLoop(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.cMut.Lock()
+		delete(s.conns, conn)
+		s.cMut.Unlock()
+	}()
+
+	remoteAddr := conn.RemoteAddr().String()
+	rdr := bufio.NewReaderSize(conn, 4096)
+	for {
+		first, err := rdr.Peek(1)
+		if err != nil {
+			if err != io.EOF {
+				s.connErr(err)
+			}
+			return
+		}
+
+		var frame []byte
+		if first[0] >= '1' && first[0] <= '9' {
+			lenStr, err := rdr.ReadString(' ')
+			if err != nil {
+				s.connErr(err)
+				return
+			}
+			length, err := strconv.Atoi(lenStr[:len(lenStr)-1])
+			if err != nil || length > s.conf.MaxBuffer {
+				s.connErr(fmt.Errorf("invalid frame length: %v", lenStr))
+				return
+			}
+			frame = make([]byte, length)
+			if _, err = io.ReadFull(rdr, frame); err != nil {
+				s.connErr(err)
+				return
+			}
+		} else {
+			for {
+				line, isPrefix, err := rdr.ReadLine()
+				if err != nil {
+					if err != io.EOF || len(frame) == 0 {
+						if err != io.EOF {
+							s.connErr(err)
+						}
+						return
+					}
+					break
+				}
+				frame = append(frame, line...)
+				if len(frame) > s.conf.MaxBuffer {
+					s.connErr(errors.New("frame exceeded max buffer size"))
+					return
+				}
+				if !isPrefix {
+					break
+				}
+			}
+			if len(frame) == 0 {
+				continue
+			}
+		}
+		if !s.sendFrame(frame, remoteAddr) {
+			return
+		}
+	}
+}
+
+func (s *Syslog) connErr(err error) {
+	select {
+	case <-s.closeChan:
+	default:
+		s.mConnErr.Incr(1)
+		s.log.Errorf("Failed to read from syslog connection: %v\n", err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Read attempts to read a new syslog message.
+func (s *Syslog) Read() (types.Message, error) {
+	var frame syslogFrame
+	select {
+	case frame = <-s.frames:
+	case <-time.After(time.Second):
+		return nil, types.ErrTimeout
+	case <-s.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+
+	parsed, err := syslog.Parse(frame.data, s.format)
+	if err != nil {
+		// Messages that can't be parsed are passed on as they are so that
+		// they can be handled downstream.
+		s.mParseErr.Incr(1)
+		s.log.Debugf("Failed to parse syslog message: %v\n", err)
+		msg := message.New([][]byte{bytes.TrimRight(frame.data, "\r\n")})
+		meta := msg.Get(0).Metadata()
+		meta.Set("syslog_remote_addr", frame.remoteAddr)
+		meta.Set("syslog_parse_error", err.Error())
+		return msg, nil
+	}
+
+	body, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, err
+	}
+	msg := message.New([][]byte{body})
+	meta := msg.Get(0).Metadata()
+	meta.Set("syslog_remote_addr", frame.remoteAddr)
+	meta.Set("syslog_facility", strconv.Itoa(parsed.Facility))
+	meta.Set("syslog_severity", strconv.Itoa(parsed.Severity))
+	if len(parsed.Hostname) > 0 {
+		meta.Set("syslog_hostname", parsed.Hostname)
+	}
+	if len(parsed.AppName) > 0 {
+		meta.Set("syslog_app_name", parsed.AppName)
+	}
+	if len(parsed.ProcID) > 0 {
+		meta.Set("syslog_proc_id", parsed.ProcID)
+	}
+	if len(parsed.MsgID) > 0 {
+		meta.Set("syslog_msg_id", parsed.MsgID)
+	}
+	return msg, nil
+}
+
+// Acknowledge is a noop as syslog messages cannot be acknowledged.
+func (s *Syslog) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the Syslog input and stops processing requests.
+func (s *Syslog) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+
+		s.cMut.Lock()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+		if s.packConn != nil {
+			s.packConn.Close()
+		}
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.cMut.Unlock()
+
+		close(s.closedChan)
+	})
+}
+
+// WaitForClose blocks until the Syslog input has closed down.
+func (s *Syslog) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-s.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func newTestSyslog(t *testing.T, protocol string) *Syslog {
+	t.Helper()
+	conf := NewSyslogConfig()
+	conf.Address = "127.0.0.1:0"
+	conf.Protocol = protocol
+
+	s, err := NewSyslog(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func readSyslog(t *testing.T, s *Syslog) types.Message {
+	t.Helper()
+	for i := 0; i < 5; i++ {
+		msg, err := s.Read()
+		if err == types.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	t.Fatal("Timed out waiting for message")
+	return nil
+}
+
+func TestSyslogTCP(t *testing.T) {
+	s := newTestSyslog(t, "tcp")
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	conn, err := net.Dial("tcp", s.addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	rfc5424 := `<165>1 2003-10-11T22:14:15.003Z host app 12 ID47 - hello world`
+	if _, err = conn.Write([]byte(
+		"<34>Oct 11 22:14:15 mymachine su: first\r\n" +
+			rfc5424 + "\n",
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := readSyslog(t, s)
+	if exp, act := `{"facility":4,"severity":2,"timestamp":`, string(msg.Get(0).Get()); len(act) < len(exp) || act[:len(exp)] != exp {
+		t.Errorf("Wrong result: %v", act)
+	}
+	meta := msg.Get(0).Metadata()
+	for k, exp := range map[string]string{
+		"syslog_facility": "4",
+		"syslog_severity": "2",
+		"syslog_hostname": "mymachine",
+		"syslog_app_name": "su",
+	} {
+		if act := meta.Get(k); act != exp {
+			t.Errorf("Wrong metadata %v: %v != %v", k, act, exp)
+		}
+	}
+}
+
+func TestSyslogTCPOctetCounting(t *testing.T) {
+	s := newTestSyslog(t, "tcp")
+	defer s.CloseAsync()
+
+	conn, err := net.Dial("tcp", s.addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	first := `<165>1 2003-10-11T22:14:15.003Z host app 12 ID47 - hello` + "\nworld"
+	second := `<165>1 - host2 - - - - second`
+	for _, frame := range []string{first, second} {
+		if _, err = conn.Write([]byte(strconv.Itoa(len(frame)) + " " + frame)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp := []string{
+		`{"facility":20,"severity":5,"version":1,"timestamp":"2003-10-11T22:14:15.003Z","hostname":"host","app_name":"app","proc_id":"12","msg_id":"ID47","message":"hello\nworld"}`,
+		`{"facility":20,"severity":5,"version":1,"hostname":"host2","message":"second"}`,
+	}
+	for _, e := range exp {
+		if act := string(readSyslog(t, s).Get(0).Get()); act != e {
+			t.Errorf("Wrong result: %v != %v", act, e)
+		}
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	s := newTestSyslog(t, "udp")
+	defer s.CloseAsync()
+
+	conn, err := net.Dial("udp", s.addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("not syslog\n")); err != nil {
+		t.Fatal(err)
+	}
+	msg := readSyslog(t, s)
+	if exp, act := "not syslog", string(msg.Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if len(msg.Get(0).Metadata().Get("syslog_parse_error")) == 0 {
+		t.Error("Expected parse error metadata")
+	}
+}
+
+func TestSyslogBadConfig(t *testing.T) {
+	conf := NewSyslogConfig()
+	conf.Protocol = "tls"
+	if _, err := NewSyslog(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from tls without certificates")
+	}
+
+	conf = NewSyslogConfig()
+	conf.Format = "nope"
+	if _, err := NewSyslog(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad format")
+	}
+}
+
+//------------------------------------------------------------------------------
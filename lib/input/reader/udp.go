@@ -0,0 +1,250 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// UDPConfig contains configuration fields for the UDP input type.
+type UDPConfig struct {
+	Address         string `json:"address" yaml:"address"`
+	MaxDatagramSize int    `json:"max_datagram_size" yaml:"max_datagram_size"`
+	Delim           string `json:"delimiter" yaml:"delimiter"`
+	SourceMetadata  bool   `json:"source_metadata" yaml:"source_metadata"`
+	Readers         int    `json:"readers" yaml:"readers"`
+	ReadBufferSize  int    `json:"read_buffer_size" yaml:"read_buffer_size"`
+}
+
+// NewUDPConfig creates a new UDPConfig with default values.
+func NewUDPConfig() UDPConfig {
+	return UDPConfig{
+		Address:         "0.0.0.0:4196",
+		MaxDatagramSize: 65536,
+		Delim:           "",
+		SourceMetadata:  false,
+		Readers:         1,
+		ReadBufferSize:  0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// UDP is an input type that listens for datagrams at a UDP address.
+type UDP struct {
+	conf  UDPConfig
+	delim []byte
+
+	cMut     sync.Mutex
+	packConn net.PacketConn
+	addr     net.Addr
+
+	msgs chan types.Message
+
+	log   log.Modular
+	stats metrics.Type
+
+	mDatagrams metrics.StatCounter
+	mTruncated metrics.StatCounter
+	mReadErr   metrics.StatCounter
+
+	readersWG  sync.WaitGroup
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewUDP creates a new UDP input type.
+func NewUDP(conf UDPConfig, log log.Modular, stats metrics.Type) (*UDP, error) {
+	if len(conf.Address) == 0 {
+		return nil, errors.New("an address must be specified")
+	}
+	if conf.MaxDatagramSize <= 0 {
+		return nil, errors.New("max_datagram_size must be greater than zero")
+	}
+	if conf.Readers <= 0 {
+		return nil, errors.New("readers must be greater than zero")
+	}
+	return &UDP{
+		conf:       conf,
+		delim:      []byte(conf.Delim),
+		msgs:       make(chan types.Message),
+		log:        log,
+		stats:      stats,
+		mDatagrams: stats.GetCounter("datagrams"),
+		mTruncated: stats.GetCounter("datagrams.truncated"),
+		mReadErr:   stats.GetCounter("read.error"),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect begins listening for datagrams.
+func (u *UDP) Connect() error {
+	u.cMut.Lock()
+	defer u.cMut.Unlock()
+
+	select {
+	case <-u.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+	if u.packConn != nil {
+		return nil
+	}
+
+	conn, err := net.ListenPacket("udp", u.conf.Address)
+	if err != nil {
+		return err
+	}
+	if u.conf.ReadBufferSize > 0 {
+		if udpConn, ok := conn.(*net.UDPConn); ok {
+			if err = udpConn.SetReadBuffer(u.conf.ReadBufferSize); err != nil {
+				conn.Close()
+				return err
+			}
+		}
+	}
+	u.packConn = conn
+	u.addr = conn.LocalAddr()
+
+	u.readersWG.Add(u.conf.Readers)
+	for i := 0; i < u.conf.Readers; i++ {
+		go u.readLoop(conn)
+	}
+
+	u.log.Infof("Receiving UDP datagrams at: %v\n", u.addr)
+	return nil
+}
+
+func (u *UDP) readLoop(conn net.PacketConn) {
+	defer u.readersWG.Done()
+
+	// A buffer one byte larger than the max size is used in order to detect
+	// truncated datagrams.
+	buf := make([]byte, u.conf.MaxDatagramSize+1)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-u.closeChan:
+				return
+			default:
+			}
+			if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
+				u.mReadErr.Incr(1)
+				u.log.Errorf("Failed to read UDP datagram: %v\n", err)
+				continue
+			}
+			u.log.Errorf("Failed to read UDP datagram: %v\n", err)
+			return
+		}
+		u.mDatagrams.Incr(1)
+		if n > u.conf.MaxDatagramSize {
+			u.mTruncated.Incr(1)
+			u.log.Warnf("Dropping datagram from %v exceeding max size of %v bytes\n", addr, u.conf.MaxDatagramSize)
+			continue
+		}
+
+		var frames [][]byte
+		if len(u.delim) > 0 {
+			frames = bytes.Split(buf[:n], u.delim)
+		} else {
+			frames = [][]byte{buf[:n]}
+		}
+		for _, frame := range frames {
+			if len(frame) == 0 {
+				continue
+			}
+			msg := message.New([][]byte{append([]byte(nil), frame...)})
+			if u.conf.SourceMetadata {
+				msg.Get(0).Metadata().Set("udp_source_address", addr.String())
+			}
+			select {
+			case u.msgs <- msg:
+			case <-u.closeChan:
+				return
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Read attempts to read a new message from the received datagrams.
+func (u *UDP) Read() (types.Message, error) {
+	select {
+	case msg := <-u.msgs:
+		return msg, nil
+	case <-time.After(time.Second):
+		return nil, types.ErrTimeout
+	case <-u.closeChan:
+		return nil, types.ErrTypeClosed
+	}
+}
+
+// Acknowledge is a noop as UDP datagrams cannot be acknowledged.
+func (u *UDP) Acknowledge(err error) error {
+	return nil
+}
+
+// CloseAsync shuts down the UDP input and stops processing requests.
+func (u *UDP) CloseAsync() {
+	u.closeOnce.Do(func() {
+		close(u.closeChan)
+
+		u.cMut.Lock()
+		if u.packConn != nil {
+			u.packConn.Close()
+		}
+		u.cMut.Unlock()
+
+		go func() {
+			u.readersWG.Wait()
+			close(u.closedChan)
+		}()
+	})
+}
+
+// WaitForClose blocks until the UDP input has closed down.
+func (u *UDP) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-u.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
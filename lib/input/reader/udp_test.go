@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reader
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func readUDP(t *testing.T, u *UDP) types.Message {
+	t.Helper()
+	for i := 0; i < 5; i++ {
+		msg, err := u.Read()
+		if err == types.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = u.Acknowledge(nil); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	t.Fatal("Timed out waiting for message")
+	return nil
+}
+
+func TestUDPBadConfig(t *testing.T) {
+	conf := NewUDPConfig()
+	conf.Readers = 0
+	if _, err := NewUDP(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero readers")
+	}
+
+	conf = NewUDPConfig()
+	conf.MaxDatagramSize = 0
+	if _, err := NewUDP(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from zero max datagram size")
+	}
+}
+
+func TestUDPBasic(t *testing.T) {
+	conf := NewUDPConfig()
+	conf.Address = "127.0.0.1:0"
+	conf.Delim = "\n"
+	conf.SourceMetadata = true
+	conf.Readers = 2
+	conf.MaxDatagramSize = 20
+
+	u, err := NewUDP(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		u.CloseAsync()
+		if err := u.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+	if err = u.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("udp", u.addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("this datagram is far too large")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Write([]byte("foo:1|c\nbar:2|c\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"foo:1|c", "bar:2|c"} {
+		msg := readUDP(t, u)
+		if act := string(msg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", act, exp)
+		}
+		if exp, act := conn.LocalAddr().String(), msg.Get(0).Metadata().Get("udp_source_address"); exp != act {
+			t.Errorf("Wrong source address: %v != %v", act, exp)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
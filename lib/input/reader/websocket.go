@@ -21,6 +21,7 @@
 package reader
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"sync"
@@ -31,6 +32,8 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/cenkalti/backoff"
 	"github.com/gorilla/websocket"
 )
 
@@ -38,17 +41,27 @@ import (
 
 // WebsocketConfig contains configuration fields for the Websocket input type.
 type WebsocketConfig struct {
-	URL         string `json:"url" yaml:"url"`
-	OpenMsg     string `json:"open_message" yaml:"open_message"`
-	auth.Config `json:",inline" yaml:",inline"`
+	URL              string          `json:"url" yaml:"url"`
+	OpenMsg          string          `json:"open_message" yaml:"open_message"`
+	PingPeriod       string          `json:"ping_period" yaml:"ping_period"`
+	PongTimeout      string          `json:"pong_timeout" yaml:"pong_timeout"`
+	ReconnectBackoff retries.Backoff `json:"reconnect_backoff" yaml:"reconnect_backoff"`
+	auth.Config      `json:",inline" yaml:",inline"`
 }
 
 // NewWebsocketConfig creates a new WebsocketConfig with default values.
 func NewWebsocketConfig() WebsocketConfig {
 	return WebsocketConfig{
-		URL:     "ws://localhost:4195/get/ws",
-		OpenMsg: "",
-		Config:  auth.NewConfig(),
+		URL:         "ws://localhost:4195/get/ws",
+		OpenMsg:     "",
+		PingPeriod:  "",
+		PongTimeout: "10s",
+		ReconnectBackoff: retries.Backoff{
+			InitialInterval: "1s",
+			MaxInterval:     "60s",
+			MaxElapsedTime:  "0s",
+		},
+		Config: auth.NewConfig(),
 	}
 }
 
@@ -63,6 +76,17 @@ type Websocket struct {
 
 	conf   WebsocketConfig
 	client *websocket.Conn
+
+	pingPeriod   time.Duration
+	pongTimeout  time.Duration
+	boff         backoff.BackOff
+	reconnecting bool
+	stopPing     chan struct{}
+
+	mPingErr metrics.StatCounter
+
+	closeChan chan struct{}
+	closeOnce sync.Once
 }
 
 // NewWebsocket creates a new Websocket input type.
@@ -72,10 +96,30 @@ func NewWebsocket(
 	stats metrics.Type,
 ) (*Websocket, error) {
 	ws := &Websocket{
-		log:   log,
-		stats: stats,
-		lock:  &sync.Mutex{},
-		conf:  conf,
+		log:       log,
+		stats:     stats,
+		lock:      &sync.Mutex{},
+		conf:      conf,
+		mPingErr:  stats.GetCounter("ping.error"),
+		closeChan: make(chan struct{}),
+	}
+
+	var err error
+	if len(conf.PingPeriod) > 0 {
+		if ws.pingPeriod, err = time.ParseDuration(conf.PingPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse ping period string: %v", err)
+		}
+	}
+	if len(conf.PongTimeout) > 0 {
+		if ws.pongTimeout, err = time.ParseDuration(conf.PongTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse pong timeout string: %v", err)
+		}
+	}
+
+	boffConf := retries.NewConfig()
+	boffConf.Backoff = conf.ReconnectBackoff
+	if ws.boff, err = boffConf.Get(); err != nil {
+		return nil, err
 	}
 	return ws, nil
 }
@@ -89,9 +133,44 @@ func (w *Websocket) getWS() *websocket.Conn {
 	return ws
 }
 
+func (w *Websocket) disconnect() {
+	w.lock.Lock()
+	if w.client != nil {
+		w.client.Close()
+		w.client = nil
+	}
+	if w.stopPing != nil {
+		close(w.stopPing)
+		w.stopPing = nil
+	}
+	w.reconnecting = true
+	w.lock.Unlock()
+}
+
+// pingLoop periodically sends ping messages to the server, the connection is
+// considered dead when a pong is not received within the pong timeout.
+func (w *Websocket) pingLoop(client *websocket.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(w.pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := client.WriteControl(
+				websocket.PingMessage, nil, time.Now().Add(w.pongTimeout),
+			); err != nil {
+				w.mPingErr.Incr(1)
+				w.log.Debugf("Failed to send ping: %v\n", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
 
-// Connect establishes a connection to a Websocket server.
+// Connect establishes a connection to a Websocket server, reconnection
+// attempts are delayed according to the reconnect backoff.
 func (w *Websocket) Connect() error {
 	w.lock.Lock()
 	defer w.lock.Unlock()
@@ -100,6 +179,24 @@ func (w *Websocket) Connect() error {
 		return nil
 	}
 
+	if w.reconnecting {
+		wait := w.boff.NextBackOff()
+		if wait == backoff.Stop {
+			w.boff.Reset()
+			wait = w.boff.NextBackOff()
+		}
+		select {
+		case <-time.After(wait):
+		case <-w.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+	select {
+	case <-w.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
 	headers := http.Header{}
 
 	purl, err := url.Parse(w.conf.URL)
@@ -114,6 +211,8 @@ func (w *Websocket) Connect() error {
 		return err
 	}
 
+	w.reconnecting = true
+
 	var client *websocket.Conn
 	if client, _, err = websocket.DefaultDialer.Dial(w.conf.URL, headers); err != nil {
 		return err
@@ -123,10 +222,21 @@ func (w *Websocket) Connect() error {
 		if err = client.WriteMessage(
 			websocket.BinaryMessage, []byte(w.conf.OpenMsg),
 		); err != nil {
+			client.Close()
 			return err
 		}
 	}
 
+	if w.pingPeriod > 0 {
+		readTimeout := w.pingPeriod + w.pongTimeout
+		client.SetReadDeadline(time.Now().Add(readTimeout))
+		client.SetPongHandler(func(string) error {
+			return client.SetReadDeadline(time.Now().Add(readTimeout))
+		})
+		w.stopPing = make(chan struct{})
+		go w.pingLoop(client, w.stopPing)
+	}
+
 	w.client = client
 	return nil
 }
@@ -142,13 +252,22 @@ func (w *Websocket) Read() (types.Message, error) {
 
 	_, data, err := client.ReadMessage()
 	if err != nil {
-		w.lock.Lock()
-		w.client = nil
-		w.lock.Unlock()
-		err = types.ErrNotConnected
-		return nil, err
+		w.log.Debugf("Websocket read failed: %v\n", err)
+		w.disconnect()
+		return nil, types.ErrNotConnected
 	}
 
+	w.lock.Lock()
+	if w.reconnecting {
+		w.reconnecting = false
+		w.boff.Reset()
+	}
+	if w.pingPeriod > 0 {
+		// Any message from the server counts as a sign of life.
+		client.SetReadDeadline(time.Now().Add(w.pingPeriod + w.pongTimeout))
+	}
+	w.lock.Unlock()
+
 	return message.New([][]byte{data}), nil
 }
 
@@ -160,12 +279,10 @@ func (w *Websocket) Acknowledge(err error) error {
 
 // CloseAsync shuts down the Websocket input and stops reading messages.
 func (w *Websocket) CloseAsync() {
-	w.lock.Lock()
-	if w.client != nil {
-		w.client.Close()
-		w.client = nil
-	}
-	w.lock.Unlock()
+	w.closeOnce.Do(func() {
+		close(w.closeChan)
+	})
+	w.disconnect()
 }
 
 // WaitForClose blocks until the Websocket input has closed down.
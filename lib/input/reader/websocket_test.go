@@ -21,11 +21,13 @@
 package reader
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -213,3 +215,97 @@ func TestWebsocketClose(t *testing.T) {
 	wg.Wait()
 	close(closeChan)
 }
+
+func TestWebsocketReconnect(t *testing.T) {
+	var connCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+
+		var ws *websocket.Conn
+		var err error
+		if ws, err = upgrader.Upgrade(w, r, nil); err != nil {
+			return
+		}
+
+		// Each connection sends a single message and is then dropped.
+		n := atomic.AddInt32(&connCount, 1)
+		ws.WriteMessage(websocket.BinaryMessage, []byte(fmt.Sprintf("msg%v", n)))
+		ws.Close()
+	}))
+	defer server.Close()
+
+	conf := NewWebsocketConfig()
+	conf.ReconnectBackoff.InitialInterval = "1ms"
+	conf.ReconnectBackoff.MaxInterval = "10ms"
+	if wsURL, err := url.Parse(server.URL); err != nil {
+		t.Fatal(err)
+	} else {
+		wsURL.Scheme = "ws"
+		conf.URL = wsURL.String()
+	}
+
+	m, err := NewWebsocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"msg1", "msg2"} {
+		if err = m.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := m.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(msg.Get(0).Get()); act != exp {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+		if _, err = m.Read(); err != types.ErrNotConnected {
+			t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+		}
+	}
+
+	m.CloseAsync()
+	if err = m.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrTypeClosed)
+	}
+}
+
+func TestWebsocketPingTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+
+		var ws *websocket.Conn
+		var err error
+		if ws, err = upgrader.Upgrade(w, r, nil); err != nil {
+			return
+		}
+		defer ws.Close()
+
+		// Never read from the connection so that pings are not answered.
+		<-time.After(time.Second)
+	}))
+	defer server.Close()
+
+	conf := NewWebsocketConfig()
+	conf.PingPeriod = "10ms"
+	conf.PongTimeout = "10ms"
+	if wsURL, err := url.Parse(server.URL); err != nil {
+		t.Fatal(err)
+	} else {
+		wsURL.Scheme = "ws"
+		conf.URL = wsURL.String()
+	}
+
+	m, err := NewWebsocket(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Read(); err != types.ErrNotConnected {
+		t.Errorf("Wrong error: %v != %v", err, types.ErrNotConnected)
+	}
+	m.CloseAsync()
+}
@@ -23,8 +23,10 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -36,25 +38,50 @@ import (
 
 //------------------------------------------------------------------------------
 
+// ZMQ4CurveConfig contains configuration fields for CURVE encryption and
+// authentication of a ZMQ4 socket. All keys are Z85 encoded.
+type ZMQ4CurveConfig struct {
+	Enabled         bool     `json:"enabled" yaml:"enabled"`
+	ServerPublicKey string   `json:"server_public_key" yaml:"server_public_key"`
+	PublicKey       string   `json:"public_key" yaml:"public_key"`
+	SecretKey       string   `json:"secret_key" yaml:"secret_key"`
+	AuthorizedKeys  []string `json:"authorized_keys" yaml:"authorized_keys"`
+}
+
+// NewZMQ4CurveConfig creates a new ZMQ4CurveConfig with default values.
+func NewZMQ4CurveConfig() ZMQ4CurveConfig {
+	return ZMQ4CurveConfig{
+		Enabled:         false,
+		ServerPublicKey: "",
+		PublicKey:       "",
+		SecretKey:       "",
+		AuthorizedKeys:  []string{},
+	}
+}
+
 // ZMQ4Config contains configuration fields for the ZMQ4 input type.
 type ZMQ4Config struct {
-	URLs          []string `json:"urls" yaml:"urls"`
-	Bind          bool     `json:"bind" yaml:"bind"`
-	SocketType    string   `json:"socket_type" yaml:"socket_type"`
-	SubFilters    []string `json:"sub_filters" yaml:"sub_filters"`
-	HighWaterMark int      `json:"high_water_mark" yaml:"high_water_mark"`
-	PollTimeout   string   `json:"poll_timeout" yaml:"poll_timeout"`
+	URLs              []string        `json:"urls" yaml:"urls"`
+	Bind              bool            `json:"bind" yaml:"bind"`
+	SocketType        string          `json:"socket_type" yaml:"socket_type"`
+	SubFilters        []string        `json:"sub_filters" yaml:"sub_filters"`
+	HighWaterMark     int             `json:"high_water_mark" yaml:"high_water_mark"`
+	SendHighWaterMark int             `json:"send_high_water_mark" yaml:"send_high_water_mark"`
+	PollTimeout       string          `json:"poll_timeout" yaml:"poll_timeout"`
+	Curve             ZMQ4CurveConfig `json:"curve" yaml:"curve"`
 }
 
 // NewZMQ4Config creates a new ZMQ4Config with default values.
 func NewZMQ4Config() *ZMQ4Config {
 	return &ZMQ4Config{
-		URLs:          []string{"tcp://localhost:5555"},
-		Bind:          false,
-		SocketType:    "PULL",
-		SubFilters:    []string{},
-		HighWaterMark: 0,
-		PollTimeout:   "5s",
+		URLs:              []string{"tcp://localhost:5555"},
+		Bind:              false,
+		SocketType:        "PULL",
+		SubFilters:        []string{},
+		HighWaterMark:     0,
+		SendHighWaterMark: 0,
+		PollTimeout:       "5s",
+		Curve:             NewZMQ4CurveConfig(),
 	}
 }
 
@@ -99,6 +126,20 @@ func NewZMQ4(conf *ZMQ4Config, log log.Modular, stats metrics.Type) (*ZMQ4, erro
 		}
 	}
 
+	if c := conf.Curve; c.Enabled {
+		if len(c.SecretKey) == 0 {
+			return nil, errors.New("curve secret_key must be set when curve is enabled")
+		}
+		if len(c.ServerPublicKey) > 0 {
+			if len(c.PublicKey) == 0 {
+				return nil, errors.New("curve public_key must be set when connecting to a curve server")
+			}
+			if len(c.AuthorizedKeys) > 0 {
+				return nil, errors.New("curve authorized_keys can only be set when acting as a curve server")
+			}
+		}
+	}
+
 	return &z, nil
 }
 
@@ -114,6 +155,31 @@ func getZMQType(t string) (zmq4.Type, error) {
 	return zmq4.PULL, types.ErrInvalidZMQType
 }
 
+// zmq4CurveDomain is the ZAP domain used for authenticating CURVE clients.
+const zmq4CurveDomain = "benthos"
+
+var zmq4AuthOnce sync.Once
+var zmq4AuthErr error
+
+// setupCurve configures CURVE encryption on a socket. When no server public key
+// is provided the socket acts as a CURVE server, and if authorized keys are
+// provided a ZAP handler is started in order to reject all other clients.
+func setupCurve(socket *zmq4.Socket, conf ZMQ4CurveConfig) error {
+	if len(conf.ServerPublicKey) > 0 {
+		return socket.ClientAuthCurve(conf.ServerPublicKey, conf.PublicKey, conf.SecretKey)
+	}
+	if len(conf.AuthorizedKeys) > 0 {
+		zmq4AuthOnce.Do(func() {
+			zmq4AuthErr = zmq4.AuthStart()
+		})
+		if zmq4AuthErr != nil {
+			return fmt.Errorf("failed to start ZAP handler: %v", zmq4AuthErr)
+		}
+		zmq4.AuthCurveAdd(zmq4CurveDomain, conf.AuthorizedKeys...)
+	}
+	return socket.ServerAuthCurve(zmq4CurveDomain, conf.SecretKey)
+}
+
 //------------------------------------------------------------------------------
 
 // Connect establishes a ZMQ4 socket.
@@ -127,14 +193,20 @@ func (z *ZMQ4) Connect() error {
 		return err
 	}
 
-	ctx, err := zmq4.NewContext()
-	if nil != err {
-		return err
-	}
-
 	var socket *zmq4.Socket
-	if socket, err = ctx.NewSocket(t); nil != err {
-		return err
+	if z.conf.Curve.Enabled && len(z.conf.Curve.AuthorizedKeys) > 0 {
+		// The ZAP handler only serves sockets of the default context.
+		if socket, err = zmq4.NewSocket(t); nil != err {
+			return err
+		}
+	} else {
+		var ctx *zmq4.Context
+		if ctx, err = zmq4.NewContext(); nil != err {
+			return err
+		}
+		if socket, err = ctx.NewSocket(t); nil != err {
+			return err
+		}
 	}
 
 	defer func() {
@@ -143,7 +215,18 @@ func (z *ZMQ4) Connect() error {
 		}
 	}()
 
-	socket.SetRcvhwm(z.conf.HighWaterMark)
+	if err = socket.SetRcvhwm(z.conf.HighWaterMark); err != nil {
+		return err
+	}
+	if err = socket.SetSndhwm(z.conf.SendHighWaterMark); err != nil {
+		return err
+	}
+
+	if z.conf.Curve.Enabled {
+		if err = setupCurve(socket, z.conf.Curve); err != nil {
+			return err
+		}
+	}
 
 	for _, address := range z.urls {
 		if z.conf.Bind {
@@ -171,6 +254,10 @@ func (z *ZMQ4) Connect() error {
 	} else {
 		z.log.Infof("Receiving ZMQ4 messages on connected URLs: %s\n", z.urls)
 	}
+	z.log.Infof(
+		"ZMQ4 socket high water marks set to receive: %v, send: %v, curve enabled: %v\n",
+		z.conf.HighWaterMark, z.conf.SendHighWaterMark, z.conf.Curve.Enabled,
+	)
 	return nil
 }
 
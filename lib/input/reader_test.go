@@ -195,6 +195,9 @@ func TestReaderTypeClosedOnReconn(t *testing.T) {
 		return
 	}
 
+	// Messages are only read once the transaction channel is requested.
+	r.TransactionChan()
+
 	go func() {
 		select {
 		case readerImpl.connChan <- nil:
@@ -232,6 +235,9 @@ func TestReaderTypeClosedOnReread(t *testing.T) {
 		return
 	}
 
+	// Messages are only read once the transaction channel is requested.
+	r.TransactionChan()
+
 	go func() {
 		select {
 		case readerImpl.connChan <- nil:
@@ -429,6 +435,9 @@ func TestReaderCloseDuringReconnect(t *testing.T) {
 		return
 	}
 
+	// Messages are only read once the transaction channel is requested.
+	r.TransactionChan()
+
 	select {
 	case readerImpl.connChan <- nil:
 	case <-time.After(time.Second):
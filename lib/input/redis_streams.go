@@ -42,7 +42,17 @@ can be split into individual messages with the ` + "`split`" + ` processor.
 
 Redis stream entries are key/value pairs, as such it is necessary to specify the
 key that contains the body of the message. All other keys/value pairs are saved
-as metadata fields.`,
+as metadata fields.
+
+Entries are only acknowledged once they have been successfully propagated, and
+acknowledgements are sent at most once every ` + "`commit_period`" + `. When
+the input starts it first consumes any entries that were delivered to its
+` + "`client_id`" + ` but never acknowledged.
+
+Entries that remain pending for other consumers of the group for longer than
+` + "`claim_min_idle`" + ` are assumed to belong to consumers that have died,
+and are claimed and consumed by this input. Set ` + "`claim_min_idle`" + ` to an
+empty string in order to disable claiming.`,
 	}
 }
 
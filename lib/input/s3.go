@@ -47,6 +47,12 @@ in the payload. However, it is also common practice to send bucket events to an
 SNS topic which sends enveloped events to SQS, in which case you must also set
 the ` + "`sqs_envelope_path`" + ` field to where the payload can be found.
 
+Object keys within S3 event notifications are URL encoded and are decoded
+before being downloaded. Events can reference objects from multiple buckets, in
+which case the field ` + "`sqs_bucket_path`" + ` can be set to where the bucket
+name is found in the payload (` + "`Records.s3.bucket.name`" + ` for S3 events),
+and the ` + "`bucket`" + ` field may then be left empty.
+
 Here is a guide for setting up an SQS queue that receives events for new S3
 bucket objects:
 
@@ -62,12 +68,31 @@ at-least-once crash resiliency, but also means that if the S3 item takes longer
 to process than the visibility timeout of your queue then the same items might
 be processed multiple times.
 
+### Decompression and Line Splitting
+
+The field ` + "`decompress`" + ` can be set to ` + "`gzip`" + ` in order to
+decompress all objects, or ` + "`auto`" + ` in order to only decompress objects
+that have a ` + "`.gz`" + ` suffix or a ` + "`Content-Encoding`" + ` of
+` + "`gzip`" + `.
+
+When ` + "`split_lines`" + ` is set to ` + "`true`" + ` each line of an object
+(separated by ` + "`delimiter`" + `, which defaults to a newline) becomes a part
+of a single message batch, which can be broken into individual messages with the
+` + "`split`" + ` processor. The object is only deleted (or its SQS message
+acknowledged) once the whole batch has been sent onwards.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
 
 ` + "```" + `
 - s3_key
+- s3_bucket
+- s3_last_modified (RFC3339)
+- s3_last_modified_unix
+- s3_content_type
+- s3_content_encoding
+- All user defined metadata
 ` + "```" + `
 
 You can access these metadata fields using
@@ -79,7 +104,7 @@ You can access these metadata fields using
 
 // NewAmazonS3 creates a new AWS S3 input type.
 func NewAmazonS3(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	if len(conf.S3.Bucket) == 0 {
+	if len(conf.S3.Bucket) == 0 && (len(conf.S3.SQSURL) == 0 || len(conf.S3.SQSBucketPath) == 0) {
 		return nil, errors.New("invalid bucket (cannot be empty)")
 	}
 	r, err := reader.NewAmazonS3(conf.S3, log, stats)
@@ -0,0 +1,280 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSequence] = TypeSpec{
+		constructor: NewSequence,
+		description: `
+Reads messages from a sequence of child inputs, starting with the first and
+moving onto the next only once the current input has closed. A child input is
+only created once the input before it has been exhausted, and the sequence
+input closes once the last child input closes.
+
+This is useful for bootstrapping a stream from a finite source before
+switching to a live source, for example replaying an archive from S3 before
+consuming from Kafka:
+
+` + "``` yaml" + `
+type: sequence
+sequence:
+  inputs:
+  - type: s3
+    s3:
+      bucket: my-archive
+      prefix: events/
+  - type: kafka
+    kafka:
+      addresses:
+      - localhost:9092
+      topic: events
+` + "```" + ``,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			var err error
+			inSlice := []interface{}{}
+			for _, input := range conf.Sequence.Inputs {
+				var sanInput interface{}
+				if sanInput, err = SanitiseConfig(input); err != nil {
+					return nil, err
+				}
+				inSlice = append(inSlice, sanInput)
+			}
+			return map[string]interface{}{
+				"inputs": inSlice,
+			}, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SequenceConfig contains configuration values for the Sequence input type.
+type SequenceConfig struct {
+	Inputs brokerInputList `json:"inputs" yaml:"inputs"`
+}
+
+// NewSequenceConfig creates a new SequenceConfig with default values.
+func NewSequenceConfig() SequenceConfig {
+	return SequenceConfig{
+		Inputs: brokerInputList{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Sequence is an input type that reads from a sequence of inputs, moving onto
+// the next input only once the current one has closed.
+type Sequence struct {
+	running int32
+	conf    SequenceConfig
+
+	targetMut sync.Mutex
+	target    Type
+
+	wrapperMgr   types.Manager
+	wrapperLog   log.Modular
+	wrapperStats metrics.Type
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactions chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewSequence creates a new Sequence input type.
+func NewSequence(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if len(conf.Sequence.Inputs) == 0 {
+		return nil, errors.New("requires at least one child input")
+	}
+
+	rdr := &Sequence{
+		running: 1,
+		conf:    conf.Sequence,
+
+		wrapperLog:   log,
+		wrapperStats: stats,
+		wrapperMgr:   mgr,
+
+		log:          log.NewModule(".sequence"),
+		stats:        metrics.Namespaced(stats, "sequence"),
+		transactions: make(chan types.Transaction),
+		closeChan:    make(chan struct{}),
+		closedChan:   make(chan struct{}),
+	}
+
+	// Create the first input eagerly so that config errors are surfaced
+	// immediately.
+	target, err := rdr.createInput(0)
+	if err != nil {
+		return nil, err
+	}
+	rdr.target = target
+
+	go rdr.loop()
+	return rdr, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Sequence) createInput(index int) (Type, error) {
+	conf := r.conf.Inputs[index]
+	ns := fmt.Sprintf("sequence.inputs.%v", index)
+	in, err := New(
+		conf, r.wrapperMgr,
+		r.wrapperLog.NewModule("."+ns),
+		metrics.Combine(r.wrapperStats, metrics.Namespaced(r.wrapperStats, ns)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input '%v': %v", conf.Type, err)
+	}
+	return in, nil
+}
+
+func (r *Sequence) getTarget() Type {
+	r.targetMut.Lock()
+	target := r.target
+	r.targetMut.Unlock()
+	return target
+}
+
+func (r *Sequence) setTarget(target Type) {
+	r.targetMut.Lock()
+	r.target = target
+	r.targetMut.Unlock()
+}
+
+func (r *Sequence) loop() {
+	var (
+		mRunning     = r.stats.GetGauge("running")
+		mInputClosed = r.stats.GetCounter("input.closed")
+		mInputErr    = r.stats.GetCounter("input.error")
+		mCount       = r.stats.GetCounter("count")
+	)
+
+	defer func() {
+		if target := r.getTarget(); target != nil {
+			target.CloseAsync()
+			err := target.WaitForClose(time.Second)
+			for ; err != nil; err = target.WaitForClose(time.Second) {
+			}
+		}
+		mRunning.Decr(1)
+
+		close(r.transactions)
+		close(r.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	index := 0
+	for atomic.LoadInt32(&r.running) == 1 {
+		target := r.getTarget()
+		if target == nil {
+			if index++; index >= len(r.conf.Inputs) {
+				r.log.Infoln("All inputs of the sequence have been exhausted.")
+				return
+			}
+			var err error
+			if target, err = r.createInput(index); err != nil {
+				mInputErr.Incr(1)
+				r.log.Errorf("%v\n", err)
+				return
+			}
+			r.log.Infof("Moving onto input '%v' at index %v of the sequence.\n", r.conf.Inputs[index].Type, index)
+			r.setTarget(target)
+		}
+
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-target.TransactionChan():
+			if !open {
+				mInputClosed.Incr(1)
+				r.setTarget(nil)
+				continue
+			}
+		case <-r.closeChan:
+			return
+		}
+		mCount.Incr(1)
+
+		select {
+		case r.transactions <- tran:
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (r *Sequence) TransactionChan() <-chan types.Transaction {
+	return r.transactions
+}
+
+// Connected returns a boolean indicating whether this input is currently
+// connected to its target.
+func (r *Sequence) Connected() bool {
+	if target := r.getTarget(); target != nil {
+		return target.Connected()
+	}
+	return false
+}
+
+// CloseAsync shuts down the Sequence input and stops processing requests.
+func (r *Sequence) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
+		close(r.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Sequence input has closed down.
+func (r *Sequence) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
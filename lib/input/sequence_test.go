@@ -0,0 +1,146 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func writeSequenceTestFile(t *testing.T, content string) string {
+	t.Helper()
+	tmpfile, err := ioutil.TempFile("", "benthos_sequence_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tmpfile.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err = tmpfile.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tmpfile.Name()
+}
+
+func TestSequenceInput(t *testing.T) {
+	pathOne := writeSequenceTestFile(t, "foo\nbar")
+	defer os.Remove(pathOne)
+	pathTwo := writeSequenceTestFile(t, "baz\nqux")
+	defer os.Remove(pathTwo)
+
+	confOne := NewConfig()
+	confOne.Type = TypeFile
+	confOne.File.Path = pathOne
+
+	confTwo := NewConfig()
+	confTwo.Type = TypeFile
+	confTwo.File.Path = pathTwo
+
+	conf := NewConfig()
+	conf.Type = TypeSequence
+	conf.Sequence.Inputs = append(conf.Sequence.Inputs, confOne, confTwo)
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expMsg := range []string{"foo", "bar", "baz", "qux"} {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-in.TransactionChan():
+			if !open {
+				t.Fatal("transaction chan closed")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		if exp, act := expMsg, string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message contents: %v != %v", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case _, open := <-in.TransactionChan():
+		if open {
+			t.Error("Expected transaction chan to close")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	if err = in.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSequenceEarlyClose(t *testing.T) {
+	path := writeSequenceTestFile(t, "foo\nbar")
+	defer os.Remove(path)
+
+	childConf := NewConfig()
+	childConf.Type = TypeFile
+	childConf.File.Path = path
+
+	conf := NewConfig()
+	conf.Type = TypeSequence
+	conf.Sequence.Inputs = append(conf.Sequence.Inputs, childConf, childConf)
+
+	in, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case _, open := <-in.TransactionChan():
+		if !open {
+			t.Fatal("transaction chan closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	in.CloseAsync()
+	if err = in.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSequenceNoInputs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSequence
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty sequence")
+	}
+}
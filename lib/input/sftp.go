@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSFTP] = TypeSpec{
+		constructor: NewSFTP,
+		description: `
+Polls a directory of an SFTP server every ` + "`poll_interval`" + ` for new
+files and reads them as messages.
+
+The client authenticates with a ` + "`password`" + `, a
+` + "`private_key_file`" + ` or both. The host key of the server is verified
+against the ` + "`known_hosts_file`" + ` when set, otherwise it is not verified.
+` + remoteFilesDescription,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// remoteFilesDescription describes the fields common to the sftp and ftp
+// inputs.
+const remoteFilesDescription = `
+Files are read in order of their modification time. When ` + "`split_lines`" + `
+is true each line of a file (separated by ` + "`delimiter`" + `, which defaults
+to a line feed) becomes a message, otherwise the entire contents of a file
+becomes a single message.
+
+The ` + "`after_read`" + ` field determines what happens to a file once all of
+its messages have been successfully propagated, and can be one of
+` + "`none`" + `, ` + "`delete`" + ` or ` + "`move`" + `. When ` + "`move`" + `
+the file is renamed into the ` + "`move_to`" + ` directory, which must already
+exist. When ` + "`none`" + ` files are left untouched and are only read again if
+their size or modification time changes, this is tracked in memory and therefore
+all files are read again when Benthos restarts.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- remote_file_path
+- remote_file_name
+- remote_file_size
+- remote_file_mod_time_unix
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`
+
+//------------------------------------------------------------------------------
+
+// NewSFTP creates a new SFTP input type.
+func NewSFTP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := reader.NewSFTP(conf.SFTP, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("sftp", reader.NewPreserver(s), log, stats)
+}
+
+//------------------------------------------------------------------------------
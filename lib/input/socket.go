@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSocket] = TypeSpec{
+		constructor: NewSocket,
+		description: `
+Listens for connections on a unix domain socket or TCP address, selected with
+the ` + "`network`" + ` field (` + "`unix`" + ` or ` + "`tcp`" + `), and reads
+messages from each connection. A stale unix socket file left behind by a
+previous run is removed before listening.
+
+If multipart is set to false each line is read as a separate message. If
+multipart is set to true each line is read as a message part, and an empty line
+indicates the end of a message. If the delimiter field is left empty then line
+feed (\n) is used.
+
+Messages are read from connections one at a time, and so a connection that
+sends faster than messages can be delivered is slowed down. Sockets provide no
+way of acknowledging messages, therefore messages that are received but not yet
+delivered will be lost if the service is shut down.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSocket creates a new Socket input type.
+func NewSocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := reader.NewSocket(conf.Socket, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("socket", reader.NewPreserver(s), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -34,7 +34,33 @@ func init() {
 		constructor: NewAmazonSQS,
 		description: `
 Receive messages from an Amazon SQS URL, only the body is extracted into
-messages.`,
+messages.
+
+Up to ` + "`max_number_of_messages`" + ` (at most 10) messages are received with
+each request and are batched together into a single message. Messages are only
+deleted from the queue once they have been successfully propagated to the
+output. Messages that fail to be propagated have their visibility timeout reset
+so that they are redelivered immediately.
+
+The field ` + "`visibility_timeout`" + ` sets the visibility timeout of each
+received message. When ` + "`extend_visibility`" + ` is set to ` + "`true`" + `
+the visibility timeout of messages that are still pending acknowledgement is
+periodically extended, which prevents them from being redelivered to other
+consumers whilst they are being processed.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- sqs_message_id
+- sqs_receipt_handle
+- sqs_approximate_receive_count
+- All message attributes with string values
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
 	}
 }
 
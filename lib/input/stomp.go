@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSTOMP] = TypeSpec{
+		constructor: NewSTOMP,
+		description: `
+Subscribes to a destination of a STOMP server, such as ActiveMQ or RabbitMQ
+with the STOMP plugin enabled. Protocol versions 1.1 and 1.2 are supported.
+
+The subscription uses the ` + "`client-individual`" + ` ack mode, where each
+message is acknowledged once it has been successfully propagated. Messages that
+fail to be propagated are negatively acknowledged, leaving it to the server to
+redeliver or dead letter them.
+
+The ` + "`host`" + ` field sets the virtual host of the connection, and any
+` + "`headers`" + ` are added to the subscription request, which can be used to
+set broker specific options such as ` + "`prefetch-count`" + ` for RabbitMQ or
+` + "`activemq.prefetchSize`" + ` for ActiveMQ.
+
+` + tls.Documentation + `
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- stomp_destination
+- stomp_message_id
+- stomp_subscription
+- stomp_content_type
+- All other message headers
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSTOMP creates a new STOMP input type.
+func NewSTOMP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := reader.NewSTOMP(conf.STOMP, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("stomp", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSubprocess] = TypeSpec{
+		constructor: NewSubprocess,
+		description: `
+Executes a command and consumes messages from its stdout, which allows you to
+wrap any tool that writes data to stdout. Anything written to stderr by the
+command is logged at the warning level.
+
+The ` + "`codec`" + ` field determines how messages are read from stdout. With
+` + "`lines`" + ` each non-empty line is a message, and with
+` + "`length_prefixed`" + ` each message is preceded by its size as a four byte
+big endian unsigned integer. Messages larger than ` + "`max_buffer`" + ` bytes
+are treated as a failure of the command.
+
+If ` + "`restart_on_exit`" + ` is true then the command is restarted whenever it
+exits, otherwise the input closes. Restarts are delayed by
+` + "`restart_backoff`" + `, which doubles each time the command exits without
+producing any messages up to a maximum of ` + "`max_restart_backoff`" + `.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSubprocess creates a new subprocess input type.
+func NewSubprocess(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := reader.NewSubprocess(conf.Subprocess, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(
+		"subprocess",
+		reader.NewPreserver(s),
+		log, stats,
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSyslog] = TypeSpec{
+		constructor: NewSyslog,
+		description: `
+Listens for syslog messages at an address over ` + "`udp`" + `, ` + "`tcp`" + `
+or ` + "`tls`" + `. When using TLS the fields ` + "`cert_file`" + ` and
+` + "`key_file`" + ` must be set.
+
+Messages are parsed according to ` + "`format`" + `, which can be
+` + "`rfc5424`" + `, ` + "`rfc3164`" + ` or ` + "`auto`" + ` in order to detect
+the format of each message. Streams received over TCP can be framed either with
+octet counting or newlines as per RFC 6587, and the largest accepted frame size
+is set by ` + "`max_buffer`" + `.
+
+Each message is converted into a JSON document of the following form, where
+fields that are not present in the message are omitted:
+
+` + "``` json" + `
+{
+  "facility": 20,
+  "severity": 5,
+  "version": 1,
+  "timestamp": "2003-10-11T22:14:15.003Z",
+  "hostname": "mymachine.example.com",
+  "app_name": "evntslog",
+  "proc_id": "1234",
+  "msg_id": "ID47",
+  "structured_data": {
+    "exampleSDID@32473": {"eventID": "1011"}
+  },
+  "message": "An application event log entry..."
+}
+` + "```" + `
+
+Messages that cannot be parsed are passed on in their raw form with the metadata
+field ` + "`syslog_parse_error`" + ` set.
+
+Syslog provides no way of acknowledging messages, therefore messages that are
+received but not yet delivered will be lost if the service is shut down.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- syslog_remote_addr
+- syslog_facility
+- syslog_severity
+- syslog_hostname
+- syslog_app_name
+- syslog_proc_id
+- syslog_msg_id
+- syslog_parse_error
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](../config_interpolation.md#metadata).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSyslog creates a new Syslog input type.
+func NewSyslog(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := reader.NewSyslog(conf.Syslog, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("syslog", reader.NewPreserver(s), log, stats)
+}
+
+//------------------------------------------------------------------------------
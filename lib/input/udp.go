@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package input
+
+import (
+	"github.com/Jeffail/benthos/lib/input/reader"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeUDP] = TypeSpec{
+		constructor: NewUDP,
+		description: `
+Listens for datagrams at a UDP address. By default each datagram is read as a
+single message. If a ` + "`delimiter`" + ` is set then datagrams are split by
+it and each non-empty segment is read as a separate message, which suits feeds
+such as statsd where a datagram may contain multiple newline delimited lines.
+
+Datagrams larger than ` + "`max_datagram_size`" + ` bytes are dropped. When
+` + "`source_metadata`" + ` is true the address of the sender is added to each
+message as the metadata field ` + "`udp_source_address`" + `.
+
+The number of goroutines reading from the socket is set with
+` + "`readers`" + `, which can be increased for high packet rates. The
+` + "`read_buffer_size`" + ` field sets the size of the receive buffer of the
+socket in bytes, and when left at zero the operating system default is used.
+
+UDP provides no way of acknowledging messages, therefore datagrams that are
+received but not yet delivered will be lost if the service is shut down, and
+datagrams may also be dropped by the operating system when the receive buffer
+is full.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewUDP creates a new UDP input type.
+func NewUDP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	u, err := reader.NewUDP(conf.UDP, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader("udp", reader.NewPreserver(u), log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -33,7 +33,20 @@ func init() {
 	Constructors[TypeWebsocket] = TypeSpec{
 		constructor: NewWebsocket,
 		description: `
-Sends messages to an HTTP server via a websocket connection.`,
+Connects to a websocket server and continuously receives messages.
+
+It is possible to configure an ` + "`open_message`" + `, which when set to a
+non-empty string will be sent to the websocket server each time a connection is
+first established, this is useful for protocols that require a subscription
+request before data is sent.
+
+When ` + "`ping_period`" + ` is set a ping is sent to the server at that
+interval, and the connection is considered dead if no pong or message is
+received within ` + "`pong_timeout`" + ` of a ping being due.
+
+Lost connections are reestablished automatically, with subsequent attempts
+delayed according to the exponential ` + "`reconnect_backoff`" + ` until a
+message is successfully received again.`,
 	}
 }
 
@@ -42,7 +42,20 @@ Build it into your project by getting libzmq installed on your machine, then
 build with the tag: 'go install -tags "ZMQ4" github.com/Jeffail/benthos/cmd/...'
 
 ZMQ4 input supports PULL and SUB sockets only. If there is demand for other
-socket types then they can be added easily.`,
+socket types then they can be added easily.
+
+The field ` + "`high_water_mark`" + ` sets the receive high water mark (RCVHWM)
+of the socket and ` + "`send_high_water_mark`" + ` sets the send high water mark
+(SNDHWM), where a value of zero means no limit.
+
+### CURVE
+
+CURVE encryption and authentication can be enabled with the ` + "`curve`" + `
+fields, where all keys are Z85 encoded. When ` + "`server_public_key`" + ` is
+set the socket acts as a CURVE client and requires ` + "`public_key`" + ` and
+` + "`secret_key`" + `. Otherwise the socket acts as a CURVE server using
+` + "`secret_key`" + `, and if ` + "`authorized_keys`" + ` is non-empty only
+clients with one of those public keys are allowed to connect.`,
 	}
 }
 
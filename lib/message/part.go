@@ -21,6 +21,7 @@
 package message
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/Jeffail/benthos/lib/message/metadata"
@@ -35,6 +36,7 @@ type Part struct {
 	data      []byte
 	metadata  types.Metadata
 	jsonCache interface{}
+	ctx       context.Context
 }
 
 // NewPart initializes a new message part.
@@ -63,6 +65,7 @@ func (p *Part) Copy() types.Part {
 		data:      p.data,
 		metadata:  clonedMeta,
 		jsonCache: clonedJSON,
+		ctx:       p.ctx,
 	}
 }
 
@@ -88,6 +91,7 @@ func (p *Part) DeepCopy() types.Part {
 		data:      np,
 		metadata:  clonedMeta,
 		jsonCache: clonedJSON,
+		ctx:       p.ctx,
 	}
 }
 
@@ -160,3 +164,23 @@ func (p *Part) IsEmpty() bool {
 }
 
 //------------------------------------------------------------------------------
+
+// GetContext returns the context of a message part, which carries values that
+// components attach to the part and, unlike metadata, cannot be read or set by
+// users. A background context is returned when the part has none.
+func GetContext(p types.Part) context.Context {
+	if mp, ok := p.(*Part); ok && mp.ctx != nil {
+		return mp.ctx
+	}
+	return context.Background()
+}
+
+// SetContext sets the context of a message part, which is retained by copies
+// of the part. Parts that are not implemented by this package are unchanged.
+func SetContext(p types.Part, ctx context.Context) {
+	if mp, ok := p.(*Part); ok {
+		mp.ctx = ctx
+	}
+}
+
+//------------------------------------------------------------------------------
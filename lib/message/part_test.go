@@ -21,10 +21,12 @@
 package message
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	"github.com/Jeffail/benthos/lib/message/metadata"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestPartBasic(t *testing.T) {
@@ -194,3 +196,26 @@ func TestPartDeepCopy(t *testing.T) {
 		t.Errorf("Metadata changed after copy: %v != %v", act, exp)
 	}
 }
+
+func TestPartContext(t *testing.T) {
+	type ctxKey int
+
+	p := NewPart([]byte("hello"))
+	if GetContext(p) == nil {
+		t.Fatal("Expected background context")
+	}
+
+	SetContext(p, context.WithValue(context.Background(), ctxKey(0), "foo"))
+	for name, part := range map[string]types.Part{
+		"original":  p,
+		"copy":      p.Copy(),
+		"deep copy": p.DeepCopy(),
+	} {
+		if act := GetContext(part).Value(ctxKey(0)); act != "foo" {
+			t.Errorf("Wrong context value of %v: %v != %v", name, act, "foo")
+		}
+	}
+	if act := GetContext(NewPart(nil)).Value(ctxKey(0)); act != nil {
+		t.Errorf("Unexpected context value: %v", act)
+	}
+}
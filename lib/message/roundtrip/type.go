@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package roundtrip
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ResultStore is a thread safe collection of messages produced in response to
+// a message.
+type ResultStore interface {
+	// Add a message to the store.
+	Add(msg types.Message)
+
+	// Get the messages of the store.
+	Get() []types.Message
+}
+
+type resultStore struct {
+	msgs []types.Message
+	mut  sync.Mutex
+}
+
+func (r *resultStore) Add(msg types.Message) {
+	r.mut.Lock()
+	r.msgs = append(r.msgs, msg.DeepCopy())
+	r.mut.Unlock()
+}
+
+func (r *resultStore) Get() []types.Message {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return append([]types.Message(nil), r.msgs...)
+}
+
+//------------------------------------------------------------------------------
+
+type storeKeyType int
+
+// storeKey is the context key of the result store of a message part.
+const storeKey storeKeyType = 0
+
+// NewResultStore creates an empty result store.
+func NewResultStore() ResultStore {
+	return &resultStore{}
+}
+
+// SetResultStore attaches a result store to the context of each part of a
+// message, so that outputs can add results to it with AddResults. The store is
+// retained by copies of the parts, but unlike metadata it cannot be read or
+// set by users, and is therefore lost by parts that are replaced rather than
+// modified.
+func SetResultStore(msg types.Message, store ResultStore) {
+	msg.Iter(func(i int, p types.Part) error {
+		message.SetContext(p, context.WithValue(message.GetContext(p), storeKey, store))
+		return nil
+	})
+}
+
+// AddResults adds a message of results to each result store attached to the
+// parts of a message, and returns the number of stores the results were added
+// to.
+func AddResults(msg types.Message, results types.Message) int {
+	stores := map[ResultStore]struct{}{}
+	msg.Iter(func(i int, p types.Part) error {
+		if store, ok := message.GetContext(p).Value(storeKey).(ResultStore); ok {
+			stores[store] = struct{}{}
+		}
+		return nil
+	})
+	for store := range stores {
+		store.Add(results)
+	}
+	return len(stores)
+}
+
+//------------------------------------------------------------------------------
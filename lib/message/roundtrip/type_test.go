@@ -0,0 +1,70 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package roundtrip
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+func TestResultStore(t *testing.T) {
+	storeA, storeB := NewResultStore(), NewResultStore()
+
+	msgA := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	SetResultStore(msgA, storeA)
+
+	msgB := message.New([][]byte{[]byte("baz")})
+	SetResultStore(msgB, storeB)
+
+	// A batch that combines parts from both messages.
+	batch := message.New(nil)
+	batch.Append(msgA.Get(0), msgA.Get(1).Copy(), msgB.Get(0))
+
+	if exp, act := 2, AddResults(batch, message.New([][]byte{[]byte("result")})); exp != act {
+		t.Errorf("Wrong count of stores: %v != %v", act, exp)
+	}
+	if exp, act := 1, AddResults(msgA, message.New([][]byte{[]byte("second")})); exp != act {
+		t.Errorf("Wrong count of stores: %v != %v", act, exp)
+	}
+	if exp, act := 0, AddResults(message.New([][]byte{[]byte("none")}), message.New(nil)); exp != act {
+		t.Errorf("Wrong count of stores: %v != %v", act, exp)
+	}
+
+	// Metadata is unable to reference a store.
+	spoofed := message.New([][]byte{[]byte("spoofed")})
+	spoofed.Get(0).Metadata().Set("benthos_result_store", "1")
+	if exp, act := 0, AddResults(spoofed, message.New(nil)); exp != act {
+		t.Errorf("Wrong count of stores: %v != %v", act, exp)
+	}
+
+	var results [][]byte
+	for _, m := range storeA.Get() {
+		results = append(results, message.GetAllBytes(m)...)
+	}
+	if exp := [][]byte{[]byte("result"), []byte("second")}; !reflect.DeepEqual(exp, results) {
+		t.Errorf("Wrong results: %s != %s", results, exp)
+	}
+	if exp, act := 1, len(storeB.Get()); exp != act {
+		t.Errorf("Wrong count of results: %v != %v", act, exp)
+	}
+}
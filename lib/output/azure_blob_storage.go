@@ -0,0 +1,65 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAzureBlobStorage] = TypeSpec{
+		constructor: NewAzureBlobStorage,
+		description: `
+Sends message parts as blobs to an Azure Blob Storage container. Both the
+` + "`container`" + ` and the ` + "`path`" + ` of each blob can be dynamically
+set using function interpolations described
+[here](../config_interpolation.md#functions), which are calculated per message
+of a batch. The container must already exist.
+
+When ` + "`blob_type`" + ` is ` + "`block`" + ` each message part is uploaded as
+a block blob, replacing any existing blob of the same path. When
+` + "`append`" + ` each message part is appended to an append blob, which is
+created if it does not yet exist. Append blobs are useful for accumulating logs
+from many messages within a single blob, but are limited to 50,000 appended
+blocks of up to 4MiB each.
+
+` + storage.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAzureBlobStorage creates a new Azure Blob Storage output type.
+func NewAzureBlobStorage(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	a, err := writer.NewAzureBlobStorage(conf.AzureBlobStorage, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("azure_blob_storage", a, log, stats)
+}
+
+//------------------------------------------------------------------------------
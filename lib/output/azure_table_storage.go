@@ -0,0 +1,79 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeAzureTableStorage] = TypeSpec{
+		constructor: NewAzureTableStorage,
+		description: `
+Writes message parts as entities to an Azure Table Storage table. The
+` + "`table_name`" + `, ` + "`partition_key`" + ` and ` + "`row_key`" + ` of
+each entity can be dynamically set using function interpolations described
+[here](../config_interpolation.md#functions), which are calculated per message
+of a batch. The table must already exist.
+
+When ` + "`properties`" + ` is empty each message part must be a JSON object,
+and its top level fields become the properties of the entity, where nested
+objects and arrays are stored as JSON strings. Otherwise the entity properties
+are the interpolated values of the ` + "`properties`" + ` map.
+
+Message parts of a batch are written using entity group transactions, where
+each transaction contains up to 100 entities of the same table and partition
+key. Entities of a transaction are written atomically, but a batch spanning
+multiple transactions may be partially written when an error occurs.
+
+The ` + "`insert_type`" + ` can be ` + "`INSERT`" + `, which fails when the
+entity already exists, ` + "`INSERT_OR_MERGE`" + `, which updates the properties
+of an existing entity, or ` + "`INSERT_OR_REPLACE`" + `, which replaces an
+existing entity. Only the latter two are idempotent, and are therefore safe
+when failed batches are retried.
+
+In order to write to the Cosmos DB Table API set ` + "`storage_endpoint`" + `
+to the table endpoint of the account, e.g.
+` + "`https://foo.table.cosmos.azure.com`" + `, and
+` + "`storage_access_key`" + ` to its primary key.
+
+` + storage.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAzureTableStorage creates a new Azure Table Storage output type.
+func NewAzureTableStorage(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	a, err := writer.NewAzureTableStorage(conf.AzureTableStorage, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("azure_table_storage", a, log, stats)
+}
+
+//------------------------------------------------------------------------------
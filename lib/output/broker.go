@@ -21,13 +21,20 @@
 package output
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/broker"
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/Jeffail/benthos/lib/util/text"
 )
 
 //------------------------------------------------------------------------------
@@ -75,9 +82,40 @@ and can be chosen from the following:
 #### ` + "`fan_out`" + `
 
 With the fan out pattern all outputs will be sent every message that passes
-through Benthos. If an output applies back pressure it will block all subsequent
-messages, and if an output fails to send a message it will be retried
-continuously until completion or service shut down.
+through Benthos. A message is only acknowledged once all outputs have sent it.
+
+Each output may fall behind the others by up to ` + "`fan_out.max_in_flight`" + `
+messages, allowing the remaining outputs to continue whilst one is stalled.
+Once an output reaches this limit it applies back pressure, blocking all
+subsequent messages. Note that more than one message can only be in flight when
+there are multiple input sources or processing threads.
+
+By default if an output fails to send a message it will be retried continuously
+until completion or service shut down. Alternatively, a
+` + "`fan_out.dead_letter`" + ` output can be set along with a retry budget
+` + "`fan_out.max_retries`" + `, in which case a message that still fails on an
+output after that many retries is sent to the dead letter output instead, and
+the other outputs are unaffected:
+
+` + "``` yaml" + `
+output:
+  type: broker
+  broker:
+    pattern: fan_out
+    fan_out:
+      max_in_flight: 10
+      max_retries: 3
+      dead_letter:
+        type: file
+        file:
+          path: ./dead_letters.jsonl
+    outputs:
+    - type: foo
+    - type: bar
+` + "```" + `
+
+The metric ` + "`broker.outputs.N.dead_letter`" + ` counts the messages of
+each output that were sent to the dead letter output.
 
 #### ` + "`round_robin`" + `
 
@@ -95,6 +133,15 @@ messages as soon as they are able to process them. This results in certain
 faster outputs potentially processing more messages at the cost of slower
 outputs.
 
+#### ` + "`least_pending`" + `
+
+The least pending pattern sends each message to the output with the fewest
+messages that are still awaiting a response, where ties are broken in round
+robin fashion. Unlike the greedy pattern this spreads messages evenly across
+outputs of a similar speed, whilst still steering messages away from an output
+that is temporarily slow. The gauge ` + "`broker.outputs.N.pending`" + ` exposes
+the number of pending messages of each output.
+
 #### ` + "`try`" + `
 
 The try pattern attempts to send each message to only one output, starting from
@@ -106,6 +153,78 @@ targets have broken. For example, if you had an output type ` + "`http_client`"
 but wished to reroute messages whenever the endpoint becomes unreachable you
 could use a try broker.
 
+By default a failed message fails over to the next output immediately. In order
+to ride out transient errors each output can be given a retry budget with
+` + "`try.max_retries`" + `, where a failed message is retried against the same
+output up to that many times, following the exponential backoff configured in
+` + "`try.backoff`" + `, before failing over. A non-zero
+` + "`try.backoff.max_elapsed_time`" + ` also limits the total time spent
+retrying each output.
+
+The metrics ` + "`broker.outputs.N.retry`" + ` and
+` + "`broker.outputs.N.failover`" + ` count the retries of each output and the
+number of messages that failed over from it to the next output respectively.
+
+#### ` + "`failover`" + `
+
+The failover pattern sends each message to the first healthy output of the list,
+where the first output is the primary. The health of each output is checked
+from its connection status every ` + "`failover.check_interval`" + `. An output
+becomes unhealthy after failing ` + "`failover.unhealthy_threshold`" + `
+consecutive checks, at which point messages are routed to the next healthy
+output. Once it passes ` + "`failover.healthy_threshold`" + ` consecutive checks
+it becomes healthy again and messages fail back to it, where a higher healthy
+threshold prevents traffic from flapping between outputs with an intermittent
+connection. If no outputs are healthy messages are sent to the primary.
+
+The gauges ` + "`broker.failover.active`" + ` and
+` + "`broker.outputs.N.healthy`" + ` expose the index of the active output and
+the health of each output respectively.
+
+#### ` + "`shadow`" + `
+
+The shadow pattern sends every message to the first output of the list, the
+primary, and a sample of messages to all other outputs, the shadows. The
+percentage of messages sent to shadows is set with
+` + "`shadow.sample_percent`" + `. Only the primary determines whether a
+message is acknowledged, failures of shadow outputs are counted by the metric
+` + "`broker.outputs.N.failed`" + ` and otherwise ignored.
+
+Shadow outputs never apply back pressure to the primary. If a shadow output is
+still busy with a previous message then the sampled message is dropped for that
+output, which is counted by the metric ` + "`broker.outputs.N.dropped`" + `.
+This pattern is useful for safely testing a new output against production
+traffic.
+
+#### ` + "`hash`" + `
+
+The hash pattern sends each message to a single output chosen by a consistent
+hash of ` + "`hash_key`" + `, which supports
+[function interpolations](../config_interpolation.md#functions) such as
+` + "`${!metadata:user_id}`" + ` or ` + "`${!json_field:user.id}`" + ` and is
+resolved for each message of a batch. All messages sharing a key are therefore
+routed to the same output, preserving their order. Batches containing messages
+of keys routed to different outputs are split into a batch per output, and are
+only acknowledged once all outputs have succeeded. If an output applies back
+pressure it will block all subsequent messages.
+
+### Copies
+
+The field ` + "`copies`" + ` sets the number of copies of the list of outputs
+to create, which is useful for increasing the parallelism of patterns such as
+` + "`greedy`" + `. Like any other field it can be set with
+[environment variable interpolation](../config_interpolation.md#environment-variables),
+e.g. ` + "`copies: ${OUTPUT_COPIES:1}`" + `.
+
+When ` + "`copies_endpoint`" + ` is set to a path, such as
+` + "`/broker/copies`" + `, the number of copies of a ` + "`greedy`" + ` broker
+can also be changed at runtime. A ` + "`GET`" + ` request to the endpoint
+returns the current number of copies as a JSON object of the form
+` + "`{\"copies\":1}`" + `, and a ` + "`POST`" + ` request with the same object
+as the body scales the broker to that number of copies. Copies can be added and
+removed, but never fewer than the ` + "`copies`" + ` configured. Removed copies
+finish sending any messages they have already claimed before shutting down.
+
 ### Utilising More Outputs
 
 When using brokered outputs with patterns such as round robin or greedy it is
@@ -119,7 +238,62 @@ It is possible to configure [processors](../processors/README.md) at the broker
 level, where they will be applied to _all_ child outputs, as well as on the
 individual child outputs. If you have processors at both the broker level _and_
 on child outputs then the broker processors will be applied _before_ the child
-nodes processors.`,
+nodes processors.
+
+### Per-Output Batching
+
+Each child output can declare its own [` + "`batching`" + `](#batching)
+policy. For example, the following fan out broker sends each message to an
+HTTP endpoint as soon as it arrives, whilst accumulating batches of 100
+messages for an S3 archive:
+
+` + "``` yaml" + `
+output:
+  type: broker
+  broker:
+    pattern: fan_out
+    outputs:
+    - type: http_client
+      http_client:
+        url: http://localhost:8080/post
+    - type: s3
+      s3:
+        bucket: archive
+      batching:
+        count: 100
+        period: 10s
+      processors:
+      - type: archive
+        archive:
+          format: lines
+` + "```" + `
+
+Messages pending within the batch of any child output are not acknowledged
+until that batch has been sent, preserving at-least-once delivery guarantees.
+As with all batching policies a pending batch is only checked when a new
+message is added, meaning a batch can outlive its ` + "`period`" + ` when
+traffic stops.
+
+### Metrics
+
+All patterns other than ` + "`greedy`" + ` expose the following gauges for
+each child output under the namespace ` + "`broker.outputs.N`" + `, which make
+it possible to identify a child output that is applying back pressure:
+
+- ` + "`in_flight`" + `: The number of messages sent to the output that are
+  awaiting a response.
+- ` + "`last_error`" + `: The unix timestamp of the latest error returned by
+  the output.
+- ` + "`time_blocked`" + `: The total nanoseconds the broker has spent waiting
+  for the output to accept messages.
+- ` + "`queue_depth`" + `: The number of messages queued for the output
+  whilst it has messages in flight, which only applies to the
+  ` + "`fan_out`" + ` pattern.
+
+The ` + "`greedy`" + ` pattern does not expose these gauges, as its child
+outputs take messages from the broker input whenever they are ready rather than
+the broker routing each message, and therefore the broker does not observe the
+responses of an output.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			nestedOutputs := conf.Broker.Outputs
 			outSlice := []interface{}{}
@@ -130,10 +304,28 @@ nodes processors.`,
 				}
 				outSlice = append(outSlice, sanOutput)
 			}
+			fanOut := map[string]interface{}{
+				"max_in_flight": conf.Broker.FanOut.MaxInFlight,
+				"max_retries":   conf.Broker.FanOut.MaxRetries,
+				"dead_letter":   struct{}{},
+			}
+			if conf.Broker.FanOut.DeadLetter != nil {
+				sanDeadLetter, err := SanitiseConfig(*conf.Broker.FanOut.DeadLetter)
+				if err != nil {
+					return nil, err
+				}
+				fanOut["dead_letter"] = sanDeadLetter
+			}
 			return map[string]interface{}{
-				"copies":  conf.Broker.Copies,
-				"pattern": conf.Broker.Pattern,
-				"outputs": outSlice,
+				"copies":          conf.Broker.Copies,
+				"pattern":         conf.Broker.Pattern,
+				"hash_key":        conf.Broker.HashKey,
+				"copies_endpoint": conf.Broker.CopiesEndpoint,
+				"fan_out":         fanOut,
+				"try":             conf.Broker.Try,
+				"failover":        conf.Broker.Failover,
+				"shadow":          conf.Broker.Shadow,
+				"outputs":         outSlice,
 			}, nil
 		},
 	}
@@ -141,18 +333,104 @@ nodes processors.`,
 
 //------------------------------------------------------------------------------
 
+// BrokerFanOutConfig contains configuration fields for the fan_out pattern of
+// the Broker output type.
+type BrokerFanOutConfig struct {
+	MaxInFlight int     `json:"max_in_flight" yaml:"max_in_flight"`
+	MaxRetries  uint64  `json:"max_retries" yaml:"max_retries"`
+	DeadLetter  *Config `json:"dead_letter" yaml:"dead_letter"`
+}
+
+type dummyBrokerFanOutConfig struct {
+	MaxInFlight int         `json:"max_in_flight" yaml:"max_in_flight"`
+	MaxRetries  uint64      `json:"max_retries" yaml:"max_retries"`
+	DeadLetter  interface{} `json:"dead_letter" yaml:"dead_letter"`
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (f BrokerFanOutConfig) MarshalJSON() ([]byte, error) {
+	dummy := dummyBrokerFanOutConfig{
+		MaxInFlight: f.MaxInFlight,
+		MaxRetries:  f.MaxRetries,
+		DeadLetter:  f.DeadLetter,
+	}
+	if f.DeadLetter == nil {
+		dummy.DeadLetter = struct{}{}
+	}
+	return json.Marshal(dummy)
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (f BrokerFanOutConfig) MarshalYAML() (interface{}, error) {
+	dummy := dummyBrokerFanOutConfig{
+		MaxInFlight: f.MaxInFlight,
+		MaxRetries:  f.MaxRetries,
+		DeadLetter:  f.DeadLetter,
+	}
+	if f.DeadLetter == nil {
+		dummy.DeadLetter = struct{}{}
+	}
+	return dummy, nil
+}
+
+// BrokerTryConfig contains configuration fields for the try pattern of the
+// Broker output type.
+type BrokerTryConfig struct {
+	MaxRetries uint64          `json:"max_retries" yaml:"max_retries"`
+	Backoff    retries.Backoff `json:"backoff" yaml:"backoff"`
+}
+
+// BrokerFailoverConfig contains configuration fields for the failover pattern
+// of the Broker output type.
+type BrokerFailoverConfig struct {
+	CheckInterval      string `json:"check_interval" yaml:"check_interval"`
+	UnhealthyThreshold int    `json:"unhealthy_threshold" yaml:"unhealthy_threshold"`
+	HealthyThreshold   int    `json:"healthy_threshold" yaml:"healthy_threshold"`
+}
+
+// BrokerShadowConfig contains configuration fields for the shadow pattern of
+// the Broker output type.
+type BrokerShadowConfig struct {
+	SamplePercent float64 `json:"sample_percent" yaml:"sample_percent"`
+}
+
 // BrokerConfig contains configuration fields for the Broker output type.
 type BrokerConfig struct {
-	Copies  int              `json:"copies" yaml:"copies"`
-	Pattern string           `json:"pattern" yaml:"pattern"`
-	Outputs brokerOutputList `json:"outputs" yaml:"outputs"`
+	Copies         int                  `json:"copies" yaml:"copies"`
+	CopiesEndpoint string               `json:"copies_endpoint" yaml:"copies_endpoint"`
+	Pattern        string               `json:"pattern" yaml:"pattern"`
+	HashKey        string               `json:"hash_key" yaml:"hash_key"`
+	FanOut         BrokerFanOutConfig   `json:"fan_out" yaml:"fan_out"`
+	Try            BrokerTryConfig      `json:"try" yaml:"try"`
+	Failover       BrokerFailoverConfig `json:"failover" yaml:"failover"`
+	Shadow         BrokerShadowConfig   `json:"shadow" yaml:"shadow"`
+	Outputs        brokerOutputList     `json:"outputs" yaml:"outputs"`
 }
 
 // NewBrokerConfig creates a new BrokerConfig with default values.
 func NewBrokerConfig() BrokerConfig {
 	return BrokerConfig{
-		Copies:  1,
-		Pattern: "fan_out",
+		Copies:         1,
+		Pattern:        "fan_out",
+		HashKey:        "",
+		CopiesEndpoint: "",
+		FanOut: BrokerFanOutConfig{
+			MaxInFlight: 1,
+			MaxRetries:  0,
+			DeadLetter:  nil,
+		},
+		Try: BrokerTryConfig{
+			MaxRetries: 0,
+			Backoff:    retries.NewConfig().Backoff,
+		},
+		Failover: BrokerFailoverConfig{
+			CheckInterval:      "1s",
+			UnhealthyThreshold: 2,
+			HealthyThreshold:   5,
+		},
+		Shadow: BrokerShadowConfig{
+			SamplePercent: 100,
+		},
 		Outputs: brokerOutputList{},
 	}
 }
@@ -175,39 +453,214 @@ func NewBroker(
 	if lOutputs <= 0 {
 		return nil, ErrBrokerNoOutputs
 	}
-	if lOutputs == 1 {
+	scalable := len(conf.Broker.CopiesEndpoint) > 0
+	if scalable && conf.Broker.Pattern != "greedy" {
+		return nil, fmt.Errorf("copies_endpoint is only supported by the greedy pattern, not %v", conf.Broker.Pattern)
+	}
+	deadLettered := conf.Broker.Pattern == "fan_out" && conf.Broker.FanOut.DeadLetter != nil
+	if lOutputs == 1 && !scalable && !deadLettered {
 		return New(outputConfs[0], mgr, log, stats, pipelines...)
 	}
 
-	outputs := make([]types.Output, lOutputs)
-
-	var err error
-	for j := 0; j < conf.Broker.Copies; j++ {
+	// newCopy creates a copy of each child output, where j is the index of the
+	// copy.
+	newCopy := func(j int) ([]types.Output, error) {
+		outputs := make([]types.Output, len(outputConfs))
 		for i, oConf := range outputConfs {
-			ns := fmt.Sprintf("broker.outputs.%v", (j*conf.Broker.Copies)+i)
-			outputs[j*len(outputConfs)+i], err = New(
+			ns := fmt.Sprintf("broker.outputs.%v", j*len(outputConfs)+i)
+			var err error
+			if outputs[i], err = New(
 				oConf, mgr,
 				log.NewModule("."+ns),
 				metrics.Combine(stats, metrics.Namespaced(stats, ns)),
-				pipelines...)
-			if err != nil {
+				pipelines...); err != nil {
 				return nil, err
 			}
 		}
+		return outputs, nil
+	}
+
+	outputs := make([]types.Output, 0, lOutputs)
+	for j := 0; j < conf.Broker.Copies; j++ {
+		copyOutputs, err := newCopy(j)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, copyOutputs...)
+	}
+
+	if scalable {
+		g, err := broker.NewGreedy(outputs)
+		if err != nil {
+			return nil, err
+		}
+		scaler := &brokerScaler{
+			copies:    conf.Broker.Copies,
+			minCopies: conf.Broker.Copies,
+			width:     len(outputConfs),
+			greedy:    g,
+			newCopy:   newCopy,
+			log:       log,
+		}
+		mgr.RegisterEndpoint(
+			conf.Broker.CopiesEndpoint,
+			"Get or set the number of copies of the outputs of a greedy broker.",
+			scaler.handle,
+		)
+		return g, nil
 	}
 
 	switch conf.Broker.Pattern {
 	case "fan_out":
-		return broker.NewFanOut(outputs, log, stats)
+		opts := []func(*broker.FanOut){
+			broker.OptFanOutSetMaxInFlight(conf.Broker.FanOut.MaxInFlight),
+		}
+		if dlConf := conf.Broker.FanOut.DeadLetter; dlConf != nil {
+			if conf.Broker.FanOut.MaxRetries == 0 {
+				return nil, errors.New("fan_out.max_retries must be greater than zero when a dead_letter output is set")
+			}
+			ns := "broker.dead_letter"
+			deadLetter, err := New(
+				*dlConf, mgr,
+				log.NewModule("."+ns),
+				metrics.Combine(stats, metrics.Namespaced(stats, ns)),
+				pipelines...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create dead_letter output '%v': %v", dlConf.Type, err)
+			}
+			opts = append(opts, broker.OptFanOutSetDeadLetter(deadLetter, conf.Broker.FanOut.MaxRetries))
+		} else if conf.Broker.FanOut.MaxRetries > 0 {
+			return nil, errors.New("fan_out.max_retries requires a dead_letter output")
+		}
+		return broker.NewFanOut(outputs, log, stats, opts...)
 	case "round_robin":
 		return broker.NewRoundRobin(outputs, stats)
 	case "greedy":
 		return broker.NewGreedy(outputs)
+	case "least_pending":
+		return broker.NewLeastPending(outputs, stats)
 	case "try":
-		return broker.NewTry(outputs, stats)
+		if conf.Broker.Try.MaxRetries == 0 {
+			return broker.NewTry(outputs, stats)
+		}
+		rConf := retries.Config{
+			MaxRetries: conf.Broker.Try.MaxRetries,
+			Backoff:    conf.Broker.Try.Backoff,
+		}
+		boffCtor, err := rConf.GetCtor()
+		if err != nil {
+			return nil, err
+		}
+		return broker.NewTry(outputs, stats, broker.OptTrySetBackoff(boffCtor))
+	case "failover":
+		interval, err := time.ParseDuration(conf.Broker.Failover.CheckInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse failover check interval: %v", err)
+		}
+		return broker.NewFailover(
+			outputs, log, stats,
+			broker.OptFailoverSetCheckInterval(interval),
+			broker.OptFailoverSetThresholds(
+				conf.Broker.Failover.UnhealthyThreshold,
+				conf.Broker.Failover.HealthyThreshold,
+			),
+		)
+	case "shadow":
+		if p := conf.Broker.Shadow.SamplePercent; p < 0 || p > 100 {
+			return nil, fmt.Errorf("shadow sample_percent must be between 0 and 100, received: %v", p)
+		}
+		return broker.NewShadow(
+			outputs, log, stats,
+			broker.OptShadowSetSampleRate(conf.Broker.Shadow.SamplePercent/100),
+		)
+	case "hash":
+		if len(conf.Broker.HashKey) == 0 {
+			return nil, errors.New("a hash_key must be specified for the hash pattern")
+		}
+		key := text.NewInterpolatedBytes([]byte(conf.Broker.HashKey))
+		return broker.NewHash(outputs, func(msg types.Message, index int) []byte {
+			return key.Get(message.Lock(msg, index))
+		}, stats)
 	}
 
 	return nil, fmt.Errorf("broker pattern was not recognised: %v", conf.Broker.Pattern)
 }
 
 //------------------------------------------------------------------------------
+
+// brokerScaler changes the number of copies of the outputs of a greedy broker
+// at runtime.
+type brokerScaler struct {
+	mut       sync.Mutex
+	copies    int
+	minCopies int
+	width     int
+	greedy    *broker.Greedy
+	newCopy   func(j int) ([]types.Output, error)
+	log       log.Modular
+}
+
+// scale adds or removes copies until the target number of copies is reached.
+// Only copies added at runtime can be removed.
+func (b *brokerScaler) scale(copies int) error {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if copies < b.minCopies {
+		return fmt.Errorf("copies cannot be fewer than the %v configured", b.minCopies)
+	}
+	for b.copies < copies {
+		outputs, err := b.newCopy(b.copies)
+		if err != nil {
+			return err
+		}
+		for _, out := range outputs {
+			if err = b.greedy.AddOutput(out); err != nil {
+				return err
+			}
+		}
+		b.copies++
+	}
+	for b.copies > copies {
+		for i := 0; i < b.width; i++ {
+			if err := b.greedy.RemoveOutput(time.Second * 5); err != nil {
+				b.log.Errorf("Failed to cleanly remove output copy: %v\n", err)
+			}
+		}
+		b.copies--
+	}
+	b.log.Infof("Scaled broker to %v copies\n", b.copies)
+	return nil
+}
+
+// handle returns the current number of copies on GET requests, and scales to
+// the number of copies in the JSON body of POST and PUT requests.
+func (b *brokerScaler) handle(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Copies int `json:"copies"`
+	}
+	switch r.Method {
+	case "GET":
+	case "POST", "PUT":
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to parse request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := b.scale(body.Copies); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to scale copies: %v", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	b.mut.Lock()
+	body.Copies = b.copies
+	b.mut.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+//------------------------------------------------------------------------------
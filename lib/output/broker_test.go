@@ -22,14 +22,20 @@ package output
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/gorilla/mux"
+	yaml "gopkg.in/yaml.v2"
 	"nanomsg.org/go-mangos/protocol/pull"
 	"nanomsg.org/go-mangos/transport/tcp"
 )
@@ -238,3 +244,369 @@ func TestRoundRobinWithNanomsg(t *testing.T) {
 		}
 	}
 }
+
+func TestBrokerHashNoKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "hash"
+
+	outOne, outTwo := NewConfig(), NewConfig()
+	outOne.Type, outTwo.Type = TypeInproc, TypeInproc
+	outOne.Inproc, outTwo.Inproc = "foo", "bar"
+	conf.Broker.Outputs = append(conf.Broker.Outputs, outOne, outTwo)
+
+	if _, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing hash key")
+	}
+
+	conf.Broker.HashKey = "${!metadata:key}"
+	s, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Consume(make(chan types.Transaction)); err != nil {
+		t.Fatal(err)
+	}
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBrokerFanOutChildBatching(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_broker_batching_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "fan_out"
+
+	single, batched := NewConfig(), NewConfig()
+	single.Type, batched.Type = TypeFiles, TypeFiles
+	single.Files.Path = filepath.Join(dir, "single", "${!count:single}.txt")
+	batched.Files.Path = filepath.Join(dir, "batched", "${!count:batched}.txt")
+
+	batched.Batching.Count = 2
+
+	archiveProc := processor.NewConfig()
+	archiveProc.Type = processor.TypeArchive
+	archiveProc.Archive.Format = "lines"
+	batched.Processors = append(batched.Processors, archiveProc)
+
+	conf.Broker.Outputs = append(conf.Broker.Outputs, single, batched)
+
+	s, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(sendChan); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		msg := message.New([][]byte{[]byte(fmt.Sprintf("test%v", i))})
+		select {
+		case sendChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+			if exp, act := i%2 == 0, res.SkipAck(); exp != act {
+				t.Errorf("Wrong skip ack for message %v: %v != %v", i, act, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	for sub, exp := range map[string]int{"single": 4, "batched": 2} {
+		files, err := ioutil.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := len(files); exp != act {
+			t.Errorf("Wrong count of files in %v: %v != %v", sub, act, exp)
+		}
+	}
+
+	for i, exp := range []string{"test0\ntest1", "test2\ntest3"} {
+		act, err := ioutil.ReadFile(filepath.Join(dir, "batched", fmt.Sprintf("%v.txt", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp != string(act) {
+			t.Errorf("Wrong batch contents: %s != %s", act, exp)
+		}
+	}
+}
+
+func TestBrokerFanOutChildBatchingPeriod(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_broker_batching_period_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "fan_out"
+
+	batched := NewConfig()
+	batched.Type = TypeFiles
+	batched.Files.Path = filepath.Join(dir, "${!count:batched_period}.txt")
+	batched.Batching.Count = 10
+	batched.Batching.Period = "50ms"
+
+	archiveProc := processor.NewConfig()
+	archiveProc.Type = processor.TypeArchive
+	archiveProc.Archive.Format = "lines"
+	batched.Processors = append(batched.Processors, archiveProc)
+
+	conf.Broker.Outputs = append(conf.Broker.Outputs, batched)
+
+	s, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(sendChan); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		msg := message.New([][]byte{[]byte(fmt.Sprintf("test%v", i))})
+		select {
+		case sendChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+			if !res.SkipAck() {
+				t.Errorf("Expected skip ack for message %v", i)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	// The pending batch should be written without any further messages.
+	exp := "test0\ntest1\ntest2"
+	filePath := filepath.Join(dir, "1.txt")
+	timeout := time.Now().Add(time.Second * 5)
+	for {
+		act, err := ioutil.ReadFile(filePath)
+		if err == nil && string(act) == exp {
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatalf("Timed out waiting for batch: %s != %s", act, exp)
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBrokerFanOutDeadLetter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_broker_dead_letter_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	confStr := fmt.Sprintf(`
+type: broker
+broker:
+  pattern: fan_out
+  fan_out:
+    max_retries: 1
+    dead_letter:
+      type: files
+      files:
+        path: %v
+  outputs:
+  - type: reject
+    reject: nope
+  - type: files
+    files:
+      path: %v
+`, filepath.Join(dir, "dead", "${!count:dead}.txt"), filepath.Join(dir, "good", "${!count:good}.txt"))
+
+	conf := NewConfig()
+	if err = yaml.Unmarshal([]byte(confStr), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(sendChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case sendChan <- types.NewTransaction(message.New([][]byte{[]byte("test")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Action timed out")
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Fatal(res.Error())
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Action timed out")
+	}
+
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	for _, sub := range []string{"dead", "good"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, sub, "1.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := "test", string(b); exp != act {
+			t.Errorf("Wrong %v contents: %v != %v", sub, act, exp)
+		}
+	}
+}
+
+func TestBrokerFanOutDeadLetterNoRetries(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "fan_out"
+	conf.Broker.Outputs = append(conf.Broker.Outputs, NewConfig(), NewConfig())
+
+	dlConf := NewConfig()
+	conf.Broker.FanOut.DeadLetter = &dlConf
+	if _, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from dead letter without max_retries")
+	}
+
+	conf.Broker.FanOut.DeadLetter = nil
+	conf.Broker.FanOut.MaxRetries = 1
+	if _, err := NewBroker(conf, types.DudMgr{}, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from max_retries without dead letter")
+	}
+}
+
+func TestBrokerCopiesEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_broker_copies_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewConfig()
+	conf.Type = TypeBroker
+	conf.Broker.Pattern = "round_robin"
+	conf.Broker.CopiesEndpoint = "/broker/copies"
+
+	child := NewConfig()
+	child.Type = TypeFiles
+	child.Files.Path = filepath.Join(dir, "${!content}.txt")
+	conf.Broker.Outputs = append(conf.Broker.Outputs, child)
+
+	mgr := &dynamicMgr{router: mux.NewRouter()}
+	if _, err = NewBroker(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from copies endpoint with round_robin pattern")
+	}
+
+	conf.Broker.Pattern = "greedy"
+	s, err := NewBroker(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sendChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(sendChan); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(content string) {
+		t.Helper()
+		select {
+		case sendChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	tests := []struct {
+		method  string
+		body    string
+		expCode int
+		expBody string
+	}{
+		{method: "GET", expCode: 200, expBody: `{"copies":1}`},
+		{method: "POST", body: `{"copies":3}`, expCode: 200, expBody: `{"copies":3}`},
+		{method: "POST", body: `{"copies":0}`, expCode: 400},
+		{method: "POST", body: `not json`, expCode: 400},
+		{method: "DELETE", expCode: 405},
+		{method: "GET", expCode: 200, expBody: `{"copies":3}`},
+		{method: "PUT", body: `{"copies":1}`, expCode: 200, expBody: `{"copies":1}`},
+	}
+	for i, test := range tests {
+		send(fmt.Sprintf("test%v", i))
+		code, body := mgr.request(t, test.method, "/broker/copies", test.body)
+		if code != test.expCode {
+			t.Errorf("Wrong code for request %v: %v != %v: %s", i, code, test.expCode, body)
+		}
+		if act := strings.TrimSpace(string(body)); len(test.expBody) > 0 && act != test.expBody {
+			t.Errorf("Wrong body for request %v: %v != %v", i, act, test.expBody)
+		}
+	}
+
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := len(tests), len(files); exp != act {
+		t.Errorf("Wrong count of files: %v != %v", act, exp)
+	}
+}
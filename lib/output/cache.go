@@ -70,7 +70,13 @@ resources:
 
 In order to create a unique ` + "`key`" + ` value per item you should use
 function interpolations described [here](../config_interpolation.md#functions).
-When sending batched messages the interpolations are performed per message part.`,
+When sending batched messages the interpolations are performed per message part.
+
+The ` + "`ttl`" + ` field, when set, overrides the default TTL of the target
+cache for each item written by this output. It is currently supported by the
+` + "`memory`" + `, ` + "`memcached`" + `, ` + "`redis`" + ` and
+` + "`dynamodb`" + ` caches, where the ` + "`dynamodb`" + ` cache requires a
+` + "`ttl_key`" + ` to be configured.`,
 	}
 }
 
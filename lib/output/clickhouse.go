@@ -0,0 +1,74 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeClickHouse] = TypeSpec{
+		constructor: NewClickHouse,
+		description: `
+Inserts messages as rows of a ClickHouse table using the HTTP interface. Each
+message part must be a JSON object, and all parts of a batched message are
+inserted with a single ` + "`INSERT`" + ` statement in the
+` + "`JSONEachRow`" + ` format. Parts that are not JSON objects are logged and
+dropped. Messages can be batched before this output with the
+[` + "`batch`" + ` processor](../processors/README.md#batch) in order to
+improve insert throughput.
+
+When ` + "`columns`" + ` is empty the fields of each object are mapped to the
+columns of the same name. Otherwise only the listed columns are inserted, where
+the value of each column is taken from the field of the same name unless a dot
+path is given for it within ` + "`column_paths`" + `.
+
+Setting ` + "`async_insert`" + ` enables asynchronous inserts, where rows are
+buffered by the server. When ` + "`wait_for_async_insert`" + ` is true writes
+are only acknowledged once the buffered rows are flushed to the table. Any other
+ClickHouse settings can be added to each insert request with
+` + "`settings`" + `.
+
+Only the HTTP interface of ClickHouse is currently supported, the native TCP
+protocol is not.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewClickHouse creates a new ClickHouse output type.
+func NewClickHouse(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	c, err := writer.NewClickHouse(conf.ClickHouse, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("clickhouse", c, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -32,6 +32,7 @@ import (
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/pipeline"
 	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/processor/condition"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/config"
 	yaml "gopkg.in/yaml.v2"
@@ -60,113 +61,178 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each output type.
 const (
-	TypeAMQP          = "amqp"
-	TypeBroker        = "broker"
-	TypeCache         = "cache"
-	TypeDynamic       = "dynamic"
-	TypeDynamoDB      = "dynamodb"
-	TypeElasticsearch = "elasticsearch"
-	TypeFile          = "file"
-	TypeFiles         = "files"
-	TypeGCPPubSub     = "gcp_pubsub"
-	TypeHDFS          = "hdfs"
-	TypeHTTPClient    = "http_client"
-	TypeHTTPServer    = "http_server"
-	TypeInproc        = "inproc"
-	TypeKafka         = "kafka"
-	TypeKinesis       = "kinesis"
-	TypeMQTT          = "mqtt"
-	TypeNanomsg       = "nanomsg"
-	TypeNATS          = "nats"
-	TypeNATSStream    = "nats_stream"
-	TypeNSQ           = "nsq"
-	TypeRedisList     = "redis_list"
-	TypeRedisPubSub   = "redis_pubsub"
-	TypeRedisStreams  = "redis_streams"
-	TypeRetry         = "retry"
-	TypeS3            = "s3"
-	TypeSQS           = "sqs"
-	TypeSTDOUT        = "stdout"
-	TypeSwitch        = "switch"
-	TypeWebsocket     = "websocket"
-	TypeZMQ4          = "zmq4"
+	TypeAMQP              = "amqp"
+	TypeAzureBlobStorage  = "azure_blob_storage"
+	TypeAzureTableStorage = "azure_table_storage"
+	TypeBroker            = "broker"
+	TypeCache             = "cache"
+	TypeClickHouse        = "clickhouse"
+	TypeDatadogLogs       = "datadog_logs"
+	TypeDropOn            = "drop_on"
+	TypeDynamic           = "dynamic"
+	TypeDynamoDB          = "dynamodb"
+	TypeElasticsearch     = "elasticsearch"
+	TypeFallback          = "fallback"
+	TypeFile              = "file"
+	TypeFiles             = "files"
+	TypeGCPBigQuery       = "gcp_bigquery"
+	TypeGCPCloudStorage   = "gcp_cloud_storage"
+	TypeGCPPubSub         = "gcp_pubsub"
+	TypeGraphite          = "graphite"
+	TypeGRPCClient        = "grpc_client"
+	TypeHDFS              = "hdfs"
+	TypeHTTPClient        = "http_client"
+	TypeHTTPServer        = "http_server"
+	TypeInfluxDB          = "influxdb"
+	TypeInproc            = "inproc"
+	TypeKafka             = "kafka"
+	TypeKinesis           = "kinesis"
+	TypeMongoDB           = "mongodb"
+	TypeMQTT              = "mqtt"
+	TypeNanomsg           = "nanomsg"
+	TypeNATS              = "nats"
+	TypeNATSJetStream     = "nats_jetstream"
+	TypeNATSStream        = "nats_stream"
+	TypeNSQ               = "nsq"
+	TypePulsar            = "pulsar"
+	TypeRedisList         = "redis_list"
+	TypeRedisPubSub       = "redis_pubsub"
+	TypeRedisStreams      = "redis_streams"
+	TypeReject            = "reject"
+	TypeRetry             = "retry"
+	TypeS3                = "s3"
+	TypeSFTP              = "sftp"
+	TypeSnowflake         = "snowflake"
+	TypeSNS               = "sns"
+	TypeSplunkHEC         = "splunk_hec"
+	TypeSQL               = "sql"
+	TypeSQS               = "sqs"
+	TypeSTDOUT            = "stdout"
+	TypeSubprocess        = "subprocess"
+	TypeSwitch            = "switch"
+	TypeWebsocket         = "websocket"
+	TypeZMQ4              = "zmq4"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all output types.
 type Config struct {
-	Type          string                     `json:"type" yaml:"type"`
-	AMQP          writer.AMQPConfig          `json:"amqp" yaml:"amqp"`
-	Broker        BrokerConfig               `json:"broker" yaml:"broker"`
-	Cache         writer.CacheConfig         `json:"cache" yaml:"cache"`
-	Dynamic       DynamicConfig              `json:"dynamic" yaml:"dynamic"`
-	DynamoDB      writer.DynamoDBConfig      `json:"dynamodb" yaml:"dynamodb"`
-	Elasticsearch writer.ElasticsearchConfig `json:"elasticsearch" yaml:"elasticsearch"`
-	File          FileConfig                 `json:"file" yaml:"file"`
-	Files         writer.FilesConfig         `json:"files" yaml:"files"`
-	GCPPubSub     writer.GCPPubSubConfig     `json:"gcp_pubsub" yaml:"gcp_pubsub"`
-	HDFS          writer.HDFSConfig          `json:"hdfs" yaml:"hdfs"`
-	HTTPClient    writer.HTTPClientConfig    `json:"http_client" yaml:"http_client"`
-	HTTPServer    HTTPServerConfig           `json:"http_server" yaml:"http_server"`
-	Inproc        InprocConfig               `json:"inproc" yaml:"inproc"`
-	Kafka         writer.KafkaConfig         `json:"kafka" yaml:"kafka"`
-	Kinesis       writer.KinesisConfig       `json:"kinesis" yaml:"kinesis"`
-	MQTT          writer.MQTTConfig          `json:"mqtt" yaml:"mqtt"`
-	Nanomsg       writer.NanomsgConfig       `json:"nanomsg" yaml:"nanomsg"`
-	NATS          writer.NATSConfig          `json:"nats" yaml:"nats"`
-	NATSStream    writer.NATSStreamConfig    `json:"nats_stream" yaml:"nats_stream"`
-	NSQ           writer.NSQConfig           `json:"nsq" yaml:"nsq"`
-	Plugin        interface{}                `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	RedisList     writer.RedisListConfig     `json:"redis_list" yaml:"redis_list"`
-	RedisPubSub   writer.RedisPubSubConfig   `json:"redis_pubsub" yaml:"redis_pubsub"`
-	RedisStreams  writer.RedisStreamsConfig  `json:"redis_streams" yaml:"redis_streams"`
-	Retry         RetryConfig                `json:"retry" yaml:"retry"`
-	S3            writer.AmazonS3Config      `json:"s3" yaml:"s3"`
-	SQS           writer.AmazonSQSConfig     `json:"sqs" yaml:"sqs"`
-	STDOUT        STDOUTConfig               `json:"stdout" yaml:"stdout"`
-	Switch        SwitchConfig               `json:"switch" yaml:"switch"`
-	Websocket     writer.WebsocketConfig     `json:"websocket" yaml:"websocket"`
-	ZMQ4          *writer.ZMQ4Config         `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
-	Processors    []processor.Config         `json:"processors" yaml:"processors"`
+	Type              string                         `json:"type" yaml:"type"`
+	AMQP              writer.AMQPConfig              `json:"amqp" yaml:"amqp"`
+	AzureBlobStorage  writer.AzureBlobStorageConfig  `json:"azure_blob_storage" yaml:"azure_blob_storage"`
+	AzureTableStorage writer.AzureTableStorageConfig `json:"azure_table_storage" yaml:"azure_table_storage"`
+	Broker            BrokerConfig                   `json:"broker" yaml:"broker"`
+	Cache             writer.CacheConfig             `json:"cache" yaml:"cache"`
+	ClickHouse        writer.ClickHouseConfig        `json:"clickhouse" yaml:"clickhouse"`
+	DatadogLogs       writer.DatadogLogsConfig       `json:"datadog_logs" yaml:"datadog_logs"`
+	DropOn            DropOnConfig                   `json:"drop_on" yaml:"drop_on"`
+	Dynamic           DynamicConfig                  `json:"dynamic" yaml:"dynamic"`
+	DynamoDB          writer.DynamoDBConfig          `json:"dynamodb" yaml:"dynamodb"`
+	Elasticsearch     writer.ElasticsearchConfig     `json:"elasticsearch" yaml:"elasticsearch"`
+	Fallback          FallbackConfig                 `json:"fallback" yaml:"fallback"`
+	File              writer.FileConfig              `json:"file" yaml:"file"`
+	Files             writer.FilesConfig             `json:"files" yaml:"files"`
+	GCPBigQuery       writer.GCPBigQueryConfig       `json:"gcp_bigquery" yaml:"gcp_bigquery"`
+	GCPCloudStorage   writer.GCPCloudStorageConfig   `json:"gcp_cloud_storage" yaml:"gcp_cloud_storage"`
+	GCPPubSub         writer.GCPPubSubConfig         `json:"gcp_pubsub" yaml:"gcp_pubsub"`
+	Graphite          writer.GraphiteConfig          `json:"graphite" yaml:"graphite"`
+	GRPCClient        writer.GRPCClientConfig        `json:"grpc_client" yaml:"grpc_client"`
+	HDFS              writer.HDFSConfig              `json:"hdfs" yaml:"hdfs"`
+	HTTPClient        writer.HTTPClientConfig        `json:"http_client" yaml:"http_client"`
+	HTTPServer        HTTPServerConfig               `json:"http_server" yaml:"http_server"`
+	InfluxDB          writer.InfluxDBConfig          `json:"influxdb" yaml:"influxdb"`
+	Inproc            InprocConfig                   `json:"inproc" yaml:"inproc"`
+	Kafka             writer.KafkaConfig             `json:"kafka" yaml:"kafka"`
+	Kinesis           writer.KinesisConfig           `json:"kinesis" yaml:"kinesis"`
+	MongoDB           writer.MongoDBConfig           `json:"mongodb" yaml:"mongodb"`
+	MQTT              writer.MQTTConfig              `json:"mqtt" yaml:"mqtt"`
+	Nanomsg           writer.NanomsgConfig           `json:"nanomsg" yaml:"nanomsg"`
+	NATS              writer.NATSConfig              `json:"nats" yaml:"nats"`
+	NATSJetStream     writer.NATSJetStreamConfig     `json:"nats_jetstream" yaml:"nats_jetstream"`
+	NATSStream        writer.NATSStreamConfig        `json:"nats_stream" yaml:"nats_stream"`
+	NSQ               writer.NSQConfig               `json:"nsq" yaml:"nsq"`
+	Plugin            interface{}                    `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Pulsar            writer.PulsarConfig            `json:"pulsar" yaml:"pulsar"`
+	RedisList         writer.RedisListConfig         `json:"redis_list" yaml:"redis_list"`
+	RedisPubSub       writer.RedisPubSubConfig       `json:"redis_pubsub" yaml:"redis_pubsub"`
+	RedisStreams      writer.RedisStreamsConfig      `json:"redis_streams" yaml:"redis_streams"`
+	Reject            RejectConfig                   `json:"reject" yaml:"reject"`
+	Retry             RetryConfig                    `json:"retry" yaml:"retry"`
+	S3                writer.AmazonS3Config          `json:"s3" yaml:"s3"`
+	SFTP              writer.SFTPConfig              `json:"sftp" yaml:"sftp"`
+	Snowflake         writer.SnowflakeConfig         `json:"snowflake" yaml:"snowflake"`
+	SNS               writer.SNSConfig               `json:"sns" yaml:"sns"`
+	SplunkHEC         writer.SplunkHECConfig         `json:"splunk_hec" yaml:"splunk_hec"`
+	SQL               writer.SQLConfig               `json:"sql" yaml:"sql"`
+	SQS               writer.AmazonSQSConfig         `json:"sqs" yaml:"sqs"`
+	STDOUT            STDOUTConfig                   `json:"stdout" yaml:"stdout"`
+	Subprocess        writer.SubprocessConfig        `json:"subprocess" yaml:"subprocess"`
+	Switch            SwitchConfig                   `json:"switch" yaml:"switch"`
+	Websocket         writer.WebsocketConfig         `json:"websocket" yaml:"websocket"`
+	ZMQ4              *writer.ZMQ4Config             `json:"zmq4,omitempty" yaml:"zmq4,omitempty"`
+	Batching          processor.BatchConfig          `json:"batching" yaml:"batching"`
+	Processors        []processor.Config             `json:"processors" yaml:"processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:          "stdout",
-		AMQP:          writer.NewAMQPConfig(),
-		Broker:        NewBrokerConfig(),
-		Cache:         writer.NewCacheConfig(),
-		Dynamic:       NewDynamicConfig(),
-		DynamoDB:      writer.NewDynamoDBConfig(),
-		Elasticsearch: writer.NewElasticsearchConfig(),
-		File:          NewFileConfig(),
-		Files:         writer.NewFilesConfig(),
-		GCPPubSub:     writer.NewGCPPubSubConfig(),
-		HDFS:          writer.NewHDFSConfig(),
-		HTTPClient:    writer.NewHTTPClientConfig(),
-		HTTPServer:    NewHTTPServerConfig(),
-		Inproc:        NewInprocConfig(),
-		Kafka:         writer.NewKafkaConfig(),
-		Kinesis:       writer.NewKinesisConfig(),
-		MQTT:          writer.NewMQTTConfig(),
-		Nanomsg:       writer.NewNanomsgConfig(),
-		NATS:          writer.NewNATSConfig(),
-		NATSStream:    writer.NewNATSStreamConfig(),
-		NSQ:           writer.NewNSQConfig(),
-		Plugin:        nil,
-		RedisList:     writer.NewRedisListConfig(),
-		RedisPubSub:   writer.NewRedisPubSubConfig(),
-		RedisStreams:  writer.NewRedisStreamsConfig(),
-		Retry:         NewRetryConfig(),
-		S3:            writer.NewAmazonS3Config(),
-		SQS:           writer.NewAmazonSQSConfig(),
-		STDOUT:        NewSTDOUTConfig(),
-		Switch:        NewSwitchConfig(),
-		Websocket:     writer.NewWebsocketConfig(),
-		ZMQ4:          writer.NewZMQ4Config(),
-		Processors:    []processor.Config{},
+		Type:              "stdout",
+		AMQP:              writer.NewAMQPConfig(),
+		AzureBlobStorage:  writer.NewAzureBlobStorageConfig(),
+		AzureTableStorage: writer.NewAzureTableStorageConfig(),
+		Broker:            NewBrokerConfig(),
+		Cache:             writer.NewCacheConfig(),
+		ClickHouse:        writer.NewClickHouseConfig(),
+		DatadogLogs:       writer.NewDatadogLogsConfig(),
+		DropOn:            NewDropOnConfig(),
+		Dynamic:           NewDynamicConfig(),
+		DynamoDB:          writer.NewDynamoDBConfig(),
+		Elasticsearch:     writer.NewElasticsearchConfig(),
+		Fallback:          NewFallbackConfig(),
+		File:              writer.NewFileConfig(),
+		Files:             writer.NewFilesConfig(),
+		GCPBigQuery:       writer.NewGCPBigQueryConfig(),
+		GCPCloudStorage:   writer.NewGCPCloudStorageConfig(),
+		GCPPubSub:         writer.NewGCPPubSubConfig(),
+		Graphite:          writer.NewGraphiteConfig(),
+		GRPCClient:        writer.NewGRPCClientConfig(),
+		HDFS:              writer.NewHDFSConfig(),
+		HTTPClient:        writer.NewHTTPClientConfig(),
+		HTTPServer:        NewHTTPServerConfig(),
+		InfluxDB:          writer.NewInfluxDBConfig(),
+		Inproc:            NewInprocConfig(),
+		Kafka:             writer.NewKafkaConfig(),
+		Kinesis:           writer.NewKinesisConfig(),
+		MongoDB:           writer.NewMongoDBConfig(),
+		MQTT:              writer.NewMQTTConfig(),
+		Nanomsg:           writer.NewNanomsgConfig(),
+		NATS:              writer.NewNATSConfig(),
+		NATSJetStream:     writer.NewNATSJetStreamConfig(),
+		NATSStream:        writer.NewNATSStreamConfig(),
+		NSQ:               writer.NewNSQConfig(),
+		Plugin:            nil,
+		Pulsar:            writer.NewPulsarConfig(),
+		RedisList:         writer.NewRedisListConfig(),
+		RedisPubSub:       writer.NewRedisPubSubConfig(),
+		RedisStreams:      writer.NewRedisStreamsConfig(),
+		Reject:            NewRejectConfig(),
+		Retry:             NewRetryConfig(),
+		S3:                writer.NewAmazonS3Config(),
+		SFTP:              writer.NewSFTPConfig(),
+		Snowflake:         writer.NewSnowflakeConfig(),
+		SNS:               writer.NewSNSConfig(),
+		SplunkHEC:         writer.NewSplunkHECConfig(),
+		SQL:               writer.NewSQLConfig(),
+		SQS:               writer.NewAmazonSQSConfig(),
+		STDOUT:            NewSTDOUTConfig(),
+		Subprocess:        writer.NewSubprocessConfig(),
+		Switch:            NewSwitchConfig(),
+		Websocket:         writer.NewWebsocketConfig(),
+		ZMQ4:              writer.NewZMQ4Config(),
+		Batching:          processor.NewBatchConfig(),
+		Processors:        []processor.Config{},
 	}
 }
 
@@ -206,6 +272,19 @@ func SanitiseConfig(conf Config) (interface{}, error) {
 		}
 	}
 
+	if batchingEnabled(conf.Batching) {
+		var condSanit interface{}
+		if condSanit, err = condition.SanitiseConfig(conf.Batching.Condition); err != nil {
+			return nil, err
+		}
+		outputMap["batching"] = map[string]interface{}{
+			"byte_size": conf.Batching.ByteSize,
+			"count":     conf.Batching.Count,
+			"condition": condSanit,
+			"period":    conf.Batching.Period,
+		}
+	}
+
 	if len(conf.Processors) == 0 {
 		return outputMap, nil
 	}
@@ -308,7 +387,39 @@ conditions please [read the docs here](../conditions/README.md)
 ### Dead Letter Queues
 
 It's possible to create fallback outputs for when an output target fails using
-a ` + "[`broker`](#broker)" + ` output with the 'try' pattern.`
+a ` + "[`broker`](#broker)" + ` output with the 'try' pattern.
+
+### Batching
+
+Every output supports a ` + "`batching`" + ` block, which combines messages
+into batches before they are sent through the processors of the output and
+written. The fields are the same as those of the
+[` + "`batch`" + ` processor](../processors/README.md#batch), where a batch is
+flushed once it reaches a ` + "`count`" + ` or ` + "`byte_size`" + `, once the
+` + "`condition`" + ` resolves ` + "`true`" + ` for an added message, or once the
+` + "`period`" + ` has passed since the last batch:
+
+` + "``` yaml" + `
+output:
+  type: s3
+  s3:
+    bucket: archive
+  batching:
+    count: 100
+    period: 10s
+  processors:
+  - type: archive
+    archive:
+      format: lines
+` + "```" + `
+
+Messages within a batch are only acknowledged once the whole batch has been
+written. Batching is disabled when none of the fields are set.
+
+A pending batch is written once its ` + "`period`" + ` has passed even if
+messages stop arriving, and is retried until the write succeeds. Since the
+input has already moved on from these messages they are acknowledged at the
+source along with the next batch.`
 
 // Descriptions returns a formatted string of collated descriptions of each
 // type.
@@ -359,6 +470,15 @@ func Descriptions() string {
 	return buf.String()
 }
 
+// batchingEnabled returns whether a batching config has any flush trigger set.
+func batchingEnabled(conf processor.BatchConfig) bool {
+	return conf.ByteSize > 0 ||
+		conf.Count > 0 ||
+		len(conf.Period) > 0 ||
+		conf.Condition.Type != "static" ||
+		conf.Condition.Static
+}
+
 // New creates an output type based on an output configuration.
 func New(
 	conf Config,
@@ -367,6 +487,18 @@ func New(
 	stats metrics.Type,
 	pipelines ...types.PipelineConstructorFunc,
 ) (Type, error) {
+	if batchingEnabled(conf.Batching) {
+		pipelines = append(pipelines, func(i *int) (types.Pipeline, error) {
+			procConf := processor.NewConfig()
+			procConf.Type = processor.TypeBatch
+			procConf.Batch = conf.Batching
+			batcher, err := processor.NewBatch(procConf, mgr, log.NewModule(".batching"), metrics.Namespaced(stats, "batching"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create batching policy: %v", err)
+			}
+			return pipeline.NewBatcher(log, stats, batcher.(*processor.Batch)), nil
+		})
+	}
 	if len(conf.Processors) > 0 {
 		pipelines = append(pipelines, []types.PipelineConstructorFunc{func(i *int) (types.Pipeline, error) {
 			if i == nil {
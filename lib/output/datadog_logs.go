@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDatadogLogs] = TypeSpec{
+		constructor: NewDatadogLogs,
+		description: `
+Sends messages as logs to the Datadog logs intake API, authenticated with
+` + "`api_key`" + `. The ` + "`url`" + ` should be set to the intake endpoint
+of your Datadog site.
+
+Message parts that are JSON objects are sent as structured logs, and other parts
+are sent as the ` + "`message`" + ` field of a log. Each log is enriched with
+the ` + "`service`" + `, ` + "`source`" + `, ` + "`hostname`" + ` and
+` + "`tags`" + ` fields, which can be dynamically set using function
+interpolations described [here](../config_interpolation.md#functions) and are
+omitted when empty. Tags are a comma separated list of the form
+` + "`key:value`" + `.
+
+All parts of a batched message are sent within a single request, which is
+compressed when ` + "`gzip`" + ` is true. Batches that exceed the intake limits
+of 1000 logs or 5MB per request are split across multiple requests, and logs
+larger than 1MB are logged and dropped. Messages can be batched before this
+output with the [` + "`batch`" + ` processor](../processors/README.md#batch)
+in order to improve throughput.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewDatadogLogs creates a new DatadogLogs output type.
+func NewDatadogLogs(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	d, err := writer.NewDatadogLogs(conf.DatadogLogs, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("datadog_logs", d, log, stats)
+}
+
+//------------------------------------------------------------------------------
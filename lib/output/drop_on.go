@@ -0,0 +1,324 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDropOn] = TypeSpec{
+		constructor: NewDropOn,
+		description: `
+Attempts to write messages to a child output and if the write fails for one of
+a list of configurable reasons the message is dropped instead of being reattempted.
+
+Regular Benthos outputs will apply back pressure when downstream services aren't
+accessible, and Benthos retries (or nacks) all messages that fail to be
+delivered. However, in some circumstances, or for certain output types, we
+instead might want to relax these mechanisms, which is when this output becomes
+useful.
+
+When ` + "`error`" + ` is true messages that the child output fails to deliver
+are acknowledged and dropped, and any other failure is propagated as usual.
+
+When ` + "`back_pressure`" + ` is a non-empty duration messages are dropped
+whenever the child output takes longer than that period to accept and deliver
+them, which prevents a slow or unavailable destination from applying back
+pressure upstream. This applies to errors as well as slow deliveries, therefore
+a failing child output that does not drop on errors will eventually have its
+messages dropped on back pressure.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.DropOn)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.DropOn.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.DropOn.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+			return confMap, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DropOnConditions is a config struct representing the different circumstances
+// under which messages should be dropped.
+type DropOnConditions struct {
+	Error        bool   `json:"error" yaml:"error"`
+	BackPressure string `json:"back_pressure" yaml:"back_pressure"`
+}
+
+// DropOnConfig contains configuration values for the DropOn output type.
+type DropOnConfig struct {
+	DropOnConditions `json:",inline" yaml:",inline"`
+	Output           *Config `json:"output" yaml:"output"`
+}
+
+// NewDropOnConfig creates a new DropOnConfig with default values.
+func NewDropOnConfig() DropOnConfig {
+	return DropOnConfig{
+		DropOnConditions: DropOnConditions{
+			Error:        false,
+			BackPressure: "",
+		},
+		Output: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDropOnConfig struct {
+	DropOnConditions `json:",inline" yaml:",inline"`
+	Output           interface{} `json:"output" yaml:"output"`
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (d DropOnConfig) MarshalJSON() ([]byte, error) {
+	dummy := dummyDropOnConfig{
+		DropOnConditions: d.DropOnConditions,
+		Output:           d.Output,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return json.Marshal(dummy)
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (d DropOnConfig) MarshalYAML() (interface{}, error) {
+	dummy := dummyDropOnConfig{
+		DropOnConditions: d.DropOnConditions,
+		Output:           d.Output,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy, nil
+}
+
+//------------------------------------------------------------------------------
+
+// DropOn is an output type that continuously writes a message to a child output
+// and drops the message when the write fails for configured reasons.
+type DropOn struct {
+	running int32
+
+	onError        bool
+	onBackpressure time.Duration
+
+	wrapped Type
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDropOn creates a new DropOn output type.
+func NewDropOn(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.DropOn.Output == nil {
+		return nil, errors.New("cannot create drop_on output without a child")
+	}
+
+	wrapped, err := New(*conf.DropOn.Output, mgr, log, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.DropOn.Output.Type, err)
+	}
+
+	var backPressure time.Duration
+	if len(conf.DropOn.BackPressure) > 0 {
+		if backPressure, err = time.ParseDuration(conf.DropOn.BackPressure); err != nil {
+			return nil, fmt.Errorf("failed to parse back_pressure duration: %v", err)
+		}
+	}
+
+	return &DropOn{
+		running:         1,
+		onError:         conf.DropOn.Error,
+		onBackpressure:  backPressure,
+		log:             log,
+		stats:           stats,
+		wrapped:         wrapped,
+		transactionsOut: make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (d *DropOn) loop() {
+	// Metrics paths
+	var (
+		mRunning          = d.stats.GetGauge("drop_on.running")
+		mCount            = d.stats.GetCounter("drop_on.count")
+		mSuccess          = d.stats.GetCounter("drop_on.send.success")
+		mPartsSuccess     = d.stats.GetCounter("drop_on.parts.send.success")
+		mError            = d.stats.GetCounter("drop_on.send.error")
+		mDropError        = d.stats.GetCounter("drop_on.dropped.error")
+		mDropBackPressure = d.stats.GetCounter("drop_on.dropped.back_pressure")
+	)
+
+	defer func() {
+		close(d.transactionsOut)
+		d.wrapped.CloseAsync()
+		err := d.wrapped.WaitForClose(time.Second)
+		for ; err != nil; err = d.wrapped.WaitForClose(time.Second) {
+		}
+		mRunning.Decr(1)
+		close(d.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	for atomic.LoadInt32(&d.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+			mCount.Incr(1)
+		case <-d.closeChan:
+			return
+		}
+
+		var backPressureChan <-chan time.Time
+		if d.onBackpressure > 0 {
+			backPressureChan = time.After(d.onBackpressure)
+		}
+
+		// The response channel is buffered so that a child output can still
+		// respond to transactions that were abandoned due to back pressure.
+		resChan := make(chan types.Response, 1)
+		var resOut types.Response
+
+		select {
+		case d.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+			select {
+			case res := <-resChan:
+				if err := res.Error(); err != nil {
+					mError.Incr(1)
+					if d.onError {
+						mDropError.Incr(1)
+						d.log.Warnf("Dropping message due to output error: %v\n", err)
+						resOut = response.NewAck()
+					} else {
+						resOut = res
+					}
+				} else {
+					mSuccess.Incr(1)
+					mPartsSuccess.Incr(int64(ts.Payload.Len()))
+					resOut = res
+				}
+			case <-backPressureChan:
+				mDropBackPressure.Incr(1)
+				d.log.Warnln("Dropping message due to back pressure")
+				resOut = response.NewAck()
+			case <-d.closeChan:
+				return
+			}
+		case <-backPressureChan:
+			mDropBackPressure.Incr(1)
+			d.log.Warnln("Dropping message due to back pressure")
+			resOut = response.NewAck()
+		case <-d.closeChan:
+			return
+		}
+
+		select {
+		case ts.ResponseChan <- resOut:
+		case <-d.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (d *DropOn) Consume(ts <-chan types.Transaction) error {
+	if d.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := d.wrapped.Consume(d.transactionsOut); err != nil {
+		return err
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (d *DropOn) Connected() bool {
+	return d.wrapped.Connected()
+}
+
+// CloseAsync shuts down the DropOn output and stops processing requests.
+func (d *DropOn) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the DropOn output has closed down.
+func (d *DropOn) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
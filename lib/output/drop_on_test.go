@@ -0,0 +1,177 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+func TestDropOnConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDropOn
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing child")
+	}
+
+	childConf := NewConfig()
+	conf.DropOn.Output = &childConf
+	conf.DropOn.BackPressure = "not a duration"
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad back pressure")
+	}
+}
+
+func newDropOnTest(t *testing.T, conf DropOnConditions) (*DropOn, *mockOutput, chan types.Transaction) {
+	t.Helper()
+
+	childConf := NewConfig()
+	oConf := NewConfig()
+	oConf.DropOn.DropOnConditions = conf
+	oConf.DropOn.Output = &childConf
+
+	output, err := NewDropOn(oConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := output.(*DropOn)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mOut := &mockOutput{}
+	d.wrapped = mOut
+
+	tChan := make(chan types.Transaction)
+	if err = d.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+	return d, mOut, tChan
+}
+
+func sendDropOn(t *testing.T, tChan chan types.Transaction, mOut *mockOutput, childRes types.Response) error {
+	t.Helper()
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	if childRes != nil {
+		var tran types.Transaction
+		select {
+		case tran = <-mOut.ts:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case tran.ResponseChan <- childRes:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestDropOnError(t *testing.T) {
+	d, mOut, tChan := newDropOnTest(t, DropOnConditions{Error: true})
+
+	if err := sendDropOn(t, tChan, mOut, response.NewAck()); err != nil {
+		t.Error(err)
+	}
+	if err := sendDropOn(t, tChan, mOut, response.NewError(errors.New("nope"))); err != nil {
+		t.Errorf("Expected message to be dropped, received: %v", err)
+	}
+
+	d.CloseAsync()
+	if err := d.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDropOnErrorPropagated(t *testing.T) {
+	d, mOut, tChan := newDropOnTest(t, DropOnConditions{BackPressure: "1s"})
+
+	if err := sendDropOn(t, tChan, mOut, response.NewError(errors.New("nope"))); err == nil {
+		t.Error("Expected error to be propagated")
+	}
+
+	d.CloseAsync()
+	if err := d.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDropOnBackPressure(t *testing.T) {
+	d, mOut, tChan := newDropOnTest(t, DropOnConditions{BackPressure: "10ms"})
+
+	// The child output never reads the first transaction.
+	if err := sendDropOn(t, tChan, mOut, nil); err != nil {
+		t.Errorf("Expected message to be dropped, received: %v", err)
+	}
+
+	// The child output reads but never responds to the second transaction.
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("bar")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case tran := <-mOut.ts:
+		if exp, act := "bar", string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		if err := res.Error(); err != nil {
+			t.Errorf("Expected message to be dropped, received: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	d.CloseAsync()
+	if err := d.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
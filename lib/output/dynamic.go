@@ -100,13 +100,24 @@ func NewDynamic(
 ) (Type, error) {
 	dynAPI := api.NewDynamic()
 
+	// Configs of outputs are removed once they're started, therefore we take
+	// a copy in order to leave the original config untouched.
+	outputConfigs := make(map[string]Config, len(conf.Dynamic.Outputs))
+	outputConfigsMut := sync.RWMutex{}
+
 	outputs := map[string]broker.DynamicOutput{}
 	for k, v := range conf.Dynamic.Outputs {
-		newOutput, err := New(v, mgr, log, stats)
+		ns := fmt.Sprintf("dynamic.outputs.%v", k)
+		newOutput, err := New(
+			v, mgr,
+			log.NewModule("."+ns),
+			metrics.Combine(stats, metrics.Namespaced(stats, ns)),
+		)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to create output '%v': %v", k, err)
 		}
 		outputs[k] = newOutput
+		outputConfigs[k] = v
 	}
 
 	var reqTimeout time.Duration
@@ -117,9 +128,6 @@ func NewDynamic(
 		}
 	}
 
-	outputConfigs := conf.Dynamic.Outputs
-	outputConfigsMut := sync.RWMutex{}
-
 	fanOut, err := broker.NewDynamicFanOut(
 		outputs, log, stats,
 		broker.OptDynamicFanOutSetOnAdd(func(l string) {
@@ -0,0 +1,150 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/gorilla/mux"
+)
+
+//------------------------------------------------------------------------------
+
+type dynamicMgr struct {
+	router *mux.Router
+}
+
+func (d *dynamicMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	d.router.HandleFunc(path, h)
+}
+func (d *dynamicMgr) GetCache(name string) (types.Cache, error) {
+	return nil, types.ErrCacheNotFound
+}
+func (d *dynamicMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (d *dynamicMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	return nil, types.ErrRateLimitNotFound
+}
+func (d *dynamicMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (d *dynamicMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (d *dynamicMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+func (d *dynamicMgr) request(t *testing.T, method, path, body string) (int, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	d.router.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+func (d *dynamicMgr) listOutputs(t *testing.T) map[string]interface{} {
+	t.Helper()
+	code, body := d.request(t, "GET", "/outputs", "")
+	if code != http.StatusOK {
+		t.Fatalf("Unexpected status from list: %v: %s", code, body)
+	}
+	outputs := map[string]interface{}{}
+	if err := json.Unmarshal(body, &outputs); err != nil {
+		t.Fatal(err)
+	}
+	return outputs
+}
+
+//------------------------------------------------------------------------------
+
+func TestDynamicCRUD(t *testing.T) {
+	mgr := &dynamicMgr{router: mux.NewRouter()}
+
+	fooConf := NewConfig()
+	fooConf.Type = TypeInproc
+	fooConf.Inproc = "foo"
+
+	conf := NewConfig()
+	conf.Type = TypeDynamic
+	conf.Dynamic.Outputs["foo"] = fooConf
+
+	out, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = out.Consume(make(chan types.Transaction)); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		out.CloseAsync()
+		if err := out.WaitForClose(time.Second * 5); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if _, exists := conf.Dynamic.Outputs["foo"]; !exists {
+		t.Error("Static output removed from original config")
+	}
+
+	if _, exists := mgr.listOutputs(t)["foo"]; !exists {
+		t.Error("Static output foo not listed")
+	}
+
+	code, body := mgr.request(t, "POST", "/outputs/bar", `{"type":"inproc","inproc":"bar"}`)
+	if code != http.StatusOK {
+		t.Fatalf("Unexpected status from create: %v: %s", code, body)
+	}
+	if _, exists := mgr.listOutputs(t)["bar"]; !exists {
+		t.Error("Output bar not listed after creation")
+	}
+
+	code, body = mgr.request(t, "POST", "/outputs/bar", `{"type":"inproc","inproc":"baz"}`)
+	if code != http.StatusOK {
+		t.Fatalf("Unexpected status from replace: %v: %s", code, body)
+	}
+	code, body = mgr.request(t, "GET", "/outputs/bar", "")
+	if code != http.StatusOK {
+		t.Fatalf("Unexpected status from get: %v: %s", code, body)
+	}
+	if !bytes.Contains(body, []byte(`"baz"`)) {
+		t.Errorf("Replaced config not returned: %s", body)
+	}
+
+	code, body = mgr.request(t, "DELETE", "/outputs/bar", "")
+	if code != http.StatusOK {
+		t.Fatalf("Unexpected status from delete: %v: %s", code, body)
+	}
+	outputs := mgr.listOutputs(t)
+	if _, exists := outputs["bar"]; exists {
+		t.Error("Output bar listed after deletion")
+	}
+	if _, exists := outputs["foo"]; !exists {
+		t.Error("Output foo not listed after deletion of bar")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -48,7 +48,43 @@ dynamodb:
     title: ${!json_field:body.title}
     topic: ${!metadata:kafka_topic}
     full_content: ${!content}
-` + "```" + ``,
+` + "```" + `
+
+### JSON Columns
+
+Columns can also be populated from the JSON contents of each message with
+` + "`json_map_columns`" + `, which is a map of column names to dot paths. The
+value at each path is converted into the equivalent DynamoDB attribute type, so
+objects become maps, arrays become lists, numbers become numbers and so on. An
+empty path maps the entire document, and an empty column name writes each field
+of the object at the path as its own column:
+
+` + "``` yaml" + `
+type: dynamodb
+dynamodb:
+  table: foo
+  json_map_columns:
+    "": body
+    topics: meta.topics
+` + "```" + `
+
+Messages that fail to parse as JSON, or where the value mapped to an empty
+column name is not an object, are logged and dropped.
+
+### Batching and Conditional Writes
+
+All parts of a batched message are written with ` + "`BatchWriteItem`" + `
+requests of up to 25 items, and any items left unprocessed by DynamoDB are
+retried according to the ` + "`backoff`" + ` and ` + "`max_retries`" + `
+fields.
+
+When a ` + "`condition_expression`" + ` is set each item is instead written
+with its own ` + "`PutItem`" + ` request, as conditions are not supported by
+batch writes. Placeholders within the expression are resolved with
+` + "`expression_attribute_names`" + ` and
+` + "`expression_attribute_values`" + `, where values are function interpolated
+strings calculated per message part. Items that fail the condition are counted
+and dropped rather than retried.`,
 	}
 }
 
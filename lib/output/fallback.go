@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Jeffail/benthos/lib/broker"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeFallback] = TypeSpec{
+		brokerConstructor: NewFallback,
+		description: `
+Attempts to send each message to a primary output, which is the first output
+in the list, and on failure attempts to send it to each of the following
+outputs in order until one succeeds. This is useful for sending messages that
+fail to be delivered to a dead letter destination:
+
+` + "``` yaml" + `
+output:
+  fallback:
+  - type: http_client
+    http_client:
+      url: http://foo:4195/post/might/become/unreachable
+  - type: s3
+    s3:
+      bucket: dead_letters
+      path: ${!metadata:fallback_error}/${!count:files}-${!timestamp_unix_nano}.txt
+` + "```" + `
+
+Before a message is sent to a fallback output each part is annotated with the
+following metadata:
+
+` + "``` text" + `
+- fallback_error
+- fallback_attempts
+` + "```" + `
+
+Where ` + "`fallback_error`" + ` is the error returned by the previous output
+attempted and ` + "`fallback_attempts`" + ` is the number of outputs that failed
+to deliver the message so far. These fields are only set on the messages sent
+to fallback outputs and are not visible to the primary output.
+
+If all outputs fail then the error of the last output is propagated back to the
+input. Note that most outputs retry failed messages continuously until success,
+in which case a [` + "`retry`" + `](#retry) output with limited retries or a
+[` + "`drop_on`" + `](#drop_on) output with a back pressure limit should be
+used in order for the primary output to yield failures.
+
+Processors can be configured on each output and are applied to the message
+sent to that output only.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			outSlice := []interface{}{}
+			for _, output := range conf.Fallback {
+				sanOutput, err := SanitiseConfig(output)
+				if err != nil {
+					return nil, err
+				}
+				outSlice = append(outSlice, sanOutput)
+			}
+			return outSlice, nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// FallbackConfig is a config struct representing a list of outputs, the first
+// of which is the primary output.
+type FallbackConfig []Config
+
+// NewFallbackConfig creates a new FallbackConfig with default values.
+func NewFallbackConfig() FallbackConfig {
+	return FallbackConfig{}
+}
+
+//------------------------------------------------------------------------------
+
+// annotateFallback returns a copy of a message that failed to be sent with
+// metadata describing the failure.
+func annotateFallback(msg types.Message, attempts int, err error) types.Message {
+	newMsg := msg.Copy()
+	errStr := ""
+	if err != nil {
+		errStr = err.Error()
+	}
+	attemptsStr := strconv.Itoa(attempts)
+	newMsg.Iter(func(i int, p types.Part) error {
+		p.Metadata().Set("fallback_error", errStr)
+		p.Metadata().Set("fallback_attempts", attemptsStr)
+		return nil
+	})
+	return newMsg
+}
+
+// NewFallback creates a new Fallback output type. Messages are sent to the
+// first output and on failure are annotated and sent to the next output in the
+// list.
+func NewFallback(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+	pipelines ...types.PipelineConstructorFunc,
+) (Type, error) {
+	if len(conf.Fallback) == 0 {
+		return nil, errors.New("cannot create fallback output without outputs")
+	}
+	if len(conf.Fallback) == 1 {
+		return New(conf.Fallback[0], mgr, log, stats, pipelines...)
+	}
+
+	outputs := make([]types.Output, len(conf.Fallback))
+	for i, oConf := range conf.Fallback {
+		ns := fmt.Sprintf("fallback.outputs.%v", i)
+		var err error
+		if outputs[i], err = New(
+			oConf, mgr,
+			log.NewModule("."+ns),
+			metrics.Combine(stats, metrics.Namespaced(stats, ns)),
+			pipelines...,
+		); err != nil {
+			return nil, fmt.Errorf("failed to create output '%v': %v", oConf.Type, err)
+		}
+	}
+
+	return broker.NewTry(outputs, stats, broker.OptTrySetOnFailure(annotateFallback))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/broker"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestFallbackConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeFallback
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from no outputs")
+	}
+
+	badConf := NewConfig()
+	badConf.Type = "not a type"
+	conf.Fallback = append(conf.Fallback, NewConfig(), badConf)
+
+	if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad output")
+	}
+}
+
+func TestFallbackBrokerType(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeFallback
+	conf.Fallback = append(conf.Fallback, NewConfig(), NewConfig())
+
+	out, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.(*broker.Try); !ok {
+		t.Errorf("Wrong output type: %T", out)
+	}
+	out.CloseAsync()
+}
+
+func TestFallbackAnnotate(t *testing.T) {
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("baz", "qux")
+
+	newMsg := annotateFallback(msg, 2, errors.New("nope"))
+	for i := 0; i < 2; i++ {
+		if exp, act := "nope", newMsg.Get(i).Metadata().Get("fallback_error"); exp != act {
+			t.Errorf("Wrong fallback_error: %v != %v", act, exp)
+		}
+		if exp, act := "2", newMsg.Get(i).Metadata().Get("fallback_attempts"); exp != act {
+			t.Errorf("Wrong fallback_attempts: %v != %v", act, exp)
+		}
+		if act := msg.Get(i).Metadata().Get("fallback_error"); len(act) > 0 {
+			t.Errorf("Original message was modified: %v", act)
+		}
+	}
+	if exp, act := "qux", newMsg.Get(0).Metadata().Get("baz"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
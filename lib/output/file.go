@@ -21,10 +21,9 @@
 package output
 
 import (
-	"os"
-
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
 )
 
@@ -34,7 +33,7 @@ func init() {
 	Constructors[TypeFile] = TypeSpec{
 		constructor: NewFile,
 		description: `
-The file output type simply appends all messages to an output file. Single part
+The file output type appends all messages to an output file. Single part
 messages are printed with a delimiter (defaults to '\n' if left empty).
 Multipart messages are written with each part delimited, with the final part
 followed by two delimiters, e.g. a multipart message [ "foo", "bar", "baz" ]
@@ -42,23 +41,28 @@ would be written as:
 
 foo\n
 bar\n
-baz\n\n`,
-	}
-}
+baz\n\n
 
-//------------------------------------------------------------------------------
+The ` + "`path`" + ` field can be dynamically set using function interpolations
+described [here](../config_interpolation.md#functions), e.g.
+` + "`/var/log/benthos/${!timestamp:2006-01-02}/out.log`" + ` writes to a
+directory per day. The interpolations are resolved against the first part of
+each message, and directories are created as required. Files that have not been
+written to for a minute are closed.
 
-// FileConfig contains configuration fields for the file based output type.
-type FileConfig struct {
-	Path  string `json:"path" yaml:"path"`
-	Delim string `json:"delimiter" yaml:"delimiter"`
-}
+### Rotation
+
+When ` + "`rotate_max_bytes`" + ` is greater than zero a file is rotated before
+a write would take it beyond that size. When ` + "`rotate_interval`" + ` is set
+a file is rotated once it has been open for that duration. Rotated files are
+renamed with a suffix of the UTC time of rotation, e.g.
+` + "`out.log.20190101T150405.000000000`" + `, and are compressed with gzip
+when ` + "`gzip_rotated`" + ` is true.
 
-// NewFileConfig creates a new FileConfig with default values.
-func NewFileConfig() FileConfig {
-	return FileConfig{
-		Path:  "",
-		Delim: "",
+When ` + "`sync`" + ` is true files are synced to disk after each message, which
+makes writes durable at the cost of throughput. Messages can be batched before
+this output with the [` + "`batch`" + ` processor](../processors/README.md#batch)
+in order to reduce the number of syncs.`,
 	}
 }
 
@@ -66,11 +70,11 @@ func NewFileConfig() FileConfig {
 
 // NewFile creates a new File output type.
 func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	file, err := os.OpenFile(conf.File.Path, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.FileMode(0666))
+	f, err := writer.NewFile(conf.File, log, stats)
 	if err != nil {
 		return nil, err
 	}
-	return NewLineWriter(file, true, []byte(conf.File.Delim), "file", log, stats)
+	return NewWriter("file", f, log, stats)
 }
 
 //------------------------------------------------------------------------------
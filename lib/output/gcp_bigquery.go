@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGCPBigQuery] = TypeSpec{
+		constructor: NewGCPBigQuery,
+		description: `
+Inserts messages as rows of a GCP BigQuery table using streaming inserts. Each
+message part must be a JSON object, where fields are mapped to the columns of
+the table schema by name and nested objects are mapped to record columns. Parts
+that are not JSON objects are logged and dropped. All parts of a batched message
+that target the same table are inserted with a single request.
+
+Rows that do not match the schema cause the whole request to fail unless
+` + "`skip_invalid_rows`" + ` is true, and fields that do not exist in the
+schema are rejected unless ` + "`ignore_unknown_values`" + ` is true.
+
+When ` + "`insert_id`" + ` is set it is used as the insert ID of each row,
+which BigQuery uses in order to deduplicate rows that are retried shortly after
+a previous attempt. When left empty a random ID is generated for each row.
+
+The ` + "`table`" + ` field can be dynamically set using function
+interpolations described [here](../config_interpolation.md#functions), which are
+performed per message part. This can be used to target a partition of a
+partitioned table with a decorator such as ` + "`events$${!metadata:day}`" + `.
+When ` + "`template_suffix`" + ` is set rows are instead inserted into the
+table named by the table and suffix combined, which is created from the schema
+of the target table when it does not yet exist.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGCPBigQuery creates a new GCPBigQuery output type.
+func NewGCPBigQuery(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g, err := writer.NewGCPBigQuery(conf.GCPBigQuery, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("gcp_bigquery", g, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,82 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGCPCloudStorage] = TypeSpec{
+		constructor: NewGCPCloudStorage,
+		description: `
+Sends message parts as objects to a GCP Cloud Storage bucket. Each object is
+uploaded with the path specified with the ` + "`path`" + ` field. In order to
+have a different path for each object you should use function interpolations
+described [here](../config_interpolation.md#functions), which are calculated per
+message of a batch. Credentials are resolved using the standard
+[application default credentials](https://cloud.google.com/docs/authentication/production).
+
+### Batching
+
+The parts of a batched message can be combined into a single object with
+` + "`batch_codec`" + `. The codec ` + "`lines`" + ` joins the parts with line
+breaks and ` + "`tar`" + ` writes each part as an entry of a tar archive,
+whereas ` + "`none`" + ` uploads each part as its own object. When batching, the
+path is calculated from the first part of the batch. Messages can be batched
+before this output with the
+[` + "`batch`" + ` processor](../processors/README.md#batch).
+
+Objects can be compressed with gzip by setting ` + "`compression`" + ` to
+` + "`gzip`" + `, which is applied after the batch codec.
+
+### Uploads
+
+Objects are uploaded in chunks of ` + "`chunk_size`" + ` bytes using resumable
+uploads. Setting ` + "`chunk_size`" + ` to zero uploads each object with a
+single request instead, which is more efficient for small objects.
+
+### Encryption
+
+Objects can be encrypted with a customer-managed encryption key by setting
+` + "`kms_key_name`" + ` to the resource name of a Cloud KMS key, in the form
+` + "`projects/P/locations/L/keyRings/R/cryptoKeys/K`" + `. The service account
+of the bucket's project must be permitted to use the key.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGCPCloudStorage creates a new GCP Cloud Storage output type.
+func NewGCPCloudStorage(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g, err := writer.NewGCPCloudStorage(conf.GCPCloudStorage, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("gcp_cloud_storage", g, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -34,7 +34,27 @@ func init() {
 		constructor: NewGCPPubSub,
 		description: `
 Sends messages to a GCP Cloud Pub/Sub topic. Metadata from messages are sent as
-attributes.`,
+attributes.
+
+### Ordering Keys
+
+The field ` + "`ordering_key`" + ` supports
+[interpolation functions](../config_interpolation.md#functions) and, when set,
+messages are published with the resolved key and message ordering is enabled on
+the topic. Messages of a batch that resolve to the same non-empty key are
+published in sequence, with each message acknowledged before the next is sent.
+If a message fails then the remaining messages with the same key are not sent.
+
+Subscribers only receive messages in order when their subscription has message
+ordering enabled.
+
+### Flow Control
+
+The fields ` + "`max_outstanding_messages`" + ` and
+` + "`max_outstanding_bytes`" + ` limit the number of messages and bytes that
+can be published without yet being acknowledged. Once a limit is reached
+publishing blocks, which applies back pressure to the pipeline. A value of zero
+disables a limit.`,
 	}
 }
 
@@ -0,0 +1,70 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGraphite] = TypeSpec{
+		constructor: NewGraphite,
+		description: `
+Converts JSON messages into metrics of the Graphite plaintext protocol and sends
+them to a Graphite server (or any compatible server such as carbon-relay) over
+TCP.
+
+The metric ` + "`path`" + ` can be dynamically set using function
+interpolations described [here](../config_interpolation.md#functions), and
+any whitespace within it is replaced with underscores. The value of each metric
+is extracted from the dot path ` + "`value_path`" + `, and can be a number, a
+numeric string or a boolean, where true and false are sent as 1 and 0.
+
+When ` + "`timestamp_path`" + ` is set the timestamp of each metric is extracted
+from it, and can be either a number of seconds since the unix epoch or an RFC
+3339 string. Otherwise, or when the path is missing from a message, the current
+time is used.
+
+Message parts that fail to be converted into metrics are logged and dropped. All
+parts of a batched message are sent within a single write, and messages can be
+batched before this output with the
+[` + "`batch`" + ` processor](../processors/README.md#batch) in order to improve
+throughput.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGraphite creates a new Graphite output type.
+func NewGraphite(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	g, err := writer.NewGraphite(conf.Graphite, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("graphite", g, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,72 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGRPCClient] = TypeSpec{
+		constructor: NewGRPCClient,
+		description: `
+Invokes a gRPC method for each message or batch. The ` + "`method`" + ` field
+is the full name of the method in the form ` + "`/package.Service/Method`" + `.
+Message contents are sent to the server as-is and should therefore already be
+serialised in the format expected by the method (usually protobuf). Responses
+from the server are discarded.
+
+The ` + "`rpc_type`" + ` field determines how messages are sent. When set to
+` + "`unary`" + ` a call is made for each message part. When set to
+` + "`client_stream`" + ` a single call is made for each batch, with each
+message part sent as a separate message of the client stream.
+
+The ` + "`timeout`" + ` field sets the deadline of each call, and can be
+disabled by setting it to an empty string.
+
+The values of the ` + "`metadata`" + ` field are sent as gRPC metadata headers
+and can be dynamically set using function interpolations described
+[here](../config_interpolation.md#functions). For unary calls these
+interpolations are performed per message part, and for client streaming calls
+they are resolved against the first message part of the batch.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewGRPCClient creates a new GRPCClient output type.
+func NewGRPCClient(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewGRPCClient(conf.GRPCClient, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("grpc_client", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
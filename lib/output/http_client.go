@@ -51,7 +51,20 @@ interpolations described [here](../config_interpolation.md#functions).
 
 The body of the HTTP request is the raw contents of the message payload. If the
 message has multiple parts the request will be sent according to
-[RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html)`,
+[RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html)
+
+### Propagating Responses
+
+When ` + "`propagate_response`" + ` is set to ` + "`true`" + ` the response of
+each request is captured as a message, where each part of a multipart response
+becomes a message part, and the response headers and the metadata field
+` + "`http_status_code`" + ` are set on each part. The response message is then
+passed back to the input of the message if that input supports it, such as the
+` + "`http_server`" + ` input with ` + "`sync_response`" + ` enabled, which
+allows Benthos to act as a request/response proxy.
+
+To process responses within a pipeline rather than pass them back to an input
+use the ` + "`http`" + ` processor instead.`,
 	}
 }
 
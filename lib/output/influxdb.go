@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeInfluxDB] = TypeSpec{
+		constructor: NewInfluxDB,
+		description: `
+Converts JSON messages into points of the InfluxDB line protocol and writes
+them over HTTP. All parts of a batched message are written with a single
+request, and parts that are not valid JSON or contain no field values are
+logged and dropped. Messages can be batched before this output with the
+[` + "`batch`" + ` processor](../processors/README.md#batch) in order to
+improve throughput.
+
+The ` + "`api_version`" + ` field selects the write endpoint. For
+` + "`v1`" + ` points are written to ` + "`database`" + ` and optionally
+` + "`retention_policy`" + `, where credentials can be set with
+` + "`basic_auth`" + `. For ` + "`v2`" + ` points are written to
+` + "`bucket`" + ` within ` + "`org`" + `, authenticated with
+` + "`token`" + `.
+
+The ` + "`measurement`" + ` field can be dynamically set using function
+interpolations described [here](../config_interpolation.md#functions), which
+are performed per message part.
+
+The ` + "`tags`" + ` and ` + "`fields`" + ` maps have the tag or field name as
+each key and a dot path to its value within the message as each value. When
+` + "`fields`" + ` is empty each top level value of the message that is not
+mapped to a tag or the timestamp is written as a field. Numbers are written as
+floats, and nested objects and arrays are ignored.
+
+When ` + "`timestamp_path`" + ` is set the timestamp of each point is taken from
+the message, either as a number in units of ` + "`precision`" + ` or as an RFC
+3339 string. Otherwise the server assigns the timestamp.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewInfluxDB creates a new InfluxDB output type.
+func NewInfluxDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	i, err := writer.NewInfluxDB(conf.InfluxDB, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("influxdb", i, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -50,10 +50,59 @@ function interpolations described [here](../config_interpolation.md#functions).
 When sending batched messages these interpolations are performed per message
 part.
 
-By default the paritioner will select partitions based on a hash of the key
-value. If the key is empty then a partition is chosen at random. You can
-alternatively force the partitioner to round-robin partitions with the field
-` + "`round_robin_partitions`" + `.
+### Partitioning
+
+The partition of each message is selected according to the
+` + "`partitioner`" + ` field, which can be one of the following:
+
+- ` + "`fnv1a_hash`" + `: Partitions by the FNV-1a hash of the key. This is the
+  default and matches previous versions of this output.
+- ` + "`murmur2_hash`" + `: Partitions by the murmur2 hash of the key, which
+  matches the default partitioner of the Java client. Use this when sharing
+  topics with Java producers in order for keys to map to the same partitions.
+- ` + "`random`" + `: Chooses a partition at random.
+- ` + "`round_robin`" + `: Cycles through partitions in turn.
+- ` + "`manual`" + `: Uses the partition specified by the
+  ` + "`partition`" + ` field, which can be dynamically set using function
+  interpolations and must resolve to an integer.
+
+With the hash partitioners messages without a key are given a random partition.
+The deprecated field ` + "`round_robin_partitions`" + ` overrides the
+partitioner with ` + "`round_robin`" + ` when set to ` + "`true`" + `.
+
+### Idempotent Writes
+
+When ` + "`idempotent_write`" + ` is set to ` + "`true`" + ` the producer is
+assigned an ID by the cluster and each message is written with a sequence
+number, allowing brokers to discard duplicates caused by the producer retrying
+a request. Idempotent writes require a ` + "`target_version`" + ` of at least
+` + "`0.11.0.0`" + `, and imply ` + "`ack_replicas`" + ` along with a single in
+flight request per broker.
+
+Duplicates can still occur when a message is rejected by this output and later
+retried by the pipeline, as the retried message is written as a new record.
+Idempotent writes therefore do not provide exactly-once delivery on their own.
+
+### Transactions
+
+When ` + "`transaction.id`" + ` is set each message batch is written within a
+Kafka transaction of that ID, so that a batch is either written in its entirety
+or not at all. Transactions imply idempotent writes and require a
+` + "`target_version`" + ` of at least ` + "`0.11.0.0`" + `. The ID should be
+unique to each instance of Benthos writing to the cluster, and stable across
+restarts so that the transactions of a previous instance are fenced off.
+
+When ` + "`transaction.consumer_group`" + ` is also set, the offsets of messages
+consumed by a ` + "`kafka_balanced`" + ` input of that consumer group are
+committed within the same transaction. The ` + "`kafka`" + ` input is not
+supported here, as it fetches its committed offsets with an older version of the
+protocol that does not see offsets committed by transactions. The offsets are
+taken from the ` + "`kafka_topic`" + `, ` + "`kafka_partition`" + ` and
+` + "`kafka_offset`" + ` metadata fields of each message, which must therefore
+be preserved by any processors. Messages of a batch are then written exactly
+once along with the progress of the consumer group, giving exactly-once
+delivery for Kafka to Kafka pipelines, provided that consumers of the output
+topics read with an isolation level of ` + "`read_committed`" + `.
 
 ` + tls.Documentation + ``,
 	}
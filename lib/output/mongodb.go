@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMongoDB] = TypeSpec{
+		constructor: NewMongoDB,
+		description: `
+Writes messages as documents of a MongoDB collection. Each message part must be
+a JSON document, and all parts of a batched message are written with a single
+ordered bulk write. Parts that are not valid JSON are logged and dropped.
+Messages can be batched with the ` + "[`batching`](#batching)" + ` field of
+this output in order to improve throughput.
+
+The ` + "`operation`" + ` field determines the type of write and can be one of
+` + "`insert`" + `, ` + "`replace`" + ` or ` + "`update`" + `. Replace and
+update operations act on the first document matching a filter, and when
+` + "`upsert`" + ` is true a document is inserted when no match is found. If the
+document of an update operation does not contain update operators, such as
+` + "`$set`" + ` or ` + "`$inc`" + `, its fields are applied with
+` + "`$set`" + `.
+
+The document written is the whole message unless ` + "`document_map`" + ` is
+set, in which case each key of the map is a field of the document and each value
+is a dot path to the value within the message. Filters are created from
+messages in the same way using ` + "`filter_map`" + `, which is required for
+replace and update operations. Missing values are written as ` + "`null`" + `.
+
+The ` + "`url`" + ` field follows the
+[MongoDB connection string](https://docs.mongodb.com/manual/reference/connection-string/)
+format, including options such as ` + "`replicaSet`" + `,
+` + "`authSource`" + ` and ` + "`authMechanism`" + `. When a URL lists
+multiple hosts, or names a replica set, the members of the deployment are
+discovered from the listed hosts and writes are always sent to the current
+primary. The database can be set either within the URL or with the
+` + "`database`" + ` field.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewMongoDB creates a new MongoDB output type.
+func NewMongoDB(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	m, err := writer.NewMongoDB(conf.MongoDB, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("mongodb", m, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
 )
 
 //------------------------------------------------------------------------------
@@ -33,7 +34,18 @@ func init() {
 	Constructors[TypeMQTT] = TypeSpec{
 		constructor: NewMQTT,
 		description: `
-Pushes messages to an MQTT broker.`,
+Pushes messages to an MQTT broker.
+
+The ` + "`topic`" + ` field can be dynamically set using function interpolations
+described [here](../config_interpolation.md#functions). When sending batched
+messages these interpolations are performed per message part.
+
+The ` + "`qos`" + ` field sets the quality of service level of published
+messages and must be 0, 1 or 2. When ` + "`retained`" + ` is true the broker
+retains the last message published to each topic and delivers it to new
+subscribers.
+
+` + tls.Documentation,
 	}
 }
 
@@ -34,9 +34,19 @@ func init() {
 		constructor: NewNanomsg,
 		description: `
 The scalability protocols are common communication patterns. This output should
-be compatible with any implementation, but specifically targets Nanomsg.
+be compatible with any implementation, including Nanomsg and NNG, and supports
+the ` + "`tcp`" + `, ` + "`ipc`" + `, ` + "`inproc`" + ` and ` + "`ws`" + `
+transports.
 
-Currently only PUSH and PUB sockets are supported.`,
+The ` + "`socket_type`" + ` can be one of PUSH, PUB or PAIR. When
+` + "`bind`" + ` is true the output listens on each of the ` + "`urls`" + `,
+otherwise it dials them. Dialed connections are reestablished in the background
+whenever they are lost, with attempts spaced by ` + "`reconnect_interval`" + `.
+When ` + "`max_reconnect_interval`" + ` is set the interval doubles after each
+failed attempt up to that maximum.
+
+The ` + "`poll_timeout`" + ` is the maximum period to wait for a message to be
+accepted by the socket before the send is considered failed and reattempted.`,
 	}
 }
 
@@ -0,0 +1,72 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeNATSJetStream] = TypeSpec{
+		constructor: NewNATSJetStream,
+		description: `
+Publish to a NATS JetStream subject. Each message is only acknowledged once the
+server has responded with a publish ack, which provides at-least-once delivery
+guarantees.
+
+The ` + "`subject`" + ` field supports
+[interpolation functions](../config_interpolation.md#functions), allowing you
+to set the subject dynamically per message part.
+
+If ` + "`stream`" + ` is set then the publish ack must originate from that
+stream, otherwise the write is rejected. A write is also rejected when no stream
+is listening on the subject.
+
+The ` + "`msg_id`" + ` field, when set, is used as the ` + "`Nats-Msg-Id`" + `
+header of each message, which the server uses to discard duplicates published
+within the duplicate window of the stream. This field also supports
+[interpolation functions](../config_interpolation.md#functions). Acks reporting
+a duplicate message are accepted and counted under the metric
+` + "`duplicate`" + `.
+
+When ` + "`msg_id`" + ` is set and no ack is received within
+` + "`ack_timeout`" + ` the publish is retried according to the
+` + "`max_retries`" + ` and ` + "`backoff`" + ` fields. Without a message ID a
+retry could store the message twice, and so the write fails immediately
+instead.`,
+	}
+}
+
+// NewNATSJetStream creates a new NATS JetStream output type.
+func NewNATSJetStream(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewNATSJetStream(conf.NATSJetStream, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("nats_jetstream", w, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,77 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypePulsar] = TypeSpec{
+		constructor: NewPulsar,
+		description: `
+Publishes messages to an Apache Pulsar topic through the WebSocket API of a
+Pulsar broker or proxy, where ` + "`url`" + ` is the WebSocket service URL
+(use a ` + "`wss://`" + ` scheme along with the ` + "`tls`" + ` fields for
+TLS connections). When ` + "`auth_token`" + ` is set it is sent as a bearer
+token in order to authenticate with the broker.
+
+Messages are only acknowledged once every part has been persisted by the
+broker. The metadata of each part is sent as message properties, and the
+` + "`key`" + ` field can be dynamically set using function interpolations
+described [here](../config_interpolation.md#functions), which are calculated
+per message of a batch. Keys determine the partition of partitioned topics and
+the consumer of ` + "`key_shared`" + ` subscriptions.
+
+The field ` + "`compression`" + ` can be one of ` + "`none`" + `,
+` + "`lz4`" + ` or ` + "`zlib`" + `. When ` + "`batching_enabled`" + ` is
+` + "`true`" + ` the broker side producer groups messages into batches of up to
+` + "`batching_max_messages`" + ` messages, waiting at most
+` + "`batching_max_publish_delay`" + ` for a batch to fill. The selection of a
+key based batcher is not exposed by the WebSocket API, and therefore batches
+may contain messages of different keys.
+
+The producer holds at most ` + "`max_pending_messages`" + ` messages awaiting
+acknowledgement from the broker, and messages that are not persisted within
+` + "`send_timeout`" + ` fail.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewPulsar creates a new Pulsar output type.
+func NewPulsar(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	p, err := writer.NewPulsar(conf.Pulsar, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("pulsar", p, log, stats)
+}
+
+//------------------------------------------------------------------------------
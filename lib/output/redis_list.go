@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/redis"
 )
 
 //------------------------------------------------------------------------------
@@ -34,7 +35,10 @@ func init() {
 		constructor: NewRedisList,
 		description: `
 Pushes messages onto the end of a Redis list (which is created if it doesn't
-already exist) using the RPUSH command.`,
+already exist) using the RPUSH command. The parts of a batch are sent as a single
+pipeline of commands.
+
+` + redis.Documentation,
 	}
 }
 
@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/redis"
 )
 
 //------------------------------------------------------------------------------
@@ -34,7 +35,10 @@ func init() {
 		constructor: NewRedisPubSub,
 		description: `
 Publishes messages through the Redis PubSub model. It is not possible to
-guarantee that messages have been received.`,
+guarantee that messages have been received. The parts of a batch are sent as a
+single pipeline of commands.
+
+` + redis.Documentation,
 	}
 }
 
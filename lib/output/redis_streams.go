@@ -25,6 +25,7 @@ import (
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/output/writer"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/redis"
 )
 
 //------------------------------------------------------------------------------
@@ -35,14 +36,28 @@ func init() {
 		description: `
 Pushes messages to a Redis (v5.0+) Stream (which is created if it doesn't
 already exist) using the XADD command. It's possible to specify a maximum length
-of the target stream by setting it to a value greater than 0, in which case this
+of the target stream by setting it to a value greater than 0. By default this
 cap is applied only when Redis is able to remove a whole macro node, for
-efficiency.
+efficiency, setting ` + "`max_length_exact`" + ` to true trims the stream to
+exactly the maximum length instead.
 
 Redis stream entries are key/value pairs, as such it is necessary to specify the
-key to be set to the body of the message. All metadata fields of the message
-will also be set as key/value pairs, if there is a key collision between
-a metadata item and the body then the body takes precedence.`,
+key to be set to the body of the message. When ` + "`include_metadata`" + ` is
+true all metadata fields of the message will also be set as key/value pairs.
+
+The field ` + "`json_fields`" + ` maps entry keys to dot separated paths of the
+message body parsed as JSON. String values are set as they are and other values
+are serialised as JSON, fields that do not exist are skipped. Setting
+` + "`body_key`" + ` to an empty string omits the body from entries.
+
+If there is a key collision then JSON fields take precedence over metadata and
+the body takes precedence over both. Messages that cannot be mapped, or that
+result in an empty entry, are dropped and counted under the metric
+` + "`error.mapping`" + `.
+
+The parts of a batch are sent as a single pipeline of commands.
+
+` + redis.Documentation,
 	}
 }
 
@@ -0,0 +1,192 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeReject] = TypeSpec{
+		constructor: NewReject,
+		description: `
+Rejects all messages, treating them as though the output destination failed to
+publish them. The error reason is the value of this field, which can be
+dynamically set using function interpolations described
+[here](../config_interpolation.md#functions).
+
+The error is propagated back to the input, which will nack the message if it
+supports it. This only works when the parent of this output returns errors to
+the input, which excludes parents that retry failed messages until success.
+This output is most useful when combined with the
+` + "[`switch`](#switch)" + ` output, with ` + "`retry_until_success`" + ` set
+to ` + "`false`" + `, in order to reject messages that match specific
+conditions, for example:
+
+` + "``` yaml" + `
+output:
+  switch:
+    retry_until_success: false
+    outputs:
+    - output:
+        reject: "message rejected due to: ${!metadata:reason}"
+      condition:
+        metadata:
+          operator: exists
+          key: reason
+      fallthrough: false
+    - output:
+        type: stdout
+` + "```" + ``,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RejectConfig contains configuration fields for the Reject output type.
+type RejectConfig string
+
+// NewRejectConfig creates a new RejectConfig with default values.
+func NewRejectConfig() RejectConfig {
+	return RejectConfig("")
+}
+
+//------------------------------------------------------------------------------
+
+// Reject is an output type that rejects all messages with an interpolated
+// error.
+type Reject struct {
+	running int32
+
+	errStr *text.InterpolatedString
+	log    log.Modular
+	stats  metrics.Type
+
+	transactionsIn <-chan types.Transaction
+
+	closedChan chan struct{}
+	closeChan  chan struct{}
+}
+
+// NewReject creates a new Reject output type.
+func NewReject(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	if len(conf.Reject) == 0 {
+		return nil, errors.New("an error reason must be specified")
+	}
+	return &Reject{
+		running:    1,
+		errStr:     text.NewInterpolatedString(string(conf.Reject)),
+		log:        log,
+		stats:      stats,
+		closedChan: make(chan struct{}),
+		closeChan:  make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// loop is an internal loop that rejects incoming messages.
+func (r *Reject) loop() {
+	var (
+		mRunning    = r.stats.GetGauge("running")
+		mCount      = r.stats.GetCounter("count")
+		mPartsCount = r.stats.GetCounter("parts.count")
+		mRejected   = r.stats.GetCounter("rejected")
+	)
+
+	defer func() {
+		mRunning.Decr(1)
+		atomic.StoreInt32(&r.running, 0)
+		close(r.closedChan)
+	}()
+	mRunning.Incr(1)
+
+	for atomic.LoadInt32(&r.running) == 1 {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-r.transactionsIn:
+			if !open {
+				return
+			}
+		case <-r.closeChan:
+			return
+		}
+
+		mCount.Incr(1)
+		mPartsCount.Incr(int64(ts.Payload.Len()))
+
+		err := errors.New(r.errStr.Get(ts.Payload))
+		r.log.Debugf("Rejecting message: %v\n", err)
+		mRejected.Incr(1)
+
+		select {
+		case ts.ResponseChan <- response.NewError(err):
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (r *Reject) Consume(ts <-chan types.Transaction) error {
+	if r.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	r.transactionsIn = ts
+	go r.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (r *Reject) Connected() bool {
+	return true
+}
+
+// CloseAsync shuts down the Reject output and stops processing messages.
+func (r *Reject) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
+		close(r.closeChan)
+	}
+}
+
+// WaitForClose blocks until the Reject output has closed down.
+func (r *Reject) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
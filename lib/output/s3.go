@@ -37,7 +37,31 @@ Sends message parts as objects to an Amazon S3 bucket. Each object is uploaded
 with the path specified with the ` + "`path`" + ` field. In order to have a
 different path for each object you should use function interpolations described
 [here](../config_interpolation.md#functions), which are calculated per message
-of a batch.`,
+of a batch. For example, a path of
+` + "`${!metadata:kafka_topic}/${!timestamp:2006/01/02}/${!count:files}.txt`" + `
+partitions objects by topic and date.
+
+### Batching
+
+Uploading an object for each message part is expensive at high volumes, and
+therefore the parts of a batched message can be combined into a single object
+with ` + "`batch_codec`" + `. The codec ` + "`lines`" + ` joins the parts with
+line breaks and ` + "`tar`" + ` writes each part as an entry of a tar archive,
+whereas ` + "`none`" + ` uploads each part as its own object. When batching, the
+path is calculated from the first part of the batch. Messages can be batched
+before this output with the
+[` + "`batch`" + ` processor](../processors/README.md#batch).
+
+Objects can be compressed with gzip by setting ` + "`compression`" + ` to
+` + "`gzip`" + `, which is applied after the batch codec.
+
+### Multipart Uploads
+
+Objects larger than ` + "`part_size`" + ` bytes are uploaded in parts, where up
+to ` + "`concurrency`" + ` parts of an object are uploaded in parallel. The part
+size must be at least 5MiB. The ` + "`timeout`" + ` applies to the upload of
+all objects of a message, and should therefore be increased when writing large
+objects.`,
 	}
 }
 
@@ -0,0 +1,69 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSFTP] = TypeSpec{
+		constructor: NewSFTP,
+		description: `
+Writes each message as a file on an SFTP server. The server is authenticated
+with either a ` + "`password`" + ` or a ` + "`private_key_file`" + `. When a
+` + "`known_hosts_file`" + ` is specified the host key of the server is
+verified against it, otherwise any host key is accepted.
+
+Each message is written to a single file, with every message part followed by
+the ` + "`delimiter`" + `, which defaults to a newline. The ` + "`path`" + `
+can be dynamically set using function interpolations described
+[here](../config_interpolation.md#functions), which are resolved using the first
+part of the message. Directories in the path that do not exist are created.
+Messages can be batched before this output with the
+[` + "`batch`" + ` processor](../processors/README.md#batch) in order to write
+multiple messages into each file.
+
+Files are first written to a temporary path with the ` + "`temp_suffix`" + `
+appended and are renamed to the target path once complete, so that consumers
+polling the remote directory never observe partially written files. An existing
+file at the target path is replaced. Set ` + "`temp_suffix`" + ` to an empty
+string in order to write directly to the target path.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSFTP creates a new SFTP output type.
+func NewSFTP(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSFTP(conf.SFTP, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("sftp", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,100 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSnowflake] = TypeSpec{
+		constructor: NewSnowflake,
+		description: `
+Loads messages into a Snowflake table through a
+[Snowpipe](https://docs.snowflake.com/en/user-guide/data-load-snowpipe-intro).
+All parts of a message are written as rows of a single file, which is uploaded
+to an internal stage with a ` + "`PUT`" + ` command and then submitted to the
+pipe via the Snowpipe REST API. A message is only acknowledged once the file has
+been accepted for ingestion, Snowpipe loads files asynchronously and load errors
+are reported through the ` + "`COPY_HISTORY`" + ` of the table.
+
+The pipe, stage and table must already exist, where the pipe is created with a
+` + "`COPY INTO`" + ` statement reading from the configured ` + "`stage`" + `,
+which is either a named internal stage such as ` + "`@my_stage`" + ` or the
+stage of a table such as ` + "`@%my_table`" + `. User stages cannot be used with
+pipes. The ` + "`path`" + ` of each file is relative to the stage and can be
+dynamically set using function interpolations described
+[here](../config_interpolation.md#functions), which are resolved against the
+first part of each message.
+
+### Authentication
+
+Connections to Snowflake and requests to Snowpipe are authenticated with key
+pair authentication, where ` + "`private_key_file`" + ` is the path of an
+unencrypted PEM encoded RSA private key whose public key has been assigned to
+` + "`user`" + `. The ` + "`role`" + ` must be granted write access to the stage.
+The ` + "`url`" + ` field can be used to override the default endpoint of
+` + "`https://<account>.snowflakecomputing.com`" + `.
+
+### File Formats
+
+When ` + "`file_format`" + ` is ` + "`json`" + ` each part must be a valid
+JSON document and is written as a line of the file, parts that fail to parse
+are logged and dropped. When ` + "`csv`" + ` each part is written as a line of
+the file as is. Files are compressed with gzip unless ` + "`compression`" + ` is
+set to ` + "`none`" + `.
+
+When ` + "`parquet`" + ` each part must be a JSON object and the columns of the
+file are the union of all object keys, where keys missing from an object are
+written as nulls. Columns containing only booleans or numbers are written with
+boolean, integer or double types, and all other columns are written as strings
+where values that are not strings are serialised as JSON. The column chunks of
+the file are compressed with gzip unless ` + "`compression`" + ` is set to
+` + "`none`" + `.
+
+The file format of the pipe must match these settings.
+
+### Flushing
+
+A file is written for each message that reaches this output, therefore small
+messages should be batched in order to avoid staging many tiny files. Flush
+policies based on message count, byte size and period can be configured with
+the ` + "[`batching`](#batching)" + ` field of this output.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSnowflake creates a new Snowflake output type.
+func NewSnowflake(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSnowflake(conf.Snowflake, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("snowflake", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
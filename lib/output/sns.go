@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSNS] = TypeSpec{
+		constructor: NewAmazonSNS,
+		description: `
+Sends messages to an AWS SNS topic. Each message part is published as an
+individual SNS message.
+
+The ` + "`topic_arn`" + ` field supports
+[interpolation functions](../config_interpolation.md#functions), allowing you
+to select the target topic per message.
+
+Metadata of each message part is sent as string message attributes, with the
+exception of empty values, which SNS rejects.
+
+When publishing to a FIFO topic the fields ` + "`message_group_id`" + ` and
+` + "`message_deduplication_id`" + ` can be set, both of which support
+interpolation functions. Leaving a field empty omits it from requests.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewAmazonSNS creates a new AmazonSNS output type.
+func NewAmazonSNS(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSNS(conf.SNS, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("sns", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
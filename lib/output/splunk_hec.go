@@ -0,0 +1,74 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSplunkHEC] = TypeSpec{
+		constructor: NewSplunkHEC,
+		description: `
+Sends messages as events to a Splunk HTTP Event Collector, authenticated with
+the ` + "`token`" + ` of the collector. Message parts that contain valid JSON
+are sent as JSON events and other parts are sent as string events. All parts of
+a batched message are sent with a single request, which can be compressed by
+setting ` + "`gzip`" + ` to true.
+
+The ` + "`index`" + `, ` + "`source`" + `, ` + "`sourcetype`" + ` and
+` + "`host`" + ` fields can be dynamically set using function interpolations
+described [here](../config_interpolation.md#functions), which are performed per
+message part. Empty values are omitted from the event, in which case the
+defaults of the token apply.
+
+### Acknowledgements
+
+When ` + "`use_ack`" + ` is true each request is sent with a channel identifier,
+and writes are only considered successful once the collector acknowledges that
+the events have been indexed. The acknowledgement endpoint is polled every
+` + "`ack_poll_interval`" + `, and if the events are not acknowledged within
+` + "`ack_timeout`" + ` the write fails and the message is sent again. The
+channel is set with ` + "`channel`" + `, or a random identifier is generated
+when it is empty. Indexer acknowledgement must be enabled for the token.
+
+` + tls.Documentation,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSplunkHEC creates a new SplunkHEC output type.
+func NewSplunkHEC(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSplunkHEC(conf.SplunkHEC, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("splunk_hec", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
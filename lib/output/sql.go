@@ -0,0 +1,75 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSQL] = TypeSpec{
+		constructor: NewSQL,
+		description: `
+Executes a parameterised statement, such as an ` + "`INSERT`" + ` or an upsert,
+against a SQL database for each message part. The supported drivers are
+` + "`postgres`" + ` and ` + "`mysql`" + `, and the format of the
+` + "`dsn`" + ` field depends on the driver. SQLite is not currently supported.
+
+The arguments of the statement are extracted from each message part, which
+must be a JSON document, using the dot paths listed in ` + "`args`" + `.
+Missing values are inserted as ` + "`NULL`" + `, and objects or arrays are
+inserted as JSON strings. Placeholders within the query must match the syntax
+of the driver, e.g. ` + "`$1`" + ` for postgres and ` + "`?`" + ` for mysql.
+Parts that are not valid JSON are logged and dropped.
+
+All parts of a batched message are executed within a single transaction.
+Messages can be batched before this output with the
+[` + "`batch`" + ` processor](../processors/README.md#batch) in order to
+improve throughput.
+
+When a transaction fails due to a serialization failure or deadlock it is
+retried according to the ` + "`max_retries`" + ` and ` + "`backoff`" + `
+fields. Other errors result in the batch being rejected.
+
+The size of the connection pool can be limited with
+` + "`max_open_connections`" + `, where zero means unlimited, and
+` + "`max_idle_connections`" + ` sets the number of connections kept open
+between writes.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSQL creates a new SQL output type.
+func NewSQL(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSQL(conf.SQL, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("sql", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -33,7 +33,18 @@ func init() {
 	Constructors[TypeSQS] = TypeSpec{
 		constructor: NewAmazonSQS,
 		description: `
-Sends messages to an SQS queue.`,
+Sends messages to an SQS queue. Message batches are sent with
+SendMessageBatch requests of up to ten messages each.
+
+When writing to a FIFO queue the fields ` + "`message_group_id`" + ` and
+` + "`message_deduplication_id`" + ` can be set, both of which support
+[interpolation functions](../config_interpolation.md#functions) resolved per
+message. Leaving a field empty omits it from requests.
+
+Messages of a batch that fail due to a server side fault are retried according
+to the ` + "`max_retries`" + ` and ` + "`backoff`" + ` fields, and each failed
+entry is counted under the metric ` + "`error.entry`" + `. Messages that fail
+due to a sender fault are not retried and fail the write.`,
 	}
 }
 
@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package output
+
+import (
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/output/writer"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSubprocess] = TypeSpec{
+		constructor: NewSubprocess,
+		description: `
+Executes a command and writes messages to its stdin, which allows you to wrap
+any tool that consumes data from stdin. Anything written to stderr by the
+command is logged at the warning level, and anything written to stdout is
+discarded.
+
+The ` + "`codec`" + ` field determines how messages are written to stdin. With
+` + "`lines`" + ` each message part is followed by a newline, and therefore
+parts should not contain newlines themselves. With ` + "`length_prefixed`" + `
+each message part is preceded by its size as a four byte big endian unsigned
+integer.
+
+The command is restarted whenever it exits, and a message that failed to be
+written is sent again to the new command. Restarts are delayed by
+` + "`restart_backoff`" + `, which doubles each time the command exits without
+consuming any messages up to a maximum of ` + "`max_restart_backoff`" + `. When
+the output is closed the stdin of the command is closed, giving it a chance to
+flush any pending data and exit gracefully.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSubprocess creates a new Subprocess output type.
+func NewSubprocess(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := writer.NewSubprocess(conf.Subprocess, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter("subprocess", s, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -105,9 +105,20 @@ The switch output requires a minimum of two outputs. If no condition is defined
 for an output, it behaves like a static ` + "`true`" + ` condition. If
 ` + "`fallthrough`" + ` is set to ` + "`true`" + `, the switch output will
 continue evaluating additional outputs after finding a match. If an output
-applies back pressure it will block all subsequent messages, and if an output
-fails to send a message, it will be retried continuously until completion or
-service shut down. Messages that do not match any outputs will be dropped.`,
+applies back pressure it will block all subsequent messages.
+
+By default, if an output fails to send a message it will be retried
+continuously until completion or service shut down. When
+` + "`retry_until_success`" + ` is set to ` + "`false`" + ` the error is
+instead returned to the input, which allows outputs such as
+` + "[`reject`](#reject)" + ` to nack messages. When a message is sent to
+multiple outputs and any of them fails the whole message is returned with an
+error, and may therefore be sent again to the outputs that succeeded.
+
+An output can be marked as a default case by setting ` + "`default`" + ` to
+` + "`true`" + `, in which case its condition and fallthrough fields are ignored
+and it receives only messages that do not match any other outputs. Messages that
+do not match any outputs and have no default case will be dropped.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			outSlice := []interface{}{}
 			for _, out := range conf.Switch.Outputs {
@@ -122,12 +133,14 @@ service shut down. Messages that do not match any outputs will be dropped.`,
 				sanit := map[string]interface{}{
 					"output":      sanOutput,
 					"fallthrough": out.Fallthrough,
+					"default":     out.Default,
 					"condition":   sanCond,
 				}
 				outSlice = append(outSlice, sanit)
 			}
 			return map[string]interface{}{
-				"outputs": outSlice,
+				"retry_until_success": conf.Switch.RetryUntilSuccess,
+				"outputs":             outSlice,
 			}, nil
 		},
 	}
@@ -137,13 +150,15 @@ service shut down. Messages that do not match any outputs will be dropped.`,
 
 // SwitchConfig contains configuration fields for the Switch output type.
 type SwitchConfig struct {
-	Outputs []SwitchConfigOutput `json:"outputs" yaml:"outputs"`
+	RetryUntilSuccess bool                 `json:"retry_until_success" yaml:"retry_until_success"`
+	Outputs           []SwitchConfigOutput `json:"outputs" yaml:"outputs"`
 }
 
 // NewSwitchConfig creates a new SwitchConfig with default values.
 func NewSwitchConfig() SwitchConfig {
 	return SwitchConfig{
-		Outputs: []SwitchConfigOutput{},
+		RetryUntilSuccess: true,
+		Outputs:           []SwitchConfigOutput{},
 	}
 }
 
@@ -151,6 +166,7 @@ func NewSwitchConfig() SwitchConfig {
 type SwitchConfigOutput struct {
 	Condition   condition.Config `json:"condition" yaml:"condition"`
 	Fallthrough bool             `json:"fallthrough" yaml:"fallthrough"`
+	Default     bool             `json:"default" yaml:"default"`
 	Output      Config           `json:"output" yaml:"output"`
 }
 
@@ -163,6 +179,7 @@ func NewSwitchConfigOutput() SwitchConfigOutput {
 	return SwitchConfigOutput{
 		Condition:   cond,
 		Fallthrough: false,
+		Default:     false,
 		Output:      NewConfig(),
 	}
 }
@@ -207,7 +224,8 @@ type Switch struct {
 	logger log.Modular
 	stats  metrics.Type
 
-	throt *throttle.Type
+	throt             *throttle.Type
+	retryUntilSuccess bool
 
 	transactions <-chan types.Transaction
 
@@ -217,6 +235,7 @@ type Switch struct {
 	outputs      []types.Output
 	conditions   []types.Condition
 	fallthroughs []bool
+	defaults     []bool
 
 	closedChan chan struct{}
 	closeChan  chan struct{}
@@ -236,15 +255,17 @@ func NewSwitch(
 	}
 
 	o := &Switch{
-		running:      1,
-		stats:        stats,
-		logger:       logger,
-		transactions: nil,
-		outputs:      make([]types.Output, lOutputs),
-		conditions:   make([]types.Condition, lOutputs),
-		fallthroughs: make([]bool, lOutputs),
-		closedChan:   make(chan struct{}),
-		closeChan:    make(chan struct{}),
+		running:           1,
+		stats:             stats,
+		logger:            logger,
+		retryUntilSuccess: conf.Switch.RetryUntilSuccess,
+		transactions:      nil,
+		outputs:           make([]types.Output, lOutputs),
+		conditions:        make([]types.Condition, lOutputs),
+		fallthroughs:      make([]bool, lOutputs),
+		defaults:          make([]bool, lOutputs),
+		closedChan:        make(chan struct{}),
+		closeChan:         make(chan struct{}),
 	}
 
 	var err error
@@ -265,6 +286,7 @@ func NewSwitch(
 			return nil, err
 		}
 		o.fallthroughs[i] = oConf.Fallthrough
+		o.defaults[i] = oConf.Default
 	}
 
 	o.throt = throttle.New(throttle.OptCloseChan(o.closeChan))
@@ -347,6 +369,9 @@ func (o *Switch) loop() {
 
 		var outputTargets []int
 		for i, oCond := range o.conditions {
+			if o.defaults[i] {
+				continue
+			}
 			if oCond.Check(ts.Payload) {
 				outputTargets = append(outputTargets, i)
 				if !o.fallthroughs[i] {
@@ -354,6 +379,13 @@ func (o *Switch) loop() {
 				}
 			}
 		}
+		if len(outputTargets) == 0 {
+			for i, isDefault := range o.defaults {
+				if isDefault {
+					outputTargets = append(outputTargets, i)
+				}
+			}
+		}
 		if len(outputTargets) == 0 {
 			select {
 			case ts.ResponseChan <- response.NewAck():
@@ -364,6 +396,7 @@ func (o *Switch) loop() {
 			continue
 		}
 
+		var resErr error
 		for len(outputTargets) > 0 {
 			for _, i := range outputTargets {
 				msgCopy := ts.Payload.Copy()
@@ -378,9 +411,13 @@ func (o *Switch) loop() {
 				select {
 				case res := <-o.outputResChans[i]:
 					if res.Error() != nil {
-						newTargets = append(newTargets, i)
 						o.logger.Errorf("Failed to dispatch switch message: %v\n", res.Error())
 						mOutputErr.Incr(1)
+						if !o.retryUntilSuccess {
+							resErr = res.Error()
+							continue
+						}
+						newTargets = append(newTargets, i)
 						if !o.throt.Retry() {
 							return
 						}
@@ -394,8 +431,12 @@ func (o *Switch) loop() {
 			}
 			outputTargets = newTargets
 		}
+		var res types.Response = response.NewAck()
+		if resErr != nil {
+			res = response.NewError(resErr)
+		}
 		select {
-		case ts.ResponseChan <- response.NewAck():
+		case ts.ResponseChan <- res:
 		case <-o.closeChan:
 			return
 		}
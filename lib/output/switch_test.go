@@ -323,6 +323,102 @@ func TestSwitchNoMatch(t *testing.T) {
 	}
 }
 
+func TestSwitchDefault(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+
+	conf := NewConfig()
+	for i := 0; i < len(mockOutputs); i++ {
+		conf.Switch.Outputs = append(conf.Switch.Outputs, NewSwitchConfigOutput())
+	}
+
+	// The default output is placed first to show that its condition and
+	// position are ignored.
+	conf.Switch.Outputs[0].Default = true
+
+	fooConfig := condition.NewConfig()
+	fooConfig.Type = condition.TypeJMESPath
+	fooConfig.JMESPath.Query = "foo == 'bar'"
+	conf.Switch.Outputs[1].Condition = fooConfig
+	conf.Switch.Outputs[1].Fallthrough = true
+
+	barConfig := condition.NewConfig()
+	barConfig.Type = condition.TypeJMESPath
+	barConfig.JMESPath.Query = "foo == 'baz'"
+	conf.Switch.Outputs[2].Condition = barConfig
+
+	s, err := newSwitch(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		content string
+		output  int
+	}{
+		{content: `{"foo":"bar"}`, output: 1},
+		{content: `{"foo":"qux"}`, output: 0},
+		{content: `{"foo":"baz"}`, output: 2},
+	}
+
+	for _, test := range tests {
+		msg := message.New([][]byte{[]byte(test.content)})
+		select {
+		case readChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for output send")
+		}
+
+		var ts types.Transaction
+		select {
+		case ts = <-mockOutputs[0].TChan:
+			if test.output != 0 {
+				t.Errorf("Message %v routed to default output", test.content)
+			}
+		case ts = <-mockOutputs[1].TChan:
+			if test.output != 1 {
+				t.Errorf("Message %v routed to output 1", test.content)
+			}
+		case ts = <-mockOutputs[2].TChan:
+			if test.output != 2 {
+				t.Errorf("Message %v routed to output 2", test.content)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for output propagate")
+		}
+		if act := string(ts.Payload.Get(0).Get()); test.content != act {
+			t.Errorf("Wrong content: %v != %v", act, test.content)
+		}
+
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to output")
+		}
+
+		select {
+		case res := <-resChan:
+			if err := res.Error(); err != nil {
+				t.Error(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to input")
+		}
+	}
+
+	s.CloseAsync()
+
+	if err := s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestSwitchWithConditionsNoFallthrough(t *testing.T) {
 	nMsgs := 100
 
@@ -541,6 +637,80 @@ func TestSwitchAtLeastOnce(t *testing.T) {
 	}
 }
 
+func TestSwitchRejectNoRetry(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}}
+
+	conf := NewConfig()
+	conf.Switch.RetryUntilSuccess = false
+
+	mockConf := NewSwitchConfigOutput()
+	mockConf.Condition.Type = condition.TypeText
+	mockConf.Condition.Text.Operator = "equals"
+	mockConf.Condition.Text.Arg = "bar"
+
+	rejectConf := NewSwitchConfigOutput()
+	rejectConf.Output.Type = TypeReject
+	rejectConf.Output.Reject = "rejected ${!content}"
+
+	conf.Switch.Outputs = append(conf.Switch.Outputs, mockConf, rejectConf)
+
+	s, err := newSwitch(conf, mockOutputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = s.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(content string) {
+		t.Helper()
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for output send")
+		}
+	}
+	expectRes := func(expErr string) {
+		t.Helper()
+		select {
+		case res := <-resChan:
+			var actErr string
+			if res.Error() != nil {
+				actErr = res.Error().Error()
+			}
+			if actErr != expErr {
+				t.Errorf("Wrong response error: %v != %v", actErr, expErr)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for response")
+		}
+	}
+
+	send("foo")
+	expectRes("rejected foo")
+
+	send("bar")
+	select {
+	case ts := <-mockOutputs[0].TChan:
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to output")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for mock output")
+	}
+	expectRes("")
+
+	s.CloseAsync()
+	if err = s.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestSwitchShutDownFromErrorResponse(t *testing.T) {
 	mockOutputs := []*MockOutputType{{}, {}}
 
@@ -33,7 +33,29 @@ func init() {
 	Constructors[TypeWebsocket] = TypeSpec{
 		constructor: NewWebsocket,
 		description: `
-Sends messages to an HTTP server via a websocket connection.`,
+Sends messages over websocket connections, either by dialling a remote server
+or by hosting an endpoint that messages are broadcast from.
+
+### Client Mode
+
+With ` + "`mode`" + ` set to ` + "`client`" + ` messages are sent to the
+websocket server at ` + "`url`" + `.
+
+### Server Mode
+
+With ` + "`mode`" + ` set to ` + "`server`" + ` each message is broadcast to all
+clients connected to the endpoint ` + "`server.path`" + `. The endpoint is
+registered on the instance wide HTTP server unless ` + "`server.address`" + `
+is set, in which case a new server is bound to that address.
+
+Each client has a buffer of ` + "`server.client_buffer_size`" + ` messages.
+Clients that fall behind far enough to fill their buffer, or that fail to accept
+a message within ` + "`server.write_timeout`" + `, are evicted, which is counted
+under the metric ` + "`server.client.evicted`" + `.
+
+While no clients are connected messages are not dropped, instead the output
+applies back pressure until a client connects. Messages are not persisted for
+clients that connect later, and delivery to clients is not acknowledged.`,
 	}
 }
 
@@ -41,7 +63,7 @@ Sends messages to an HTTP server via a websocket connection.`,
 
 // NewWebsocket creates a new Websocket output type.
 func NewWebsocket(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	w, err := writer.NewWebsocket(conf.Websocket, log, stats)
+	w, err := writer.NewWebsocket(conf.Websocket, mgr, log, stats)
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +35,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
 )
 
 //------------------------------------------------------------------------------
@@ -45,6 +46,10 @@ type AmazonS3Config struct {
 	Bucket      string `json:"bucket" yaml:"bucket"`
 	Path        string `json:"path" yaml:"path"`
 	ContentType string `json:"content_type" yaml:"content_type"`
+	BatchCodec  string `json:"batch_codec" yaml:"batch_codec"`
+	Compression string `json:"compression" yaml:"compression"`
+	PartSize    int64  `json:"part_size" yaml:"part_size"`
+	Concurrency int    `json:"concurrency" yaml:"concurrency"`
 	Timeout     string `json:"timeout" yaml:"timeout"`
 }
 
@@ -55,6 +60,10 @@ func NewAmazonS3Config() AmazonS3Config {
 		Bucket:      "",
 		Path:        "${!count:files}-${!timestamp_unix_nano}.txt",
 		ContentType: "application/octet-stream",
+		BatchCodec:  "none",
+		Compression: "none",
+		PartSize:    s3manager.DefaultUploadPartSize,
+		Concurrency: s3manager.DefaultUploadConcurrency,
 		Timeout:     "5s",
 	}
 }
@@ -69,7 +78,7 @@ type AmazonS3 struct {
 	path *text.InterpolatedString
 
 	session  *session.Session
-	uploader *s3manager.Uploader
+	uploader s3manageriface.UploaderAPI
 	timeout  time.Duration
 
 	log   log.Modular
@@ -89,6 +98,12 @@ func NewAmazonS3(
 			return nil, fmt.Errorf("failed to parse timeout period string: %v", err)
 		}
 	}
+	if err := checkObjectCodec(conf.BatchCodec, conf.Compression); err != nil {
+		return nil, err
+	}
+	if conf.PartSize < s3manager.MinUploadPartSize {
+		return nil, fmt.Errorf("part size must be at least %v bytes", s3manager.MinUploadPartSize)
+	}
 	return &AmazonS3{
 		conf:    conf,
 		log:     log,
@@ -110,12 +125,27 @@ func (a *AmazonS3) Connect() error {
 	}
 
 	a.session = sess
-	a.uploader = s3manager.NewUploader(sess)
+	a.uploader = s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		u.PartSize = a.conf.PartSize
+		if a.conf.Concurrency > 0 {
+			u.Concurrency = a.conf.Concurrency
+		}
+	})
 
 	a.log.Infof("Uploading message parts as objects to Amazon S3 bucket: %v\n", a.conf.Bucket)
 	return nil
 }
 
+func (a *AmazonS3) upload(ctx context.Context, key string, body []byte) error {
+	_, err := a.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      &a.conf.Bucket,
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: &a.conf.ContentType,
+	})
+	return err
+}
+
 // Write attempts to write message contents to a target S3 bucket as files.
 func (a *AmazonS3) Write(msg types.Message) error {
 	if a.session == nil {
@@ -127,16 +157,25 @@ func (a *AmazonS3) Write(msg types.Message) error {
 	)
 	defer cancel()
 
+	if a.conf.BatchCodec != "none" {
+		parts := make([]types.Part, 0, msg.Len())
+		msg.Iter(func(i int, p types.Part) error {
+			parts = append(parts, p)
+			return nil
+		})
+		body, err := encodeObject(a.conf.BatchCodec, a.conf.Compression, parts)
+		if err != nil {
+			return err
+		}
+		return a.upload(ctx, a.path.Get(message.Lock(msg, 0)), body)
+	}
+
 	return msg.Iter(func(i int, p types.Part) error {
-		if _, err := a.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
-			Bucket:      &a.conf.Bucket,
-			Key:         aws.String(a.path.Get(message.Lock(msg, i))),
-			Body:        bytes.NewReader(p.Get()),
-			ContentType: &a.conf.ContentType,
-		}); err != nil {
+		body, err := encodeObject(a.conf.BatchCodec, a.conf.Compression, []types.Part{p})
+		if err != nil {
 			return err
 		}
-		return nil
+		return a.upload(ctx, a.path.Get(message.Lock(msg, i)), body)
 	})
 }
 
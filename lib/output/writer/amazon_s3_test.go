@@ -0,0 +1,180 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+)
+
+//------------------------------------------------------------------------------
+
+type mockS3Uploader struct {
+	s3manageriface.UploaderAPI
+	uploads map[string][]byte
+}
+
+func (m *mockS3Uploader) UploadWithContext(
+	ctx aws.Context, input *s3manager.UploadInput, opts ...func(*s3manager.Uploader),
+) (*s3manager.UploadOutput, error) {
+	b, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.uploads[*input.Bucket+"/"+*input.Key] = b
+	return &s3manager.UploadOutput{}, nil
+}
+
+func testS3Writer(t *testing.T, conf AmazonS3Config) (*AmazonS3, *mockS3Uploader) {
+	t.Helper()
+
+	conf.Bucket = "bucket"
+	a, err := NewAmazonS3(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader := &mockS3Uploader{uploads: map[string][]byte{}}
+	a.session = &session.Session{}
+	a.uploader = uploader
+	return a, uploader
+}
+
+func TestAmazonS3PerPart(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "${!metadata:dir}/${!content}.txt"
+
+	a, uploader := testS3Writer(t, conf)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("dir", "a")
+	msg.Get(1).Metadata().Set("dir", "b")
+	if err := a.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"bucket/a/foo.txt": "foo",
+		"bucket/b/bar.txt": "bar",
+	}
+	if len(uploader.uploads) != len(exp) {
+		t.Errorf("Wrong count of uploads: %v", len(uploader.uploads))
+	}
+	for k, v := range exp {
+		if act := string(uploader.uploads[k]); act != v {
+			t.Errorf("Wrong contents for '%v': %v != %v", k, act, v)
+		}
+	}
+}
+
+func TestAmazonS3LinesGzip(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "${!metadata:dir}.jsonl.gz"
+	conf.BatchCodec = "lines"
+	conf.Compression = "gzip"
+
+	a, uploader := testS3Writer(t, conf)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	msg.Get(0).Metadata().Set("dir", "first")
+	if err := a.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 1, len(uploader.uploads); exp != act {
+		t.Fatalf("Wrong count of uploads: %v != %v", act, exp)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(uploader.uploads["bucket/first.jsonl.gz"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar\nbaz", string(contents); exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonS3Tar(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.Path = "foo.tar"
+	conf.BatchCodec = "tar"
+
+	a, uploader := testS3Writer(t, conf)
+
+	if err := a.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(bytes.NewReader(uploader.uploads["bucket/foo.tar"]))
+	exp := []string{"foo", "bar"}
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			if i != len(exp) {
+				t.Errorf("Wrong count of entries: %v != %v", i, len(exp))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(exp) {
+			t.Fatalf("Unexpected entry: %v", hdr.Name)
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(contents); act != exp[i] {
+			t.Errorf("Wrong contents of entry %v: %v != %v", hdr.Name, act, exp[i])
+		}
+	}
+}
+
+func TestAmazonS3BadConfig(t *testing.T) {
+	conf := NewAmazonS3Config()
+	conf.BatchCodec = "zip"
+	if _, err := NewAmazonS3(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad codec")
+	}
+
+	conf = NewAmazonS3Config()
+	conf.PartSize = 1024
+	if _, err := NewAmazonS3(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from small part size")
+	}
+}
+
+//------------------------------------------------------------------------------
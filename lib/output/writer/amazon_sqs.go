@@ -26,13 +26,16 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	sess "github.com/Jeffail/benthos/lib/util/aws/session"
 	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/Jeffail/benthos/lib/util/text"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/cenkalti/backoff"
 )
 
@@ -46,9 +49,11 @@ const (
 
 // AmazonSQSConfig contains configuration fields for the output AmazonSQS type.
 type AmazonSQSConfig struct {
-	sessionConfig  `json:",inline" yaml:",inline"`
-	URL            string `json:"url" yaml:"url"`
-	retries.Config `json:",inline" yaml:",inline"`
+	sessionConfig          `json:",inline" yaml:",inline"`
+	URL                    string `json:"url" yaml:"url"`
+	MessageGroupID         string `json:"message_group_id" yaml:"message_group_id"`
+	MessageDeduplicationID string `json:"message_deduplication_id" yaml:"message_deduplication_id"`
+	retries.Config         `json:",inline" yaml:",inline"`
 }
 
 // NewAmazonSQSConfig creates a new Config with default values.
@@ -61,8 +66,10 @@ func NewAmazonSQSConfig() AmazonSQSConfig {
 		sessionConfig: sessionConfig{
 			Config: sess.NewConfig(),
 		},
-		URL:    "",
-		Config: rConf,
+		URL:                    "",
+		MessageGroupID:         "",
+		MessageDeduplicationID: "",
+		Config:                 rConf,
 	}
 }
 
@@ -73,9 +80,14 @@ func NewAmazonSQSConfig() AmazonSQSConfig {
 type AmazonSQS struct {
 	conf AmazonSQSConfig
 
+	groupID *text.InterpolatedString
+	dedupID *text.InterpolatedString
+
 	backoff backoff.BackOff
 	session *session.Session
-	sqs     *sqs.SQS
+	sqs     sqsiface.SQSAPI
+
+	mEntryErr metrics.StatCounter
 
 	log   log.Modular
 	stats metrics.Type
@@ -88,9 +100,16 @@ func NewAmazonSQS(
 	stats metrics.Type,
 ) (*AmazonSQS, error) {
 	s := &AmazonSQS{
-		conf:  conf,
-		log:   log,
-		stats: stats,
+		conf:      conf,
+		log:       log,
+		stats:     stats,
+		mEntryErr: stats.GetCounter("error.entry"),
+	}
+	if len(conf.MessageGroupID) > 0 {
+		s.groupID = text.NewInterpolatedString(conf.MessageGroupID)
+	}
+	if len(conf.MessageDeduplicationID) > 0 {
+		s.dedupID = text.NewInterpolatedString(conf.MessageDeduplicationID)
 	}
 
 	var err error
@@ -120,83 +139,85 @@ func (a *AmazonSQS) Connect() error {
 
 // Write attempts to write message contents to a target SQS.
 func (a *AmazonSQS) Write(msg types.Message) error {
-	if a.session == nil {
+	if a.sqs == nil {
 		return types.ErrNotConnected
 	}
 
-	entries := []*sqs.SendMessageBatchRequestEntry{}
+	entries := make([]*sqs.SendMessageBatchRequestEntry, 0, msg.Len())
 	msg.Iter(func(i int, p types.Part) error {
-		entries = append(entries, &sqs.SendMessageBatchRequestEntry{
+		entry := &sqs.SendMessageBatchRequestEntry{
 			Id:          aws.String(strconv.FormatInt(int64(i), 10)),
 			MessageBody: aws.String(string(p.Get())),
-		})
+		}
+		if a.groupID != nil {
+			entry.MessageGroupId = aws.String(a.groupID.Get(message.Lock(msg, i)))
+		}
+		if a.dedupID != nil {
+			entry.MessageDeduplicationId = aws.String(a.dedupID.Get(message.Lock(msg, i)))
+		}
+		entries = append(entries, entry)
 		return nil
 	})
 
-	input := &sqs.SendMessageBatchInput{
-		QueueUrl: aws.String(a.conf.URL),
-		Entries:  entries,
-	}
-
-	// trim input input length to max sqs batch size
-	if len(entries) > sqsMaxRecordsCount {
-		input.Entries, entries = entries[:sqsMaxRecordsCount], entries[sqsMaxRecordsCount:]
-	} else {
-		entries = nil
+	err := a.sendBatches(entries)
+	if err == nil {
+		a.backoff.Reset()
 	}
+	return err
+}
 
+// sendBatches sends entries with SendMessageBatch requests of up to ten
+// entries, retrying any entries that failed due to a server fault. Entries
+// that failed due to a sender fault are not retried and result in an error.
+func (a *AmazonSQS) sendBatches(entries []*sqs.SendMessageBatchRequestEntry) error {
 	var err error
-	for len(input.Entries) > 0 {
-		wait := a.backoff.NextBackOff()
+	for len(entries) > 0 {
+		batch := entries
+		if len(batch) > sqsMaxRecordsCount {
+			batch = batch[:sqsMaxRecordsCount]
+		}
 
 		var batchResult *sqs.SendMessageBatchOutput
-		if batchResult, err = a.sqs.SendMessageBatch(input); err != nil {
+		batchResult, err = a.sqs.SendMessageBatch(&sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(a.conf.URL),
+			Entries:  batch,
+		})
+		if err != nil {
 			a.log.Warnf("SQS error: %v\n", err)
-			// bail if a message is too large or all retry attempts expired
-			if wait == backoff.Stop {
-				return err
+		} else if len(batchResult.Failed) > 0 {
+			byID := make(map[string]*sqs.SendMessageBatchRequestEntry, len(batch))
+			for _, entry := range batch {
+				byID[*entry.Id] = entry
 			}
-			continue
-		}
-
-		if unproc := batchResult.Failed; len(unproc) > 0 {
-			input.Entries = []*sqs.SendMessageBatchRequestEntry{}
-			for _, v := range unproc {
-				if *v.SenderFault {
-					err = fmt.Errorf("record failed with code: %v", *v.Code)
-					a.log.Errorf("SQS record error: %v\n", err)
-					return err
+			failed := make([]*sqs.SendMessageBatchRequestEntry, 0, len(batchResult.Failed))
+			for _, v := range batchResult.Failed {
+				a.mEntryErr.Incr(1)
+				entryErr := fmt.Errorf(
+					"message %v failed with code %v: %v",
+					aws.StringValue(v.Id), aws.StringValue(v.Code), aws.StringValue(v.Message),
+				)
+				if aws.BoolValue(v.SenderFault) {
+					a.log.Errorf("SQS record error: %v\n", entryErr)
+					return entryErr
+				}
+				a.log.Warnf("SQS record error: %v\n", entryErr)
+				if entry, exists := byID[aws.StringValue(v.Id)]; exists {
+					failed = append(failed, entry)
 				}
-				input.Entries = append(input.Entries, &sqs.SendMessageBatchRequestEntry{
-					Id:          v.Id,
-					MessageBody: v.Message,
-				})
 			}
-			err = fmt.Errorf("failed to send %v messages", len(unproc))
+			entries = append(failed, entries[len(batch):]...)
+			err = fmt.Errorf("failed to send %v messages", len(failed))
 		} else {
-			input.Entries = nil
+			entries = entries[len(batch):]
 		}
 
 		if err != nil {
+			wait := a.backoff.NextBackOff()
 			if wait == backoff.Stop {
 				break
 			}
-			time.After(wait)
+			<-time.After(wait)
 		}
-
-		// add remaining records to batch
-		l := len(input.Entries)
-		if n := len(entries); n > 0 && l < sqsMaxRecordsCount {
-			if remaining := sqsMaxRecordsCount - l; remaining < n {
-				input.Entries, entries = append(input.Entries, entries[:remaining]...), entries[remaining:]
-			} else {
-				input.Entries, entries = append(input.Entries, entries...), nil
-			}
-		}
-	}
-
-	if err == nil {
-		a.backoff.Reset()
 	}
 	return err
 }
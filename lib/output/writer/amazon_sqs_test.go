@@ -0,0 +1,203 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+//------------------------------------------------------------------------------
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+	fn func(*sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error)
+}
+
+func (m *mockSQS) SendMessageBatch(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+	return m.fn(input)
+}
+
+func testSQSConf() AmazonSQSConfig {
+	conf := NewAmazonSQSConfig()
+	conf.URL = "http://foo"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	return conf
+}
+
+func testSQSMessage(n int) *message.Type {
+	parts := make([][]byte, n)
+	for i := range parts {
+		parts[i] = []byte(fmt.Sprintf(`{"id":%v}`, i))
+	}
+	return message.New(parts)
+}
+
+func TestAmazonSQSBatching(t *testing.T) {
+	s, err := NewAmazonSQS(testSQSConf(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sizes []int
+	var bodies []string
+	s.sqs = &mockSQS{
+		fn: func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+			sizes = append(sizes, len(input.Entries))
+			for _, e := range input.Entries {
+				bodies = append(bodies, *e.MessageBody)
+			}
+			return &sqs.SendMessageBatchOutput{}, nil
+		},
+	}
+
+	if err = s.Write(testSQSMessage(23)); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []int{10, 10, 3}, sizes; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong batch sizes: %v != %v", act, exp)
+	}
+	if exp, act := 23, len(bodies); exp != act {
+		t.Fatalf("Wrong count of bodies: %v != %v", act, exp)
+	}
+	for i, b := range bodies {
+		if exp := fmt.Sprintf(`{"id":%v}`, i); exp != b {
+			t.Errorf("Wrong body at %v: %v != %v", i, b, exp)
+		}
+	}
+}
+
+func TestAmazonSQSFIFO(t *testing.T) {
+	conf := testSQSConf()
+	conf.MessageGroupID = "${!json_field:id}-group"
+	conf.MessageDeduplicationID = "${!json_field:id}-dedupe"
+
+	s, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var groups, dedupes []string
+	s.sqs = &mockSQS{
+		fn: func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+			for _, e := range input.Entries {
+				groups = append(groups, *e.MessageGroupId)
+				dedupes = append(dedupes, *e.MessageDeduplicationId)
+			}
+			return &sqs.SendMessageBatchOutput{}, nil
+		},
+	}
+
+	if err = s.Write(testSQSMessage(2)); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"0-group", "1-group"}, groups; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong group ids: %v != %v", act, exp)
+	}
+	if exp, act := []string{"0-dedupe", "1-dedupe"}, dedupes; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong deduplication ids: %v != %v", act, exp)
+	}
+}
+
+func TestAmazonSQSRetryFailedEntries(t *testing.T) {
+	s, err := NewAmazonSQS(testSQSConf(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls [][]string
+	s.sqs = &mockSQS{
+		fn: func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+			var ids []string
+			for _, e := range input.Entries {
+				ids = append(ids, *e.Id)
+			}
+			calls = append(calls, ids)
+			if len(calls) > 1 {
+				return &sqs.SendMessageBatchOutput{}, nil
+			}
+			return &sqs.SendMessageBatchOutput{
+				Failed: []*sqs.BatchResultErrorEntry{
+					{
+						Id:          aws.String("3"),
+						Code:        aws.String("InternalError"),
+						SenderFault: aws.Bool(false),
+					},
+				},
+			}, nil
+		},
+	}
+
+	if err = s.Write(testSQSMessage(12)); err != nil {
+		t.Fatal(err)
+	}
+	exp := [][]string{
+		{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		{"3", "10", "11"},
+	}
+	if !reflect.DeepEqual(exp, calls) {
+		t.Errorf("Wrong calls: %v != %v", calls, exp)
+	}
+}
+
+func TestAmazonSQSSenderFault(t *testing.T) {
+	s, err := NewAmazonSQS(testSQSConf(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	s.sqs = &mockSQS{
+		fn: func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+			calls++
+			return &sqs.SendMessageBatchOutput{
+				Failed: []*sqs.BatchResultErrorEntry{
+					{
+						Id:          aws.String("1"),
+						Code:        aws.String("InvalidMessageContents"),
+						Message:     aws.String("bad message"),
+						SenderFault: aws.Bool(true),
+					},
+				},
+			}, nil
+		},
+	}
+
+	err = s.Write(testSQSMessage(2))
+	if err == nil || !strings.Contains(err.Error(), "InvalidMessageContents: bad message") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if exp, act := 1, calls; exp != act {
+		t.Errorf("Wrong count of calls: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
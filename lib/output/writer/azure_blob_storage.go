@@ -0,0 +1,175 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// AzureBlobStorageConfig contains configuration fields for the
+// AzureBlobStorage output type.
+type AzureBlobStorageConfig struct {
+	storage.Config `json:",inline" yaml:",inline"`
+	Container      string `json:"container" yaml:"container"`
+	Path           string `json:"path" yaml:"path"`
+	BlobType       string `json:"blob_type" yaml:"blob_type"`
+	ContentType    string `json:"content_type" yaml:"content_type"`
+}
+
+// NewAzureBlobStorageConfig creates a new AzureBlobStorageConfig with default
+// values.
+func NewAzureBlobStorageConfig() AzureBlobStorageConfig {
+	return AzureBlobStorageConfig{
+		Config:      storage.NewConfig(),
+		Container:   "",
+		Path:        "${!count:files}-${!timestamp_unix_nano}.txt",
+		BlobType:    "block",
+		ContentType: "application/octet-stream",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// blobWriter is the subset of blob storage operations used by the output.
+type blobWriter interface {
+	PutBlob(container, blob string, data []byte, contentType string) error
+	CreateAppendBlob(container, blob, contentType string) error
+	AppendBlock(container, blob string, data []byte) error
+}
+
+// AzureBlobStorage is a benthos writer.Type implementation that writes messages
+// as blobs to an Azure Blob Storage container.
+type AzureBlobStorage struct {
+	conf AzureBlobStorageConfig
+
+	container *text.InterpolatedString
+	path      *text.InterpolatedString
+
+	clientMut sync.RWMutex
+	client    blobWriter
+	newClient func() (blobWriter, error)
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewAzureBlobStorage creates a new Azure Blob Storage writer.Type.
+func NewAzureBlobStorage(
+	conf AzureBlobStorageConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*AzureBlobStorage, error) {
+	if len(conf.Container) == 0 {
+		return nil, errors.New("a container must be specified")
+	}
+	switch conf.BlobType {
+	case "block", "append":
+	default:
+		return nil, fmt.Errorf("blob type not recognised: %v", conf.BlobType)
+	}
+	a := &AzureBlobStorage{
+		conf:      conf,
+		container: text.NewInterpolatedString(conf.Container),
+		path:      text.NewInterpolatedString(conf.Path),
+		log:       log,
+		stats:     stats,
+	}
+	a.newClient = func() (blobWriter, error) {
+		return conf.NewClient()
+	}
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to create a client for the storage account.
+func (a *AzureBlobStorage) Connect() error {
+	a.clientMut.Lock()
+	defer a.clientMut.Unlock()
+	if a.client != nil {
+		return nil
+	}
+
+	client, err := a.newClient()
+	if err != nil {
+		return err
+	}
+
+	a.log.Infof("Uploading message parts as %v blobs to Azure Blob Storage account: %v\n", a.conf.BlobType, a.conf.Account)
+	a.client = client
+	return nil
+}
+
+// appendBlob appends data to an append blob, creating it if it does not yet
+// exist.
+func (a *AzureBlobStorage) appendBlob(client blobWriter, container, blob string, data []byte) error {
+	err := client.AppendBlock(container, blob, data)
+	if err != storage.ErrBlobNotFound {
+		return err
+	}
+	if err = client.CreateAppendBlob(container, blob, a.conf.ContentType); err != nil {
+		return err
+	}
+	return client.AppendBlock(container, blob, data)
+}
+
+// Write attempts to write message contents to the target container as blobs.
+func (a *AzureBlobStorage) Write(msg types.Message) error {
+	a.clientMut.RLock()
+	client := a.client
+	a.clientMut.RUnlock()
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	return msg.Iter(func(i int, p types.Part) error {
+		lMsg := message.Lock(msg, i)
+		container, blob := a.container.Get(lMsg), a.path.Get(lMsg)
+		if a.conf.BlobType == "append" {
+			return a.appendBlob(client, container, blob, p.Get())
+		}
+		return client.PutBlob(container, blob, p.Get(), a.conf.ContentType)
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *AzureBlobStorage) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (a *AzureBlobStorage) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,150 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+)
+
+//------------------------------------------------------------------------------
+
+type mockBlobWriter struct {
+	blobs     map[string][]byte
+	blobTypes map[string]string
+}
+
+func (m *mockBlobWriter) PutBlob(container, blob string, data []byte, contentType string) error {
+	m.blobs[container+"/"+blob] = data
+	m.blobTypes[container+"/"+blob] = "block"
+	return nil
+}
+
+func (m *mockBlobWriter) CreateAppendBlob(container, blob, contentType string) error {
+	if _, exists := m.blobs[container+"/"+blob]; !exists {
+		m.blobs[container+"/"+blob] = []byte{}
+		m.blobTypes[container+"/"+blob] = "append"
+	}
+	return nil
+}
+
+func (m *mockBlobWriter) AppendBlock(container, blob string, data []byte) error {
+	existing, exists := m.blobs[container+"/"+blob]
+	if !exists {
+		return storage.ErrBlobNotFound
+	}
+	m.blobs[container+"/"+blob] = append(existing, data...)
+	return nil
+}
+
+func testAzureBlobWriter(t *testing.T, conf AzureBlobStorageConfig) (*AzureBlobStorage, *mockBlobWriter) {
+	t.Helper()
+
+	a, err := NewAzureBlobStorage(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &mockBlobWriter{
+		blobs:     map[string][]byte{},
+		blobTypes: map[string]string{},
+	}
+	a.newClient = func() (blobWriter, error) {
+		return client, nil
+	}
+	if err = a.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return a, client
+}
+
+func TestAzureBlobStorageBlock(t *testing.T) {
+	conf := NewAzureBlobStorageConfig()
+	conf.Container = "${!metadata:container}"
+	conf.Path = "${!content}.txt"
+
+	a, client := testAzureBlobWriter(t, conf)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("container", "a")
+	msg.Get(1).Metadata().Set("container", "b")
+	if err := a.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"a/foo.txt": "foo",
+		"b/bar.txt": "bar",
+	}
+	if len(client.blobs) != len(exp) {
+		t.Errorf("Wrong count of blobs: %v", len(client.blobs))
+	}
+	for k, v := range exp {
+		if act := string(client.blobs[k]); act != v {
+			t.Errorf("Wrong contents for '%v': %v != %v", k, act, v)
+		}
+		if act := client.blobTypes[k]; act != "block" {
+			t.Errorf("Wrong blob type for '%v': %v", k, act)
+		}
+	}
+}
+
+func TestAzureBlobStorageAppend(t *testing.T) {
+	conf := NewAzureBlobStorageConfig()
+	conf.Container = "foo"
+	conf.Path = "${!metadata:log}.log"
+	conf.BlobType = "append"
+
+	a, client := testAzureBlobWriter(t, conf)
+
+	for _, content := range []string{"first\n", "second\n"} {
+		msg := message.New([][]byte{[]byte(content)})
+		msg.Get(0).Metadata().Set("log", "bar")
+		if err := a.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if exp, act := "first\nsecond\n", string(client.blobs["foo/bar.log"]); exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+	if exp, act := "append", client.blobTypes["foo/bar.log"]; exp != act {
+		t.Errorf("Wrong blob type: %v != %v", act, exp)
+	}
+}
+
+func TestAzureBlobStorageBadConfig(t *testing.T) {
+	conf := NewAzureBlobStorageConfig()
+	if _, err := NewAzureBlobStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing container")
+	}
+
+	conf.Container = "foo"
+	conf.BlobType = "page"
+	if _, err := NewAzureBlobStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad blob type")
+	}
+}
+
+//------------------------------------------------------------------------------
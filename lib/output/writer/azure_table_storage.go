@@ -0,0 +1,257 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// AzureTableStorageConfig contains configuration fields for the
+// AzureTableStorage output type.
+type AzureTableStorageConfig struct {
+	storage.Config `json:",inline" yaml:",inline"`
+	TableName      string            `json:"table_name" yaml:"table_name"`
+	PartitionKey   string            `json:"partition_key" yaml:"partition_key"`
+	RowKey         string            `json:"row_key" yaml:"row_key"`
+	Properties     map[string]string `json:"properties" yaml:"properties"`
+	InsertType     string            `json:"insert_type" yaml:"insert_type"`
+}
+
+// NewAzureTableStorageConfig creates a new AzureTableStorageConfig with default
+// values.
+func NewAzureTableStorageConfig() AzureTableStorageConfig {
+	return AzureTableStorageConfig{
+		Config:       storage.NewConfig(),
+		TableName:    "",
+		PartitionKey: "",
+		RowKey:       "",
+		Properties:   map[string]string{},
+		InsertType:   storage.TableInsertOrReplace,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// tableWriter is the subset of table storage operations used by the output.
+type tableWriter interface {
+	WriteTableBatch(table, operation string, entities []storage.TableEntity) error
+}
+
+// AzureTableStorage is a benthos writer.Type implementation that writes
+// messages as entities to an Azure Table Storage or Cosmos DB Table API table.
+type AzureTableStorage struct {
+	conf AzureTableStorageConfig
+
+	table        *text.InterpolatedString
+	partitionKey *text.InterpolatedString
+	rowKey       *text.InterpolatedString
+	properties   map[string]*text.InterpolatedString
+
+	clientMut sync.RWMutex
+	client    tableWriter
+	newClient func() (tableWriter, error)
+
+	log   log.Modular
+	stats metrics.Type
+
+	mBatchSent metrics.StatCounter
+}
+
+// NewAzureTableStorage creates a new Azure Table Storage writer.Type.
+func NewAzureTableStorage(
+	conf AzureTableStorageConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*AzureTableStorage, error) {
+	if len(conf.TableName) == 0 {
+		return nil, errors.New("a table name must be specified")
+	}
+	if len(conf.RowKey) == 0 {
+		return nil, errors.New("a row key must be specified")
+	}
+	switch conf.InsertType {
+	case storage.TableInsert, storage.TableInsertOrMerge, storage.TableInsertOrReplace:
+	default:
+		return nil, fmt.Errorf("insert type not recognised: %v", conf.InsertType)
+	}
+	a := &AzureTableStorage{
+		conf:         conf,
+		table:        text.NewInterpolatedString(conf.TableName),
+		partitionKey: text.NewInterpolatedString(conf.PartitionKey),
+		rowKey:       text.NewInterpolatedString(conf.RowKey),
+		properties:   map[string]*text.InterpolatedString{},
+		log:          log,
+		stats:        stats,
+		mBatchSent:   stats.GetCounter("batch.sent"),
+	}
+	for k, v := range conf.Properties {
+		a.properties[k] = text.NewInterpolatedString(v)
+	}
+	a.newClient = func() (tableWriter, error) {
+		return conf.NewTableClient()
+	}
+	return a, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to create a client for the storage account.
+func (a *AzureTableStorage) Connect() error {
+	a.clientMut.Lock()
+	defer a.clientMut.Unlock()
+	if a.client != nil {
+		return nil
+	}
+
+	client, err := a.newClient()
+	if err != nil {
+		return err
+	}
+
+	a.log.Infof("Writing message parts as entities to Azure Table Storage account: %v\n", a.conf.Account)
+	a.client = client
+	return nil
+}
+
+// entityProperties returns the properties of an entity, which are either
+// interpolated from the configured properties or, when none are configured,
+// the top level fields of a JSON object message part. Nested values are
+// serialised as JSON strings.
+func (a *AzureTableStorage) entityProperties(msg types.Message, index int) (map[string]interface{}, error) {
+	props := map[string]interface{}{}
+	if len(a.properties) > 0 {
+		lMsg := message.Lock(msg, index)
+		for k, v := range a.properties {
+			props[k] = v.Get(lMsg)
+		}
+		return props, nil
+	}
+
+	jObj, err := msg.Get(index).JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message part as JSON: %v", err)
+	}
+	obj, ok := jObj.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected JSON object, found: %T", jObj)
+	}
+	for k, v := range obj {
+		switch t := v.(type) {
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(t)
+			if err != nil {
+				return nil, err
+			}
+			props[k] = string(b)
+		default:
+			props[k] = t
+		}
+	}
+	return props, nil
+}
+
+// tableBatch is a group of entities that can be written within a single
+// entity group transaction.
+type tableBatch struct {
+	table    string
+	entities []storage.TableEntity
+	rowKeys  map[string]struct{}
+}
+
+// Write attempts to write message contents to the target table as entities.
+// Message parts are grouped into entity group transactions by table and
+// partition key.
+func (a *AzureTableStorage) Write(msg types.Message) error {
+	a.clientMut.RLock()
+	client := a.client
+	a.clientMut.RUnlock()
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	var batches []*tableBatch
+	open := map[string]*tableBatch{}
+	if err := msg.Iter(func(i int, p types.Part) error {
+		lMsg := message.Lock(msg, i)
+		entity := storage.TableEntity{
+			PartitionKey: a.partitionKey.Get(lMsg),
+			RowKey:       a.rowKey.Get(lMsg),
+		}
+		var err error
+		if entity.Properties, err = a.entityProperties(msg, i); err != nil {
+			return err
+		}
+
+		table := a.table.Get(lMsg)
+		groupKey := table + "\x00" + entity.PartitionKey
+		b, exists := open[groupKey]
+		if exists {
+			_, dupe := b.rowKeys[entity.RowKey]
+			exists = !dupe && len(b.entities) < storage.MaxTableBatchSize
+		}
+		if !exists {
+			b = &tableBatch{
+				table:   table,
+				rowKeys: map[string]struct{}{},
+			}
+			open[groupKey] = b
+			batches = append(batches, b)
+		}
+		b.entities = append(b.entities, entity)
+		b.rowKeys[entity.RowKey] = struct{}{}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, b := range batches {
+		if err := client.WriteTableBatch(b.table, a.conf.InsertType, b.entities); err != nil {
+			return err
+		}
+		a.mBatchSent.Incr(1)
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *AzureTableStorage) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (a *AzureTableStorage) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
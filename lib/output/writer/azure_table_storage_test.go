@@ -0,0 +1,258 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/azure/storage"
+)
+
+//------------------------------------------------------------------------------
+
+type mockTableBatch struct {
+	table     string
+	operation string
+	entities  []storage.TableEntity
+}
+
+type mockTableWriter struct {
+	batches []mockTableBatch
+	err     error
+}
+
+func (m *mockTableWriter) WriteTableBatch(table, operation string, entities []storage.TableEntity) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.batches = append(m.batches, mockTableBatch{
+		table:     table,
+		operation: operation,
+		entities:  entities,
+	})
+	return nil
+}
+
+func testAzureTableWriter(t *testing.T, conf AzureTableStorageConfig) (*AzureTableStorage, *mockTableWriter) {
+	t.Helper()
+
+	a, err := NewAzureTableStorage(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &mockTableWriter{}
+	a.newClient = func() (tableWriter, error) {
+		return client, nil
+	}
+	if err = a.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return a, client
+}
+
+func TestAzureTableStorageBadConfig(t *testing.T) {
+	conf := NewAzureTableStorageConfig()
+	if _, err := NewAzureTableStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing table name")
+	}
+
+	conf.TableName = "foo"
+	if _, err := NewAzureTableStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing row key")
+	}
+
+	conf.RowKey = "${!json_field:id}"
+	conf.InsertType = "UPSERT"
+	if _, err := NewAzureTableStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad insert type")
+	}
+}
+
+func TestAzureTableStorageJSONProperties(t *testing.T) {
+	conf := NewAzureTableStorageConfig()
+	conf.TableName = "foo"
+	conf.PartitionKey = "${!json_field:type}"
+	conf.RowKey = "${!json_field:id}"
+
+	a, client := testAzureTableWriter(t, conf)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"1","type":"a","value":10,"tags":["x","y"]}`),
+		[]byte(`{"id":"2","type":"b","value":20}`),
+		[]byte(`{"id":"3","type":"a","nested":{"z":true}}`),
+	})
+	if err := a.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []mockTableBatch{
+		{
+			table:     "foo",
+			operation: storage.TableInsertOrReplace,
+			entities: []storage.TableEntity{
+				{
+					PartitionKey: "a",
+					RowKey:       "1",
+					Properties: map[string]interface{}{
+						"id":    "1",
+						"type":  "a",
+						"value": float64(10),
+						"tags":  `["x","y"]`,
+					},
+				},
+				{
+					PartitionKey: "a",
+					RowKey:       "3",
+					Properties: map[string]interface{}{
+						"id":     "3",
+						"type":   "a",
+						"nested": `{"z":true}`,
+					},
+				},
+			},
+		},
+		{
+			table:     "foo",
+			operation: storage.TableInsertOrReplace,
+			entities: []storage.TableEntity{
+				{
+					PartitionKey: "b",
+					RowKey:       "2",
+					Properties: map[string]interface{}{
+						"id":    "2",
+						"type":  "b",
+						"value": float64(20),
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(exp, client.batches) {
+		t.Errorf("Wrong batches: %+v != %+v", client.batches, exp)
+	}
+}
+
+func TestAzureTableStorageProperties(t *testing.T) {
+	conf := NewAzureTableStorageConfig()
+	conf.TableName = "${!metadata:table}"
+	conf.PartitionKey = "foo"
+	conf.RowKey = "${!metadata:id}"
+	conf.Properties = map[string]string{
+		"content": "${!content}",
+	}
+	conf.InsertType = storage.TableInsert
+
+	a, client := testAzureTableWriter(t, conf)
+
+	msg := message.New([][]byte{
+		[]byte("first"),
+		[]byte("second"),
+		[]byte("third"),
+	})
+	msg.Get(0).Metadata().Set("table", "a").Set("id", "1")
+	msg.Get(1).Metadata().Set("table", "b").Set("id", "1")
+	msg.Get(2).Metadata().Set("table", "a").Set("id", "1")
+	if err := a.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	type tableRow struct {
+		table   string
+		content interface{}
+	}
+	var act []tableRow
+	for _, b := range client.batches {
+		if b.operation != storage.TableInsert {
+			t.Errorf("Wrong operation: %v", b.operation)
+		}
+		if len(b.entities) != 1 {
+			t.Errorf("Expected duplicate row keys in separate batches: %v", len(b.entities))
+		}
+		for _, e := range b.entities {
+			act = append(act, tableRow{table: b.table, content: e.Properties["content"]})
+		}
+	}
+	exp := []tableRow{
+		{table: "a", content: "first"},
+		{table: "b", content: "second"},
+		{table: "a", content: "third"},
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong entities: %v != %v", act, exp)
+	}
+}
+
+func TestAzureTableStorageBatchLimit(t *testing.T) {
+	conf := NewAzureTableStorageConfig()
+	conf.TableName = "foo"
+	conf.PartitionKey = "bar"
+	conf.RowKey = "${!count:rows}"
+	conf.Properties = map[string]string{
+		"content": "${!content}",
+	}
+
+	a, client := testAzureTableWriter(t, conf)
+
+	parts := make([][]byte, storage.MaxTableBatchSize+10)
+	for i := range parts {
+		parts[i] = []byte("hello world")
+	}
+	if err := a.Write(message.New(parts)); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 2, len(client.batches); exp != act {
+		t.Fatalf("Wrong count of batches: %v != %v", act, exp)
+	}
+	if exp, act := storage.MaxTableBatchSize, len(client.batches[0].entities); exp != act {
+		t.Errorf("Wrong size of first batch: %v != %v", act, exp)
+	}
+	if exp, act := 10, len(client.batches[1].entities); exp != act {
+		t.Errorf("Wrong size of second batch: %v != %v", act, exp)
+	}
+}
+
+func TestAzureTableStorageErrors(t *testing.T) {
+	conf := NewAzureTableStorageConfig()
+	conf.TableName = "foo"
+	conf.RowKey = "bar"
+
+	a, client := testAzureTableWriter(t, conf)
+
+	if err := a.Write(message.New([][]byte{[]byte("not json")})); err == nil {
+		t.Error("Expected error from non JSON message")
+	}
+	if err := a.Write(message.New([][]byte{[]byte(`["not","object"]`)})); err == nil {
+		t.Error("Expected error from non object message")
+	}
+
+	client.err = errors.New("nope")
+	if err := a.Write(message.New([][]byte{[]byte(`{"foo":"bar"}`)})); err != client.err {
+		t.Errorf("Wrong error returned: %v", err)
+	}
+}
+
+//------------------------------------------------------------------------------
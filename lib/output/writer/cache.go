@@ -37,6 +37,7 @@ import (
 type CacheConfig struct {
 	Target string `json:"target" yaml:"target"`
 	Key    string `json:"key" yaml:"key"`
+	TTL    string `json:"ttl" yaml:"ttl"`
 }
 
 // NewCacheConfig creates a new Config with default values.
@@ -44,6 +45,7 @@ func NewCacheConfig() CacheConfig {
 	return CacheConfig{
 		Target: "",
 		Key:    "${!count:items}-${!timestamp_unix_nano}",
+		TTL:    "",
 	}
 }
 
@@ -57,6 +59,9 @@ type Cache struct {
 	key   *text.InterpolatedString
 	cache types.Cache
 
+	ttl      time.Duration
+	ttlCache types.CacheWithTTL
+
 	log   log.Modular
 	stats metrics.Type
 }
@@ -72,13 +77,23 @@ func NewCache(
 	if err != nil {
 		return nil, fmt.Errorf("failed to obtain cache '%v': %v", conf.Target, err)
 	}
-	return &Cache{
+	c := &Cache{
 		conf:  conf,
 		key:   text.NewInterpolatedString(conf.Key),
 		cache: cache,
 		log:   log,
 		stats: stats,
-	}, nil
+	}
+	if len(conf.TTL) > 0 {
+		if c.ttl, err = time.ParseDuration(conf.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl string: %v", err)
+		}
+		var ok bool
+		if c.ttlCache, ok = cache.(types.CacheWithTTL); !ok {
+			return nil, fmt.Errorf("cache '%v' does not support custom TTLs", conf.Target)
+		}
+	}
+	return c, nil
 }
 
 // Connect does nothing.
@@ -89,6 +104,11 @@ func (c *Cache) Connect() error {
 
 // Write attempts to write message contents to a target Cache directory as files.
 func (c *Cache) Write(msg types.Message) error {
+	if c.ttlCache != nil {
+		return msg.Iter(func(i int, p types.Part) error {
+			return c.ttlCache.SetWithTTL(c.key.Get(message.Lock(msg, i)), p.Get(), c.ttl)
+		})
+	}
 	if msg.Len() == 1 {
 		return c.cache.Set(c.key.Get(msg), msg.Get(0).Get())
 	}
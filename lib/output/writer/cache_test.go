@@ -22,13 +22,16 @@ package writer
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/cache"
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/manager"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 )
 
 func TestCacheBasic(t *testing.T) {
@@ -120,3 +123,62 @@ func TestCacheBatches(t *testing.T) {
 		}
 	}
 }
+
+type fakeTTLCache struct {
+	types.Cache
+	ttls map[string]time.Duration
+}
+
+func (f *fakeTTLCache) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	f.ttls[key] = ttl
+	return f.Cache.Set(key, value)
+}
+
+func TestCacheTTL(t *testing.T) {
+	mgrConf := manager.NewConfig()
+	mgrConf.Caches["foo"] = cache.NewConfig()
+
+	mgr, err := manager.New(mgrConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cacheConf := NewCacheConfig()
+	cacheConf.Target = "foo"
+	cacheConf.Key = "${!json_field:key}"
+	cacheConf.TTL = "not a duration"
+
+	if _, err = NewCache(cacheConf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad ttl")
+	}
+
+	cacheConf.TTL = "2m"
+	c, err := NewCache(cacheConf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memCache, err := mgr.GetCache("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeTTLCache{Cache: memCache, ttls: map[string]time.Duration{}}
+	c.ttlCache = fake
+
+	if err = c.Write(message.New([][]byte{
+		[]byte(`{"key":"a"}`),
+		[]byte(`{"key":"b"}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]time.Duration{"a": time.Minute * 2, "b": time.Minute * 2}
+	if !reflect.DeepEqual(exp, fake.ttls) {
+		t.Errorf("Wrong ttls: %v != %v", fake.ttls, exp)
+	}
+	if res, err := memCache.Get("b"); err != nil {
+		t.Error(err)
+	} else if exp, act := `{"key":"b"}`, string(res); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+// ClickHouseConfig contains configuration fields for the ClickHouse output
+// type.
+type ClickHouseConfig struct {
+	URL                string               `json:"url" yaml:"url"`
+	Database           string               `json:"database" yaml:"database"`
+	Table              string               `json:"table" yaml:"table"`
+	Columns            []string             `json:"columns" yaml:"columns"`
+	ColumnPaths        map[string]string    `json:"column_paths" yaml:"column_paths"`
+	AsyncInsert        bool                 `json:"async_insert" yaml:"async_insert"`
+	WaitForAsyncInsert bool                 `json:"wait_for_async_insert" yaml:"wait_for_async_insert"`
+	Settings           map[string]string    `json:"settings" yaml:"settings"`
+	Timeout            string               `json:"timeout" yaml:"timeout"`
+	Auth               auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	TLS                btls.Config          `json:"tls" yaml:"tls"`
+}
+
+// NewClickHouseConfig creates a new ClickHouseConfig with default values.
+func NewClickHouseConfig() ClickHouseConfig {
+	return ClickHouseConfig{
+		URL:                "http://localhost:8123",
+		Database:           "default",
+		Table:              "benthos",
+		Columns:            []string{},
+		ColumnPaths:        map[string]string{},
+		AsyncInsert:        false,
+		WaitForAsyncInsert: true,
+		Settings:           map[string]string{},
+		Timeout:            "5s",
+		Auth:               auth.NewBasicAuthConfig(),
+		TLS:                btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ClickHouse is a writer type that inserts messages as rows of a ClickHouse
+// table over the HTTP interface.
+type ClickHouse struct {
+	conf   ClickHouseConfig
+	client http.Client
+
+	insertURL string
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRows    metrics.StatCounter
+	mJSONErr metrics.StatCounter
+}
+
+// NewClickHouse creates a new ClickHouse writer type.
+func NewClickHouse(conf ClickHouseConfig, log log.Modular, stats metrics.Type) (*ClickHouse, error) {
+	c := ClickHouse{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		mRows:    stats.GetCounter("rows"),
+		mJSONErr: stats.GetCounter("error.json"),
+	}
+	if len(conf.Table) == 0 {
+		return nil, errors.New("a table must be specified")
+	}
+	for col := range conf.ColumnPaths {
+		found := false
+		for _, c := range conf.Columns {
+			if c == col {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column path specified for unknown column: %v", col)
+		}
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if c.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		c.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+	query := u.Query()
+	query.Set("query", clickHouseInsertQuery(conf.Database, conf.Table, conf.Columns))
+	if conf.AsyncInsert {
+		query.Set("async_insert", "1")
+		if conf.WaitForAsyncInsert {
+			query.Set("wait_for_async_insert", "1")
+		} else {
+			query.Set("wait_for_async_insert", "0")
+		}
+	}
+	for k, v := range conf.Settings {
+		query.Set(k, v)
+	}
+	u.RawQuery = query.Encode()
+	c.insertURL = u.String()
+
+	return &c, nil
+}
+
+// clickHouseInsertQuery creates the insert statement for a table, where rows
+// are provided in the request body as JSON.
+func clickHouseInsertQuery(database, table string, columns []string) string {
+	quote := func(s string) string {
+		return "`" + strings.Replace(s, "`", "\\`", -1) + "`"
+	}
+	var query bytes.Buffer
+	query.WriteString("INSERT INTO ")
+	if len(database) > 0 {
+		query.WriteString(quote(database))
+		query.WriteByte('.')
+	}
+	query.WriteString(quote(table))
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = quote(col)
+		}
+		query.WriteString(" (")
+		query.WriteString(strings.Join(quoted, ", "))
+		query.WriteByte(')')
+	}
+	query.WriteString(" FORMAT JSONEachRow")
+	return query.String()
+}
+
+//------------------------------------------------------------------------------
+
+// Connect checks that the ClickHouse server is reachable.
+func (c *ClickHouse) Connect() error {
+	u, err := url.Parse(c.conf.URL)
+	if err != nil {
+		return err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ping"
+	u.RawQuery = ""
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	c.conf.Auth.Sign(req)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("ping returned unexpected status: %v", res.Status)
+	}
+
+	c.log.Infof("Inserting rows into ClickHouse table '%v' at: %v\n", c.conf.Table, c.conf.URL)
+	return nil
+}
+
+// row creates a JSON row from a message part.
+func (c *ClickHouse) row(part types.Part) ([]byte, error) {
+	jObj, err := part.JSON()
+	if err != nil {
+		return nil, err
+	}
+	if len(c.conf.Columns) == 0 {
+		if _, ok := jObj.(map[string]interface{}); !ok {
+			return nil, errors.New("message is not a JSON object")
+		}
+		return json.Marshal(jObj)
+	}
+
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]interface{}, len(c.conf.Columns))
+	for _, col := range c.conf.Columns {
+		path, exists := c.conf.ColumnPaths[col]
+		if !exists {
+			path = col
+		}
+		if v := gObj.Path(path).Data(); v != nil {
+			row[col] = v
+		}
+	}
+	return json.Marshal(row)
+}
+
+// Write attempts to insert each part of a message as a row of the table.
+func (c *ClickHouse) Write(msg types.Message) error {
+	var body bytes.Buffer
+	rows := 0
+	msg.Iter(func(i int, part types.Part) error {
+		row, err := c.row(part)
+		if err != nil {
+			c.mJSONErr.Incr(1)
+			c.log.Errorf("Failed to create row from message: %v\n", err)
+			return nil
+		}
+		body.Write(row)
+		body.WriteByte('\n')
+		rows++
+		return nil
+	})
+	if rows == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", c.insertURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.conf.Auth.Sign(req)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resBody, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("insert returned unexpected status: %v: %s", res.Status, bytes.TrimSpace(resBody))
+	}
+	c.mRows.Incr(int64(rows))
+	return nil
+}
+
+// CloseAsync shuts down the ClickHouse writer and stops processing messages.
+func (c *ClickHouse) CloseAsync() {
+}
+
+// WaitForClose blocks until the ClickHouse writer has closed down.
+func (c *ClickHouse) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
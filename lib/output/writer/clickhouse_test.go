@@ -0,0 +1,153 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestClickHouseInsertQuery(t *testing.T) {
+	tests := []struct {
+		database string
+		table    string
+		columns  []string
+		exp      string
+	}{
+		{"", "foo", nil, "INSERT INTO `foo` FORMAT JSONEachRow"},
+		{"db", "foo", nil, "INSERT INTO `db`.`foo` FORMAT JSONEachRow"},
+		{"db", "foo", []string{"a", "b`c"}, "INSERT INTO `db`.`foo` (`a`, `b\\`c`) FORMAT JSONEachRow"},
+	}
+	for _, test := range tests {
+		if act := clickHouseInsertQuery(test.database, test.table, test.columns); act != test.exp {
+			t.Errorf("Wrong query: %v != %v", act, test.exp)
+		}
+	}
+}
+
+func TestClickHouseBadConfig(t *testing.T) {
+	conf := NewClickHouseConfig()
+	conf.Table = ""
+	if _, err := NewClickHouse(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty table")
+	}
+
+	conf = NewClickHouseConfig()
+	conf.ColumnPaths = map[string]string{"foo": "bar"}
+	if _, err := NewClickHouse(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from path of unknown column")
+	}
+}
+
+func TestClickHouseWrite(t *testing.T) {
+	var reqQuery, reqBody string
+	var reqAsync, reqWait, reqSetting string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			w.Write([]byte("Ok.\n"))
+			return
+		}
+		if user, pass, _ := r.BasicAuth(); user != "foo" || pass != "bar" {
+			http.Error(w, "bad auth", http.StatusUnauthorized)
+			return
+		}
+		reqQuery = r.URL.Query().Get("query")
+		reqAsync = r.URL.Query().Get("async_insert")
+		reqWait = r.URL.Query().Get("wait_for_async_insert")
+		reqSetting = r.URL.Query().Get("insert_quorum")
+		body, _ := ioutil.ReadAll(r.Body)
+		reqBody = string(body)
+	}))
+	defer ts.Close()
+
+	conf := NewClickHouseConfig()
+	conf.URL = ts.URL
+	conf.Database = "db"
+	conf.Table = "events"
+	conf.Columns = []string{"id", "user"}
+	conf.ColumnPaths = map[string]string{"user": "user.name"}
+	conf.AsyncInsert = true
+	conf.WaitForAsyncInsert = false
+	conf.Settings = map[string]string{"insert_quorum": "2"}
+	conf.Auth.Enabled = true
+	conf.Auth.Username = "foo"
+	conf.Auth.Password = "bar"
+
+	c, err := NewClickHouse(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.Write(message.New([][]byte{
+		[]byte(`{"id":1,"user":{"name":"foo"},"ignored":true}`),
+		[]byte(`not json`),
+		[]byte(`{"id":2}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "INSERT INTO `db`.`events` (`id`, `user`) FORMAT JSONEachRow", reqQuery; exp != act {
+		t.Errorf("Wrong query: %v != %v", act, exp)
+	}
+	if exp, act := "{\"id\":1,\"user\":\"foo\"}\n{\"id\":2}\n", reqBody; exp != act {
+		t.Errorf("Wrong body: %v != %v", act, exp)
+	}
+	if exp, act := "1", reqAsync; exp != act {
+		t.Errorf("Wrong async_insert: %v != %v", act, exp)
+	}
+	if exp, act := "0", reqWait; exp != act {
+		t.Errorf("Wrong wait_for_async_insert: %v != %v", act, exp)
+	}
+	if exp, act := "2", reqSetting; exp != act {
+		t.Errorf("Wrong setting: %v != %v", act, exp)
+	}
+}
+
+func TestClickHouseWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Code: 60. DB::Exception: Table db.events doesn't exist.", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	conf := NewClickHouseConfig()
+	conf.URL = ts.URL
+
+	c, err := NewClickHouse(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Write(message.New([][]byte{[]byte(`{"id":1}`)})); err == nil {
+		t.Error("Expected error from failed insert")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,252 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+// Limits of the Datadog logs intake API.
+const (
+	datadogMaxBatchCount = 1000
+	datadogMaxBatchBytes = 5 * 1024 * 1024
+	datadogMaxLogBytes   = 1024 * 1024
+)
+
+// DatadogLogsConfig contains configuration fields for the DatadogLogs output
+// type.
+type DatadogLogsConfig struct {
+	URL      string      `json:"url" yaml:"url"`
+	APIKey   string      `json:"api_key" yaml:"api_key"`
+	Service  string      `json:"service" yaml:"service"`
+	Source   string      `json:"source" yaml:"source"`
+	Hostname string      `json:"hostname" yaml:"hostname"`
+	Tags     string      `json:"tags" yaml:"tags"`
+	Gzip     bool        `json:"gzip" yaml:"gzip"`
+	Timeout  string      `json:"timeout" yaml:"timeout"`
+	TLS      btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewDatadogLogsConfig creates a new DatadogLogsConfig with default values.
+func NewDatadogLogsConfig() DatadogLogsConfig {
+	return DatadogLogsConfig{
+		URL:      "https://http-intake.logs.datadoghq.com/api/v2/logs",
+		APIKey:   "",
+		Service:  "",
+		Source:   "benthos",
+		Hostname: "",
+		Tags:     "",
+		Gzip:     true,
+		Timeout:  "5s",
+		TLS:      btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DatadogLogs is a writer type that sends messages as logs to the Datadog logs
+// intake API.
+type DatadogLogs struct {
+	conf   DatadogLogsConfig
+	client http.Client
+
+	attrs map[string]*text.InterpolatedString
+
+	log   log.Modular
+	stats metrics.Type
+
+	mLogs     metrics.StatCounter
+	mRequests metrics.StatCounter
+	mTooLarge metrics.StatCounter
+}
+
+// NewDatadogLogs creates a new DatadogLogs writer type.
+func NewDatadogLogs(conf DatadogLogsConfig, log log.Modular, stats metrics.Type) (*DatadogLogs, error) {
+	d := &DatadogLogs{
+		conf: conf,
+		attrs: map[string]*text.InterpolatedString{
+			"service":  text.NewInterpolatedString(conf.Service),
+			"ddsource": text.NewInterpolatedString(conf.Source),
+			"hostname": text.NewInterpolatedString(conf.Hostname),
+			"ddtags":   text.NewInterpolatedString(conf.Tags),
+		},
+		log:       log,
+		stats:     stats,
+		mLogs:     stats.GetCounter("logs"),
+		mRequests: stats.GetCounter("requests"),
+		mTooLarge: stats.GetCounter("error.too_large"),
+	}
+	if len(conf.APIKey) == 0 {
+		return nil, errors.New("an api key must be specified")
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if d.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		d.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect does nothing as each write is a separate request.
+func (d *DatadogLogs) Connect() error {
+	d.log.Infof("Sending logs to Datadog at: %v\n", d.conf.URL)
+	return nil
+}
+
+// entry creates a log entry from a message part. JSON objects are sent as
+// structured logs, and other parts are sent as the message of the log.
+func (d *DatadogLogs) entry(msg types.Message, index int) ([]byte, error) {
+	part := msg.Get(index)
+
+	var entry map[string]interface{}
+	if jObj, err := part.JSON(); err == nil {
+		entry, _ = jObj.(map[string]interface{})
+	}
+	if entry == nil {
+		entry = map[string]interface{}{
+			"message": string(part.Get()),
+		}
+	}
+
+	lMsg := message.Lock(msg, index)
+	for k, v := range d.attrs {
+		if str := v.Get(lMsg); len(str) > 0 {
+			entry[k] = str
+		}
+	}
+	return json.Marshal(entry)
+}
+
+func (d *DatadogLogs) send(entries [][]byte) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+
+	var zw *gzip.Writer
+	if d.conf.Gzip {
+		zw = gzip.NewWriter(&body)
+		w = zw
+	}
+	w.Write([]byte{'['})
+	for i, e := range entries {
+		if i > 0 {
+			w.Write([]byte{','})
+		}
+		w.Write(e)
+	}
+	w.Write([]byte{']'})
+	if zw != nil {
+		zw.Close()
+	}
+
+	req, err := http.NewRequest("POST", d.conf.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("DD-API-KEY", d.conf.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	if d.conf.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resBody, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	d.mRequests.Incr(1)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("request returned unexpected status: %v: %s", res.Status, bytes.TrimSpace(resBody))
+	}
+	d.mLogs.Incr(int64(len(entries)))
+	return nil
+}
+
+// Write attempts to send each part of a message as a log, splitting the
+// message into multiple requests where it would exceed the limits of the
+// intake API.
+func (d *DatadogLogs) Write(msg types.Message) error {
+	var entries [][]byte
+	size := 2
+	for i := 0; i < msg.Len(); i++ {
+		entry, err := d.entry(msg, i)
+		if err != nil {
+			return err
+		}
+		if len(entry) > datadogMaxLogBytes {
+			d.mTooLarge.Incr(1)
+			d.log.Errorf("Dropping log of %v bytes as it exceeds the intake limit\n", len(entry))
+			continue
+		}
+		if len(entries) == datadogMaxBatchCount || size+len(entry)+1 > datadogMaxBatchBytes {
+			if err = d.send(entries); err != nil {
+				return err
+			}
+			entries, size = nil, 2
+		}
+		entries = append(entries, entry)
+		size += len(entry) + 1
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return d.send(entries)
+}
+
+// CloseAsync shuts down the DatadogLogs writer and stops processing messages.
+func (d *DatadogLogs) CloseAsync() {
+}
+
+// WaitForClose blocks until the DatadogLogs writer has closed down.
+func (d *DatadogLogs) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
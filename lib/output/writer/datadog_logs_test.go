@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func fakeDatadogServer(t *testing.T) (*httptest.Server, func() [][]map[string]interface{}) {
+	t.Helper()
+
+	var mut sync.Mutex
+	var reqs [][]map[string]interface{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "fookey" {
+			http.Error(w, `{"errors":[{"status":"403","title":"Forbidden"}]}`, http.StatusForbidden)
+			return
+		}
+		var rdr io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			rdr = zr
+		}
+		var logs []map[string]interface{}
+		if err := json.NewDecoder(rdr).Decode(&logs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mut.Lock()
+		reqs = append(reqs, logs)
+		mut.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{}`))
+	}))
+
+	return ts, func() [][]map[string]interface{} {
+		mut.Lock()
+		defer mut.Unlock()
+		return reqs
+	}
+}
+
+func TestDatadogLogsWrite(t *testing.T) {
+	ts, reqs := fakeDatadogServer(t)
+	defer ts.Close()
+
+	conf := NewDatadogLogsConfig()
+	conf.URL = ts.URL
+	conf.APIKey = "fookey"
+	conf.Service = "${!metadata:service}"
+	conf.Tags = "env:test"
+
+	d, err := NewDatadogLogs(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"message":"foo","level":"info"}`),
+		[]byte(`bar baz`),
+	})
+	msg.Get(0).Metadata().Set("service", "fooservice")
+
+	if err = d.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]map[string]interface{}{
+		{
+			{
+				"message":  "foo",
+				"level":    "info",
+				"service":  "fooservice",
+				"ddsource": "benthos",
+				"ddtags":   "env:test",
+			},
+			{
+				"message":  "bar baz",
+				"ddsource": "benthos",
+				"ddtags":   "env:test",
+			},
+		},
+	}
+	if act := reqs(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong requests: %v != %v", act, exp)
+	}
+}
+
+func TestDatadogLogsLimits(t *testing.T) {
+	ts, reqs := fakeDatadogServer(t)
+	defer ts.Close()
+
+	conf := NewDatadogLogsConfig()
+	conf.URL = ts.URL
+	conf.APIKey = "fookey"
+	conf.Gzip = false
+
+	d, err := NewDatadogLogs(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New(nil)
+	for i := 0; i < datadogMaxBatchCount+5; i++ {
+		msg.Append(message.NewPart([]byte("foo")))
+	}
+	msg.Append(message.NewPart(bytes.Repeat([]byte("a"), datadogMaxLogBytes)))
+
+	if err = d.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	act := reqs()
+	if len(act) != 2 {
+		t.Fatalf("Wrong count of requests: %v", len(act))
+	}
+	if exp, act := datadogMaxBatchCount, len(act[0]); exp != act {
+		t.Errorf("Wrong count of logs in first request: %v != %v", act, exp)
+	}
+	if exp, act := 5, len(act[1]); exp != act {
+		t.Errorf("Wrong count of logs in second request: %v != %v", act, exp)
+	}
+}
+
+func TestDatadogLogsError(t *testing.T) {
+	ts, _ := fakeDatadogServer(t)
+	defer ts.Close()
+
+	conf := NewDatadogLogsConfig()
+	conf.URL = ts.URL
+	conf.APIKey = "barkey"
+
+	d, err := NewDatadogLogs(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Write(message.New([][]byte{[]byte("foo")})); err == nil {
+		t.Error("Expected error from bad api key")
+	}
+
+	conf.APIKey = ""
+	if _, err = NewDatadogLogs(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing api key")
+	}
+}
+
+//------------------------------------------------------------------------------
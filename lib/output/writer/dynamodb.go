@@ -33,8 +33,11 @@ import (
 	"github.com/Jeffail/benthos/lib/util/aws/session"
 	"github.com/Jeffail/benthos/lib/util/retries"
 	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/cenkalti/backoff"
 )
@@ -43,12 +46,16 @@ import (
 
 // DynamoDBConfig contains config fields for the DynamoDB output type.
 type DynamoDBConfig struct {
-	sessionConfig  `json:",inline" yaml:",inline"`
-	Table          string            `json:"table" yaml:"table"`
-	StringColumns  map[string]string `json:"string_columns" yaml:"string_columns"`
-	TTL            string            `json:"ttl" yaml:"ttl"`
-	TTLKey         string            `json:"ttl_key" yaml:"ttl_key"`
-	retries.Config `json:",inline" yaml:",inline"`
+	sessionConfig             `json:",inline" yaml:",inline"`
+	Table                     string            `json:"table" yaml:"table"`
+	StringColumns             map[string]string `json:"string_columns" yaml:"string_columns"`
+	JSONMapColumns            map[string]string `json:"json_map_columns" yaml:"json_map_columns"`
+	ConditionExpression       string            `json:"condition_expression" yaml:"condition_expression"`
+	ExpressionAttributeNames  map[string]string `json:"expression_attribute_names" yaml:"expression_attribute_names"`
+	ExpressionAttributeValues map[string]string `json:"expression_attribute_values" yaml:"expression_attribute_values"`
+	TTL                       string            `json:"ttl" yaml:"ttl"`
+	TTLKey                    string            `json:"ttl_key" yaml:"ttl_key"`
+	retries.Config            `json:",inline" yaml:",inline"`
 }
 
 // NewDynamoDBConfig creates a DynamoDBConfig populated with default values.
@@ -62,18 +69,22 @@ func NewDynamoDBConfig() DynamoDBConfig {
 		sessionConfig: sessionConfig{
 			Config: session.NewConfig(),
 		},
-		Table:         "",
-		StringColumns: map[string]string{},
-		TTL:           "",
-		TTLKey:        "",
-		Config:        rConf,
+		Table:                     "",
+		StringColumns:             map[string]string{},
+		JSONMapColumns:            map[string]string{},
+		ConditionExpression:       "",
+		ExpressionAttributeNames:  map[string]string{},
+		ExpressionAttributeValues: map[string]string{},
+		TTL:                       "",
+		TTLKey:                    "",
+		Config:                    rConf,
 	}
 }
 
 //------------------------------------------------------------------------------
 
 // DynamoDB is a benthos writer.Type implementation that writes messages to an
-// Amazon SQS queue.
+// Amazon DynamoDB table.
 type DynamoDB struct {
 	client  dynamodbiface.DynamoDBAPI
 	conf    DynamoDBConfig
@@ -84,9 +95,13 @@ type DynamoDB struct {
 	table      *string
 	ttl        time.Duration
 	strColumns map[string]*text.InterpolatedString
+	exprValues map[string]*text.InterpolatedString
+
+	mJSONErr    metrics.StatCounter
+	mCondFailed metrics.StatCounter
 }
 
-// NewDynamoDB creates a new Amazon SQS writer.Type.
+// NewDynamoDB creates a new Amazon DynamoDB writer.Type.
 func NewDynamoDB(
 	conf DynamoDBConfig,
 	log log.Modular,
@@ -103,13 +118,24 @@ func NewDynamoDB(
 		table:      aws.String(conf.Table),
 		backoff:    boff,
 		strColumns: map[string]*text.InterpolatedString{},
+		exprValues: map[string]*text.InterpolatedString{},
+
+		mJSONErr:    stats.GetCounter("error.json"),
+		mCondFailed: stats.GetCounter("condition_failed"),
 	}
-	if len(conf.StringColumns) == 0 {
+	if len(conf.StringColumns) == 0 && len(conf.JSONMapColumns) == 0 {
 		return nil, errors.New("you must provide at least one column")
 	}
 	for k, v := range conf.StringColumns {
 		db.strColumns[k] = text.NewInterpolatedString(v)
 	}
+	if len(conf.ConditionExpression) == 0 &&
+		(len(conf.ExpressionAttributeNames) > 0 || len(conf.ExpressionAttributeValues) > 0) {
+		return nil, errors.New("expression attributes require a condition expression")
+	}
+	for k, v := range conf.ExpressionAttributeValues {
+		db.exprValues[k] = text.NewInterpolatedString(v)
+	}
 	if conf.TTL != "" {
 		ttl, err := time.ParseDuration(conf.TTL)
 		if err != nil {
@@ -120,7 +146,7 @@ func NewDynamoDB(
 	return db, nil
 }
 
-// Connect attempts to establish a connection to the target SQS queue.
+// Connect attempts to establish a connection to the target DynamoDB table.
 func (d *DynamoDB) Connect() error {
 	if d.client != nil {
 		return nil
@@ -146,62 +172,202 @@ func (d *DynamoDB) Connect() error {
 	return nil
 }
 
-// Write attempts to write message contents to a target SQS.
+// dynamoDBBatchLimit is the maximum number of items that can be written with a
+// single BatchWriteItem request.
+const dynamoDBBatchLimit = 25
+
+// dynamoDBItem is an item to be written along with the values of any condition
+// expression attributes calculated for it.
+type dynamoDBItem struct {
+	item   map[string]*dynamodb.AttributeValue
+	values map[string]*dynamodb.AttributeValue
+}
+
+// jsonColumns adds the columns mapped from the JSON contents of a message part
+// to an item.
+func (d *DynamoDB) jsonColumns(p types.Part, item map[string]*dynamodb.AttributeValue) error {
+	jObj, err := p.JSON()
+	if err != nil {
+		return err
+	}
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return err
+	}
+	for col, path := range d.conf.JSONMapColumns {
+		target := gObj
+		if len(path) > 0 && path != "." {
+			target = gObj.Path(path)
+		}
+		if len(col) > 0 {
+			if target.Data() == nil {
+				continue
+			}
+			attr, err := dynamodbattribute.Marshal(target.Data())
+			if err != nil {
+				return fmt.Errorf("failed to map column '%v': %v", col, err)
+			}
+			item[col] = attr
+			continue
+		}
+		fields, ok := target.Data().(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("value at path '%v' is not an object", path)
+		}
+		for k, v := range fields {
+			attr, err := dynamodbattribute.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to map column '%v': %v", k, err)
+			}
+			item[k] = attr
+		}
+	}
+	return nil
+}
+
+// Write attempts to write message contents to a target DynamoDB table.
 func (d *DynamoDB) Write(msg types.Message) error {
 	if d.client == nil {
 		return types.ErrNotConnected
 	}
 
-	writeReqs := []*dynamodb.WriteRequest{}
+	items := make([]dynamoDBItem, 0, msg.Len())
 	msg.Iter(func(i int, p types.Part) error {
-		items := map[string]*dynamodb.AttributeValue{}
+		item := map[string]*dynamodb.AttributeValue{}
+		if len(d.conf.JSONMapColumns) > 0 {
+			if err := d.jsonColumns(p, item); err != nil {
+				d.mJSONErr.Incr(1)
+				d.log.Errorf("Failed to map JSON columns of message: %v\n", err)
+				return nil
+			}
+		}
 		if d.ttl != 0 && d.conf.TTLKey != "" {
-			items[d.conf.TTLKey] = &dynamodb.AttributeValue{
+			item[d.conf.TTLKey] = &dynamodb.AttributeValue{
 				N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10)),
 			}
 		}
 		for k, v := range d.strColumns {
 			s := v.Get(message.Lock(msg, i))
-			items[k] = &dynamodb.AttributeValue{
+			item[k] = &dynamodb.AttributeValue{
 				S: &s,
 			}
 		}
-		writeReqs = append(writeReqs, &dynamodb.WriteRequest{
-			PutRequest: &dynamodb.PutRequest{
-				Item: items,
-			},
+		var values map[string]*dynamodb.AttributeValue
+		if len(d.exprValues) > 0 {
+			values = make(map[string]*dynamodb.AttributeValue, len(d.exprValues))
+			for k, v := range d.exprValues {
+				s := v.Get(message.Lock(msg, i))
+				values[k] = &dynamodb.AttributeValue{
+					S: &s,
+				}
+			}
+		}
+		items = append(items, dynamoDBItem{
+			item:   item,
+			values: values,
 		})
 		return nil
 	})
 
+	var err error
+	if len(d.conf.ConditionExpression) > 0 {
+		err = d.putItems(items)
+	} else {
+		err = d.batchWriteItems(items)
+	}
+	if err == nil {
+		d.backoff.Reset()
+	}
+	return err
+}
+
+// batchWriteItems writes items with BatchWriteItem requests, retrying any
+// items that were left unprocessed.
+func (d *DynamoDB) batchWriteItems(items []dynamoDBItem) error {
+	writeReqs := make([]*dynamodb.WriteRequest, len(items))
+	for i, item := range items {
+		writeReqs[i] = &dynamodb.WriteRequest{
+			PutRequest: &dynamodb.PutRequest{
+				Item: item.item,
+			},
+		}
+	}
+
 	var err error
 	for len(writeReqs) > 0 {
-		wait := d.backoff.NextBackOff()
+		batch := writeReqs
+		if len(batch) > dynamoDBBatchLimit {
+			batch = batch[:dynamoDBBatchLimit]
+		}
+
 		var batchResult *dynamodb.BatchWriteItemOutput
 		batchResult, err = d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{
 			RequestItems: map[string][]*dynamodb.WriteRequest{
-				*d.table: writeReqs,
+				*d.table: batch,
 			},
 		})
 		if err != nil {
 			d.log.Errorf("Write multi error: %v\n", err)
-		} else if unproc := batchResult.UnprocessedItems[*d.table]; len(unproc) > 0 {
-			writeReqs = unproc
-			err = fmt.Errorf("failed to set %v items", len(unproc))
 		} else {
-			writeReqs = nil
+			unproc := batchResult.UnprocessedItems[*d.table]
+			writeReqs = append(unproc, writeReqs[len(batch):]...)
+			if len(unproc) > 0 {
+				err = fmt.Errorf("failed to set %v items", len(unproc))
+			}
 		}
 
 		if err != nil {
+			wait := d.backoff.NextBackOff()
 			if wait == backoff.Stop {
 				break
 			}
-			time.After(wait)
+			<-time.After(wait)
 		}
 	}
+	return err
+}
 
-	if err == nil {
-		d.backoff.Reset()
+// putItems writes items individually with conditional PutItem requests,
+// retrying any that fail for reasons other than the condition.
+func (d *DynamoDB) putItems(items []dynamoDBItem) error {
+	var names map[string]*string
+	if len(d.conf.ExpressionAttributeNames) > 0 {
+		names = aws.StringMap(d.conf.ExpressionAttributeNames)
+	}
+
+	var err error
+	for len(items) > 0 {
+		var failed []dynamoDBItem
+		err = nil
+		for _, item := range items {
+			_, perr := d.client.PutItem(&dynamodb.PutItemInput{
+				TableName:                 d.table,
+				Item:                      item.item,
+				ConditionExpression:       aws.String(d.conf.ConditionExpression),
+				ExpressionAttributeNames:  names,
+				ExpressionAttributeValues: item.values,
+			})
+			if perr == nil {
+				continue
+			}
+			if aerr, ok := perr.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				d.mCondFailed.Incr(1)
+				d.log.Debugf("Skipping item that failed condition: %v\n", perr)
+				continue
+			}
+			d.log.Errorf("Write error: %v\n", perr)
+			failed = append(failed, item)
+			err = perr
+		}
+		items = failed
+
+		if err != nil {
+			wait := d.backoff.NextBackOff()
+			if wait == backoff.Stop {
+				break
+			}
+			<-time.After(wait)
+		}
 	}
 	return err
 }
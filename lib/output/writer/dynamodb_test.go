@@ -0,0 +1,242 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+//------------------------------------------------------------------------------
+
+type mockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	fnBatch func(*dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error)
+	fnPut   func(*dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error)
+}
+
+func (m *mockDynamoDB) BatchWriteItem(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return m.fnBatch(input)
+}
+
+func (m *mockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return m.fnPut(input)
+}
+
+func testDynamoDBConf() DynamoDBConfig {
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	return conf
+}
+
+func TestDynamoDBJSONMapColumns(t *testing.T) {
+	conf := testDynamoDBConf()
+	conf.StringColumns = map[string]string{
+		"id": "${!json_field:id}",
+	}
+	conf.JSONMapColumns = map[string]string{
+		"":     "body",
+		"meta": "meta",
+		"all":  "",
+	}
+
+	db, err := NewDynamoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	db.client = &mockDynamoDB{
+		fnBatch: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+			for _, req := range input.RequestItems["foo"] {
+				items = append(items, req.PutRequest.Item)
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+
+	if err = db.Write(message.New([][]byte{
+		[]byte(`{"id":"a","body":{"count":5,"ok":true},"meta":{"tags":["x"]}}`),
+		[]byte(`not json`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 1, len(items); exp != act {
+		t.Fatalf("Wrong count of items: %v != %v", act, exp)
+	}
+	item := items[0]
+	if exp, act := "a", aws.StringValue(item["id"].S); exp != act {
+		t.Errorf("Wrong id: %v != %v", act, exp)
+	}
+	if exp, act := "5", aws.StringValue(item["count"].N); exp != act {
+		t.Errorf("Wrong count: %v != %v", act, exp)
+	}
+	if exp, act := true, aws.BoolValue(item["ok"].BOOL); exp != act {
+		t.Errorf("Wrong ok: %v != %v", act, exp)
+	}
+	if exp, act := "x", aws.StringValue(item["meta"].M["tags"].L[0].S); exp != act {
+		t.Errorf("Wrong meta: %v != %v", act, exp)
+	}
+	if exp, act := "a", aws.StringValue(item["all"].M["id"].S); exp != act {
+		t.Errorf("Wrong all: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBBatchUnprocessed(t *testing.T) {
+	conf := testDynamoDBConf()
+	conf.StringColumns = map[string]string{
+		"id": "${!content}",
+	}
+
+	db, err := NewDynamoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batchSizes []int
+	var written []string
+	retried := false
+	db.client = &mockDynamoDB{
+		fnBatch: func(input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+			reqs := input.RequestItems["foo"]
+			batchSizes = append(batchSizes, len(reqs))
+			if !retried {
+				retried = true
+				for _, req := range reqs[:24] {
+					written = append(written, *req.PutRequest.Item["id"].S)
+				}
+				return &dynamodb.BatchWriteItemOutput{
+					UnprocessedItems: map[string][]*dynamodb.WriteRequest{
+						"foo": reqs[24:],
+					},
+				}, nil
+			}
+			for _, req := range reqs {
+				written = append(written, *req.PutRequest.Item["id"].S)
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+	}
+
+	parts := make([][]byte, 30)
+	for i := range parts {
+		parts[i] = []byte{byte('a' + i)}
+	}
+	if err = db.Write(message.New(parts)); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := []int{25, 6}, batchSizes; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong batch sizes: %v != %v", act, exp)
+	}
+	if exp, act := 30, len(written); exp != act {
+		t.Errorf("Wrong count of written items: %v != %v", act, exp)
+	}
+}
+
+func TestDynamoDBConditionalPut(t *testing.T) {
+	conf := testDynamoDBConf()
+	conf.StringColumns = map[string]string{
+		"id": "${!content}",
+	}
+	conf.ConditionExpression = "attribute_not_exists(#id) OR #v < :v"
+	conf.ExpressionAttributeNames = map[string]string{
+		"#id": "id",
+		"#v":  "version",
+	}
+	conf.ExpressionAttributeValues = map[string]string{
+		":v": "${!content}",
+	}
+
+	db, err := NewDynamoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := map[string]int{}
+	db.client = &mockDynamoDB{
+		fnPut: func(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+			id := *input.Item["id"].S
+			attempts[id]++
+			if exp, act := conf.ConditionExpression, aws.StringValue(input.ConditionExpression); exp != act {
+				t.Errorf("Wrong condition: %v != %v", act, exp)
+			}
+			if exp, act := "version", aws.StringValue(input.ExpressionAttributeNames["#v"]); exp != act {
+				t.Errorf("Wrong attribute name: %v != %v", act, exp)
+			}
+			if exp, act := id, aws.StringValue(input.ExpressionAttributeValues[":v"].S); exp != act {
+				t.Errorf("Wrong attribute value: %v != %v", act, exp)
+			}
+			switch id {
+			case "exists":
+				return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "nope", nil)
+			case "flaky":
+				if attempts[id] == 1 {
+					return nil, awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "slow down", nil)
+				}
+			}
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+
+	if err = db.Write(message.New([][]byte{
+		[]byte("new"),
+		[]byte("exists"),
+		[]byte("flaky"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]int{
+		"new":    1,
+		"exists": 1,
+		"flaky":  2,
+	}
+	if !reflect.DeepEqual(exp, attempts) {
+		t.Errorf("Wrong put attempts: %v != %v", attempts, exp)
+	}
+}
+
+func TestDynamoDBBadConfig(t *testing.T) {
+	conf := testDynamoDBConf()
+	if _, err := NewDynamoDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing columns")
+	}
+
+	conf.StringColumns = map[string]string{"id": "${!content}"}
+	conf.ExpressionAttributeValues = map[string]string{":v": "foo"}
+	if _, err := NewDynamoDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from expression values without condition")
+	}
+}
+
+//------------------------------------------------------------------------------
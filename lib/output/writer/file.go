@@ -0,0 +1,302 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// FileConfig contains configuration fields for the file output type.
+type FileConfig struct {
+	Path           string `json:"path" yaml:"path"`
+	Delim          string `json:"delimiter" yaml:"delimiter"`
+	RotateMaxBytes int64  `json:"rotate_max_bytes" yaml:"rotate_max_bytes"`
+	RotateInterval string `json:"rotate_interval" yaml:"rotate_interval"`
+	GzipRotated    bool   `json:"gzip_rotated" yaml:"gzip_rotated"`
+	Sync           bool   `json:"sync" yaml:"sync"`
+}
+
+// NewFileConfig creates a new FileConfig with default values.
+func NewFileConfig() FileConfig {
+	return FileConfig{
+		Path:           "",
+		Delim:          "",
+		RotateMaxBytes: 0,
+		RotateInterval: "",
+		GzipRotated:    false,
+		Sync:           false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// fileIdlePeriod is the period after which files that have not been written
+// to are closed.
+const fileIdlePeriod = time.Minute
+
+// openFile is a file currently being appended to.
+type openFile struct {
+	handle    *os.File
+	size      int64
+	opened    time.Time
+	lastWrite time.Time
+}
+
+// File is a writer type that appends messages to files as lines, where files
+// can be rotated once they reach a size or age.
+type File struct {
+	conf FileConfig
+
+	path           *text.InterpolatedString
+	delim          []byte
+	rotateInterval time.Duration
+
+	files     map[string]*openFile
+	filesMut  sync.Mutex
+	compressW sync.WaitGroup
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRotated     metrics.StatCounter
+	mCompressErr metrics.StatCounter
+}
+
+// NewFile creates a new File writer type.
+func NewFile(conf FileConfig, log log.Modular, stats metrics.Type) (*File, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("a path must be specified")
+	}
+	f := &File{
+		conf:         conf,
+		path:         text.NewInterpolatedString(conf.Path),
+		delim:        []byte("\n"),
+		files:        map[string]*openFile{},
+		log:          log,
+		stats:        stats,
+		mRotated:     stats.GetCounter("rotated"),
+		mCompressErr: stats.GetCounter("error.compress"),
+	}
+	if len(conf.Delim) > 0 {
+		f.delim = []byte(conf.Delim)
+	}
+	if tout := conf.RotateInterval; len(tout) > 0 {
+		var err error
+		if f.rotateInterval, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse rotate interval string: %v", err)
+		}
+	}
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect is a noop.
+func (f *File) Connect() error {
+	f.log.Infof("Appending messages to files at path: %v\n", f.conf.Path)
+	return nil
+}
+
+func (f *File) open(path string) (*openFile, error) {
+	if file, exists := f.files[path]; exists {
+		return file, nil
+	}
+	if dir := filepath.Dir(path); len(dir) > 0 {
+		if err := os.MkdirAll(dir, os.FileMode(0777)); err != nil {
+			return nil, err
+		}
+	}
+	handle, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, os.FileMode(0666))
+	if err != nil {
+		return nil, err
+	}
+	info, err := handle.Stat()
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+	// The age of an existing file is taken from its modification time so that
+	// restarts do not postpone rotation indefinitely.
+	opened := time.Now()
+	if info.Size() > 0 {
+		opened = info.ModTime()
+	}
+	file := &openFile{
+		handle: handle,
+		size:   info.Size(),
+		opened: opened,
+	}
+	f.files[path] = file
+	return file, nil
+}
+
+// rotate closes a file and renames it with a timestamp suffix, compressing it
+// in the background if configured to do so.
+func (f *File) rotate(path string, file *openFile) error {
+	delete(f.files, path)
+	if err := file.handle.Close(); err != nil {
+		return err
+	}
+	rotated := path + "." + time.Now().UTC().Format("20060102T150405.000000000")
+	if err := os.Rename(path, rotated); err != nil {
+		return err
+	}
+	f.mRotated.Incr(1)
+	if f.conf.GzipRotated {
+		f.compressW.Add(1)
+		go func() {
+			defer f.compressW.Done()
+			if err := gzipFile(rotated); err != nil {
+				f.mCompressErr.Incr(1)
+				f.log.Errorf("Failed to compress rotated file '%v': %v\n", rotated, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// gzipFile compresses a file and removes the original.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0666))
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cErr := dst.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// closeIdle closes files that have not been written to recently, which allows
+// handles of paths that are no longer resolved to be released.
+func (f *File) closeIdle(now time.Time) {
+	for path, file := range f.files {
+		if now.Sub(file.lastWrite) > fileIdlePeriod {
+			file.handle.Close()
+			delete(f.files, path)
+		}
+	}
+}
+
+// Write attempts to append a message to a file, where the path is resolved
+// against the first part of the message.
+func (f *File) Write(msg types.Message) error {
+	var data []byte
+	if msg.Len() == 1 {
+		data = append(append(data, msg.Get(0).Get()...), f.delim...)
+	} else {
+		data = append(bytes.Join(message.GetAllBytes(msg), f.delim), f.delim...)
+		data = append(data, f.delim...)
+	}
+	path := f.path.Get(message.Lock(msg, 0))
+
+	f.filesMut.Lock()
+	defer f.filesMut.Unlock()
+
+	now := time.Now()
+	f.closeIdle(now)
+
+	file, err := f.open(path)
+	if err != nil {
+		return err
+	}
+	if file.size > 0 {
+		sizeExceeded := f.conf.RotateMaxBytes > 0 && file.size+int64(len(data)) > f.conf.RotateMaxBytes
+		ageExceeded := f.rotateInterval > 0 && now.Sub(file.opened) >= f.rotateInterval
+		if sizeExceeded || ageExceeded {
+			if err = f.rotate(path, file); err != nil {
+				return fmt.Errorf("failed to rotate file: %v", err)
+			}
+			if file, err = f.open(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	n, err := file.handle.Write(data)
+	file.size += int64(n)
+	file.lastWrite = now
+	if err != nil {
+		return err
+	}
+	if f.conf.Sync {
+		return file.handle.Sync()
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (f *File) CloseAsync() {
+	f.filesMut.Lock()
+	for path, file := range f.files {
+		file.handle.Close()
+		delete(f.files, path)
+	}
+	f.filesMut.Unlock()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (f *File) WaitForClose(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		f.compressW.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,230 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func readDirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileDelimiters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(dir, "out.txt")
+
+	f, err := NewFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Write(message.New([][]byte{[]byte("bar"), []byte("baz")})); err != nil {
+		t.Fatal(err)
+	}
+	f.CloseAsync()
+	if err = f.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(conf.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar\nbaz\n\n", string(b); exp != act {
+		t.Errorf("Wrong contents: %q != %q", act, exp)
+	}
+}
+
+func TestFileInterpolatedPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(dir, "${!metadata:day}", "out.txt")
+	conf.Delim = "|"
+	conf.Sync = true
+
+	f, err := NewFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, day := range []string{"mon", "tue", "mon"} {
+		msg := message.New([][]byte{[]byte(day)})
+		msg.Get(0).Metadata().Set("day", day)
+		if err = f.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.CloseAsync()
+
+	for day, exp := range map[string]string{"mon": "mon|mon|", "tue": "tue|"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, day, "out.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(b); exp != act {
+			t.Errorf("Wrong contents for %v: %q != %q", day, act, exp)
+		}
+	}
+}
+
+func TestFileRotateSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(dir, "out.txt")
+	conf.RotateMaxBytes = 8
+
+	f, err := NewFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"foo", "bar", "baz"} {
+		if err = f.Write(message.New([][]byte{[]byte(content)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.CloseAsync()
+	if err = f.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	names := readDirFiles(t, dir)
+	if len(names) != 2 {
+		t.Fatalf("Wrong files: %v", names)
+	}
+	if !strings.HasPrefix(names[1], "out.txt.") {
+		t.Errorf("Wrong rotated file name: %v", names[1])
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, names[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar\n", string(b); exp != act {
+		t.Errorf("Wrong rotated contents: %q != %q", act, exp)
+	}
+	if b, err = ioutil.ReadFile(conf.Path); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "baz\n", string(b); exp != act {
+		t.Errorf("Wrong contents: %q != %q", act, exp)
+	}
+}
+
+func TestFileRotateIntervalGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_file_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := NewFileConfig()
+	conf.Path = filepath.Join(dir, "out.txt")
+	conf.RotateInterval = "10ms"
+	conf.GzipRotated = true
+
+	f, err := NewFile(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Fatal(err)
+	}
+	<-time.After(time.Millisecond * 20)
+	if err = f.Write(message.New([][]byte{[]byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	f.CloseAsync()
+	if err = f.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	names := readDirFiles(t, dir)
+	if len(names) != 2 || !strings.HasSuffix(names[1], ".gz") {
+		t.Fatalf("Wrong files: %v", names)
+	}
+
+	gzFile, err := os.Open(filepath.Join(dir, names[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gzFile.Close()
+	zr, err := gzip.NewReader(gzFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\n", string(b); exp != act {
+		t.Errorf("Wrong rotated contents: %q != %q", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,247 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"google.golang.org/api/option"
+)
+
+//------------------------------------------------------------------------------
+
+// GCPBigQueryConfig contains configuration fields for the GCPBigQuery output
+// type.
+type GCPBigQueryConfig struct {
+	ProjectID           string `json:"project" yaml:"project"`
+	Dataset             string `json:"dataset" yaml:"dataset"`
+	Table               string `json:"table" yaml:"table"`
+	InsertID            string `json:"insert_id" yaml:"insert_id"`
+	TemplateSuffix      string `json:"template_suffix" yaml:"template_suffix"`
+	IgnoreUnknownValues bool   `json:"ignore_unknown_values" yaml:"ignore_unknown_values"`
+	SkipInvalidRows     bool   `json:"skip_invalid_rows" yaml:"skip_invalid_rows"`
+	Timeout             string `json:"timeout" yaml:"timeout"`
+}
+
+// NewGCPBigQueryConfig creates a new GCPBigQueryConfig with default values.
+func NewGCPBigQueryConfig() GCPBigQueryConfig {
+	return GCPBigQueryConfig{
+		ProjectID:           "",
+		Dataset:             "",
+		Table:               "",
+		InsertID:            "",
+		TemplateSuffix:      "",
+		IgnoreUnknownValues: false,
+		SkipInvalidRows:     false,
+		Timeout:             "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// bigQueryRow is a JSON object to be inserted as a row, implementing
+// bigquery.ValueSaver.
+type bigQueryRow struct {
+	row      map[string]bigquery.Value
+	insertID string
+}
+
+func (b bigQueryRow) Save() (map[string]bigquery.Value, string, error) {
+	return b.row, b.insertID, nil
+}
+
+// GCPBigQuery is a writer type that streams messages as rows into a GCP
+// BigQuery table.
+type GCPBigQuery struct {
+	conf    GCPBigQueryConfig
+	timeout time.Duration
+
+	tableStr    *text.InterpolatedString
+	insertIDStr *text.InterpolatedString
+
+	clientOpts []option.ClientOption
+	client     *bigquery.Client
+	clientMut  sync.Mutex
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRows    metrics.StatCounter
+	mJSONErr metrics.StatCounter
+}
+
+// NewGCPBigQuery creates a new GCP BigQuery writer type.
+func NewGCPBigQuery(conf GCPBigQueryConfig, log log.Modular, stats metrics.Type) (*GCPBigQuery, error) {
+	if len(conf.ProjectID) == 0 {
+		return nil, errors.New("a project must be specified")
+	}
+	if len(conf.Dataset) == 0 {
+		return nil, errors.New("a dataset must be specified")
+	}
+	if len(conf.Table) == 0 {
+		return nil, errors.New("a table must be specified")
+	}
+	g := GCPBigQuery{
+		conf:     conf,
+		tableStr: text.NewInterpolatedString(conf.Table),
+		log:      log,
+		stats:    stats,
+		mRows:    stats.GetCounter("rows"),
+		mJSONErr: stats.GetCounter("error.json"),
+	}
+	if len(conf.InsertID) > 0 {
+		g.insertIDStr = text.NewInterpolatedString(conf.InsertID)
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if g.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	return &g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GCPBigQuery) ctx() (context.Context, context.CancelFunc) {
+	if g.timeout > 0 {
+		return context.WithTimeout(context.Background(), g.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Connect attempts to create a BigQuery client and, when the table name is
+// static, checks that the table exists.
+func (g *GCPBigQuery) Connect() error {
+	g.clientMut.Lock()
+	defer g.clientMut.Unlock()
+	if g.client != nil {
+		return nil
+	}
+
+	ctx, cancel := g.ctx()
+	defer cancel()
+
+	client, err := bigquery.NewClient(ctx, g.conf.ProjectID, g.clientOpts...)
+	if err != nil {
+		return err
+	}
+	if !text.ContainsFunctionVariables([]byte(g.conf.Table)) {
+		if _, err = client.Dataset(g.conf.Dataset).Table(g.conf.Table).Metadata(ctx); err != nil {
+			client.Close()
+			return fmt.Errorf("failed to obtain table '%v': %v", g.conf.Table, err)
+		}
+	}
+
+	g.client = client
+	g.log.Infof("Inserting rows into GCP BigQuery dataset '%v' of project '%v'\n", g.conf.Dataset, g.conf.ProjectID)
+	return nil
+}
+
+// Write attempts to insert each part of a message as a row, where parts are
+// grouped into a single insert request per table.
+func (g *GCPBigQuery) Write(msg types.Message) error {
+	g.clientMut.Lock()
+	client := g.client
+	g.clientMut.Unlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	var tables []string
+	rows := map[string][]bigQueryRow{}
+	msg.Iter(func(i int, part types.Part) error {
+		jObj, err := part.JSON()
+		if err != nil {
+			g.mJSONErr.Incr(1)
+			g.log.Errorf("Failed to parse message as JSON: %v\n", err)
+			return nil
+		}
+		obj, ok := jObj.(map[string]interface{})
+		if !ok {
+			g.mJSONErr.Incr(1)
+			g.log.Errorf("Failed to insert message: not a JSON object\n")
+			return nil
+		}
+
+		lMsg := message.Lock(msg, i)
+		row := bigQueryRow{
+			row: make(map[string]bigquery.Value, len(obj)),
+		}
+		for k, v := range obj {
+			row.row[k] = v
+		}
+		if g.insertIDStr != nil {
+			row.insertID = g.insertIDStr.Get(lMsg)
+		}
+
+		table := g.tableStr.Get(lMsg)
+		if _, exists := rows[table]; !exists {
+			tables = append(tables, table)
+		}
+		rows[table] = append(rows[table], row)
+		return nil
+	})
+
+	for _, table := range tables {
+		inserter := client.Dataset(g.conf.Dataset).Table(table).Inserter()
+		inserter.SkipInvalidRows = g.conf.SkipInvalidRows
+		inserter.IgnoreUnknownValues = g.conf.IgnoreUnknownValues
+		inserter.TableTemplateSuffix = g.conf.TemplateSuffix
+
+		ctx, cancel := g.ctx()
+		err := inserter.Put(ctx, rows[table])
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to insert rows into table '%v': %v", table, err)
+		}
+		g.mRows.Incr(int64(len(rows[table])))
+	}
+	return nil
+}
+
+// CloseAsync shuts down the GCP BigQuery writer and stops processing messages.
+func (g *GCPBigQuery) CloseAsync() {
+	g.clientMut.Lock()
+	if g.client != nil {
+		g.client.Close()
+		g.client = nil
+	}
+	g.clientMut.Unlock()
+}
+
+// WaitForClose blocks until the GCP BigQuery writer has closed down.
+func (g *GCPBigQuery) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
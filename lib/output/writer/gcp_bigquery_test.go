@@ -0,0 +1,165 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"google.golang.org/api/option"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGCPBigQueryBadConfig(t *testing.T) {
+	conf := NewGCPBigQueryConfig()
+	conf.Dataset = "foo"
+	conf.Table = "bar"
+	if _, err := NewGCPBigQuery(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing project")
+	}
+}
+
+func TestGCPBigQueryWrite(t *testing.T) {
+	type insertReq struct {
+		Rows []struct {
+			InsertID string                 `json:"insertId"`
+			JSON     map[string]interface{} `json:"json"`
+		} `json:"rows"`
+		SkipInvalidRows bool `json:"skipInvalidRows"`
+	}
+
+	var reqMut sync.Mutex
+	inserts := map[string]insertReq{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/insertAll") {
+			w.Write([]byte(`{}`))
+			return
+		}
+		var req insertReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		reqMut.Lock()
+		inserts[r.URL.Path] = req
+		reqMut.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	conf := NewGCPBigQueryConfig()
+	conf.ProjectID = "proj"
+	conf.Dataset = "ds"
+	conf.Table = `events$${!metadata:day}`
+	conf.InsertID = "${!json_field:id}"
+	conf.SkipInvalidRows = true
+
+	g, err := NewGCPBigQuery(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.clientOpts = []option.ClientOption{
+		option.WithEndpoint(ts.URL + "/"),
+		option.WithoutAuthentication(),
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"a","value":1}`),
+		[]byte(`{"id":"b","value":2}`),
+		[]byte(`not json`),
+		[]byte(`{"id":"c","nested":{"value":3}}`),
+	})
+	msg.Get(0).Metadata().Set("day", "20190101")
+	msg.Get(1).Metadata().Set("day", "20190102")
+	msg.Get(3).Metadata().Set("day", "20190101")
+	if err = g.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	first := inserts["/projects/proj/datasets/ds/tables/events$20190101/insertAll"]
+	if exp, act := 2, len(first.Rows); exp != act {
+		t.Fatalf("Wrong count of rows: %v != %v", act, exp)
+	}
+	if !first.SkipInvalidRows {
+		t.Error("Expected skip invalid rows")
+	}
+	if exp, act := "a", first.Rows[0].InsertID; exp != act {
+		t.Errorf("Wrong insert ID: %v != %v", act, exp)
+	}
+	if exp, act := map[string]interface{}{
+		"id":     "c",
+		"nested": map[string]interface{}{"value": float64(3)},
+	}, first.Rows[1].JSON; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong row: %v != %v", act, exp)
+	}
+
+	second := inserts["/projects/proj/datasets/ds/tables/events$20190102/insertAll"]
+	if exp, act := 1, len(second.Rows); exp != act {
+		t.Fatalf("Wrong count of rows: %v != %v", act, exp)
+	}
+	if exp, act := "b", second.Rows[0].InsertID; exp != act {
+		t.Errorf("Wrong insert ID: %v != %v", act, exp)
+	}
+}
+
+func TestGCPBigQueryInsertErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/insertAll") {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"insertErrors":[{"index":0,"errors":[{"reason":"invalid","message":"no such field"}]}]}`))
+	}))
+	defer ts.Close()
+
+	conf := NewGCPBigQueryConfig()
+	conf.ProjectID = "proj"
+	conf.Dataset = "ds"
+	conf.Table = "events"
+
+	g, err := NewGCPBigQuery(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.clientOpts = []option.ClientOption{
+		option.WithEndpoint(ts.URL + "/"),
+		option.WithoutAuthentication(),
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Write(message.New([][]byte{[]byte(`{"foo":"bar"}`)})); err == nil {
+		t.Error("Expected error from insert errors")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,228 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"google.golang.org/api/googleapi"
+)
+
+//------------------------------------------------------------------------------
+
+// GCPCloudStorageConfig contains configuration fields for the GCP Cloud Storage
+// output type.
+type GCPCloudStorageConfig struct {
+	Bucket      string `json:"bucket" yaml:"bucket"`
+	Path        string `json:"path" yaml:"path"`
+	ContentType string `json:"content_type" yaml:"content_type"`
+	BatchCodec  string `json:"batch_codec" yaml:"batch_codec"`
+	Compression string `json:"compression" yaml:"compression"`
+	KMSKeyName  string `json:"kms_key_name" yaml:"kms_key_name"`
+	ChunkSize   int    `json:"chunk_size" yaml:"chunk_size"`
+	Timeout     string `json:"timeout" yaml:"timeout"`
+}
+
+// NewGCPCloudStorageConfig creates a new GCPCloudStorageConfig with default
+// values.
+func NewGCPCloudStorageConfig() GCPCloudStorageConfig {
+	return GCPCloudStorageConfig{
+		Bucket:      "",
+		Path:        "${!count:files}-${!timestamp_unix_nano}.txt",
+		ContentType: "application/octet-stream",
+		BatchCodec:  "none",
+		Compression: "none",
+		KMSKeyName:  "",
+		ChunkSize:   googleapi.DefaultUploadChunkSize,
+		Timeout:     "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// gcsUploader is the subset of cloud storage operations used by the output.
+type gcsUploader interface {
+	Upload(ctx context.Context, name string, body []byte) error
+	Close() error
+}
+
+type gcsClientUploader struct {
+	client *storage.Client
+	bucket string
+	conf   GCPCloudStorageConfig
+}
+
+func (g *gcsClientUploader) Upload(ctx context.Context, name string, body []byte) error {
+	w := g.client.Bucket(g.bucket).Object(name).NewWriter(ctx)
+	w.ContentType = g.conf.ContentType
+	w.KMSKeyName = g.conf.KMSKeyName
+	w.ChunkSize = g.conf.ChunkSize
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (g *gcsClientUploader) Close() error {
+	return g.client.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// GCPCloudStorage is a benthos writer.Type implementation that writes messages
+// as objects to a GCP Cloud Storage bucket.
+type GCPCloudStorage struct {
+	conf GCPCloudStorageConfig
+
+	path    *text.InterpolatedString
+	timeout time.Duration
+
+	uploaderMut sync.RWMutex
+	uploader    gcsUploader
+	newUploader func(ctx context.Context) (gcsUploader, error)
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewGCPCloudStorage creates a new GCP Cloud Storage bucket writer.Type.
+func NewGCPCloudStorage(
+	conf GCPCloudStorageConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*GCPCloudStorage, error) {
+	if len(conf.Bucket) == 0 {
+		return nil, errors.New("a bucket must be specified")
+	}
+	if err := checkObjectCodec(conf.BatchCodec, conf.Compression); err != nil {
+		return nil, err
+	}
+	g := &GCPCloudStorage{
+		conf:  conf,
+		path:  text.NewInterpolatedString(conf.Path),
+		log:   log,
+		stats: stats,
+	}
+	if len(conf.Timeout) > 0 {
+		var err error
+		if g.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	g.newUploader = func(ctx context.Context) (gcsUploader, error) {
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &gcsClientUploader{client: client, bucket: conf.Bucket, conf: conf}, nil
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GCPCloudStorage) ctx() (context.Context, context.CancelFunc) {
+	if g.timeout > 0 {
+		return context.WithTimeout(context.Background(), g.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Connect attempts to create a client for the target bucket.
+func (g *GCPCloudStorage) Connect() error {
+	g.uploaderMut.Lock()
+	defer g.uploaderMut.Unlock()
+	if g.uploader != nil {
+		return nil
+	}
+
+	uploader, err := g.newUploader(context.Background())
+	if err != nil {
+		return err
+	}
+
+	g.log.Infof("Uploading message parts as objects to GCP Cloud Storage bucket: %v\n", g.conf.Bucket)
+	g.uploader = uploader
+	return nil
+}
+
+// Write attempts to write message contents to the target bucket as objects.
+func (g *GCPCloudStorage) Write(msg types.Message) error {
+	g.uploaderMut.RLock()
+	uploader := g.uploader
+	g.uploaderMut.RUnlock()
+	if uploader == nil {
+		return types.ErrNotConnected
+	}
+
+	ctx, done := g.ctx()
+	defer done()
+
+	if g.conf.BatchCodec != "none" {
+		parts := make([]types.Part, 0, msg.Len())
+		msg.Iter(func(i int, p types.Part) error {
+			parts = append(parts, p)
+			return nil
+		})
+		body, err := encodeObject(g.conf.BatchCodec, g.conf.Compression, parts)
+		if err != nil {
+			return err
+		}
+		return uploader.Upload(ctx, g.path.Get(message.Lock(msg, 0)), body)
+	}
+
+	return msg.Iter(func(i int, p types.Part) error {
+		body, err := encodeObject(g.conf.BatchCodec, g.conf.Compression, []types.Part{p})
+		if err != nil {
+			return err
+		}
+		return uploader.Upload(ctx, g.path.Get(message.Lock(msg, i)), body)
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (g *GCPCloudStorage) CloseAsync() {
+	g.uploaderMut.Lock()
+	if g.uploader != nil {
+		g.uploader.Close()
+		g.uploader = nil
+	}
+	g.uploaderMut.Unlock()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (g *GCPCloudStorage) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,152 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type mockGCSUploader struct {
+	objects map[string][]byte
+	closed  bool
+}
+
+func (m *mockGCSUploader) Upload(ctx context.Context, name string, body []byte) error {
+	m.objects[name] = body
+	return nil
+}
+
+func (m *mockGCSUploader) Close() error {
+	m.closed = true
+	return nil
+}
+
+func testGCSWriter(t *testing.T, conf GCPCloudStorageConfig) (*GCPCloudStorage, *mockGCSUploader) {
+	t.Helper()
+
+	conf.Bucket = "bucket"
+	g, err := NewGCPCloudStorage(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader := &mockGCSUploader{objects: map[string][]byte{}}
+	g.newUploader = func(ctx context.Context) (gcsUploader, error) {
+		return uploader, nil
+	}
+	return g, uploader
+}
+
+func TestGCPCloudStoragePerPart(t *testing.T) {
+	conf := NewGCPCloudStorageConfig()
+	conf.Path = "${!metadata:dir}/${!content}.txt"
+
+	g, uploader := testGCSWriter(t, conf)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("dir", "a")
+	msg.Get(1).Metadata().Set("dir", "b")
+	if err := g.Write(msg); err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error: %v", err)
+	}
+	if err := g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]string{
+		"a/foo.txt": "foo",
+		"b/bar.txt": "bar",
+	}
+	if len(uploader.objects) != len(exp) {
+		t.Errorf("Wrong count of objects: %v", len(uploader.objects))
+	}
+	for k, v := range exp {
+		if act := string(uploader.objects[k]); act != v {
+			t.Errorf("Wrong contents for '%v': %v != %v", k, act, v)
+		}
+	}
+
+	g.CloseAsync()
+	if !uploader.closed {
+		t.Error("Expected uploader to be closed")
+	}
+}
+
+func TestGCPCloudStorageLinesGzip(t *testing.T) {
+	conf := NewGCPCloudStorageConfig()
+	conf.Path = "${!metadata:dir}.jsonl.gz"
+	conf.BatchCodec = "lines"
+	conf.Compression = "gzip"
+
+	g, uploader := testGCSWriter(t, conf)
+	if err := g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("dir", "first")
+	if err := g.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 1, len(uploader.objects); exp != act {
+		t.Fatalf("Wrong count of objects: %v != %v", act, exp)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(uploader.objects["first.jsonl.gz"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "foo\nbar", string(contents); exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+}
+
+func TestGCPCloudStorageBadConfig(t *testing.T) {
+	conf := NewGCPCloudStorageConfig()
+	if _, err := NewGCPCloudStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing bucket")
+	}
+
+	conf.Bucket = "foo"
+	conf.Compression = "lz4"
+	if _, err := NewGCPCloudStorage(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad compression")
+	}
+}
+
+//------------------------------------------------------------------------------
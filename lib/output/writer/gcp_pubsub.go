@@ -1,4 +1,4 @@
-// Copyright (c) 2018 Ashley Jeffs
+// Copyright (c) 2019 Ashley Jeffs
 //
 // Permission is hereby granted, free of charge, to any person obtaining a copy
 // of this software and associated documentation files (the "Software"), to deal
@@ -15,7 +15,7 @@
 // FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
 // AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
 // LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
-// OUT OF OR IN CONNETION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
 package writer
@@ -28,37 +28,115 @@ import (
 
 	"cloud.google.com/go/pubsub"
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
 )
 
 //------------------------------------------------------------------------------
 
 // GCPPubSubConfig contains configuration fields for the output GCPPubSub type.
 type GCPPubSubConfig struct {
-	ProjectID string `json:"project" yaml:"project"`
-	TopicID   string `json:"topic" yaml:"topic"`
+	ProjectID              string `json:"project" yaml:"project"`
+	TopicID                string `json:"topic" yaml:"topic"`
+	OrderingKey            string `json:"ordering_key" yaml:"ordering_key"`
+	MaxOutstandingMessages int    `json:"max_outstanding_messages" yaml:"max_outstanding_messages"`
+	MaxOutstandingBytes    int    `json:"max_outstanding_bytes" yaml:"max_outstanding_bytes"`
 }
 
 // NewGCPPubSubConfig creates a new Config with default values.
 func NewGCPPubSubConfig() GCPPubSubConfig {
 	return GCPPubSubConfig{
-		ProjectID: "",
-		TopicID:   "",
+		ProjectID:              "",
+		TopicID:                "",
+		OrderingKey:            "",
+		MaxOutstandingMessages: 1000,
+		MaxOutstandingBytes:    1000000,
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// pubsubTopic is the subset of Pub/Sub topic operations used by the output.
+type pubsubTopic interface {
+	Publish(ctx context.Context, msg *pubsub.Message) pubsubResult
+	ResumePublish(orderingKey string)
+	Stop()
+}
+
+// pubsubResult is the eventual result of publishing a message.
+type pubsubResult interface {
+	Get(ctx context.Context) (string, error)
+}
+
+// pubsubTopicWrapper adapts a *pubsub.Topic to the pubsubTopic interface.
+type pubsubTopicWrapper struct {
+	*pubsub.Topic
+}
+
+func (t pubsubTopicWrapper) Publish(ctx context.Context, msg *pubsub.Message) pubsubResult {
+	return t.Topic.Publish(ctx, msg)
+}
+
+//------------------------------------------------------------------------------
+
+// pubsubFlowControl limits the number of messages and bytes that are published
+// but not yet acknowledged, blocking further publishes until capacity frees up.
+type pubsubFlowControl struct {
+	maxMessages int
+	maxBytes    int
+
+	messages int
+	bytes    int
+	cond     *sync.Cond
+}
+
+func newPubSubFlowControl(maxMessages, maxBytes int) *pubsubFlowControl {
+	return &pubsubFlowControl{
+		maxMessages: maxMessages,
+		maxBytes:    maxBytes,
+		cond:        sync.NewCond(&sync.Mutex{}),
+	}
+}
+
+// acquire blocks until a message of a given size can be published. A message
+// larger than the byte limit is allowed when nothing else is outstanding.
+func (f *pubsubFlowControl) acquire(size int) {
+	f.cond.L.Lock()
+	for f.messages > 0 &&
+		((f.maxMessages > 0 && f.messages+1 > f.maxMessages) ||
+			(f.maxBytes > 0 && f.bytes+size > f.maxBytes)) {
+		f.cond.Wait()
+	}
+	f.messages++
+	f.bytes += size
+	f.cond.L.Unlock()
+}
+
+// release frees up capacity taken by a message once it has been published.
+func (f *pubsubFlowControl) release(size int) {
+	f.cond.L.Lock()
+	f.messages--
+	f.bytes -= size
+	f.cond.L.Unlock()
+	f.cond.Broadcast()
+}
+
+//------------------------------------------------------------------------------
+
 // GCPPubSub is a benthos writer.Type implementation that writes messages to a
 // GCP Pub/Sub topic.
 type GCPPubSub struct {
 	conf GCPPubSubConfig
 
 	client   *pubsub.Client
-	topic    *pubsub.Topic
+	topic    pubsubTopic
 	topicMut sync.Mutex
 
+	orderingKey *text.InterpolatedString
+	flow        *pubsubFlowControl
+
 	log   log.Modular
 	stats metrics.Type
 }
@@ -75,12 +153,26 @@ func NewGCPPubSub(
 	if err != nil {
 		return nil, err
 	}
-	return &GCPPubSub{
+	return newGCPPubSub(conf, client, log, stats), nil
+}
+
+func newGCPPubSub(
+	conf GCPPubSubConfig,
+	client *pubsub.Client,
+	log log.Modular,
+	stats metrics.Type,
+) *GCPPubSub {
+	c := &GCPPubSub{
 		conf:   conf,
 		log:    log,
 		client: client,
 		stats:  stats,
-	}, nil
+		flow:   newPubSubFlowControl(conf.MaxOutstandingMessages, conf.MaxOutstandingBytes),
+	}
+	if len(conf.OrderingKey) > 0 {
+		c.orderingKey = text.NewInterpolatedString(conf.OrderingKey)
+	}
+	return c
 }
 
 // Connect attempts to establish a connection to the target GCP Pub/Sub topic.
@@ -92,6 +184,7 @@ func (c *GCPPubSub) Connect() error {
 	}
 
 	topic := c.client.Topic(c.conf.TopicID)
+	topic.EnableMessageOrdering = c.orderingKey != nil
 
 	existsCtx, existsCancel := context.WithTimeout(context.Background(), time.Second*5)
 	exists, err := topic.Exists(existsCtx)
@@ -103,23 +196,52 @@ func (c *GCPPubSub) Connect() error {
 		return fmt.Errorf("topic '%v' does not exist", c.conf.TopicID)
 	}
 
-	c.topic = topic
+	c.topic = pubsubTopicWrapper{topic}
 	c.log.Infof("Sending GCP Cloud Pub/Sub messages to project '%v' and topic '%v'\n", c.conf.ProjectID, c.conf.TopicID)
 	return nil
 }
 
+// publishSequence publishes a sequence of messages, waiting for each to be
+// acknowledged before the next is published. When the messages have an
+// ordering key the remaining messages of the sequence are abandoned after the
+// first failure, and publishing of the key, which the client pauses after a
+// failure, is resumed so that the sequence can be retried.
+func (c *GCPPubSub) publishSequence(ctx context.Context, topic pubsubTopic, gmsgs []*pubsub.Message) error {
+	for i, gmsg := range gmsgs {
+		size := len(gmsg.Data)
+		c.flow.acquire(size)
+		_, err := topic.Publish(ctx, gmsg).Get(ctx)
+		c.flow.release(size)
+		if err != nil {
+			if len(gmsg.OrderingKey) == 0 {
+				return err
+			}
+			topic.ResumePublish(gmsg.OrderingKey)
+			if i < len(gmsgs)-1 {
+				return fmt.Errorf("%v, abandoned %v subsequent messages with the same ordering key", err, len(gmsgs)-i-1)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 // Write attempts to write message contents to a target topic.
 func (c *GCPPubSub) Write(msg types.Message) error {
 	c.topicMut.Lock()
 	topic := c.topic
 	c.topicMut.Unlock()
 
-	if c.topic == nil {
+	if topic == nil {
 		return types.ErrNotConnected
 	}
 
 	ctx := context.Background()
-	results := make([]*pubsub.PublishResult, msg.Len())
+
+	// Messages without an ordering key are each published independently,
+	// whereas messages sharing a key are published in sequence.
+	var sequences [][]*pubsub.Message
+	keyed := map[string]int{}
 
 	msg.Iter(func(i int, part types.Part) error {
 		attr := map[string]string{}
@@ -133,18 +255,44 @@ func (c *GCPPubSub) Write(msg types.Message) error {
 		if len(attr) > 0 {
 			gmsg.Attributes = attr
 		}
-		results[i] = topic.Publish(ctx, gmsg)
+
+		var key string
+		if c.orderingKey != nil {
+			key = c.orderingKey.Get(message.Lock(msg, i))
+		}
+		if len(key) == 0 {
+			sequences = append(sequences, []*pubsub.Message{gmsg})
+			return nil
+		}
+		gmsg.OrderingKey = key
+		if j, exists := keyed[key]; exists {
+			sequences[j] = append(sequences[j], gmsg)
+			return nil
+		}
+		keyed[key] = len(sequences)
+		sequences = append(sequences, []*pubsub.Message{gmsg})
 		return nil
 	})
 
-	var errs []error
-	for _, r := range results {
-		if _, err := r.Get(ctx); err != nil {
-			errs = append(errs, err)
+	errs := make([]error, len(sequences))
+	wg := sync.WaitGroup{}
+	wg.Add(len(sequences))
+	for i, seq := range sequences {
+		go func(i int, seq []*pubsub.Message) {
+			errs[i] = c.publishSequence(ctx, topic, seq)
+			wg.Done()
+		}(i, seq)
+	}
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
 		}
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("failed to send messages: %v", errs)
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to send messages: %v", failed)
 	}
 	return nil
 }
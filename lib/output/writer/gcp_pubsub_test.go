@@ -0,0 +1,217 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+type mockPubSubResult struct {
+	err error
+}
+
+func (m mockPubSubResult) Get(ctx context.Context) (string, error) {
+	return "", m.err
+}
+
+type mockPubSubTopic struct {
+	fn func(msg *pubsub.Message) error
+
+	resumedMut sync.Mutex
+	resumed    []string
+}
+
+func (m *mockPubSubTopic) Publish(ctx context.Context, msg *pubsub.Message) pubsubResult {
+	return mockPubSubResult{err: m.fn(msg)}
+}
+
+func (m *mockPubSubTopic) ResumePublish(orderingKey string) {
+	m.resumedMut.Lock()
+	m.resumed = append(m.resumed, orderingKey)
+	m.resumedMut.Unlock()
+}
+
+func (m *mockPubSubTopic) Stop() {}
+
+func TestGCPPubSubAttributes(t *testing.T) {
+	c := newGCPPubSub(NewGCPPubSubConfig(), nil, log.Noop(), metrics.Noop())
+
+	var resMut sync.Mutex
+	attrs := map[string]map[string]string{}
+	c.topic = &mockPubSubTopic{
+		fn: func(msg *pubsub.Message) error {
+			resMut.Lock()
+			attrs[string(msg.Data)] = msg.Attributes
+			resMut.Unlock()
+			return nil
+		},
+	}
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("a", "1")
+	if err := c.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]map[string]string{
+		"foo": {"a": "1"},
+		"bar": nil,
+	}
+	if !reflect.DeepEqual(exp, attrs) {
+		t.Errorf("Wrong attributes: %v != %v", attrs, exp)
+	}
+}
+
+func TestGCPPubSubOrderingKey(t *testing.T) {
+	conf := NewGCPPubSubConfig()
+	conf.OrderingKey = "${!metadata:key}"
+	c := newGCPPubSub(conf, nil, log.Noop(), metrics.Noop())
+
+	var resMut sync.Mutex
+	published := map[string][]string{}
+	topic := &mockPubSubTopic{
+		fn: func(msg *pubsub.Message) error {
+			// Delay the first message in order to detect reordering.
+			if string(msg.Data) == "a1" {
+				<-time.After(time.Millisecond * 10)
+			}
+			resMut.Lock()
+			key := msg.OrderingKey
+			published[key] = append(published[key], string(msg.Data))
+			resMut.Unlock()
+			if string(msg.Data) == "b1" {
+				return errors.New("nope")
+			}
+			return nil
+		},
+	}
+	c.topic = topic
+
+	msg := message.New(nil)
+	for _, p := range [][2]string{
+		{"a1", "a"}, {"b1", "b"}, {"a2", "a"}, {"b2", "b"}, {"a3", "a"},
+	} {
+		part := message.NewPart([]byte(p[0]))
+		part.Metadata().Set("key", p[1])
+		msg.Append(part)
+	}
+
+	err := c.Write(msg)
+	if err == nil || !strings.Contains(err.Error(), "abandoned 1 subsequent messages") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	exp := map[string][]string{
+		"a": {"a1", "a2", "a3"},
+		"b": {"b1"},
+	}
+	if !reflect.DeepEqual(exp, published) {
+		t.Errorf("Wrong published messages: %v != %v", published, exp)
+	}
+	if exp := []string{"b"}; !reflect.DeepEqual(exp, topic.resumed) {
+		t.Errorf("Wrong resumed keys: %v != %v", topic.resumed, exp)
+	}
+}
+
+func TestGCPPubSubFlowControl(t *testing.T) {
+	conf := NewGCPPubSubConfig()
+	conf.MaxOutstandingMessages = 2
+	conf.MaxOutstandingBytes = 0
+	c := newGCPPubSub(conf, nil, log.Noop(), metrics.Noop())
+
+	var resMut sync.Mutex
+	outstanding, maxOutstanding := 0, 0
+	var published []string
+	c.topic = &mockPubSubTopic{
+		fn: func(msg *pubsub.Message) error {
+			resMut.Lock()
+			outstanding++
+			if outstanding > maxOutstanding {
+				maxOutstanding = outstanding
+			}
+			published = append(published, string(msg.Data))
+			resMut.Unlock()
+
+			<-time.After(time.Millisecond * 5)
+
+			resMut.Lock()
+			outstanding--
+			resMut.Unlock()
+			return nil
+		},
+	}
+
+	if err := c.Write(message.New([][]byte{
+		[]byte("1"), []byte("2"), []byte("3"), []byte("4"), []byte("5"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(published)
+	if exp, act := []string{"1", "2", "3", "4", "5"}, published; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong published messages: %v != %v", act, exp)
+	}
+	if maxOutstanding > 2 {
+		t.Errorf("Too many outstanding messages: %v", maxOutstanding)
+	}
+}
+
+func TestGCPPubSubFlowControlBytes(t *testing.T) {
+	f := newPubSubFlowControl(0, 10)
+
+	// Messages larger than the limit are allowed when nothing is outstanding.
+	f.acquire(20)
+
+	acquired := make(chan struct{})
+	go func() {
+		f.acquire(5)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected acquire to block")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	f.release(20)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for acquire")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,270 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+// GraphiteConfig contains configuration fields for the Graphite output type.
+type GraphiteConfig struct {
+	Address       string `json:"address" yaml:"address"`
+	Path          string `json:"path" yaml:"path"`
+	ValuePath     string `json:"value_path" yaml:"value_path"`
+	TimestampPath string `json:"timestamp_path" yaml:"timestamp_path"`
+	Timeout       string `json:"timeout" yaml:"timeout"`
+}
+
+// NewGraphiteConfig creates a new GraphiteConfig with default values.
+func NewGraphiteConfig() GraphiteConfig {
+	return GraphiteConfig{
+		Address:       "localhost:2003",
+		Path:          "benthos",
+		ValuePath:     "value",
+		TimestampPath: "",
+		Timeout:       "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Graphite is a writer type that converts JSON messages into metrics of the
+// Graphite plaintext protocol and writes them over TCP.
+type Graphite struct {
+	conf    GraphiteConfig
+	path    *text.InterpolatedString
+	timeout time.Duration
+
+	conn    net.Conn
+	connMut sync.Mutex
+
+	log   log.Modular
+	stats metrics.Type
+
+	mMetrics  metrics.StatCounter
+	mParseErr metrics.StatCounter
+}
+
+// NewGraphite creates a new Graphite writer type.
+func NewGraphite(conf GraphiteConfig, log log.Modular, stats metrics.Type) (*Graphite, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("a metric path must be specified")
+	}
+	if len(conf.ValuePath) == 0 {
+		return nil, errors.New("a value path must be specified")
+	}
+	g := &Graphite{
+		conf:      conf,
+		path:      text.NewInterpolatedString(conf.Path),
+		log:       log,
+		stats:     stats,
+		mMetrics:  stats.GetCounter("metrics"),
+		mParseErr: stats.GetCounter("error.parse"),
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if g.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a TCP connection to the Graphite server.
+func (g *Graphite) Connect() error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", g.conf.Address, g.timeout)
+	if err != nil {
+		return err
+	}
+	g.conn = conn
+
+	g.log.Infof("Sending Graphite metrics to: %v\n", g.conf.Address)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// graphitePathSanitiser replaces characters that delimit the fields of a
+// plaintext protocol line.
+var graphitePathSanitiser = strings.NewReplacer(" ", "_", "\t", "_", "\n", "_", "\r", "_")
+
+// graphiteValue formats a JSON value as a metric value.
+func graphiteValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		if t {
+			return "1", nil
+		}
+		return "0", nil
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return "", fmt.Errorf("value is not a number: %v", t)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case nil:
+		return "", errors.New("value not found")
+	}
+	return "", fmt.Errorf("unsupported value type: %T", v)
+}
+
+// graphiteTimestamp extracts the unix timestamp of a metric from a JSON value,
+// which is either a number of seconds or an RFC 3339 string.
+func graphiteTimestamp(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case float64:
+		return int64(t), nil
+	case string:
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return 0, err
+		}
+		return ts.Unix(), nil
+	}
+	return 0, fmt.Errorf("unsupported timestamp type: %T", v)
+}
+
+// line creates a plaintext protocol metric from a message part.
+func (g *Graphite) line(msg types.Message, index int, now int64) ([]byte, error) {
+	jObj, err := msg.Get(index).JSON()
+	if err != nil {
+		return nil, err
+	}
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return nil, err
+	}
+
+	path := graphitePathSanitiser.Replace(g.path.Get(message.Lock(msg, index)))
+	if len(path) == 0 {
+		return nil, errors.New("metric path is empty")
+	}
+	value, err := graphiteValue(gObj.Path(g.conf.ValuePath).Data())
+	if err != nil {
+		return nil, err
+	}
+	ts := now
+	if len(g.conf.TimestampPath) > 0 {
+		if v := gObj.Path(g.conf.TimestampPath).Data(); v != nil {
+			if ts, err = graphiteTimestamp(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var line bytes.Buffer
+	line.WriteString(path)
+	line.WriteByte(' ')
+	line.WriteString(value)
+	line.WriteByte(' ')
+	line.WriteString(strconv.FormatInt(ts, 10))
+	line.WriteByte('\n')
+	return line.Bytes(), nil
+}
+
+// Write attempts to write each part of a message as a metric with a single
+// write.
+func (g *Graphite) Write(msg types.Message) error {
+	g.connMut.Lock()
+	conn := g.conn
+	g.connMut.Unlock()
+
+	if conn == nil {
+		return types.ErrNotConnected
+	}
+
+	var body bytes.Buffer
+	lines := 0
+	now := time.Now().Unix()
+	for i := 0; i < msg.Len(); i++ {
+		line, err := g.line(msg, i, now)
+		if err != nil {
+			g.mParseErr.Incr(1)
+			g.log.Errorf("Failed to create metric from message: %v\n", err)
+			continue
+		}
+		body.Write(line)
+		lines++
+	}
+	if lines == 0 {
+		return nil
+	}
+
+	if g.timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(g.timeout))
+	}
+	if _, err := conn.Write(body.Bytes()); err != nil {
+		g.log.Errorf("Failed to write metrics: %v\n", err)
+		g.connMut.Lock()
+		if g.conn == conn {
+			g.conn.Close()
+			g.conn = nil
+		}
+		g.connMut.Unlock()
+		return types.ErrNotConnected
+	}
+	g.mMetrics.Incr(int64(lines))
+	return nil
+}
+
+// CloseAsync shuts down the Graphite writer and stops processing messages.
+func (g *Graphite) CloseAsync() {
+	g.connMut.Lock()
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+	g.connMut.Unlock()
+}
+
+// WaitForClose blocks until the Graphite writer has closed down.
+func (g *Graphite) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
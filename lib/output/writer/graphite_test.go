@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGraphiteBadConfig(t *testing.T) {
+	conf := NewGraphiteConfig()
+	conf.Path = ""
+	if _, err := NewGraphite(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from empty path")
+	}
+
+	conf = NewGraphiteConfig()
+	conf.Timeout = "not a duration"
+	if _, err := NewGraphite(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad timeout")
+	}
+}
+
+func TestGraphiteLines(t *testing.T) {
+	conf := NewGraphiteConfig()
+	conf.Path = "servers.${!json_field:host}.${!metadata:metric}"
+	conf.ValuePath = "stats.value"
+	conf.TimestampPath = "ts"
+
+	g, err := NewGraphite(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input  string
+		output string
+		err    bool
+	}{
+		{
+			input:  `{"host":"foo","stats":{"value":1.5},"ts":1500000000}`,
+			output: "servers.foo.cpu 1.5 1500000000\n",
+		},
+		{
+			input:  `{"host":"bar baz","stats":{"value":"20"},"ts":"2017-07-14T02:40:00Z"}`,
+			output: "servers.bar_baz.cpu 20 1500000000\n",
+		},
+		{
+			input:  `{"host":"foo","stats":{"value":true}}`,
+			output: "servers.foo.cpu 1 1000\n",
+		},
+		{
+			input: `{"host":"foo","stats":{"value":"nope"}}`,
+			err:   true,
+		},
+		{
+			input: `{"host":"foo"}`,
+			err:   true,
+		},
+		{
+			input: `not json`,
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		msg := message.New([][]byte{[]byte(test.input)})
+		msg.Get(0).Metadata().Set("metric", "cpu")
+		line, err := g.line(msg, 0, 1000)
+		if test.err {
+			if err == nil {
+				t.Errorf("Expected error from input: %v", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error from input %v: %v", test.input, err)
+			continue
+		}
+		if act := string(line); test.output != act {
+			t.Errorf("Wrong line: %q != %q", act, test.output)
+		}
+	}
+}
+
+func TestGraphiteWrite(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	linesChan := make(chan string)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				scanner := bufio.NewScanner(c)
+				for scanner.Scan() {
+					linesChan <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+
+	conf := NewGraphiteConfig()
+	conf.Address = ln.Addr().String()
+	conf.Path = "foo.${!json_field:name}"
+	conf.TimestampPath = "ts"
+
+	g, err := NewGraphite(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = g.Write(message.New(nil)); err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error, received: %v", err)
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		g.CloseAsync()
+		if err := g.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = g.Write(message.New([][]byte{
+		[]byte(`{"name":"a","value":1,"ts":10}`),
+		[]byte(`{"name":"b"}`),
+		[]byte(`{"name":"c","value":3,"ts":30}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for i := 0; i < 2; i++ {
+		select {
+		case line := <-linesChan:
+			lines = append(lines, line)
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for metrics")
+		}
+	}
+	if exp := []string{"foo.a 1 10", "foo.c 3 30"}; !reflect.DeepEqual(exp, lines) {
+		t.Errorf("Wrong lines: %v != %v", strings.Join(lines, ","), strings.Join(exp, ","))
+	}
+}
+
+//------------------------------------------------------------------------------
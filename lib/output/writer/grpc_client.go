@@ -0,0 +1,282 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+//------------------------------------------------------------------------------
+
+// GRPCClientConfig contains configuration fields for the GRPCClient output
+// type.
+type GRPCClientConfig struct {
+	Address  string            `json:"address" yaml:"address"`
+	Method   string            `json:"method" yaml:"method"`
+	RPCType  string            `json:"rpc_type" yaml:"rpc_type"`
+	Timeout  string            `json:"timeout" yaml:"timeout"`
+	Metadata map[string]string `json:"metadata" yaml:"metadata"`
+	TLS      btls.Config       `json:"tls" yaml:"tls"`
+}
+
+// NewGRPCClientConfig creates a new GRPCClientConfig with default values.
+func NewGRPCClientConfig() GRPCClientConfig {
+	return GRPCClientConfig{
+		Address:  "localhost:50051",
+		Method:   "",
+		RPCType:  "unary",
+		Timeout:  "5s",
+		Metadata: map[string]string{},
+		TLS:      btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// rawCodec is a gRPC codec that passes message payloads through untouched,
+// which allows us to send pre-serialised messages without knowledge of their
+// schema.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch t := v.(type) {
+	case []byte:
+		return t, nil
+	case *[]byte:
+		return *t, nil
+	}
+	return nil, fmt.Errorf("unsupported message type for raw codec: %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unsupported message type for raw codec: %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "raw"
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCClient is an output type that invokes a gRPC method for each message or
+// batch.
+type GRPCClient struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf     GRPCClientConfig
+	streamed bool
+	timeout  time.Duration
+	tlsConf  *tls.Config
+	metadata map[string]*text.InterpolatedString
+
+	conn    *grpc.ClientConn
+	connMut sync.RWMutex
+}
+
+// NewGRPCClient creates a new GRPCClient output type.
+func NewGRPCClient(
+	conf GRPCClientConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*GRPCClient, error) {
+	g := &GRPCClient{
+		log:      log,
+		stats:    stats,
+		conf:     conf,
+		metadata: map[string]*text.InterpolatedString{},
+	}
+
+	if len(conf.Method) == 0 {
+		return nil, errors.New("a method must be specified")
+	}
+	if !strings.HasPrefix(conf.Method, "/") {
+		g.conf.Method = "/" + conf.Method
+	}
+
+	switch conf.RPCType {
+	case "unary":
+	case "client_stream":
+		g.streamed = true
+	default:
+		return nil, fmt.Errorf("rpc type not recognised: %v", conf.RPCType)
+	}
+
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if g.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	if conf.TLS.Enabled {
+		var err error
+		if g.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, v := range conf.Metadata {
+		g.metadata[strings.ToLower(k)] = text.NewInterpolatedString(v)
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a connection to the gRPC server.
+func (g *GRPCClient) Connect() error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.conn != nil {
+		return nil
+	}
+
+	opts := []grpc.DialOption{}
+	if g.tlsConf != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(g.tlsConf)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	conn, err := grpc.Dial(g.conf.Address, opts...)
+	if err != nil {
+		return err
+	}
+
+	g.log.Infof("Invoking gRPC method '%v' at: %v\n", g.conf.Method, g.conf.Address)
+	g.conn = conn
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// callContext creates a context for a call with the deadline and metadata of
+// the message part at the provided index.
+func (g *GRPCClient) callContext(msg types.Message, index int) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var done context.CancelFunc
+	if g.timeout > 0 {
+		ctx, done = context.WithTimeout(context.Background(), g.timeout)
+	} else {
+		ctx, done = context.WithCancel(context.Background())
+	}
+	if len(g.metadata) > 0 {
+		lMsg := message.Lock(msg, index)
+		md := metadata.MD{}
+		for k, v := range g.metadata {
+			md.Set(k, v.Get(lMsg))
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	return ctx, done
+}
+
+func (g *GRPCClient) invokeUnary(conn *grpc.ClientConn, msg types.Message) error {
+	return msg.Iter(func(i int, p types.Part) error {
+		ctx, done := g.callContext(msg, i)
+		defer done()
+
+		var resp []byte
+		return conn.Invoke(ctx, g.conf.Method, p.Get(), &resp, grpc.CallCustomCodec(rawCodec{}))
+	})
+}
+
+func (g *GRPCClient) invokeStream(conn *grpc.ClientConn, msg types.Message) error {
+	ctx, done := g.callContext(msg, 0)
+	defer done()
+
+	stream, err := conn.NewStream(
+		ctx, &grpc.StreamDesc{ClientStreams: true}, g.conf.Method,
+		grpc.CallCustomCodec(rawCodec{}),
+	)
+	if err != nil {
+		return err
+	}
+	if err = msg.Iter(func(i int, p types.Part) error {
+		return stream.SendMsg(p.Get())
+	}); err != nil {
+		return err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return err
+	}
+
+	var resp []byte
+	return stream.RecvMsg(&resp)
+}
+
+// Write attempts to write a message by invoking the configured gRPC method.
+// Unary calls are made for each message part, whereas client streaming calls
+// send an entire batch as a single stream.
+func (g *GRPCClient) Write(msg types.Message) error {
+	g.connMut.RLock()
+	conn := g.conn
+	g.connMut.RUnlock()
+
+	if conn == nil {
+		return types.ErrNotConnected
+	}
+
+	if g.streamed {
+		return g.invokeStream(conn, msg)
+	}
+	return g.invokeUnary(conn, msg)
+}
+
+// CloseAsync shuts down the GRPCClient output and stops processing messages.
+func (g *GRPCClient) CloseAsync() {
+	g.connMut.Lock()
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn = nil
+	}
+	g.connMut.Unlock()
+}
+
+// WaitForClose blocks until the GRPCClient output has closed down.
+func (g *GRPCClient) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
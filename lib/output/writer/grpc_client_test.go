@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+//------------------------------------------------------------------------------
+
+type grpcCall struct {
+	method   string
+	metadata []string
+	payloads []string
+}
+
+func fakeGRPCServer(t *testing.T) (string, func() []grpcCall, func()) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []grpcCall
+	var callsMut sync.Mutex
+
+	srv := grpc.NewServer(
+		grpc.CustomCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+			call := grpcCall{}
+			call.method, _ = grpc.MethodFromServerStream(stream)
+			if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+				call.metadata = md.Get("foo")
+			}
+			for {
+				var payload []byte
+				if err := stream.RecvMsg(&payload); err != nil {
+					if err == io.EOF {
+						break
+					}
+					return err
+				}
+				call.payloads = append(call.payloads, string(payload))
+			}
+			callsMut.Lock()
+			calls = append(calls, call)
+			callsMut.Unlock()
+			return stream.SendMsg([]byte("ack"))
+		}),
+	)
+	go srv.Serve(ln)
+
+	return ln.Addr().String(), func() []grpcCall {
+			callsMut.Lock()
+			defer callsMut.Unlock()
+			return append([]grpcCall{}, calls...)
+		}, func() {
+			srv.Stop()
+		}
+}
+
+func TestGRPCClientBadConfig(t *testing.T) {
+	conf := NewGRPCClientConfig()
+	if _, err := NewGRPCClient(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing method")
+	}
+
+	conf.Method = "/foo.Bar/Baz"
+	conf.RPCType = "bidi_stream"
+	if _, err := NewGRPCClient(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad rpc type")
+	}
+}
+
+func TestGRPCClientUnary(t *testing.T) {
+	addr, calls, closeFn := fakeGRPCServer(t)
+	defer closeFn()
+
+	conf := NewGRPCClientConfig()
+	conf.Address = addr
+	conf.Method = "foo.Bar/Baz"
+	conf.Metadata = map[string]string{
+		"Foo": "${!metadata:foo}",
+	}
+
+	g, err := NewGRPCClient(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		g.CloseAsync()
+		if cErr := g.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+
+	msg := message.New([][]byte{[]byte("hello"), []byte("world")})
+	msg.Get(0).Metadata().Set("foo", "first")
+	msg.Get(1).Metadata().Set("foo", "second")
+
+	if err = g.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []grpcCall{
+		{method: "/foo.Bar/Baz", metadata: []string{"first"}, payloads: []string{"hello"}},
+		{method: "/foo.Bar/Baz", metadata: []string{"second"}, payloads: []string{"world"}},
+	}
+	if act := calls(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong calls: %v != %v", act, exp)
+	}
+}
+
+func TestGRPCClientStream(t *testing.T) {
+	addr, calls, closeFn := fakeGRPCServer(t)
+	defer closeFn()
+
+	conf := NewGRPCClientConfig()
+	conf.Address = addr
+	conf.Method = "/foo.Bar/Baz"
+	conf.RPCType = "client_stream"
+	conf.Metadata = map[string]string{
+		"foo": "${!metadata:foo}",
+	}
+
+	g, err := NewGRPCClient(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		g.CloseAsync()
+		if cErr := g.WaitForClose(time.Second); cErr != nil {
+			t.Error(cErr)
+		}
+	}()
+
+	msg := message.New([][]byte{[]byte("hello"), []byte("world"), []byte("!")})
+	msg.Get(0).Metadata().Set("foo", "first")
+
+	if err = g.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []grpcCall{
+		{method: "/foo.Bar/Baz", metadata: []string{"first"}, payloads: []string{"hello", "world", "!"}},
+	}
+	if act := calls(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong calls: %v != %v", act, exp)
+	}
+}
+
+func TestGRPCClientNotConnected(t *testing.T) {
+	conf := NewGRPCClientConfig()
+	conf.Method = "/foo.Bar/Baz"
+
+	g, err := NewGRPCClient(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = g.Write(message.New([][]byte{[]byte("hello")})); err == nil {
+		t.Error("Expected error from unconnected write")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -21,9 +21,12 @@
 package writer
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/benthos/lib/util/http/client"
@@ -34,13 +37,15 @@ import (
 // HTTPClientConfig contains configuration fields for the HTTPClient output
 // type.
 type HTTPClientConfig struct {
-	client.Config `json:",inline" yaml:",inline"`
+	client.Config     `json:",inline" yaml:",inline"`
+	PropagateResponse bool `json:"propagate_response" yaml:"propagate_response"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
 func NewHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
-		Config: client.NewConfig(),
+		Config:            client.NewConfig(),
+		PropagateResponse: false,
 	}
 }
 
@@ -95,8 +100,37 @@ func (h *HTTPClient) Connect() error {
 // Write attempts to send a message to an HTTP server, this attempt may include
 // retries, and if all retries fail an error is returned.
 func (h *HTTPClient) Write(msg types.Message) error {
-	_, err := h.client.Send(msg)
-	return err
+	if !h.conf.PropagateResponse {
+		_, err := h.client.Send(msg)
+		return err
+	}
+
+	res, err := h.client.Do(msg)
+	if err != nil {
+		return err
+	}
+	resMsg, err := h.client.ParseResponse(res)
+	if err != nil {
+		return err
+	}
+
+	// An empty part is used for empty bodies in order to carry the status
+	// code and headers of the response.
+	if resMsg.Len() == 0 {
+		resMsg.Append(message.NewPart(nil))
+	}
+	resMsg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		for k, v := range res.Header {
+			if len(v) > 0 {
+				meta.Set(k, v[0])
+			}
+		}
+		meta.Set("http_status_code", strconv.Itoa(res.StatusCode))
+		return nil
+	})
+	roundtrip.AddResults(msg, resMsg)
+	return nil
 }
 
 // CloseAsync shuts down the HTTPClient output and stops processing messages.
@@ -35,6 +35,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
 )
@@ -221,4 +222,76 @@ func TestHTTPClientMultipart(t *testing.T) {
 	}
 }
 
+func TestHTTPClientPropagateResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Foo", "bar")
+		if string(b) == "empty" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append([]byte("echo: "), b...))
+	}))
+	defer ts.Close()
+
+	conf := NewHTTPClientConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.PropagateResponse = true
+
+	h, err := NewHTTPClient(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.CloseAsync()
+
+	store := roundtrip.NewResultStore()
+
+	msg := message.New([][]byte{[]byte("hello")})
+	roundtrip.SetResultStore(msg, store)
+	if err = h.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = message.New([][]byte{[]byte("empty")})
+	roundtrip.SetResultStore(msg, store)
+	if err = h.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages without a result store are sent as usual.
+	if err = h.Write(message.New([][]byte{[]byte("ignored")})); err != nil {
+		t.Fatal(err)
+	}
+
+	results := store.Get()
+	if exp, act := 2, len(results); exp != act {
+		t.Fatalf("Wrong count of results: %v != %v", act, exp)
+	}
+
+	type expPart struct {
+		body, status string
+	}
+	for i, exp := range []expPart{
+		{"echo: hello", "201"},
+		{"", "202"},
+	} {
+		part := results[i].Get(0)
+		if act := string(part.Get()); exp.body != act {
+			t.Errorf("Wrong body of result %v: %v != %v", i, act, exp.body)
+		}
+		if act := part.Metadata().Get("http_status_code"); exp.status != act {
+			t.Errorf("Wrong status code of result %v: %v != %v", i, act, exp.status)
+		}
+		if act := part.Metadata().Get("X-Foo"); act != "bar" {
+			t.Errorf("Wrong header of result %v: %v", i, act)
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,403 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/gabs"
+)
+
+//------------------------------------------------------------------------------
+
+// InfluxDBConfig contains configuration fields for the InfluxDB output type.
+type InfluxDBConfig struct {
+	URL             string               `json:"url" yaml:"url"`
+	APIVersion      string               `json:"api_version" yaml:"api_version"`
+	Database        string               `json:"database" yaml:"database"`
+	RetentionPolicy string               `json:"retention_policy" yaml:"retention_policy"`
+	Org             string               `json:"org" yaml:"org"`
+	Bucket          string               `json:"bucket" yaml:"bucket"`
+	Token           string               `json:"token" yaml:"token"`
+	Measurement     string               `json:"measurement" yaml:"measurement"`
+	Tags            map[string]string    `json:"tags" yaml:"tags"`
+	Fields          map[string]string    `json:"fields" yaml:"fields"`
+	TimestampPath   string               `json:"timestamp_path" yaml:"timestamp_path"`
+	Precision       string               `json:"precision" yaml:"precision"`
+	Timeout         string               `json:"timeout" yaml:"timeout"`
+	Auth            auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	TLS             btls.Config          `json:"tls" yaml:"tls"`
+}
+
+// NewInfluxDBConfig creates a new InfluxDBConfig with default values.
+func NewInfluxDBConfig() InfluxDBConfig {
+	return InfluxDBConfig{
+		URL:             "http://localhost:8086",
+		APIVersion:      "v1",
+		Database:        "",
+		RetentionPolicy: "",
+		Org:             "",
+		Bucket:          "",
+		Token:           "",
+		Measurement:     "benthos",
+		Tags:            map[string]string{},
+		Fields:          map[string]string{},
+		TimestampPath:   "",
+		Precision:       "ns",
+		Timeout:         "5s",
+		Auth:            auth.NewBasicAuthConfig(),
+		TLS:             btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// InfluxDB is a writer type that converts JSON messages into points of the
+// InfluxDB line protocol and writes them over HTTP.
+type InfluxDB struct {
+	conf   InfluxDBConfig
+	client http.Client
+
+	writeURL    string
+	measurement *text.InterpolatedString
+	precision   time.Duration
+	tagKeys     []string
+	fieldKeys   []string
+	nonFields   map[string]struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mPoints  metrics.StatCounter
+	mJSONErr metrics.StatCounter
+}
+
+// NewInfluxDB creates a new InfluxDB writer type.
+func NewInfluxDB(conf InfluxDBConfig, log log.Modular, stats metrics.Type) (*InfluxDB, error) {
+	i := InfluxDB{
+		conf:        conf,
+		measurement: text.NewInterpolatedString(conf.Measurement),
+		log:         log,
+		stats:       stats,
+		mPoints:     stats.GetCounter("points"),
+		mJSONErr:    stats.GetCounter("error.json"),
+	}
+	if len(conf.Measurement) == 0 {
+		return nil, errors.New("a measurement must be specified")
+	}
+
+	var v1Precision string
+	switch conf.Precision {
+	case "ns":
+		i.precision, v1Precision = time.Nanosecond, "n"
+	case "us":
+		i.precision, v1Precision = time.Microsecond, "u"
+	case "ms":
+		i.precision, v1Precision = time.Millisecond, "ms"
+	case "s":
+		i.precision, v1Precision = time.Second, "s"
+	default:
+		return nil, fmt.Errorf("precision not recognised: %v", conf.Precision)
+	}
+
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+	query := url.Values{}
+	switch conf.APIVersion {
+	case "v1":
+		if len(conf.Database) == 0 {
+			return nil, errors.New("a database must be specified")
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/write"
+		query.Set("db", conf.Database)
+		if len(conf.RetentionPolicy) > 0 {
+			query.Set("rp", conf.RetentionPolicy)
+		}
+		query.Set("precision", v1Precision)
+	case "v2":
+		if len(conf.Org) == 0 || len(conf.Bucket) == 0 {
+			return nil, errors.New("an org and bucket must be specified")
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+		query.Set("org", conf.Org)
+		query.Set("bucket", conf.Bucket)
+		query.Set("precision", conf.Precision)
+	default:
+		return nil, fmt.Errorf("api version not recognised: %v", conf.APIVersion)
+	}
+	u.RawQuery = query.Encode()
+	i.writeURL = u.String()
+
+	i.nonFields = map[string]struct{}{conf.TimestampPath: {}}
+	for k, path := range conf.Tags {
+		i.tagKeys = append(i.tagKeys, k)
+		i.nonFields[path] = struct{}{}
+	}
+	sort.Strings(i.tagKeys)
+	for k := range conf.Fields {
+		i.fieldKeys = append(i.fieldKeys, k)
+	}
+	sort.Strings(i.fieldKeys)
+
+	if tout := conf.Timeout; len(tout) > 0 {
+		if i.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		i.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return &i, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (i *InfluxDB) sign(req *http.Request) {
+	if len(i.conf.Token) > 0 {
+		req.Header.Set("Authorization", "Token "+i.conf.Token)
+	}
+	i.conf.Auth.Sign(req)
+}
+
+// Connect checks that the InfluxDB server is reachable.
+func (i *InfluxDB) Connect() error {
+	u, err := url.Parse(i.conf.URL)
+	if err != nil {
+		return err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ping"
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	i.sign(req)
+
+	res, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("ping returned unexpected status: %v", res.Status)
+	}
+
+	i.log.Infof("Writing points to InfluxDB at: %v\n", i.conf.URL)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxKeyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// influxFieldValue formats a JSON value as a line protocol field value.
+func influxFieldValue(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(t), true
+	case string:
+		return `"` + influxStringEscaper.Replace(t) + `"`, true
+	}
+	return "", false
+}
+
+// influxTagValue formats a JSON value as a line protocol tag value.
+func influxTagValue(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(t), true
+	case string:
+		if len(t) == 0 {
+			return "", false
+		}
+		return influxKeyEscaper.Replace(t), true
+	}
+	return "", false
+}
+
+// timestamp extracts the timestamp of a point from a JSON value, which is
+// either a number in units of the configured precision or an RFC 3339 string.
+func (i *InfluxDB) timestamp(v interface{}) (int64, error) {
+	switch t := v.(type) {
+	case float64:
+		return int64(t), nil
+	case string:
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return 0, err
+		}
+		return ts.UnixNano() / int64(i.precision), nil
+	}
+	return 0, fmt.Errorf("unsupported timestamp type: %T", v)
+}
+
+// line creates a line protocol point from a message part.
+func (i *InfluxDB) line(msg types.Message, index int) ([]byte, error) {
+	jObj, err := msg.Get(index).JSON()
+	if err != nil {
+		return nil, err
+	}
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return nil, err
+	}
+
+	var line bytes.Buffer
+	line.WriteString(influxMeasurementEscaper.Replace(i.measurement.Get(message.Lock(msg, index))))
+	for _, k := range i.tagKeys {
+		if v, ok := influxTagValue(gObj.Path(i.conf.Tags[k]).Data()); ok {
+			line.WriteByte(',')
+			line.WriteString(influxKeyEscaper.Replace(k))
+			line.WriteByte('=')
+			line.WriteString(v)
+		}
+	}
+
+	fields := 0
+	writeField := func(k string, v interface{}) {
+		if fv, ok := influxFieldValue(v); ok {
+			if fields == 0 {
+				line.WriteByte(' ')
+			} else {
+				line.WriteByte(',')
+			}
+			line.WriteString(influxKeyEscaper.Replace(k))
+			line.WriteByte('=')
+			line.WriteString(fv)
+			fields++
+		}
+	}
+	if len(i.fieldKeys) > 0 {
+		for _, k := range i.fieldKeys {
+			writeField(k, gObj.Path(i.conf.Fields[k]).Data())
+		}
+	} else if obj, ok := jObj.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			if _, exists := i.nonFields[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeField(k, obj[k])
+		}
+	}
+	if fields == 0 {
+		return nil, errors.New("message contains no field values")
+	}
+
+	if len(i.conf.TimestampPath) > 0 {
+		if v := gObj.Path(i.conf.TimestampPath).Data(); v != nil {
+			ts, err := i.timestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			line.WriteByte(' ')
+			line.WriteString(strconv.FormatInt(ts, 10))
+		}
+	}
+	return line.Bytes(), nil
+}
+
+// Write attempts to write each part of a message as a point with a single
+// request.
+func (i *InfluxDB) Write(msg types.Message) error {
+	var body bytes.Buffer
+	points := 0
+	for j := 0; j < msg.Len(); j++ {
+		line, err := i.line(msg, j)
+		if err != nil {
+			i.mJSONErr.Incr(1)
+			i.log.Errorf("Failed to create point from message: %v\n", err)
+			continue
+		}
+		body.Write(line)
+		body.WriteByte('\n')
+		points++
+	}
+	if points == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", i.writeURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	i.sign(req)
+
+	res, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resBody, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("write returned unexpected status: %v: %s", res.Status, bytes.TrimSpace(resBody))
+	}
+	i.mPoints.Incr(int64(points))
+	return nil
+}
+
+// CloseAsync shuts down the InfluxDB writer and stops processing messages.
+func (i *InfluxDB) CloseAsync() {
+}
+
+// WaitForClose blocks until the InfluxDB writer has closed down.
+func (i *InfluxDB) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
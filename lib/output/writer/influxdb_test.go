@@ -0,0 +1,207 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestInfluxDBBadConfig(t *testing.T) {
+	conf := NewInfluxDBConfig()
+	if _, err := NewInfluxDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing database")
+	}
+
+	conf.APIVersion = "v2"
+	if _, err := NewInfluxDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing org and bucket")
+	}
+
+	conf.Org, conf.Bucket = "foo", "bar"
+	conf.Precision = "m"
+	if _, err := NewInfluxDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad precision")
+	}
+}
+
+func TestInfluxDBLines(t *testing.T) {
+	tests := []struct {
+		tags      map[string]string
+		fields    map[string]string
+		timestamp string
+		precision string
+		input     string
+		exp       string
+	}{
+		{
+			tags:  map[string]string{"host": "host"},
+			input: `{"host":"a","value":1.5,"name":"foo \"bar\"","ok":true,"nested":{"a":1}}`,
+			exp:   `cpu,host=a name="foo \"bar\"",ok=true,value=1.5`,
+		},
+		{
+			tags:   map[string]string{"host": "meta.host", "region": "meta.region"},
+			fields: map[string]string{"used": "stats.used", "free": "stats.free"},
+			input:  `{"meta":{"host":"a b","region":"eu,west"},"stats":{"used":10}}`,
+			exp:    `cpu,host=a\ b,region=eu\,west used=10`,
+		},
+		{
+			timestamp: "ts",
+			input:     `{"value":1,"ts":1546300800000000000}`,
+			exp:       `cpu value=1 1546300800000000000`,
+		},
+		{
+			timestamp: "ts",
+			precision: "s",
+			input:     `{"value":1,"ts":"2019-01-01T00:00:00Z"}`,
+			exp:       `cpu value=1 1546300800`,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewInfluxDBConfig()
+		conf.Database = "db"
+		conf.Measurement = "${!metadata:measurement}"
+		conf.Tags = test.tags
+		conf.Fields = test.fields
+		conf.TimestampPath = test.timestamp
+		if len(test.precision) > 0 {
+			conf.Precision = test.precision
+		}
+
+		i, err := NewInfluxDB(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := message.New([][]byte{[]byte(test.input)})
+		msg.Get(0).Metadata().Set("measurement", "cpu")
+
+		line, err := i.line(msg, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(line); act != test.exp {
+			t.Errorf("Wrong line: %v != %v", act, test.exp)
+		}
+	}
+}
+
+func TestInfluxDBWriteV1(t *testing.T) {
+	var reqPath, reqQuery, reqBody string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		reqPath, reqQuery, reqBody = r.URL.Path, r.URL.RawQuery, string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf := NewInfluxDBConfig()
+	conf.URL = ts.URL
+	conf.Database = "foo"
+	conf.RetentionPolicy = "autogen"
+	conf.Precision = "ms"
+
+	i, err := NewInfluxDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = i.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = i.Write(message.New([][]byte{
+		[]byte(`{"value":1}`),
+		[]byte(`not json`),
+		[]byte(`{"value":2}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "/write", reqPath; exp != act {
+		t.Errorf("Wrong path: %v != %v", act, exp)
+	}
+	if exp, act := "db=foo&precision=ms&rp=autogen", reqQuery; exp != act {
+		t.Errorf("Wrong query: %v != %v", act, exp)
+	}
+	if exp, act := "benthos value=1\nbenthos value=2\n", reqBody; exp != act {
+		t.Errorf("Wrong body: %v != %v", act, exp)
+	}
+}
+
+func TestInfluxDBWriteV2(t *testing.T) {
+	var reqPath, reqQuery, reqAuth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqPath, reqQuery, reqAuth = r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization")
+		if r.URL.Path != "/ping" && r.URL.Query().Get("bucket") == "bad" {
+			http.Error(w, `{"code":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	conf := NewInfluxDBConfig()
+	conf.URL = ts.URL
+	conf.APIVersion = "v2"
+	conf.Org = "foo"
+	conf.Bucket = "bar"
+	conf.Token = "baz"
+
+	i, err := NewInfluxDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = i.Write(message.New([][]byte{[]byte(`{"value":1}`)})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "/api/v2/write", reqPath; exp != act {
+		t.Errorf("Wrong path: %v != %v", act, exp)
+	}
+	if exp, act := "bucket=bar&org=foo&precision=ns", reqQuery; exp != act {
+		t.Errorf("Wrong query: %v != %v", act, exp)
+	}
+	if exp, act := "Token baz", reqAuth; exp != act {
+		t.Errorf("Wrong auth: %v != %v", act, exp)
+	}
+
+	conf.Bucket = "bad"
+	if i, err = NewInfluxDB(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+	if err = i.Write(message.New([][]byte{[]byte(`{"value":1}`)})); err == nil {
+		t.Error("Expected error from bad bucket")
+	}
+}
+
+//------------------------------------------------------------------------------
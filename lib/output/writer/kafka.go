@@ -22,7 +22,10 @@ package writer
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"hash"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -38,19 +41,39 @@ import (
 
 //------------------------------------------------------------------------------
 
+// KafkaTransactionConfig contains configuration fields for the transactional
+// writes of the Kafka output type.
+type KafkaTransactionConfig struct {
+	ID            string `json:"id" yaml:"id"`
+	ConsumerGroup string `json:"consumer_group" yaml:"consumer_group"`
+}
+
+// NewKafkaTransactionConfig creates a new KafkaTransactionConfig with default
+// values.
+func NewKafkaTransactionConfig() KafkaTransactionConfig {
+	return KafkaTransactionConfig{
+		ID:            "",
+		ConsumerGroup: "",
+	}
+}
+
 // KafkaConfig contains configuration fields for the Kafka output type.
 type KafkaConfig struct {
-	Addresses            []string    `json:"addresses" yaml:"addresses"`
-	ClientID             string      `json:"client_id" yaml:"client_id"`
-	Key                  string      `json:"key" yaml:"key"`
-	RoundRobinPartitions bool        `json:"round_robin_partitions" yaml:"round_robin_partitions"`
-	Topic                string      `json:"topic" yaml:"topic"`
-	Compression          string      `json:"compression" yaml:"compression"`
-	MaxMsgBytes          int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
-	Timeout              string      `json:"timeout" yaml:"timeout"`
-	AckReplicas          bool        `json:"ack_replicas" yaml:"ack_replicas"`
-	TargetVersion        string      `json:"target_version" yaml:"target_version"`
-	TLS                  btls.Config `json:"tls" yaml:"tls"`
+	Addresses            []string               `json:"addresses" yaml:"addresses"`
+	ClientID             string                 `json:"client_id" yaml:"client_id"`
+	Key                  string                 `json:"key" yaml:"key"`
+	Partitioner          string                 `json:"partitioner" yaml:"partitioner"`
+	Partition            string                 `json:"partition" yaml:"partition"`
+	RoundRobinPartitions bool                   `json:"round_robin_partitions" yaml:"round_robin_partitions"`
+	Topic                string                 `json:"topic" yaml:"topic"`
+	Compression          string                 `json:"compression" yaml:"compression"`
+	MaxMsgBytes          int                    `json:"max_msg_bytes" yaml:"max_msg_bytes"`
+	Timeout              string                 `json:"timeout" yaml:"timeout"`
+	AckReplicas          bool                   `json:"ack_replicas" yaml:"ack_replicas"`
+	IdempotentWrite      bool                   `json:"idempotent_write" yaml:"idempotent_write"`
+	Transaction          KafkaTransactionConfig `json:"transaction" yaml:"transaction"`
+	TargetVersion        string                 `json:"target_version" yaml:"target_version"`
+	TLS                  btls.Config            `json:"tls" yaml:"tls"`
 }
 
 // NewKafkaConfig creates a new KafkaConfig with default values.
@@ -59,12 +82,16 @@ func NewKafkaConfig() KafkaConfig {
 		Addresses:            []string{"localhost:9092"},
 		ClientID:             "benthos_kafka_output",
 		Key:                  "",
+		Partitioner:          "fnv1a_hash",
+		Partition:            "",
 		RoundRobinPartitions: false,
 		Topic:                "benthos_stream",
 		Compression:          "none",
 		MaxMsgBytes:          1000000,
 		Timeout:              "5s",
 		AckReplicas:          false,
+		IdempotentWrite:      false,
+		Transaction:          NewKafkaTransactionConfig(),
 		TargetVersion:        sarama.V1_0_0_0.String(),
 		TLS:                  btls.NewConfig(),
 	}
@@ -86,8 +113,11 @@ type Kafka struct {
 
 	mDroppedMaxBytes metrics.StatCounter
 
-	key   *text.InterpolatedBytes
-	topic *text.InterpolatedString
+	key       *text.InterpolatedBytes
+	topic     *text.InterpolatedString
+	partition *text.InterpolatedString
+
+	partitioner sarama.PartitionerConstructor
 
 	producer    sarama.SyncProducer
 	compression sarama.CompressionCodec
@@ -120,6 +150,22 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 		}
 	}
 
+	partitioner := conf.Partitioner
+	if conf.RoundRobinPartitions {
+		partitioner = "round_robin"
+	}
+	if k.partitioner, err = strToPartitioner(partitioner); err != nil {
+		return nil, err
+	}
+	if partitioner == "manual" {
+		if len(conf.Partition) == 0 {
+			return nil, errors.New("a partition must be specified when using the manual partitioner")
+		}
+		k.partition = text.NewInterpolatedString(conf.Partition)
+	} else if len(conf.Partition) > 0 {
+		return nil, errors.New("a partition can only be specified when using the manual partitioner")
+	}
+
 	if conf.TLS.Enabled {
 		var err error
 		if k.tlsConf, err = conf.TLS.Get(); err != nil {
@@ -130,6 +176,15 @@ func NewKafka(conf KafkaConfig, log log.Modular, stats metrics.Type) (*Kafka, er
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if conf.IdempotentWrite && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, fmt.Errorf("idempotent writes require a target version of at least %v", sarama.V0_11_0_0)
+	}
+	if len(conf.Transaction.ID) > 0 && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, fmt.Errorf("transactional writes require a target version of at least %v", sarama.V0_11_0_0)
+	}
+	if len(conf.Transaction.ConsumerGroup) > 0 && len(conf.Transaction.ID) == 0 {
+		return nil, errors.New("a transaction consumer group can only be specified along with a transaction id")
+	}
 
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
@@ -158,6 +213,98 @@ func strToCompressionCodec(str string) (sarama.CompressionCodec, error) {
 	return sarama.CompressionNone, fmt.Errorf("compression codec not recognised: %v", str)
 }
 
+func strToPartitioner(str string) (sarama.PartitionerConstructor, error) {
+	switch str {
+	case "fnv1a_hash":
+		return sarama.NewHashPartitioner, nil
+	case "murmur2_hash":
+		return sarama.NewCustomPartitioner(
+			sarama.WithAbsFirst(),
+			sarama.WithCustomHashFunction(newMurmur2Hash32),
+		), nil
+	case "random":
+		return sarama.NewRandomPartitioner, nil
+	case "round_robin":
+		return sarama.NewRoundRobinPartitioner, nil
+	case "manual":
+		return sarama.NewManualPartitioner, nil
+	}
+	return nil, fmt.Errorf("partitioner not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
+// murmur2Hash32 is a hash.Hash32 implementation of the murmur2 hash used by the
+// default partitioner of the Java Kafka client.
+type murmur2Hash32 struct {
+	data []byte
+}
+
+func newMurmur2Hash32() hash.Hash32 {
+	return &murmur2Hash32{}
+}
+
+func (m *murmur2Hash32) Write(p []byte) (int, error) {
+	m.data = append(m.data, p...)
+	return len(p), nil
+}
+
+func (m *murmur2Hash32) Sum(b []byte) []byte {
+	h := m.Sum32()
+	return append(b, byte(h>>24), byte(h>>16), byte(h>>8), byte(h))
+}
+
+func (m *murmur2Hash32) Reset() {
+	m.data = m.data[:0]
+}
+
+func (m *murmur2Hash32) Size() int {
+	return 4
+}
+
+func (m *murmur2Hash32) BlockSize() int {
+	return 4
+}
+
+func (m *murmur2Hash32) Sum32() uint32 {
+	const (
+		seed uint32 = 0x9747b28c
+		mul  uint32 = 0x5bd1e995
+		r           = 24
+	)
+
+	data := m.data
+	length := len(data)
+	h := seed ^ uint32(length)
+
+	for i := 0; i+4 <= length; i += 4 {
+		k := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		k *= mul
+		k ^= k >> r
+		k *= mul
+		h *= mul
+		h ^= k
+	}
+
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= mul
+	}
+
+	h ^= h >> 13
+	h *= mul
+	h ^= h >> 15
+	return h
+}
+
 //------------------------------------------------------------------------------
 
 func buildHeaders(part types.Part) []sarama.RecordHeader {
@@ -176,15 +323,8 @@ func buildHeaders(part types.Part) []sarama.RecordHeader {
 
 //------------------------------------------------------------------------------
 
-// Connect attempts to establish a connection to a Kafka broker.
-func (k *Kafka) Connect() error {
-	k.connMut.Lock()
-	defer k.connMut.Unlock()
-
-	if k.producer != nil {
-		return nil
-	}
-
+// saramaConfig creates the configuration of the producer.
+func (k *Kafka) saramaConfig() *sarama.Config {
 	config := sarama.NewConfig()
 	config.ClientID = k.conf.ClientID
 
@@ -200,9 +340,7 @@ func (k *Kafka) Connect() error {
 		config.Net.TLS.Config = k.tlsConf
 	}
 
-	if k.conf.RoundRobinPartitions {
-		config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
-	}
+	config.Producer.Partitioner = k.partitioner
 
 	if k.conf.AckReplicas {
 		config.Producer.RequiredAcks = sarama.WaitForAll
@@ -210,8 +348,30 @@ func (k *Kafka) Connect() error {
 		config.Producer.RequiredAcks = sarama.WaitForLocal
 	}
 
+	if k.conf.IdempotentWrite || len(k.conf.Transaction.ID) > 0 {
+		// Idempotence requires acks from all in-sync replicas and at most a
+		// single in flight request per broker in order to preserve ordering.
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+	}
+	if len(k.conf.Transaction.ID) > 0 {
+		config.Producer.Transaction.ID = k.conf.Transaction.ID
+	}
+	return config
+}
+
+// Connect attempts to establish a connection to a Kafka broker.
+func (k *Kafka) Connect() error {
+	k.connMut.Lock()
+	defer k.connMut.Unlock()
+
+	if k.producer != nil {
+		return nil
+	}
+
 	var err error
-	k.producer, err = sarama.NewSyncProducer(k.addresses, config)
+	k.producer, err = sarama.NewSyncProducer(k.addresses, k.saramaConfig())
 
 	if err == nil {
 		k.log.Infof("Sending Kafka messages to addresses: %s\n", k.addresses)
@@ -231,7 +391,7 @@ func (k *Kafka) Write(msg types.Message) error {
 	}
 
 	msgs := []*sarama.ProducerMessage{}
-	msg.Iter(func(i int, p types.Part) error {
+	err := msg.Iter(func(i int, p types.Part) error {
 		if len(p.Get()) > k.conf.MaxMsgBytes {
 			k.mDroppedMaxBytes.Incr(1)
 			return nil
@@ -248,20 +408,121 @@ func (k *Kafka) Write(msg types.Message) error {
 		if len(key) > 0 {
 			nextMsg.Key = sarama.ByteEncoder(key)
 		}
+		if k.partition != nil {
+			partStr := k.partition.Get(lMsg)
+			partition, err := strconv.ParseInt(partStr, 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse partition '%v': %v", partStr, err)
+			}
+			nextMsg.Partition = int32(partition)
+		}
 		msgs = append(msgs, nextMsg)
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if producer.IsTransactional() {
+		return k.writeTransaction(producer, msg, msgs)
+	}
+	return sendMessages(producer, msgs)
+}
 
+func sendMessages(producer sarama.SyncProducer, msgs []*sarama.ProducerMessage) error {
 	err := producer.SendMessages(msgs)
 	if err != nil {
 		if pErr, ok := err.(sarama.ProducerErrors); ok && len(pErr) > 0 {
 			err = fmt.Errorf("failed to send %v parts from message: %v", len(pErr), pErr[0].Err)
 		}
 	}
+	return err
+}
+
+// writeTransaction writes a batch of messages within a single transaction,
+// along with the consumed offsets of the message when a consumer group is
+// configured, so that either both are committed or neither are.
+func (k *Kafka) writeTransaction(producer sarama.SyncProducer, msg types.Message, msgs []*sarama.ProducerMessage) error {
+	if err := producer.BeginTxn(); err != nil {
+		return k.abortTransaction(producer, fmt.Errorf("failed to begin transaction: %v", err))
+	}
+	if err := sendMessages(producer, msgs); err != nil {
+		return k.abortTransaction(producer, err)
+	}
+	if group := k.conf.Transaction.ConsumerGroup; len(group) > 0 {
+		if offsets := transactionOffsets(msg); len(offsets) > 0 {
+			if err := producer.AddOffsetsToTxn(offsets, group); err != nil {
+				return k.abortTransaction(producer, fmt.Errorf("failed to add offsets to transaction: %v", err))
+			}
+		}
+	}
+	if err := producer.CommitTxn(); err != nil {
+		return k.abortTransaction(producer, fmt.Errorf("failed to commit transaction: %v", err))
+	}
+	return nil
+}
 
+// abortTransaction aborts the current transaction and returns err. When the
+// transaction cannot be aborted the producer is closed, and is recreated on
+// the next connection attempt.
+func (k *Kafka) abortTransaction(producer sarama.SyncProducer, err error) error {
+	status := producer.TxnStatus()
+	if status&sarama.ProducerTxnFlagFatalError == 0 {
+		if status&(sarama.ProducerTxnFlagInTransaction|sarama.ProducerTxnFlagAbortableError) == 0 {
+			return err
+		}
+		aErr := producer.AbortTxn()
+		if aErr == nil {
+			return err
+		}
+		k.log.Errorf("Failed to abort transaction: %v\n", aErr)
+	}
+
+	k.connMut.Lock()
+	if k.producer == producer {
+		k.producer.Close()
+		k.producer = nil
+	}
+	k.connMut.Unlock()
 	return err
 }
 
+// transactionOffsets returns the offsets to commit for the kafka input
+// messages that a message was consumed from, which are those following the
+// latest offset of each partition.
+func transactionOffsets(msg types.Message) map[string][]*sarama.PartitionOffsetMetadata {
+	latest := map[string]map[int32]int64{}
+	msg.Iter(func(i int, p types.Part) error {
+		meta := p.Metadata()
+		topic := meta.Get("kafka_topic")
+		partition, pErr := strconv.ParseInt(meta.Get("kafka_partition"), 10, 32)
+		offset, oErr := strconv.ParseInt(meta.Get("kafka_offset"), 10, 64)
+		if len(topic) == 0 || pErr != nil || oErr != nil {
+			return nil
+		}
+		topicMap, exists := latest[topic]
+		if !exists {
+			topicMap = map[int32]int64{}
+			latest[topic] = topicMap
+		}
+		if current, exists := topicMap[int32(partition)]; !exists || offset > current {
+			topicMap[int32(partition)] = offset
+		}
+		return nil
+	})
+
+	offsets := map[string][]*sarama.PartitionOffsetMetadata{}
+	for topic, topicMap := range latest {
+		for partition, offset := range topicMap {
+			offsets[topic] = append(offsets[topic], &sarama.PartitionOffsetMetadata{
+				Partition: partition,
+				Offset:    offset + 1,
+			})
+		}
+	}
+	return offsets
+}
+
 // CloseAsync shuts down the Kafka writer and stops processing messages.
 func (k *Kafka) CloseAsync() {
 	k.connMut.Lock()
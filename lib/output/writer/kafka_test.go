@@ -0,0 +1,322 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func TestKafkaIdempotentConfig(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.IdempotentWrite = true
+
+	k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := k.saramaConfig()
+	if err = config.Validate(); err != nil {
+		t.Fatalf("Invalid producer config: %v", err)
+	}
+	if !config.Producer.Idempotent {
+		t.Error("Expected idempotent producer")
+	}
+	if exp, act := sarama.WaitForAll, config.Producer.RequiredAcks; exp != act {
+		t.Errorf("Wrong required acks: %v != %v", act, exp)
+	}
+	if exp, act := 1, config.Net.MaxOpenRequests; exp != act {
+		t.Errorf("Wrong max open requests: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaIdempotentBadVersion(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.IdempotentWrite = true
+	conf.TargetVersion = sarama.V0_10_2_0.String()
+
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from old target version")
+	}
+}
+
+func TestKafkaMurmur2Hash(t *testing.T) {
+	// Test vectors from the Java client
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	h := newMurmur2Hash32()
+	for input, exp := range tests {
+		h.Reset()
+		h.Write([]byte(input))
+		if act := int32(h.Sum32()); act != exp {
+			t.Errorf("Wrong hash for '%v': %v != %v", input, act, exp)
+		}
+	}
+}
+
+func TestKafkaMurmur2Partitioner(t *testing.T) {
+	ctor, err := strToPartitioner("murmur2_hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := ctor("foo")
+
+	// Java: toPositive(murmur2("foobar")) % 10
+	partition, err := p.Partition(&sarama.ProducerMessage{
+		Key: sarama.StringEncoder("foobar"),
+	}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := int32((-790332482&0x7fffffff)%10), partition; exp != act {
+		t.Errorf("Wrong partition: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaPartitionerConfig(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Partitioner = "manual"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from manual partitioner without partition")
+	}
+
+	conf.Partition = "${!metadata:partition}"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err != nil {
+		t.Error(err)
+	}
+
+	conf.Partitioner = "murmur2_hash"
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from partition without manual partitioner")
+	}
+
+	conf.Partitioner = "nope"
+	conf.Partition = ""
+	if _, err := NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unknown partitioner")
+	}
+}
+
+//------------------------------------------------------------------------------
+
+//------------------------------------------------------------------------------
+
+type mockTxnProducer struct {
+	sarama.SyncProducer
+
+	sendErr error
+	fatal   bool
+	status  sarama.ProducerTxnStatusFlag
+	calls   []string
+	sent    []string
+	offsets map[string]map[int32]int64
+	group   string
+	closed  bool
+}
+
+func (m *mockTxnProducer) IsTransactional() bool {
+	return true
+}
+
+func (m *mockTxnProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	return m.status
+}
+
+func (m *mockTxnProducer) BeginTxn() error {
+	m.calls = append(m.calls, "begin")
+	m.status = sarama.ProducerTxnFlagInTransaction
+	return nil
+}
+
+func (m *mockTxnProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	m.calls = append(m.calls, "send")
+	if m.sendErr != nil {
+		m.status = sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagAbortableError
+		if m.fatal {
+			m.status = sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagFatalError
+		}
+		return m.sendErr
+	}
+	for _, msg := range msgs {
+		b, _ := msg.Value.Encode()
+		m.sent = append(m.sent, string(b))
+	}
+	return nil
+}
+
+func (m *mockTxnProducer) AddOffsetsToTxn(offsets map[string][]*sarama.PartitionOffsetMetadata, groupID string) error {
+	m.calls = append(m.calls, "offsets")
+	m.group = groupID
+	m.offsets = map[string]map[int32]int64{}
+	for topic, parts := range offsets {
+		m.offsets[topic] = map[int32]int64{}
+		for _, p := range parts {
+			m.offsets[topic][p.Partition] = p.Offset
+		}
+	}
+	return nil
+}
+
+func (m *mockTxnProducer) CommitTxn() error {
+	m.calls = append(m.calls, "commit")
+	m.status = sarama.ProducerTxnFlagReady
+	return nil
+}
+
+func (m *mockTxnProducer) AbortTxn() error {
+	m.calls = append(m.calls, "abort")
+	m.status = sarama.ProducerTxnFlagReady
+	return nil
+}
+
+func (m *mockTxnProducer) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestKafkaTransactionConfig(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+
+	k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := k.saramaConfig()
+	if err = config.Validate(); err != nil {
+		t.Fatalf("Invalid producer config: %v", err)
+	}
+	if exp, act := "foo", config.Producer.Transaction.ID; exp != act {
+		t.Errorf("Wrong transaction id: %v != %v", act, exp)
+	}
+	if !config.Producer.Idempotent {
+		t.Error("Expected idempotent producer")
+	}
+
+	conf = NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+	conf.TargetVersion = sarama.V0_10_2_0.String()
+	if _, err = NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from transactions with an old target version")
+	}
+
+	conf = NewKafkaConfig()
+	conf.Transaction.ConsumerGroup = "bar"
+	if _, err = NewKafka(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from consumer group without transaction id")
+	}
+}
+
+func TestKafkaTransactionWrite(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+	conf.Transaction.ConsumerGroup = "bar"
+
+	k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer := &mockTxnProducer{status: sarama.ProducerTxnFlagReady}
+	k.producer = producer
+
+	msg := message.New([][]byte{[]byte("first"), []byte("second"), []byte("third")})
+	msg.Get(0).Metadata().Set("kafka_topic", "a").Set("kafka_partition", "0").Set("kafka_offset", "5")
+	msg.Get(1).Metadata().Set("kafka_topic", "a").Set("kafka_partition", "0").Set("kafka_offset", "6")
+	msg.Get(2).Metadata().Set("kafka_topic", "b").Set("kafka_partition", "2").Set("kafka_offset", "10")
+
+	if err = k.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"begin", "send", "offsets", "commit"}; !reflect.DeepEqual(exp, producer.calls) {
+		t.Errorf("Wrong transaction calls: %v != %v", producer.calls, exp)
+	}
+	if exp := []string{"first", "second", "third"}; !reflect.DeepEqual(exp, producer.sent) {
+		t.Errorf("Wrong messages sent: %v != %v", producer.sent, exp)
+	}
+	exp := map[string]map[int32]int64{
+		"a": {0: 7},
+		"b": {2: 11},
+	}
+	if !reflect.DeepEqual(exp, producer.offsets) {
+		t.Errorf("Wrong offsets: %v != %v", producer.offsets, exp)
+	}
+	if exp, act := "bar", producer.group; exp != act {
+		t.Errorf("Wrong consumer group: %v != %v", act, exp)
+	}
+}
+
+func TestKafkaTransactionAbort(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Transaction.ID = "foo"
+	conf.Transaction.ConsumerGroup = "bar"
+
+	k, err := NewKafka(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer := &mockTxnProducer{
+		status:  sarama.ProducerTxnFlagReady,
+		sendErr: errors.New("nope"),
+	}
+	k.producer = producer
+
+	msg := message.New([][]byte{[]byte("first")})
+	msg.Get(0).Metadata().Set("kafka_topic", "a").Set("kafka_partition", "0").Set("kafka_offset", "5")
+
+	if err = k.Write(msg); err == nil {
+		t.Error("Expected error from failed send")
+	}
+	if exp := []string{"begin", "send", "abort"}; !reflect.DeepEqual(exp, producer.calls) {
+		t.Errorf("Wrong transaction calls: %v != %v", producer.calls, exp)
+	}
+	if producer.closed {
+		t.Error("Expected producer to remain open after abort")
+	}
+
+	producer.calls = nil
+	producer.fatal = true
+	if err = k.Write(msg); err == nil {
+		t.Error("Expected error from failed send")
+	}
+	if !producer.closed {
+		t.Error("Expected producer to be closed after fatal error")
+	}
+	if err = k.Write(msg); err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/Jeffail/gabs"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/connstring"
+)
+
+//------------------------------------------------------------------------------
+
+// MongoDBConfig contains configuration fields for the MongoDB output type.
+type MongoDBConfig struct {
+	URL         string            `json:"url" yaml:"url"`
+	Database    string            `json:"database" yaml:"database"`
+	Collection  string            `json:"collection" yaml:"collection"`
+	Operation   string            `json:"operation" yaml:"operation"`
+	DocumentMap map[string]string `json:"document_map" yaml:"document_map"`
+	FilterMap   map[string]string `json:"filter_map" yaml:"filter_map"`
+	Upsert      bool              `json:"upsert" yaml:"upsert"`
+	Timeout     string            `json:"timeout" yaml:"timeout"`
+	TLS         btls.Config       `json:"tls" yaml:"tls"`
+}
+
+// NewMongoDBConfig creates a new MongoDBConfig with default values.
+func NewMongoDBConfig() MongoDBConfig {
+	return MongoDBConfig{
+		URL:         "mongodb://localhost:27017",
+		Database:    "",
+		Collection:  "",
+		Operation:   "insert",
+		DocumentMap: map[string]string{},
+		FilterMap:   map[string]string{},
+		Upsert:      false,
+		Timeout:     "5s",
+		TLS:         btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDB is a writer type that writes messages as documents of a MongoDB
+// collection, where each batch is sent as a single bulk write.
+type MongoDB struct {
+	conf       MongoDBConfig
+	database   string
+	clientOpts *options.ClientOptions
+	timeout    time.Duration
+
+	connMut    sync.Mutex
+	client     *mongo.Client
+	collection *mongo.Collection
+
+	log   log.Modular
+	stats metrics.Type
+
+	mDocs    metrics.StatCounter
+	mJSONErr metrics.StatCounter
+}
+
+// NewMongoDB creates a new MongoDB writer type.
+func NewMongoDB(conf MongoDBConfig, log log.Modular, stats metrics.Type) (*MongoDB, error) {
+	m := &MongoDB{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		mDocs:    stats.GetCounter("documents"),
+		mJSONErr: stats.GetCounter("error.json"),
+	}
+
+	connStr, err := connstring.ParseAndValidate(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+	if m.database = conf.Database; len(m.database) == 0 {
+		m.database = connStr.Database
+	}
+	if len(m.database) == 0 {
+		return nil, errors.New("a database must be specified")
+	}
+	if len(conf.Collection) == 0 {
+		return nil, errors.New("a collection must be specified")
+	}
+
+	switch conf.Operation {
+	case "insert":
+	case "replace", "update":
+		if len(conf.FilterMap) == 0 {
+			return nil, fmt.Errorf("a filter map must be specified for %v operations", conf.Operation)
+		}
+	default:
+		return nil, fmt.Errorf("operation not recognised: %v", conf.Operation)
+	}
+
+	m.clientOpts = options.Client().ApplyURI(conf.URL)
+	if tout := conf.Timeout; len(tout) > 0 {
+		if m.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+		m.clientOpts.SetConnectTimeout(m.timeout).SetServerSelectionTimeout(m.timeout)
+	}
+	if conf.TLS.Enabled {
+		var tlsConf *tls.Config
+		if tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+		m.clientOpts.SetTLSConfig(tlsConf)
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// context returns a context bounded by the configured timeout.
+func (m *MongoDB) context() (context.Context, context.CancelFunc) {
+	if m.timeout > 0 {
+		return context.WithTimeout(context.Background(), m.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// Connect attempts to establish a connection to the primary of the MongoDB
+// deployment.
+func (m *MongoDB) Connect() error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	client, err := mongo.Connect(m.clientOpts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+	if err = client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(ctx)
+		return err
+	}
+	m.client = client
+	m.collection = client.Database(m.database).Collection(m.conf.Collection)
+
+	m.log.Infof("Writing documents to MongoDB collection '%v.%v' at: %v\n", m.database, m.conf.Collection, strings.Join(m.clientOpts.Hosts, ","))
+	return nil
+}
+
+// mapDocument creates a document from a message using a map of field names to
+// dot paths. When the map is empty the whole message is used.
+func mapDocument(gObj *gabs.Container, fields map[string]string) (map[string]interface{}, error) {
+	if len(fields) == 0 {
+		doc, ok := gObj.Data().(map[string]interface{})
+		if !ok {
+			return nil, errors.New("message is not a JSON object")
+		}
+		return doc, nil
+	}
+	doc := make(map[string]interface{}, len(fields))
+	for k, path := range fields {
+		doc[k] = gObj.Path(path).Data()
+	}
+	return doc, nil
+}
+
+// isUpdateDocument returns true if a document contains update operators.
+func isUpdateDocument(doc map[string]interface{}) bool {
+	for k := range doc {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// write creates the write of the configured operation from a message part.
+func (m *MongoDB) write(part types.Part) (mongo.WriteModel, error) {
+	jObj, err := part.JSON()
+	if err != nil {
+		return nil, err
+	}
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := mapDocument(gObj, m.conf.DocumentMap)
+	if err != nil {
+		return nil, err
+	}
+	if m.conf.Operation == "insert" {
+		return mongo.NewInsertOneModel().SetDocument(doc), nil
+	}
+
+	filter, err := mapDocument(gObj, m.conf.FilterMap)
+	if err != nil {
+		return nil, err
+	}
+	if m.conf.Operation == "replace" {
+		return mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(doc).
+			SetUpsert(m.conf.Upsert), nil
+	}
+	var update interface{} = doc
+	if !isUpdateDocument(doc) {
+		update = map[string]interface{}{"$set": doc}
+	}
+	return mongo.NewUpdateOneModel().
+		SetFilter(filter).
+		SetUpdate(update).
+		SetUpsert(m.conf.Upsert), nil
+}
+
+// Write attempts to write each part of a message as a document with a single
+// ordered bulk write.
+func (m *MongoDB) Write(msg types.Message) error {
+	m.connMut.Lock()
+	collection := m.collection
+	m.connMut.Unlock()
+
+	if collection == nil {
+		return types.ErrNotConnected
+	}
+
+	writes := make([]mongo.WriteModel, 0, msg.Len())
+	msg.Iter(func(i int, part types.Part) error {
+		w, err := m.write(part)
+		if err != nil {
+			m.mJSONErr.Incr(1)
+			m.log.Errorf("Failed to create document from message: %v\n", err)
+			return nil
+		}
+		writes = append(writes, w)
+		return nil
+	})
+	if len(writes) == 0 {
+		return nil
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true)); err != nil {
+		return err
+	}
+	m.mDocs.Incr(int64(len(writes)))
+	return nil
+}
+
+// CloseAsync shuts down the MongoDB writer and stops processing messages.
+func (m *MongoDB) CloseAsync() {
+	m.connMut.Lock()
+	if m.client != nil {
+		ctx, cancel := m.context()
+		m.client.Disconnect(ctx)
+		cancel()
+		m.client = nil
+		m.collection = nil
+	}
+	m.connMut.Unlock()
+}
+
+// WaitForClose blocks until the MongoDB writer has closed down.
+func (m *MongoDB) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
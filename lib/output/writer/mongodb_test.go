@@ -0,0 +1,448 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/x/bsonx/bsoncore"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/wiremessage"
+)
+
+//------------------------------------------------------------------------------
+
+// fakeMongoDBNode describes the replica set membership of a fake server.
+type fakeMongoDBNode struct {
+	setName string
+	primary bool
+}
+
+// fakeMongoDBServers runs a fake server for each node, accepting commands and
+// returning the write commands received by each server. Servers of nodes with
+// a set name report all other servers as members of the replica set. Writes
+// with a document containing the field fail are rejected with a write error.
+func fakeMongoDBServers(t *testing.T, nodes ...fakeMongoDBNode) ([]string, func(i int) []map[string]interface{}, func()) {
+	t.Helper()
+
+	lns := make([]net.Listener, len(nodes))
+	addrs := make([]string, len(nodes))
+	for i := range nodes {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lns[i], addrs[i] = ln, ln.Addr().String()
+	}
+
+	cmds := make([][]map[string]interface{}, len(nodes))
+	var cmdsMut sync.Mutex
+
+	for i, node := range nodes {
+		hello := bson.D{
+			{Key: "ismaster", Value: node.primary},
+			{Key: "isWritablePrimary", Value: node.primary},
+			{Key: "secondary", Value: !node.primary},
+			{Key: "helloOk", Value: true},
+			{Key: "minWireVersion", Value: int32(0)},
+			{Key: "maxWireVersion", Value: int32(21)},
+			{Key: "maxBsonObjectSize", Value: int32(16777216)},
+			{Key: "maxMessageSizeBytes", Value: int32(48000000)},
+			{Key: "maxWriteBatchSize", Value: int32(100000)},
+			{Key: "ok", Value: 1.0},
+		}
+		if len(node.setName) > 0 {
+			hello = append(hello,
+				bson.E{Key: "setName", Value: node.setName},
+				bson.E{Key: "hosts", Value: addrs},
+				bson.E{Key: "me", Value: addrs[i]},
+			)
+			for j, n := range nodes {
+				if n.primary {
+					hello = append(hello, bson.E{Key: "primary", Value: addrs[j]})
+				}
+			}
+		}
+
+		go func(i int, ln net.Listener, hello bson.D) {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				go func(conn net.Conn) {
+					defer conn.Close()
+					rd := bufio.NewReader(conn)
+					for {
+						reqID, opCode, cmd, err := readFakeMongoDBMessage(rd)
+						if err != nil {
+							return
+						}
+						var res interface{} = bson.D{{Key: "ok", Value: 1.0}}
+						switch {
+						case cmd["hello"] != nil, cmd["isMaster"] != nil, cmd["ismaster"] != nil:
+							res = hello
+						case cmd["insert"] != nil, cmd["update"] != nil:
+							cmdsMut.Lock()
+							cmds[i] = append(cmds[i], cmd)
+							cmdsMut.Unlock()
+
+							writes, _ := cmd["documents"].([]interface{})
+							if writes == nil {
+								writes, _ = cmd["updates"].([]interface{})
+							}
+							var wErrs bson.A
+							for j, w := range writes {
+								if _, exists := w.(map[string]interface{})["fail"]; exists {
+									wErrs = append(wErrs, bson.D{
+										{Key: "index", Value: int32(j)},
+										{Key: "code", Value: int32(11000)},
+										{Key: "errmsg", Value: "duplicate key"},
+									})
+								}
+							}
+							resDoc := bson.D{
+								{Key: "n", Value: int32(len(writes) - len(wErrs))},
+								{Key: "ok", Value: 1.0},
+							}
+							if len(wErrs) > 0 {
+								resDoc = append(resDoc, bson.E{Key: "writeErrors", Value: wErrs})
+							}
+							res = resDoc
+						}
+						if err = writeFakeMongoDBReply(conn, reqID, opCode, res); err != nil {
+							return
+						}
+					}
+				}(conn)
+			}
+		}(i, lns[i], hello)
+	}
+
+	return addrs, func(i int) []map[string]interface{} {
+			cmdsMut.Lock()
+			defer cmdsMut.Unlock()
+			return append([]map[string]interface{}{}, cmds[i]...)
+		}, func() {
+			for _, ln := range lns {
+				ln.Close()
+			}
+		}
+}
+
+// readFakeMongoDBMessage reads an OP_QUERY or OP_MSG command, where document
+// sequences are added to the command as arrays, and where the command is
+// converted into a generic JSON structure.
+func readFakeMongoDBMessage(r io.Reader) (int32, wiremessage.OpCode, map[string]interface{}, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+	msg := make([]byte, binary.LittleEndian.Uint32(header))
+	copy(msg, header)
+	if _, err := io.ReadFull(r, msg[16:]); err != nil {
+		return 0, 0, nil, err
+	}
+	_, reqID, _, opCode, rem, _ := wiremessage.ReadHeader(msg)
+
+	toJSON := func(doc bsoncore.Document) interface{} {
+		b, _ := bson.MarshalExtJSON(bson.Raw(doc), false, false)
+		var v interface{}
+		json.Unmarshal(b, &v)
+		return v
+	}
+
+	cmd := map[string]interface{}{}
+	if opCode == wiremessage.OpQuery {
+		_, rem, _ = wiremessage.ReadQueryFlags(rem)
+		_, rem, _ = wiremessage.ReadQueryFullCollectionName(rem)
+		_, rem, _ = wiremessage.ReadQueryNumberToSkip(rem)
+		_, rem, _ = wiremessage.ReadQueryNumberToReturn(rem)
+		query, _, _ := wiremessage.ReadQueryQuery(rem)
+		cmd, _ = toJSON(query).(map[string]interface{})
+		return reqID, opCode, cmd, nil
+	}
+
+	_, rem, _ = wiremessage.ReadMsgFlags(rem)
+	for len(rem) > 0 {
+		var stype wiremessage.SectionType
+		var ok bool
+		if stype, rem, ok = wiremessage.ReadMsgSectionType(rem); !ok {
+			break
+		}
+		if stype == wiremessage.SingleDocument {
+			var doc bsoncore.Document
+			doc, rem, _ = wiremessage.ReadMsgSectionSingleDocument(rem)
+			for k, v := range toJSON(doc).(map[string]interface{}) {
+				cmd[k] = v
+			}
+			continue
+		}
+		var id string
+		var docs []bsoncore.Document
+		id, docs, rem, _ = wiremessage.ReadMsgSectionDocumentSequence(rem)
+		seq := []interface{}{}
+		for _, d := range docs {
+			seq = append(seq, toJSON(d))
+		}
+		cmd[id] = seq
+	}
+	return reqID, opCode, cmd, nil
+}
+
+// writeFakeMongoDBReply writes a reply to a command of either an OP_QUERY or
+// OP_MSG message.
+func writeFakeMongoDBReply(w io.Writer, respTo int32, opCode wiremessage.OpCode, res interface{}) error {
+	doc, err := bson.Marshal(res)
+	if err != nil {
+		return err
+	}
+	var idx int32
+	var msg []byte
+	if opCode == wiremessage.OpQuery {
+		idx, msg = wiremessage.AppendHeaderStart(nil, 1, respTo, wiremessage.OpReply)
+		msg = wiremessage.AppendReplyFlags(msg, 0)
+		msg = wiremessage.AppendReplyCursorID(msg, 0)
+		msg = wiremessage.AppendReplyStartingFrom(msg, 0)
+		msg = wiremessage.AppendReplyNumberReturned(msg, 1)
+	} else {
+		idx, msg = wiremessage.AppendHeaderStart(nil, 1, respTo, wiremessage.OpMsg)
+		msg = wiremessage.AppendMsgFlags(msg, 0)
+		msg = wiremessage.AppendMsgSectionType(msg, wiremessage.SingleDocument)
+	}
+	msg = append(msg, doc...)
+	msg = bsoncore.UpdateLength(msg, idx, int32(len(msg)))
+	_, err = w.Write(msg)
+	return err
+}
+
+// fakeMongoDBCommand returns the fields of a command that are relevant to the
+// tests, omitting fields added by the driver such as sessions.
+func fakeMongoDBCommand(cmd map[string]interface{}) map[string]interface{} {
+	relevant := map[string]interface{}{}
+	for _, k := range []string{"insert", "update", "ordered", "$db", "documents", "updates"} {
+		if v, exists := cmd[k]; exists {
+			relevant[k] = v
+		}
+	}
+	return relevant
+}
+
+func TestMongoDBBadConfig(t *testing.T) {
+	conf := NewMongoDBConfig()
+	conf.Collection = "bar"
+	if _, err := NewMongoDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing database")
+	}
+
+	conf.Database = "foo"
+	conf.Operation = "replace"
+	if _, err := NewMongoDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing filter map")
+	}
+
+	conf.Operation = "delete"
+	if _, err := NewMongoDB(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad operation")
+	}
+}
+
+func TestMongoDBOperations(t *testing.T) {
+	addrs, cmds, closeFn := fakeMongoDBServers(t, fakeMongoDBNode{primary: true})
+	defer closeFn()
+
+	tests := map[string]struct {
+		operation   string
+		documentMap map[string]string
+		upsert      bool
+		exp         map[string]interface{}
+	}{
+		"insert": {
+			operation:   "insert",
+			documentMap: map[string]string{"_id": "id", "value": "value"},
+			exp: map[string]interface{}{
+				"insert":  "bar",
+				"ordered": true,
+				"$db":     "foo",
+				"documents": []interface{}{
+					map[string]interface{}{"_id": "a", "value": map[string]interface{}{"count": 1.0}},
+					map[string]interface{}{"_id": "b", "value": map[string]interface{}{"count": 2.0}},
+				},
+			},
+		},
+		"replace": {
+			operation:   "replace",
+			documentMap: map[string]string{"count": "value.count"},
+			upsert:      true,
+			exp: map[string]interface{}{
+				"update":  "bar",
+				"ordered": true,
+				"$db":     "foo",
+				"updates": []interface{}{
+					map[string]interface{}{
+						"q":      map[string]interface{}{"_id": "a"},
+						"u":      map[string]interface{}{"count": 1.0},
+						"upsert": true,
+					},
+					map[string]interface{}{
+						"q":      map[string]interface{}{"_id": "b"},
+						"u":      map[string]interface{}{"count": 2.0},
+						"upsert": true,
+					},
+				},
+			},
+		},
+		"update": {
+			operation:   "update",
+			documentMap: map[string]string{"count": "value.count"},
+			exp: map[string]interface{}{
+				"update":  "bar",
+				"ordered": true,
+				"$db":     "foo",
+				"updates": []interface{}{
+					map[string]interface{}{
+						"q":      map[string]interface{}{"_id": "a"},
+						"u":      map[string]interface{}{"$set": map[string]interface{}{"count": 1.0}},
+						"upsert": false,
+					},
+					map[string]interface{}{
+						"q":      map[string]interface{}{"_id": "b"},
+						"u":      map[string]interface{}{"$set": map[string]interface{}{"count": 2.0}},
+						"upsert": false,
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewMongoDBConfig()
+		conf.URL = "mongodb://" + addrs[0] + "/foo"
+		conf.Collection = "bar"
+		conf.Operation = test.operation
+		conf.DocumentMap = test.documentMap
+		conf.FilterMap = map[string]string{"_id": "id"}
+		conf.Upsert = test.upsert
+
+		m, err := NewMongoDB(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = m.Connect(); err != nil {
+			t.Fatal(err)
+		}
+
+		before := len(cmds(0))
+		if err = m.Write(message.New([][]byte{
+			[]byte(`{"id":"a","value":{"count":1}}`),
+			[]byte(`not json`),
+			[]byte(`{"id":"b","value":{"count":2}}`),
+		})); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		m.CloseAsync()
+
+		act := cmds(0)
+		if len(act) != before+1 {
+			t.Fatalf("%v: Wrong count of commands: %v", name, len(act)-before)
+		}
+		if cmd := fakeMongoDBCommand(act[before]); !reflect.DeepEqual(test.exp, cmd) {
+			t.Errorf("%v: Wrong command: %v != %v", name, cmd, test.exp)
+		}
+	}
+}
+
+func TestMongoDBWriteErrors(t *testing.T) {
+	addrs, _, closeFn := fakeMongoDBServers(t, fakeMongoDBNode{primary: true})
+	defer closeFn()
+
+	conf := NewMongoDBConfig()
+	conf.URL = "mongodb://" + addrs[0]
+	conf.Database = "foo"
+	conf.Collection = "bar"
+
+	m, err := NewMongoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.CloseAsync()
+
+	err = m.Write(message.New([][]byte{
+		[]byte(`{"id":"a"}`),
+		[]byte(`{"id":"b","fail":true}`),
+	}))
+	if _, ok := err.(mongo.BulkWriteException); !ok {
+		t.Fatalf("Expected write errors, received: %v", err)
+	}
+
+	// The connection should remain open after write errors.
+	if err = m.Write(message.New([][]byte{[]byte(`{"id":"c"}`)})); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMongoDBReplicaSetPrimary(t *testing.T) {
+	addrs, cmds, closeFn := fakeMongoDBServers(t,
+		fakeMongoDBNode{setName: "rs0"},
+		fakeMongoDBNode{setName: "rs0", primary: true},
+	)
+	defer closeFn()
+
+	// Only the secondary is listed as a seed, the primary must be discovered.
+	conf := NewMongoDBConfig()
+	conf.URL = "mongodb://" + addrs[0] + "/foo?replicaSet=rs0"
+	conf.Collection = "bar"
+
+	m, err := NewMongoDB(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.CloseAsync()
+
+	if err = m.Write(message.New([][]byte{[]byte(`{"id":"a"}`)})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 0, len(cmds(0)); exp != act {
+		t.Errorf("Wrong count of commands sent to secondary: %v != %v", act, exp)
+	}
+	if exp, act := 1, len(cmds(1)); exp != act {
+		t.Errorf("Wrong count of commands sent to primary: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -21,13 +21,18 @@
 package writer
 
 import (
+	"crypto/tls"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -35,10 +40,14 @@ import (
 
 // MQTTConfig contains configuration fields for the MQTT output type.
 type MQTTConfig struct {
-	URLs     []string `json:"urls" yaml:"urls"`
-	QoS      uint8    `json:"qos" yaml:"qos"`
-	Topic    string   `json:"topic" yaml:"topic"`
-	ClientID string   `json:"client_id" yaml:"client_id"`
+	URLs     []string    `json:"urls" yaml:"urls"`
+	QoS      uint8       `json:"qos" yaml:"qos"`
+	Retained bool        `json:"retained" yaml:"retained"`
+	Topic    string      `json:"topic" yaml:"topic"`
+	ClientID string      `json:"client_id" yaml:"client_id"`
+	User     string      `json:"user" yaml:"user"`
+	Password string      `json:"password" yaml:"password"`
+	TLS      btls.Config `json:"tls" yaml:"tls"`
 }
 
 // NewMQTTConfig creates a new MQTTConfig with default values.
@@ -46,8 +55,12 @@ func NewMQTTConfig() MQTTConfig {
 	return MQTTConfig{
 		URLs:     []string{"tcp://localhost:1883"},
 		QoS:      1,
+		Retained: false,
 		Topic:    "benthos_topic",
 		ClientID: "benthos_output",
+		User:     "",
+		Password: "",
+		TLS:      btls.NewConfig(),
 	}
 }
 
@@ -58,8 +71,10 @@ type MQTT struct {
 	log   log.Modular
 	stats metrics.Type
 
-	urls []string
-	conf MQTTConfig
+	urls    []string
+	conf    MQTTConfig
+	topic   *text.InterpolatedString
+	tlsConf *tls.Config
 
 	client  mqtt.Client
 	connMut sync.RWMutex
@@ -75,6 +90,17 @@ func NewMQTT(
 		log:   log,
 		stats: stats,
 		conf:  conf,
+		topic: text.NewInterpolatedString(conf.Topic),
+	}
+
+	if conf.QoS > 2 {
+		return nil, fmt.Errorf("qos level not supported: %v", conf.QoS)
+	}
+	if conf.TLS.Enabled {
+		var err error
+		if m.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
 	}
 
 	for _, u := range conf.URLs {
@@ -105,6 +131,16 @@ func (m *MQTT) Connect() error {
 		SetWriteTimeout(time.Second).
 		SetClientID(m.conf.ClientID)
 
+	if m.tlsConf != nil {
+		conf = conf.SetTLSConfig(m.tlsConf)
+	}
+	if len(m.conf.User) > 0 {
+		conf = conf.SetUsername(m.conf.User)
+	}
+	if len(m.conf.Password) > 0 {
+		conf = conf.SetPassword(m.conf.Password)
+	}
+
 	for _, u := range m.urls {
 		conf = conf.AddBroker(u)
 	}
@@ -117,6 +153,7 @@ func (m *MQTT) Connect() error {
 		return err
 	}
 
+	m.log.Infof("Sending MQTT messages to topic: %v\n", m.conf.Topic)
 	m.client = client
 	return nil
 }
@@ -134,7 +171,8 @@ func (m *MQTT) Write(msg types.Message) error {
 	}
 
 	return msg.Iter(func(i int, p types.Part) error {
-		mtok := client.Publish(m.conf.Topic, byte(m.conf.QoS), false, p.Get())
+		topic := m.topic.Get(message.Lock(msg, i))
+		mtok := client.Publish(topic, byte(m.conf.QoS), m.conf.Retained, p.Get())
 		mtok.Wait()
 		return mtok.Error()
 	})
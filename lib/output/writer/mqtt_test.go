@@ -0,0 +1,61 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+)
+
+func TestMQTTBadConfig(t *testing.T) {
+	conf := NewMQTTConfig()
+	conf.QoS = 3
+	if _, err := NewMQTT(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad qos")
+	}
+
+	conf = NewMQTTConfig()
+	conf.TLS.Enabled = true
+	conf.TLS.ClientCertificates = []btls.ClientCertConfig{
+		{CertFile: "/does/not/exist.pem", KeyFile: "/does/not/exist.key"},
+	}
+	if _, err := NewMQTT(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing client certificate")
+	}
+}
+
+func TestMQTTURLs(t *testing.T) {
+	conf := NewMQTTConfig()
+	conf.URLs = []string{"tcp://foo:1883,tcp://bar:1883", "", "tcp://baz:1883"}
+	for _, qos := range []uint8{0, 1, 2} {
+		conf.QoS = qos
+		m, err := NewMQTT(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := 3, len(m.urls); exp != act {
+			t.Errorf("Wrong count of urls: %v != %v", act, exp)
+		}
+	}
+}
@@ -27,10 +27,13 @@ import (
 	"time"
 
 	"nanomsg.org/go-mangos"
+	"nanomsg.org/go-mangos/protocol/pair"
 	"nanomsg.org/go-mangos/protocol/pub"
 	"nanomsg.org/go-mangos/protocol/push"
+	"nanomsg.org/go-mangos/transport/inproc"
 	"nanomsg.org/go-mangos/transport/ipc"
 	"nanomsg.org/go-mangos/transport/tcp"
+	"nanomsg.org/go-mangos/transport/ws"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
@@ -41,19 +44,23 @@ import (
 
 // NanomsgConfig contains configuration fields for the Nanomsg output type.
 type NanomsgConfig struct {
-	URLs        []string `json:"urls" yaml:"urls"`
-	Bind        bool     `json:"bind" yaml:"bind"`
-	SocketType  string   `json:"socket_type" yaml:"socket_type"`
-	PollTimeout string   `json:"poll_timeout" yaml:"poll_timeout"`
+	URLs                 []string `json:"urls" yaml:"urls"`
+	Bind                 bool     `json:"bind" yaml:"bind"`
+	SocketType           string   `json:"socket_type" yaml:"socket_type"`
+	PollTimeout          string   `json:"poll_timeout" yaml:"poll_timeout"`
+	ReconnectInterval    string   `json:"reconnect_interval" yaml:"reconnect_interval"`
+	MaxReconnectInterval string   `json:"max_reconnect_interval" yaml:"max_reconnect_interval"`
 }
 
 // NewNanomsgConfig creates a new NanomsgConfig with default values.
 func NewNanomsgConfig() NanomsgConfig {
 	return NanomsgConfig{
-		URLs:        []string{"tcp://localhost:5556"},
-		Bind:        false,
-		SocketType:  "PUSH",
-		PollTimeout: "5s",
+		URLs:                 []string{"tcp://localhost:5556"},
+		Bind:                 false,
+		SocketType:           "PUSH",
+		PollTimeout:          "5s",
+		ReconnectInterval:    "100ms",
+		MaxReconnectInterval: "",
 	}
 }
 
@@ -67,7 +74,9 @@ type Nanomsg struct {
 	urls []string
 	conf NanomsgConfig
 
-	timeout time.Duration
+	timeout   time.Duration
+	reconn    time.Duration
+	reconnMax time.Duration
 
 	socket  mangos.Socket
 	sockMut sync.RWMutex
@@ -94,6 +103,18 @@ func NewNanomsg(conf NanomsgConfig, log log.Modular, stats metrics.Type) (*Nanom
 			return nil, fmt.Errorf("failed to parse poll timeout string: %v", err)
 		}
 	}
+	if tout := conf.ReconnectInterval; len(tout) > 0 {
+		var err error
+		if s.reconn, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse reconnect interval string: %v", err)
+		}
+	}
+	if tout := conf.MaxReconnectInterval; len(tout) > 0 {
+		var err error
+		if s.reconnMax, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse max reconnect interval string: %v", err)
+		}
+	}
 
 	socket, err := getSocketFromType(conf.SocketType)
 	if nil != err {
@@ -112,6 +133,8 @@ func getSocketFromType(t string) (mangos.Socket, error) {
 		return push.NewSocket()
 	case "PUB":
 		return pub.NewSocket()
+	case "PAIR":
+		return pair.NewSocket()
 	}
 	return nil, types.ErrInvalidScaleProtoType
 }
@@ -132,13 +155,26 @@ func (s *Nanomsg) Connect() error {
 
 	// Set timeout to prevent endless lock.
 	if err = socket.SetOption(
-		mangos.OptionRecvDeadline, s.timeout,
+		mangos.OptionSendDeadline, s.timeout,
 	); nil != err {
+		socket.Close()
+		return err
+	}
+	if s.reconn > 0 {
+		if err = socket.SetOption(mangos.OptionReconnectTime, s.reconn); err != nil {
+			socket.Close()
+			return err
+		}
+	}
+	if err = socket.SetOption(mangos.OptionMaxReconnectTime, s.reconnMax); err != nil {
+		socket.Close()
 		return err
 	}
 
+	socket.AddTransport(inproc.NewTransport())
 	socket.AddTransport(ipc.NewTransport())
 	socket.AddTransport(tcp.NewTransport())
+	socket.AddTransport(ws.NewTransport())
 
 	if s.conf.Bind {
 		for _, addr := range s.urls {
@@ -154,6 +190,7 @@ func (s *Nanomsg) Connect() error {
 		}
 	}
 	if err != nil {
+		socket.Close()
 		return err
 	}
 
@@ -26,7 +26,9 @@ import (
 	"time"
 
 	"nanomsg.org/go-mangos"
+	"nanomsg.org/go-mangos/protocol/pair"
 	"nanomsg.org/go-mangos/protocol/pull"
+	"nanomsg.org/go-mangos/transport/inproc"
 	"nanomsg.org/go-mangos/transport/tcp"
 
 	"github.com/Jeffail/benthos/lib/log"
@@ -94,4 +96,67 @@ func TestNanomsgBasic(t *testing.T) {
 	}
 }
 
+func TestNanomsgBadConfig(t *testing.T) {
+	conf := NewNanomsgConfig()
+	conf.SocketType = "REQ"
+	if _, err := NewNanomsg(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad socket type")
+	}
+
+	conf = NewNanomsgConfig()
+	conf.ReconnectInterval = "not a duration"
+	if _, err := NewNanomsg(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad reconnect interval")
+	}
+}
+
+func TestNanomsgPairDial(t *testing.T) {
+	socket, err := pair.NewSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer socket.Close()
+
+	socket.AddTransport(inproc.NewTransport())
+	socket.SetOption(mangos.OptionRecvDeadline, time.Second)
+
+	if err = socket.Listen("inproc://benthos_nanomsg_pair_test"); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewNanomsgConfig()
+	conf.URLs = []string{"inproc://benthos_nanomsg_pair_test"}
+	conf.SocketType = "PAIR"
+	conf.PollTimeout = "1s"
+	conf.ReconnectInterval = "10ms"
+	conf.MaxReconnectInterval = "100ms"
+
+	s, err := NewNanomsg(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		s.CloseAsync()
+		if err = s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err = s.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"foo", "bar"} {
+		data, err := socket.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(data); exp != act {
+			t.Errorf("Wrong value on output: %v != %v", act, exp)
+		}
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,231 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/cenkalti/backoff"
+	"github.com/nats-io/nats.go"
+)
+
+//------------------------------------------------------------------------------
+
+// NATSJetStreamConfig contains configuration fields for the NATSJetStream
+// output type.
+type NATSJetStreamConfig struct {
+	URLs           []string `json:"urls" yaml:"urls"`
+	Subject        string   `json:"subject" yaml:"subject"`
+	Stream         string   `json:"stream" yaml:"stream"`
+	MsgID          string   `json:"msg_id" yaml:"msg_id"`
+	AckTimeout     string   `json:"ack_timeout" yaml:"ack_timeout"`
+	retries.Config `json:",inline" yaml:",inline"`
+}
+
+// NewNATSJetStreamConfig creates a new NATSJetStreamConfig with default values.
+func NewNATSJetStreamConfig() NATSJetStreamConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "500ms"
+	rConf.Backoff.MaxInterval = "5s"
+	rConf.Backoff.MaxElapsedTime = "30s"
+	return NATSJetStreamConfig{
+		URLs:       []string{nats.DefaultURL},
+		Subject:    "benthos_messages",
+		Stream:     "",
+		MsgID:      "",
+		AckTimeout: "5s",
+		Config:     rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NATSJetStream is an output type that publishes messages to NATS JetStream
+// streams, waiting for each message to be acknowledged by the server.
+type NATSJetStream struct {
+	log   log.Modular
+	stats metrics.Type
+
+	natsConn *nats.Conn
+	js       nats.JetStreamContext
+	connMut  sync.RWMutex
+
+	urls       string
+	conf       NATSJetStreamConfig
+	subject    *text.InterpolatedString
+	msgID      *text.InterpolatedString
+	ackTimeout time.Duration
+	newBackoff func() backoff.BackOff
+
+	mDuplicate metrics.StatCounter
+	mRetry     metrics.StatCounter
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewNATSJetStream creates a new NATSJetStream output type.
+func NewNATSJetStream(conf NATSJetStreamConfig, log log.Modular, stats metrics.Type) (*NATSJetStream, error) {
+	n := NATSJetStream{
+		log:        log,
+		stats:      stats,
+		conf:       conf,
+		subject:    text.NewInterpolatedString(conf.Subject),
+		mDuplicate: stats.GetCounter("duplicate"),
+		mRetry:     stats.GetCounter("retry"),
+		closeChan:  make(chan struct{}),
+	}
+	n.urls = strings.Join(conf.URLs, ",")
+	if len(conf.MsgID) > 0 {
+		n.msgID = text.NewInterpolatedString(conf.MsgID)
+	}
+
+	var err error
+	if n.newBackoff, err = conf.GetCtor(); err != nil {
+		return nil, err
+	}
+	if len(conf.AckTimeout) > 0 {
+		if n.ackTimeout, err = time.ParseDuration(conf.AckTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse ack timeout duration string: %v", err)
+		}
+	}
+	return &n, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to NATS servers.
+func (n *NATSJetStream) Connect() error {
+	n.connMut.Lock()
+	defer n.connMut.Unlock()
+
+	if n.natsConn != nil {
+		return nil
+	}
+
+	conn, err := nats.Connect(n.urls)
+	if err != nil {
+		return err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	n.natsConn, n.js = conn, js
+	n.log.Infof("Sending NATS JetStream messages to subject: %v\n", n.conf.Subject)
+	return nil
+}
+
+// publish sends a message to a subject and waits for it to be acknowledged.
+// When a message ID is set the server deduplicates publishes, and therefore a
+// publish that times out is retried. Without an ID a retry could store the
+// message twice, and so the timeout is returned instead.
+func (n *NATSJetStream) publish(js nats.JetStreamContext, msg *nats.Msg, id string) error {
+	opts := []nats.PubOpt{nats.AckWait(n.ackTimeout)}
+	if len(n.conf.Stream) > 0 {
+		opts = append(opts, nats.ExpectStream(n.conf.Stream))
+	}
+	if len(id) > 0 {
+		opts = append(opts, nats.MsgId(id))
+	}
+
+	boff := n.newBackoff()
+	for {
+		ack, err := js.PublishMsg(msg, opts...)
+		if err == nil {
+			if ack.Duplicate {
+				n.mDuplicate.Incr(1)
+			}
+			return nil
+		}
+		if len(id) == 0 || !errors.Is(err, nats.ErrTimeout) {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return fmt.Errorf("no publish ack received for subject '%v': %v", msg.Subject, err)
+		}
+		n.mRetry.Incr(1)
+		select {
+		case <-time.After(wait):
+		case <-n.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+}
+
+// Write attempts to write a message.
+func (n *NATSJetStream) Write(msg types.Message) error {
+	n.connMut.RLock()
+	js := n.js
+	n.connMut.RUnlock()
+
+	if js == nil {
+		return types.ErrNotConnected
+	}
+
+	return msg.Iter(func(i int, p types.Part) error {
+		lMsg := message.Lock(msg, i)
+		nMsg := nats.NewMsg(n.subject.Get(lMsg))
+		nMsg.Data = p.Get()
+
+		var id string
+		if n.msgID != nil {
+			id = n.msgID.Get(lMsg)
+		}
+		return n.publish(js, nMsg, id)
+	})
+}
+
+// CloseAsync shuts down the NATSJetStream output and stops processing
+// messages.
+func (n *NATSJetStream) CloseAsync() {
+	n.closeOnce.Do(func() {
+		close(n.closeChan)
+	})
+	n.connMut.Lock()
+	if n.natsConn != nil {
+		n.natsConn.Close()
+		n.natsConn = nil
+		n.js = nil
+	}
+	n.connMut.Unlock()
+}
+
+// WaitForClose blocks until the NATSJetStream output has closed down.
+func (n *NATSJetStream) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
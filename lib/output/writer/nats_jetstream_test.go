@@ -0,0 +1,314 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// fakeNATSServer speaks enough of the NATS protocol to serve request/reply
+// calls from a single client, responding to publishes with the result of
+// handler, or not at all when handler returns nil.
+func fakeNATSServer(t *testing.T, handler func(subject string, headers map[string]string, data []byte) []byte) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		conn, err := ln.Accept()
+		ln.Close()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var writeMut sync.Mutex
+		write := func(s string) {
+			writeMut.Lock()
+			conn.Write([]byte(s))
+			writeMut.Unlock()
+		}
+		write(`INFO {"server_id":"fake","version":"2.10.0","headers":true,"max_payload":1048576}` + "\r\n")
+
+		subs := map[string]string{}
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args := strings.Fields(line)
+			if len(args) == 0 {
+				continue
+			}
+			switch cmd := strings.ToUpper(args[0]); cmd {
+			case "PING":
+				write("PONG\r\n")
+			case "SUB":
+				subs[strings.TrimSuffix(args[1], "*")] = args[len(args)-1]
+			case "PUB", "HPUB":
+				size, _ := strconv.Atoi(args[len(args)-1])
+				data := make([]byte, size+2)
+				if _, err = io.ReadFull(r, data); err != nil {
+					return
+				}
+				data = data[:size]
+
+				headers := map[string]string{}
+				if cmd == "HPUB" {
+					hdrSize, _ := strconv.Atoi(args[len(args)-2])
+					for _, hdr := range strings.Split(string(data[:hdrSize]), "\r\n")[1:] {
+						if kv := strings.SplitN(hdr, ":", 2); len(kv) == 2 {
+							headers[kv[0]] = strings.TrimSpace(kv[1])
+						}
+					}
+					data = data[hdrSize:]
+					args = args[:len(args)-1]
+				}
+				if len(args) < 4 {
+					continue
+				}
+				res := handler(args[1], headers, data)
+				if res == nil {
+					continue
+				}
+				reply := args[2]
+				for prefix, sid := range subs {
+					if strings.HasPrefix(reply, prefix) {
+						write(fmt.Sprintf("MSG %v %v %v\r\n%s\r\n", reply, sid, len(res), res))
+					}
+				}
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String()
+}
+
+//------------------------------------------------------------------------------
+
+func TestNATSJetStreamBadConfig(t *testing.T) {
+	conf := NewNATSJetStreamConfig()
+	conf.AckTimeout = "nope"
+	if _, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad ack timeout")
+	}
+
+	conf = NewNATSJetStreamConfig()
+	conf.Backoff.InitialInterval = "nope"
+	if _, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad backoff")
+	}
+}
+
+func TestNATSJetStreamWrite(t *testing.T) {
+	var resMut sync.Mutex
+	received := map[string][]string{}
+	msgIDs := []string{}
+
+	url := fakeNATSServer(t, func(subject string, headers map[string]string, data []byte) []byte {
+		resMut.Lock()
+		received[subject] = append(received[subject], string(data))
+		msgIDs = append(msgIDs, headers["Nats-Msg-Id"])
+		resMut.Unlock()
+		return []byte(`{"stream":"foo","seq":1}`)
+	})
+
+	conf := NewNATSJetStreamConfig()
+	conf.URLs = []string{url}
+	conf.Subject = `${!metadata:topic}`
+	conf.Stream = "foo"
+	conf.MsgID = `${!metadata:id}`
+
+	w, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	msg := message.New([][]byte{[]byte("first"), []byte("second")})
+	msg.Get(0).Metadata().Set("topic", "a").Set("id", "1")
+	msg.Get(1).Metadata().Set("topic", "b").Set("id", "2")
+	if err = w.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	resMut.Lock()
+	defer resMut.Unlock()
+	exp := map[string][]string{
+		"a": {"first"},
+		"b": {"second"},
+	}
+	if !reflect.DeepEqual(exp, received) {
+		t.Errorf("Wrong published messages: %v != %v", received, exp)
+	}
+	if exp := []string{"1", "2"}; !reflect.DeepEqual(exp, msgIDs) {
+		t.Errorf("Wrong message IDs: %v != %v", msgIDs, exp)
+	}
+}
+
+func TestNATSJetStreamPublishError(t *testing.T) {
+	url := fakeNATSServer(t, func(subject string, headers map[string]string, data []byte) []byte {
+		return []byte(`{"error":{"code":503,"err_code":10039,"description":"jetstream not enabled"}}`)
+	})
+
+	conf := NewNATSJetStreamConfig()
+	conf.URLs = []string{url}
+
+	w, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	err = w.Write(message.New([][]byte{[]byte("foo")}))
+	if err == nil || !strings.Contains(err.Error(), "jetstream not enabled") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestNATSJetStreamTimeoutRetries(t *testing.T) {
+	var attemptsMut sync.Mutex
+	attempts := 0
+
+	url := fakeNATSServer(t, func(subject string, headers map[string]string, data []byte) []byte {
+		attemptsMut.Lock()
+		defer attemptsMut.Unlock()
+		if attempts++; attempts < 3 {
+			return nil
+		}
+		return []byte(`{"stream":"foo","seq":1,"duplicate":true}`)
+	})
+
+	conf := NewNATSJetStreamConfig()
+	conf.URLs = []string{url}
+	conf.MsgID = "foo"
+	conf.AckTimeout = "50ms"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	w, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	if err = w.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Fatal(err)
+	}
+
+	attemptsMut.Lock()
+	if exp, act := 3, attempts; exp != act {
+		t.Errorf("Wrong count of attempts: %v != %v", act, exp)
+	}
+	attemptsMut.Unlock()
+}
+
+func TestNATSJetStreamTimeoutNoMsgID(t *testing.T) {
+	var attemptsMut sync.Mutex
+	attempts := 0
+
+	url := fakeNATSServer(t, func(subject string, headers map[string]string, data []byte) []byte {
+		attemptsMut.Lock()
+		attempts++
+		attemptsMut.Unlock()
+		return nil
+	})
+
+	conf := NewNATSJetStreamConfig()
+	conf.URLs = []string{url}
+	conf.AckTimeout = "50ms"
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	w, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	if err = w.Write(message.New([][]byte{[]byte("foo")})); err == nil {
+		t.Error("Expected error")
+	}
+
+	attemptsMut.Lock()
+	if exp, act := 1, attempts; exp != act {
+		t.Errorf("Wrong count of attempts: %v != %v", act, exp)
+	}
+	attemptsMut.Unlock()
+}
+
+func TestNATSJetStreamRetriesExhausted(t *testing.T) {
+	url := fakeNATSServer(t, func(subject string, headers map[string]string, data []byte) []byte {
+		return nil
+	})
+
+	conf := NewNATSJetStreamConfig()
+	conf.URLs = []string{url}
+	conf.MsgID = "foo"
+	conf.AckTimeout = "10ms"
+	conf.MaxRetries = 1
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	w, err := NewNATSJetStream(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	err = w.Write(message.New([][]byte{[]byte("foo")}))
+	if err == nil || !strings.Contains(err.Error(), "no publish ack received") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+//------------------------------------------------------------------------------
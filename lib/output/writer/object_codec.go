@@ -0,0 +1,111 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// checkObjectCodec returns an error if a batch codec or compression algorithm
+// used for writing objects is not recognised.
+func checkObjectCodec(codec, compression string) error {
+	switch codec {
+	case "none", "lines", "tar":
+	default:
+		return fmt.Errorf("batch codec not recognised: %v", codec)
+	}
+	switch compression {
+	case "none", "gzip":
+	default:
+		return fmt.Errorf("compression type not recognised: %v", compression)
+	}
+	return nil
+}
+
+// encodeObject writes the contents of message parts as the body of a single
+// object using a batch codec and compression algorithm.
+func encodeObject(codec, compression string, parts []types.Part) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+
+	var zw *gzip.Writer
+	if compression == "gzip" {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+
+	switch codec {
+	case "tar":
+		tw := tar.NewWriter(w)
+		for i, p := range parts {
+			if err := tw.WriteHeader(&tar.Header{
+				Name:    strconv.Itoa(i),
+				Mode:    0644,
+				Size:    int64(len(p.Get())),
+				ModTime: time.Now(),
+			}); err != nil {
+				return nil, err
+			}
+			if _, err := tw.Write(p.Get()); err != nil {
+				return nil, err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return nil, err
+		}
+	case "lines":
+		for i, p := range parts {
+			if i > 0 {
+				if _, err := w.Write([]byte("\n")); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := w.Write(p.Get()); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		for _, p := range parts {
+			if _, err := w.Write(p.Get()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,307 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/pulsar"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/gorilla/websocket"
+)
+
+//------------------------------------------------------------------------------
+
+// PulsarConfig contains configuration fields for the Pulsar output type.
+type PulsarConfig struct {
+	URL                     string      `json:"url" yaml:"url"`
+	Topic                   string      `json:"topic" yaml:"topic"`
+	Key                     string      `json:"key" yaml:"key"`
+	ProducerName            string      `json:"producer_name" yaml:"producer_name"`
+	Compression             string      `json:"compression" yaml:"compression"`
+	BatchingEnabled         bool        `json:"batching_enabled" yaml:"batching_enabled"`
+	BatchingMaxMessages     int         `json:"batching_max_messages" yaml:"batching_max_messages"`
+	BatchingMaxPublishDelay string      `json:"batching_max_publish_delay" yaml:"batching_max_publish_delay"`
+	MaxPendingMessages      int         `json:"max_pending_messages" yaml:"max_pending_messages"`
+	SendTimeout             string      `json:"send_timeout" yaml:"send_timeout"`
+	AuthToken               string      `json:"auth_token" yaml:"auth_token"`
+	TLS                     btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewPulsarConfig creates a new PulsarConfig with default values.
+func NewPulsarConfig() PulsarConfig {
+	return PulsarConfig{
+		URL:                     "ws://localhost:8080",
+		Topic:                   "persistent://public/default/benthos",
+		Key:                     "",
+		ProducerName:            "",
+		Compression:             "none",
+		BatchingEnabled:         false,
+		BatchingMaxMessages:     1000,
+		BatchingMaxPublishDelay: "10ms",
+		MaxPendingMessages:      1000,
+		SendTimeout:             "30s",
+		AuthToken:               "",
+		TLS:                     btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// pulsarSend is a message sent to the Pulsar WebSocket producer API.
+type pulsarSend struct {
+	Payload    string            `json:"payload"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Context    string            `json:"context"`
+	Key        string            `json:"key,omitempty"`
+}
+
+// pulsarSendResult is the response to each message sent to the Pulsar
+// WebSocket producer API.
+type pulsarSendResult struct {
+	Result    string `json:"result"`
+	ErrorMsg  string `json:"errorMsg"`
+	MessageID string `json:"messageId"`
+	Context   string `json:"context"`
+}
+
+var pulsarCompressionTypes = map[string]string{
+	"none": "NONE",
+	"lz4":  "LZ4",
+	"zlib": "ZLIB",
+}
+
+//------------------------------------------------------------------------------
+
+// Pulsar is a writer type that produces messages to an Apache Pulsar topic
+// through the WebSocket producer API.
+type Pulsar struct {
+	conf  PulsarConfig
+	stats metrics.Type
+	log   log.Modular
+
+	key         *text.InterpolatedString
+	producerURL string
+	tlsConf     *tls.Config
+	timeout     time.Duration
+
+	context uint64
+
+	conn *websocket.Conn
+	cMut sync.Mutex
+}
+
+// NewPulsar creates a new Pulsar writer type.
+func NewPulsar(conf PulsarConfig, log log.Modular, stats metrics.Type) (*Pulsar, error) {
+	topicPath, err := pulsar.TopicPath(conf.Topic)
+	if err != nil {
+		return nil, err
+	}
+	compression, exists := pulsarCompressionTypes[conf.Compression]
+	if !exists {
+		return nil, fmt.Errorf("unrecognised compression type: %v", conf.Compression)
+	}
+
+	p := Pulsar{
+		conf:  conf,
+		stats: stats,
+		log:   log,
+		key:   text.NewInterpolatedString(conf.Key),
+	}
+
+	params := url.Values{}
+	params.Set("compressionType", compression)
+	if len(conf.ProducerName) > 0 {
+		params.Set("producerName", conf.ProducerName)
+	}
+	if conf.BatchingEnabled {
+		params.Set("batchingEnabled", "true")
+		if conf.BatchingMaxMessages > 0 {
+			params.Set("batchingMaxMessages", strconv.Itoa(conf.BatchingMaxMessages))
+		}
+		if len(conf.BatchingMaxPublishDelay) > 0 {
+			delay, err := time.ParseDuration(conf.BatchingMaxPublishDelay)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse batching max publish delay string: %v", err)
+			}
+			params.Set("batchingMaxPublishDelay", strconv.FormatInt(int64(delay/time.Millisecond), 10))
+		}
+	}
+	if conf.MaxPendingMessages > 0 {
+		params.Set("maxPendingMessages", strconv.Itoa(conf.MaxPendingMessages))
+	}
+	if len(conf.SendTimeout) > 0 {
+		if p.timeout, err = time.ParseDuration(conf.SendTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse send timeout string: %v", err)
+		}
+		params.Set("sendTimeoutMillis", strconv.FormatInt(int64(p.timeout/time.Millisecond), 10))
+	}
+	p.producerURL = fmt.Sprintf(
+		"%v/ws/v2/producer/%v?%v",
+		strings.TrimSuffix(conf.URL, "/"), topicPath, params.Encode(),
+	)
+
+	if conf.TLS.Enabled {
+		if p.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a producer connection to a Pulsar broker.
+func (p *Pulsar) Connect() error {
+	p.cMut.Lock()
+	defer p.cMut.Unlock()
+
+	if p.conn != nil {
+		return nil
+	}
+
+	headers := http.Header{}
+	if len(p.conf.AuthToken) > 0 {
+		headers.Set("Authorization", "Bearer "+p.conf.AuthToken)
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = p.tlsConf
+
+	conn, res, err := dialer.Dial(p.producerURL, headers)
+	if err != nil {
+		if res != nil {
+			return fmt.Errorf("failed to create producer: %v (%v)", err, res.Status)
+		}
+		return err
+	}
+
+	p.conn = conn
+	p.log.Infof("Sending Pulsar messages to topic: %v\n", p.conf.Topic)
+	return nil
+}
+
+func (p *Pulsar) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+// Write attempts to write a message to the Pulsar topic, waiting for each part
+// to be persisted by the broker.
+func (p *Pulsar) Write(msg types.Message) error {
+	p.cMut.Lock()
+	defer p.cMut.Unlock()
+
+	if p.conn == nil {
+		return types.ErrNotConnected
+	}
+
+	pending := map[string]struct{}{}
+	err := msg.Iter(func(i int, part types.Part) error {
+		p.context++
+		ctx := strconv.FormatUint(p.context, 10)
+
+		send := pulsarSend{
+			Payload: base64.StdEncoding.EncodeToString(part.Get()),
+			Context: ctx,
+			Key:     p.key.Get(message.Lock(msg, i)),
+		}
+		part.Metadata().Iter(func(k, v string) error {
+			if send.Properties == nil {
+				send.Properties = map[string]string{}
+			}
+			send.Properties[k] = v
+			return nil
+		})
+		if err := p.conn.WriteJSON(send); err != nil {
+			return err
+		}
+		pending[ctx] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		p.disconnect()
+		p.log.Errorf("Lost connection to Pulsar: %v\n", err)
+		return types.ErrNotConnected
+	}
+
+	if p.timeout > 0 {
+		p.conn.SetReadDeadline(time.Now().Add(p.timeout))
+		defer func() {
+			if p.conn != nil {
+				p.conn.SetReadDeadline(time.Time{})
+			}
+		}()
+	}
+
+	var sendErr error
+	for len(pending) > 0 {
+		var res pulsarSendResult
+		if err = p.conn.ReadJSON(&res); err != nil {
+			p.disconnect()
+			p.log.Errorf("Lost connection to Pulsar: %v\n", err)
+			return types.ErrNotConnected
+		}
+		if _, exists := pending[res.Context]; !exists {
+			// A late response to a previously failed write.
+			continue
+		}
+		delete(pending, res.Context)
+		if res.Result != "ok" {
+			errMsg := res.Result
+			if len(res.ErrorMsg) > 0 {
+				errMsg = errMsg + ": " + res.ErrorMsg
+			}
+			sendErr = errors.New(errMsg)
+		}
+	}
+	return sendErr
+}
+
+// CloseAsync shuts down the Pulsar writer and stops processing messages.
+func (p *Pulsar) CloseAsync() {
+	p.cMut.Lock()
+	p.disconnect()
+	p.cMut.Unlock()
+}
+
+// WaitForClose blocks until the Pulsar writer has closed down.
+func (p *Pulsar) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
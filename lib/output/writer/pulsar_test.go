@@ -0,0 +1,149 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/gorilla/websocket"
+)
+
+//------------------------------------------------------------------------------
+
+func TestPulsarBadConfig(t *testing.T) {
+	conf := NewPulsarConfig()
+	conf.Compression = "nope"
+	if _, err := NewPulsar(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad compression")
+	}
+
+	conf = NewPulsarConfig()
+	conf.Topic = "a/b"
+	if _, err := NewPulsar(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad topic")
+	}
+}
+
+func TestPulsarWrite(t *testing.T) {
+	reqs := make(chan *http.Request, 1)
+	sent := make(chan pulsarSend, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs <- r
+		upgrader := websocket.Upgrader{}
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for {
+			var send pulsarSend
+			if err := ws.ReadJSON(&send); err != nil {
+				return
+			}
+			sent <- send
+			res := pulsarSendResult{
+				Result:    "ok",
+				MessageID: "id" + send.Context,
+				Context:   send.Context,
+			}
+			if payload, _ := base64.StdEncoding.DecodeString(send.Payload); string(payload) == "fail" {
+				res.Result = "send-error:1"
+				res.ErrorMsg = "nope"
+			}
+			if err := ws.WriteJSON(res); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	conf := NewPulsarConfig()
+	conf.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+	conf.Topic = "foo"
+	conf.Key = "${!metadata:id}"
+	conf.Compression = "lz4"
+	conf.BatchingEnabled = true
+	conf.AuthToken = "tok"
+
+	p, err := NewPulsar(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseAsync()
+
+	req := <-reqs
+	if exp, act := "/ws/v2/producer/persistent/public/default/foo", req.URL.Path; exp != act {
+		t.Errorf("Wrong path: %v != %v", act, exp)
+	}
+	query := req.URL.Query()
+	if exp, act := "LZ4", query.Get("compressionType"); exp != act {
+		t.Errorf("Wrong compression: %v != %v", act, exp)
+	}
+	if exp, act := "true", query.Get("batchingEnabled"); exp != act {
+		t.Errorf("Wrong batching: %v != %v", act, exp)
+	}
+	if exp, act := "10", query.Get("batchingMaxPublishDelay"); exp != act {
+		t.Errorf("Wrong batching delay: %v != %v", act, exp)
+	}
+	if exp, act := "30000", query.Get("sendTimeoutMillis"); exp != act {
+		t.Errorf("Wrong send timeout: %v != %v", act, exp)
+	}
+	if exp, act := "Bearer tok", req.Header.Get("Authorization"); exp != act {
+		t.Errorf("Wrong auth header: %v != %v", act, exp)
+	}
+
+	msg := message.New([][]byte{[]byte("hello"), []byte("world")})
+	msg.Get(0).Metadata().Set("id", "a")
+	msg.Get(1).Metadata().Set("id", "b")
+	if err = p.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{"a", "b"} {
+		send := <-sent
+		if act := send.Key; act != exp {
+			t.Errorf("Wrong key: %v != %v", act, exp)
+		}
+		if act := send.Properties["id"]; act != exp {
+			t.Errorf("Wrong property: %v != %v", act, exp)
+		}
+	}
+
+	if err = p.Write(message.New([][]byte{[]byte("fail")})); err == nil {
+		t.Error("Expected error from failed send")
+	} else if !strings.Contains(err.Error(), "nope") {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -21,13 +21,13 @@
 package writer
 
 import (
-	"net/url"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	bredis "github.com/Jeffail/benthos/lib/util/redis"
 	"github.com/go-redis/redis"
 )
 
@@ -35,15 +35,15 @@ import (
 
 // RedisListConfig contains configuration fields for the RedisList output type.
 type RedisListConfig struct {
-	URL string `json:"url" yaml:"url"`
-	Key string `json:"key" yaml:"key"`
+	bredis.Config `json:",inline" yaml:",inline"`
+	Key           string `json:"key" yaml:"key"`
 }
 
 // NewRedisListConfig creates a new RedisListConfig with default values.
 func NewRedisListConfig() RedisListConfig {
 	return RedisListConfig{
-		URL: "tcp://localhost:6379",
-		Key: "benthos_list",
+		Config: bredis.NewConfig(),
+		Key:    "benthos_list",
 	}
 }
 
@@ -54,10 +54,9 @@ type RedisList struct {
 	log   log.Modular
 	stats metrics.Type
 
-	url  *url.URL
 	conf RedisListConfig
 
-	client  *redis.Client
+	client  redis.UniversalClient
 	connMut sync.RWMutex
 }
 
@@ -74,9 +73,7 @@ func NewRedisList(
 		conf:  conf,
 	}
 
-	var err error
-	r.url, err = url.Parse(conf.URL)
-	if err != nil {
+	if err := conf.Config.Validate(); err != nil {
 		return nil, err
 	}
 
@@ -90,17 +87,16 @@ func (r *RedisList) Connect() error {
 	r.connMut.Lock()
 	defer r.connMut.Unlock()
 
-	var pass string
-	if r.url.User != nil {
-		pass, _ = r.url.User.Password()
+	if r.client != nil {
+		return nil
 	}
-	client := redis.NewClient(&redis.Options{
-		Addr:     r.url.Host,
-		Network:  r.url.Scheme,
-		Password: pass,
-	})
 
-	if _, err := client.Ping().Result(); err != nil {
+	client, err := r.conf.Config.Client()
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping().Result(); err != nil {
+		client.Close()
 		return err
 	}
 
@@ -122,14 +118,17 @@ func (r *RedisList) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
-	return msg.Iter(func(i int, p types.Part) error {
-		if err := client.RPush(r.conf.Key, p.Get()).Err(); err != nil {
-			r.disconnect()
-			r.log.Errorf("Error from redis: %v\n", err)
-			return types.ErrNotConnected
-		}
+	pipe := client.Pipeline()
+	msg.Iter(func(i int, p types.Part) error {
+		pipe.RPush(r.conf.Key, p.Get())
 		return nil
 	})
+	if _, err := pipe.Exec(); err != nil {
+		r.disconnect()
+		r.log.Errorf("Error from redis: %v\n", err)
+		return types.ErrNotConnected
+	}
+	return nil
 }
 
 // disconnect safely closes a connection to an RedisList server.
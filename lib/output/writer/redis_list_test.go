@@ -0,0 +1,154 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// fakeRedisServer accepts connections and records the commands it receives,
+// responding to PING with PONG and to everything else with an integer reply.
+func fakeRedisServer(t *testing.T) (addr string, commands func() [][]string, closeFn func()) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmdMut sync.Mutex
+	var cmds [][]string
+
+	readCommand := func(r *bufio.Reader) ([]string, error) {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "*") {
+			return nil, fmt.Errorf("unexpected line: %q", line)
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			if line, err = r.ReadString('\n'); err != nil {
+				return nil, err
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			data := make([]byte, size+2)
+			if _, err = io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			args[i] = string(data[:size])
+		}
+		return args, nil
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					cmd, err := readCommand(r)
+					if err != nil {
+						return
+					}
+					if strings.ToUpper(cmd[0]) == "PING" {
+						conn.Write([]byte("+PONG\r\n"))
+						continue
+					}
+					cmdMut.Lock()
+					cmds = append(cmds, cmd)
+					cmdMut.Unlock()
+					conn.Write([]byte(":1\r\n"))
+				}
+			}(conn)
+		}
+	}()
+	return ln.Addr().String(), func() [][]string {
+		cmdMut.Lock()
+		defer cmdMut.Unlock()
+		return append([][]string(nil), cmds...)
+	}, func() { ln.Close() }
+}
+
+//------------------------------------------------------------------------------
+
+func TestRedisListPipelined(t *testing.T) {
+	addr, commands, closeFn := fakeRedisServer(t)
+	defer closeFn()
+
+	conf := NewRedisListConfig()
+	conf.URL = "tcp://" + addr
+	conf.Key = "foo"
+
+	w, err := NewRedisList(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	if err = w.Write(message.New([][]byte{
+		[]byte("first"), []byte("second"), []byte("third"),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]string{
+		{"rpush", "foo", "first"},
+		{"rpush", "foo", "second"},
+		{"rpush", "foo", "third"},
+	}
+	if act := commands(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong commands: %v != %v", act, exp)
+	}
+}
+
+func TestRedisListBadConfig(t *testing.T) {
+	conf := NewRedisListConfig()
+	conf.Kind = "nope"
+	if _, err := NewRedisList(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad kind")
+	}
+}
+
+//------------------------------------------------------------------------------
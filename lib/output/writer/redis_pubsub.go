@@ -21,13 +21,13 @@
 package writer
 
 import (
-	"net/url"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	bredis "github.com/Jeffail/benthos/lib/util/redis"
 	"github.com/go-redis/redis"
 )
 
@@ -36,14 +36,14 @@ import (
 // RedisPubSubConfig contains configuration fields for the RedisPubSub output
 // type.
 type RedisPubSubConfig struct {
-	URL     string `json:"url" yaml:"url"`
-	Channel string `json:"channel" yaml:"channel"`
+	bredis.Config `json:",inline" yaml:",inline"`
+	Channel       string `json:"channel" yaml:"channel"`
 }
 
 // NewRedisPubSubConfig creates a new RedisPubSubConfig with default values.
 func NewRedisPubSubConfig() RedisPubSubConfig {
 	return RedisPubSubConfig{
-		URL:     "tcp://localhost:6379",
+		Config:  bredis.NewConfig(),
 		Channel: "benthos_chan",
 	}
 }
@@ -55,10 +55,9 @@ type RedisPubSub struct {
 	log   log.Modular
 	stats metrics.Type
 
-	url  *url.URL
 	conf RedisPubSubConfig
 
-	client  *redis.Client
+	client  redis.UniversalClient
 	connMut sync.RWMutex
 }
 
@@ -75,9 +74,7 @@ func NewRedisPubSub(
 		conf:  conf,
 	}
 
-	var err error
-	r.url, err = url.Parse(conf.URL)
-	if err != nil {
+	if err := conf.Config.Validate(); err != nil {
 		return nil, err
 	}
 
@@ -91,17 +88,16 @@ func (r *RedisPubSub) Connect() error {
 	r.connMut.Lock()
 	defer r.connMut.Unlock()
 
-	var pass string
-	if r.url.User != nil {
-		pass, _ = r.url.User.Password()
+	if r.client != nil {
+		return nil
 	}
-	client := redis.NewClient(&redis.Options{
-		Addr:     r.url.Host,
-		Network:  r.url.Scheme,
-		Password: pass,
-	})
 
-	if _, err := client.Ping().Result(); err != nil {
+	client, err := r.conf.Config.Client()
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping().Result(); err != nil {
+		client.Close()
 		return err
 	}
 
@@ -123,14 +119,17 @@ func (r *RedisPubSub) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
-	return msg.Iter(func(i int, p types.Part) error {
-		if err := client.Publish(r.conf.Channel, p.Get()).Err(); err != nil {
-			r.disconnect()
-			r.log.Errorf("Error from redis: %v\n", err)
-			return types.ErrNotConnected
-		}
+	pipe := client.Pipeline()
+	msg.Iter(func(i int, p types.Part) error {
+		pipe.Publish(r.conf.Channel, p.Get())
 		return nil
 	})
+	if _, err := pipe.Exec(); err != nil {
+		r.disconnect()
+		r.log.Errorf("Error from redis: %v\n", err)
+		return types.ErrNotConnected
+	}
+	return nil
 }
 
 // disconnect safely closes a connection to an RedisPubSub server.
@@ -21,13 +21,17 @@
 package writer
 
 import (
-	"net/url"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/metrics"
 	"github.com/Jeffail/benthos/lib/types"
+	bredis "github.com/Jeffail/benthos/lib/util/redis"
+	"github.com/Jeffail/gabs"
 	"github.com/go-redis/redis"
 )
 
@@ -35,19 +39,25 @@ import (
 
 // RedisStreamsConfig contains configuration fields for the RedisStreams output type.
 type RedisStreamsConfig struct {
-	URL          string `json:"url" yaml:"url"`
-	Stream       string `json:"stream" yaml:"stream"`
-	BodyKey      string `json:"body_key" yaml:"body_key"`
-	MaxLenApprox int64  `json:"max_length" yaml:"max_length"`
+	bredis.Config   `json:",inline" yaml:",inline"`
+	Stream          string            `json:"stream" yaml:"stream"`
+	BodyKey         string            `json:"body_key" yaml:"body_key"`
+	IncludeMetadata bool              `json:"include_metadata" yaml:"include_metadata"`
+	JSONFields      map[string]string `json:"json_fields" yaml:"json_fields"`
+	MaxLenApprox    int64             `json:"max_length" yaml:"max_length"`
+	MaxLenExact     bool              `json:"max_length_exact" yaml:"max_length_exact"`
 }
 
 // NewRedisStreamsConfig creates a new RedisStreamsConfig with default values.
 func NewRedisStreamsConfig() RedisStreamsConfig {
 	return RedisStreamsConfig{
-		URL:          "tcp://localhost:6379",
-		Stream:       "benthos_stream",
-		BodyKey:      "body",
-		MaxLenApprox: 0,
+		Config:          bredis.NewConfig(),
+		Stream:          "benthos_stream",
+		BodyKey:         "body",
+		IncludeMetadata: true,
+		JSONFields:      map[string]string{},
+		MaxLenApprox:    0,
+		MaxLenExact:     false,
 	}
 }
 
@@ -58,11 +68,12 @@ type RedisStreams struct {
 	log   log.Modular
 	stats metrics.Type
 
-	url  *url.URL
 	conf RedisStreamsConfig
 
-	client  *redis.Client
+	client  redis.UniversalClient
 	connMut sync.RWMutex
+
+	mMapErr metrics.StatCounter
 }
 
 // NewRedisStreams creates a new RedisStreams output type.
@@ -76,11 +87,15 @@ func NewRedisStreams(
 		log:   log,
 		stats: stats,
 		conf:  conf,
+
+		mMapErr: stats.GetCounter("error.mapping"),
 	}
 
-	var err error
-	r.url, err = url.Parse(conf.URL)
-	if err != nil {
+	if len(conf.BodyKey) == 0 && len(conf.JSONFields) == 0 && !conf.IncludeMetadata {
+		return nil, errors.New("at least one of body_key, json_fields or include_metadata must be set")
+	}
+
+	if err := conf.Config.Validate(); err != nil {
 		return nil, err
 	}
 
@@ -94,17 +109,16 @@ func (r *RedisStreams) Connect() error {
 	r.connMut.Lock()
 	defer r.connMut.Unlock()
 
-	var pass string
-	if r.url.User != nil {
-		pass, _ = r.url.User.Password()
+	if r.client != nil {
+		return nil
 	}
-	client := redis.NewClient(&redis.Options{
-		Addr:     r.url.Host,
-		Network:  r.url.Scheme,
-		Password: pass,
-	})
 
-	if _, err := client.Ping().Result(); err != nil {
+	client, err := r.conf.Config.Client()
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping().Result(); err != nil {
+		client.Close()
 		return err
 	}
 
@@ -126,25 +140,77 @@ func (r *RedisStreams) Write(msg types.Message) error {
 		return types.ErrNotConnected
 	}
 
-	return msg.Iter(func(i int, p types.Part) error {
-		values := map[string]interface{}{}
+	pipe := client.Pipeline()
+	msg.Iter(func(i int, p types.Part) error {
+		values, err := r.entryValues(p)
+		if err != nil {
+			r.mMapErr.Incr(1)
+			r.log.Errorf("Failed to map message fields, dropping message: %v\n", err)
+			return nil
+		}
+		args := &redis.XAddArgs{
+			ID:     "*",
+			Stream: r.conf.Stream,
+			Values: values,
+		}
+		if r.conf.MaxLenExact {
+			args.MaxLen = r.conf.MaxLenApprox
+		} else {
+			args.MaxLenApprox = r.conf.MaxLenApprox
+		}
+		pipe.XAdd(args)
+		return nil
+	})
+	if _, err := pipe.Exec(); err != nil {
+		r.disconnect()
+		r.log.Errorf("Error from redis: %v\n", err)
+		return types.ErrNotConnected
+	}
+	return nil
+}
+
+// entryValues returns the key/value pairs of a stream entry for a message
+// part. Metadata fields are overridden by JSON fields, which are in turn
+// overridden by the body.
+func (r *RedisStreams) entryValues(p types.Part) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if r.conf.IncludeMetadata {
 		p.Metadata().Iter(func(k, v string) error {
 			values[k] = v
 			return nil
 		})
-		values[r.conf.BodyKey] = p.Get()
-		if err := client.XAdd(&redis.XAddArgs{
-			ID:           "*",
-			Stream:       r.conf.Stream,
-			MaxLenApprox: r.conf.MaxLenApprox,
-			Values:       values,
-		}).Err(); err != nil {
-			r.disconnect()
-			r.log.Errorf("Error from redis: %v\n", err)
-			return types.ErrNotConnected
+	}
+	if len(r.conf.JSONFields) > 0 {
+		jObj, err := p.JSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
 		}
-		return nil
-	})
+		gObj, err := gabs.Consume(jObj)
+		if err != nil {
+			return nil, err
+		}
+		for k, path := range r.conf.JSONFields {
+			v := gObj.Path(path).Data()
+			switch t := v.(type) {
+			case nil:
+			case string:
+				values[k] = t
+			default:
+				b, err := json.Marshal(t)
+				if err != nil {
+					return nil, fmt.Errorf("failed to serialise field '%v': %v", k, err)
+				}
+				values[k] = string(b)
+			}
+		}
+	}
+	if len(r.conf.BodyKey) > 0 {
+		values[r.conf.BodyKey] = p.Get()
+	}
+	if len(values) == 0 {
+		return nil, errors.New("message resulted in an empty stream entry")
+	}
+	return values, nil
 }
 
 // disconnect safely closes a connection to an RedisStreams server.
@@ -0,0 +1,185 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestRedisStreamsBadConfig(t *testing.T) {
+	conf := NewRedisStreamsConfig()
+	conf.BodyKey = ""
+	conf.IncludeMetadata = false
+	if _, err := NewRedisStreams(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from config without fields")
+	}
+}
+
+func TestRedisStreamsEntryValues(t *testing.T) {
+	type testCase struct {
+		name     string
+		conf     func(c *RedisStreamsConfig)
+		input    string
+		metadata map[string]string
+		output   map[string]interface{}
+		err      bool
+	}
+
+	tests := []testCase{
+		{
+			name:     "default fields",
+			conf:     func(c *RedisStreamsConfig) {},
+			input:    `hello world`,
+			metadata: map[string]string{"foo": "bar", "body": "nope"},
+			output: map[string]interface{}{
+				"foo":  "bar",
+				"body": []byte(`hello world`),
+			},
+		},
+		{
+			name: "json fields without metadata",
+			conf: func(c *RedisStreamsConfig) {
+				c.BodyKey = ""
+				c.IncludeMetadata = false
+				c.JSONFields = map[string]string{
+					"id":     "doc.id",
+					"tags":   "doc.tags",
+					"count":  "doc.count",
+					"absent": "doc.absent",
+				}
+			},
+			input:    `{"doc":{"id":"foo","tags":["a","b"],"count":5}}`,
+			metadata: map[string]string{"foo": "bar"},
+			output: map[string]interface{}{
+				"id":    "foo",
+				"tags":  `["a","b"]`,
+				"count": "5",
+			},
+		},
+		{
+			name: "json fields override metadata",
+			conf: func(c *RedisStreamsConfig) {
+				c.BodyKey = ""
+				c.JSONFields = map[string]string{
+					"foo": "foo",
+				}
+			},
+			input:    `{"foo":"from json"}`,
+			metadata: map[string]string{"foo": "from metadata", "bar": "baz"},
+			output: map[string]interface{}{
+				"foo": "from json",
+				"bar": "baz",
+			},
+		},
+		{
+			name: "invalid json",
+			conf: func(c *RedisStreamsConfig) {
+				c.JSONFields = map[string]string{
+					"foo": "foo",
+				}
+			},
+			input: `not json`,
+			err:   true,
+		},
+		{
+			name: "empty entry",
+			conf: func(c *RedisStreamsConfig) {
+				c.BodyKey = ""
+				c.IncludeMetadata = false
+				c.JSONFields = map[string]string{
+					"foo": "foo",
+				}
+			},
+			input: `{"bar":"baz"}`,
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewRedisStreamsConfig()
+		test.conf(&conf)
+		r, err := NewRedisStreams(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		part := message.NewPart([]byte(test.input))
+		for k, v := range test.metadata {
+			part.Metadata().Set(k, v)
+		}
+
+		values, err := r.entryValues(part)
+		if test.err {
+			if err == nil {
+				t.Errorf("%v: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.output, values) {
+			t.Errorf("%v: wrong values: %v != %v", test.name, values, test.output)
+		}
+	}
+}
+
+func TestRedisStreamsPipelined(t *testing.T) {
+	addr, commands, closeFn := fakeRedisServer(t)
+	defer closeFn()
+
+	conf := NewRedisStreamsConfig()
+	conf.URL = "tcp://" + addr
+	conf.Stream = "foo"
+	conf.BodyKey = ""
+	conf.JSONFields = map[string]string{"id": "id"}
+	conf.MaxLenApprox = 10
+	conf.MaxLenExact = true
+
+	w, err := NewRedisStreams(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	if err = w.Write(message.New([][]byte{
+		[]byte(`{"id":"a"}`), []byte(`not json`), []byte(`{"id":"b"}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]string{
+		{"xadd", "foo", "maxlen", "10", "*", "id", "a"},
+		{"xadd", "foo", "maxlen", "10", "*", "id", "b"},
+	}
+	if act := commands(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong commands: %v != %v", act, exp)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/sftp"
+	"github.com/Jeffail/benthos/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// SFTPConfig contains configuration fields for the SFTP output type.
+type SFTPConfig struct {
+	Address        string `json:"address" yaml:"address"`
+	User           string `json:"user" yaml:"user"`
+	Password       string `json:"password" yaml:"password"`
+	PrivateKeyFile string `json:"private_key_file" yaml:"private_key_file"`
+	KnownHostsFile string `json:"known_hosts_file" yaml:"known_hosts_file"`
+	Timeout        string `json:"timeout" yaml:"timeout"`
+	Path           string `json:"path" yaml:"path"`
+	Delim          string `json:"delimiter" yaml:"delimiter"`
+	TempSuffix     string `json:"temp_suffix" yaml:"temp_suffix"`
+}
+
+// NewSFTPConfig creates a new SFTPConfig with default values.
+func NewSFTPConfig() SFTPConfig {
+	return SFTPConfig{
+		Address:        "localhost:22",
+		User:           "",
+		Password:       "",
+		PrivateKeyFile: "",
+		KnownHostsFile: "",
+		Timeout:        "30s",
+		Path:           "${!count:files}-${!timestamp_unix_nano}.txt",
+		Delim:          "",
+		TempSuffix:     ".tmp",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// sftpClient is the subset of the SFTP client used by the writer.
+type sftpClient interface {
+	MkdirAll(path string) error
+	WriteFile(path string, data []byte) error
+	Stat(path string) (os.FileInfo, error)
+	Rename(from, to string) error
+	Remove(path string) error
+	Close() error
+}
+
+// SFTP is a writer type that writes each message as a file on an SFTP server.
+type SFTP struct {
+	conf  SFTPConfig
+	path  *text.InterpolatedString
+	delim []byte
+	dial  func() (sftpClient, error)
+
+	client    sftpClient
+	clientMut sync.Mutex
+
+	log   log.Modular
+	stats metrics.Type
+
+	mFiles metrics.StatCounter
+}
+
+// NewSFTP creates a new SFTP writer type.
+func NewSFTP(conf SFTPConfig, log log.Modular, stats metrics.Type) (*SFTP, error) {
+	sshConf, err := sftp.SSHConfig(conf.User, conf.Password, conf.PrivateKeyFile, conf.KnownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.KnownHostsFile) == 0 {
+		log.Warnln("No known hosts file specified, the host key of the SFTP server will not be verified")
+	}
+	if len(conf.Timeout) > 0 {
+		if sshConf.Timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+
+	s := &SFTP{
+		conf:   conf,
+		path:   text.NewInterpolatedString(conf.Path),
+		delim:  []byte("\n"),
+		log:    log,
+		stats:  stats,
+		mFiles: stats.GetCounter("files"),
+	}
+	if len(conf.Delim) > 0 {
+		s.delim = []byte(conf.Delim)
+	}
+	s.dial = func() (sftpClient, error) {
+		return sftp.Dial(conf.Address, sshConf)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to establish a connection to the SFTP server.
+func (s *SFTP) Connect() error {
+	s.clientMut.Lock()
+	defer s.clientMut.Unlock()
+
+	if s.client != nil {
+		return nil
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.client = client
+
+	s.log.Infof("Writing files to SFTP server at: %v\n", s.conf.Address)
+	return nil
+}
+
+// upload writes a file to a temporary path and renames it into place once it
+// is complete, replacing any existing file.
+func (s *SFTP) upload(client sftpClient, target string, data []byte) error {
+	if dir := path.Dir(target); dir != "." {
+		if err := client.MkdirAll(dir); err != nil {
+			return err
+		}
+	}
+
+	tmp := target + s.conf.TempSuffix
+	if tmp == target {
+		return client.WriteFile(target, data)
+	}
+	if err := client.WriteFile(tmp, data); err != nil {
+		return err
+	}
+	err := client.Rename(tmp, target)
+	if err == nil {
+		return nil
+	}
+
+	// SFTP servers commonly refuse to rename over an existing file.
+	if _, sErr := client.Stat(target); sErr != nil {
+		return err
+	}
+	if err = client.Remove(target); err != nil {
+		return err
+	}
+	return client.Rename(tmp, target)
+}
+
+// Write attempts to write a message as a file, where each part is followed by
+// the delimiter and the path is resolved against the first part.
+func (s *SFTP) Write(msg types.Message) error {
+	s.clientMut.Lock()
+	client := s.client
+	s.clientMut.Unlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	var data bytes.Buffer
+	msg.Iter(func(i int, p types.Part) error {
+		data.Write(p.Get())
+		data.Write(s.delim)
+		return nil
+	})
+
+	err := s.upload(client, s.path.Get(message.Lock(msg, 0)), data.Bytes())
+	if err != nil {
+		if !sftp.IsStatus(err) {
+			// Errors other than server statuses indicate a broken session.
+			s.clientMut.Lock()
+			if s.client == client {
+				s.client.Close()
+				s.client = nil
+			}
+			s.clientMut.Unlock()
+		}
+		return err
+	}
+	s.mFiles.Incr(1)
+	return nil
+}
+
+// CloseAsync shuts down the SFTP writer and stops processing messages.
+func (s *SFTP) CloseAsync() {
+	s.clientMut.Lock()
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+	s.clientMut.Unlock()
+}
+
+// WaitForClose blocks until the SFTP writer has closed down.
+func (s *SFTP) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,199 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/pkg/sftp"
+)
+
+//------------------------------------------------------------------------------
+
+// fakeSFTPClient is an in-memory SFTP client that refuses to rename over
+// existing files, like many servers.
+type fakeSFTPClient struct {
+	files  map[string]string
+	dirs   map[string]bool
+	ops    []string
+	closed bool
+	err    error
+}
+
+func (f *fakeSFTPClient) MkdirAll(path string) error {
+	f.dirs[path] = true
+	return nil
+}
+
+func (f *fakeSFTPClient) WriteFile(path string, data []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.ops = append(f.ops, "write "+path)
+	f.files[path] = string(data)
+	return nil
+}
+
+func (f *fakeSFTPClient) Stat(path string) (os.FileInfo, error) {
+	if _, exists := f.files[path]; exists {
+		return nil, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (f *fakeSFTPClient) Rename(from, to string) error {
+	if _, exists := f.files[to]; exists {
+		return &sftp.StatusError{Code: 4}
+	}
+	f.ops = append(f.ops, "rename "+from+" "+to)
+	f.files[to] = f.files[from]
+	delete(f.files, from)
+	return nil
+}
+
+func (f *fakeSFTPClient) Remove(path string) error {
+	f.ops = append(f.ops, "remove "+path)
+	delete(f.files, path)
+	return nil
+}
+
+func (f *fakeSFTPClient) Close() error {
+	f.closed = true
+	return nil
+}
+
+func newFakeSFTP(t *testing.T, conf SFTPConfig, client *fakeSFTPClient) *SFTP {
+	t.Helper()
+	conf.Password = "foo"
+	s, err := NewSFTP(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.dial = func() (sftpClient, error) {
+		return client, nil
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSFTPBadConfig(t *testing.T) {
+	conf := NewSFTPConfig()
+	if _, err := NewSFTP(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing credentials")
+	}
+}
+
+func TestSFTPWrite(t *testing.T) {
+	client := &fakeSFTPClient{
+		files: map[string]string{"/drop/b.txt": "old"},
+		dirs:  map[string]bool{},
+	}
+
+	conf := NewSFTPConfig()
+	conf.Path = "/drop/${!metadata:name}.txt"
+	s := newFakeSFTP(t, conf, client)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("name", "a")
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = message.New([][]byte{[]byte("baz")})
+	msg.Get(0).Metadata().Set("name", "b")
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	expFiles := map[string]string{
+		"/drop/a.txt": "foo\nbar\n",
+		"/drop/b.txt": "baz\n",
+	}
+	if !reflect.DeepEqual(expFiles, client.files) {
+		t.Errorf("Wrong files: %v != %v", client.files, expFiles)
+	}
+	expOps := []string{
+		"write /drop/a.txt.tmp",
+		"rename /drop/a.txt.tmp /drop/a.txt",
+		"write /drop/b.txt.tmp",
+		"remove /drop/b.txt",
+		"rename /drop/b.txt.tmp /drop/b.txt",
+	}
+	if !reflect.DeepEqual(expOps, client.ops) {
+		t.Errorf("Wrong operations: %v != %v", client.ops, expOps)
+	}
+	if !client.dirs["/drop"] {
+		t.Error("Expected directory to be created")
+	}
+}
+
+func TestSFTPWriteNoTemp(t *testing.T) {
+	client := &fakeSFTPClient{
+		files: map[string]string{},
+		dirs:  map[string]bool{},
+	}
+
+	conf := NewSFTPConfig()
+	conf.Path = "foo.txt"
+	conf.Delim = "|"
+	conf.TempSuffix = ""
+	s := newFakeSFTP(t, conf, client)
+
+	if err := s.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []string{"write foo.txt"}, client.ops; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong operations: %v != %v", act, exp)
+	}
+	if exp, act := "foo|", client.files["foo.txt"]; exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+}
+
+func TestSFTPBrokenSession(t *testing.T) {
+	client := &fakeSFTPClient{
+		files: map[string]string{},
+		dirs:  map[string]bool{},
+		err:   errors.New("connection reset"),
+	}
+	s := newFakeSFTP(t, NewSFTPConfig(), client)
+
+	if err := s.Write(message.New([][]byte{[]byte("foo")})); err == nil {
+		t.Fatal("Expected error from broken session")
+	}
+	if !client.closed {
+		t.Error("Expected client to be closed")
+	}
+	if err := s.Write(message.New([][]byte{[]byte("foo")})); err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error, received: %v", err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,557 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/parquet"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/snowflakedb/gosnowflake"
+)
+
+//------------------------------------------------------------------------------
+
+// SnowflakeConfig contains configuration fields for the Snowflake output type.
+type SnowflakeConfig struct {
+	Account        string `json:"account" yaml:"account"`
+	User           string `json:"user" yaml:"user"`
+	PrivateKeyFile string `json:"private_key_file" yaml:"private_key_file"`
+	Role           string `json:"role" yaml:"role"`
+	Database       string `json:"database" yaml:"database"`
+	Schema         string `json:"schema" yaml:"schema"`
+	Stage          string `json:"stage" yaml:"stage"`
+	Pipe           string `json:"pipe" yaml:"pipe"`
+	URL            string `json:"url" yaml:"url"`
+	Path           string `json:"path" yaml:"path"`
+	FileFormat     string `json:"file_format" yaml:"file_format"`
+	Compression    string `json:"compression" yaml:"compression"`
+	Timeout        string `json:"timeout" yaml:"timeout"`
+}
+
+// NewSnowflakeConfig creates a new SnowflakeConfig with default values.
+func NewSnowflakeConfig() SnowflakeConfig {
+	return SnowflakeConfig{
+		Account:        "",
+		User:           "",
+		PrivateKeyFile: "",
+		Role:           "",
+		Database:       "",
+		Schema:         "PUBLIC",
+		Stage:          "",
+		Pipe:           "",
+		URL:            "",
+		Path:           "${!count:snowflake_files}-${!timestamp_unix_nano}.json.gz",
+		FileFormat:     "json",
+		Compression:    "gzip",
+		Timeout:        "30s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// snowflakeStage uploads files to an internal Snowflake stage.
+type snowflakeStage interface {
+	Put(ctx context.Context, dir, name string, file []byte) error
+	Close() error
+}
+
+// snowflakeDBStage uploads files to an internal stage with the PUT command of
+// the Snowflake driver.
+type snowflakeDBStage struct {
+	db                *sql.DB
+	stage             string
+	sourceCompression string
+}
+
+// Put uploads a file to a directory of the stage, overwriting any existing
+// file of the same name.
+func (s *snowflakeDBStage) Put(ctx context.Context, dir, name string, file []byte) error {
+	_, err := s.db.ExecContext(
+		gosnowflake.WithFileStream(ctx, bytes.NewReader(file)),
+		snowflakePutQuery(s.stage, dir, name, s.sourceCompression),
+	)
+	return err
+}
+
+// Close closes the underlying database connections.
+func (s *snowflakeDBStage) Close() error {
+	return s.db.Close()
+}
+
+// snowflakePutQuery returns a PUT command that uploads a streamed file to a
+// directory of a stage without compressing it further.
+func snowflakePutQuery(stage, dir, name, sourceCompression string) string {
+	location := stage
+	if len(dir) > 0 {
+		location += "/" + dir
+	}
+	return fmt.Sprintf(
+		"PUT 'file://%v' '%v' AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = %v OVERWRITE = TRUE",
+		name, location, sourceCompression,
+	)
+}
+
+//------------------------------------------------------------------------------
+
+// Snowflake is a writer type that uploads batched messages as files to the
+// internal stage of a Snowflake pipe and then notifies Snowpipe of the new
+// files.
+type Snowflake struct {
+	conf SnowflakeConfig
+
+	path       *text.InterpolatedString
+	key        *rsa.PrivateKey
+	stageName  string
+	insertURL  string
+	qualifier  string
+	issuer     string
+	client     http.Client
+	timeout    time.Duration
+	jwtExpiry  time.Time
+	cachedAuth string
+
+	stage    snowflakeStage
+	stageMut sync.RWMutex
+
+	log   log.Modular
+	stats metrics.Type
+
+	mFiles   metrics.StatCounter
+	mRows    metrics.StatCounter
+	mJSONErr metrics.StatCounter
+}
+
+// NewSnowflake creates a new Snowflake writer type.
+func NewSnowflake(conf SnowflakeConfig, log log.Modular, stats metrics.Type) (*Snowflake, error) {
+	s := Snowflake{
+		conf:     conf,
+		path:     text.NewInterpolatedString(conf.Path),
+		log:      log,
+		stats:    stats,
+		mFiles:   stats.GetCounter("files"),
+		mRows:    stats.GetCounter("rows"),
+		mJSONErr: stats.GetCounter("error.json"),
+	}
+	if len(conf.Account) == 0 || len(conf.User) == 0 {
+		return nil, errors.New("an account and user must be specified")
+	}
+	if len(conf.Database) == 0 || len(conf.Schema) == 0 || len(conf.Pipe) == 0 {
+		return nil, errors.New("a database, schema and pipe must be specified")
+	}
+	if s.stageName = strings.TrimPrefix(conf.Stage, "@"); len(s.stageName) == 0 {
+		return nil, errors.New("a stage must be specified")
+	}
+	if strings.ContainsAny(s.stageName, "' ") {
+		return nil, fmt.Errorf("stage name contains invalid characters: %v", conf.Stage)
+	}
+	s.stageName = "@" + s.stageName
+	switch conf.FileFormat {
+	case "json", "csv", "parquet":
+	default:
+		return nil, fmt.Errorf("file format not recognised: %v", conf.FileFormat)
+	}
+	switch conf.Compression {
+	case "gzip", "none":
+	default:
+		return nil, fmt.Errorf("compression type not recognised: %v", conf.Compression)
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if s.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+		s.client.Timeout = s.timeout
+	}
+
+	keyBytes, err := ioutil.ReadFile(conf.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key file: %v", err)
+	}
+	if s.key, err = parseSnowflakePrivateKey(keyBytes); err != nil {
+		return nil, err
+	}
+	if s.issuer, err = snowflakeIssuer(conf.Account, conf.User, &s.key.PublicKey); err != nil {
+		return nil, err
+	}
+	s.qualifier = snowflakeQualifier(conf.Account, conf.User)
+
+	baseURL := conf.URL
+	if len(baseURL) == 0 {
+		baseURL = fmt.Sprintf("https://%v.snowflakecomputing.com", conf.Account)
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + fmt.Sprintf(
+		"/v1/data/pipes/%v.%v.%v/insertFiles", conf.Database, conf.Schema, conf.Pipe,
+	)
+	s.insertURL = u.String()
+	return &s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// parseSnowflakePrivateKey parses an unencrypted PEM encoded RSA private key in
+// either the PKCS#1 or PKCS#8 format.
+func parseSnowflakePrivateKey(keyBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, errors.New("failed to decode private key PEM block")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// snowflakeQualifier returns the ACCOUNT.USER name used as the subject of key
+// pair tokens, where any region or cloud suffix is removed from the account.
+func snowflakeQualifier(account, user string) string {
+	if i := strings.Index(account, "."); i >= 0 {
+		account = account[:i]
+	}
+	return strings.ToUpper(account) + "." + strings.ToUpper(user)
+}
+
+// snowflakeIssuer returns the issuer of key pair tokens, which includes the
+// fingerprint of the public key registered with the user.
+func snowflakeIssuer(account, user string, pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return snowflakeQualifier(account, user) + ".SHA256:" + base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// authToken returns a signed JWT for the Snowpipe REST API, reusing the
+// previous token until it is close to expiring.
+func (s *Snowflake) authToken() (string, error) {
+	now := time.Now()
+	if len(s.cachedAuth) > 0 && now.Before(s.jwtExpiry.Add(-time.Minute*5)) {
+		return s.cachedAuth, nil
+	}
+	expiry := now.Add(time.Hour)
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": s.issuer,
+		"sub": s.qualifier,
+		"iat": now.Unix(),
+		"exp": expiry.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	s.cachedAuth = signed + "." + enc.EncodeToString(sig)
+	s.jwtExpiry = expiry
+	return s.cachedAuth, nil
+}
+
+//------------------------------------------------------------------------------
+
+// driverConfig returns the configuration of the Snowflake driver used for
+// staging files.
+func (s *Snowflake) driverConfig() (*gosnowflake.Config, error) {
+	cfg := &gosnowflake.Config{
+		Account:       s.conf.Account,
+		User:          s.conf.User,
+		Role:          s.conf.Role,
+		Database:      s.conf.Database,
+		Schema:        s.conf.Schema,
+		Authenticator: gosnowflake.AuthTypeJwt,
+		PrivateKey:    s.key,
+		LoginTimeout:  s.timeout,
+	}
+	if len(s.conf.URL) > 0 {
+		u, err := url.Parse(s.conf.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse url: %v", err)
+		}
+		cfg.Protocol = u.Scheme
+		cfg.Host = u.Hostname()
+		if port := u.Port(); len(port) > 0 {
+			if cfg.Port, err = strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("failed to parse url port: %v", err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// Connect opens a connection to Snowflake for uploading files to the stage.
+func (s *Snowflake) Connect() error {
+	s.stageMut.Lock()
+	defer s.stageMut.Unlock()
+	if s.stage != nil {
+		return nil
+	}
+
+	cfg, err := s.driverConfig()
+	if err != nil {
+		return err
+	}
+	dsn, err := gosnowflake.DSN(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create dsn: %v", err)
+	}
+	db, err := sql.Open("snowflake", dsn)
+	if err != nil {
+		return err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+
+	sourceCompression := "NONE"
+	if s.conf.Compression == "gzip" && s.conf.FileFormat != "parquet" {
+		sourceCompression = "GZIP"
+	}
+	s.stage = &snowflakeDBStage{
+		db:                db,
+		stage:             s.stageName,
+		sourceCompression: sourceCompression,
+	}
+
+	s.log.Infof(
+		"Writing files to Snowflake stage '%v' for pipe: %v.%v.%v\n",
+		s.stageName, s.conf.Database, s.conf.Schema, s.conf.Pipe,
+	)
+	return nil
+}
+
+// file creates the contents of a staged file from a message, returning the
+// file along with the number of rows written to it.
+func (s *Snowflake) file(msg types.Message) ([]byte, int, error) {
+	if s.conf.FileFormat == "parquet" {
+		return s.parquetFile(msg)
+	}
+
+	var buf bytes.Buffer
+	rows := 0
+	msg.Iter(func(i int, part types.Part) error {
+		b := part.Get()
+		if s.conf.FileFormat == "json" {
+			if _, err := part.JSON(); err != nil {
+				s.mJSONErr.Incr(1)
+				s.log.Errorf("Failed to parse message as JSON: %v\n", err)
+				return nil
+			}
+		}
+		buf.Write(bytes.TrimRight(b, "\n"))
+		buf.WriteByte('\n')
+		rows++
+		return nil
+	})
+	if rows == 0 || s.conf.Compression == "none" {
+		return buf.Bytes(), rows, nil
+	}
+
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+	return zipped.Bytes(), rows, nil
+}
+
+// parquetFile creates a Parquet file from a message, where each part must be
+// a JSON object, and where the columns of the file are the union of all object
+// keys.
+func (s *Snowflake) parquetFile(msg types.Message) ([]byte, int, error) {
+	rows := []map[string]interface{}{}
+	msg.Iter(func(i int, part types.Part) error {
+		jObj, err := part.JSON()
+		if err != nil {
+			s.mJSONErr.Incr(1)
+			s.log.Errorf("Failed to parse message as JSON: %v\n", err)
+			return nil
+		}
+		row, ok := jObj.(map[string]interface{})
+		if !ok {
+			s.mJSONErr.Incr(1)
+			s.log.Errorf("Failed to add message to Parquet file: expected JSON object, found %T\n", jObj)
+			return nil
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if len(rows) == 0 {
+		return nil, 0, nil
+	}
+
+	compression := parquet.CompressionNone
+	if s.conf.Compression == "gzip" {
+		compression = parquet.CompressionGzip
+	}
+	var buf bytes.Buffer
+	if err := parquet.WriteRows(&buf, rows, compression); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), len(rows), nil
+}
+
+// insertFiles notifies Snowpipe of a file that has been staged.
+func (s *Snowflake) insertFiles(path string) error {
+	token, err := s.authToken()
+	if err != nil {
+		return fmt.Errorf("failed to create auth token: %v", err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"files": []map[string]string{
+			{"path": path},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.insertURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resBody, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("insertFiles returned unexpected status: %v: %s", res.Status, bytes.TrimSpace(resBody))
+	}
+	return nil
+}
+
+// Write uploads all parts of a message as a single file to the stage and then
+// requests that Snowpipe ingests it.
+func (s *Snowflake) Write(msg types.Message) error {
+	s.stageMut.RLock()
+	stage := s.stage
+	s.stageMut.RUnlock()
+	if stage == nil {
+		return types.ErrNotConnected
+	}
+
+	file, rows, err := s.file(msg)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return nil
+	}
+
+	filePath := strings.Trim(s.path.Get(message.Lock(msg, 0)), "/")
+	if strings.Contains(filePath, "'") {
+		return fmt.Errorf("staged file path contains invalid characters: %v", filePath)
+	}
+	dir, name := path.Split(filePath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if err = stage.Put(ctx, strings.TrimSuffix(dir, "/"), name, file); err != nil {
+		return fmt.Errorf("failed to upload staged file: %v", err)
+	}
+
+	if err = s.insertFiles(filePath); err != nil {
+		return err
+	}
+	s.mFiles.Incr(1)
+	s.mRows.Incr(int64(rows))
+	return nil
+}
+
+// CloseAsync shuts down the Snowflake writer and stops processing messages.
+func (s *Snowflake) CloseAsync() {
+	s.stageMut.Lock()
+	if s.stage != nil {
+		s.stage.Close()
+		s.stage = nil
+	}
+	s.stageMut.Unlock()
+}
+
+// WaitForClose blocks until the Snowflake writer has closed down.
+func (s *Snowflake) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,388 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/util/parquet"
+)
+
+//------------------------------------------------------------------------------
+
+type mockSnowflakeStage struct {
+	files  map[string][]byte
+	closed bool
+}
+
+func (m *mockSnowflakeStage) Put(ctx context.Context, dir, name string, file []byte) error {
+	m.files[dir+"/"+name] = file
+	return nil
+}
+
+func (m *mockSnowflakeStage) Close() error {
+	m.closed = true
+	return nil
+}
+
+func snowflakeTestConf(t *testing.T) (SnowflakeConfig, *rsa.PrivateKey, func()) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "benthos_snowflake_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "rsa_key.p8")
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: keyDER,
+	}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewSnowflakeConfig()
+	conf.Account = "xy12345.us-east-1"
+	conf.User = "benthos"
+	conf.PrivateKeyFile = keyPath
+	conf.Database = "db"
+	conf.Pipe = "pipe"
+	conf.Stage = "@%table"
+	return conf, key, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSnowflakeQualifier(t *testing.T) {
+	tests := map[string]string{
+		"xy12345":                     "XY12345.FOO",
+		"xy12345.us-east-1":           "XY12345.FOO",
+		"xy12345.eu-west-1.aws":       "XY12345.FOO",
+		"myorg-myaccount":             "MYORG-MYACCOUNT.FOO",
+		"myorg-myaccount.privatelink": "MYORG-MYACCOUNT.FOO",
+	}
+	for input, exp := range tests {
+		if act := snowflakeQualifier(input, "foo"); act != exp {
+			t.Errorf("Wrong result for '%v': %v != %v", input, act, exp)
+		}
+	}
+}
+
+func TestSnowflakeAuthToken(t *testing.T) {
+	conf, key, done := snowflakeTestConf(t)
+	defer done()
+
+	s, err := NewSnowflake(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := s.authToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := s.authToken(); again != token {
+		t.Error("Expected cached token to be reused")
+	}
+
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		t.Fatalf("Wrong count of token segments: %v", len(segments))
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(segments[0] + "." + segments[1]))
+	if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+		t.Errorf("Failed to verify token signature: %v", err)
+	}
+
+	claimBytes, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{}
+	if err = json.Unmarshal(claimBytes, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "XY12345.BENTHOS", claims["sub"]; exp != act {
+		t.Errorf("Wrong subject: %v != %v", act, exp)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubSum := sha256.Sum256(pubDER)
+	expIss := "XY12345.BENTHOS.SHA256:" + base64.StdEncoding.EncodeToString(pubSum[:])
+	if act := claims["iss"]; act != expIss {
+		t.Errorf("Wrong issuer: %v != %v", act, expIss)
+	}
+}
+
+func TestSnowflakeBadConfig(t *testing.T) {
+	conf, _, done := snowflakeTestConf(t)
+	defer done()
+
+	badFormat := conf
+	badFormat.FileFormat = "avro"
+	if _, err := NewSnowflake(badFormat, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unsupported file format")
+	}
+
+	noStage := conf
+	noStage.Stage = "@"
+	if _, err := NewSnowflake(noStage, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing stage")
+	}
+
+	badStage := conf
+	badStage.Stage = "foo' bar"
+	if _, err := NewSnowflake(badStage, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from invalid stage")
+	}
+}
+
+func TestSnowflakePutQuery(t *testing.T) {
+	tests := []struct {
+		dir, compression, exp string
+	}{
+		{"foo/bar", "GZIP", "PUT 'file://baz.json.gz' '@%table/foo/bar' AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = GZIP OVERWRITE = TRUE"},
+		{"", "NONE", "PUT 'file://baz.json.gz' '@%table' AUTO_COMPRESS = FALSE SOURCE_COMPRESSION = NONE OVERWRITE = TRUE"},
+	}
+	for _, test := range tests {
+		if act := snowflakePutQuery("@%table", test.dir, "baz.json.gz", test.compression); act != test.exp {
+			t.Errorf("Wrong query: %v != %v", act, test.exp)
+		}
+	}
+}
+
+func TestSnowflakeDriverConfig(t *testing.T) {
+	conf, key, done := snowflakeTestConf(t)
+	defer done()
+
+	conf.Role = "loader"
+	conf.URL = "http://localhost:8080"
+
+	s, err := NewSnowflake(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := s.driverConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "loader", cfg.Role; exp != act {
+		t.Errorf("Wrong role: %v != %v", act, exp)
+	}
+	if cfg.Protocol != "http" || cfg.Host != "localhost" || cfg.Port != 8080 {
+		t.Errorf("Wrong endpoint: %v://%v:%v", cfg.Protocol, cfg.Host, cfg.Port)
+	}
+	if cfg.PrivateKey == nil || cfg.PrivateKey.N.Cmp(key.N) != 0 {
+		t.Error("Wrong private key")
+	}
+}
+
+func TestSnowflakeWrite(t *testing.T) {
+	conf, _, done := snowflakeTestConf(t)
+	defer done()
+
+	var reqPath, reqAuth, reqTokenType string
+	var reqBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqPath = r.URL.Path
+		reqAuth = r.Header.Get("Authorization")
+		reqTokenType = r.Header.Get("X-Snowflake-Authorization-Token-Type")
+		reqBody, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"responseCode":"SUCCESS"}`))
+	}))
+	defer ts.Close()
+
+	conf.URL = ts.URL
+	conf.Path = "foo/${!metadata:id}.json.gz"
+
+	s, err := NewSnowflake(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := &mockSnowflakeStage{files: map[string][]byte{}}
+	s.stage = stage
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"1"}`),
+		[]byte(`not json`),
+		[]byte(`{"id":"2"}`),
+	})
+	msg.Get(0).Metadata().Set("id", "first")
+	if err = s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	zipped, exists := stage.files["foo/first.json.gz"]
+	if !exists {
+		t.Fatalf("Expected upload not found: %v", stage.files)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "{\"id\":\"1\"}\n{\"id\":\"2\"}\n", string(contents); exp != act {
+		t.Errorf("Wrong file contents: %v != %v", act, exp)
+	}
+
+	if exp, act := "/v1/data/pipes/db.PUBLIC.pipe/insertFiles", reqPath; exp != act {
+		t.Errorf("Wrong request path: %v != %v", act, exp)
+	}
+	if !strings.HasPrefix(reqAuth, "Bearer ") {
+		t.Errorf("Wrong auth header: %v", reqAuth)
+	}
+	if exp, act := "KEYPAIR_JWT", reqTokenType; exp != act {
+		t.Errorf("Wrong token type: %v != %v", act, exp)
+	}
+	if exp, act := `{"files":[{"path":"foo/first.json.gz"}]}`, string(reqBody); exp != act {
+		t.Errorf("Wrong request body: %v != %v", act, exp)
+	}
+}
+
+func TestSnowflakeWriteCSV(t *testing.T) {
+	conf, _, done := snowflakeTestConf(t)
+	defer done()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	conf.URL = ts.URL
+	conf.Path = "foo.csv"
+	conf.FileFormat = "csv"
+	conf.Compression = "none"
+
+	s, err := NewSnowflake(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := &mockSnowflakeStage{files: map[string][]byte{}}
+	s.stage = stage
+
+	if err = s.Write(message.New([][]byte{
+		[]byte("a,b\n"),
+		[]byte("c,d"),
+	})); err == nil {
+		t.Error("Expected error from failed insertFiles request")
+	}
+	if exp, act := "a,b\nc,d\n", string(stage.files["/foo.csv"]); exp != act {
+		t.Errorf("Wrong file contents: %v != %v", act, exp)
+	}
+}
+
+func TestSnowflakeWriteParquet(t *testing.T) {
+	conf, _, done := snowflakeTestConf(t)
+	defer done()
+
+	var reqBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"responseCode":"SUCCESS"}`))
+	}))
+	defer ts.Close()
+
+	conf.URL = ts.URL
+	conf.Path = "foo/bar.parquet"
+	conf.FileFormat = "parquet"
+
+	s, err := NewSnowflake(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stage := &mockSnowflakeStage{files: map[string][]byte{}}
+	s.stage = stage
+
+	if err = s.Write(message.New([][]byte{
+		[]byte(`{"id":1,"name":"foo"}`),
+		[]byte(`["not","an","object"]`),
+		[]byte(`{"id":2}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	file, exists := stage.files["foo/bar.parquet"]
+	if !exists {
+		t.Fatalf("Expected upload not found: %v", stage.files)
+	}
+	f, err := parquet.Open(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.ReadRowGroup(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []map[string]interface{}{
+		{"id": int64(1), "name": "foo"},
+		{"id": int64(2), "name": nil},
+	}
+	if !reflect.DeepEqual(exp, rows) {
+		t.Errorf("Wrong rows: %v != %v", rows, exp)
+	}
+	if exp, act := `{"files":[{"path":"foo/bar.parquet"}]}`, string(reqBody); exp != act {
+		t.Errorf("Wrong request body: %v != %v", act, exp)
+	}
+
+	s.CloseAsync()
+	if !stage.closed {
+		t.Error("Expected stage to be closed")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,222 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	sess "github.com/Jeffail/benthos/lib/util/aws/session"
+	"github.com/Jeffail/benthos/lib/util/text"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+//------------------------------------------------------------------------------
+
+// SNSConfig contains configuration fields for the output SNS type.
+type SNSConfig struct {
+	sessionConfig          `json:",inline" yaml:",inline"`
+	TopicArn               string `json:"topic_arn" yaml:"topic_arn"`
+	MessageGroupID         string `json:"message_group_id" yaml:"message_group_id"`
+	MessageDeduplicationID string `json:"message_deduplication_id" yaml:"message_deduplication_id"`
+	Timeout                string `json:"timeout" yaml:"timeout"`
+}
+
+// NewSNSConfig creates a new Config with default values.
+func NewSNSConfig() SNSConfig {
+	return SNSConfig{
+		sessionConfig: sessionConfig{
+			Config: sess.NewConfig(),
+		},
+		TopicArn:               "",
+		MessageGroupID:         "",
+		MessageDeduplicationID: "",
+		Timeout:                "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SNS is a benthos writer.Type implementation that writes messages to an
+// Amazon SNS topic.
+type SNS struct {
+	conf SNSConfig
+
+	sns snsiface.SNSAPI
+
+	topicArn *text.InterpolatedString
+	groupID  *text.InterpolatedString
+	dedupID  *text.InterpolatedString
+	timeout  time.Duration
+
+	log   log.Modular
+	stats metrics.Type
+}
+
+// NewSNS creates a new Amazon SNS writer.Type.
+func NewSNS(
+	conf SNSConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*SNS, error) {
+	if len(conf.TopicArn) == 0 {
+		return nil, errors.New("topic_arn must not be empty")
+	}
+	s := &SNS{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		topicArn: text.NewInterpolatedString(conf.TopicArn),
+	}
+	if len(conf.MessageGroupID) > 0 {
+		s.groupID = text.NewInterpolatedString(conf.MessageGroupID)
+	}
+	if len(conf.MessageDeduplicationID) > 0 {
+		s.dedupID = text.NewInterpolatedString(conf.MessageDeduplicationID)
+	}
+	if tout := conf.Timeout; len(tout) > 0 {
+		var err error
+		if s.timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout period string: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// Connect attempts to establish a connection to the target SNS topic.
+func (a *SNS) Connect() error {
+	if a.sns != nil {
+		return nil
+	}
+
+	sess, err := a.conf.GetSession()
+	if err != nil {
+		return err
+	}
+
+	a.sns = sns.New(sess)
+
+	a.log.Infof("Sending messages to Amazon SNS ARN: %v\n", a.conf.TopicArn)
+	return nil
+}
+
+// snsFIFOParams returns a request option that adds FIFO topic parameters to
+// the body of a Publish request. These parameters are added after the request
+// is built as they are not supported by the PublishInput type of the AWS SDK
+// in use.
+func snsFIFOParams(groupID, dedupID string) request.Option {
+	return func(r *request.Request) {
+		r.Handlers.Build.PushBack(func(r *request.Request) {
+			if r.Error != nil || r.Body == nil {
+				return
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				r.Error = err
+				return
+			}
+			values, err := url.ParseQuery(string(body))
+			if err != nil {
+				r.Error = err
+				return
+			}
+			if len(groupID) > 0 {
+				values.Set("MessageGroupId", groupID)
+			}
+			if len(dedupID) > 0 {
+				values.Set("MessageDeduplicationId", dedupID)
+			}
+			r.SetBufferBody([]byte(values.Encode()))
+		})
+	}
+}
+
+// Write attempts to write message contents to a target SNS topic.
+func (a *SNS) Write(msg types.Message) error {
+	if a.sns == nil {
+		return types.ErrNotConnected
+	}
+
+	return msg.Iter(func(i int, p types.Part) error {
+		lMsg := message.Lock(msg, i)
+
+		attributes := map[string]*sns.MessageAttributeValue{}
+		p.Metadata().Iter(func(k, v string) error {
+			// SNS rejects attributes with empty values.
+			if len(v) > 0 {
+				attributes[k] = &sns.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String(v),
+				}
+			}
+			return nil
+		})
+
+		input := &sns.PublishInput{
+			TopicArn: aws.String(a.topicArn.Get(lMsg)),
+			Message:  aws.String(string(p.Get())),
+		}
+		if len(attributes) > 0 {
+			input.MessageAttributes = attributes
+		}
+
+		var opts []request.Option
+		if a.groupID != nil || a.dedupID != nil {
+			var groupID, dedupID string
+			if a.groupID != nil {
+				groupID = a.groupID.Get(lMsg)
+			}
+			if a.dedupID != nil {
+				dedupID = a.dedupID.Get(lMsg)
+			}
+			opts = append(opts, snsFIFOParams(groupID, dedupID))
+		}
+
+		ctx, cancel := context.WithTimeout(aws.BackgroundContext(), a.timeout)
+		defer cancel()
+
+		_, err := a.sns.PublishWithContext(ctx, input, opts...)
+		return err
+	})
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (a *SNS) CloseAsync() {
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (a *SNS) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+//------------------------------------------------------------------------------
+
+type mockSNS struct {
+	snsiface.SNSAPI
+	fn func(*sns.PublishInput, ...request.Option) (*sns.PublishOutput, error)
+}
+
+func (m *mockSNS) PublishWithContext(_ aws.Context, input *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error) {
+	return m.fn(input, opts...)
+}
+
+func TestSNSBadConfig(t *testing.T) {
+	conf := NewSNSConfig()
+	if _, err := NewSNS(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing topic")
+	}
+
+	conf.TopicArn = "foo"
+	conf.Timeout = "nope"
+	if _, err := NewSNS(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad timeout")
+	}
+}
+
+func TestSNSPublish(t *testing.T) {
+	conf := NewSNSConfig()
+	conf.TopicArn = "arn:aws:sns:us-east-1:123:${!metadata:topic}"
+
+	s, err := NewSNS(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var inputs []*sns.PublishInput
+	s.sns = &mockSNS{
+		fn: func(input *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error) {
+			if len(opts) > 0 {
+				t.Errorf("Unexpected request options: %v", len(opts))
+			}
+			inputs = append(inputs, input)
+			return &sns.PublishOutput{}, nil
+		},
+	}
+
+	msg := message.New([][]byte{[]byte("first"), []byte("second")})
+	msg.Get(0).Metadata().Set("topic", "foo").Set("empty", "")
+	msg.Get(1).Metadata().Set("topic", "bar")
+	if err = s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []*sns.PublishInput{
+		{
+			TopicArn: aws.String("arn:aws:sns:us-east-1:123:foo"),
+			Message:  aws.String("first"),
+			MessageAttributes: map[string]*sns.MessageAttributeValue{
+				"topic": {
+					DataType:    aws.String("String"),
+					StringValue: aws.String("foo"),
+				},
+			},
+		},
+		{
+			TopicArn: aws.String("arn:aws:sns:us-east-1:123:bar"),
+			Message:  aws.String("second"),
+			MessageAttributes: map[string]*sns.MessageAttributeValue{
+				"topic": {
+					DataType:    aws.String("String"),
+					StringValue: aws.String("bar"),
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(exp, inputs) {
+		t.Errorf("Wrong publish inputs: %v != %v", inputs, exp)
+	}
+}
+
+func TestSNSFIFOParams(t *testing.T) {
+	var bodies []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, values)
+		w.Write([]byte(`<PublishResponse><PublishResult><MessageId>foo</MessageId></PublishResult></PublishResponse>`))
+	}))
+	defer server.Close()
+
+	conf := NewSNSConfig()
+	conf.TopicArn = "arn:aws:sns:us-east-1:123:foo.fifo"
+	conf.MessageGroupID = "${!metadata:group}"
+	conf.MessageDeduplicationID = "${!content}-dedupe"
+
+	s, err := NewSNS(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sns = sns.New(session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})))
+
+	msg := message.New([][]byte{[]byte("hello")})
+	msg.Get(0).Metadata().Set("group", "foo")
+	if err = s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := 1, len(bodies); exp != act {
+		t.Fatalf("Wrong count of requests: %v != %v", act, exp)
+	}
+	for k, v := range map[string]string{
+		"Action":                 "Publish",
+		"TopicArn":               "arn:aws:sns:us-east-1:123:foo.fifo",
+		"Message":                "hello",
+		"MessageGroupId":         "foo",
+		"MessageDeduplicationId": "hello-dedupe",
+	} {
+		if act := bodies[0].Get(k); act != v {
+			t.Errorf("Wrong value for %v: %v != %v", k, act, v)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,325 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/text"
+	btls "github.com/Jeffail/benthos/lib/util/tls"
+	"github.com/gofrs/uuid"
+)
+
+//------------------------------------------------------------------------------
+
+// SplunkHECConfig contains configuration fields for the SplunkHEC output type.
+type SplunkHECConfig struct {
+	URL             string      `json:"url" yaml:"url"`
+	Token           string      `json:"token" yaml:"token"`
+	Index           string      `json:"index" yaml:"index"`
+	Source          string      `json:"source" yaml:"source"`
+	SourceType      string      `json:"sourcetype" yaml:"sourcetype"`
+	Host            string      `json:"host" yaml:"host"`
+	Gzip            bool        `json:"gzip" yaml:"gzip"`
+	UseAck          bool        `json:"use_ack" yaml:"use_ack"`
+	Channel         string      `json:"channel" yaml:"channel"`
+	AckPollInterval string      `json:"ack_poll_interval" yaml:"ack_poll_interval"`
+	AckTimeout      string      `json:"ack_timeout" yaml:"ack_timeout"`
+	Timeout         string      `json:"timeout" yaml:"timeout"`
+	TLS             btls.Config `json:"tls" yaml:"tls"`
+}
+
+// NewSplunkHECConfig creates a new SplunkHECConfig with default values.
+func NewSplunkHECConfig() SplunkHECConfig {
+	return SplunkHECConfig{
+		URL:             "https://localhost:8088/services/collector/event",
+		Token:           "",
+		Index:           "",
+		Source:          "",
+		SourceType:      "",
+		Host:            "",
+		Gzip:            false,
+		UseAck:          false,
+		Channel:         "",
+		AckPollInterval: "1s",
+		AckTimeout:      "30s",
+		Timeout:         "5s",
+		TLS:             btls.NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SplunkHEC is a writer type that sends messages as events to a Splunk HTTP
+// Event Collector.
+type SplunkHEC struct {
+	conf   SplunkHECConfig
+	client http.Client
+
+	ackURL          string
+	channel         string
+	ackPollInterval time.Duration
+	ackTimeout      time.Duration
+
+	index      *text.InterpolatedString
+	source     *text.InterpolatedString
+	sourceType *text.InterpolatedString
+	host       *text.InterpolatedString
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mEvents     metrics.StatCounter
+	mAckTimeout metrics.StatCounter
+}
+
+// NewSplunkHEC creates a new SplunkHEC writer type.
+func NewSplunkHEC(conf SplunkHECConfig, log log.Modular, stats metrics.Type) (*SplunkHEC, error) {
+	s := &SplunkHEC{
+		conf:        conf,
+		channel:     conf.Channel,
+		index:       text.NewInterpolatedString(conf.Index),
+		source:      text.NewInterpolatedString(conf.Source),
+		sourceType:  text.NewInterpolatedString(conf.SourceType),
+		host:        text.NewInterpolatedString(conf.Host),
+		closeChan:   make(chan struct{}),
+		log:         log,
+		stats:       stats,
+		mEvents:     stats.GetCounter("events"),
+		mAckTimeout: stats.GetCounter("ack.timeout"),
+	}
+	if len(conf.Token) == 0 {
+		return nil, errors.New("a token must be specified")
+	}
+
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+
+	if conf.UseAck {
+		if len(s.channel) == 0 {
+			u4, err := uuid.NewV4()
+			if err != nil {
+				return nil, err
+			}
+			s.channel = u4.String()
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/event")
+		u.Path = strings.TrimSuffix(u.Path, "/raw")
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/ack"
+		u.RawQuery = url.Values{"channel": []string{s.channel}}.Encode()
+		s.ackURL = u.String()
+
+		if s.ackPollInterval, err = time.ParseDuration(conf.AckPollInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse ack poll interval string: %v", err)
+		}
+		if s.ackTimeout, err = time.ParseDuration(conf.AckTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse ack timeout string: %v", err)
+		}
+	}
+
+	if tout := conf.Timeout; len(tout) > 0 {
+		if s.client.Timeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
+		}
+	}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		s.client.Transport = &http.Transport{
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect does nothing as each write is a separate request.
+func (s *SplunkHEC) Connect() error {
+	s.log.Infof("Sending events to Splunk HTTP Event Collector at: %v\n", s.conf.URL)
+	return nil
+}
+
+// splunkResponse is the body of a response from the collector.
+type splunkResponse struct {
+	Text  string          `json:"text"`
+	Code  int             `json:"code"`
+	AckID *int64          `json:"ackId"`
+	Acks  map[string]bool `json:"acks"`
+}
+
+func (s *SplunkHEC) post(u string, body []byte, compress bool) (splunkResponse, error) {
+	var res splunkResponse
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		zw.Close()
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return res, err
+	}
+	req.Header.Set("Authorization", "Splunk "+s.conf.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if len(s.channel) > 0 {
+		req.Header.Set("X-Splunk-Request-Channel", s.channel)
+	}
+
+	hRes, err := s.client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	resBody, _ := ioutil.ReadAll(hRes.Body)
+	hRes.Body.Close()
+
+	jErr := json.Unmarshal(resBody, &res)
+	if hRes.StatusCode != http.StatusOK {
+		if jErr == nil && len(res.Text) > 0 {
+			return res, fmt.Errorf("request returned unexpected status: %v: %v (code %v)", hRes.Status, res.Text, res.Code)
+		}
+		return res, fmt.Errorf("request returned unexpected status: %v", hRes.Status)
+	}
+	return res, nil
+}
+
+// event creates an event from a message part. Parts containing valid JSON are
+// embedded as JSON, and other parts are sent as strings.
+func (s *SplunkHEC) event(msg types.Message, index int) ([]byte, error) {
+	lMsg := message.Lock(msg, index)
+	event := map[string]interface{}{}
+
+	raw := msg.Get(index).Get()
+	if json.Valid(raw) {
+		event["event"] = json.RawMessage(raw)
+	} else {
+		event["event"] = string(raw)
+	}
+	for k, v := range map[string]*text.InterpolatedString{
+		"index":      s.index,
+		"source":     s.source,
+		"sourcetype": s.sourceType,
+		"host":       s.host,
+	} {
+		if str := v.Get(lMsg); len(str) > 0 {
+			event[k] = str
+		}
+	}
+	return json.Marshal(event)
+}
+
+// waitForAck polls the collector until an event batch is acknowledged as
+// indexed.
+func (s *SplunkHEC) waitForAck(ackID int64) error {
+	body, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return err
+	}
+	key := strconv.FormatInt(ackID, 10)
+	deadline := time.Now().Add(s.ackTimeout)
+	for {
+		select {
+		case <-time.After(s.ackPollInterval):
+		case <-s.closeChan:
+			return types.ErrTypeClosed
+		}
+		res, err := s.post(s.ackURL, body, false)
+		if err != nil {
+			return fmt.Errorf("failed to poll ack: %v", err)
+		}
+		if res.Acks[key] {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			s.mAckTimeout.Incr(1)
+			return fmt.Errorf("timed out waiting for ack %v", ackID)
+		}
+	}
+}
+
+// Write attempts to send each part of a message as an event with a single
+// request.
+func (s *SplunkHEC) Write(msg types.Message) error {
+	var body bytes.Buffer
+	for i := 0; i < msg.Len(); i++ {
+		event, err := s.event(msg, i)
+		if err != nil {
+			return err
+		}
+		body.Write(event)
+	}
+
+	res, err := s.post(s.conf.URL, body.Bytes(), s.conf.Gzip)
+	if err != nil {
+		return err
+	}
+	if s.conf.UseAck {
+		if res.AckID == nil {
+			return errors.New("response did not contain an ack id, indexer acknowledgement may be disabled for the token")
+		}
+		if err = s.waitForAck(*res.AckID); err != nil {
+			return err
+		}
+	}
+	s.mEvents.Incr(int64(msg.Len()))
+	return nil
+}
+
+// CloseAsync shuts down the SplunkHEC writer and stops processing messages.
+func (s *SplunkHEC) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+	})
+}
+
+// WaitForClose blocks until the SplunkHEC writer has closed down.
+func (s *SplunkHEC) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
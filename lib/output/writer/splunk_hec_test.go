@@ -0,0 +1,225 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+func TestSplunkHECBadConfig(t *testing.T) {
+	conf := NewSplunkHECConfig()
+	if _, err := NewSplunkHEC(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing token")
+	}
+
+	conf.Token = "foo"
+	conf.UseAck = true
+	conf.AckTimeout = "nope"
+	if _, err := NewSplunkHEC(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad ack timeout")
+	}
+}
+
+func TestSplunkHECWrite(t *testing.T) {
+	var reqBody, reqAuth, reqEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rdr io.Reader = r.Body
+		if reqEncoding = r.Header.Get("Content-Encoding"); reqEncoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			rdr = zr
+		}
+		b, _ := ioutil.ReadAll(rdr)
+		reqBody, reqAuth = string(b), r.Header.Get("Authorization")
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	for _, compress := range []bool{false, true} {
+		conf := NewSplunkHECConfig()
+		conf.URL = ts.URL + "/services/collector/event"
+		conf.Token = "footoken"
+		conf.Index = "${!metadata:index}"
+		conf.SourceType = "benthos"
+		conf.Gzip = compress
+
+		s, err := NewSplunkHEC(conf, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := message.New([][]byte{
+			[]byte(`{"foo":"bar"}`),
+			[]byte(`hello world`),
+		})
+		msg.Get(0).Metadata().Set("index", "main")
+
+		if err = s.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+
+		exp := `{"event":{"foo":"bar"},"index":"main","sourcetype":"benthos"}` +
+			`{"event":"hello world","sourcetype":"benthos"}`
+		if reqBody != exp {
+			t.Errorf("Wrong body: %v != %v", reqBody, exp)
+		}
+		if exp, act := "Splunk footoken", reqAuth; exp != act {
+			t.Errorf("Wrong auth: %v != %v", act, exp)
+		}
+		if compress != (reqEncoding == "gzip") {
+			t.Errorf("Wrong content encoding: %v", reqEncoding)
+		}
+	}
+}
+
+func TestSplunkHECWriteError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"text":"Invalid token","code":4}`))
+	}))
+	defer ts.Close()
+
+	conf := NewSplunkHECConfig()
+	conf.URL = ts.URL
+	conf.Token = "footoken"
+
+	s, err := NewSplunkHEC(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Write(message.New([][]byte{[]byte(`foo`)}))
+	if err == nil {
+		t.Fatal("Expected error from forbidden response")
+	}
+	if exp, act := "request returned unexpected status: 403 Forbidden: Invalid token (code 4)", err.Error(); exp != act {
+		t.Errorf("Wrong error: %v != %v", act, exp)
+	}
+}
+
+func TestSplunkHECAck(t *testing.T) {
+	var mut sync.Mutex
+	polls := 0
+	channels := map[string]int{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+
+		channels[r.Header.Get("X-Splunk-Request-Channel")]++
+		switch r.URL.Path {
+		case "/services/collector/event":
+			w.Write([]byte(`{"text":"Success","code":0,"ackId":7}`))
+		case "/services/collector/ack":
+			var req struct {
+				Acks []int64 `json:"acks"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Acks) != 1 || req.Acks[0] != 7 {
+				http.Error(w, "bad ack request", http.StatusBadRequest)
+				return
+			}
+			if r.URL.Query().Get("channel") != "foochannel" {
+				http.Error(w, "bad channel", http.StatusBadRequest)
+				return
+			}
+			polls++
+			if polls < 3 {
+				w.Write([]byte(`{"acks":{"7":false}}`))
+			} else {
+				w.Write([]byte(`{"acks":{"7":true}}`))
+			}
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewSplunkHECConfig()
+	conf.URL = ts.URL + "/services/collector/event"
+	conf.Token = "footoken"
+	conf.UseAck = true
+	conf.Channel = "foochannel"
+	conf.AckPollInterval = "1ms"
+
+	s, err := NewSplunkHEC(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Write(message.New([][]byte{[]byte(`foo`)})); err != nil {
+		t.Fatal(err)
+	}
+
+	mut.Lock()
+	defer mut.Unlock()
+	if polls != 3 {
+		t.Errorf("Wrong count of polls: %v", polls)
+	}
+	if exp, act := 4, channels["foochannel"]; exp != act {
+		t.Errorf("Wrong count of requests with channel: %v != %v", act, exp)
+	}
+}
+
+func TestSplunkHECAckTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/collector/ack" {
+			w.Write([]byte(`{"acks":{"1":false}}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0,"ackId":1}`))
+	}))
+	defer ts.Close()
+
+	conf := NewSplunkHECConfig()
+	conf.URL = ts.URL + "/services/collector/event"
+	conf.Token = "footoken"
+	conf.UseAck = true
+	conf.AckPollInterval = "1ms"
+	conf.AckTimeout = "10ms"
+
+	s, err := NewSplunkHEC(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.CloseAsync()
+
+	if err = s.Write(message.New([][]byte{[]byte(`foo`)})); err == nil {
+		t.Error("Expected error from ack timeout")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,292 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/retries"
+	"github.com/Jeffail/gabs"
+	"github.com/cenkalti/backoff"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLConfig contains configuration fields for the SQL output type.
+type SQLConfig struct {
+	Driver             string   `json:"driver" yaml:"driver"`
+	DSN                string   `json:"dsn" yaml:"dsn"`
+	Query              string   `json:"query" yaml:"query"`
+	Args               []string `json:"args" yaml:"args"`
+	MaxOpenConnections int      `json:"max_open_connections" yaml:"max_open_connections"`
+	MaxIdleConnections int      `json:"max_idle_connections" yaml:"max_idle_connections"`
+	retries.Config     `json:",inline" yaml:",inline"`
+}
+
+// NewSQLConfig creates a new SQLConfig with default values.
+func NewSQLConfig() SQLConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "100ms"
+	rConf.Backoff.MaxInterval = "1s"
+	rConf.Backoff.MaxElapsedTime = "10s"
+	return SQLConfig{
+		Driver:             "postgres",
+		DSN:                "",
+		Query:              "",
+		Args:               []string{},
+		MaxOpenConnections: 0,
+		MaxIdleConnections: 2,
+		Config:             rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SQL is a writer type that executes a parameterised statement against a SQL
+// database for each message part, where each batch is executed within a single
+// transaction.
+type SQL struct {
+	conf SQLConfig
+
+	newBackoff func() backoff.BackOff
+
+	dbMut sync.RWMutex
+	db    *sql.DB
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mRows     metrics.StatCounter
+	mRetry    metrics.StatCounter
+	mJSONErr  metrics.StatCounter
+	mTxFailed metrics.StatCounter
+}
+
+// NewSQL creates a new SQL writer type.
+func NewSQL(conf SQLConfig, log log.Modular, stats metrics.Type) (*SQL, error) {
+	if conf.Driver != "postgres" && conf.Driver != "mysql" {
+		return nil, fmt.Errorf("unsupported driver: %v", conf.Driver)
+	}
+	if len(conf.Query) == 0 {
+		return nil, errors.New("a query must be specified")
+	}
+
+	s := &SQL{
+		conf:      conf,
+		closeChan: make(chan struct{}),
+		log:       log,
+		stats:     stats,
+
+		mRows:     stats.GetCounter("rows"),
+		mRetry:    stats.GetCounter("retry"),
+		mJSONErr:  stats.GetCounter("error.json"),
+		mTxFailed: stats.GetCounter("error.transaction"),
+	}
+
+	var err error
+	if s.newBackoff, err = conf.GetCtor(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to open a connection pool to the target database.
+func (s *SQL) Connect() error {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	if s.db != nil {
+		return nil
+	}
+
+	db, err := sql.Open(s.conf.Driver, s.conf.DSN)
+	if err != nil {
+		return err
+	}
+	db.SetMaxOpenConns(s.conf.MaxOpenConnections)
+	db.SetMaxIdleConns(s.conf.MaxIdleConnections)
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return err
+	}
+	s.db = db
+
+	s.log.Infof("Executing statements against %v database\n", s.conf.Driver)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// sqlRetryable returns true if an error indicates that a transaction was
+// aborted due to a conflict with a concurrent transaction, and can therefore
+// be retried.
+func sqlRetryable(err error) bool {
+	switch t := err.(type) {
+	case *pq.Error:
+		// serialization_failure and deadlock_detected
+		return t.Code == "40001" || t.Code == "40P01"
+	case *mysql.MySQLError:
+		// ER_LOCK_DEADLOCK and ER_LOCK_WAIT_TIMEOUT
+		return t.Number == 1213 || t.Number == 1205
+	}
+	return false
+}
+
+// args extracts the statement arguments from a message part.
+func (s *SQL) args(part types.Part) ([]interface{}, error) {
+	if len(s.conf.Args) == 0 {
+		return nil, nil
+	}
+	jObj, err := part.JSON()
+	if err != nil {
+		return nil, err
+	}
+	gObj, err := gabs.Consume(jObj)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(s.conf.Args))
+	for i, path := range s.conf.Args {
+		switch t := gObj.Path(path).Data().(type) {
+		case map[string]interface{}, []interface{}:
+			var b []byte
+			if b, err = json.Marshal(t); err != nil {
+				return nil, err
+			}
+			args[i] = string(b)
+		default:
+			args[i] = t
+		}
+	}
+	return args, nil
+}
+
+// execBatch executes the statement for each set of arguments within a single
+// transaction.
+func (s *SQL) execBatch(db *sql.DB, batch [][]interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(s.conf.Query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, args := range batch {
+		if _, err = stmt.Exec(args...); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	stmt.Close()
+	return tx.Commit()
+}
+
+// Write attempts to execute the statement for each part of a message within a
+// transaction, retrying the transaction if it fails due to a serialization
+// failure.
+func (s *SQL) Write(msg types.Message) error {
+	s.dbMut.RLock()
+	db := s.db
+	s.dbMut.RUnlock()
+
+	if db == nil {
+		return types.ErrNotConnected
+	}
+
+	batch := make([][]interface{}, 0, msg.Len())
+	msg.Iter(func(i int, part types.Part) error {
+		args, err := s.args(part)
+		if err != nil {
+			s.mJSONErr.Incr(1)
+			s.log.Errorf("Failed to extract statement arguments from message: %v\n", err)
+			return nil
+		}
+		batch = append(batch, args)
+		return nil
+	})
+	if len(batch) == 0 {
+		return nil
+	}
+
+	boff := s.newBackoff()
+	for {
+		err := s.execBatch(db, batch)
+		if err == nil {
+			s.mRows.Incr(int64(len(batch)))
+			return nil
+		}
+		if !sqlRetryable(err) {
+			s.mTxFailed.Incr(1)
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			s.mTxFailed.Incr(1)
+			return fmt.Errorf("transaction retries exhausted: %v", err)
+		}
+		s.mRetry.Incr(1)
+		select {
+		case <-time.After(wait):
+		case <-s.closeChan:
+			return types.ErrTypeClosed
+		}
+	}
+}
+
+// CloseAsync shuts down the SQL writer and stops processing messages.
+func (s *SQL) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+		s.dbMut.Lock()
+		if s.db != nil {
+			s.db.Close()
+			s.db = nil
+		}
+		s.dbMut.Unlock()
+	})
+}
+
+// WaitForClose blocks until the SQL writer has closed down.
+func (s *SQL) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
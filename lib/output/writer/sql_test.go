@@ -0,0 +1,265 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/lib/pq"
+)
+
+//------------------------------------------------------------------------------
+
+// fakeSQLDriver records the arguments of committed statements, and can be
+// configured to fail a number of transactions with an error.
+type fakeSQLDriver struct {
+	mut       sync.Mutex
+	committed [][]driver.Value
+	failures  []error
+}
+
+var fakeSQL = &fakeSQLDriver{}
+
+func init() {
+	sql.Register("sql_output_fake", fakeSQL)
+}
+
+func (d *fakeSQLDriver) reset(failures ...error) {
+	d.mut.Lock()
+	d.committed = nil
+	d.failures = failures
+	d.mut.Unlock()
+}
+
+func (d *fakeSQLDriver) rows() [][]driver.Value {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.committed
+}
+
+func (d *fakeSQLDriver) Open(name string) (driver.Conn, error) {
+	return &fakeSQLConn{d: d}, nil
+}
+
+type fakeSQLConn struct {
+	d       *fakeSQLDriver
+	pending [][]driver.Value
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{c: c}, nil
+}
+
+func (c *fakeSQLConn) Close() error {
+	return nil
+}
+
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	c.pending = nil
+	return c, nil
+}
+
+func (c *fakeSQLConn) Commit() error {
+	c.d.mut.Lock()
+	defer c.d.mut.Unlock()
+	if len(c.d.failures) > 0 {
+		err := c.d.failures[0]
+		c.d.failures = c.d.failures[1:]
+		return err
+	}
+	c.d.committed = append(c.d.committed, c.pending...)
+	return nil
+}
+
+func (c *fakeSQLConn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+type fakeSQLStmt struct {
+	c *fakeSQLConn
+}
+
+func (s *fakeSQLStmt) Close() error {
+	return nil
+}
+
+func (s *fakeSQLStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.c.pending = append(s.c.pending, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+//------------------------------------------------------------------------------
+
+func newFakeSQL(t *testing.T, conf SQLConfig) *SQL {
+	t.Helper()
+	s, err := NewSQL(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.conf.Driver = "sql_output_fake"
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSQLBadConfig(t *testing.T) {
+	conf := NewSQLConfig()
+	conf.Query = "INSERT INTO foo (bar) VALUES ($1)"
+	conf.Driver = "sqlite3"
+	if _, err := NewSQL(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from unsupported driver")
+	}
+
+	conf.Driver = "postgres"
+	conf.Query = ""
+	if _, err := NewSQL(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing query")
+	}
+}
+
+func TestSQLBatchTransaction(t *testing.T) {
+	fakeSQL.reset()
+
+	conf := NewSQLConfig()
+	conf.Query = "INSERT INTO foo (id, name, tags) VALUES ($1, $2, $3)"
+	conf.Args = []string{"id", "user.name", "tags"}
+
+	s := newFakeSQL(t, conf)
+	defer s.CloseAsync()
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":1,"user":{"name":"foo"},"tags":["a","b"]}`),
+		[]byte(`not json`),
+		[]byte(`{"id":2,"user":{"name":"bar"}}`),
+	})
+	if err := s.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]driver.Value{
+		{float64(1), "foo", `["a","b"]`},
+		{float64(2), "bar", nil},
+	}
+	if act := fakeSQL.rows(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rows committed: %v != %v", act, exp)
+	}
+}
+
+func TestSQLSerializationRetry(t *testing.T) {
+	fakeSQL.reset(
+		&pq.Error{Code: "40001"},
+		&pq.Error{Code: "40P01"},
+	)
+
+	conf := NewSQLConfig()
+	conf.Query = "INSERT INTO foo (id) VALUES ($1)"
+	conf.Args = []string{"id"}
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	s := newFakeSQL(t, conf)
+	defer s.CloseAsync()
+
+	if err := s.Write(message.New([][]byte{
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":2}`),
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][]driver.Value{{float64(1)}, {float64(2)}}
+	if act := fakeSQL.rows(); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rows committed: %v != %v", act, exp)
+	}
+}
+
+func TestSQLNonRetryableError(t *testing.T) {
+	fakeSQL.reset(
+		&pq.Error{Code: "23505"},
+		&pq.Error{Code: "23505"},
+	)
+
+	conf := NewSQLConfig()
+	conf.Query = "INSERT INTO foo (id) VALUES ($1)"
+	conf.Args = []string{"id"}
+	conf.Backoff.InitialInterval = "1ms"
+
+	s := newFakeSQL(t, conf)
+	defer s.CloseAsync()
+
+	if err := s.Write(message.New([][]byte{[]byte(`{"id":1}`)})); err == nil {
+		t.Error("Expected error from unique violation")
+	}
+	if act := fakeSQL.rows(); len(act) > 0 {
+		t.Errorf("Unexpected rows committed: %v", act)
+	}
+}
+
+func TestSQLRetriesExhausted(t *testing.T) {
+	fakeSQL.reset(
+		&pq.Error{Code: "40001"},
+		&pq.Error{Code: "40001"},
+		&pq.Error{Code: "40001"},
+	)
+
+	conf := NewSQLConfig()
+	conf.Query = "INSERT INTO foo (id) VALUES ($1)"
+	conf.Args = []string{"id"}
+	conf.MaxRetries = 2
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	s := newFakeSQL(t, conf)
+	defer func() {
+		s.CloseAsync()
+		if err := s.WaitForClose(time.Second); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := s.Write(message.New([][]byte{[]byte(`{"id":1}`)})); err == nil {
+		t.Error("Expected error from exhausted retries")
+	}
+	if act := fakeSQL.rows(); len(act) > 0 {
+		t.Errorf("Unexpected rows committed: %v", act)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,317 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// SubprocessConfig contains configuration fields for the Subprocess output
+// type.
+type SubprocessConfig struct {
+	Name              string   `json:"name" yaml:"name"`
+	Args              []string `json:"args" yaml:"args"`
+	Codec             string   `json:"codec" yaml:"codec"`
+	RestartBackoff    string   `json:"restart_backoff" yaml:"restart_backoff"`
+	MaxRestartBackoff string   `json:"max_restart_backoff" yaml:"max_restart_backoff"`
+}
+
+// NewSubprocessConfig creates a new SubprocessConfig with default values.
+func NewSubprocessConfig() SubprocessConfig {
+	return SubprocessConfig{
+		Name:              "",
+		Args:              []string{},
+		Codec:             "lines",
+		RestartBackoff:    "1s",
+		MaxRestartBackoff: "60s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Subprocess is a writer that executes a command and writes messages to its
+// stdin, restarting the command if it exits.
+type Subprocess struct {
+	conf SubprocessConfig
+
+	restartThrot *throttle.Type
+
+	cmdMut   sync.Mutex
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	exited   chan struct{}
+	started  bool
+	consumed bool
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
+	log   log.Modular
+	stats metrics.Type
+
+	mStarted metrics.StatCounter
+	mExited  metrics.StatCounter
+	mStderr  metrics.StatCounter
+}
+
+// NewSubprocess creates a new Subprocess writer type.
+func NewSubprocess(
+	conf SubprocessConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*Subprocess, error) {
+	if len(conf.Name) == 0 {
+		return nil, errors.New("a command name must be specified")
+	}
+	if conf.Codec != "lines" && conf.Codec != "length_prefixed" {
+		return nil, fmt.Errorf("codec not recognised: %v", conf.Codec)
+	}
+
+	backoff, maxBackoff := time.Second, time.Minute
+	if tout := conf.RestartBackoff; len(tout) > 0 {
+		var err error
+		if backoff, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse restart backoff string: %v", err)
+		}
+	}
+	if tout := conf.MaxRestartBackoff; len(tout) > 0 {
+		var err error
+		if maxBackoff, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse max restart backoff string: %v", err)
+		}
+	}
+
+	s := &Subprocess{
+		conf:       conf,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		log:        log,
+		stats:      stats,
+		mStarted:   stats.GetCounter("subprocess.started"),
+		mExited:    stats.GetCounter("subprocess.exited"),
+		mStderr:    stats.GetCounter("subprocess.stderr"),
+	}
+	s.restartThrot = throttle.New(
+		throttle.OptMaxUnthrottledRetries(0),
+		throttle.OptThrottlePeriod(backoff),
+		throttle.OptMaxExponentPeriod(maxBackoff),
+		throttle.OptCloseChan(s.closeChan),
+	)
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *Subprocess) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		s.mStderr.Incr(1)
+		s.log.Warnf("Subprocess stderr: %s\n", scanner.Bytes())
+	}
+}
+
+func (s *Subprocess) waitProcess(cmd *exec.Cmd, exited chan struct{}) {
+	if err := cmd.Wait(); err != nil {
+		s.log.Warnf("Command exited: %v\n", err)
+	} else {
+		s.log.Infoln("Command exited")
+	}
+	s.mExited.Incr(1)
+	close(exited)
+}
+
+// stopProcess kills a running command and waits for it to be reaped.
+func (s *Subprocess) stopProcess(cmd *exec.Cmd) {
+	s.cmdMut.Lock()
+	defer s.cmdMut.Unlock()
+
+	if s.cmd != cmd {
+		return
+	}
+	s.stdin.Close()
+	cmd.Process.Kill()
+	<-s.exited
+	s.cmd = nil
+	s.stdin = nil
+	s.exited = nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect attempts to start the command, waiting for a restart backoff if the
+// command has previously exited.
+func (s *Subprocess) Connect() error {
+	s.cmdMut.Lock()
+	defer s.cmdMut.Unlock()
+
+	if s.cmd != nil {
+		return nil
+	}
+
+	if s.started {
+		// Backoff is only reset for commands that managed to consume data,
+		// this prevents busy looping over a command that fails immediately.
+		if s.consumed {
+			s.restartThrot.Reset()
+		}
+		s.cmdMut.Unlock()
+		ok := s.restartThrot.ExponentialRetry()
+		s.cmdMut.Lock()
+		if !ok {
+			return types.ErrTypeClosed
+		}
+	}
+
+	select {
+	case <-s.closeChan:
+		return types.ErrTypeClosed
+	default:
+	}
+
+	cmd := exec.Command(s.conf.Name, s.conf.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	s.started = true
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start command: %v", err)
+	}
+	go s.logStderr(stderr)
+
+	s.cmd = cmd
+	s.stdin = stdin
+	s.exited = make(chan struct{})
+	s.consumed = false
+	s.mStarted.Incr(1)
+	go s.waitProcess(cmd, s.exited)
+
+	s.log.Infof("Writing messages to the stdin of command: %v\n", s.conf.Name)
+	return nil
+}
+
+// Write attempts to write a message to the stdin of the command.
+func (s *Subprocess) Write(msg types.Message) error {
+	s.cmdMut.Lock()
+	cmd, stdin, exited := s.cmd, s.stdin, s.exited
+	s.cmdMut.Unlock()
+
+	if cmd == nil {
+		return types.ErrNotConnected
+	}
+
+	var buf bytes.Buffer
+	msg.Iter(func(i int, p types.Part) error {
+		data := p.Get()
+		if s.conf.Codec == "length_prefixed" {
+			var lenBytes [4]byte
+			binary.BigEndian.PutUint32(lenBytes[:], uint32(len(data)))
+			buf.Write(lenBytes[:])
+			buf.Write(data)
+		} else {
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+		return nil
+	})
+
+	select {
+	case <-exited:
+		s.stopProcess(cmd)
+		return types.ErrNotConnected
+	default:
+	}
+
+	if _, err := stdin.Write(buf.Bytes()); err != nil {
+		s.log.Errorf("Failed to write to command stdin: %v\n", err)
+		s.stopProcess(cmd)
+		select {
+		case <-s.closeChan:
+			return types.ErrTypeClosed
+		default:
+		}
+		return types.ErrNotConnected
+	}
+
+	s.cmdMut.Lock()
+	s.consumed = true
+	s.cmdMut.Unlock()
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+// The stdin of the command is closed, allowing it to flush any pending data
+// and exit gracefully.
+func (s *Subprocess) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+		s.cmdMut.Lock()
+		stdin, exited := s.stdin, s.exited
+		s.cmdMut.Unlock()
+		if stdin == nil {
+			close(s.closedChan)
+			return
+		}
+		stdin.Close()
+		go func() {
+			<-exited
+			close(s.closedChan)
+		}()
+	})
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs, in which case the command is killed.
+func (s *Subprocess) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-s.closedChan:
+	case <-time.After(timeout):
+		s.cmdMut.Lock()
+		if s.cmd != nil {
+			s.cmd.Process.Kill()
+		}
+		s.cmdMut.Unlock()
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,187 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package writer
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func newTestSubprocess(t *testing.T, conf SubprocessConfig) (*Subprocess, string, func()) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	dir, err := ioutil.TempDir("", "benthos_subprocess_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, "out")
+
+	conf.Name = "sh"
+	conf.Args = append([]string{"-c"}, conf.Args[0]+" "+outPath)
+	conf.RestartBackoff = "1ms"
+
+	s, err := NewSubprocess(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return s, outPath, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func closeSubprocess(t *testing.T, s *Subprocess) {
+	t.Helper()
+	s.CloseAsync()
+	if err := s.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubprocessBadConfig(t *testing.T) {
+	conf := NewSubprocessConfig()
+	if _, err := NewSubprocess(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from missing name")
+	}
+	conf.Name = "cat"
+	conf.Codec = "nope"
+	if _, err := NewSubprocess(conf, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad codec")
+	}
+}
+
+func TestSubprocessLines(t *testing.T) {
+	conf := NewSubprocessConfig()
+	conf.Args = []string{"cat >"}
+	s, outPath, cleanup := newTestSubprocess(t, conf)
+	defer cleanup()
+
+	if err := s.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(message.New([][]byte{[]byte("baz")})); err != nil {
+		t.Fatal(err)
+	}
+	closeSubprocess(t, s)
+
+	act, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "foo\nbar\nbaz\n"; exp != string(act) {
+		t.Errorf("Wrong output: %q != %q", act, exp)
+	}
+}
+
+func TestSubprocessLengthPrefixed(t *testing.T) {
+	conf := NewSubprocessConfig()
+	conf.Codec = "length_prefixed"
+	conf.Args = []string{"cat >"}
+	s, outPath, cleanup := newTestSubprocess(t, conf)
+	defer cleanup()
+
+	if err := s.Write(message.New([][]byte{[]byte("foo"), []byte("hello\nworld")})); err != nil {
+		t.Fatal(err)
+	}
+	closeSubprocess(t, s)
+
+	act, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exp []byte
+	for _, p := range []string{"foo", "hello\nworld"} {
+		var lenBytes [4]byte
+		binary.BigEndian.PutUint32(lenBytes[:], uint32(len(p)))
+		exp = append(exp, lenBytes[:]...)
+		exp = append(exp, p...)
+	}
+	if string(exp) != string(act) {
+		t.Errorf("Wrong output: %q != %q", act, exp)
+	}
+}
+
+func TestSubprocessRestart(t *testing.T) {
+	conf := NewSubprocessConfig()
+	conf.Args = []string{"head -n 1 >>"}
+	s, outPath, cleanup := newTestSubprocess(t, conf)
+	defer cleanup()
+	defer closeSubprocess(t, s)
+
+	if err := s.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Fatal(err)
+	}
+
+	s.cmdMut.Lock()
+	exited := s.exited
+	s.cmdMut.Unlock()
+	select {
+	case <-exited:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for command to exit")
+	}
+
+	if err := s.Write(message.New([][]byte{[]byte("bar")})); err != types.ErrNotConnected {
+		t.Fatalf("Expected not connected error, received: %v", err)
+	}
+	if err := s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(message.New([][]byte{[]byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+
+	s.cmdMut.Lock()
+	exited = s.exited
+	s.cmdMut.Unlock()
+	select {
+	case <-exited:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for command to exit")
+	}
+
+	act, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "foo\nbar\n"; exp != string(act) {
+		t.Errorf("Wrong output: %q != %q", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -21,6 +21,10 @@
 package writer
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"sync"
@@ -35,23 +39,59 @@ import (
 
 //------------------------------------------------------------------------------
 
+// WebsocketServerConfig contains configuration fields for hosting a websocket
+// endpoint that messages are broadcast from.
+type WebsocketServerConfig struct {
+	Address          string `json:"address" yaml:"address"`
+	Path             string `json:"path" yaml:"path"`
+	ClientBufferSize int    `json:"client_buffer_size" yaml:"client_buffer_size"`
+	WriteTimeout     string `json:"write_timeout" yaml:"write_timeout"`
+}
+
 // WebsocketConfig contains configuration fields for the Websocket output type.
 type WebsocketConfig struct {
+	Mode        string `json:"mode" yaml:"mode"`
 	URL         string `json:"url" yaml:"url"`
 	auth.Config `json:",inline" yaml:",inline"`
+	Server      WebsocketServerConfig `json:"server" yaml:"server"`
 }
 
 // NewWebsocketConfig creates a new WebsocketConfig with default values.
 func NewWebsocketConfig() WebsocketConfig {
 	return WebsocketConfig{
+		Mode:   "client",
 		URL:    "ws://localhost:4195/post/ws",
 		Config: auth.NewConfig(),
+		Server: WebsocketServerConfig{
+			Address:          "",
+			Path:             "/get/ws",
+			ClientBufferSize: 100,
+			WriteTimeout:     "5s",
+		},
 	}
 }
 
 //------------------------------------------------------------------------------
 
-// Websocket is an output type that serves Websocket messages.
+// wsServerClient is a client connected to the websocket server endpoint.
+type wsServerClient struct {
+	conn      *websocket.Conn
+	sendChan  chan []byte
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *wsServerClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+		c.conn.Close()
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// Websocket is an output type that either sends messages to a websocket server,
+// or broadcasts them to clients connected to a hosted websocket endpoint.
 type Websocket struct {
 	log   log.Modular
 	stats metrics.Type
@@ -60,19 +100,62 @@ type Websocket struct {
 
 	conf   WebsocketConfig
 	client *websocket.Conn
+
+	server        *http.Server
+	serverStarted bool
+	serverClosed  bool
+	writeTimeout  time.Duration
+	clients       map[*wsServerClient]struct{}
+	clientsMut    sync.RWMutex
+
+	mClientConnected metrics.StatCounter
+	mClientEvicted   metrics.StatCounter
 }
 
 // NewWebsocket creates a new Websocket output type.
 func NewWebsocket(
 	conf WebsocketConfig,
+	mgr types.Manager,
 	log log.Modular,
 	stats metrics.Type,
 ) (*Websocket, error) {
 	ws := &Websocket{
-		log:   log,
-		stats: stats,
-		lock:  &sync.Mutex{},
-		conf:  conf,
+		log:     log,
+		stats:   stats,
+		lock:    &sync.Mutex{},
+		conf:    conf,
+		clients: map[*wsServerClient]struct{}{},
+
+		mClientConnected: stats.GetCounter("server.client.connected"),
+		mClientEvicted:   stats.GetCounter("server.client.evicted"),
+	}
+
+	switch conf.Mode {
+	case "client":
+		return ws, nil
+	case "server":
+	default:
+		return nil, fmt.Errorf("mode not recognised: %v", conf.Mode)
+	}
+
+	if conf.Server.ClientBufferSize < 1 {
+		return nil, fmt.Errorf("client buffer size must be at least 1, got: %v", conf.Server.ClientBufferSize)
+	}
+	if tout := conf.Server.WriteTimeout; len(tout) > 0 {
+		var err error
+		if ws.writeTimeout, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse write timeout string: %v", err)
+		}
+	}
+
+	if len(conf.Server.Address) > 0 {
+		mux := http.NewServeMux()
+		mux.HandleFunc(conf.Server.Path, ws.serverHandler)
+		ws.server = &http.Server{Addr: conf.Server.Address, Handler: mux}
+	} else {
+		mgr.RegisterEndpoint(
+			conf.Server.Path, "Receive messages broadcast over websocket connections.", ws.serverHandler,
+		)
 	}
 	return ws, nil
 }
@@ -88,11 +171,132 @@ func (w *Websocket) getWS() *websocket.Conn {
 
 //------------------------------------------------------------------------------
 
-// Connect establishes a connection to an Websocket server.
+// serverHandler upgrades requests to websocket connections and registers them
+// as clients that receive broadcast messages.
+func (w *Websocket) serverHandler(rw http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		w.log.Warnf("Websocket request failed: %v\n", err)
+		return
+	}
+
+	client := &wsServerClient{
+		conn:      conn,
+		sendChan:  make(chan []byte, w.conf.Server.ClientBufferSize),
+		closeChan: make(chan struct{}),
+	}
+
+	w.lock.Lock()
+	closed := w.serverClosed
+	w.lock.Unlock()
+	if closed {
+		conn.Close()
+		return
+	}
+
+	w.clientsMut.Lock()
+	w.clients[client] = struct{}{}
+	w.clientsMut.Unlock()
+	w.mClientConnected.Incr(1)
+
+	go func() {
+		for {
+			select {
+			case data := <-client.sendChan:
+				if w.writeTimeout > 0 {
+					conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+				}
+				if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+					w.log.Debugf("Failed to write to websocket client: %v\n", err)
+					w.removeClient(client)
+					return
+				}
+			case <-client.closeChan:
+				return
+			}
+		}
+	}()
+
+	// Read until the client goes away in order to process control messages.
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			w.removeClient(client)
+			return
+		}
+	}
+}
+
+// removeClient deregisters and closes a client connected to the server.
+func (w *Websocket) removeClient(client *wsServerClient) {
+	w.clientsMut.Lock()
+	delete(w.clients, client)
+	w.clientsMut.Unlock()
+	client.close()
+}
+
+// errEvicted stops sending the parts of a message to an evicted client.
+var errEvicted = errors.New("client evicted")
+
+// broadcast sends a message to all connected clients, evicting any client
+// whose send buffer is full.
+func (w *Websocket) broadcast(msg types.Message) error {
+	var evict []*wsServerClient
+
+	w.clientsMut.RLock()
+	if len(w.clients) == 0 {
+		w.clientsMut.RUnlock()
+		return types.ErrNotConnected
+	}
+	for client := range w.clients {
+		msg.Iter(func(i int, p types.Part) error {
+			select {
+			case client.sendChan <- p.Get():
+				return nil
+			default:
+			}
+			evict = append(evict, client)
+			return errEvicted
+		})
+	}
+	w.clientsMut.RUnlock()
+
+	for _, client := range evict {
+		w.log.Warnln("Evicting slow websocket client")
+		w.mClientEvicted.Incr(1)
+		w.removeClient(client)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Connect establishes a connection to an Websocket server, or when running in
+// server mode starts the server if it has its own address.
 func (w *Websocket) Connect() error {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 
+	if w.conf.Mode == "server" {
+		if w.serverClosed {
+			return types.ErrTypeClosed
+		}
+		if w.server != nil && !w.serverStarted {
+			ln, err := net.Listen("tcp", w.server.Addr)
+			if err != nil {
+				return err
+			}
+			w.serverStarted = true
+			go func(server *http.Server) {
+				if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+					w.log.Errorf("Server error: %v\n", err)
+				}
+			}(w.server)
+			w.log.Infof("Broadcasting websocket messages at: ws://%s%s\n", w.server.Addr, w.conf.Server.Path)
+		}
+		return nil
+	}
+
 	if w.client != nil {
 		return nil
 	}
@@ -133,6 +337,10 @@ func (w *Websocket) Connect() error {
 
 // Write attempts to write a message by pushing it to an Websocket broker.
 func (w *Websocket) Write(msg types.Message) error {
+	if w.conf.Mode == "server" {
+		return w.broadcast(msg)
+	}
+
 	client := w.getWS()
 	if client == nil {
 		return types.ErrNotConnected
@@ -160,7 +368,25 @@ func (w *Websocket) CloseAsync() {
 		w.client.Close()
 		w.client = nil
 	}
+	var server *http.Server
+	if w.serverStarted {
+		server = w.server
+	}
+	w.serverClosed = true
 	w.lock.Unlock()
+
+	if server != nil {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		server.Shutdown(ctx)
+		done()
+	}
+
+	w.clientsMut.Lock()
+	for client := range w.clients {
+		delete(w.clients, client)
+		client.close()
+	}
+	w.clientsMut.Unlock()
 }
 
 // WaitForClose blocks until the Websocket output has closed down.
@@ -21,6 +21,7 @@
 package writer
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -32,6 +33,7 @@ import (
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
 	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
 	"github.com/gorilla/websocket"
 )
 
@@ -71,7 +73,7 @@ func TestWebsocketBasic(t *testing.T) {
 		conf.URL = wsURL.String()
 	}
 
-	m, err := NewWebsocket(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
+	m, err := NewWebsocket(conf, types.NoopMgr(), log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -114,7 +116,7 @@ func TestWebsocketClose(t *testing.T) {
 		conf.URL = wsURL.String()
 	}
 
-	m, err := NewWebsocket(conf, log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
+	m, err := NewWebsocket(conf, types.NoopMgr(), log.New(os.Stdout, log.Config{LogLevel: "NONE"}), metrics.DudType{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -136,3 +138,197 @@ func TestWebsocketClose(t *testing.T) {
 	wg.Wait()
 	close(closeChan)
 }
+
+type wsEndpointMgr struct {
+	types.DudMgr
+	handlers map[string]http.HandlerFunc
+}
+
+func (m *wsEndpointMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	m.handlers[path] = h
+}
+
+func dialTestWS(t *testing.T, serverURL string) *websocket.Conn {
+	t.Helper()
+	purl, err := url.Parse(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	purl.Scheme = "ws"
+	conn, _, err := websocket.DefaultDialer.Dial(purl.String(), http.Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func waitForWSClients(t *testing.T, w *Websocket, n int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		w.clientsMut.RLock()
+		l := len(w.clients)
+		w.clientsMut.RUnlock()
+		if l == n {
+			return
+		}
+		<-time.After(time.Millisecond * 10)
+	}
+	t.Fatalf("Timed out waiting for %v clients", n)
+}
+
+func TestWebsocketServerBroadcast(t *testing.T) {
+	mgr := &wsEndpointMgr{handlers: map[string]http.HandlerFunc{}}
+
+	conf := NewWebsocketConfig()
+	conf.Mode = "server"
+
+	w, err := NewWebsocket(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	handler, exists := mgr.handlers["/get/ws"]
+	if !exists {
+		t.Fatal("Endpoint was not registered")
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(message.New([][]byte{[]byte("nope")})); err != types.ErrNotConnected {
+		t.Errorf("Expected not connected error without clients: %v", err)
+	}
+
+	clientA := dialTestWS(t, server.URL)
+	defer clientA.Close()
+	clientB := dialTestWS(t, server.URL)
+	defer clientB.Close()
+	waitForWSClients(t, w, 2)
+
+	if err = w.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*websocket.Conn{clientA, clientB} {
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		for _, exp := range []string{"foo", "bar"} {
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act := string(data); exp != act {
+				t.Errorf("Wrong message: %v != %v", act, exp)
+			}
+		}
+	}
+
+	clientA.Close()
+	waitForWSClients(t, w, 1)
+}
+
+func TestWebsocketServerEviction(t *testing.T) {
+	conf := NewWebsocketConfig()
+	conf.Mode = "server"
+	conf.Server.ClientBufferSize = 1
+
+	w, err := NewWebsocket(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.CloseAsync()
+
+	connChan := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(rw, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		connChan <- conn
+	}))
+	defer server.Close()
+
+	clientConn := dialTestWS(t, server.URL)
+	defer clientConn.Close()
+
+	// A client that is never drained by a write loop.
+	slow := &wsServerClient{
+		conn:      <-connChan,
+		sendChan:  make(chan []byte, conf.Server.ClientBufferSize),
+		closeChan: make(chan struct{}),
+	}
+	w.clients[slow] = struct{}{}
+
+	if err = w.Write(message.New([][]byte{[]byte("foo"), []byte("bar")})); err != nil {
+		t.Fatal(err)
+	}
+	waitForWSClients(t, w, 0)
+
+	select {
+	case <-slow.closeChan:
+	default:
+		t.Error("Expected evicted client to be closed")
+	}
+}
+
+func TestWebsocketServerAddress(t *testing.T) {
+	conf := NewWebsocketConfig()
+	conf.Mode = "server"
+	conf.Server.Address = "localhost:1247"
+
+	w, err := NewWebsocket(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	client := dialTestWS(t, fmt.Sprintf("http://%v/get/ws", conf.Server.Address))
+	defer client.Close()
+	waitForWSClients(t, w, 1)
+
+	if err = w.Write(message.New([][]byte{[]byte("foo")})); err != nil {
+		t.Fatal(err)
+	}
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, data, err := client.ReadMessage(); err != nil {
+		t.Fatal(err)
+	} else if exp, act := "foo", string(data); exp != act {
+		t.Errorf("Wrong message: %v != %v", act, exp)
+	}
+
+	w.CloseAsync()
+	if err = w.Connect(); err != types.ErrTypeClosed {
+		t.Errorf("Expected closed error: %v", err)
+	}
+}
+
+func TestWebsocketBadConfig(t *testing.T) {
+	conf := NewWebsocketConfig()
+	conf.Mode = "nope"
+	if _, err := NewWebsocket(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad mode")
+	}
+
+	conf = NewWebsocketConfig()
+	conf.Mode = "server"
+	conf.Server.ClientBufferSize = 0
+	if _, err := NewWebsocket(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad buffer size")
+	}
+
+	conf = NewWebsocketConfig()
+	conf.Mode = "server"
+	conf.Server.WriteTimeout = "nope"
+	if _, err := NewWebsocket(conf, types.NoopMgr(), log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad write timeout")
+	}
+}
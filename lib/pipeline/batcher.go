@@ -0,0 +1,212 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// Batcher is a pipeline that combines messages into batches using a batch
+// processor. As with the processor messages added to a pending batch are
+// responded to with a NoAck, and a batch is propagated along with the response
+// channel of the message that completed it. However, once the period of a
+// pending batch has passed it is also flushed without waiting for another
+// message, in which case the batch is retried until it is successfully sent
+// and its messages are acknowledged along with the next completed batch.
+type Batcher struct {
+	running int32
+
+	log   log.Modular
+	stats metrics.Type
+
+	batcher *processor.Batch
+
+	messagesOut chan types.Transaction
+	messagesIn  <-chan types.Transaction
+
+	mSndSucc metrics.StatCounter
+	mSndErr  metrics.StatCounter
+
+	closeChan chan struct{}
+	closed    chan struct{}
+}
+
+// NewBatcher returns a new batching pipeline.
+func NewBatcher(
+	log log.Modular,
+	stats metrics.Type,
+	batcher *processor.Batch,
+) *Batcher {
+	return &Batcher{
+		running:     1,
+		log:         log,
+		stats:       stats,
+		batcher:     batcher,
+		messagesOut: make(chan types.Transaction),
+		mSndSucc:    stats.GetCounter("batcher.period.send.success"),
+		mSndErr:     stats.GetCounter("batcher.period.send.error"),
+		closeChan:   make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// loop is the processing loop of this pipeline.
+func (b *Batcher) loop() {
+	defer func() {
+		b.batcher.CloseAsync()
+
+		close(b.messagesOut)
+		close(b.closed)
+	}()
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for atomic.LoadInt32(&b.running) == 1 {
+		var timerChan <-chan time.Time
+		if until := b.batcher.UntilNext(); until >= 0 {
+			timer.Reset(until)
+			timerChan = timer.C
+		}
+
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-b.messagesIn:
+			if !open {
+				return
+			}
+		case <-timerChan:
+			if batch := b.batcher.FlushExpired(); batch != nil {
+				if !b.sendExpired(batch) {
+					return
+				}
+			}
+			continue
+		case <-b.closeChan:
+			return
+		}
+
+		timer.Stop()
+
+		msgs, res := b.batcher.ProcessMessage(tran.Payload)
+		if len(msgs) == 0 {
+			select {
+			case tran.ResponseChan <- res:
+			case <-b.closeChan:
+				return
+			}
+			continue
+		}
+
+		select {
+		case b.messagesOut <- types.NewTransaction(msgs[0], tran.ResponseChan):
+		case <-b.closeChan:
+			return
+		}
+	}
+}
+
+// sendExpired attempts to send a batch that was flushed after its period
+// expired. Since there is no transaction waiting on the result the send is
+// retried until success. Returns false if the pipeline was closed first.
+func (b *Batcher) sendExpired(batch types.Message) bool {
+	throt := throttle.New(throttle.OptCloseChan(b.closeChan))
+	resChan := make(chan types.Response)
+
+	for {
+		select {
+		case b.messagesOut <- types.NewTransaction(batch, resChan):
+		case <-b.closeChan:
+			return false
+		}
+
+		var res types.Response
+		var open bool
+		select {
+		case res, open = <-resChan:
+			if !open {
+				return false
+			}
+		case <-b.closeChan:
+			return false
+		}
+
+		if res.Error() == nil {
+			b.mSndSucc.Incr(1)
+			return true
+		}
+		b.mSndErr.Incr(1)
+		b.log.Errorf("Failed to send expired batch: %v\n", res.Error())
+		if !throt.Retry() {
+			return false
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a messages channel for the pipeline to read.
+func (b *Batcher) Consume(msgs <-chan types.Transaction) error {
+	if b.messagesIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	b.messagesIn = msgs
+	go b.loop()
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming messages from this
+// pipeline.
+func (b *Batcher) TransactionChan() <-chan types.Transaction {
+	return b.messagesOut
+}
+
+// CloseAsync shuts down the pipeline and stops processing messages.
+func (b *Batcher) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&b.running, 1, 0) {
+		close(b.closeChan)
+	}
+}
+
+// WaitForClose blocks until the pipeline has closed down.
+func (b *Batcher) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-b.closed:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pipeline
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func newTestBatcher(t *testing.T, count int, period string) *Batcher {
+	t.Helper()
+
+	conf := processor.NewConfig()
+	conf.Batch.Count = count
+	conf.Batch.Period = period
+
+	proc, err := processor.NewBatch(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBatcher(log.Noop(), metrics.Noop(), proc.(*processor.Batch))
+}
+
+func TestBatcherCount(t *testing.T) {
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	b := newTestBatcher(t, 2, "")
+	if err := b.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case res := <-resChan:
+		if !res.SkipAck() {
+			t.Error("Expected skip ack")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("bar")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	var tran types.Transaction
+	select {
+	case tran = <-b.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	exp := [][]byte{[]byte("foo"), []byte("bar")}
+	if act := message.GetAllBytes(tran.Payload); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong batch: %s != %s", act, exp)
+	}
+
+	go func() {
+		tran.ResponseChan <- response.NewAck()
+	}()
+	select {
+	case res := <-resChan:
+		if res.Error() != nil || res.SkipAck() {
+			t.Errorf("Unexpected response: %v", res)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	b.CloseAsync()
+	if err := b.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBatcherPeriod(t *testing.T) {
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	b := newTestBatcher(t, 10, "50ms")
+	if err := b.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case res := <-resChan:
+		if !res.SkipAck() {
+			t.Error("Expected skip ack")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	exp := [][]byte{[]byte("foo")}
+	for _, res := range []types.Response{
+		response.NewError(errors.New("nope")),
+		response.NewAck(),
+	} {
+		var tran types.Transaction
+		select {
+		case tran = <-b.TransactionChan():
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+		if act := message.GetAllBytes(tran.Payload); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong batch: %s != %s", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- res:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	b.CloseAsync()
+	if err := b.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -221,16 +221,7 @@ func (c *Batch) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 
 	// If we have reached our target count of parts in the buffer.
 	if batch {
-		newMsg := message.New(nil)
-		newMsg.Append(c.parts...)
-
-		c.parts = nil
-		c.sizeTally = 0
-		c.lastBatch = time.Now()
-
-		c.mSent.Incr(int64(newMsg.Len()))
-		c.mBatchSent.Incr(1)
-		msgs := [1]types.Message{newMsg}
+		msgs := [1]types.Message{c.flush()}
 		return msgs[:], nil
 	}
 
@@ -239,6 +230,45 @@ func (c *Batch) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 	return nil, response.NewUnack()
 }
 
+// UntilNext returns the duration remaining until the pending batch should be
+// flushed based on the period field. A negative duration is returned when
+// there are no pending messages or no period is configured.
+func (c *Batch) UntilNext() time.Duration {
+	if c.period <= 0 || len(c.parts) == 0 {
+		return -1
+	}
+	if until := c.period - time.Since(c.lastBatch); until > 0 {
+		return until
+	}
+	return 0
+}
+
+// FlushExpired returns the pending messages as a batch if the period has passed
+// since the last batch, otherwise nil is returned. This allows a pending batch
+// to be flushed without needing a new message to be added.
+func (c *Batch) FlushExpired() types.Message {
+	if c.UntilNext() != 0 {
+		return nil
+	}
+	c.mPeriodBatch.Incr(1)
+	c.log.Traceln("Batching based on period")
+	return c.flush()
+}
+
+// flush combines and resets the pending message parts into a single batch.
+func (c *Batch) flush() types.Message {
+	newMsg := message.New(nil)
+	newMsg.Append(c.parts...)
+
+	c.parts = nil
+	c.sizeTally = 0
+	c.lastBatch = time.Now()
+
+	c.mSent.Incr(int64(newMsg.Len()))
+	c.mBatchSent.Incr(1)
+	return newMsg
+}
+
 // CloseAsync shuts down the processor and stops processing requests.
 func (c *Batch) CloseAsync() {
 }
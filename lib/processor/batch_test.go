@@ -24,6 +24,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/lib/log"
 	"github.com/Jeffail/benthos/lib/message"
@@ -283,3 +284,51 @@ func TestBatchCondition(t *testing.T) {
 		t.Errorf("Wrong batch contents: %s != %s", act, exp)
 	}
 }
+
+func TestBatchFlushExpired(t *testing.T) {
+	conf := NewConfig()
+	conf.Batch.Count = 10
+	conf.Batch.Period = "10ms"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	proc, err := NewBatch(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batcher := proc.(*Batch)
+
+	if until := batcher.UntilNext(); until >= 0 {
+		t.Errorf("Expected negative duration without pending messages: %v", until)
+	}
+	if msg := batcher.FlushExpired(); msg != nil {
+		t.Error("Expected nil flush without pending messages")
+	}
+
+	exp := [][]byte{[]byte("foo")}
+	if msgs, res := batcher.ProcessMessage(message.New(exp)); len(msgs) != 0 {
+		t.Error("Expected no batch")
+	} else if !res.SkipAck() {
+		t.Error("Expected skip ack")
+	}
+	if until := batcher.UntilNext(); until <= 0 || until > time.Millisecond*10 {
+		t.Errorf("Unexpected duration until next batch: %v", until)
+	}
+	if msg := batcher.FlushExpired(); msg != nil {
+		t.Error("Expected nil flush before period")
+	}
+
+	<-time.After(time.Millisecond * 20)
+	if until := batcher.UntilNext(); until != 0 {
+		t.Errorf("Expected zero duration after period: %v", until)
+	}
+	msg := batcher.FlushExpired()
+	if msg == nil {
+		t.Fatal("Expected flushed batch")
+	}
+	if act := message.GetAllBytes(msg); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %s != %s", act, exp)
+	}
+	if until := batcher.UntilNext(); until >= 0 {
+		t.Errorf("Expected negative duration after flush: %v", until)
+	}
+}
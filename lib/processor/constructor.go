@@ -55,142 +55,169 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each processor type.
 const (
-	TypeArchive      = "archive"
-	TypeAWK          = "awk"
-	TypeBatch        = "batch"
-	TypeBoundsCheck  = "bounds_check"
-	TypeCatch        = "catch"
-	TypeCompress     = "compress"
-	TypeConditional  = "conditional"
-	TypeDecode       = "decode"
-	TypeDecompress   = "decompress"
-	TypeDedupe       = "dedupe"
-	TypeEncode       = "encode"
-	TypeFilter       = "filter"
-	TypeFilterParts  = "filter_parts"
-	TypeGrok         = "grok"
-	TypeGroupBy      = "group_by"
-	TypeGroupByValue = "group_by_value"
-	TypeHash         = "hash"
-	TypeHashSample   = "hash_sample"
-	TypeHTTP         = "http"
-	TypeInsertPart   = "insert_part"
-	TypeJMESPath     = "jmespath"
-	TypeJSON         = "json"
-	TypeLambda       = "lambda"
-	TypeLog          = "log"
-	TypeMergeJSON    = "merge_json"
-	TypeMetadata     = "metadata"
-	TypeMetric       = "metric"
-	TypeNoop         = "noop"
-	TypeProcessBatch = "process_batch"
-	TypeProcessDAG   = "process_dag"
-	TypeProcessField = "process_field"
-	TypeProcessMap   = "process_map"
-	TypeSample       = "sample"
-	TypeSelectParts  = "select_parts"
-	TypeSleep        = "sleep"
-	TypeSplit        = "split"
-	TypeSubprocess   = "subprocess"
-	TypeText         = "text"
-	TypeTry          = "try"
-	TypeThrottle     = "throttle"
-	TypeUnarchive    = "unarchive"
+	TypeArchive              = "archive"
+	TypeAWK                  = "awk"
+	TypeBatch                = "batch"
+	TypeBoundsCheck          = "bounds_check"
+	TypeCatch                = "catch"
+	TypeCompress             = "compress"
+	TypeConditional          = "conditional"
+	TypeCSV                  = "csv"
+	TypeDecode               = "decode"
+	TypeDecompress           = "decompress"
+	TypeDedupe               = "dedupe"
+	TypeEncode               = "encode"
+	TypeFilter               = "filter"
+	TypeFilterParts          = "filter_parts"
+	TypeGrok                 = "grok"
+	TypeGroupBy              = "group_by"
+	TypeGroupByValue         = "group_by_value"
+	TypeHash                 = "hash"
+	TypeHashSample           = "hash_sample"
+	TypeHTTP                 = "http"
+	TypeInsertPart           = "insert_part"
+	TypeJMESPath             = "jmespath"
+	TypeJQ                   = "jq"
+	TypeJSON                 = "json"
+	TypeJSONSchema           = "json_schema"
+	TypeLambda               = "lambda"
+	TypeLog                  = "log"
+	TypeMapping              = "mapping"
+	TypeMergeJSON            = "merge_json"
+	TypeMetadata             = "metadata"
+	TypeMetric               = "metric"
+	TypeMsgPack              = "msgpack"
+	TypeNoop                 = "noop"
+	TypeProcessBatch         = "process_batch"
+	TypeProcessDAG           = "process_dag"
+	TypeProcessField         = "process_field"
+	TypeProcessMap           = "process_map"
+	TypeProtobuf             = "protobuf"
+	TypeSample               = "sample"
+	TypeSchemaRegistryDecode = "schema_registry_decode"
+	TypeSchemaRegistryEncode = "schema_registry_encode"
+	TypeSelectParts          = "select_parts"
+	TypeSleep                = "sleep"
+	TypeSplit                = "split"
+	TypeSubprocess           = "subprocess"
+	TypeText                 = "text"
+	TypeTry                  = "try"
+	TypeThrottle             = "throttle"
+	TypeUnarchive            = "unarchive"
+	TypeXML                  = "xml"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all processor types.
 type Config struct {
-	Type         string             `json:"type" yaml:"type"`
-	Archive      ArchiveConfig      `json:"archive" yaml:"archive"`
-	AWK          AWKConfig          `json:"awk" yaml:"awk"`
-	Batch        BatchConfig        `json:"batch" yaml:"batch"`
-	BoundsCheck  BoundsCheckConfig  `json:"bounds_check" yaml:"bounds_check"`
-	Catch        CatchConfig        `json:"catch" yaml:"catch"`
-	Compress     CompressConfig     `json:"compress" yaml:"compress"`
-	Conditional  ConditionalConfig  `json:"conditional" yaml:"conditional"`
-	Decode       DecodeConfig       `json:"decode" yaml:"decode"`
-	Decompress   DecompressConfig   `json:"decompress" yaml:"decompress"`
-	Dedupe       DedupeConfig       `json:"dedupe" yaml:"dedupe"`
-	Encode       EncodeConfig       `json:"encode" yaml:"encode"`
-	Filter       FilterConfig       `json:"filter" yaml:"filter"`
-	FilterParts  FilterPartsConfig  `json:"filter_parts" yaml:"filter_parts"`
-	Grok         GrokConfig         `json:"grok" yaml:"grok"`
-	GroupBy      GroupByConfig      `json:"group_by" yaml:"group_by"`
-	GroupByValue GroupByValueConfig `json:"group_by_value" yaml:"group_by_value"`
-	Hash         HashConfig         `json:"hash" yaml:"hash"`
-	HashSample   HashSampleConfig   `json:"hash_sample" yaml:"hash_sample"`
-	HTTP         HTTPConfig         `json:"http" yaml:"http"`
-	InsertPart   InsertPartConfig   `json:"insert_part" yaml:"insert_part"`
-	JMESPath     JMESPathConfig     `json:"jmespath" yaml:"jmespath"`
-	JSON         JSONConfig         `json:"json" yaml:"json"`
-	Lambda       LambdaConfig       `json:"lambda" yaml:"lambda"`
-	Log          LogConfig          `json:"log" yaml:"log"`
-	MergeJSON    MergeJSONConfig    `json:"merge_json" yaml:"merge_json"`
-	Metadata     MetadataConfig     `json:"metadata" yaml:"metadata"`
-	Metric       MetricConfig       `json:"metric" yaml:"metric"`
-	Plugin       interface{}        `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	ProcessBatch ProcessBatchConfig `json:"process_batch" yaml:"process_batch"`
-	ProcessDAG   ProcessDAGConfig   `json:"process_dag" yaml:"process_dag"`
-	ProcessField ProcessFieldConfig `json:"process_field" yaml:"process_field"`
-	ProcessMap   ProcessMapConfig   `json:"process_map" yaml:"process_map"`
-	Sample       SampleConfig       `json:"sample" yaml:"sample"`
-	SelectParts  SelectPartsConfig  `json:"select_parts" yaml:"select_parts"`
-	Sleep        SleepConfig        `json:"sleep" yaml:"sleep"`
-	Split        SplitConfig        `json:"split" yaml:"split"`
-	Subprocess   SubprocessConfig   `json:"subprocess" yaml:"subprocess"`
-	Text         TextConfig         `json:"text" yaml:"text"`
-	Try          TryConfig          `json:"try" yaml:"try"`
-	Throttle     ThrottleConfig     `json:"throttle" yaml:"throttle"`
-	Unarchive    UnarchiveConfig    `json:"unarchive" yaml:"unarchive"`
+	Type                 string                     `json:"type" yaml:"type"`
+	Archive              ArchiveConfig              `json:"archive" yaml:"archive"`
+	AWK                  AWKConfig                  `json:"awk" yaml:"awk"`
+	Batch                BatchConfig                `json:"batch" yaml:"batch"`
+	BoundsCheck          BoundsCheckConfig          `json:"bounds_check" yaml:"bounds_check"`
+	Catch                CatchConfig                `json:"catch" yaml:"catch"`
+	Compress             CompressConfig             `json:"compress" yaml:"compress"`
+	Conditional          ConditionalConfig          `json:"conditional" yaml:"conditional"`
+	CSV                  CSVConfig                  `json:"csv" yaml:"csv"`
+	Decode               DecodeConfig               `json:"decode" yaml:"decode"`
+	Decompress           DecompressConfig           `json:"decompress" yaml:"decompress"`
+	Dedupe               DedupeConfig               `json:"dedupe" yaml:"dedupe"`
+	Encode               EncodeConfig               `json:"encode" yaml:"encode"`
+	Filter               FilterConfig               `json:"filter" yaml:"filter"`
+	FilterParts          FilterPartsConfig          `json:"filter_parts" yaml:"filter_parts"`
+	Grok                 GrokConfig                 `json:"grok" yaml:"grok"`
+	GroupBy              GroupByConfig              `json:"group_by" yaml:"group_by"`
+	GroupByValue         GroupByValueConfig         `json:"group_by_value" yaml:"group_by_value"`
+	Hash                 HashConfig                 `json:"hash" yaml:"hash"`
+	HashSample           HashSampleConfig           `json:"hash_sample" yaml:"hash_sample"`
+	HTTP                 HTTPConfig                 `json:"http" yaml:"http"`
+	InsertPart           InsertPartConfig           `json:"insert_part" yaml:"insert_part"`
+	JMESPath             JMESPathConfig             `json:"jmespath" yaml:"jmespath"`
+	JQ                   JQConfig                   `json:"jq" yaml:"jq"`
+	JSON                 JSONConfig                 `json:"json" yaml:"json"`
+	JSONSchema           JSONSchemaConfig           `json:"json_schema" yaml:"json_schema"`
+	Lambda               LambdaConfig               `json:"lambda" yaml:"lambda"`
+	Log                  LogConfig                  `json:"log" yaml:"log"`
+	Mapping              MappingConfig              `json:"mapping" yaml:"mapping"`
+	MergeJSON            MergeJSONConfig            `json:"merge_json" yaml:"merge_json"`
+	Metadata             MetadataConfig             `json:"metadata" yaml:"metadata"`
+	Metric               MetricConfig               `json:"metric" yaml:"metric"`
+	Plugin               interface{}                `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	ProcessBatch         ProcessBatchConfig         `json:"process_batch" yaml:"process_batch"`
+	ProcessDAG           ProcessDAGConfig           `json:"process_dag" yaml:"process_dag"`
+	ProcessField         ProcessFieldConfig         `json:"process_field" yaml:"process_field"`
+	ProcessMap           ProcessMapConfig           `json:"process_map" yaml:"process_map"`
+	Protobuf             ProtobufConfig             `json:"protobuf" yaml:"protobuf"`
+	Sample               SampleConfig               `json:"sample" yaml:"sample"`
+	SchemaRegistryDecode SchemaRegistryDecodeConfig `json:"schema_registry_decode" yaml:"schema_registry_decode"`
+	SchemaRegistryEncode SchemaRegistryEncodeConfig `json:"schema_registry_encode" yaml:"schema_registry_encode"`
+	SelectParts          SelectPartsConfig          `json:"select_parts" yaml:"select_parts"`
+	Sleep                SleepConfig                `json:"sleep" yaml:"sleep"`
+	Split                SplitConfig                `json:"split" yaml:"split"`
+	Subprocess           SubprocessConfig           `json:"subprocess" yaml:"subprocess"`
+	Text                 TextConfig                 `json:"text" yaml:"text"`
+	Try                  TryConfig                  `json:"try" yaml:"try"`
+	Throttle             ThrottleConfig             `json:"throttle" yaml:"throttle"`
+	Unarchive            UnarchiveConfig            `json:"unarchive" yaml:"unarchive"`
+	MsgPack              MsgPackConfig              `json:"msgpack" yaml:"msgpack"`
+	XML                  XMLConfig                  `json:"xml" yaml:"xml"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:         "bounds_check",
-		Archive:      NewArchiveConfig(),
-		AWK:          NewAWKConfig(),
-		Batch:        NewBatchConfig(),
-		BoundsCheck:  NewBoundsCheckConfig(),
-		Catch:        NewCatchConfig(),
-		Compress:     NewCompressConfig(),
-		Conditional:  NewConditionalConfig(),
-		Decode:       NewDecodeConfig(),
-		Decompress:   NewDecompressConfig(),
-		Dedupe:       NewDedupeConfig(),
-		Encode:       NewEncodeConfig(),
-		Filter:       NewFilterConfig(),
-		FilterParts:  NewFilterPartsConfig(),
-		Grok:         NewGrokConfig(),
-		GroupBy:      NewGroupByConfig(),
-		GroupByValue: NewGroupByValueConfig(),
-		Hash:         NewHashConfig(),
-		HashSample:   NewHashSampleConfig(),
-		HTTP:         NewHTTPConfig(),
-		InsertPart:   NewInsertPartConfig(),
-		JMESPath:     NewJMESPathConfig(),
-		JSON:         NewJSONConfig(),
-		Lambda:       NewLambdaConfig(),
-		Log:          NewLogConfig(),
-		MergeJSON:    NewMergeJSONConfig(),
-		Metadata:     NewMetadataConfig(),
-		Metric:       NewMetricConfig(),
-		Plugin:       nil,
-		ProcessBatch: NewProcessBatchConfig(),
-		ProcessDAG:   NewProcessDAGConfig(),
-		ProcessField: NewProcessFieldConfig(),
-		ProcessMap:   NewProcessMapConfig(),
-		Sample:       NewSampleConfig(),
-		SelectParts:  NewSelectPartsConfig(),
-		Sleep:        NewSleepConfig(),
-		Split:        NewSplitConfig(),
-		Subprocess:   NewSubprocessConfig(),
-		Text:         NewTextConfig(),
-		Try:          NewTryConfig(),
-		Throttle:     NewThrottleConfig(),
-		Unarchive:    NewUnarchiveConfig(),
+		Type:                 "bounds_check",
+		Archive:              NewArchiveConfig(),
+		AWK:                  NewAWKConfig(),
+		Batch:                NewBatchConfig(),
+		BoundsCheck:          NewBoundsCheckConfig(),
+		Catch:                NewCatchConfig(),
+		Compress:             NewCompressConfig(),
+		Conditional:          NewConditionalConfig(),
+		CSV:                  NewCSVConfig(),
+		Decode:               NewDecodeConfig(),
+		Decompress:           NewDecompressConfig(),
+		Dedupe:               NewDedupeConfig(),
+		Encode:               NewEncodeConfig(),
+		Filter:               NewFilterConfig(),
+		FilterParts:          NewFilterPartsConfig(),
+		Grok:                 NewGrokConfig(),
+		GroupBy:              NewGroupByConfig(),
+		GroupByValue:         NewGroupByValueConfig(),
+		Hash:                 NewHashConfig(),
+		HashSample:           NewHashSampleConfig(),
+		HTTP:                 NewHTTPConfig(),
+		InsertPart:           NewInsertPartConfig(),
+		JMESPath:             NewJMESPathConfig(),
+		JQ:                   NewJQConfig(),
+		JSON:                 NewJSONConfig(),
+		JSONSchema:           NewJSONSchemaConfig(),
+		Lambda:               NewLambdaConfig(),
+		Log:                  NewLogConfig(),
+		Mapping:              NewMappingConfig(),
+		MergeJSON:            NewMergeJSONConfig(),
+		Metadata:             NewMetadataConfig(),
+		Metric:               NewMetricConfig(),
+		Plugin:               nil,
+		ProcessBatch:         NewProcessBatchConfig(),
+		ProcessDAG:           NewProcessDAGConfig(),
+		ProcessField:         NewProcessFieldConfig(),
+		ProcessMap:           NewProcessMapConfig(),
+		Protobuf:             NewProtobufConfig(),
+		Sample:               NewSampleConfig(),
+		SchemaRegistryDecode: NewSchemaRegistryDecodeConfig(),
+		SchemaRegistryEncode: NewSchemaRegistryEncodeConfig(),
+		SelectParts:          NewSelectPartsConfig(),
+		Sleep:                NewSleepConfig(),
+		Split:                NewSplitConfig(),
+		Subprocess:           NewSubprocessConfig(),
+		Text:                 NewTextConfig(),
+		Try:                  NewTryConfig(),
+		Throttle:             NewThrottleConfig(),
+		Unarchive:            NewUnarchiveConfig(),
+		MsgPack:              NewMsgPackConfig(),
+		XML:                  NewXMLConfig(),
 	}
 }
 
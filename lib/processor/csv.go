@@ -0,0 +1,374 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCSV] = TypeSpec{
+		constructor: NewCSV,
+		description: `
+Converts message parts between delimited text and JSON. The operator
+` + "`to_json`" + ` parses delimited text into JSON objects, and
+` + "`from_json`" + ` serialises JSON objects into delimited rows.
+
+### ` + "`to_json`" + `
+
+Each row of a part becomes a new message part containing a JSON object, where
+the field names are taken from ` + "`headers`" + ` when set, or otherwise from
+the first row of the part when ` + "`parse_header_row`" + ` is ` + "`true`" + `.
+When neither apply each row becomes a JSON array of strings. All values are
+strings.
+
+For example, with the default config the part:
+
+` + "```" + `
+id,name
+1,foo
+2,bar
+` + "```" + `
+
+Is replaced with the two parts:
+
+` + "``` json" + `
+{"id":"1","name":"foo"}
+{"id":"2","name":"bar"}
+` + "```" + `
+
+### ` + "`from_json`" + `
+
+Each part containing a JSON object, or an array of JSON objects, is replaced
+with a row for each object. The columns are taken from ` + "`headers`" + `
+when set, or otherwise are the sorted field names of the objects. A header row
+is written before the rows when ` + "`write_header_row`" + ` is ` + "`true`" + `.
+String values are written as they are, fields that are missing or ` + "`null`" + `
+are written as empty values, and other values are written as JSON.
+
+In order to write a batch of documents to a single CSV file, for example with
+an ` + "`s3`" + ` or ` + "`file`" + ` output, the rows can be combined after
+inserting a header with an ` + "[`insert_part`](#insert_part)" + ` processor:
+
+` + "``` yaml" + `
+- csv:
+    operator: from_json
+    headers: [ id, name ]
+    write_header_row: false
+- insert_part:
+    index: 0
+    content: "id,name"
+- archive:
+    format: lines
+` + "```" + `
+
+Parts that fail to convert are left unchanged and flagged as having failed, and
+can therefore be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CSVConfig contains configuration fields for the CSV processor.
+type CSVConfig struct {
+	Parts          []int    `json:"parts" yaml:"parts"`
+	Operator       string   `json:"operator" yaml:"operator"`
+	Delim          string   `json:"delimiter" yaml:"delimiter"`
+	Headers        []string `json:"headers" yaml:"headers"`
+	ParseHeaderRow bool     `json:"parse_header_row" yaml:"parse_header_row"`
+	WriteHeaderRow bool     `json:"write_header_row" yaml:"write_header_row"`
+	LazyQuotes     bool     `json:"lazy_quotes" yaml:"lazy_quotes"`
+}
+
+// NewCSVConfig returns a CSVConfig with default values.
+func NewCSVConfig() CSVConfig {
+	return CSVConfig{
+		Parts:          []int{},
+		Operator:       "to_json",
+		Delim:          ",",
+		Headers:        []string{},
+		ParseHeaderRow: true,
+		WriteHeaderRow: true,
+		LazyQuotes:     false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CSV is a processor that converts message parts between delimited text and
+// JSON.
+type CSV struct {
+	conf     CSVConfig
+	delim    rune
+	operator func(part types.Part) ([]types.Part, error)
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewCSV returns a CSV processor.
+func NewCSV(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if utf8.RuneCountInString(conf.CSV.Delim) != 1 {
+		return nil, fmt.Errorf("delimiter must be a single character, got '%v'", conf.CSV.Delim)
+	}
+	delim, _ := utf8.DecodeRuneInString(conf.CSV.Delim)
+	if delim == '"' || delim == '\r' || delim == '\n' || delim == utf8.RuneError {
+		return nil, fmt.Errorf("invalid delimiter: '%v'", conf.CSV.Delim)
+	}
+
+	c := &CSV{
+		conf:  conf.CSV,
+		delim: delim,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	switch conf.CSV.Operator {
+	case "to_json":
+		c.operator = c.toJSON
+	case "from_json":
+		c.operator = c.fromJSON
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.CSV.Operator)
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *CSV) toJSON(part types.Part) ([]types.Part, error) {
+	reader := csv.NewReader(bytes.NewReader(part.Get()))
+	reader.Comma = c.delim
+	reader.LazyQuotes = c.conf.LazyQuotes
+	reader.ReuseRecord = true
+
+	headers := c.conf.Headers
+	if len(headers) > 0 {
+		reader.FieldsPerRecord = len(headers)
+	}
+
+	var newParts []types.Part
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse row: %v", err)
+		}
+
+		if len(headers) == 0 && c.conf.ParseHeaderRow {
+			headers = append([]string{}, record...)
+			continue
+		}
+
+		var doc interface{}
+		if len(headers) > 0 {
+			obj := make(map[string]interface{}, len(headers))
+			for i, h := range headers {
+				obj[h] = record[i]
+			}
+			doc = obj
+		} else {
+			arr := make([]interface{}, len(record))
+			for i, v := range record {
+				arr[i] = v
+			}
+			doc = arr
+		}
+
+		newPart := message.NewPart(nil).SetMetadata(part.Metadata().Copy())
+		if err = newPart.SetJSON(doc); err != nil {
+			return nil, fmt.Errorf("failed to set json: %v", err)
+		}
+		newParts = append(newParts, newPart)
+	}
+	return newParts, nil
+}
+
+func csvValueToString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (c *CSV) fromJSON(part types.Part) ([]types.Part, error) {
+	jsonPart, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse part into json: %v", err)
+	}
+
+	var objs []map[string]interface{}
+	switch t := jsonPart.(type) {
+	case map[string]interface{}:
+		objs = append(objs, t)
+	case []interface{}:
+		for i, e := range t {
+			obj, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("array element %v is not an object", i)
+			}
+			objs = append(objs, obj)
+		}
+	default:
+		return nil, errors.New("document must be an object or an array of objects")
+	}
+
+	headers := c.conf.Headers
+	if len(headers) == 0 {
+		keys := map[string]struct{}{}
+		for _, obj := range objs {
+			for k := range obj {
+				keys[k] = struct{}{}
+			}
+		}
+		for k := range keys {
+			headers = append(headers, k)
+		}
+		sort.Strings(headers)
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Comma = c.delim
+
+	if c.conf.WriteHeaderRow {
+		if err = writer.Write(headers); err != nil {
+			return nil, err
+		}
+	}
+
+	record := make([]string, len(headers))
+	for _, obj := range objs {
+		for i, h := range headers {
+			if record[i], err = csvValueToString(obj[h]); err != nil {
+				return nil, fmt.Errorf("failed to serialise field '%v': %v", h, err)
+			}
+		}
+		if err = writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return nil, err
+	}
+
+	part.Set(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return []types.Part{part}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *CSV) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+	newMsg := message.New(nil)
+
+	lParts := msg.Len()
+	noParts := len(c.conf.Parts) == 0
+	msg.Iter(func(i int, part types.Part) error {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, t := range c.conf.Parts {
+				if t == nI || t == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			newMsg.Append(part.Copy())
+			return nil
+		}
+
+		newParts, err := c.operator(part.Copy())
+		if err != nil {
+			c.mErr.Incr(1)
+			c.log.Debugf("Failed to convert part: %v\n", err)
+			newMsg.Append(part.Copy())
+			FlagFail(newMsg.Get(-1))
+			return nil
+		}
+		newMsg.Append(newParts...)
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	c.mBatchSent.Incr(1)
+	c.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (c *CSV) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (c *CSV) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
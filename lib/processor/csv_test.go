@@ -0,0 +1,239 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestCSVToJSON(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	type cTest struct {
+		name     string
+		delim    string
+		headers  []string
+		parseHdr bool
+		input    []string
+		output   []string
+	}
+
+	tests := []cTest{
+		{
+			name:     "header row",
+			delim:    ",",
+			parseHdr: true,
+			input:    []string{"id,name\n1,foo\n2,\"bar, baz\"\n"},
+			output: []string{
+				`{"id":"1","name":"foo"}`,
+				`{"id":"2","name":"bar, baz"}`,
+			},
+		},
+		{
+			name:     "configured headers",
+			delim:    "\t",
+			headers:  []string{"a", "b"},
+			parseHdr: true,
+			input:    []string{"1\t2", "3\t4\n5\t6"},
+			output: []string{
+				`{"a":"1","b":"2"}`,
+				`{"a":"3","b":"4"}`,
+				`{"a":"5","b":"6"}`,
+			},
+		},
+		{
+			name:   "no headers",
+			delim:  ";",
+			input:  []string{"1;2;3\n4;5;6"},
+			output: []string{`["1","2","3"]`, `["4","5","6"]`},
+		},
+		{
+			name:     "header only",
+			delim:    ",",
+			parseHdr: true,
+			input:    []string{"id,name", "id,name\n1,foo"},
+			output:   []string{`{"id":"1","name":"foo"}`},
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.CSV.Operator = "to_json"
+		conf.CSV.Delim = test.delim
+		conf.CSV.Headers = test.headers
+		conf.CSV.ParseHeaderRow = test.parseHdr
+
+		proc, err := NewCSV(conf, nil, tLog, tStats)
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		inMsg := message.New(nil)
+		for _, in := range test.input {
+			inMsg.Append(message.NewPart([]byte(in)))
+		}
+		msgs, res := proc.ProcessMessage(inMsg)
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+
+		var act []string
+		for _, b := range message.GetAllBytes(msgs[0]) {
+			act = append(act, string(b))
+		}
+		if !reflect.DeepEqual(test.output, act) {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, test.output)
+		}
+	}
+}
+
+func TestCSVToJSONErrors(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.CSV.Operator = "to_json"
+	conf.CSV.Parts = []int{0}
+
+	proc, err := NewCSV(conf, nil, tLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte("a,b\n1,2,3"),
+		[]byte("not,targeted"),
+	}))
+	if exp, act := 2, msgs[0].Len(); exp != act {
+		t.Fatalf("Wrong count of parts: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected ragged part to fail")
+	}
+	if exp, act := "a,b\n1,2,3", string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Failed part was modified: %v != %v", act, exp)
+	}
+	if exp, act := "not,targeted", string(msgs[0].Get(1).Get()); exp != act {
+		t.Errorf("Untargeted part was modified: %v != %v", act, exp)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("a,b")}))
+	if len(msgs) != 0 || res == nil {
+		t.Error("Expected empty result to be acknowledged")
+	}
+}
+
+func TestCSVFromJSON(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	type cTest struct {
+		name     string
+		headers  []string
+		writeHdr bool
+		input    string
+		output   string
+		failed   bool
+	}
+
+	tests := []cTest{
+		{
+			name:     "object with header",
+			writeHdr: true,
+			input:    `{"name":"foo, bar","id":1}`,
+			output:   "id,name\n1,\"foo, bar\"",
+		},
+		{
+			name:    "array without header",
+			headers: []string{"name", "id", "missing"},
+			input:   `[{"id":1,"name":"foo","ignored":true},{"id":2.5,"name":null,"missing":{"a":"b"}}]`,
+			output:  "foo,1,\n,2.5,\"{\"\"a\"\":\"\"b\"\"}\"",
+		},
+		{
+			name:     "union of fields",
+			writeHdr: true,
+			input:    `[{"a":"1"},{"b":"2"}]`,
+			output:   "a,b\n1,\n,2",
+		},
+		{
+			name:   "not an object",
+			input:  `"foo"`,
+			output: `"foo"`,
+			failed: true,
+		},
+		{
+			name:   "array of non objects",
+			input:  `[{"a":"b"},5]`,
+			output: `[{"a":"b"},5]`,
+			failed: true,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.CSV.Operator = "from_json"
+		conf.CSV.Headers = test.headers
+		conf.CSV.WriteHeaderRow = test.writeHdr
+
+		proc, err := NewCSV(conf, nil, tLog, tStats)
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, exp)
+		}
+		if exp, act := test.failed, HasFailed(msgs[0].Get(0)); exp != act {
+			t.Errorf("Wrong fail flag '%v': %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestCSVBadConfig(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.CSV.Delim = ",,"
+	if _, err := NewCSV(conf, nil, tLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad delimiter")
+	}
+
+	conf.CSV.Delim = "\""
+	if _, err := NewCSV(conf, nil, tLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from quote delimiter")
+	}
+
+	conf.CSV.Delim = ","
+	conf.CSV.Operator = "nope"
+	if _, err := NewCSV(conf, nil, tLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+}
@@ -43,7 +43,22 @@ valid output format.
 
 This processor respects type hints in the grok patterns, therefore with the
 pattern ` + "`%{WORD:first},%{INT:second:int}`" + ` and a payload of ` + "`foo,1`" + `
-the resulting payload would be ` + "`{\"first\":\"foo\",\"second\":1}`" + `.`,
+the resulting payload would be ` + "`{\"first\":\"foo\",\"second\":1}`" + `.
+
+The field ` + "`use_default_patterns`" + ` enables the standard library of patterns
+such as ` + "`WORD`" + `, ` + "`INT`" + ` and ` + "`COMMONAPACHELOG`" + `. Custom
+patterns can be added with the field ` + "`pattern_definitions`" + `, which maps
+pattern names to their definitions, and can be referenced from patterns as well
+as from other definitions:
+
+` + "``` yaml" + `
+grok:
+  patterns:
+    - "%{APP_LOG}"
+  pattern_definitions:
+    LEVEL: "DEBUG|INFO|WARN|ERROR"
+    APP_LOG: "%{TIMESTAMP_ISO8601:timestamp} %{LEVEL:level} %{GREEDYDATA:message}"
+` + "```",
 	}
 }
 
@@ -51,23 +66,25 @@ the resulting payload would be ` + "`{\"first\":\"foo\",\"second\":1}`" + `.`,
 
 // GrokConfig contains configuration fields for the Grok processor.
 type GrokConfig struct {
-	Parts       []int    `json:"parts" yaml:"parts"`
-	Patterns    []string `json:"patterns" yaml:"patterns"`
-	RemoveEmpty bool     `json:"remove_empty_values" yaml:"remove_empty_values"`
-	NamedOnly   bool     `json:"named_captures_only" yaml:"named_captures_only"`
-	UseDefaults bool     `json:"use_default_patterns" yaml:"use_default_patterns"`
-	To          string   `json:"output_format" yaml:"output_format"`
+	Parts              []int             `json:"parts" yaml:"parts"`
+	Patterns           []string          `json:"patterns" yaml:"patterns"`
+	PatternDefinitions map[string]string `json:"pattern_definitions" yaml:"pattern_definitions"`
+	RemoveEmpty        bool              `json:"remove_empty_values" yaml:"remove_empty_values"`
+	NamedOnly          bool              `json:"named_captures_only" yaml:"named_captures_only"`
+	UseDefaults        bool              `json:"use_default_patterns" yaml:"use_default_patterns"`
+	To                 string            `json:"output_format" yaml:"output_format"`
 }
 
 // NewGrokConfig returns a GrokConfig with default values.
 func NewGrokConfig() GrokConfig {
 	return GrokConfig{
-		Parts:       []int{},
-		Patterns:    []string{},
-		RemoveEmpty: true,
-		NamedOnly:   true,
-		UseDefaults: true,
-		To:          "json",
+		Parts:              []int{},
+		Patterns:           []string{},
+		PatternDefinitions: map[string]string{},
+		RemoveEmpty:        true,
+		NamedOnly:          true,
+		UseDefaults:        true,
+		To:                 "json",
 	}
 }
 
@@ -99,6 +116,7 @@ func NewGrok(
 		RemoveEmptyValues:   conf.Grok.RemoveEmpty,
 		NamedCapturesOnly:   conf.Grok.NamedOnly,
 		SkipDefaultPatterns: !conf.Grok.UseDefaults,
+		Patterns:            conf.Grok.PatternDefinitions,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create grok compiler: %v", err)
@@ -73,10 +73,11 @@ func TestGrok(t *testing.T) {
 	tStats := metrics.DudType{}
 
 	type gTest struct {
-		name    string
-		pattern string
-		input   string
-		output  string
+		name        string
+		pattern     string
+		definitions map[string]string
+		input       string
+		output      string
 	}
 
 	tests := []gTest{
@@ -86,12 +87,25 @@ func TestGrok(t *testing.T) {
 			input:   `127.0.0.1 - - [23/Apr/2014:22:58:32 +0200] "GET /index.php HTTP/1.1" 404 207`,
 			output:  `{"auth":"-","bytes":"207","clientip":"127.0.0.1","httpversion":"1.1","ident":"-","request":"/index.php","response":"404","timestamp":"23/Apr/2014:22:58:32 +0200","verb":"GET"}`,
 		},
+		{
+			name:    "Custom pattern definitions",
+			pattern: "%{APP_LOG}",
+			definitions: map[string]string{
+				"LEVEL":   "DEBUG|INFO|WARN|ERROR",
+				"APP_LOG": "%{TIMESTAMP_ISO8601:timestamp} %{LEVEL:level} %{GREEDYDATA:message}",
+			},
+			input:  `2019-01-02T15:04:05Z WARN something happened`,
+			output: `{"level":"WARN","message":"something happened","timestamp":"2019-01-02T15:04:05Z"}`,
+		},
 	}
 
 	for _, test := range tests {
 		conf := NewConfig()
 		conf.Grok.Parts = []int{0}
 		conf.Grok.Patterns = []string{test.pattern}
+		if test.definitions != nil {
+			conf.Grok.PatternDefinitions = test.definitions
+		}
 
 		gSet, err := NewGrok(conf, nil, tLog, tStats)
 		if err != nil {
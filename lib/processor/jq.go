@@ -0,0 +1,236 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/itchyny/gojq"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJQ] = TypeSpec{
+		constructor: NewJQ,
+		description: `
+Parses a message part as a JSON document and runs a
+[jq](https://stedolan.github.io/jq/manual/) query against it. Each document
+emitted by the query becomes a message part in place of the original, which
+means a query can expand a part into many parts or, if it emits nothing,
+remove the part entirely.
+
+For example, with the following config:
+
+` + "``` yaml" + `
+jq:
+  query: .items[] | {id, total: (.price * .quantity)}
+` + "```" + `
+
+If the initial contents of a part were:
+
+` + "``` json" + `
+{"items":[{"id":"a","price":2,"quantity":3},{"id":"b","price":5,"quantity":1}]}
+` + "```" + `
+
+Then the part would be replaced with the two parts:
+
+` + "``` json" + `
+{"id":"a","total":6}
+{"id":"b","total":5}
+` + "```" + `
+
+When ` + "`raw`" + ` is set to ` + "`true`" + ` results that are strings are
+written to the part as they are rather than as quoted JSON strings, similar to
+the ` + "`-r`" + ` flag of the jq command line tool.
+
+Parts that fail to parse as JSON, or where the query fails at runtime, are left
+unchanged and flagged as having failed, and can therefore be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JQConfig contains configuration fields for the JQ processor.
+type JQConfig struct {
+	Parts []int  `json:"parts" yaml:"parts"`
+	Query string `json:"query" yaml:"query"`
+	Raw   bool   `json:"raw" yaml:"raw"`
+}
+
+// NewJQConfig returns a JQConfig with default values.
+func NewJQConfig() JQConfig {
+	return JQConfig{
+		Parts: []int{},
+		Query: ".",
+		Raw:   false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JQ is a processor that executes a jq query on message parts and replaces each
+// part with the documents it emits.
+type JQ struct {
+	conf JQConfig
+	code *gojq.Code
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErrJSONP  metrics.StatCounter
+	mErrQuery  metrics.StatCounter
+	mErrJSONS  metrics.StatCounter
+	mErr       metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewJQ returns a JQ processor.
+func NewJQ(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	query, err := gojq.Parse(conf.JQ.Query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jq query: %v", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile jq query: %v", err)
+	}
+	return &JQ{
+		conf:  conf.JQ,
+		code:  code,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErrJSONP:  stats.GetCounter("error.json_parse"),
+		mErrQuery:  stats.GetCounter("error.query"),
+		mErrJSONS:  stats.GetCounter("error.json_set"),
+		mErr:       stats.GetCounter("error"),
+		mDropped:   stats.GetCounter("dropped"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (j *JQ) query(part types.Part) ([]types.Part, error) {
+	jsonPart, err := part.JSON()
+	if err != nil {
+		j.mErrJSONP.Incr(1)
+		return nil, fmt.Errorf("failed to parse part into json: %v", err)
+	}
+
+	var newParts []types.Part
+	iter := j.code.Run(jsonPart)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, isErr := v.(error); isErr {
+			j.mErrQuery.Incr(1)
+			return nil, fmt.Errorf("failed to execute query: %v", err)
+		}
+
+		newPart := message.NewPart(nil).SetMetadata(part.Metadata().Copy())
+		if str, isStr := v.(string); isStr && j.conf.Raw {
+			newPart.Set([]byte(str))
+		} else if err = newPart.SetJSON(v); err != nil {
+			j.mErrJSONS.Incr(1)
+			return nil, fmt.Errorf("failed to convert query result into part: %v", err)
+		}
+		newParts = append(newParts, newPart)
+	}
+	return newParts, nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (j *JQ) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	j.mCount.Incr(1)
+	newMsg := message.New(nil)
+
+	lParts := msg.Len()
+	noParts := len(j.conf.Parts) == 0
+	msg.Iter(func(i int, part types.Part) error {
+		isTarget := noParts
+		if !isTarget {
+			nI := i - lParts
+			for _, t := range j.conf.Parts {
+				if t == nI || t == i {
+					isTarget = true
+					break
+				}
+			}
+		}
+		if !isTarget {
+			newMsg.Append(part.Copy())
+			return nil
+		}
+
+		newParts, err := j.query(part)
+		if err != nil {
+			j.mErr.Incr(1)
+			j.log.Debugf("Failed to run jq query: %v\n", err)
+			newMsg.Append(part.Copy())
+			FlagFail(newMsg.Get(-1))
+			return nil
+		}
+		if len(newParts) == 0 {
+			j.mDropped.Incr(1)
+		}
+		newMsg.Append(newParts...)
+		return nil
+	})
+
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	j.mBatchSent.Incr(1)
+	j.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (j *JQ) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (j *JQ) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
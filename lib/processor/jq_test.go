@@ -0,0 +1,233 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestJQBadQuery(t *testing.T) {
+	conf := NewConfig()
+	conf.JQ.Query = ".foo |"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	if _, err := NewJQ(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad query")
+	}
+}
+
+func TestJQValidation(t *testing.T) {
+	conf := NewConfig()
+	conf.JQ.Parts = []int{0}
+	conf.JQ.Query = ".foo + 1"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewJQ(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{[]byte("this is bad json")})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("No passthrough for bad input data")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+	if exp, act := "this is bad json", string(message.GetAllBytes(msgs[0])[0]); exp != act {
+		t.Errorf("Wrong output from bad json: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected part to be flagged as failed")
+	}
+
+	msgIn = message.New([][]byte{[]byte(`{"foo":"not a number"}`)})
+	msgs, res = proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("No passthrough for failed query")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+	if exp, act := `{"foo":"not a number"}`, string(message.GetAllBytes(msgs[0])[0]); exp != act {
+		t.Errorf("Wrong output from failed query: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected part to be flagged as failed")
+	}
+}
+
+func TestJQ(t *testing.T) {
+	tLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+	tStats := metrics.DudType{}
+
+	type jTest struct {
+		name   string
+		query  string
+		raw    bool
+		parts  []int
+		input  []string
+		output []string
+	}
+
+	tests := []jTest{
+		{
+			name:   "select field",
+			query:  ".foo.bar",
+			input:  []string{`{"foo":{"bar":"baz"}}`},
+			output: []string{`"baz"`},
+		},
+		{
+			name:   "select field raw",
+			query:  ".foo.bar",
+			raw:    true,
+			input:  []string{`{"foo":{"bar":"baz"}}`},
+			output: []string{`baz`},
+		},
+		{
+			name:   "raw non string",
+			query:  ".foo",
+			raw:    true,
+			input:  []string{`{"foo":{"bar":"baz"}}`},
+			output: []string{`{"bar":"baz"}`},
+		},
+		{
+			name:   "construct object",
+			query:  `{id, total: (.price * .quantity)}`,
+			input:  []string{`{"id":"a","price":2,"quantity":3}`},
+			output: []string{`{"id":"a","total":6}`},
+		},
+		{
+			name:  "expand array",
+			query: ".items[]",
+			input: []string{
+				`{"items":[1,2,3]}`,
+				`{"items":[4]}`,
+			},
+			output: []string{`1`, `2`, `3`, `4`},
+		},
+		{
+			name:  "select filter",
+			query: "select(.keep)",
+			input: []string{
+				`{"keep":true,"id":1}`,
+				`{"keep":false,"id":2}`,
+				`{"keep":true,"id":3}`,
+			},
+			output: []string{
+				`{"id":1,"keep":true}`,
+				`{"id":3,"keep":true}`,
+			},
+		},
+		{
+			name:  "target parts",
+			query: ".items[]",
+			parts: []int{-1},
+			input: []string{
+				`{"items":[1,2]}`,
+				`{"items":[3,4]}`,
+			},
+			output: []string{`{"items":[1,2]}`, `3`, `4`},
+		},
+		{
+			name:   "all dropped",
+			query:  "empty",
+			input:  []string{`{}`, `{}`},
+			output: nil,
+		},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig()
+		conf.JQ.Parts = test.parts
+		conf.JQ.Query = test.query
+		conf.JQ.Raw = test.raw
+
+		proc, err := NewJQ(conf, nil, tLog, tStats)
+		if err != nil {
+			t.Fatalf("Error for test '%v': %v", test.name, err)
+		}
+
+		inMsg := message.New(nil)
+		for _, in := range test.input {
+			inMsg.Append(message.NewPart([]byte(in)))
+		}
+		msgs, res := proc.ProcessMessage(inMsg)
+		if test.output == nil {
+			if len(msgs) != 0 {
+				t.Errorf("Expected no messages for test '%v'", test.name)
+			}
+			if res == nil {
+				t.Errorf("Expected response for test '%v'", test.name)
+			}
+			continue
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("Test '%v' did not succeed", test.name)
+		}
+
+		var act []string
+		for _, b := range message.GetAllBytes(msgs[0]) {
+			act = append(act, string(b))
+		}
+		if !reflect.DeepEqual(test.output, act) {
+			t.Errorf("Wrong result '%v': %v != %v", test.name, act, test.output)
+		}
+	}
+}
+
+func TestJQMetadata(t *testing.T) {
+	conf := NewConfig()
+	conf.JQ.Query = ".[]"
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewJQ(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part := message.NewPart([]byte(`["a","b"]`))
+	part.Metadata().Set("foo", "bar")
+	msgIn := message.New(nil)
+	msgIn.Append(part)
+
+	msgs, _ := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if exp, act := 2, msgs[0].Len(); exp != act {
+		t.Fatalf("Wrong count of parts: %v != %v", act, exp)
+	}
+	for i := 0; i < 2; i++ {
+		if exp, act := "bar", msgs[0].Get(i).Metadata().Get("foo"); exp != act {
+			t.Errorf("Wrong metadata on part %v: %v != %v", i, act, exp)
+		}
+	}
+}
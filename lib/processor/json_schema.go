@@ -0,0 +1,263 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJSONSchema] = TypeSpec{
+		constructor: NewJSONSchema,
+		description: `
+Checks message parts against a [JSON Schema](https://json-schema.org/) (drafts
+4, 6 and 7 are supported). The schema can either be provided inline with the
+field ` + "`schema`" + ` or loaded from a file or URL with the field
+` + "`schema_path`" + `, e.g. ` + "`file://path/to/schema.json`" + ` or
+` + "`http://example.com/schema.json`" + `. Exactly one of these fields must be
+set.
+
+Parts that fail validation, or that cannot be parsed as JSON, are left
+unchanged but are flagged as having failed and have the reasons written to the
+metadata key ` + "`json_schema_errors`" + `. They can then be dropped or routed
+elsewhere using [error handling patterns](../error_handling.md).
+
+For example, with the following config:
+
+` + "``` yaml" + `
+json_schema:
+  schema_path: "file://path/to/schema.json"
+` + "```" + `
+
+Where ` + "`schema.json`" + ` is:
+
+` + "``` json" + `
+{
+  "$id": "https://example.com/person.schema.json",
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Person",
+  "type": "object",
+  "properties": {
+    "firstName": {
+      "type": "string",
+      "description": "The person's first name."
+    },
+    "lastName": {
+      "type": "string",
+      "description": "The person's last name."
+    },
+    "age": {
+      "description": "Age in years which must be equal to or greater than zero.",
+      "type": "integer",
+      "minimum": 0
+    }
+  }
+}
+` + "```" + `
+
+And the input part is:
+
+` + "``` json" + `
+{
+  "firstName": "John",
+  "lastName": "Doe",
+  "age": -21
+}
+` + "```" + `
+
+Then the part would be flagged as failed with the following metadata:
+
+` + "```" + `
+json_schema_errors: age: Must be greater than or equal to 0
+` + "```" + `
+
+Failed parts can be removed from the pipeline with a
+` + "[`filter_parts`](#filter_parts)" + ` processor using a
+` + "[`processor_failed`](../conditions/README.md#processor_failed)" + `
+condition:
+
+` + "``` yaml" + `
+- json_schema:
+    schema_path: "file://path/to/schema.json"
+- filter_parts:
+    not:
+      processor_failed: {}
+` + "```",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JSONSchemaConfig contains configuration fields for the JSONSchema processor.
+type JSONSchemaConfig struct {
+	Parts      []int  `json:"parts" yaml:"parts"`
+	SchemaPath string `json:"schema_path" yaml:"schema_path"`
+	Schema     string `json:"schema" yaml:"schema"`
+}
+
+// NewJSONSchemaConfig returns a JSONSchemaConfig with default values.
+func NewJSONSchemaConfig() JSONSchemaConfig {
+	return JSONSchemaConfig{
+		Parts:      []int{},
+		SchemaPath: "",
+		Schema:     "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JSONSchema is a processor that validates message parts against a JSON schema
+// and flags those that fail.
+type JSONSchema struct {
+	parts  []int
+	schema *gojsonschema.Schema
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount      metrics.StatCounter
+	mErrJSONP   metrics.StatCounter
+	mErrInvalid metrics.StatCounter
+	mErr        metrics.StatCounter
+	mSent       metrics.StatCounter
+	mBatchSent  metrics.StatCounter
+}
+
+// NewJSONSchema returns a JSONSchema processor.
+func NewJSONSchema(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var loader gojsonschema.JSONLoader
+	if len(conf.JSONSchema.Schema) > 0 && len(conf.JSONSchema.SchemaPath) > 0 {
+		return nil, errors.New("only one of schema and schema_path may be specified")
+	} else if len(conf.JSONSchema.Schema) > 0 {
+		loader = gojsonschema.NewStringLoader(conf.JSONSchema.Schema)
+	} else if len(conf.JSONSchema.SchemaPath) > 0 {
+		if !strings.HasPrefix(conf.JSONSchema.SchemaPath, "file://") &&
+			!strings.HasPrefix(conf.JSONSchema.SchemaPath, "http://") &&
+			!strings.HasPrefix(conf.JSONSchema.SchemaPath, "https://") {
+			return nil, errors.New("schema_path must begin with file://, http:// or https://")
+		}
+		loader = gojsonschema.NewReferenceLoader(conf.JSONSchema.SchemaPath)
+	} else {
+		return nil, errors.New("either schema or schema_path must be specified")
+	}
+
+	schema, err := gojsonschema.NewSchema(loader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON schema: %v", err)
+	}
+
+	return &JSONSchema{
+		parts:  conf.JSONSchema.Parts,
+		schema: schema,
+		log:    log,
+		stats:  stats,
+
+		mCount:      stats.GetCounter("count"),
+		mErrJSONP:   stats.GetCounter("error.json_parse"),
+		mErrInvalid: stats.GetCounter("error.invalid"),
+		mErr:        stats.GetCounter("error"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *JSONSchema) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int) {
+		part := newMsg.Get(index)
+		jsonPart, err := part.JSON()
+		if err != nil {
+			s.mErrJSONP.Incr(1)
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to parse part into json: %v\n", err)
+			part.Metadata().Set("json_schema_errors", err.Error())
+			FlagFail(part)
+			return
+		}
+
+		result, err := s.schema.Validate(gojsonschema.NewGoLoader(jsonPart))
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to validate json: %v\n", err)
+			part.Metadata().Set("json_schema_errors", err.Error())
+			FlagFail(part)
+			return
+		}
+
+		if !result.Valid() {
+			s.mErrInvalid.Incr(1)
+			s.mErr.Incr(1)
+			var errStrs []string
+			for _, desc := range result.Errors() {
+				errStrs = append(errStrs, desc.String())
+			}
+			errStr := strings.Join(errStrs, "; ")
+			s.log.Debugf("The document is not valid: %v\n", errStr)
+			part.Metadata().Set("json_schema_errors", errStr)
+			FlagFail(part)
+		}
+	}
+
+	if len(s.parts) == 0 {
+		for i := 0; i < newMsg.Len(); i++ {
+			proc(i)
+		}
+	} else {
+		for _, i := range s.parts {
+			proc(i)
+		}
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *JSONSchema) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *JSONSchema) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+const testJSONSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"age": {"type": "integer", "minimum": 0}
+	},
+	"required": ["name"]
+}`
+
+func TestJSONSchemaInline(t *testing.T) {
+	conf := NewConfig()
+	conf.JSONSchema.Schema = testJSONSchema
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewJSONSchema(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"name":"foo","age":10}`),
+		[]byte(`{"name":"bar","age":-1}`),
+		[]byte(`{"age":5}`),
+		[]byte(`not json`),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := []struct {
+		failed bool
+		errs   string
+	}{
+		{false, ""},
+		{true, "age: Must be greater than or equal to 0"},
+		{true, "(root): name is required"},
+		{true, "invalid character 'o' in literal null (expecting 'u')"},
+	}
+	for i, e := range exp {
+		part := msgs[0].Get(i)
+		if act := HasFailed(part); act != e.failed {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, e.failed)
+		}
+		if act := part.Metadata().Get("json_schema_errors"); act != e.errs {
+			t.Errorf("Wrong errors for part %v: %v != %v", i, act, e.errs)
+		}
+	}
+	if exp, act := `{"name":"bar","age":-1}`, string(msgs[0].Get(1).Get()); exp != act {
+		t.Errorf("Wrong part contents: %v != %v", act, exp)
+	}
+}
+
+func TestJSONSchemaPath(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_json_schema_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	schemaPath := filepath.Join(tmpDir, "schema.json")
+	if err = ioutil.WriteFile(schemaPath, []byte(testJSONSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := NewConfig()
+	conf.JSONSchema.Parts = []int{-1}
+	conf.JSONSchema.SchemaPath = "file://" + schemaPath
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	proc, err := NewJSONSchema(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"age":5}`),
+		[]byte(`{"name":"foo"}`),
+	})
+	msgs, _ := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected untargeted part to be ignored")
+	}
+	if HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected valid part to pass")
+	}
+}
+
+func TestJSONSchemaBadConfig(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	if _, err := NewJSONSchema(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing schema")
+	}
+
+	conf.JSONSchema.Schema = testJSONSchema
+	conf.JSONSchema.SchemaPath = "file://foo.json"
+	if _, err := NewJSONSchema(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from both schema fields")
+	}
+
+	conf.JSONSchema.Schema = ""
+	conf.JSONSchema.SchemaPath = "foo.json"
+	if _, err := NewJSONSchema(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from schema path without scheme")
+	}
+
+	conf.JSONSchema.SchemaPath = ""
+	conf.JSONSchema.Schema = `{"type": 10}`
+	if _, err := NewJSONSchema(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from invalid schema")
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/processor/mapping"
+	"github.com/Jeffail/benthos/lib/response"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMapping] = TypeSpec{
+		constructor: NewMapping,
+		description: `
+Executes a mapping against each message part, allowing a single processor to
+perform transformations that would otherwise require a chain of ` + "`json`" + `,
+` + "`text`" + ` and ` + "`metadata`" + ` processors.
+
+` + "``` yaml" + `
+mapping:
+  mapping: |
+    root.id = this.user.id
+    root.name = this.user.first_name + " " + this.user.last_name
+    root.tier = if this.spend > 1000 { "gold" } else { "standard" }
+    root.ingested_at = now()
+    meta kafka_key = this.user.id.string()
+` + "```" + `
+
+A mapping is a list of assignments, one per line, of the form
+` + "`target = expression`" + `. Lines starting with ` + "`#`" + ` are comments.
+
+### Targets
+
+The target ` + "`root`" + ` refers to the new document, which starts empty, and
+fields of it are assigned with paths such as ` + "`root.foo.bar`" + `, where
+the ` + "`root.`" + ` prefix can be omitted. Path segments containing dots or
+spaces can be quoted: ` + "`root.\"foo.bar\"`" + `. Assigning to
+` + "`root`" + ` itself replaces the whole document, e.g. ` + "`root = this`" + `
+copies the input document to be modified by the following assignments.
+
+The target ` + "`meta foo`" + ` sets the metadata key ` + "`foo`" + ` to the
+string form of the value. Metadata of the resulting part starts as a copy of the
+metadata of the input part.
+
+If the mapping only assigns metadata then the content of the part is left
+unchanged. Otherwise, the content of the part is replaced with the new
+document, which is serialised as JSON unless it is a string.
+
+### Expressions
+
+- Literals: ` + "`\"strings\"`" + `, numbers, ` + "`true`" + `,
+  ` + "`false`" + `, ` + "`null`" + `, arrays ` + "`[1, 2]`" + ` and objects
+  ` + "`{\"a\": this.b}`" + `.
+- ` + "`this`" + ` refers to the input part parsed as JSON, and its fields are
+  accessed with paths such as ` + "`this.foo.bar`" + ` or
+  ` + "`this.items.0`" + ` for an array index. Fields that do not exist are
+  ` + "`null`" + `.
+- Arithmetic ` + "`+ - * / %`" + `, where ` + "`+`" + ` also concatenates
+  strings, comparisons ` + "`== != > >= < <=`" + ` and logic
+  ` + "`&& || !`" + `. Parentheses control the order of evaluation.
+- Conditionals ` + "`if this.a > 1 { \"big\" } else if this.a > 0 { \"small\" } else { \"none\" }`" + `,
+  where an if expression without a final else that does not match leaves the
+  target unchanged.
+
+### Functions
+
+- ` + "`batch_index()`" + ` and ` + "`batch_size()`" + ` return the index of the
+  part within the batch and the size of the batch.
+- ` + "`content()`" + ` returns the raw content of the input part as a string.
+- ` + "`deleted()`" + ` deletes the target. Deleting ` + "`root`" + ` removes
+  the part from the batch, and deleting a metadata key removes it.
+- ` + "`hostname()`" + `, ` + "`now()`" + ` returning an RFC3339 timestamp,
+  ` + "`timestamp_unix()`" + ` and ` + "`uuid_v4()`" + `.
+- ` + "`meta(\"key\")`" + ` returns a metadata value of the input part, or
+  ` + "`null`" + ` if it does not exist, and ` + "`meta()`" + ` returns an object
+  of all metadata.
+- ` + "`nothing()`" + ` leaves the target unchanged.
+
+### Methods
+
+Methods are called on the result of an expression, e.g.
+` + "`this.name.uppercase()`" + `:
+
+- ` + "`contains(value)`" + `: Whether a string contains a substring, or an
+  array contains a value.
+- ` + "`join(sep)`" + ` and ` + "`split(sep)`" + `: Join an array into a string
+  and split a string into an array.
+- ` + "`keys()`" + `: The sorted keys of an object.
+- ` + "`length()`" + `: The length of a string, array or object.
+- ` + "`lowercase()`" + `, ` + "`uppercase()`" + ` and ` + "`trim()`" + `.
+- ` + "`number()`" + ` and ` + "`string()`" + `: Convert a value into a number
+  or a string, where objects and arrays are serialised as JSON.
+- ` + "`or(value)`" + `: Returns the argument if the target is
+  ` + "`null`" + ` or fails.
+- ` + "`parse_json()`" + `: Parses a string as JSON.
+- ` + "`replace(old, new)`" + `: Replaces all occurrences of a substring.
+
+If a mapping fails for a part, for example when it is not valid JSON, the part
+is left unchanged and flagged as failed, allowing it to be handled with
+[error handling](../error_handling.md) patterns.`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MappingConfig contains configuration fields for the Mapping processor.
+type MappingConfig struct {
+	Parts   []int  `json:"parts" yaml:"parts"`
+	Mapping string `json:"mapping" yaml:"mapping"`
+}
+
+// NewMappingConfig returns a MappingConfig with default values.
+func NewMappingConfig() MappingConfig {
+	return MappingConfig{
+		Parts:   []int{},
+		Mapping: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Mapping is a processor that executes a mapping against message parts.
+type Mapping struct {
+	parts   []int
+	mapping *mapping.Mapping
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewMapping returns a Mapping processor.
+func NewMapping(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	m, err := mapping.New(conf.Mapping.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping: %v", err)
+	}
+	return &Mapping{
+		parts:   conf.Mapping.Parts,
+		mapping: m,
+		log:     log,
+		stats:   stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mDropped:   stats.GetCounter("dropped"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Mapping) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+
+	results := make([]types.Part, msg.Len())
+	for i := range results {
+		results[i] = msg.Get(i).Copy()
+	}
+	deleted := make([]bool, msg.Len())
+
+	proc := func(index int) {
+		if index < 0 {
+			index = msg.Len() + index
+		}
+		if index < 0 || index >= msg.Len() {
+			return
+		}
+		part, err := p.mapping.MapPart(index, msg)
+		if err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to execute mapping: %v\n", err)
+			FlagFail(results[index])
+			return
+		}
+		if part == nil {
+			p.mDropped.Incr(1)
+			deleted[index] = true
+			return
+		}
+		results[index] = part
+	}
+
+	if len(p.parts) == 0 {
+		for i := 0; i < msg.Len(); i++ {
+			proc(i)
+		}
+	} else {
+		for _, i := range p.parts {
+			proc(i)
+		}
+	}
+
+	newMsg := message.New(nil)
+	for i, part := range results {
+		if !deleted[i] {
+			newMsg.Append(part)
+		}
+	}
+	if newMsg.Len() == 0 {
+		return nil, response.NewAck()
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Mapping) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Mapping) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,355 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// nothingValue is returned by expressions that have no value, such as an if
+// expression without an else branch whose condition fails. Assigning nothing
+// leaves the target unchanged.
+type nothingValue struct{}
+
+// deleteValue is returned by the deleted function. Assigning it deletes the
+// target.
+type deleteValue struct{}
+
+// context is the state available to expressions whilst mapping a part.
+type context struct {
+	index int
+	msg   types.Message
+
+	doc    interface{}
+	docErr error
+	parsed bool
+}
+
+func newContext(index int, msg types.Message) *context {
+	return &context{index: index, msg: msg}
+}
+
+// document returns the part being mapped parsed as JSON.
+func (c *context) document() (interface{}, error) {
+	if !c.parsed {
+		c.parsed = true
+		if c.doc, c.docErr = c.msg.Get(c.index).JSON(); c.docErr != nil {
+			c.docErr = fmt.Errorf("failed to parse message as JSON: %v", c.docErr)
+		}
+	}
+	return c.doc, c.docErr
+}
+
+// expr is a parsed expression that resolves to a value.
+type expr func(ctx *context) (interface{}, error)
+
+func literalExpr(v interface{}) expr {
+	return func(*context) (interface{}, error) {
+		return v, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// typeName returns a human readable name for the type of a value.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string, []byte:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, float32, int, int64, uint64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case nothingValue:
+		return "nothing"
+	case deleteValue:
+		return "delete"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// toNumber converts a numeric value into a float64.
+func toNumber(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case float32:
+		return float64(t), nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	}
+	return 0, fmt.Errorf("expected number value, found %v", typeName(v))
+}
+
+// toString converts a value into a string, where structured values are
+// serialised as JSON.
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case json.Number:
+		return t.String()
+	}
+	if b, err := json.Marshal(v); err == nil {
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// isEqual compares two values, where numbers of different types are equal if
+// their values are equal.
+func isEqual(lhs, rhs interface{}) bool {
+	if l, err := toNumber(lhs); err == nil {
+		if r, err := toNumber(rhs); err == nil {
+			return l == r
+		}
+		return false
+	}
+	if l, ok := lhs.([]byte); ok {
+		lhs = string(l)
+	}
+	if r, ok := rhs.([]byte); ok {
+		rhs = string(r)
+	}
+	return reflect.DeepEqual(lhs, rhs)
+}
+
+// cloneValue returns a deep copy of structured values, which prevents
+// assignments from modifying the document being mapped.
+func cloneValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			c[k] = cloneValue(v)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for i, v := range t {
+			c[i] = cloneValue(v)
+		}
+		return c
+	}
+	return v
+}
+
+//------------------------------------------------------------------------------
+
+// getField returns the value of a field of an object, or an element of an
+// array when the field is an index. Fields of null values, and fields that do
+// not exist, are null.
+func getField(v interface{}, field string) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t[field], nil
+	case []interface{}:
+		i, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access field %v of an array", field)
+		}
+		if i < 0 || i >= len(t) {
+			return nil, nil
+		}
+		return t[i], nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cannot access field %v of a %v value", field, typeName(v))
+}
+
+// getPath returns the value at a path of fields.
+func getPath(v interface{}, path []string) (interface{}, error) {
+	var err error
+	for _, field := range path {
+		if v, err = getField(v, field); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// setPath sets the value at a path of an object, creating objects along the
+// path that do not exist, and returns the resulting root.
+func setPath(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	var obj map[string]interface{}
+	switch t := root.(type) {
+	case map[string]interface{}:
+		obj = t
+	case nil:
+		obj = map[string]interface{}{}
+	default:
+		return nil, fmt.Errorf("cannot set field %v of a %v value", path[0], typeName(root))
+	}
+	child, err := setPath(obj[path[0]], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	obj[path[0]] = child
+	return obj, nil
+}
+
+// deletePath removes the value at a path of an object, if it exists.
+func deletePath(root interface{}, path []string) {
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+	deletePath(obj[path[0]], path[1:])
+}
+
+//------------------------------------------------------------------------------
+
+// toBool asserts that a value is a bool.
+func toBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected bool value, found %v", typeName(v))
+	}
+	return b, nil
+}
+
+// arithmetic applies an arithmetic operator to two values.
+func arithmetic(op string, lhs, rhs interface{}) (interface{}, error) {
+	if op == "+" {
+		if l, ok := lhs.(string); ok {
+			if r, ok := rhs.(string); ok {
+				return l + r, nil
+			}
+			return nil, fmt.Errorf("cannot add %v to string", typeName(rhs))
+		}
+	}
+	l, err := toNumber(lhs)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toNumber(rhs)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, errors.New("attempted to divide by zero")
+		}
+		return l / r, nil
+	case "%":
+		if int64(r) == 0 {
+			return nil, errors.New("attempted to divide by zero")
+		}
+		return float64(int64(l) % int64(r)), nil
+	}
+	return nil, fmt.Errorf("unrecognised operator: %v", op)
+}
+
+// compare applies a comparison operator to two values.
+func compare(op string, lhs, rhs interface{}) (bool, error) {
+	switch op {
+	case "==":
+		return isEqual(lhs, rhs), nil
+	case "!=":
+		return !isEqual(lhs, rhs), nil
+	}
+	var cmp int
+	if l, ok := lhs.(string); ok {
+		r, ok := rhs.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %v", typeName(rhs))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	} else {
+		l, err := toNumber(lhs)
+		if err != nil {
+			return false, err
+		}
+		r, err := toNumber(rhs)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unrecognised operator: %v", op)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,344 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+//------------------------------------------------------------------------------
+
+// functionCtor creates an expression from the arguments of a function call.
+type functionCtor func(args []expr) (expr, error)
+
+// methodCtor creates an expression from the target and arguments of a method
+// call.
+type methodCtor func(target expr, args []expr) (expr, error)
+
+func expectArgs(name string, args []expr, n ...int) error {
+	for _, c := range n {
+		if len(args) == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v expected %v arguments, received %v", name, n[0], len(args))
+}
+
+// stringArg resolves an argument that must be a string.
+func stringArg(ctx *context, name string, arg expr) (string, error) {
+	v, err := arg(ctx)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%v expected string argument, found %v", name, typeName(v))
+	}
+	return s, nil
+}
+
+// noArgsFunction creates a function that takes no arguments.
+func noArgsFunction(name string, fn func(ctx *context) (interface{}, error)) functionCtor {
+	return func(args []expr) (expr, error) {
+		if err := expectArgs(name, args, 0); err != nil {
+			return nil, err
+		}
+		return fn, nil
+	}
+}
+
+var functions = map[string]functionCtor{
+	"batch_index": noArgsFunction("batch_index", func(ctx *context) (interface{}, error) {
+		return int64(ctx.index), nil
+	}),
+	"batch_size": noArgsFunction("batch_size", func(ctx *context) (interface{}, error) {
+		return int64(ctx.msg.Len()), nil
+	}),
+	"content": noArgsFunction("content", func(ctx *context) (interface{}, error) {
+		return string(ctx.msg.Get(ctx.index).Get()), nil
+	}),
+	"deleted": noArgsFunction("deleted", func(*context) (interface{}, error) {
+		return deleteValue{}, nil
+	}),
+	"hostname": noArgsFunction("hostname", func(*context) (interface{}, error) {
+		return os.Hostname()
+	}),
+	"meta": func(args []expr) (expr, error) {
+		if err := expectArgs("meta", args, 1, 0); err != nil {
+			return nil, err
+		}
+		if len(args) == 0 {
+			return func(ctx *context) (interface{}, error) {
+				kvs := map[string]interface{}{}
+				ctx.msg.Get(ctx.index).Metadata().Iter(func(k, v string) error {
+					kvs[k] = v
+					return nil
+				})
+				return kvs, nil
+			}, nil
+		}
+		return func(ctx *context) (interface{}, error) {
+			key, err := stringArg(ctx, "meta", args[0])
+			if err != nil {
+				return nil, err
+			}
+			if v := ctx.msg.Get(ctx.index).Metadata().Get(key); len(v) > 0 {
+				return v, nil
+			}
+			return nil, nil
+		}, nil
+	},
+	"nothing": noArgsFunction("nothing", func(*context) (interface{}, error) {
+		return nothingValue{}, nil
+	}),
+	"now": noArgsFunction("now", func(*context) (interface{}, error) {
+		return time.Now().Format(time.RFC3339Nano), nil
+	}),
+	"timestamp_unix": noArgsFunction("timestamp_unix", func(*context) (interface{}, error) {
+		return time.Now().Unix(), nil
+	}),
+	"uuid_v4": noArgsFunction("uuid_v4", func(*context) (interface{}, error) {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		return u.String(), nil
+	}),
+}
+
+//------------------------------------------------------------------------------
+
+// simpleMethod creates a method that takes no arguments and transforms the
+// value of its target.
+func simpleMethod(name string, fn func(v interface{}) (interface{}, error)) methodCtor {
+	return func(target expr, args []expr) (expr, error) {
+		if err := expectArgs(name, args, 0); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return fn(v)
+		}, nil
+	}
+}
+
+// stringMethod creates a method that takes no arguments and transforms a
+// string.
+func stringMethod(name string, fn func(s string) interface{}) methodCtor {
+	return simpleMethod(name, func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v expected string value, found %v", name, typeName(v))
+		}
+		return fn(s), nil
+	})
+}
+
+var methods = map[string]methodCtor{
+	"contains": func(target expr, args []expr) (expr, error) {
+		if err := expectArgs("contains", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			arg, err := args[0](ctx)
+			if err != nil {
+				return nil, err
+			}
+			switch t := v.(type) {
+			case string:
+				sub, ok := arg.(string)
+				if !ok {
+					return nil, fmt.Errorf("contains expected string argument, found %v", typeName(arg))
+				}
+				return strings.Contains(t, sub), nil
+			case []interface{}:
+				for _, e := range t {
+					if isEqual(e, arg) {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+			return nil, fmt.Errorf("contains expected string or array value, found %v", typeName(v))
+		}, nil
+	},
+	"join": func(target expr, args []expr) (expr, error) {
+		if err := expectArgs("join", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			sep, err := stringArg(ctx, "join", args[0])
+			if err != nil {
+				return nil, err
+			}
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("join expected array value, found %v", typeName(v))
+			}
+			strs := make([]string, len(arr))
+			for i, e := range arr {
+				strs[i] = toString(e)
+			}
+			return strings.Join(strs, sep), nil
+		}, nil
+	},
+	"keys": simpleMethod("keys", func(v interface{}) (interface{}, error) {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("keys expected object value, found %v", typeName(v))
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		arr := make([]interface{}, len(keys))
+		for i, k := range keys {
+			arr[i] = k
+		}
+		return arr, nil
+	}),
+	"length": simpleMethod("length", func(v interface{}) (interface{}, error) {
+		switch t := v.(type) {
+		case string:
+			return int64(len(t)), nil
+		case []interface{}:
+			return int64(len(t)), nil
+		case map[string]interface{}:
+			return int64(len(t)), nil
+		}
+		return nil, fmt.Errorf("length expected string, array or object value, found %v", typeName(v))
+	}),
+	"lowercase": stringMethod("lowercase", func(s string) interface{} {
+		return strings.ToLower(s)
+	}),
+	"number": simpleMethod("number", func(v interface{}) (interface{}, error) {
+		if s, ok := v.(string); ok {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %q as number", s)
+			}
+			return f, nil
+		}
+		return toNumber(v)
+	}),
+	"or": func(target expr, args []expr) (expr, error) {
+		if err := expectArgs("or", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			if v, err := target(ctx); err == nil && v != nil {
+				return v, nil
+			}
+			return args[0](ctx)
+		}, nil
+	},
+	"parse_json": simpleMethod("parse_json", func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("parse_json expected string value, found %v", typeName(v))
+		}
+		var doc interface{}
+		if err := json.Unmarshal([]byte(s), &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse value as JSON: %v", err)
+		}
+		return doc, nil
+	}),
+	"replace": func(target expr, args []expr) (expr, error) {
+		if err := expectArgs("replace", args, 2); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("replace expected string value, found %v", typeName(v))
+			}
+			old, err := stringArg(ctx, "replace", args[0])
+			if err != nil {
+				return nil, err
+			}
+			with, err := stringArg(ctx, "replace", args[1])
+			if err != nil {
+				return nil, err
+			}
+			return strings.Replace(s, old, with, -1), nil
+		}, nil
+	},
+	"split": func(target expr, args []expr) (expr, error) {
+		if err := expectArgs("split", args, 1); err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := target(ctx)
+			if err != nil {
+				return nil, err
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("split expected string value, found %v", typeName(v))
+			}
+			sep, err := stringArg(ctx, "split", args[0])
+			if err != nil {
+				return nil, err
+			}
+			strs := strings.Split(s, sep)
+			arr := make([]interface{}, len(strs))
+			for i, str := range strs {
+				arr[i] = str
+			}
+			return arr, nil
+		}, nil
+	},
+	"string": simpleMethod("string", func(v interface{}) (interface{}, error) {
+		return toString(v), nil
+	}),
+	"trim": stringMethod("trim", func(s string) interface{} {
+		return strings.TrimSpace(s)
+	}),
+	"uppercase": stringMethod("uppercase", func(s string) interface{} {
+		return strings.ToUpper(s)
+	}),
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,117 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// statement assigns the result of an expression to either a path of the new
+// document or a metadata key.
+type statement struct {
+	line   int
+	isMeta bool
+	meta   string
+	path   []string
+	value  expr
+}
+
+// Mapping is a parsed mapping that can be executed against message parts.
+type Mapping struct {
+	statements []statement
+}
+
+// New parses a mapping and returns a Mapping, or an error if the mapping is
+// invalid.
+func New(mapping string) (*Mapping, error) {
+	statements, err := parse(mapping)
+	if err != nil {
+		return nil, err
+	}
+	return &Mapping{statements: statements}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// MapPart executes the mapping against a part of a message and returns the
+// resulting part, which is nil if the mapping deleted it. The content of the
+// resulting part is only replaced when the mapping assigns to the new document,
+// and metadata assignments apply to a copy of the metadata of the original
+// part.
+func (m *Mapping) MapPart(index int, msg types.Message) (types.Part, error) {
+	part := msg.Get(index).Copy()
+	ctx := newContext(index, msg)
+
+	var root interface{}
+	rootSet := false
+
+	for _, s := range m.statements {
+		v, err := s.value(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", s.line, err)
+		}
+		if _, isNothing := v.(nothingValue); isNothing {
+			continue
+		}
+		_, isDelete := v.(deleteValue)
+		if s.isMeta {
+			if isDelete {
+				part.Metadata().Delete(s.meta)
+			} else {
+				part.Metadata().Set(s.meta, toString(v))
+			}
+			continue
+		}
+		rootSet = true
+		if len(s.path) == 0 {
+			if isDelete {
+				return nil, nil
+			}
+			root = cloneValue(v)
+			continue
+		}
+		if isDelete {
+			deletePath(root, s.path)
+		} else if root, err = setPath(root, s.path, cloneValue(v)); err != nil {
+			return nil, fmt.Errorf("line %v: %v", s.line, err)
+		}
+	}
+
+	if rootSet {
+		switch t := root.(type) {
+		case string:
+			part.Set([]byte(t))
+		case []byte:
+			part.Set(t)
+		default:
+			if err := part.SetJSON(t); err != nil {
+				return nil, fmt.Errorf("failed to set result of mapping: %v", err)
+			}
+		}
+	}
+	return part, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,291 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/message"
+)
+
+//------------------------------------------------------------------------------
+
+func TestMappingParseErrors(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		err     string
+	}{
+		"empty": {
+			mapping: "  # nothing here\n",
+			err:     "mapping must contain at least one statement",
+		},
+		"no assignment": {
+			mapping: `root.foo this.bar`,
+			err:     "line 1 char 10: expected '=' after assignment target",
+		},
+		"assign to this": {
+			mapping: `this.foo = "bar"`,
+			err:     "line 1 char 1: cannot assign to this, assign to root instead",
+		},
+		"bare field reference": {
+			mapping: "root.foo = \"bar\"\nroot.bar = baz",
+			err:     "line 2 char 12: unrecognised keyword baz, fields of the input document are referenced with this.baz",
+		},
+		"unknown function": {
+			mapping: `root = nope()`,
+			err:     "line 1 char 8: unrecognised function: nope",
+		},
+		"unknown method": {
+			mapping: `root = this.foo.nope()`,
+			err:     "line 1 char 17: unrecognised method: nope",
+		},
+		"wrong arguments": {
+			mapping: `root = this.foo.replace("a")`,
+			err:     "line 1 char 17: replace expected 2 arguments, received 1",
+		},
+		"two statements on a line": {
+			mapping: `root.a = "a" root.b = "b"`,
+			err:     "line 1 char 14: expected end of statement, found 'r'",
+		},
+		"unterminated string": {
+			mapping: `root = "foo`,
+			err:     "line 1 char 8: unterminated string literal",
+		},
+		"unterminated if": {
+			mapping: `root = if this.a { "a"`,
+			err:     "line 1 char 23: expected '}'",
+		},
+	}
+
+	for name, test := range tests {
+		_, err := New(test.mapping)
+		if err == nil {
+			t.Errorf("%v: expected error", name)
+			continue
+		}
+		if exp, act := test.err, err.Error(); exp != act {
+			t.Errorf("%v: wrong error: %v != %v", name, act, exp)
+		}
+	}
+}
+
+func TestMappingExecution(t *testing.T) {
+	type part struct {
+		content string
+		meta    map[string]string
+	}
+
+	tests := map[string]struct {
+		mapping string
+		input   []part
+		index   int
+		output  string
+		meta    map[string]string
+		deleted bool
+		err     string
+	}{
+		"field mapping": {
+			mapping: `
+# Build a new document from parts of the input.
+root.id = this.user.id
+root.name = this.user.first + " " + this.user.last
+root."full stop" = "."
+root.tags = this.tags.join(",").uppercase()`,
+			input:  []part{{content: `{"user":{"id":5,"first":"foo","last":"bar"},"tags":["a","b"]}`}},
+			output: `{"full stop":".","id":5,"name":"foo bar","tags":"A,B"}`,
+		},
+		"copy then modify": {
+			mapping: `root = this
+root.user.password = deleted()
+root.count = this.count + 1`,
+			input:  []part{{content: `{"user":{"name":"foo","password":"bar"},"count":1}`}},
+			output: `{"count":2,"user":{"name":"foo"}}`,
+		},
+		"conditionals": {
+			mapping: `root.size = if this.n > 10 { "big" } else if this.n > 5 { "medium" } else { "small" }
+root.even = if this.n % 2 == 0 { true }
+root.odd = if this.n % 2 != 0 { true }`,
+			input:  []part{{content: `{"n":7}`}},
+			output: `{"odd":true,"size":"medium"}`,
+		},
+		"logic": {
+			mapping: `root.a = this.x > 1 && !this.y
+root.b = this.y || this.missing.field == null
+root.c = (1 + 2) * 3 - 4 / 2
+root.d = -this.x`,
+			input:  []part{{content: `{"x":2,"y":false}`}},
+			output: `{"a":true,"b":true,"c":7,"d":-2}`,
+		},
+		"metadata": {
+			mapping: `meta topic = this.topic
+meta "old key" = deleted()
+meta count = this.count
+root.key = meta("key")
+root.missing = meta("nope").or("default")`,
+			input: []part{{
+				content: `{"topic":"foo","count":3}`,
+				meta:    map[string]string{"key": "bar", "old key": "baz"},
+			}},
+			output: `{"key":"bar","missing":"default"}`,
+			meta:   map[string]string{"key": "bar", "topic": "foo", "count": "3"},
+		},
+		"metadata only leaves content": {
+			mapping: `meta foo = content().trim()`,
+			input:   []part{{content: ` not json `}},
+			output:  ` not json `,
+			meta:    map[string]string{"foo": "not json"},
+		},
+		"string root": {
+			mapping: `root = this.message.replace("foo", "bar")`,
+			input:   []part{{content: `{"message":"foo baz foo"}`}},
+			output:  `bar baz bar`,
+		},
+		"literals": {
+			mapping: `root = {"a": [1, "two", null, true], b: {"c": this.nope.or(this.c)}, "d": if false { 1 }}`,
+			input:   []part{{content: `{"c":"c"}`}},
+			output:  `{"a":[1,"two",null,true],"b":{"c":"c"}}`,
+		},
+		"methods": {
+			mapping: `root.a = this.s.split(",").length()
+root.b = this.s.contains("b")
+root.c = this.arr.contains(2)
+root.d = this.obj.keys()
+root.e = this.num.number() + 1
+root.f = this.obj.string()
+root.g = this.json.parse_json().foo
+root.h = this.arr.0
+root.i = "  Foo ".trim().lowercase()`,
+			input:  []part{{content: `{"s":"a,b,c","arr":[1,2],"obj":{"y":1,"x":2},"num":"5","json":"{\"foo\":\"bar\"}"}`}},
+			output: `{"a":3,"b":true,"c":true,"d":["x","y"],"e":6,"f":"{\"x\":2,\"y\":1}","g":"bar","h":1,"i":"foo"}`,
+		},
+		"batch functions": {
+			mapping: `root.index = batch_index()
+root.size = batch_size()`,
+			input:  []part{{content: `{}`}, {content: `{}`}},
+			index:  1,
+			output: `{"index":1,"size":2}`,
+		},
+		"delete part": {
+			mapping: `root = if this.drop { deleted() } else { this }`,
+			input:   []part{{content: `{"drop":true}`}},
+			deleted: true,
+		},
+		"not json": {
+			mapping: `root.foo = this.foo`,
+			input:   []part{{content: `not json`}},
+			err:     "line 1: failed to parse message as JSON",
+		},
+		"type error": {
+			mapping: `root.foo = this.foo + 1`,
+			input:   []part{{content: `{"foo":"bar"}`}},
+			err:     "line 1: cannot add number to string",
+		},
+		"set field of non object": {
+			mapping: `root = "foo"
+root.bar = "baz"`,
+			input: []part{{content: `{}`}},
+			err:   "line 2: cannot set field bar of a string value",
+		},
+	}
+
+	for name, test := range tests {
+		m, err := New(test.mapping)
+		if err != nil {
+			t.Errorf("%v: failed to parse: %v", name, err)
+			continue
+		}
+
+		msg := message.New(nil)
+		for _, p := range test.input {
+			part := message.NewPart([]byte(p.content))
+			for k, v := range p.meta {
+				part.Metadata().Set(k, v)
+			}
+			msg.Append(part)
+		}
+
+		res, err := m.MapPart(test.index, msg)
+		if len(test.err) > 0 {
+			if err == nil {
+				t.Errorf("%v: expected error", name)
+			} else if !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("%v: wrong error: %v", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		if test.deleted {
+			if res != nil {
+				t.Errorf("%v: expected part to be deleted", name)
+			}
+			continue
+		}
+		if exp, act := test.output, string(res.Get()); exp != act {
+			t.Errorf("%v: wrong output: %v != %v", name, act, exp)
+		}
+		if test.meta != nil {
+			act := map[string]string{}
+			res.Metadata().Iter(func(k, v string) error {
+				act[k] = v
+				return nil
+			})
+			if len(act) != len(test.meta) {
+				t.Errorf("%v: wrong metadata: %v != %v", name, act, test.meta)
+			}
+			for k, v := range test.meta {
+				if act[k] != v {
+					t.Errorf("%v: wrong metadata: %v != %v", name, act, test.meta)
+				}
+			}
+		}
+	}
+}
+
+func TestMappingDoesNotModifyInput(t *testing.T) {
+	m, err := New(`root = this
+root.foo.bar = "changed"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := message.New([][]byte{[]byte(`{"foo":{"bar":"original"}}`)})
+	if _, err = msg.Get(0).JSON(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := m.MapPart(0, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := `{"foo":{"bar":"changed"}}`, string(res.Get()); exp != act {
+		t.Errorf("Wrong output: %v != %v", act, exp)
+	}
+	doc, _ := msg.Get(0).JSON()
+	if exp, act := "original", doc.(map[string]interface{})["foo"].(map[string]interface{})["bar"]; exp != act {
+		t.Errorf("Input document was modified: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,696 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+//------------------------------------------------------------------------------
+
+// parser is a recursive descent parser of mappings.
+type parser struct {
+	input []rune
+	pos   int
+}
+
+// parse parses a mapping into a list of statements.
+func parse(mapping string) ([]statement, error) {
+	p := &parser{input: []rune(mapping)}
+
+	var statements []statement
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			break
+		}
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+
+		p.skipSpace(false)
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("expected end of statement, found %q", p.peek())
+		}
+	}
+	if len(statements) == 0 {
+		return nil, errors.New("mapping must contain at least one statement")
+	}
+	return statements, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line, char := p.lineChar(p.pos)
+	return fmt.Errorf("line %v char %v: %v", line, char, fmt.Sprintf(format, args...))
+}
+
+// lineChar returns the line and character number of a position.
+func (p *parser) lineChar(pos int) (int, int) {
+	line, char := 1, 1
+	for i := 0; i < pos && i < len(p.input); i++ {
+		if p.input[i] == '\n' {
+			line++
+			char = 1
+		} else {
+			char++
+		}
+	}
+	return line, char
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+// peek returns the next rune without consuming it, or zero at the end of the
+// input.
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// skipSpace skips whitespace and comments, where newlines are only skipped
+// when newlines is true.
+func (p *parser) skipSpace(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case c == '\n':
+			if !newlines {
+				return
+			}
+			p.pos++
+		case unicode.IsSpace(c):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume advances past a string if the input continues with it.
+func (p *parser) consume(s string) bool {
+	r := []rune(s)
+	if p.pos+len(r) > len(p.input) {
+		return false
+	}
+	for i, c := range r {
+		if p.input[p.pos+i] != c {
+			return false
+		}
+	}
+	p.pos += len(r)
+	return true
+}
+
+func isIdentRune(c rune, first bool) bool {
+	if c == '_' || unicode.IsLetter(c) {
+		return true
+	}
+	return !first && unicode.IsDigit(c)
+}
+
+// ident reads an identifier, returning an empty string if there is none.
+func (p *parser) ident() string {
+	start := p.pos
+	for !p.eof() && isIdentRune(p.peek(), p.pos == start) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// keyword advances past an identifier if it matches word.
+func (p *parser) keyword(word string) bool {
+	start := p.pos
+	if p.ident() == word {
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+// quoted reads a double quoted string literal.
+func (p *parser) quoted() (string, error) {
+	start := p.pos
+	p.pos++
+	for {
+		if p.eof() || p.peek() == '\n' {
+			p.pos = start
+			return "", p.errorf("unterminated string literal")
+		}
+		c := p.peek()
+		p.pos++
+		if c == '\\' {
+			p.pos++
+		} else if c == '"' {
+			break
+		}
+	}
+	s, err := strconv.Unquote(string(p.input[start:p.pos]))
+	if err != nil {
+		p.pos = start
+		return "", p.errorf("invalid string literal: %v", err)
+	}
+	return s, nil
+}
+
+// segment reads a path segment, which is either a quoted string or a sequence
+// of letters, digits and underscores. Returns whether the segment was quoted.
+func (p *parser) segment() (string, bool, error) {
+	if p.peek() == '"' {
+		s, err := p.quoted()
+		return s, true, err
+	}
+	start := p.pos
+	for !p.eof() && isIdentRune(p.peek(), false) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", false, p.errorf("expected field name")
+	}
+	return string(p.input[start:p.pos]), false, nil
+}
+
+// pathTail reads a sequence of dot separated path segments.
+func (p *parser) pathTail() ([]string, error) {
+	var path []string
+	for p.peek() == '.' {
+		p.pos++
+		seg, _, err := p.segment()
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, seg)
+	}
+	return path, nil
+}
+
+//------------------------------------------------------------------------------
+
+// statement parses an assignment of the form target = expression.
+func (p *parser) statement() (statement, error) {
+	line, _ := p.lineChar(p.pos)
+	s := statement{line: line}
+
+	start := p.pos
+	switch name := p.ident(); name {
+	case "":
+		return s, p.errorf("expected assignment target")
+	case "meta":
+		p.skipSpace(false)
+		key, _, err := p.segment()
+		if err != nil {
+			return s, p.errorf("expected metadata key")
+		}
+		s.isMeta, s.meta = true, key
+	case "root":
+		var err error
+		if s.path, err = p.pathTail(); err != nil {
+			return s, err
+		}
+	case "this":
+		p.pos = start
+		return s, p.errorf("cannot assign to this, assign to root instead")
+	default:
+		tail, err := p.pathTail()
+		if err != nil {
+			return s, err
+		}
+		s.path = append([]string{name}, tail...)
+	}
+
+	p.skipSpace(false)
+	if p.peek() != '=' || p.consume("==") {
+		return s, p.errorf("expected '=' after assignment target")
+	}
+	p.pos++
+
+	var err error
+	s.value, err = p.expression()
+	return s, err
+}
+
+//------------------------------------------------------------------------------
+
+// operators lists binary operators from the lowest precedence to the highest.
+var operators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) expression() (expr, error) {
+	return p.binary(0)
+}
+
+// binary parses a chain of binary operators of a precedence level.
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(operators) {
+		return p.unary()
+	}
+	lhs, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		save := p.pos
+		p.skipSpace(true)
+		op := ""
+		for _, o := range operators[level] {
+			if p.consume(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			p.pos = save
+			return lhs, nil
+		}
+		rhs, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryExpr(op, lhs, rhs)
+	}
+}
+
+func binaryExpr(op string, lhs, rhs expr) expr {
+	switch op {
+	case "&&", "||":
+		return func(ctx *context) (interface{}, error) {
+			l, err := lhs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			lb, err := toBool(l)
+			if err != nil {
+				return nil, err
+			}
+			if lb == (op == "||") {
+				return lb, nil
+			}
+			r, err := rhs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return toBool(r)
+		}
+	}
+	return func(ctx *context) (interface{}, error) {
+		l, err := lhs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r, err := rhs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "+", "-", "*", "/", "%":
+			return arithmetic(op, l, r)
+		}
+		return compare(op, l, r)
+	}
+}
+
+// unary parses a not or negation operator followed by its operand.
+func (p *parser) unary() (expr, error) {
+	p.skipSpace(true)
+	switch p.peek() {
+	case '!':
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := operand(ctx)
+			if err != nil {
+				return nil, err
+			}
+			b, err := toBool(v)
+			if err != nil {
+				return nil, err
+			}
+			return !b, nil
+		}, nil
+	case '-':
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx *context) (interface{}, error) {
+			v, err := operand(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return arithmetic("-", float64(0), v)
+		}, nil
+	}
+	return p.postfix()
+}
+
+// postfix parses an operand followed by any field accesses and method calls.
+func (p *parser) postfix() (expr, error) {
+	e, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '.' {
+		p.pos++
+		start := p.pos
+		seg, quoted, err := p.segment()
+		if err != nil {
+			return nil, err
+		}
+		if quoted || p.peek() != '(' {
+			e = fieldExpr(e, seg)
+			continue
+		}
+		ctor, exists := methods[seg]
+		if !exists {
+			p.pos = start
+			return nil, p.errorf("unrecognised method: %v", seg)
+		}
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		if e, err = ctor(e, args); err != nil {
+			p.pos = start
+			return nil, p.errorf("%v", err)
+		}
+	}
+	return e, nil
+}
+
+func fieldExpr(target expr, field string) expr {
+	return func(ctx *context) (interface{}, error) {
+		v, err := target(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return getField(v, field)
+	}
+}
+
+// args parses a parenthesised list of arguments.
+func (p *parser) args() ([]expr, error) {
+	p.pos++
+	var args []expr
+	p.skipSpace(true)
+	if p.consume(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		p.skipSpace(true)
+		if p.consume(")") {
+			return args, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or ')' in arguments")
+		}
+	}
+}
+
+// primary parses a literal, this, a function call, a parenthesised expression
+// or an if expression.
+func (p *parser) primary() (expr, error) {
+	p.skipSpace(true)
+	switch c := p.peek(); {
+	case c == '"':
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return literalExpr(s), nil
+	case c == '(':
+		p.pos++
+		e, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace(true)
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return e, nil
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.object()
+	case unicode.IsDigit(c):
+		return p.number()
+	}
+
+	start := p.pos
+	switch name := p.ident(); name {
+	case "":
+		if p.eof() {
+			return nil, p.errorf("expected expression, found end of mapping")
+		}
+		return nil, p.errorf("expected expression, found %q", p.peek())
+	case "this":
+		return func(ctx *context) (interface{}, error) {
+			return ctx.document()
+		}, nil
+	case "true":
+		return literalExpr(true), nil
+	case "false":
+		return literalExpr(false), nil
+	case "null":
+		return literalExpr(nil), nil
+	case "if":
+		return p.ifExpr()
+	default:
+		if p.peek() != '(' {
+			p.pos = start
+			return nil, p.errorf("unrecognised keyword %v, fields of the input document are referenced with this.%v", name, name)
+		}
+		ctor, exists := functions[name]
+		if !exists {
+			p.pos = start
+			return nil, p.errorf("unrecognised function: %v", name)
+		}
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		e, err := ctor(args)
+		if err != nil {
+			p.pos = start
+			return nil, p.errorf("%v", err)
+		}
+		return e, nil
+	}
+}
+
+// number parses a number literal.
+func (p *parser) number() (expr, error) {
+	start := p.pos
+	for !p.eof() && unicode.IsDigit(p.peek()) {
+		p.pos++
+	}
+	if p.peek() == '.' && p.pos+1 < len(p.input) && unicode.IsDigit(p.input[p.pos+1]) {
+		p.pos++
+		for !p.eof() && unicode.IsDigit(p.peek()) {
+			p.pos++
+		}
+	}
+	f, err := strconv.ParseFloat(string(p.input[start:p.pos]), 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid number: %v", err)
+	}
+	return literalExpr(f), nil
+}
+
+// array parses an array literal, where elements can be any expression.
+func (p *parser) array() (expr, error) {
+	p.pos++
+	var elements []expr
+	p.skipSpace(true)
+	if !p.consume("]") {
+		for {
+			e, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, e)
+			p.skipSpace(true)
+			if p.consume("]") {
+				break
+			}
+			if !p.consume(",") {
+				return nil, p.errorf("expected ',' or ']' in array")
+			}
+		}
+	}
+	return func(ctx *context) (interface{}, error) {
+		arr := make([]interface{}, 0, len(elements))
+		for _, e := range elements {
+			v, err := e(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if _, isNothing := v.(nothingValue); isNothing {
+				continue
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	}, nil
+}
+
+// object parses an object literal, where keys are quoted strings or
+// identifiers and values can be any expression.
+func (p *parser) object() (expr, error) {
+	p.pos++
+	var keys []string
+	var values []expr
+	p.skipSpace(true)
+	if !p.consume("}") {
+		for {
+			key, _, err := p.segment()
+			if err != nil {
+				return nil, p.errorf("expected object key")
+			}
+			p.skipSpace(true)
+			if !p.consume(":") {
+				return nil, p.errorf("expected ':' after object key")
+			}
+			v, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			keys, values = append(keys, key), append(values, v)
+			p.skipSpace(true)
+			if p.consume("}") {
+				break
+			}
+			if !p.consume(",") {
+				return nil, p.errorf("expected ',' or '}' in object")
+			}
+			p.skipSpace(true)
+		}
+	}
+	return func(ctx *context) (interface{}, error) {
+		obj := make(map[string]interface{}, len(keys))
+		for i, k := range keys {
+			v, err := values[i](ctx)
+			if err != nil {
+				return nil, err
+			}
+			if _, isNothing := v.(nothingValue); isNothing {
+				continue
+			}
+			obj[k] = v
+		}
+		return obj, nil
+	}, nil
+}
+
+// ifExpr parses the remainder of an if expression, which resolves to nothing
+// when its condition fails and it has no else branch.
+func (p *parser) ifExpr() (expr, error) {
+	cond, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+
+	var elseBody expr
+	save := p.pos
+	p.skipSpace(true)
+	if p.keyword("else") {
+		p.skipSpace(true)
+		if p.keyword("if") {
+			elseBody, err = p.ifExpr()
+		} else {
+			elseBody, err = p.block()
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p.pos = save
+	}
+
+	return func(ctx *context) (interface{}, error) {
+		v, err := cond(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b, err := toBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("if condition: %v", err)
+		}
+		if b {
+			return body(ctx)
+		}
+		if elseBody != nil {
+			return elseBody(ctx)
+		}
+		return nothingValue{}, nil
+	}, nil
+}
+
+// block parses an expression wrapped in braces.
+func (p *parser) block() (expr, error) {
+	p.skipSpace(true)
+	if !p.consume("{") {
+		return nil, p.errorf("expected '{'")
+	}
+	e, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace(true)
+	if !p.consume("}") {
+		return nil, p.errorf("expected '}'")
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
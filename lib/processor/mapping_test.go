@@ -0,0 +1,130 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestMappingAllParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root = if this.drop == true { deleted() } else { this }
+root.doubled = this.value * 2
+meta index = batch_index()`
+
+	proc, err := NewMapping(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgIn := message.New([][]byte{
+		[]byte(`{"value":1}`),
+		[]byte(`{"value":2,"drop":true}`),
+		[]byte(`not json`),
+		[]byte(`{"value":3}`),
+	})
+	msgs, res := proc.ProcessMessage(msgIn)
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	if res != nil {
+		t.Fatal("Non-nil result")
+	}
+
+	exp := []string{
+		`{"doubled":2,"value":1}`,
+		`not json`,
+		`{"doubled":6,"value":3}`,
+	}
+	if act := message.GetAllBytes(msgs[0]); len(act) != len(exp) {
+		t.Fatalf("Wrong count of parts: %s", act)
+	}
+	for i, part := range message.GetAllBytes(msgs[0]) {
+		if exp, act := exp[i], string(part); exp != act {
+			t.Errorf("Wrong output from mapping: %v != %v", act, exp)
+		}
+	}
+	if exp, act := "3", msgs[0].Get(2).Metadata().Get("index"); exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected invalid part to be flagged as failed")
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Unexpected failure flag")
+	}
+	if HasFailed(msgIn.Get(2)) {
+		t.Error("Input part was flagged as failed")
+	}
+}
+
+func TestMappingParts(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Parts = []int{-1}
+	conf.Mapping.Mapping = `root = content().uppercase()`
+
+	proc, err := NewMapping(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte("foo"), []byte("bar")}))
+	if len(msgs) != 1 {
+		t.Fatal("Wrong count of messages")
+	}
+	exp := []string{"foo", "BAR"}
+	for i, part := range message.GetAllBytes(msgs[0]) {
+		if exp, act := exp[i], string(part); exp != act {
+			t.Errorf("Wrong output from mapping: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestMappingDeleteAll(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root = deleted()`
+
+	proc, err := NewMapping(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	if len(msgs) != 0 {
+		t.Error("Expected no messages")
+	}
+	if res == nil || res.Error() != nil {
+		t.Errorf("Expected ack response: %v", res)
+	}
+}
+
+func TestMappingBadMapping(t *testing.T) {
+	conf := NewConfig()
+	conf.Mapping.Mapping = `root = this.foo +`
+
+	if _, err := NewMapping(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from bad mapping")
+	}
+}
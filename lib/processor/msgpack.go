@@ -0,0 +1,243 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	msgpack "gopkg.in/vmihailenco/msgpack.v2"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMsgPack] = TypeSpec{
+		constructor: NewMsgPack,
+		description: `
+Converts message parts between [MessagePack](https://msgpack.org/) and JSON.
+The operator ` + "`to_json`" + ` converts MessagePack encoded parts into JSON,
+and ` + "`from_json`" + ` converts JSON parts into MessagePack.
+
+When converting to JSON, map keys that are not strings are converted into their
+string representation and binary values are encoded as base64 strings. When
+converting from JSON, whole numbers are encoded as MessagePack integers and map
+keys are written in sorted order.
+
+Parts that fail to convert are left unchanged and flagged as having failed, and
+can therefore be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MsgPackConfig contains configuration fields for the MsgPack processor.
+type MsgPackConfig struct {
+	Parts    []int  `json:"parts" yaml:"parts"`
+	Operator string `json:"operator" yaml:"operator"`
+}
+
+// NewMsgPackConfig returns a MsgPackConfig with default values.
+func NewMsgPackConfig() MsgPackConfig {
+	return MsgPackConfig{
+		Parts:    []int{},
+		Operator: "to_json",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type msgPackOperator func(part types.Part) error
+
+// msgPackToJSONValue converts a decoded MessagePack value into a form that can
+// be serialised as JSON.
+func msgPackToJSONValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprintf("%v", k)] = msgPackToJSONValue(v)
+		}
+		return m
+	case []interface{}:
+		for i, v := range t {
+			t[i] = msgPackToJSONValue(v)
+		}
+		return t
+	}
+	return v
+}
+
+// jsonToMsgPackValue converts a JSON value decoded with json.Number enabled
+// into a form where whole numbers are encoded as integers.
+func jsonToMsgPackValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = jsonToMsgPackValue(v)
+		}
+		return t
+	case []interface{}:
+		for i, v := range t {
+			t[i] = jsonToMsgPackValue(v)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	}
+	return v
+}
+
+func msgPackToJSON(part types.Part) error {
+	v, err := msgpack.NewDecoder(bytes.NewReader(part.Get())).DecodeInterface()
+	if err != nil {
+		return fmt.Errorf("failed to decode msgpack: %v", err)
+	}
+	if err = part.SetJSON(msgPackToJSONValue(v)); err != nil {
+		return fmt.Errorf("failed to set json: %v", err)
+	}
+	return nil
+}
+
+func msgPackFromJSON(part types.Part) error {
+	dec := json.NewDecoder(bytes.NewReader(part.Get()))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("failed to parse json: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := msgpack.NewEncoder(&buf).SortMapKeys(true).Encode(jsonToMsgPackValue(v)); err != nil {
+		return fmt.Errorf("failed to encode msgpack: %v", err)
+	}
+	part.Set(buf.Bytes())
+	return nil
+}
+
+func strToMsgPackOperator(str string) (msgPackOperator, error) {
+	switch str {
+	case "to_json":
+		return msgPackToJSON, nil
+	case "from_json":
+		return msgPackFromJSON, nil
+	}
+	return nil, fmt.Errorf("operator not recognised: %v", str)
+}
+
+//------------------------------------------------------------------------------
+
+// MsgPack is a processor that converts message parts between MessagePack and
+// JSON.
+type MsgPack struct {
+	parts    []int
+	operator msgPackOperator
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewMsgPack returns a MsgPack processor.
+func NewMsgPack(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	op, err := strToMsgPackOperator(conf.MsgPack.Operator)
+	if err != nil {
+		return nil, err
+	}
+	return &MsgPack{
+		parts:    conf.MsgPack.Parts,
+		operator: op,
+		log:      log,
+		stats:    stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (m *MsgPack) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	m.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int) {
+		if err := m.operator(newMsg.Get(index)); err != nil {
+			m.mErr.Incr(1)
+			m.log.Debugf("Failed to convert part: %v\n", err)
+			FlagFail(newMsg.Get(index))
+		}
+	}
+
+	if len(m.parts) == 0 {
+		for i := 0; i < newMsg.Len(); i++ {
+			proc(i)
+		}
+	} else {
+		for _, i := range m.parts {
+			proc(i)
+		}
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	m.mBatchSent.Incr(1)
+	m.mSent.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (m *MsgPack) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (m *MsgPack) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
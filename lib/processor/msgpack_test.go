@@ -0,0 +1,138 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.MsgPack.Operator = "from_json"
+	fromJSON, err := NewMsgPack(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.MsgPack.Operator = "to_json"
+	toJSON, err := NewMsgPack(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []string{
+		`{"a":1,"b":-2.5,"c":"foo","d":[true,false,null],"e":{"f":18446744073709551615}}`,
+		`[1,2,3]`,
+		`"foo"`,
+		`{}`,
+	}
+
+	for _, input := range tests {
+		msgs, res := fromJSON.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Wrong result from input: %v", input)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Fatalf("Failed to convert from json: %v", input)
+		}
+
+		msgs, res = toJSON.ProcessMessage(msgs[0])
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Wrong result from input: %v", input)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Fatalf("Failed to convert to json: %v", input)
+		}
+		if exp, act := input, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestMsgPackFromJSON(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.MsgPack.Operator = "from_json"
+	conf.MsgPack.Parts = []int{0}
+
+	proc, err := NewMsgPack(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"b":1,"a":"x"}`),
+		[]byte(`not json`),
+	}))
+	exp := []byte{0x82, 0xa1, 'a', 0xa1, 'x', 0xa1, 'b', 0x01}
+	if act := msgs[0].Get(0).Get(); string(exp) != string(act) {
+		t.Errorf("Wrong result: %x != %x", act, exp)
+	}
+	if exp, act := "not json", string(msgs[0].Get(1).Get()); exp != act {
+		t.Errorf("Untargeted part was modified: %v != %v", act, exp)
+	}
+
+	msgs, _ = proc.ProcessMessage(message.New([][]byte{[]byte(`not json`)}))
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected bad json to fail")
+	}
+}
+
+func TestMsgPackToJSON(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.MsgPack.Operator = "to_json"
+
+	proc, err := NewMsgPack(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		// {1: "a", "b": <bin "hi">}
+		{0x82, 0x01, 0xa1, 'a', 0xa1, 'b', 0xc4, 0x02, 'h', 'i'},
+		// Truncated string
+		{0xa5, 'a'},
+	}))
+	if exp, act := `{"1":"a","b":"aGk="}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected first part to succeed")
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected truncated part to fail")
+	}
+
+	conf.MsgPack.Operator = "nope"
+	if _, err = NewMsgPack(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeProtobuf] = TypeSpec{
+		constructor: NewProtobuf,
+		description: `
+Converts message parts between protobuf and JSON using message definitions
+loaded at start up. The operator ` + "`to_json`" + ` converts protobuf encoded
+parts into JSON, and ` + "`from_json`" + ` converts JSON parts into protobuf.
+
+The field ` + "`message`" + ` is the fully qualified name of the message type
+each part is converted with, e.g. ` + "`foo.bar.Person`" + `.
+
+Definitions are loaded either by compiling every ` + "`.proto`" + ` file found
+within the directories listed in ` + "`import_paths`" + `, or from a serialised
+` + "`FileDescriptorSet`" + ` at ` + "`descriptor_set_path`" + `, such as one
+produced with ` + "`protoc --include_imports --descriptor_set_out`" + `. The
+well known types (` + "`google/protobuf/*.proto`" + `) are always available for
+import.
+
+JSON documents follow the
+[proto3 JSON mapping](https://developers.google.com/protocol-buffers/docs/proto3#json),
+which includes the special forms of wrapper types and ` + "`Any`" + ` messages.
+An ` + "`Any`" + ` message can only be converted when its contained type is one
+of the loaded definitions or a well known type.
+
+Parts that fail to convert are left unchanged and flagged as having failed, and
+can therefore be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProtobufConfig contains configuration fields for the Protobuf processor.
+type ProtobufConfig struct {
+	Parts             []int    `json:"parts" yaml:"parts"`
+	Operator          string   `json:"operator" yaml:"operator"`
+	Message           string   `json:"message" yaml:"message"`
+	ImportPaths       []string `json:"import_paths" yaml:"import_paths"`
+	DescriptorSetPath string   `json:"descriptor_set_path" yaml:"descriptor_set_path"`
+}
+
+// NewProtobufConfig returns a ProtobufConfig with default values.
+func NewProtobufConfig() ProtobufConfig {
+	return ProtobufConfig{
+		Parts:             []int{},
+		Operator:          "to_json",
+		Message:           "",
+		ImportPaths:       []string{},
+		DescriptorSetPath: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func loadProtobufFiles(importPaths []string) ([]*desc.FileDescriptor, error) {
+	var files []string
+	for _, importPath := range importPaths {
+		if err := filepath.Walk(importPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || filepath.Ext(path) != ".proto" {
+				return nil
+			}
+			rel, err := filepath.Rel(importPath, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to walk import path '%v': %v", importPath, err)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no .proto files were found within import_paths")
+	}
+
+	parser := protoparse.Parser{
+		ImportPaths: importPaths,
+	}
+	fds, err := parser.ParseFiles(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse .proto files: %v", err)
+	}
+	return fds, nil
+}
+
+func loadProtobufDescriptorSet(path string) ([]*desc.FileDescriptor, error) {
+	setBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %v", err)
+	}
+
+	var set dpb.FileDescriptorSet
+	if err = proto.Unmarshal(setBytes, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set: %v", err)
+	}
+
+	fdMap, err := desc.CreateFileDescriptorsFromSet(&set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link descriptor set: %v", err)
+	}
+
+	var fds []*desc.FileDescriptor
+	for _, fd := range fdMap {
+		fds = append(fds, fd)
+	}
+	return fds, nil
+}
+
+func findProtobufMessage(fds []*desc.FileDescriptor, name string) *desc.MessageDescriptor {
+	for _, fd := range fds {
+		if md := fd.FindMessage(name); md != nil {
+			return md
+		}
+		if md := findProtobufMessage(fd.GetDependencies(), name); md != nil {
+			return md
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Protobuf is a processor that converts message parts between protobuf and
+// JSON.
+type Protobuf struct {
+	parts    []int
+	operator func(part types.Part) error
+
+	msgDesc     *desc.MessageDescriptor
+	factory     *dynamic.MessageFactory
+	marshaler   *jsonpb.Marshaler
+	unmarshaler *jsonpb.Unmarshaler
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewProtobuf returns a Protobuf processor.
+func NewProtobuf(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	pConf := conf.Protobuf
+	if len(pConf.Message) == 0 {
+		return nil, errors.New("a message type must be specified")
+	}
+
+	var fds []*desc.FileDescriptor
+	var err error
+	if len(pConf.ImportPaths) > 0 && len(pConf.DescriptorSetPath) > 0 {
+		return nil, errors.New("only one of import_paths and descriptor_set_path may be specified")
+	} else if len(pConf.ImportPaths) > 0 {
+		fds, err = loadProtobufFiles(pConf.ImportPaths)
+	} else if len(pConf.DescriptorSetPath) > 0 {
+		fds, err = loadProtobufDescriptorSet(pConf.DescriptorSetPath)
+	} else {
+		err = errors.New("either import_paths or descriptor_set_path must be specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msgDesc := findProtobufMessage(fds, pConf.Message)
+	if msgDesc == nil {
+		return nil, fmt.Errorf("failed to find message type '%v'", pConf.Message)
+	}
+
+	factory := dynamic.NewMessageFactoryWithDefaults()
+	resolver := dynamic.AnyResolver(factory, fds...)
+
+	p := &Protobuf{
+		parts:       pConf.Parts,
+		msgDesc:     msgDesc,
+		factory:     factory,
+		marshaler:   &jsonpb.Marshaler{AnyResolver: resolver},
+		unmarshaler: &jsonpb.Unmarshaler{AnyResolver: resolver},
+		log:         log,
+		stats:       stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	switch pConf.Operator {
+	case "to_json":
+		p.operator = p.toJSON
+	case "from_json":
+		p.operator = p.fromJSON
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", pConf.Operator)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *Protobuf) toJSON(part types.Part) error {
+	msg := p.factory.NewDynamicMessage(p.msgDesc)
+	if err := msg.Unmarshal(part.Get()); err != nil {
+		return fmt.Errorf("failed to unmarshal protobuf: %v", err)
+	}
+
+	jsonBytes, err := msg.MarshalJSONPB(p.marshaler)
+	if err != nil {
+		return fmt.Errorf("failed to marshal json: %v", err)
+	}
+	part.Set(jsonBytes)
+	return nil
+}
+
+func (p *Protobuf) fromJSON(part types.Part) error {
+	msg := p.factory.NewDynamicMessage(p.msgDesc)
+	if err := msg.UnmarshalJSONPB(p.unmarshaler, part.Get()); err != nil {
+		return fmt.Errorf("failed to unmarshal json: %v", err)
+	}
+
+	protoBytes, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal protobuf: %v", err)
+	}
+	part.Set(protoBytes)
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (p *Protobuf) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	p.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int) {
+		if err := p.operator(newMsg.Get(index)); err != nil {
+			p.mErr.Incr(1)
+			p.log.Debugf("Failed to convert part: %v\n", err)
+			FlagFail(newMsg.Get(index))
+		}
+	}
+
+	if len(p.parts) == 0 {
+		for i := 0; i < newMsg.Len(); i++ {
+			proc(i)
+		}
+	} else {
+		for _, i := range p.parts {
+			proc(i)
+		}
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	p.mBatchSent.Incr(1)
+	p.mSent.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (p *Protobuf) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (p *Protobuf) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,237 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+const testProtobufPerson = `
+syntax = "proto3";
+
+package testing;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/wrappers.proto";
+import "common/address.proto";
+
+message Person {
+  string name = 1;
+  int32 age = 2;
+  google.protobuf.StringValue nick = 3;
+  common.Address address = 4;
+  repeated google.protobuf.Any extra = 5;
+
+  message Pet {
+    string name = 1;
+  }
+  repeated Pet pets = 6;
+}
+`
+
+const testProtobufAddress = `
+syntax = "proto3";
+
+package common;
+
+message Address {
+  string city = 1;
+}
+`
+
+func testProtobufDir(t *testing.T) string {
+	t.Helper()
+	tmpDir, err := ioutil.TempDir("", "benthos_protobuf_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(filepath.Join(tmpDir, "common"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(tmpDir, "person.proto"), []byte(testProtobufPerson), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(tmpDir, "common", "address.proto"), []byte(testProtobufAddress), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return tmpDir
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	tmpDir := testProtobufDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Protobuf.Operator = "from_json"
+	conf.Protobuf.Message = "testing.Person"
+	conf.Protobuf.ImportPaths = []string{tmpDir}
+
+	fromJSON, err := NewProtobuf(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Protobuf.Operator = "to_json"
+	toJSON, err := NewProtobuf(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []string{
+		`{"name":"foo","age":10}`,
+		`{"name":"foo","nick":"f","address":{"city":"bar"}}`,
+		`{"name":"foo","pets":[{"name":"a"},{"name":"b"}]}`,
+		`{"name":"foo","extra":[{"@type":"type.googleapis.com/common.Address","city":"baz"},{"@type":"type.googleapis.com/google.protobuf.Int64Value","value":"5"}]}`,
+	}
+
+	for _, input := range tests {
+		msgs, res := fromJSON.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Wrong result from input: %v", input)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Fatalf("Failed to convert from json: %v", input)
+		}
+		if string(msgs[0].Get(0).Get()) == input {
+			t.Fatalf("Expected part to be converted: %v", input)
+		}
+
+		msgs, res = toJSON.ProcessMessage(msgs[0])
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Wrong result from input: %v", input)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Fatalf("Failed to convert to json: %v", input)
+		}
+		if exp, act := input, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestProtobufDescriptorSet(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_protobuf_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	set := &dpb.FileDescriptorSet{
+		File: []*dpb.FileDescriptorProto{{
+			Name:    proto.String("address.proto"),
+			Package: proto.String("common"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*dpb.DescriptorProto{{
+				Name: proto.String("Address"),
+				Field: []*dpb.FieldDescriptorProto{{
+					Name:     proto.String("city"),
+					JsonName: proto.String("city"),
+					Number:   proto.Int32(1),
+					Label:    dpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     dpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				}},
+			}},
+		}},
+	}
+	setBytes, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPath := filepath.Join(tmpDir, "set.pb")
+	if err = ioutil.WriteFile(setPath, setBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Protobuf.Operator = "to_json"
+	conf.Protobuf.Message = "common.Address"
+	conf.Protobuf.DescriptorSetPath = setPath
+
+	proc, err := NewProtobuf(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := proc.ProcessMessage(message.New([][]byte{
+		{0x0a, 0x03, 'f', 'o', 'o'},
+		{0xff, 0xff},
+	}))
+	if exp, act := `{"city":"foo"}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected first part to succeed")
+	}
+	if !HasFailed(msgs[0].Get(1)) {
+		t.Error("Expected second part to fail")
+	}
+}
+
+func TestProtobufBadConfig(t *testing.T) {
+	tmpDir := testProtobufDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.Protobuf.ImportPaths = []string{tmpDir}
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing message")
+	}
+
+	conf.Protobuf.Message = "testing.Nope"
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from unknown message")
+	}
+
+	conf.Protobuf.Message = "testing.Person"
+	conf.Protobuf.Operator = "nope"
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+
+	conf.Protobuf.Operator = "to_json"
+	conf.Protobuf.ImportPaths = []string{}
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing definitions")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "bad.proto"), []byte("not a proto"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf.Protobuf.ImportPaths = []string{tmpDir}
+	if _, err := NewProtobuf(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad proto file")
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/linkedin/goavro/v2"
+)
+
+//------------------------------------------------------------------------------
+
+// schemaRegistryClient fetches Avro schemas from a Confluent Schema Registry
+// and caches the resulting codecs by their schema ID, which the registry
+// guarantees to be immutable.
+type schemaRegistryClient struct {
+	url    *url.URL
+	auth   auth.BasicAuthConfig
+	client http.Client
+
+	codecs   map[int]*goavro.Codec
+	codecMut sync.Mutex
+}
+
+func newSchemaRegistryClient(
+	urlStr string, basicAuth auth.BasicAuthConfig, timeoutStr string,
+) (*schemaRegistryClient, error) {
+	if len(urlStr) == 0 {
+		return nil, errors.New("a schema registry url must be specified")
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+
+	var timeout time.Duration
+	if len(timeoutStr) > 0 {
+		if timeout, err = time.ParseDuration(timeoutStr); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+
+	return &schemaRegistryClient{
+		url:    u,
+		auth:   basicAuth,
+		client: http.Client{Timeout: timeout},
+		codecs: map[int]*goavro.Codec{},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+type schemaRegistryResponse struct {
+	ID      int    `json:"id"`
+	Version int    `json:"version"`
+	Schema  string `json:"schema"`
+}
+
+func (c *schemaRegistryClient) get(p string) (*schemaRegistryResponse, error) {
+	reqURL := *c.url
+	reqURL.Path = path.Join(reqURL.Path, p)
+
+	req, err := http.NewRequest("GET", reqURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+	if err = c.auth.Sign(req); err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("schema registry request failed with status %v: %s", res.StatusCode, body)
+	}
+
+	var resPayload schemaRegistryResponse
+	if err = json.Unmarshal(body, &resPayload); err != nil {
+		return nil, fmt.Errorf("failed to parse schema registry response: %v", err)
+	}
+	return &resPayload, nil
+}
+
+func (c *schemaRegistryClient) cacheCodec(id int, schema string) (*goavro.Codec, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %v: %v", id, err)
+	}
+	c.codecMut.Lock()
+	c.codecs[id] = codec
+	c.codecMut.Unlock()
+	return codec, nil
+}
+
+// codecByID returns a codec for a schema ID, fetching it from the registry
+// only if it has not been seen before.
+func (c *schemaRegistryClient) codecByID(id int) (*goavro.Codec, error) {
+	c.codecMut.Lock()
+	codec, exists := c.codecs[id]
+	c.codecMut.Unlock()
+	if exists {
+		return codec, nil
+	}
+
+	res, err := c.get(fmt.Sprintf("/schemas/ids/%v", id))
+	if err != nil {
+		return nil, err
+	}
+	return c.cacheCodec(id, res.Schema)
+}
+
+// codecBySubject fetches the schema registered under a subject and version,
+// where version is either a version number or "latest".
+func (c *schemaRegistryClient) codecBySubject(subject, version string) (int, *goavro.Codec, error) {
+	res, err := c.get(fmt.Sprintf("/subjects/%v/versions/%v", url.PathEscape(subject), version))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	c.codecMut.Lock()
+	codec, exists := c.codecs[res.ID]
+	c.codecMut.Unlock()
+	if exists {
+		return res.ID, codec, nil
+	}
+
+	codec, err = c.cacheCodec(res.ID, res.Schema)
+	return res.ID, codec, err
+}
+
+//------------------------------------------------------------------------------
+
+// Messages framed for the Confluent Schema Registry begin with a zero magic
+// byte followed by a four byte big endian schema ID.
+const schemaRegistryHeaderLen = 5
+
+func schemaRegistryDecodeHeader(b []byte) (int, []byte, error) {
+	if len(b) < schemaRegistryHeaderLen || b[0] != 0 {
+		return 0, nil, errors.New("payload is not framed with a schema registry header")
+	}
+	return int(binary.BigEndian.Uint32(b[1:schemaRegistryHeaderLen])), b[schemaRegistryHeaderLen:], nil
+}
+
+func schemaRegistryEncodeHeader(id int) []byte {
+	b := make([]byte, schemaRegistryHeaderLen)
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+	return b
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,210 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSchemaRegistryDecode] = TypeSpec{
+		constructor: NewSchemaRegistryDecode,
+		description: `
+Decodes message parts containing Avro documents framed in the
+[Confluent Schema Registry wire format](https://docs.confluent.io/current/schema-registry/serializer-formatter.html#wire-format)
+into JSON. The schema ID within each part is used to fetch its schema from the
+registry at ` + "`url`" + `, and schemas are cached once fetched.
+
+The resulting JSON follows the
+[Avro JSON encoding](https://avro.apache.org/docs/current/spec.html#json_encoding),
+which means values of union types are wrapped in an object keyed by their type
+(e.g. ` + "`{\"string\":\"foo\"}`" + `).
+
+The ID of the schema used to decode each part is written to the metadata key
+` + "`schema_registry_id`" + `. Parts that fail to decode are left unchanged and
+flagged as having failed, and can therefore be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryDecodeConfig contains configuration fields for the
+// SchemaRegistryDecode processor.
+type SchemaRegistryDecodeConfig struct {
+	Parts     []int                `json:"parts" yaml:"parts"`
+	URL       string               `json:"url" yaml:"url"`
+	Timeout   string               `json:"timeout" yaml:"timeout"`
+	BasicAuth auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+}
+
+// NewSchemaRegistryDecodeConfig returns a SchemaRegistryDecodeConfig with
+// default values.
+func NewSchemaRegistryDecodeConfig() SchemaRegistryDecodeConfig {
+	return SchemaRegistryDecodeConfig{
+		Parts:     []int{},
+		URL:       "http://localhost:8081",
+		Timeout:   "5s",
+		BasicAuth: auth.NewBasicAuthConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryDecode is a processor that decodes Avro message parts into JSON
+// using schemas fetched from a Confluent Schema Registry.
+type SchemaRegistryDecode struct {
+	parts  []int
+	client *schemaRegistryClient
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErrSchema metrics.StatCounter
+	mErrDecode metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewSchemaRegistryDecode returns a SchemaRegistryDecode processor.
+func NewSchemaRegistryDecode(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	client, err := newSchemaRegistryClient(
+		conf.SchemaRegistryDecode.URL,
+		conf.SchemaRegistryDecode.BasicAuth,
+		conf.SchemaRegistryDecode.Timeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaRegistryDecode{
+		parts:  conf.SchemaRegistryDecode.Parts,
+		client: client,
+		log:    log,
+		stats:  stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErrSchema: stats.GetCounter("error.schema"),
+		mErrDecode: stats.GetCounter("error.decode"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *SchemaRegistryDecode) decode(part types.Part) error {
+	id, payload, err := schemaRegistryDecodeHeader(part.Get())
+	if err != nil {
+		s.mErrDecode.Incr(1)
+		return err
+	}
+
+	codec, err := s.client.codecByID(id)
+	if err != nil {
+		s.mErrSchema.Incr(1)
+		return fmt.Errorf("failed to obtain schema %v: %v", id, err)
+	}
+
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		s.mErrDecode.Incr(1)
+		return fmt.Errorf("failed to decode avro: %v", err)
+	}
+	jsonBytes, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		s.mErrDecode.Incr(1)
+		return fmt.Errorf("failed to convert avro to json: %v", err)
+	}
+
+	// Round trip through the standard library so that the resulting document
+	// has a deterministic field order.
+	var jsonDoc interface{}
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+	if err = dec.Decode(&jsonDoc); err != nil {
+		s.mErrDecode.Incr(1)
+		return fmt.Errorf("failed to parse avro json: %v", err)
+	}
+	if err = part.SetJSON(jsonDoc); err != nil {
+		s.mErrDecode.Incr(1)
+		return fmt.Errorf("failed to set json: %v", err)
+	}
+	part.Metadata().Set("schema_registry_id", strconv.Itoa(id))
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *SchemaRegistryDecode) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int) {
+		if err := s.decode(newMsg.Get(index)); err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to decode part: %v\n", err)
+			FlagFail(newMsg.Get(index))
+		}
+	}
+
+	if len(s.parts) == 0 {
+		for i := 0; i < newMsg.Len(); i++ {
+			proc(i)
+		}
+	} else {
+		for _, i := range s.parts {
+			proc(i)
+		}
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *SchemaRegistryDecode) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *SchemaRegistryDecode) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,254 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+	"github.com/Jeffail/benthos/lib/util/http/auth"
+	"github.com/linkedin/goavro/v2"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSchemaRegistryEncode] = TypeSpec{
+		constructor: NewSchemaRegistryEncode,
+		description: `
+Encodes JSON message parts into Avro documents framed in the
+[Confluent Schema Registry wire format](https://docs.confluent.io/current/schema-registry/serializer-formatter.html#wire-format),
+using the schema registered under ` + "`subject`" + ` in the registry at
+` + "`url`" + `.
+
+The field ` + "`version`" + ` selects the schema version to use, and can either
+be a version number or ` + "`latest`" + `. When using ` + "`latest`" + ` the
+schema is refreshed from the registry at the interval specified by
+` + "`refresh_period`" + `, otherwise it is fetched only once.
+
+Input documents must follow the
+[Avro JSON encoding](https://avro.apache.org/docs/current/spec.html#json_encoding),
+which means values of union types must be wrapped in an object keyed by their
+type (e.g. ` + "`{\"string\":\"foo\"}`" + `).
+
+Parts that fail to encode are left unchanged and flagged as having failed, and
+can therefore be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryEncodeConfig contains configuration fields for the
+// SchemaRegistryEncode processor.
+type SchemaRegistryEncodeConfig struct {
+	Parts         []int                `json:"parts" yaml:"parts"`
+	URL           string               `json:"url" yaml:"url"`
+	Subject       string               `json:"subject" yaml:"subject"`
+	Version       string               `json:"version" yaml:"version"`
+	RefreshPeriod string               `json:"refresh_period" yaml:"refresh_period"`
+	Timeout       string               `json:"timeout" yaml:"timeout"`
+	BasicAuth     auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+}
+
+// NewSchemaRegistryEncodeConfig returns a SchemaRegistryEncodeConfig with
+// default values.
+func NewSchemaRegistryEncodeConfig() SchemaRegistryEncodeConfig {
+	return SchemaRegistryEncodeConfig{
+		Parts:         []int{},
+		URL:           "http://localhost:8081",
+		Subject:       "",
+		Version:       "latest",
+		RefreshPeriod: "10m",
+		Timeout:       "5s",
+		BasicAuth:     auth.NewBasicAuthConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryEncode is a processor that encodes JSON message parts into Avro
+// using a schema fetched from a Confluent Schema Registry.
+type SchemaRegistryEncode struct {
+	parts         []int
+	subject       string
+	version       string
+	refreshPeriod time.Duration
+	client        *schemaRegistryClient
+
+	schemaID    int
+	codec       *goavro.Codec
+	lastFetched time.Time
+	schemaMut   sync.Mutex
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErrSchema metrics.StatCounter
+	mErrEncode metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewSchemaRegistryEncode returns a SchemaRegistryEncode processor.
+func NewSchemaRegistryEncode(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	eConf := conf.SchemaRegistryEncode
+	if len(eConf.Subject) == 0 {
+		return nil, errors.New("a subject must be specified")
+	}
+	if eConf.Version != "latest" {
+		if _, err := strconv.Atoi(eConf.Version); err != nil {
+			return nil, fmt.Errorf("version must be a number or 'latest', got: %v", eConf.Version)
+		}
+	}
+
+	var refreshPeriod time.Duration
+	if len(eConf.RefreshPeriod) > 0 {
+		var err error
+		if refreshPeriod, err = time.ParseDuration(eConf.RefreshPeriod); err != nil {
+			return nil, fmt.Errorf("failed to parse refresh period: %v", err)
+		}
+	}
+
+	client, err := newSchemaRegistryClient(eConf.URL, eConf.BasicAuth, eConf.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaRegistryEncode{
+		parts:         eConf.Parts,
+		subject:       eConf.Subject,
+		version:       eConf.Version,
+		refreshPeriod: refreshPeriod,
+		client:        client,
+		log:           log,
+		stats:         stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErrSchema: stats.GetCounter("error.schema"),
+		mErrEncode: stats.GetCounter("error.encode"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// getCodec returns the codec for the configured subject, fetching it from the
+// registry when it has not yet been obtained or, for the latest version, when
+// the refresh period has elapsed. A failed refresh falls back to the last codec
+// obtained.
+func (s *SchemaRegistryEncode) getCodec() (int, *goavro.Codec, error) {
+	s.schemaMut.Lock()
+	defer s.schemaMut.Unlock()
+
+	if s.codec != nil {
+		if s.version != "latest" || time.Since(s.lastFetched) < s.refreshPeriod {
+			return s.schemaID, s.codec, nil
+		}
+	}
+
+	id, codec, err := s.client.codecBySubject(s.subject, s.version)
+	if err != nil {
+		if s.codec == nil {
+			return 0, nil, err
+		}
+		s.log.Errorf("Failed to refresh schema for subject '%v': %v\n", s.subject, err)
+		return s.schemaID, s.codec, nil
+	}
+
+	s.schemaID, s.codec, s.lastFetched = id, codec, time.Now()
+	return id, codec, nil
+}
+
+func (s *SchemaRegistryEncode) encode(part types.Part) error {
+	id, codec, err := s.getCodec()
+	if err != nil {
+		s.mErrSchema.Incr(1)
+		return fmt.Errorf("failed to obtain schema: %v", err)
+	}
+
+	native, _, err := codec.NativeFromTextual(part.Get())
+	if err != nil {
+		s.mErrEncode.Incr(1)
+		return fmt.Errorf("failed to convert json to avro: %v", err)
+	}
+	avroBytes, err := codec.BinaryFromNative(schemaRegistryEncodeHeader(id), native)
+	if err != nil {
+		s.mErrEncode.Incr(1)
+		return fmt.Errorf("failed to encode avro: %v", err)
+	}
+
+	part.Set(avroBytes)
+	return nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *SchemaRegistryEncode) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int) {
+		if err := s.encode(newMsg.Get(index)); err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to encode part: %v\n", err)
+			FlagFail(newMsg.Get(index))
+		}
+	}
+
+	if len(s.parts) == 0 {
+		for i := 0; i < newMsg.Len(); i++ {
+			proc(i)
+		}
+	} else {
+		for _, i := range s.parts {
+			proc(i)
+		}
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *SchemaRegistryEncode) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *SchemaRegistryEncode) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,262 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+const testSchemaRegistrySchemaV1 = `{
+	"type": "record",
+	"name": "person",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int"}
+	]
+}`
+
+const testSchemaRegistrySchemaV2 = `{
+	"type": "record",
+	"name": "person",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "age", "type": "int"},
+		{"name": "nick", "type": ["null", "string"], "default": null}
+	]
+}`
+
+func testSchemaRegistryServer(t *testing.T, reqCount *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(reqCount, 1)
+		if user, pass, _ := r.BasicAuth(); user != "foo" || pass != "bar" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var res interface{}
+		switch r.URL.Path {
+		case "/subjects/people/versions/1":
+			res = map[string]interface{}{"id": 3, "version": 1, "schema": testSchemaRegistrySchemaV1}
+		case "/subjects/people/versions/latest":
+			res = map[string]interface{}{"id": 4, "version": 2, "schema": testSchemaRegistrySchemaV2}
+		case "/schemas/ids/3":
+			res = map[string]interface{}{"schema": testSchemaRegistrySchemaV1}
+		case "/schemas/ids/4":
+			res = map[string]interface{}{"schema": testSchemaRegistrySchemaV2}
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+}
+
+func TestSchemaRegistryRoundTrip(t *testing.T) {
+	var reqCount int32
+	ts := testSchemaRegistryServer(t, &reqCount)
+	defer ts.Close()
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	tests := []struct {
+		version string
+		id      byte
+		input   string
+		output  string
+	}{
+		{
+			version: "1",
+			id:      3,
+			input:   `{"name":"foo","age":10}`,
+			output:  `{"age":10,"name":"foo"}`,
+		},
+		{
+			version: "latest",
+			id:      4,
+			input:   `{"name":"foo","age":10,"nick":{"string":"f"}}`,
+			output:  `{"age":10,"name":"foo","nick":{"string":"f"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		encConf := NewConfig()
+		encConf.SchemaRegistryEncode.URL = ts.URL
+		encConf.SchemaRegistryEncode.Subject = "people"
+		encConf.SchemaRegistryEncode.Version = test.version
+		encConf.SchemaRegistryEncode.BasicAuth.Enabled = true
+		encConf.SchemaRegistryEncode.BasicAuth.Username = "foo"
+		encConf.SchemaRegistryEncode.BasicAuth.Password = "bar"
+
+		enc, err := NewSchemaRegistryEncode(encConf, nil, testLog, metrics.DudType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decConf := NewConfig()
+		decConf.SchemaRegistryDecode.URL = ts.URL
+		decConf.SchemaRegistryDecode.BasicAuth = encConf.SchemaRegistryEncode.BasicAuth
+
+		dec, err := NewSchemaRegistryDecode(decConf, nil, testLog, metrics.DudType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msgs, res := enc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Wrong encode result for version %v", test.version)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Fatalf("Encode failed for version %v", test.version)
+		}
+		encoded := msgs[0].Get(0).Get()
+		if exp, act := []byte{0, 0, 0, 0, test.id}, encoded[:5]; string(exp) != string(act) {
+			t.Errorf("Wrong header for version %v: %v != %v", test.version, act, exp)
+		}
+
+		msgs, res = dec.ProcessMessage(msgs[0])
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Wrong decode result for version %v", test.version)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Fatalf("Decode failed for version %v", test.version)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong output for version %v: %v != %v", test.version, act, exp)
+		}
+		if exp, act := string('0'+test.id), msgs[0].Get(0).Metadata().Get("schema_registry_id"); exp != act {
+			t.Errorf("Wrong schema id metadata: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestSchemaRegistryCaching(t *testing.T) {
+	var reqCount int32
+	ts := testSchemaRegistryServer(t, &reqCount)
+	defer ts.Close()
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	encConf := NewConfig()
+	encConf.SchemaRegistryEncode.URL = ts.URL
+	encConf.SchemaRegistryEncode.Subject = "people"
+	encConf.SchemaRegistryEncode.BasicAuth.Enabled = true
+	encConf.SchemaRegistryEncode.BasicAuth.Username = "foo"
+	encConf.SchemaRegistryEncode.BasicAuth.Password = "bar"
+
+	enc, err := NewSchemaRegistryEncode(encConf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decConf := NewConfig()
+	decConf.SchemaRegistryDecode.URL = ts.URL
+	decConf.SchemaRegistryDecode.BasicAuth = encConf.SchemaRegistryEncode.BasicAuth
+
+	dec, err := NewSchemaRegistryDecode(decConf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		msgs, _ := enc.ProcessMessage(message.New([][]byte{
+			[]byte(`{"name":"foo","age":10,"nick":null}`),
+			[]byte(`{"name":"bar","age":20,"nick":null}`),
+		}))
+		msgs, _ = dec.ProcessMessage(msgs[0])
+		for j := 0; j < 2; j++ {
+			if HasFailed(msgs[0].Get(j)) {
+				t.Fatalf("Part %v failed", j)
+			}
+		}
+	}
+
+	if exp, act := int32(2), atomic.LoadInt32(&reqCount); exp != act {
+		t.Errorf("Wrong count of registry requests: %v != %v", act, exp)
+	}
+}
+
+func TestSchemaRegistryErrors(t *testing.T) {
+	var reqCount int32
+	ts := testSchemaRegistryServer(t, &reqCount)
+	defer ts.Close()
+
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	encConf := NewConfig()
+	encConf.SchemaRegistryEncode.URL = ts.URL
+	encConf.SchemaRegistryEncode.Subject = "people"
+
+	enc, err := NewSchemaRegistryEncode(encConf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := enc.ProcessMessage(message.New([][]byte{[]byte(`{"name":"foo","age":10}`)}))
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected failure without basic auth")
+	}
+	if exp, act := `{"name":"foo","age":10}`, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong output: %v != %v", act, exp)
+	}
+
+	decConf := NewConfig()
+	decConf.SchemaRegistryDecode.URL = ts.URL
+	decConf.SchemaRegistryDecode.BasicAuth.Enabled = true
+	decConf.SchemaRegistryDecode.BasicAuth.Username = "foo"
+	decConf.SchemaRegistryDecode.BasicAuth.Password = "bar"
+
+	dec, err := NewSchemaRegistryDecode(decConf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ = dec.ProcessMessage(message.New([][]byte{
+		[]byte(`not avro`),
+		{0, 0, 0, 0, 9, 1, 2},
+		{0, 0, 0, 0, 3, 200},
+	}))
+	for i := 0; i < 3; i++ {
+		if !HasFailed(msgs[0].Get(i)) {
+			t.Errorf("Expected part %v to fail", i)
+		}
+	}
+
+	encConf.SchemaRegistryEncode.Subject = ""
+	if _, err = NewSchemaRegistryEncode(encConf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from missing subject")
+	}
+
+	encConf.SchemaRegistryEncode.Subject = "people"
+	encConf.SchemaRegistryEncode.Version = "nope"
+	if _, err = NewSchemaRegistryEncode(encConf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad version")
+	}
+}
@@ -0,0 +1,410 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/metrics"
+	"github.com/Jeffail/benthos/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeXML] = TypeSpec{
+		constructor: NewXML,
+		description: `
+Converts message parts between XML and JSON. The operator ` + "`to_json`" + `
+converts XML documents into JSON, and ` + "`from_json`" + ` converts JSON
+documents back into XML.
+
+The JSON representation of a document is an object with a single key, the name
+of the root element. Elements are converted according to the following rules:
+
+- Elements without attributes or child elements become strings of their text.
+- Attributes become fields prefixed with a hyphen, e.g. ` + "`-id`" + `.
+- Child elements become fields of their name, and children that share a name
+  become an array.
+- Text within an element that also has attributes or child elements becomes
+  the field ` + "`#text`" + `.
+- Names keep any namespace prefix as written in the document (e.g.
+  ` + "`soap:Body`" + `), and namespace declarations are kept as attributes
+  (e.g. ` + "`-xmlns:soap`" + `).
+
+Whitespace surrounding text is trimmed, comments and processing instructions are
+dropped, and all values are strings.
+
+For example, the document:
+
+` + "``` xml" + `
+<root>
+  <title>This is a title</title>
+  <content tag="foo">This is some content</content>
+  <item>a</item>
+  <item>b</item>
+</root>
+` + "```" + `
+
+Becomes:
+
+` + "``` json" + `
+{
+  "root": {
+    "title": "This is a title",
+    "content": {
+      "#text": "This is some content",
+      "-tag": "foo"
+    },
+    "item": ["a", "b"]
+  }
+}
+` + "```" + `
+
+When converting from JSON the same rules are applied in reverse, with fields
+written in sorted order.
+
+Parts that fail to convert are left unchanged and flagged as having failed, and
+can therefore be handled using
+[error handling patterns](../error_handling.md).`,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// XMLConfig contains configuration fields for the XML processor.
+type XMLConfig struct {
+	Parts    []int  `json:"parts" yaml:"parts"`
+	Operator string `json:"operator" yaml:"operator"`
+}
+
+// NewXMLConfig returns a XMLConfig with default values.
+func NewXMLConfig() XMLConfig {
+	return XMLConfig{
+		Parts:    []int{},
+		Operator: "to_json",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	xmlAttrPrefix = "-"
+	xmlTextKey    = "#text"
+)
+
+func xmlName(n xml.Name) string {
+	if len(n.Space) > 0 {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// xmlAddChild adds a value to an element object, converting the field into an
+// array when the name has already been seen.
+func xmlAddChild(obj map[string]interface{}, name string, v interface{}) {
+	existing, exists := obj[name]
+	if !exists {
+		obj[name] = v
+		return
+	}
+	if arr, isArr := existing.([]interface{}); isArr {
+		obj[name] = append(arr, v)
+		return
+	}
+	obj[name] = []interface{}{existing, v}
+}
+
+// xmlDecodeElement consumes tokens until the end of the element started by
+// start, returning its JSON representation.
+func xmlDecodeElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	obj := map[string]interface{}{}
+	for _, attr := range start.Attr {
+		obj[xmlAttrPrefix+xmlName(attr.Name)] = attr.Value
+	}
+
+	var text bytes.Buffer
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("unexpected EOF within element '%v'", xmlName(start.Name))
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := xmlDecodeElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			xmlAddChild(obj, xmlName(t.Name), child)
+		case xml.EndElement:
+			if t.Name != start.Name {
+				return nil, fmt.Errorf("element '%v' closed by '%v'", xmlName(start.Name), xmlName(t.Name))
+			}
+			textStr := strings.TrimSpace(text.String())
+			if len(obj) == 0 {
+				return textStr, nil
+			}
+			if len(textStr) > 0 {
+				obj[xmlTextKey] = textStr
+			}
+			return obj, nil
+		case xml.CharData:
+			text.Write(t)
+		}
+	}
+}
+
+func xmlToJSON(part types.Part) error {
+	dec := xml.NewDecoder(bytes.NewReader(part.Get()))
+	dec.Strict = true
+
+	var root map[string]interface{}
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse xml: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil {
+				return errors.New("failed to parse xml: document has multiple root elements")
+			}
+			v, err := xmlDecodeElement(dec, t)
+			if err != nil {
+				return fmt.Errorf("failed to parse xml: %v", err)
+			}
+			root = map[string]interface{}{xmlName(t.Name): v}
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return errors.New("failed to parse xml: text found outside of root element")
+			}
+		}
+	}
+	if root == nil {
+		return errors.New("failed to parse xml: no root element found")
+	}
+
+	if err := part.SetJSON(root); err != nil {
+		return fmt.Errorf("failed to set json: %v", err)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func xmlValueToString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case float64, bool:
+		return fmt.Sprintf("%v", t), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("value of type %T cannot be used as text", v)
+}
+
+func xmlEncodeElement(buf *bytes.Buffer, name string, v interface{}) error {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			if _, isArr := e.([]interface{}); isArr {
+				return fmt.Errorf("element '%v' contains nested arrays", name)
+			}
+			if err := xmlEncodeElement(buf, name, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("<" + name)
+		for _, k := range keys {
+			if !strings.HasPrefix(k, xmlAttrPrefix) {
+				continue
+			}
+			attrStr, err := xmlValueToString(t[k])
+			if err != nil {
+				return fmt.Errorf("attribute '%v' of element '%v': %v", k, name, err)
+			}
+			buf.WriteString(" " + strings.TrimPrefix(k, xmlAttrPrefix) + `="`)
+			xml.EscapeText(buf, []byte(attrStr))
+			buf.WriteString(`"`)
+		}
+		buf.WriteString(">")
+
+		if text, exists := t[xmlTextKey]; exists {
+			textStr, err := xmlValueToString(text)
+			if err != nil {
+				return fmt.Errorf("text of element '%v': %v", name, err)
+			}
+			xml.EscapeText(buf, []byte(textStr))
+		}
+		for _, k := range keys {
+			if strings.HasPrefix(k, xmlAttrPrefix) || k == xmlTextKey {
+				continue
+			}
+			if err := xmlEncodeElement(buf, k, t[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("</" + name + ">")
+		return nil
+	}
+
+	textStr, err := xmlValueToString(v)
+	if err != nil {
+		return fmt.Errorf("element '%v': %v", name, err)
+	}
+	buf.WriteString("<" + name + ">")
+	xml.EscapeText(buf, []byte(textStr))
+	buf.WriteString("</" + name + ">")
+	return nil
+}
+
+func xmlFromJSON(part types.Part) error {
+	jsonPart, err := part.JSON()
+	if err != nil {
+		return fmt.Errorf("failed to parse part into json: %v", err)
+	}
+
+	root, ok := jsonPart.(map[string]interface{})
+	if !ok || len(root) != 1 {
+		return errors.New("document must be an object with a single root element field")
+	}
+
+	var buf bytes.Buffer
+	for name, v := range root {
+		if _, isArr := v.([]interface{}); isArr {
+			return errors.New("root element must not be an array")
+		}
+		if err = xmlEncodeElement(&buf, name, v); err != nil {
+			return fmt.Errorf("failed to encode xml: %v", err)
+		}
+	}
+	part.Set(buf.Bytes())
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// XML is a processor that converts message parts between XML and JSON.
+type XML struct {
+	parts    []int
+	operator func(part types.Part) error
+
+	log   log.Modular
+	stats metrics.Type
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewXML returns a XML processor.
+func NewXML(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	x := &XML{
+		parts: conf.XML.Parts,
+		log:   log,
+		stats: stats,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	switch conf.XML.Operator {
+	case "to_json":
+		x.operator = xmlToJSON
+	case "from_json":
+		x.operator = xmlFromJSON
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.XML.Operator)
+	}
+	return x, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (x *XML) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	x.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int) {
+		if err := x.operator(newMsg.Get(index)); err != nil {
+			x.mErr.Incr(1)
+			x.log.Debugf("Failed to convert part: %v\n", err)
+			FlagFail(newMsg.Get(index))
+		}
+	}
+
+	if len(x.parts) == 0 {
+		for i := 0; i < newMsg.Len(); i++ {
+			proc(i)
+		}
+	} else {
+		for _, i := range x.parts {
+			proc(i)
+		}
+	}
+
+	msgs := [1]types.Message{newMsg}
+
+	x.mBatchSent.Incr(1)
+	x.mSent.Incr(int64(newMsg.Len()))
+	return msgs[:], nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (x *XML) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (x *XML) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
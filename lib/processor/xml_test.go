@@ -0,0 +1,205 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package processor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Jeffail/benthos/lib/log"
+	"github.com/Jeffail/benthos/lib/message"
+	"github.com/Jeffail/benthos/lib/metrics"
+)
+
+func TestXMLToJSON(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.XML.Operator = "to_json"
+
+	proc, err := NewXML(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{
+			name: "basic",
+			input: `<?xml version="1.0" encoding="UTF-8"?>
+<root>
+  <title>This is a title</title>
+  <content tag="foo">This is some content</content>
+  <item>a</item>
+  <item>b</item>
+  <empty/>
+</root>`,
+			output: `{"root":{"content":{"#text":"This is some content","-tag":"foo"},"empty":"","item":["a","b"],"title":"This is a title"}}`,
+		},
+		{
+			name: "namespaces",
+			input: `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+  <soap:Body><m:Price xmlns:m="https://example.com/prices">1.5</m:Price></soap:Body>
+</soap:Envelope>`,
+			output: `{"soap:Envelope":{"-xmlns:soap":"http://www.w3.org/2003/05/soap-envelope","soap:Body":{"m:Price":{"#text":"1.5","-xmlns:m":"https://example.com/prices"}}}}`,
+		},
+		{
+			name:   "mixed content and comments",
+			input:  `<a><!-- ignored -->foo<b>bar</b> &amp; baz</a>`,
+			output: `{"a":{"#text":"foo \u0026 baz","b":"bar"}}`,
+		},
+		{
+			name:   "three repeats",
+			input:  `<a><b>1</b><c/><b>2</b><b>3</b></a>`,
+			output: `{"a":{"b":["1","2","3"],"c":""}}`,
+		},
+	}
+
+	for _, test := range tests {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if len(msgs) != 1 || res != nil {
+			t.Fatalf("Wrong result for test '%v'", test.name)
+		}
+		if HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Test '%v' failed", test.name)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result for test '%v': %v != %v", test.name, act, exp)
+		}
+	}
+}
+
+func TestXMLToJSONErrors(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.XML.Operator = "to_json"
+
+	proc, err := NewXML(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []string{
+		`not xml`,
+		`<a><b></a>`,
+		`<a>foo`,
+		`<a/><b/>`,
+		``,
+	}
+	for _, input := range inputs {
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if !HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Expected input to fail: %v", input)
+		}
+		if exp, act := input, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Failed part was modified: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestXMLFromJSON(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.XML.Operator = "from_json"
+
+	proc, err := NewXML(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{
+			input:  `{"root":{"content":{"#text":"This is some content","-tag":"foo"},"empty":"","item":["a","b"],"title":"This is a title"}}`,
+			output: `<root><content tag="foo">This is some content</content><empty></empty><item>a</item><item>b</item><title>This is a title</title></root>`,
+		},
+		{
+			input:  `{"a":{"-n":5,"-b":true,"c":null,"#text":"x < y"}}`,
+			output: `<a b="true" n="5">x &lt; y<c></c></a>`,
+		},
+		{
+			input:  `{"a":"\"quoted\""}`,
+			output: `<a>&#34;quoted&#34;</a>`,
+		},
+	}
+
+	for _, test := range tests {
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(test.input)}))
+		if HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Input failed: %v", test.input)
+		}
+		if exp, act := test.output, string(msgs[0].Get(0).Get()); exp != act {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+	}
+
+	badInputs := []string{
+		`not json`,
+		`{"a":"b","c":"d"}`,
+		`["a"]`,
+		`{"a":["b","c"]}`,
+		`{"a":{"-b":{"c":"d"}}}`,
+		`{"a":{"b":[["c"]]}}`,
+	}
+	for _, input := range badInputs {
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+		if !HasFailed(msgs[0].Get(0)) {
+			t.Errorf("Expected input to fail: %v", input)
+		}
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	testLog := log.New(os.Stdout, log.Config{LogLevel: "NONE"})
+
+	conf := NewConfig()
+	conf.XML.Operator = "to_json"
+	toJSON, err := NewXML(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.XML.Operator = "from_json"
+	fromJSON, err := NewXML(conf, nil, testLog, metrics.DudType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><item id="1">a</item><item id="2">b</item></soap:Body></soap:Envelope>`
+
+	msgs, _ := toJSON.ProcessMessage(message.New([][]byte{[]byte(input)}))
+	msgs, _ = fromJSON.ProcessMessage(msgs[0])
+	if exp, act := input, string(msgs[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	conf.XML.Operator = "nope"
+	if _, err = NewXML(conf, nil, testLog, metrics.DudType{}); err == nil {
+		t.Error("Expected error from bad operator")
+	}
+}
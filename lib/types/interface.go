@@ -51,6 +51,16 @@ type Cache interface {
 	Delete(key string) error
 }
 
+// CacheWithTTL is a Cache that supports setting the TTL of individual keys,
+// overriding the default TTL of the cache.
+type CacheWithTTL interface {
+	Cache
+
+	// SetWithTTL attempts to set the value of a key with a specific TTL,
+	// returns an error if the command fails.
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
+}
+
 //------------------------------------------------------------------------------
 
 // RateLimit is a strategy for limiting access to a shared resource, this
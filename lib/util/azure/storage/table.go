@@ -0,0 +1,145 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+)
+
+//------------------------------------------------------------------------------
+
+// MaxTableBatchSize is the maximum number of entities that can be written
+// within a single entity group transaction.
+const MaxTableBatchSize = 100
+
+// Operations supported when writing entities to a table.
+const (
+	TableInsert          = "INSERT"
+	TableInsertOrMerge   = "INSERT_OR_MERGE"
+	TableInsertOrReplace = "INSERT_OR_REPLACE"
+)
+
+// TableEntity is an entity of a table identified by its partition and row
+// keys.
+type TableEntity struct {
+	PartitionKey string
+	RowKey       string
+	Properties   map[string]interface{}
+}
+
+// TableClient writes entities to the tables of a storage account.
+type TableClient struct {
+	svc     *aztables.ServiceClient
+	timeout time.Duration
+}
+
+// NewTableClient creates a TableClient from the config. The endpoint defaults
+// to the table service of the storage account, and can be set to a Cosmos DB
+// Table API endpoint instead.
+func (c Config) NewTableClient() (*TableClient, error) {
+	serviceURL, err := c.serviceURL("https://%v.table.core.windows.net/")
+	if err != nil {
+		return nil, err
+	}
+
+	var svc *aztables.ServiceClient
+	switch {
+	case len(c.SASToken) > 0:
+		svc, err = aztables.NewServiceClientWithNoCredential(withSASToken(serviceURL, c.SASToken), nil)
+	case c.UseManagedIdentity:
+		cred, cErr := c.identityCredential()
+		if cErr != nil {
+			return nil, cErr
+		}
+		svc, err = aztables.NewServiceClient(serviceURL, cred, nil)
+	default:
+		cred, cErr := aztables.NewSharedKeyCredential(c.Account, c.AccessKey)
+		if cErr != nil {
+			return nil, fmt.Errorf("failed to decode storage access key: %v", cErr)
+		}
+		svc, err = aztables.NewServiceClientWithSharedKey(serviceURL, cred, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TableClient{svc: svc, timeout: defaultTimeout}, nil
+}
+
+// WriteTableBatch writes entities to a table within a single entity group
+// transaction, where either all of the entities are written or none of them
+// are. All entities must share the same partition key, a row key may only
+// appear once, and at most MaxTableBatchSize entities may be written.
+func (c *TableClient) WriteTableBatch(table, operation string, entities []TableEntity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	if len(entities) > MaxTableBatchSize {
+		return fmt.Errorf("entity group transactions are limited to %v entities, received %v", MaxTableBatchSize, len(entities))
+	}
+	for _, e := range entities[1:] {
+		if e.PartitionKey != entities[0].PartitionKey {
+			return errors.New("entities of an entity group transaction must share the same partition key")
+		}
+	}
+
+	var actionType aztables.TransactionType
+	switch operation {
+	case TableInsert:
+		actionType = aztables.TransactionTypeAdd
+	case TableInsertOrMerge:
+		actionType = aztables.TransactionTypeInsertMerge
+	case TableInsertOrReplace:
+		actionType = aztables.TransactionTypeInsertReplace
+	default:
+		return fmt.Errorf("table operation not recognised: %v", operation)
+	}
+
+	actions := make([]aztables.TransactionAction, 0, len(entities))
+	for _, e := range entities {
+		props := make(map[string]interface{}, len(e.Properties)+2)
+		for k, v := range e.Properties {
+			props[k] = v
+		}
+		props["PartitionKey"] = e.PartitionKey
+		props["RowKey"] = e.RowKey
+		body, err := json.Marshal(props)
+		if err != nil {
+			return fmt.Errorf("failed to serialise entity: %v", err)
+		}
+		actions = append(actions, aztables.TransactionAction{
+			ActionType: actionType,
+			Entity:     body,
+		})
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), c.timeout)
+	defer done()
+	_, err := c.svc.NewClient(table).SubmitTransaction(ctx, actions, nil)
+	return err
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTableClientWriteBatch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKeyLite foo:") {
+			t.Errorf("Wrong authorization header: %v", r.Header.Get("Authorization"))
+		}
+		if exp, act := "/$batch", r.URL.Path; exp != act {
+			t.Errorf("Wrong path: %v != %v", act, exp)
+		}
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/mixed; boundary=batch_") {
+			t.Errorf("Wrong content type: %v", r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		for _, exp := range []string{
+			"PATCH " + "http://" + r.Host + "/bar(PartitionKey='baz',RowKey='it%27s%20one') HTTP/1.1",
+			"PATCH " + "http://" + r.Host + "/bar(PartitionKey='baz',RowKey='2') HTTP/1.1",
+			`{"PartitionKey":"baz","RowKey":"2","value":"second"}`,
+		} {
+			if !strings.Contains(string(body), exp) {
+				t.Errorf("Body missing '%v': %s", exp, body)
+			}
+		}
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=batchresponse_1")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("--batchresponse_1\r\nContent-Type: multipart/mixed; boundary=changesetresponse_1\r\n\r\n--changesetresponse_1\r\nContent-Type: application/http\r\n\r\nHTTP/1.1 204 No Content\r\nDataServiceVersion: 1.0;\r\n\r\n\r\n--changesetresponse_1\r\nContent-Type: application/http\r\n\r\nHTTP/1.1 204 No Content\r\nDataServiceVersion: 1.0;\r\n\r\n\r\n--changesetresponse_1--\r\n--batchresponse_1--\r\n"))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Account = "foo"
+	conf.AccessKey = "c2VjcmV0"
+	conf.Endpoint = ts.URL
+
+	c, err := conf.NewTableClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.WriteTableBatch("bar", TableInsertOrMerge, []TableEntity{
+		{PartitionKey: "baz", RowKey: "it's one", Properties: map[string]interface{}{"value": "first"}},
+		{PartitionKey: "baz", RowKey: "2", Properties: map[string]interface{}{"value": "second"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableClientWriteBatchFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=batchresponse_1")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("--batchresponse_1\r\nContent-Type: multipart/mixed; boundary=changesetresponse_1\r\n\r\n--changesetresponse_1\r\nContent-Type: application/http\r\n\r\nHTTP/1.1 409 Conflict\r\nContent-Type: application/json\r\n\r\n{\"odata.error\":{\"code\":\"EntityAlreadyExists\"}}\r\n\r\n--changesetresponse_1--\r\n--batchresponse_1--\r\n"))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Account = "foo"
+	conf.AccessKey = "c2VjcmV0"
+	conf.Endpoint = ts.URL
+
+	c, err := conf.NewTableClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.WriteTableBatch("bar", TableInsert, []TableEntity{
+		{PartitionKey: "baz", RowKey: "1"},
+	})
+	if err == nil {
+		t.Fatal("Expected error from conflict")
+	}
+	if !strings.Contains(err.Error(), "EntityAlreadyExists") {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	if err = c.WriteTableBatch("bar", TableInsert, []TableEntity{
+		{PartitionKey: "baz", RowKey: "1"},
+		{PartitionKey: "buz", RowKey: "2"},
+	}); err == nil {
+		t.Error("Expected error from mixed partition keys")
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package storage provides Benthos configuration fields and clients for Azure
+// Blob and Table Storage built on the Azure SDK.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrBlobNotFound is returned when a requested blob does not exist.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// defaultTimeout is the maximum duration of each request made by the clients.
+const defaultTimeout = time.Second * 30
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration fields for accessing an Azure storage
+// account.
+type Config struct {
+	Account                 string `json:"storage_account" yaml:"storage_account"`
+	AccessKey               string `json:"storage_access_key" yaml:"storage_access_key"`
+	SASToken                string `json:"storage_sas_token" yaml:"storage_sas_token"`
+	UseManagedIdentity      bool   `json:"storage_use_managed_identity" yaml:"storage_use_managed_identity"`
+	ManagedIdentityClientID string `json:"storage_managed_identity_client_id" yaml:"storage_managed_identity_client_id"`
+	Endpoint                string `json:"storage_endpoint" yaml:"storage_endpoint"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		Account:                 "",
+		AccessKey:               "",
+		SASToken:                "",
+		UseManagedIdentity:      false,
+		ManagedIdentityClientID: "",
+		Endpoint:                "",
+	}
+}
+
+// Documentation describes the authentication methods supported by Config.
+const Documentation = `### Authentication
+
+Requests are authorized with the shared key of the storage account set with
+` + "`storage_access_key`" + ` by default. Alternatively, a shared access
+signature token can be set with ` + "`storage_sas_token`" + `, which must grant
+the permissions required by the component. When running within Azure, setting
+` + "`storage_use_managed_identity`" + ` to ` + "`true`" + ` obtains tokens for
+the managed identity of the host instead, where
+` + "`storage_managed_identity_client_id`" + ` selects a user assigned
+identity.`
+
+// serviceURL returns the endpoint of a storage service, which is derived from
+// the storage account unless an endpoint is configured.
+func (c Config) serviceURL(defaultEndpoint string) (string, error) {
+	if len(c.Account) == 0 {
+		return "", errors.New("a storage account must be specified")
+	}
+	endpoint := c.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = fmt.Sprintf(defaultEndpoint, c.Account)
+	}
+	return strings.TrimSuffix(endpoint, "/") + "/", nil
+}
+
+// identityCredential returns a credential for the managed identity of the
+// host, where a client ID selects a user assigned identity.
+func (c Config) identityCredential() (azcore.TokenCredential, error) {
+	opts := &azidentity.ManagedIdentityCredentialOptions{}
+	if len(c.ManagedIdentityClientID) > 0 {
+		opts.ID = azidentity.ClientID(c.ManagedIdentityClientID)
+	}
+	return azidentity.NewManagedIdentityCredential(opts)
+}
+
+// withSASToken adds a shared access signature token to a service URL.
+func withSASToken(serviceURL, token string) string {
+	return serviceURL + "?" + strings.TrimPrefix(token, "?")
+}
+
+// NewClient creates a blob storage Client from the config.
+func (c Config) NewClient() (*Client, error) {
+	serviceURL, err := c.serviceURL("https://%v.blob.core.windows.net/")
+	if err != nil {
+		return nil, err
+	}
+
+	var svc *service.Client
+	switch {
+	case len(c.SASToken) > 0:
+		svc, err = service.NewClientWithNoCredential(withSASToken(serviceURL, c.SASToken), nil)
+	case c.UseManagedIdentity:
+		cred, cErr := c.identityCredential()
+		if cErr != nil {
+			return nil, cErr
+		}
+		svc, err = service.NewClient(serviceURL, cred, nil)
+	default:
+		cred, cErr := service.NewSharedKeyCredential(c.Account, c.AccessKey)
+		if cErr != nil {
+			return nil, fmt.Errorf("failed to decode storage access key: %v", cErr)
+		}
+		svc, err = service.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Client{svc: svc, timeout: defaultTimeout}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Blob contains the properties and metadata of a blob.
+type Blob struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
+// Client wraps an Azure Blob Storage service client with the operations used
+// by Benthos components.
+type Client struct {
+	svc     *service.Client
+	timeout time.Duration
+}
+
+// Container returns a client for a container of the storage account.
+func (c *Client) Container(name string) *container.Client {
+	return c.svc.NewContainerClient(name)
+}
+
+func (c *Client) ctx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+func blobErr(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+func metaFromPtrs(m map[string]*string) map[string]string {
+	meta := make(map[string]string, len(m))
+	for k, v := range m {
+		if v != nil {
+			meta[strings.ToLower(k)] = *v
+		}
+	}
+	return meta
+}
+
+//------------------------------------------------------------------------------
+
+// CreateContainer creates a container if it does not already exist.
+func (c *Client) CreateContainer(name string) error {
+	ctx, done := c.ctx()
+	defer done()
+
+	_, err := c.svc.CreateContainer(ctx, name, nil)
+	if bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// GetBlob returns the contents and properties of a blob.
+func (c *Client) GetBlob(containerName, blobName string) ([]byte, *Blob, error) {
+	ctx, done := c.ctx()
+	defer done()
+
+	res, err := c.Container(containerName).NewBlobClient(blobName).DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, nil, blobErr(err)
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	b := &Blob{
+		Name:     blobName,
+		Size:     int64(len(data)),
+		Metadata: metaFromPtrs(res.Metadata),
+	}
+	if res.LastModified != nil {
+		b.LastModified = *res.LastModified
+	}
+	return data, b, nil
+}
+
+// PutBlob writes the contents of a block blob, replacing it if it already
+// exists.
+func (c *Client) PutBlob(containerName, blobName string, data []byte, contentType string) error {
+	ctx, done := c.ctx()
+	defer done()
+
+	opts := &blockblob.UploadBufferOptions{}
+	if len(contentType) > 0 {
+		opts.HTTPHeaders = &blob.HTTPHeaders{BlobContentType: &contentType}
+	}
+	_, err := c.Container(containerName).NewBlockBlobClient(blobName).UploadBuffer(ctx, data, opts)
+	return blobErr(err)
+}
+
+// MaxAppendBlockSize is the largest block of data that can be appended to an
+// append blob with a single request.
+const MaxAppendBlockSize = 4 * 1024 * 1024
+
+// CreateAppendBlob creates an empty append blob if it does not already exist.
+func (c *Client) CreateAppendBlob(containerName, blobName, contentType string) error {
+	ctx, done := c.ctx()
+	defer done()
+
+	etagAny := azcore.ETagAny
+	opts := &appendblob.CreateOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &etagAny},
+		},
+	}
+	if len(contentType) > 0 {
+		opts.HTTPHeaders = &blob.HTTPHeaders{BlobContentType: &contentType}
+	}
+	_, err := c.Container(containerName).NewAppendBlobClient(blobName).Create(ctx, opts)
+	if bloberror.HasCode(err, bloberror.BlobAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// AppendBlock appends data to the end of an existing append blob, where data
+// larger than MaxAppendBlockSize is appended in multiple blocks.
+func (c *Client) AppendBlock(containerName, blobName string, data []byte) error {
+	client := c.Container(containerName).NewAppendBlobClient(blobName)
+	for len(data) > 0 {
+		block := data
+		if len(block) > MaxAppendBlockSize {
+			block = block[:MaxAppendBlockSize]
+		}
+		ctx, done := c.ctx()
+		_, err := client.AppendBlock(ctx, streaming.NopCloser(bytes.NewReader(block)), nil)
+		done()
+		if err != nil {
+			return blobErr(err)
+		}
+		data = data[len(block):]
+	}
+	return nil
+}
+
+// ListBlobs returns all blobs of a container with a name matching a prefix,
+// including their metadata.
+func (c *Client) ListBlobs(containerName, prefix string) ([]Blob, error) {
+	opts := &container.ListBlobsFlatOptions{
+		Include: container.ListBlobsInclude{Metadata: true},
+	}
+	if len(prefix) > 0 {
+		opts.Prefix = &prefix
+	}
+
+	var blobs []Blob
+	pager := c.Container(containerName).NewListBlobsFlatPager(opts)
+	for pager.More() {
+		ctx, done := c.ctx()
+		page, err := pager.NextPage(ctx)
+		done()
+		if err != nil {
+			return nil, blobErr(err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			b := Blob{
+				Name:     *item.Name,
+				Metadata: metaFromPtrs(item.Metadata),
+			}
+			if props := item.Properties; props != nil {
+				if props.ContentLength != nil {
+					b.Size = *props.ContentLength
+				}
+				if props.LastModified != nil {
+					b.LastModified = *props.LastModified
+				}
+			}
+			blobs = append(blobs, b)
+		}
+	}
+	return blobs, nil
+}
+
+//------------------------------------------------------------------------------
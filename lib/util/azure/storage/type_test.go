@@ -0,0 +1,178 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package storage
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClientListBlobs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey foo:") {
+			t.Errorf("Wrong authorization header: %v", r.Header.Get("Authorization"))
+		}
+		if exp, act := "/bar", r.URL.Path; exp != act {
+			t.Errorf("Wrong path: %v != %v", act, exp)
+		}
+		if exp, act := "baz/", r.URL.Query().Get("prefix"); exp != act {
+			t.Errorf("Wrong prefix: %v != %v", act, exp)
+		}
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<EnumerationResults ServiceEndpoint="http://foo" ContainerName="bar">
+  <Blobs>
+    <Blob>
+      <Name>baz/0</Name>
+      <Properties>
+        <Content-Length>10</Content-Length>
+      </Properties>
+      <Metadata>
+        <owner>first</owner>
+      </Metadata>
+    </Blob>
+    <Blob>
+      <Name>baz/1</Name>
+      <Properties>
+        <Content-Length>20</Content-Length>
+      </Properties>
+    </Blob>
+  </Blobs>
+  <NextMarker />
+</EnumerationResults>`))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Account = "foo"
+	conf.AccessKey = "c2VjcmV0"
+	conf.Endpoint = ts.URL
+
+	c, err := conf.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs, err := c.ListBlobs("bar", "baz/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, len(blobs); exp != act {
+		t.Fatalf("Wrong count of blobs: %v != %v", act, exp)
+	}
+	if exp, act := "baz/0", blobs[0].Name; exp != act {
+		t.Errorf("Wrong blob name: %v != %v", act, exp)
+	}
+	if exp, act := "first", blobs[0].Metadata["owner"]; exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+	if exp, act := int64(20), blobs[1].Size; exp != act {
+		t.Errorf("Wrong size: %v != %v", act, exp)
+	}
+}
+
+func TestClientSASToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); len(auth) > 0 {
+			t.Errorf("Unexpected authorization header: %v", auth)
+		}
+		if exp, act := "abc", r.URL.Query().Get("sig"); exp != act {
+			t.Errorf("Wrong sig: %v != %v", act, exp)
+		}
+		if exp, act := "/bar/baz", r.URL.Path; exp != act {
+			t.Errorf("Wrong path: %v != %v", act, exp)
+		}
+		w.Header().Set("x-ms-meta-owner", "first")
+		w.Write([]byte("hello world"))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Account = "foo"
+	conf.SASToken = "?sv=2018-03-28&sp=rw&sig=abc"
+	conf.Endpoint = ts.URL
+
+	c, err := conf.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, b, err := c.GetBlob("bar", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(data); exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+	if exp, act := "first", b.Metadata["owner"]; exp != act {
+		t.Errorf("Wrong metadata: %v != %v", act, exp)
+	}
+}
+
+func TestClientAppendBlob(t *testing.T) {
+	var blocks []int
+	created := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("comp") == "appendblock" {
+			body, _ := ioutil.ReadAll(r.Body)
+			blocks = append(blocks, len(body))
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if exp, act := "AppendBlob", r.Header.Get("x-ms-blob-type"); exp != act {
+			t.Errorf("Wrong blob type: %v != %v", act, exp)
+		}
+		if exp, act := "*", r.Header.Get("If-None-Match"); exp != act {
+			t.Errorf("Wrong if-none-match: %v != %v", act, exp)
+		}
+		if created {
+			w.Header().Set("x-ms-error-code", "BlobAlreadyExists")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		created = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Account = "foo"
+	conf.AccessKey = "c2VjcmV0"
+	conf.Endpoint = ts.URL
+
+	c, err := conf.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = c.CreateAppendBlob("bar", "baz", "text/plain"); err != nil {
+			t.Error(err)
+		}
+	}
+	if err = c.AppendBlock("bar", "baz", make([]byte, MaxAppendBlockSize+10)); err != nil {
+		t.Error(err)
+	}
+	if exp, act := []int{MaxAppendBlockSize, 10}, blocks; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong appended blocks: %v != %v", act, exp)
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package cron implements parsing of cron expressions and calculation of their
+// activation times.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondBounds = bounds{0, 59, nil}
+	minuteBounds = bounds{0, 59, nil}
+	hourBounds   = bounds{0, 23, nil}
+	domBounds    = bounds{1, 31, nil}
+	monthBounds  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+//------------------------------------------------------------------------------
+
+// Schedule is a parsed cron expression which can calculate the next time at
+// which it activates.
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// Whether the day of month or day of week fields were restricted, which
+	// determines how the two are combined.
+	domStar, dowStar bool
+
+	every    time.Duration
+	location *time.Location
+}
+
+// Parse creates a Schedule from a cron expression, where activation times are
+// calculated within the provided location (UTC if nil).
+//
+// Expressions consist of either five fields (minute, hour, day of month, month,
+// day of week) or six fields with a leading seconds field. Each field supports
+// wildcards (* or ?), ranges (1-5), lists (1,3,5) and steps (*/15 or 10-40/5).
+// Month and day of week fields also accept three letter names (JAN, MON).
+//
+// The descriptors @yearly, @annually, @monthly, @weekly, @daily, @midnight and
+// @hourly are also supported, as is @every <duration> (e.g. @every 1h30m).
+func Parse(expr string, location *time.Location) (*Schedule, error) {
+	if location == nil {
+		location = time.UTC
+	}
+	expr = strings.TrimSpace(expr)
+	if len(expr) == 0 {
+		return nil, errors.New("empty cron expression")
+	}
+
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse @every duration: %v", err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("@every duration must be at least one second: %v", d)
+		}
+		return &Schedule{every: d, location: location}, nil
+	}
+	if strings.HasPrefix(expr, "@") {
+		var exists bool
+		if expr, exists = descriptors[strings.ToLower(expr)]; !exists {
+			return nil, fmt.Errorf("unrecognised descriptor: %v", expr)
+		}
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields, found %v: %v", len(fields), expr)
+	}
+
+	s := &Schedule{location: location}
+	var err error
+	if s.second, err = parseField(fields[0], secondBounds); err != nil {
+		return nil, fmt.Errorf("failed to parse seconds: %v", err)
+	}
+	if s.minute, err = parseField(fields[1], minuteBounds); err != nil {
+		return nil, fmt.Errorf("failed to parse minutes: %v", err)
+	}
+	if s.hour, err = parseField(fields[2], hourBounds); err != nil {
+		return nil, fmt.Errorf("failed to parse hours: %v", err)
+	}
+	if s.dom, err = parseField(fields[3], domBounds); err != nil {
+		return nil, fmt.Errorf("failed to parse day of month: %v", err)
+	}
+	if s.month, err = parseField(fields[4], monthBounds); err != nil {
+		return nil, fmt.Errorf("failed to parse month: %v", err)
+	}
+	if s.dow, err = parseField(fields[5], dowBounds); err != nil {
+		return nil, fmt.Errorf("failed to parse day of week: %v", err)
+	}
+
+	// Seven is an alias for Sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow = (s.dow | 1) &^ (1 << 7)
+	}
+	s.domStar = isWildcard(fields[3])
+	s.dowStar = isWildcard(fields[5])
+	return s, nil
+}
+
+func isWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+func parseValue(str string, b bounds) (int, error) {
+	if v, exists := b.names[strings.ToLower(str)]; exists {
+		return v, nil
+	}
+	v, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %v", str)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %v out of range [%v, %v]", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		if len(part) == 0 {
+			return 0, fmt.Errorf("empty list element: %v", field)
+		}
+
+		rangeStr, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step: %v", part)
+			}
+			rangeStr = part[:i]
+		}
+
+		start, end := b.min, b.max
+		if !isWildcard(rangeStr) {
+			var err error
+			if i := strings.Index(rangeStr, "-"); i >= 0 {
+				if start, err = parseValue(rangeStr[:i], b); err != nil {
+					return 0, err
+				}
+				if end, err = parseValue(rangeStr[i+1:], b); err != nil {
+					return 0, err
+				}
+				if end < start {
+					return 0, fmt.Errorf("range end is before start: %v", rangeStr)
+				}
+			} else {
+				if start, err = parseValue(rangeStr, b); err != nil {
+					return 0, err
+				}
+				end = start
+				// A single value with a step runs until the maximum.
+				if strings.Contains(part, "/") {
+					end = b.max
+				}
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+//------------------------------------------------------------------------------
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := has(s.dom, t.Day())
+	dowMatch := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the earliest activation time of the schedule that is strictly
+// after t, in the location of the schedule. A zero time is returned if the
+// schedule cannot be satisfied (e.g. the 30th of February).
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Truncate(time.Second).Add(s.every).In(s.location)
+	}
+
+	t = t.In(s.location)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for !has(s.month, int(t.Month())) {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for !has(s.hour, t.Hour()) {
+		// Adding an absolute hour rather than normalising the wall clock avoids
+		// getting stuck on hours skipped by daylight savings transitions.
+		t = t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second).Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for !has(s.minute, t.Minute()) {
+		t = t.Add(-time.Duration(t.Second()) * time.Second).Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	for !has(s.second, t.Second()) {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
+
+//------------------------------------------------------------------------------
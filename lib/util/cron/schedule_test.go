@@ -0,0 +1,133 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+func TestScheduleNext(t *testing.T) {
+	type testCase struct {
+		expr string
+		from string
+		exp  string
+	}
+
+	tests := []testCase{
+		{"* * * * *", "2019-01-01T10:00:00Z", "2019-01-01T10:01:00Z"},
+		{"* * * * *", "2019-01-01T10:00:30.5Z", "2019-01-01T10:01:00Z"},
+		{"* * * * * *", "2019-01-01T10:00:00.1Z", "2019-01-01T10:00:01Z"},
+		{"*/15 * * * *", "2019-01-01T10:07:00Z", "2019-01-01T10:15:00Z"},
+		{"5/20 * * * *", "2019-01-01T10:26:00Z", "2019-01-01T10:45:00Z"},
+		{"0 2 * * *", "2019-01-01T10:00:00Z", "2019-01-02T02:00:00Z"},
+		{"0 2 * * *", "2019-12-31T10:00:00Z", "2020-01-01T02:00:00Z"},
+		{"30 9-17/4 * * *", "2019-01-01T14:00:00Z", "2019-01-01T17:30:00Z"},
+		{"0 0 29 2 *", "2019-01-01T00:00:00Z", "2020-02-29T00:00:00Z"},
+		{"0 0 * * MON", "2019-01-01T00:00:00Z", "2019-01-07T00:00:00Z"},
+		{"0 0 * * 7", "2019-01-01T00:00:00Z", "2019-01-06T00:00:00Z"},
+		{"0 0 * * mon-fri", "2019-01-05T00:00:00Z", "2019-01-07T00:00:00Z"},
+		{"0 0 1 JAN,jul *", "2019-02-01T00:00:00Z", "2019-07-01T00:00:00Z"},
+		{"0 0 13 * FRI", "2019-01-01T00:00:00Z", "2019-01-04T00:00:00Z"},
+		{"0 0 13 * ?", "2019-01-01T00:00:00Z", "2019-01-13T00:00:00Z"},
+		{"@hourly", "2019-01-01T10:20:00Z", "2019-01-01T11:00:00Z"},
+		{"@daily", "2019-01-01T10:20:00Z", "2019-01-02T00:00:00Z"},
+		{"@weekly", "2019-01-01T10:20:00Z", "2019-01-06T00:00:00Z"},
+		{"@monthly", "2019-01-01T10:20:00Z", "2019-02-01T00:00:00Z"},
+		{"@yearly", "2019-01-01T10:20:00Z", "2020-01-01T00:00:00Z"},
+		{"@every 90s", "2019-01-01T10:20:00.5Z", "2019-01-01T10:21:30Z"},
+		{"0 0 30 2 *", "2019-01-01T00:00:00Z", "0001-01-01T00:00:00Z"},
+	}
+
+	for _, test := range tests {
+		s, err := Parse(test.expr, nil)
+		if err != nil {
+			t.Errorf("Failed to parse '%v': %v", test.expr, err)
+			continue
+		}
+		from, err := time.Parse(time.RFC3339Nano, test.from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		act := s.Next(from)
+		if exp := test.exp; act.UTC().Format(time.RFC3339) != exp {
+			t.Errorf("Wrong result for '%v' from %v: %v != %v", test.expr, test.from, act.UTC().Format(time.RFC3339), exp)
+		}
+	}
+}
+
+func TestScheduleLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("Timezone data not available: %v", err)
+	}
+
+	s, err := Parse("0 2 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	act := s.Next(from)
+	if exp := "2019-01-02T07:00:00Z"; act.UTC().Format(time.RFC3339) != exp {
+		t.Errorf("Wrong result: %v != %v", act.UTC().Format(time.RFC3339), exp)
+	}
+	if act.Location() != loc {
+		t.Errorf("Wrong location: %v", act.Location())
+	}
+
+	// 2am does not exist on the day clocks spring forward, so it is skipped.
+	from = time.Date(2019, 3, 10, 0, 0, 0, 0, loc)
+	act = s.Next(from)
+	if exp := "2019-03-11T06:00:00Z"; act.UTC().Format(time.RFC3339) != exp {
+		t.Errorf("Wrong result: %v != %v", act.UTC().Format(time.RFC3339), exp)
+	}
+}
+
+func TestScheduleParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"foo * * * *",
+		"1,,2 * * * *",
+		"@fortnightly",
+		"@every nope",
+		"@every 10ms",
+	}
+
+	for _, test := range tests {
+		if _, err := Parse(test, nil); err == nil {
+			t.Errorf("Expected error from '%v'", test)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,341 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package docker provides a minimal client for the Docker Engine API, covering
+// the listing of containers and the streaming of their logs.
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Container describes a container as returned by the list endpoint.
+type Container struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// Name returns the primary name of the container without its leading slash.
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// LogsOptions determines which logs are streamed from a container.
+type LogsOptions struct {
+	Follow     bool
+	Stdout     bool
+	Stderr     bool
+	Timestamps bool
+	Since      time.Time
+	Tail       string
+}
+
+// Error is a non-successful response from the Docker API.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("docker API error (%v): %v", e.Status, e.Message)
+}
+
+//------------------------------------------------------------------------------
+
+// Client is a Docker Engine API client.
+type Client struct {
+	base string
+	http *http.Client
+}
+
+// NewClient creates a client for a Docker host, which is either a unix socket
+// (unix:///var/run/docker.sock) or a TCP address (tcp://localhost:2375). An
+// http:// or https:// URL is used as is.
+func NewClient(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse host: %v", err)
+	}
+
+	transport := &http.Transport{}
+	c := &Client{http: &http.Client{Transport: transport}}
+
+	switch u.Scheme {
+	case "unix":
+		socketPath := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		}
+		c.base = "http://docker"
+	case "tcp":
+		c.base = "http://" + u.Host
+	case "http", "https":
+		c.base = strings.TrimSuffix(host, "/")
+	default:
+		return nil, fmt.Errorf("unsupported host scheme: %v", u.Scheme)
+	}
+	return c, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &msg) != nil || len(msg.Message) == 0 {
+			msg.Message = strings.TrimSpace(string(body))
+		}
+		return nil, &Error{Status: res.StatusCode, Message: msg.Message}
+	}
+	return res, nil
+}
+
+// ListContainers returns the running containers that match filters, where
+// filters follow the format of the Docker API (e.g. {"label": ["foo=bar"]}).
+func (c *Client) ListContainers(ctx context.Context, filters map[string][]string) ([]Container, error) {
+	query := url.Values{}
+	if len(filters) > 0 {
+		filterBytes, err := json.Marshal(filters)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("filters", string(filterBytes))
+	}
+	res, err := c.get(ctx, "/containers/json", query)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var containers []Container
+	if err = json.NewDecoder(res.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to decode containers: %v", err)
+	}
+	return containers, nil
+}
+
+// ContainerTTY returns whether a container was started with a TTY, in which
+// case its logs are not multiplexed.
+func (c *Client) ContainerTTY(ctx context.Context, id string) (bool, error) {
+	res, err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/json", nil)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	var inspect struct {
+		Config struct {
+			Tty bool `json:"Tty"`
+		} `json:"Config"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&inspect); err != nil {
+		return false, fmt.Errorf("failed to decode container: %v", err)
+	}
+	return inspect.Config.Tty, nil
+}
+
+// Logs opens a stream of logs from a container. The stream should be read
+// with a LogReader and closed once finished with.
+func (c *Client) Logs(ctx context.Context, id string, opts LogsOptions) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("follow", strconv.FormatBool(opts.Follow))
+	query.Set("stdout", strconv.FormatBool(opts.Stdout))
+	query.Set("stderr", strconv.FormatBool(opts.Stderr))
+	query.Set("timestamps", strconv.FormatBool(opts.Timestamps))
+	if !opts.Since.IsZero() {
+		query.Set("since", fmt.Sprintf("%d.%09d", opts.Since.Unix(), opts.Since.Nanosecond()))
+	}
+	if len(opts.Tail) > 0 {
+		query.Set("tail", opts.Tail)
+	}
+	res, err := c.get(ctx, "/containers/"+url.PathEscape(id)+"/logs", query)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Stream identifies the output stream of a log line.
+type Stream string
+
+// Log streams.
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+)
+
+// LogLine is a single line of a container log.
+type LogLine struct {
+	Stream    Stream
+	Timestamp time.Time
+	Content   []byte
+}
+
+// LogReader reads lines from a log stream.
+type LogReader struct {
+	multiplexed bool
+	timestamps  bool
+	raw         *bufio.Reader
+
+	// Partial lines of each stream carried between frames.
+	partial map[Stream][]byte
+	lines   []LogLine
+}
+
+// NewLogReader creates a reader of log lines. The stream is multiplexed unless
+// the container has a TTY, and timestamps should match the option used to
+// request the logs.
+func NewLogReader(r io.Reader, multiplexed, timestamps bool) *LogReader {
+	return &LogReader{
+		multiplexed: multiplexed,
+		timestamps:  timestamps,
+		raw:         bufio.NewReader(r),
+		partial:     map[Stream][]byte{},
+	}
+}
+
+func (l *LogReader) parseLine(stream Stream, line []byte) LogLine {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	logLine := LogLine{Stream: stream, Content: line}
+	if l.timestamps {
+		if i := bytes.IndexByte(line, ' '); i > 0 {
+			if ts, err := time.Parse(time.RFC3339Nano, string(line[:i])); err == nil {
+				logLine.Timestamp = ts
+				logLine.Content = line[i+1:]
+			}
+		}
+	}
+	return logLine
+}
+
+func (l *LogReader) push(stream Stream, data []byte) {
+	buf := append(l.partial[stream], data...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.lines = append(l.lines, l.parseLine(stream, append([]byte(nil), buf[:i]...)))
+		buf = buf[i+1:]
+	}
+	l.partial[stream] = append([]byte(nil), buf...)
+}
+
+func (l *LogReader) flush() {
+	for _, stream := range []Stream{StreamStdout, StreamStderr} {
+		if buf := l.partial[stream]; len(buf) > 0 {
+			l.lines = append(l.lines, l.parseLine(stream, buf))
+		}
+		delete(l.partial, stream)
+	}
+}
+
+// Next returns the next line of the log, or io.EOF once the stream has ended.
+func (l *LogReader) Next() (LogLine, error) {
+	for len(l.lines) == 0 {
+		if err := l.readChunk(); err != nil {
+			if err != io.EOF {
+				return LogLine{}, err
+			}
+			l.flush()
+			if len(l.lines) == 0 {
+				return LogLine{}, io.EOF
+			}
+		}
+	}
+	line := l.lines[0]
+	l.lines = l.lines[1:]
+	return line, nil
+}
+
+func (l *LogReader) readChunk() error {
+	if !l.multiplexed {
+		data, err := l.raw.ReadSlice('\n')
+		if len(data) > 0 {
+			l.push(StreamStdout, data)
+		}
+		if err == bufio.ErrBufferFull {
+			return nil
+		}
+		return err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(l.raw, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("truncated log frame header: %v", err)
+		}
+		return err
+	}
+	stream := StreamStdout
+	switch header[0] {
+	case 0, 1:
+	case 2:
+		stream = StreamStderr
+	default:
+		return fmt.Errorf("unrecognised log stream type: %v", header[0])
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+	if _, err := io.ReadFull(l.raw, payload); err != nil {
+		return fmt.Errorf("truncated log frame: %v", err)
+	}
+	l.push(stream, payload)
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,226 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+func frame(stream byte, data string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func readAllLines(t *testing.T, r *LogReader) []LogLine {
+	t.Helper()
+	var lines []LogLine
+	for {
+		line, err := r.Next()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestLogReaderMultiplexed(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(frame(1, "2019-01-02T03:04:05.123456789Z hello "))
+	buf.Write(frame(2, "2019-01-02T03:04:06Z oh no\n"))
+	buf.Write(frame(1, "world\n2019-01-02T03:04:07Z foo\r\nbar"))
+
+	lines := readAllLines(t, NewLogReader(&buf, true, true))
+
+	exp := []LogLine{
+		{Stream: StreamStderr, Timestamp: time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC), Content: []byte("oh no")},
+		{Stream: StreamStdout, Timestamp: time.Date(2019, 1, 2, 3, 4, 5, 123456789, time.UTC), Content: []byte("hello world")},
+		{Stream: StreamStdout, Timestamp: time.Date(2019, 1, 2, 3, 4, 7, 0, time.UTC), Content: []byte("foo")},
+		{Stream: StreamStdout, Content: []byte("bar")},
+	}
+	if len(lines) != len(exp) {
+		t.Fatalf("Wrong count of lines: %v != %v", len(lines), len(exp))
+	}
+	for i, line := range lines {
+		if line.Stream != exp[i].Stream || !line.Timestamp.Equal(exp[i].Timestamp) || string(line.Content) != string(exp[i].Content) {
+			t.Errorf("Wrong line %v: %+v != %+v", i, line, exp[i])
+		}
+	}
+}
+
+func TestLogReaderRaw(t *testing.T) {
+	r := NewLogReader(strings.NewReader("foo\nbar\n"), false, false)
+	lines := readAllLines(t, r)
+	if len(lines) != 2 || string(lines[0].Content) != "foo" || string(lines[1].Content) != "bar" {
+		t.Errorf("Wrong lines: %+v", lines)
+	}
+	if lines[0].Stream != StreamStdout {
+		t.Errorf("Wrong stream: %v", lines[0].Stream)
+	}
+}
+
+func TestLogReaderBadFrame(t *testing.T) {
+	r := NewLogReader(bytes.NewReader(frame(7, "foo\n")), true, false)
+	if _, err := r.Next(); err == nil {
+		t.Error("Expected error from bad stream type")
+	}
+
+	r = NewLogReader(bytes.NewReader(frame(1, "foo\n")[:10]), true, false)
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("Expected error from truncated frame, received: %v", err)
+	}
+}
+
+func TestClientListAndLogs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/json":
+			if exp, act := `{"label":["app=foo"]}`, r.URL.Query().Get("filters"); exp != act {
+				t.Errorf("Wrong filters: %v != %v", act, exp)
+			}
+			w.Write([]byte(`[{"Id":"abc","Names":["/foo_1"],"Image":"foo:latest","State":"running","Labels":{"app":"foo"}}]`))
+		case "/containers/abc/json":
+			w.Write([]byte(`{"Id":"abc","Config":{"Tty":true}}`))
+		case "/containers/abc/logs":
+			q := r.URL.Query()
+			if q.Get("follow") != "true" || q.Get("stdout") != "true" || q.Get("stderr") != "false" {
+				t.Errorf("Wrong log options: %v", q)
+			}
+			if exp, act := "1546398245.000000001", q.Get("since"); exp != act {
+				t.Errorf("Wrong since: %v != %v", act, exp)
+			}
+			w.Write([]byte("foo\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"No such container: nope"}`))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := NewClient("tcp://" + strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	containers, err := c.ListContainers(ctx, map[string][]string{"label": {"app=foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []Container{{
+		ID:     "abc",
+		Names:  []string{"/foo_1"},
+		Image:  "foo:latest",
+		State:  "running",
+		Labels: map[string]string{"app": "foo"},
+	}}
+	if !reflect.DeepEqual(exp, containers) {
+		t.Errorf("Wrong containers: %+v != %+v", containers, exp)
+	}
+	if exp, act := "foo_1", containers[0].Name(); exp != act {
+		t.Errorf("Wrong name: %v != %v", act, exp)
+	}
+
+	tty, err := c.ContainerTTY(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tty {
+		t.Error("Expected TTY")
+	}
+
+	logs, err := c.Logs(ctx, "abc", LogsOptions{
+		Follow: true,
+		Stdout: true,
+		Since:  time.Unix(1546398245, 1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logBytes, _ := ioutil.ReadAll(logs)
+	logs.Close()
+	if exp, act := "foo\n", string(logBytes); exp != act {
+		t.Errorf("Wrong logs: %v != %v", act, exp)
+	}
+
+	_, err = c.ContainerTTY(ctx, "nope")
+	if dErr, ok := err.(*Error); !ok {
+		t.Errorf("Expected API error, received: %v", err)
+	} else if dErr.Status != 404 || dErr.Message != "No such container: nope" {
+		t.Errorf("Wrong error: %v", dErr)
+	}
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_docker_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	c, err := NewClient("unix://" + socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers, err := c.ListContainers(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 0 {
+		t.Errorf("Expected no containers: %v", containers)
+	}
+
+	if _, err = NewClient("ftp://nope"); err == nil {
+		t.Error("Expected error from bad scheme")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,321 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package ftp provides a minimal FTP client for listing, retrieving, removing
+// and renaming remote files using passive mode transfers.
+package ftp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// FileInfo describes a remote file.
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	Dir     bool
+}
+
+// IsDir returns true if the file is a directory.
+func (f FileInfo) IsDir() bool {
+	return f.Dir
+}
+
+// IsNotExist returns true if an error indicates that a file does not exist.
+func IsNotExist(err error) bool {
+	if tErr, ok := err.(*textproto.Error); ok {
+		return tErr.Code == 550
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+// Client is an FTP client, commands are sent one at a time.
+type Client struct {
+	conn    net.Conn
+	tp      *textproto.Conn
+	host    string
+	timeout time.Duration
+}
+
+// Dial connects to an FTP server and waits for its greeting.
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:    conn,
+		tp:      textproto.NewConn(conn),
+		host:    host,
+		timeout: timeout,
+	}
+	if _, _, err = c.tp.ReadResponse(220); err != nil {
+		c.tp.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// cmd sends a command and reads its response, which must have a code that
+// begins with expectCode.
+func (c *Client) cmd(expectCode int, format string, args ...interface{}) (int, string, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if _, err := c.tp.Cmd(format, args...); err != nil {
+		return 0, "", err
+	}
+	return c.tp.ReadResponse(expectCode)
+}
+
+// Login authenticates with the server and switches to binary mode.
+func (c *Client) Login(user, password string) error {
+	code, _, err := c.cmd(0, "USER %v", user)
+	if err != nil {
+		return err
+	}
+	switch code {
+	case 230:
+	case 331:
+		if _, _, err = c.cmd(230, "PASS %v", password); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unexpected USER response code: %v", code)
+	}
+	_, _, err = c.cmd(200, "TYPE I")
+	return err
+}
+
+// Quit ends the session and closes the connection.
+func (c *Client) Quit() error {
+	c.cmd(221, "QUIT")
+	return c.tp.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// parseEPSV extracts the port from an extended passive mode response such as
+// "Entering Extended Passive Mode (|||6446|)".
+func parseEPSV(msg string) (int, error) {
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("invalid EPSV response: %v", msg)
+	}
+	fields := strings.Split(msg[start+1:end], string(msg[start+1]))
+	if len(fields) != 5 {
+		return 0, fmt.Errorf("invalid EPSV response: %v", msg)
+	}
+	return strconv.Atoi(fields[3])
+}
+
+// parsePASV extracts the port from a passive mode response such as
+// "Entering Passive Mode (h1,h2,h3,h4,p1,p2)". The host is ignored in favour
+// of the control connection host, which is friendlier to NAT.
+func parsePASV(msg string) (int, error) {
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("invalid PASV response: %v", msg)
+	}
+	fields := strings.Split(msg[start+1:end], ",")
+	if len(fields) != 6 {
+		return 0, fmt.Errorf("invalid PASV response: %v", msg)
+	}
+	p1, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return 0, err
+	}
+	p2, err := strconv.Atoi(fields[5])
+	if err != nil {
+		return 0, err
+	}
+	return p1<<8 | p2, nil
+}
+
+// transfer opens a passive data connection, sends a command and reads all
+// data from the connection.
+func (c *Client) transfer(format string, args ...interface{}) ([]byte, error) {
+	var port int
+	_, msg, err := c.cmd(229, "EPSV")
+	if err == nil {
+		port, err = parseEPSV(msg)
+	} else {
+		if _, msg, err = c.cmd(227, "PASV"); err == nil {
+			port, err = parsePASV(msg)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dataConn, err := net.DialTimeout("tcp", net.JoinHostPort(c.host, strconv.Itoa(port)), c.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer dataConn.Close()
+
+	if _, _, err = c.cmd(1, format, args...); err != nil {
+		return nil, err
+	}
+	if c.timeout > 0 {
+		dataConn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	data, err := ioutil.ReadAll(dataConn)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err = c.tp.ReadResponse(2); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+//------------------------------------------------------------------------------
+
+// parseMLSxEntry parses a machine readable listing entry such as
+// "type=file;size=12;modify=20190101120000; foo.txt".
+func parseMLSxEntry(line string) (info FileInfo, err error) {
+	i := strings.Index(line, " ")
+	if i < 0 {
+		return info, fmt.Errorf("invalid listing entry: %v", line)
+	}
+	info.Name = line[i+1:]
+	for _, fact := range strings.Split(line[:i], ";") {
+		kv := strings.SplitN(fact, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.ToLower(kv[0]) {
+		case "type":
+			switch strings.ToLower(kv[1]) {
+			case "dir", "cdir", "pdir":
+				info.Dir = true
+			}
+		case "size":
+			if info.Size, err = strconv.ParseInt(kv[1], 10, 64); err != nil {
+				return
+			}
+		case "modify":
+			ts := kv[1]
+			if j := strings.Index(ts, "."); j >= 0 {
+				ts = ts[:j]
+			}
+			if info.ModTime, err = time.Parse("20060102150405", ts); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// ReadDir lists the files of a directory, excluding . and .. entries. Servers
+// that do not support MLSD are listed with NLST instead, in which case the
+// size and modified time of each file are queried individually.
+func (c *Client) ReadDir(dir string) ([]FileInfo, error) {
+	data, err := c.transfer("MLSD %v", dir)
+	if err == nil {
+		var files []FileInfo
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimRight(line, "\r"); len(line) == 0 {
+				continue
+			}
+			info, err := parseMLSxEntry(line)
+			if err != nil {
+				return nil, err
+			}
+			if info.Name == "." || info.Name == ".." {
+				continue
+			}
+			files = append(files, info)
+		}
+		return files, nil
+	}
+	if tErr, ok := err.(*textproto.Error); !ok || tErr.Code < 500 || tErr.Code > 502 {
+		return nil, err
+	}
+
+	if data, err = c.transfer("NLST %v", dir); err != nil {
+		return nil, err
+	}
+	var files []FileInfo
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); len(line) == 0 {
+			continue
+		}
+		info := FileInfo{Name: path.Base(line)}
+		if info.Name == "." || info.Name == ".." {
+			continue
+		}
+		fullPath := path.Join(dir, info.Name)
+		_, msg, err := c.cmd(213, "SIZE %v", fullPath)
+		if err != nil {
+			// Directories do not have a size.
+			info.Dir = true
+			files = append(files, info)
+			continue
+		}
+		if info.Size, err = strconv.ParseInt(strings.TrimSpace(msg), 10, 64); err != nil {
+			return nil, err
+		}
+		if _, msg, err = c.cmd(213, "MDTM %v", fullPath); err == nil {
+			info.ModTime, _ = time.Parse("20060102150405", strings.TrimSpace(msg))
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// ReadFile retrieves the full contents of a file.
+func (c *Client) ReadFile(path string) ([]byte, error) {
+	return c.transfer("RETR %v", path)
+}
+
+// Remove deletes a file.
+func (c *Client) Remove(path string) error {
+	_, _, err := c.cmd(250, "DELE %v", path)
+	return err
+}
+
+// Rename moves a file to a new path.
+func (c *Client) Rename(from, to string) error {
+	if _, _, err := c.cmd(350, "RNFR %v", from); err != nil {
+		return err
+	}
+	_, _, err := c.cmd(250, "RNTO %v", to)
+	return err
+}
+
+//------------------------------------------------------------------------------
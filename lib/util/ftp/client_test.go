@@ -0,0 +1,230 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ftp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMLSxEntry(t *testing.T) {
+	tests := map[string]FileInfo{
+		"type=file;size=12;modify=20190101120000; foo.txt": {
+			Name:    "foo.txt",
+			Size:    12,
+			ModTime: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		"Type=dir;Modify=20190101120000.123; bar baz": {
+			Name:    "bar baz",
+			ModTime: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+			Dir:     true,
+		},
+	}
+	for input, exp := range tests {
+		act, err := parseMLSxEntry(input)
+		if err != nil {
+			t.Errorf("Unexpected error for '%v': %v", input, err)
+		} else if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong result for '%v': %+v != %+v", input, act, exp)
+		}
+	}
+	if _, err := parseMLSxEntry("nospace"); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestParsePassive(t *testing.T) {
+	if port, err := parseEPSV("Entering Extended Passive Mode (|||6446|)"); err != nil {
+		t.Error(err)
+	} else if port != 6446 {
+		t.Errorf("Wrong port: %v", port)
+	}
+	if port, err := parsePASV("Entering Passive Mode (127,0,0,1,25,46)"); err != nil {
+		t.Error(err)
+	} else if port != 25<<8|46 {
+		t.Errorf("Wrong port: %v", port)
+	}
+	if _, err := parsePASV("Entering Passive Mode (127,0,0,1)"); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+// fakeServer runs a minimal FTP server that supports a single client.
+func fakeServer(t *testing.T, files map[string]string) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rd := bufio.NewReader(conn)
+		reply := func(format string, args ...interface{}) {
+			fmt.Fprintf(conn, format+"\r\n", args...)
+		}
+
+		var dataLn net.Listener
+		var renameFrom string
+		sendData := func(data string) {
+			dataConn, err := dataLn.Accept()
+			dataLn.Close()
+			if err != nil {
+				reply("425 No data connection")
+				return
+			}
+			reply("150 Opening data connection")
+			dataConn.Write([]byte(data))
+			dataConn.Close()
+			reply("226 Transfer complete")
+		}
+
+		reply("220 Welcome")
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			parts := strings.SplitN(strings.TrimRight(line, "\r\n"), " ", 2)
+			arg := ""
+			if len(parts) > 1 {
+				arg = parts[1]
+			}
+			switch parts[0] {
+			case "USER":
+				reply("331 Password required")
+			case "PASS":
+				if arg != "bar" {
+					reply("530 Login incorrect")
+				} else {
+					reply("230 Logged in")
+				}
+			case "TYPE":
+				reply("200 Type set")
+			case "EPSV":
+				if dataLn, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+					reply("425 Failed")
+					continue
+				}
+				reply("229 Entering Extended Passive Mode (|||%v|)", dataLn.Addr().(*net.TCPAddr).Port)
+			case "MLSD":
+				var listing string
+				for name, content := range files {
+					if strings.HasPrefix(name, arg+"/") {
+						listing += fmt.Sprintf("type=file;size=%v;modify=20190101120000; %v\r\n", len(content), strings.TrimPrefix(name, arg+"/"))
+					}
+				}
+				sendData("type=cdir;modify=20190101120000; .\r\n" + listing)
+			case "RETR":
+				content, exists := files[arg]
+				if !exists {
+					dataLn.Close()
+					reply("550 No such file")
+					continue
+				}
+				sendData(content)
+			case "DELE":
+				delete(files, arg)
+				reply("250 Deleted")
+			case "RNFR":
+				renameFrom = arg
+				reply("350 Ready")
+			case "RNTO":
+				files[arg] = files[renameFrom]
+				delete(files, renameFrom)
+				reply("250 Renamed")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), func() {
+		ln.Close()
+		<-done
+	}
+}
+
+func TestClient(t *testing.T) {
+	files := map[string]string{
+		"/in/foo.txt": "hello world",
+	}
+	addr, stop := fakeServer(t, files)
+	defer stop()
+
+	c, err := Dial(addr, time.Second*5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Login("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := c.ReadDir("/in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []FileInfo{{
+		Name:    "foo.txt",
+		Size:    11,
+		ModTime: time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+	}}
+	if !reflect.DeepEqual(exp, infos) {
+		t.Errorf("Wrong listing: %+v != %+v", infos, exp)
+	}
+
+	data, err := c.ReadFile("/in/foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello world", string(data); exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+	if _, err = c.ReadFile("/in/nope"); !IsNotExist(err) {
+		t.Errorf("Expected not exist error, received: %v", err)
+	}
+
+	if err = c.Rename("/in/foo.txt", "/done/foo.txt"); err != nil {
+		t.Error(err)
+	}
+	if err = c.Remove("/done/foo.txt"); err != nil {
+		t.Error(err)
+	}
+	if err = c.Quit(); err != nil {
+		t.Error(err)
+	}
+	stop()
+	if len(files) != 0 {
+		t.Errorf("Expected no files, found: %v", files)
+	}
+}
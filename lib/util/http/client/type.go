@@ -283,8 +283,10 @@ func (h *Type) waitForAccess() bool {
 
 // CreateRequest creates an HTTP request out of a single message.
 func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
-	url := h.url.Get(msg)
+	return h.createRequest(h.url.Get(msg), msg)
+}
 
+func (h *Type) createRequest(url string, msg types.Message) (req *http.Request, err error) {
 	if msg == nil || msg.Len() == 0 {
 		if req, err = http.NewRequest(h.conf.Verb, url, nil); err == nil {
 			for k, v := range h.headers {
@@ -420,10 +422,25 @@ func (h *Type) checkStatus(code int) (succeeded bool, retStrat retryStrategy) {
 // This attempt may include retries, and if all retries fail an error is
 // returned.
 func (h *Type) Do(msg types.Message) (res *http.Response, err error) {
+	return h.do(msg, h.CreateRequest)
+}
+
+// DoURL performs the same as Do but targets a specific URL rather than the
+// configured one, which is useful for following links such as pagination.
+func (h *Type) DoURL(url string, msg types.Message) (res *http.Response, err error) {
+	return h.do(msg, func(m types.Message) (*http.Request, error) {
+		return h.createRequest(url, m)
+	})
+}
+
+func (h *Type) do(
+	msg types.Message,
+	createRequest func(types.Message) (*http.Request, error),
+) (res *http.Response, err error) {
 	h.mCount.Incr(1)
 
 	var req *http.Request
-	if req, err = h.CreateRequest(msg); err != nil {
+	if req, err = createRequest(msg); err != nil {
 		h.mErrReq.Incr(1)
 		h.mErr.Incr(1)
 		return nil, err
@@ -456,7 +473,7 @@ func (h *Type) Do(msg types.Message) (res *http.Response, err error) {
 		h.mErrRes.Incr(1)
 		h.mErr.Incr(1)
 
-		req, err = h.CreateRequest(msg)
+		req, err = createRequest(msg)
 		if err != nil {
 			h.mErrReq.Incr(1)
 			h.mErr.Incr(1)
@@ -0,0 +1,344 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mysql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// Binary log event types.
+const (
+	eventQuery             = 0x02
+	eventRotate            = 0x04
+	eventFormatDescription = 0x0f
+	eventXID               = 0x10
+	eventTableMap          = 0x13
+	eventWriteRowsV1       = 0x17
+	eventUpdateRowsV1      = 0x18
+	eventDeleteRowsV1      = 0x19
+	eventWriteRowsV2       = 0x1e
+	eventUpdateRowsV2      = 0x1f
+	eventDeleteRowsV2      = 0x20
+	eventGTID              = 0x21
+)
+
+const eventHeaderLen = 19
+
+// EventHeader is the common header of all binary log events.
+type EventHeader struct {
+	Timestamp uint32
+	Type      byte
+	ServerID  uint32
+
+	// LogPos is the position of the next event within the binary log file.
+	LogPos uint32
+}
+
+// RotateEvent indicates that following events are within a new binary log
+// file.
+type RotateEvent struct {
+	File     string
+	Position uint64
+}
+
+// GTIDEvent marks the beginning of a transaction.
+type GTIDEvent struct {
+	GTID GTID
+}
+
+// QueryEvent contains a statement, which is either the beginning of a
+// transaction or a statement that is not logged as rows, such as DDL.
+type QueryEvent struct {
+	Schema string
+	Query  string
+}
+
+// XIDEvent marks the commit of a transaction.
+type XIDEvent struct {
+	XID uint64
+}
+
+// TableMapEvent describes the table of following rows events.
+type TableMapEvent struct {
+	TableID     uint64
+	Schema      string
+	Table       string
+	ColumnTypes []byte
+	ColumnMeta  []uint16
+}
+
+// RowsEventType is the operation of a rows event.
+type RowsEventType string
+
+// Rows event types.
+const (
+	RowsInsert RowsEventType = "insert"
+	RowsUpdate RowsEventType = "update"
+	RowsDelete RowsEventType = "delete"
+)
+
+// RowsEvent contains the row images of changes to a table. Inserts only have
+// after images, deletes only have before images and updates have both, where
+// each before image corresponds to the after image of the same index.
+//
+// Columns that are not present within an image are set to nil.
+type RowsEvent struct {
+	Type   RowsEventType
+	Table  *TableMapEvent
+	Before [][]interface{}
+	After  [][]interface{}
+}
+
+//------------------------------------------------------------------------------
+
+// Parser decodes binary log events. Parsers are stateful as rows events refer
+// to previously received table maps.
+type Parser struct {
+	checksum bool
+	tables   map[uint64]*TableMapEvent
+}
+
+// NewParser creates a new binary log event parser.
+func NewParser() *Parser {
+	return &Parser{
+		tables: map[uint64]*TableMapEvent{},
+	}
+}
+
+// Parse decodes a raw event. Events of types that are not supported are
+// returned as nil.
+func (p *Parser) Parse(data []byte) (EventHeader, interface{}, error) {
+	var h EventHeader
+	if len(data) < eventHeaderLen {
+		return h, nil, errors.New("event is shorter than its header")
+	}
+	r := &reader{b: data}
+	h.Timestamp = r.uint32()
+	h.Type = r.uint8()
+	h.ServerID = r.uint32()
+	size := r.uint32()
+	h.LogPos = r.uint32()
+	r.skip(2) // Flags
+
+	if int(size) != len(data) {
+		return h, nil, fmt.Errorf("event size %v does not match data length %v", size, len(data))
+	}
+
+	body := data[eventHeaderLen:]
+	if h.Type == eventFormatDescription {
+		p.checksum = fdeHasChecksum(body)
+	}
+	if p.checksum {
+		if len(body) < 4 {
+			return h, nil, errShortRead
+		}
+		body = body[:len(body)-4]
+	}
+	r = &reader{b: body}
+
+	var e interface{}
+	switch h.Type {
+	case eventRotate:
+		pos := r.uint64()
+		e = &RotateEvent{File: string(r.b), Position: pos}
+	case eventGTID:
+		r.skip(1) // Flags
+		var g GTID
+		copy(g.SID[:], r.bytes(16))
+		g.GNO = int64(r.uint64())
+		e = &GTIDEvent{GTID: g}
+	case eventQuery:
+		r.skip(8) // Thread id and execution time
+		schemaLen := int(r.uint8())
+		r.skip(2) // Error code
+		r.skip(int(r.uint16()))
+		schema := string(r.bytes(schemaLen))
+		r.skip(1)
+		e = &QueryEvent{Schema: schema, Query: string(r.b)}
+	case eventXID:
+		e = &XIDEvent{XID: r.uint64()}
+	case eventTableMap:
+		t := &TableMapEvent{TableID: r.uintN(6)}
+		r.skip(2) // Flags
+		t.Schema = string(r.bytes(int(r.uint8())))
+		r.skip(1)
+		t.Table = string(r.bytes(int(r.uint8())))
+		r.skip(1)
+		t.ColumnTypes = r.bytes(int(r.lenEncInt()))
+		metaBytes := r.bytes(int(r.lenEncInt()))
+		if r.err == nil {
+			var err error
+			if t.ColumnMeta, err = parseColumnMeta(t.ColumnTypes, metaBytes); err != nil {
+				return h, nil, err
+			}
+			p.tables[t.TableID] = t
+		}
+		e = t
+	case eventWriteRowsV1, eventUpdateRowsV1, eventDeleteRowsV1,
+		eventWriteRowsV2, eventUpdateRowsV2, eventDeleteRowsV2:
+		rows, err := p.parseRows(h.Type, r)
+		if err != nil {
+			return h, nil, err
+		}
+		e = rows
+	}
+	if r.err != nil {
+		return h, nil, r.err
+	}
+	return h, e, nil
+}
+
+// fdeHasChecksum determines whether events carry a checksum from the body of
+// a format description event.
+func fdeHasChecksum(body []byte) bool {
+	if len(body) < 57 {
+		return false
+	}
+	version := strings.TrimRight(string(body[2:52]), "\x00")
+	if !versionAtLeast(version, 5, 6, 1) {
+		return false
+	}
+	// The checksum algorithm precedes the checksum of the event itself.
+	return body[len(body)-5] == 1
+}
+
+func versionAtLeast(version string, major, minor, patch int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 3 {
+		return false
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		end := 0
+		for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+			end++
+		}
+		nums[i], _ = strconv.Atoi(p[:end])
+	}
+	want := []int{major, minor, patch}
+	for i := range nums {
+		if nums[i] != want[i] {
+			return nums[i] > want[i]
+		}
+	}
+	return true
+}
+
+func (p *Parser) parseRows(t byte, r *reader) (*RowsEvent, error) {
+	tableID := r.uintN(6)
+	r.skip(2) // Flags
+	if t >= eventWriteRowsV2 {
+		r.skip(int(r.uint16()) - 2)
+	}
+
+	table, exists := p.tables[tableID]
+	if r.err == nil && !exists {
+		return nil, fmt.Errorf("rows event refers to unknown table id: %v", tableID)
+	}
+
+	n := int(r.lenEncInt())
+	if r.err != nil {
+		return nil, r.err
+	}
+	if n != len(table.ColumnTypes) {
+		return nil, fmt.Errorf("rows event has %v columns but table map has %v", n, len(table.ColumnTypes))
+	}
+	bitmapLen := (n + 7) / 8
+	present := r.bytes(bitmapLen)
+	presentAfter := present
+	if t == eventUpdateRowsV1 || t == eventUpdateRowsV2 {
+		presentAfter = r.bytes(bitmapLen)
+	}
+
+	e := &RowsEvent{Table: table}
+	switch t {
+	case eventWriteRowsV1, eventWriteRowsV2:
+		e.Type = RowsInsert
+	case eventUpdateRowsV1, eventUpdateRowsV2:
+		e.Type = RowsUpdate
+	default:
+		e.Type = RowsDelete
+	}
+
+	for len(r.b) > 0 && r.err == nil {
+		row, err := decodeRow(r, table, present)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Type {
+		case RowsInsert:
+			e.After = append(e.After, row)
+		case RowsDelete:
+			e.Before = append(e.Before, row)
+		case RowsUpdate:
+			e.Before = append(e.Before, row)
+			if row, err = decodeRow(r, table, presentAfter); err != nil {
+				return nil, err
+			}
+			e.After = append(e.After, row)
+		}
+	}
+	return e, r.err
+}
+
+func bitSet(bitmap []byte, i int) bool {
+	return bitmap[i/8]&(1<<uint(i%8)) != 0
+}
+
+func decodeRow(r *reader, table *TableMapEvent, present []byte) ([]interface{}, error) {
+	n := len(table.ColumnTypes)
+	count := 0
+	for i := 0; i < n; i++ {
+		if bitSet(present, i) {
+			count++
+		}
+	}
+	nulls := r.bytes((count + 7) / 8)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	row := make([]interface{}, n)
+	idx := 0
+	for i := 0; i < n; i++ {
+		if !bitSet(present, i) {
+			continue
+		}
+		isNull := bitSet(nulls, idx)
+		idx++
+		if isNull {
+			continue
+		}
+		v, err := decodeValue(r, table.ColumnTypes[i], table.ColumnMeta[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode column %v of %v.%v: %v", i, table.Schema, table.Table, err)
+		}
+		row[i] = v
+	}
+	return row, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,288 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mysql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func testEvent(t byte, logPos uint32, body []byte) []byte {
+	b := make([]byte, eventHeaderLen)
+	b[4] = t
+	binary.LittleEndian.PutUint32(b[9:], uint32(eventHeaderLen+len(body)))
+	binary.LittleEndian.PutUint32(b[13:], logPos)
+	return append(b, body...)
+}
+
+func TestParserRows(t *testing.T) {
+	p := NewParser()
+
+	tableMap := []byte{1, 0, 0, 0, 0, 0, 0, 0}
+	tableMap = append(tableMap, 2, 'd', 'b', 0, 1, 't', 0)
+	tableMap = append(tableMap, 3, TypeLong, TypeVarchar, TypeTiny)
+	tableMap = append(tableMap, 2, 0xff, 0x00, 0x04)
+
+	h, e, err := p.Parse(testEvent(eventTableMap, 100, tableMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := uint32(100), h.LogPos; exp != act {
+		t.Errorf("Wrong log pos: %v != %v", act, exp)
+	}
+	tm, ok := e.(*TableMapEvent)
+	if !ok {
+		t.Fatalf("Wrong event type: %T", e)
+	}
+	if tm.Schema != "db" || tm.Table != "t" {
+		t.Errorf("Wrong table: %v.%v", tm.Schema, tm.Table)
+	}
+	if exp, act := []uint16{0, 255, 0}, tm.ColumnMeta; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong column meta: %v != %v", act, exp)
+	}
+
+	row := func(id uint32, name string) []byte {
+		b := []byte{0x04, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(b[1:], id)
+		return append(append(b, byte(len(name))), name...)
+	}
+
+	insert := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 3, 0x07}
+	insert = append(insert, row(42, "hi")...)
+	insert = append(insert, row(43, "ho")...)
+	if _, e, err = p.Parse(testEvent(eventWriteRowsV2, 200, insert)); err != nil {
+		t.Fatal(err)
+	}
+	exp := &RowsEvent{
+		Type:  RowsInsert,
+		Table: tm,
+		After: [][]interface{}{
+			{int64(42), "hi", nil},
+			{int64(43), "ho", nil},
+		},
+	}
+	if !reflect.DeepEqual(exp, e) {
+		t.Errorf("Wrong event: %+v != %+v", e, exp)
+	}
+
+	update := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 3, 0x07, 0x07}
+	update = append(update, row(42, "hi")...)
+	update = append(update, row(42, "hey")...)
+	if _, e, err = p.Parse(testEvent(eventUpdateRowsV2, 300, update)); err != nil {
+		t.Fatal(err)
+	}
+	exp = &RowsEvent{
+		Type:   RowsUpdate,
+		Table:  tm,
+		Before: [][]interface{}{{int64(42), "hi", nil}},
+		After:  [][]interface{}{{int64(42), "hey", nil}},
+	}
+	if !reflect.DeepEqual(exp, e) {
+		t.Errorf("Wrong event: %+v != %+v", e, exp)
+	}
+
+	del := []byte{1, 0, 0, 0, 0, 0, 0, 0, 3, 0x07}
+	del = append(del, row(42, "hey")...)
+	if _, e, err = p.Parse(testEvent(eventDeleteRowsV1, 400, del)); err != nil {
+		t.Fatal(err)
+	}
+	exp = &RowsEvent{
+		Type:   RowsDelete,
+		Table:  tm,
+		Before: [][]interface{}{{int64(42), "hey", nil}},
+	}
+	if !reflect.DeepEqual(exp, e) {
+		t.Errorf("Wrong event: %+v != %+v", e, exp)
+	}
+
+	unknown := []byte{9, 0, 0, 0, 0, 0, 0, 0, 2, 0, 3, 0x07}
+	if _, _, err = p.Parse(testEvent(eventWriteRowsV2, 500, unknown)); err == nil {
+		t.Error("Expected error from unknown table id")
+	}
+}
+
+func TestParserChecksum(t *testing.T) {
+	p := NewParser()
+
+	fde := make([]byte, 57+4)
+	copy(fde[2:], "5.7.25-log")
+	fde[len(fde)-5] = 1
+	if _, _, err := p.Parse(testEvent(eventFormatDescription, 0, fde)); err != nil {
+		t.Fatal(err)
+	}
+
+	rotate := []byte{4, 0, 0, 0, 0, 0, 0, 0}
+	rotate = append(rotate, "mysql-bin.000002"...)
+	rotate = append(rotate, 0xde, 0xad, 0xbe, 0xef)
+	_, e, err := p.Parse(testEvent(eventRotate, 0, rotate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := (&RotateEvent{File: "mysql-bin.000002", Position: 4}), e; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong event: %+v != %+v", act, exp)
+	}
+}
+
+func TestParserGTIDAndXID(t *testing.T) {
+	p := NewParser()
+
+	sid, err := ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gtid := append([]byte{1}, sid[:]...)
+	gtid = append(gtid, 23, 0, 0, 0, 0, 0, 0, 0)
+	_, e, err := p.Parse(testEvent(eventGTID, 0, gtid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := (&GTIDEvent{GTID: GTID{SID: sid, GNO: 23}}), e; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong event: %+v != %+v", act, exp)
+	}
+	if exp, act := "3e11fa47-71ca-11e1-9e33-c80aa9429562:23", e.(*GTIDEvent).GTID.String(); exp != act {
+		t.Errorf("Wrong gtid: %v != %v", act, exp)
+	}
+
+	if _, e, err = p.Parse(testEvent(eventXID, 0, []byte{5, 0, 0, 0, 0, 0, 0, 0})); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := (&XIDEvent{XID: 5}), e; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong event: %+v != %+v", act, exp)
+	}
+
+	query := []byte{0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0}
+	query = append(query, "db"...)
+	query = append(query, 0)
+	query = append(query, "ALTER TABLE t ADD COLUMN c INT"...)
+	if _, e, err = p.Parse(testEvent(eventQuery, 0, query)); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := (&QueryEvent{Schema: "db", Query: "ALTER TABLE t ADD COLUMN c INT"}), e; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong event: %+v != %+v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func TestDecodeDecimal(t *testing.T) {
+	tests := []struct {
+		precision, scale int
+		in               []byte
+		exp              string
+	}{
+		{14, 4, []byte{0x81, 0x0D, 0xFB, 0x38, 0xD2, 0x04, 0xD2}, "1234567890.1234"},
+		{14, 4, []byte{0x7E, 0xF2, 0x04, 0xC7, 0x2D, 0xFB, 0x2D}, "-1234567890.1234"},
+		{5, 2, []byte{0x80, 0x00, 0x05}, "0.05"},
+		{4, 0, []byte{0x80, 0x07}, "7"},
+	}
+	for _, test := range tests {
+		v, err := decodeDecimal(&reader{b: test.in}, test.precision, test.scale)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := v.(json.Number); string(act) != test.exp {
+			t.Errorf("Wrong result: %v != %v", act, test.exp)
+		}
+	}
+}
+
+func TestDecodeTemporal(t *testing.T) {
+	ymdhms := int64((2019*13+3)<<5|4)<<17 | 5<<12 | 6<<6 | 7
+	v := uint64(ymdhms + 0x8000000000)
+	b := []byte{byte(v >> 32), byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v), 0x03, 0xe8}
+	res, err := decodeValue(&reader{b: b}, TypeDateTime2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "2019-03-04 05:06:07.100", res; exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	tv := uint32(0x800000 + (10<<12 | 20<<6 | 30))
+	if res, err = decodeValue(&reader{b: []byte{byte(tv >> 16), byte(tv >> 8), byte(tv)}}, TypeTime2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "10:20:30", res; exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	dv := uint32(2019<<9 | 3<<5 | 4)
+	if res, err = decodeValue(&reader{b: []byte{byte(dv), byte(dv >> 8), byte(dv >> 16)}}, TypeDate, 0); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "2019-03-04", res; exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	b := []byte{
+		0x02, 0x00, 0x17, 0x00,
+		0x12, 0x00, 0x01, 0x00,
+		0x13, 0x00, 0x01, 0x00,
+		jsonInt16, 0x01, 0x00,
+		jsonString, 0x14, 0x00,
+		'a', 'b',
+		0x02, 'x', 'y',
+	}
+	res, err := decodeJSON(jsonSmallObject, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{"a": int64(1), "b": "xy"}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("Wrong result: %v != %v", res, exp)
+	}
+
+	arr := []byte{
+		0x02, 0x00, 0x0a, 0x00,
+		jsonLiteral, 0x01, 0x00,
+		jsonLiteral, 0x00, 0x00,
+	}
+	if res, err = decodeJSON(jsonSmallArray, arr); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []interface{}{true, nil}; !reflect.DeepEqual(exp, res) {
+		t.Errorf("Wrong result: %v != %v", res, exp)
+	}
+
+	if _, err = decodeJSON(jsonSmallObject, b[:10]); err == nil {
+		t.Error("Expected error from truncated object")
+	}
+}
+
+func TestToUnsigned(t *testing.T) {
+	if exp, act := uint64(255), ToUnsigned(TypeTiny, -1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := uint64(0xffffff), ToUnsigned(TypeInt24, -1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+	if exp, act := uint64(0xffffffff), ToUnsigned(TypeLong, -1); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,563 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mysql implements a minimal client for the MySQL protocol, supporting
+// simple queries and streaming row based binary log events as a replica.
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Capability flags.
+const (
+	clientLongPassword     = 0x00000001
+	clientLongFlag         = 0x00000004
+	clientProtocol41       = 0x00000200
+	clientTransactions     = 0x00002000
+	clientSecureConnection = 0x00008000
+	clientMultiResults     = 0x00020000
+	clientPluginAuth       = 0x00080000
+)
+
+// Commands.
+const (
+	comQuit           = 0x01
+	comQuery          = 0x03
+	comBinlogDump     = 0x12
+	comRegisterSlave  = 0x15
+	comBinlogDumpGTID = 0x1e
+)
+
+//------------------------------------------------------------------------------
+
+// Error is an error returned by the server.
+type Error struct {
+	Code     uint16
+	SQLState string
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("error %v (%v): %v", e.Code, e.SQLState, e.Message)
+}
+
+func parseError(b []byte) *Error {
+	e := &Error{}
+	if len(b) < 3 {
+		e.Message = "malformed error packet"
+		return e
+	}
+	e.Code = binary.LittleEndian.Uint16(b[1:])
+	b = b[3:]
+	if len(b) >= 6 && b[0] == '#' {
+		e.SQLState = string(b[1:6])
+		b = b[6:]
+	}
+	e.Message = string(b)
+	return e
+}
+
+//------------------------------------------------------------------------------
+
+// Config contains the parameters used to establish a connection.
+type Config struct {
+	Address  string
+	User     string
+	Password string
+	Timeout  time.Duration
+}
+
+// Conn is a connection to a MySQL server.
+type Conn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	seq  byte
+
+	// ServerVersion is the version reported by the server.
+	ServerVersion string
+}
+
+// Dial opens a connection to a server and authenticates.
+func Dial(conf Config) (*Conn, error) {
+	netConn, err := net.DialTimeout("tcp", conf.Address, conf.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Timeout > 0 {
+		netConn.SetDeadline(time.Now().Add(conf.Timeout))
+	}
+	c := &Conn{
+		conn: netConn,
+		rd:   bufio.NewReader(netConn),
+	}
+	if err = c.handshake(conf); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// Close terminates the connection.
+func (c *Conn) Close() error {
+	c.seq = 0
+	c.writePacket([]byte{comQuit})
+	return c.conn.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// readPacket reads a full payload, joining packets that exceed the maximum
+// packet size.
+func (c *Conn) readPacket() ([]byte, error) {
+	var payload []byte
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(c.rd, header); err != nil {
+			return nil, err
+		}
+		length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		c.seq = header[3] + 1
+
+		start := len(payload)
+		payload = append(payload, make([]byte, length)...)
+		if _, err := io.ReadFull(c.rd, payload[start:]); err != nil {
+			return nil, err
+		}
+		if length < 0xffffff {
+			return payload, nil
+		}
+	}
+}
+
+func (c *Conn) writePacket(payload []byte) error {
+	for {
+		length := len(payload)
+		if length > 0xffffff {
+			length = 0xffffff
+		}
+		packet := make([]byte, 4, 4+length)
+		packet[0], packet[1], packet[2], packet[3] = byte(length), byte(length>>8), byte(length>>16), c.seq
+		packet = append(packet, payload[:length]...)
+		if _, err := c.conn.Write(packet); err != nil {
+			return err
+		}
+		c.seq++
+		payload = payload[length:]
+		if length < 0xffffff {
+			return nil
+		}
+	}
+}
+
+// writeCommand writes a command packet, which begins a new sequence.
+func (c *Conn) writeCommand(payload []byte) error {
+	c.seq = 0
+	return c.writePacket(payload)
+}
+
+// readOK reads a packet and returns an error unless it is an OK packet.
+func (c *Conn) readOK() error {
+	b, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(b) > 0 && b[0] == 0xff {
+		return parseError(b)
+	}
+	if len(b) == 0 || (b[0] != 0x00 && b[0] != 0xfe) {
+		return errors.New("unexpected response, expected OK packet")
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Conn) handshake(conf Config) error {
+	b, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(b) > 0 && b[0] == 0xff {
+		return parseError(b)
+	}
+	if len(b) < 1 || b[0] != 10 {
+		return errors.New("unsupported handshake protocol version")
+	}
+	r := &reader{b: b[1:]}
+	c.ServerVersion = r.nulString()
+	r.skip(4) // Connection id
+	scramble := append([]byte{}, r.bytes(8)...)
+	r.skip(1)
+	caps := uint32(r.uint16())
+	r.skip(3) // Charset and status
+	caps |= uint32(r.uint16()) << 16
+	authDataLen := int(r.uint8())
+	r.skip(10)
+	plugin := "mysql_native_password"
+	if caps&clientSecureConnection != 0 {
+		n := authDataLen - 8
+		if n < 13 {
+			n = 13
+		}
+		part := r.bytes(n)
+		// The final byte is a null terminator.
+		scramble = append(scramble, bytes.TrimRight(part, "\x00")...)
+	}
+	if caps&clientPluginAuth != 0 {
+		if name := r.nulString(); len(name) > 0 {
+			plugin = name
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	authResp, err := authResponse(plugin, conf.Password, scramble)
+	if err != nil {
+		return err
+	}
+
+	flags := uint32(clientLongPassword | clientLongFlag | clientProtocol41 |
+		clientTransactions | clientSecureConnection | clientMultiResults | clientPluginAuth)
+	resp := make([]byte, 32)
+	binary.LittleEndian.PutUint32(resp, flags)
+	binary.LittleEndian.PutUint32(resp[4:], 1<<24)
+	resp[8] = 45 // utf8mb4_general_ci
+	resp = append(append(resp, conf.User...), 0)
+	resp = append(append(resp, byte(len(authResp))), authResp...)
+	resp = append(append(resp, plugin...), 0)
+	if err = c.writePacket(resp); err != nil {
+		return err
+	}
+
+	for {
+		if b, err = c.readPacket(); err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			return errors.New("empty authentication response")
+		}
+		switch b[0] {
+		case 0x00:
+			return nil
+		case 0xff:
+			return parseError(b)
+		case 0xfe:
+			// Authentication method switch.
+			r := &reader{b: b[1:]}
+			plugin = r.nulString()
+			scramble = bytes.TrimRight(r.b, "\x00")
+			if authResp, err = authResponse(plugin, conf.Password, scramble); err != nil {
+				return err
+			}
+			if err = c.writePacket(authResp); err != nil {
+				return err
+			}
+		case 0x01:
+			if plugin != "caching_sha2_password" || len(b) < 2 {
+				return errors.New("unexpected authentication data")
+			}
+			switch b[1] {
+			case 3:
+				// Fast authentication succeeded, an OK packet follows.
+			case 4:
+				if err = c.fullSHA2Auth(conf.Password, scramble); err != nil {
+					return err
+				}
+			default:
+				return errors.New("unexpected caching_sha2_password state")
+			}
+		default:
+			return errors.New("unexpected authentication response")
+		}
+	}
+}
+
+// fullSHA2Auth performs a full caching_sha2_password authentication over an
+// unencrypted connection by encrypting the password with the public key of the
+// server.
+func (c *Conn) fullSHA2Auth(password string, scramble []byte) error {
+	if err := c.writePacket([]byte{2}); err != nil {
+		return err
+	}
+	b, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(b) > 0 && b[0] == 0xff {
+		return parseError(b)
+	}
+	if len(b) < 2 || b[0] != 0x01 {
+		return errors.New("unexpected public key response")
+	}
+	block, _ := pem.Decode(b[1:])
+	if block == nil {
+		return errors.New("failed to decode server public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("server public key is not an RSA key")
+	}
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	enc, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaPub, plain, nil)
+	if err != nil {
+		return err
+	}
+	return c.writePacket(enc)
+}
+
+func authResponse(plugin, password string, scramble []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, nil
+	}
+	switch plugin {
+	case "mysql_native_password":
+		return nativePassword(password, scramble), nil
+	case "caching_sha2_password":
+		return sha2Password(password, scramble), nil
+	}
+	return nil, fmt.Errorf("unsupported authentication plugin: %v", plugin)
+}
+
+func nativePassword(password string, scramble []byte) []byte {
+	if len(scramble) > 20 {
+		scramble = scramble[:20]
+	}
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	h := sha1.New()
+	h.Write(scramble)
+	h.Write(stage2[:])
+	res := h.Sum(nil)
+	for i := range res {
+		res[i] ^= stage1[i]
+	}
+	return res
+}
+
+func sha2Password(password string, scramble []byte) []byte {
+	stage1 := sha256.Sum256([]byte(password))
+	stage2 := sha256.Sum256(stage1[:])
+	h := sha256.New()
+	h.Write(stage2[:])
+	h.Write(scramble)
+	res := h.Sum(nil)
+	for i := range res {
+		res[i] ^= stage1[i]
+	}
+	return res
+}
+
+//------------------------------------------------------------------------------
+
+// Exec executes a statement that returns no rows.
+func (c *Conn) Exec(sql string) error {
+	_, _, err := c.Query(sql)
+	return err
+}
+
+// Query executes a simple query and returns the column names and rows of the
+// result. Null values are returned as nil.
+func (c *Conn) Query(sql string) ([]string, [][]*string, error) {
+	if err := c.writeCommand(append([]byte{comQuery}, sql...)); err != nil {
+		return nil, nil, err
+	}
+	b, err := c.readPacket()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(b) == 0 {
+		return nil, nil, errors.New("empty query response")
+	}
+	switch b[0] {
+	case 0x00:
+		return nil, nil, nil
+	case 0xff:
+		return nil, nil, parseError(b)
+	}
+
+	r := &reader{b: b}
+	n := int(r.lenEncInt())
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+
+	cols := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if b, err = c.readPacket(); err != nil {
+			return nil, nil, err
+		}
+		r = &reader{b: b}
+		for j := 0; j < 4; j++ {
+			r.lenEncString() // Catalog, schema, table and original table
+		}
+		cols = append(cols, r.lenEncString())
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+	}
+	if b, err = c.readPacket(); err != nil {
+		return nil, nil, err
+	}
+	if len(b) == 0 || b[0] != 0xfe {
+		return nil, nil, errors.New("expected EOF packet after column definitions")
+	}
+
+	var rows [][]*string
+	for {
+		if b, err = c.readPacket(); err != nil {
+			return nil, nil, err
+		}
+		if len(b) > 0 && b[0] == 0xfe && len(b) < 9 {
+			return cols, rows, nil
+		}
+		if len(b) > 0 && b[0] == 0xff {
+			return nil, nil, parseError(b)
+		}
+		r = &reader{b: b}
+		row := make([]*string, n)
+		for i := 0; i < n; i++ {
+			if len(r.b) > 0 && r.b[0] == 0xfb {
+				r.skip(1)
+				continue
+			}
+			v := r.lenEncString()
+			row[i] = &v
+		}
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+		rows = append(rows, row)
+	}
+}
+
+// QuoteString quotes a string for use as a literal within a query.
+func QuoteString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('\'')
+	for _, c := range []byte(s) {
+		switch c {
+		case 0:
+			buf.WriteString(`\0`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\\', '\'', '"':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\'')
+	return buf.String()
+}
+
+//------------------------------------------------------------------------------
+
+// RegisterReplica registers the connection as a replica with the given server
+// id, which must be unique amongst the replicas of the server.
+func (c *Conn) RegisterReplica(serverID uint32) error {
+	b := make([]byte, 5, 18)
+	b[0] = comRegisterSlave
+	binary.LittleEndian.PutUint32(b[1:], serverID)
+	b = append(b, 0, 0, 0)    // Hostname, user and password
+	b = append(b, 0, 0)       // Port
+	b = append(b, 0, 0, 0, 0) // Replication rank
+	b = append(b, 0, 0, 0, 0) // Master id
+	if err := c.writeCommand(b); err != nil {
+		return err
+	}
+	return c.readOK()
+}
+
+// StartBinlogDump begins streaming binary log events from a file and position.
+func (c *Conn) StartBinlogDump(serverID uint32, file string, pos uint32) error {
+	b := make([]byte, 11, 11+len(file))
+	b[0] = comBinlogDump
+	binary.LittleEndian.PutUint32(b[1:], pos)
+	binary.LittleEndian.PutUint32(b[7:], serverID)
+	b = append(b, file...)
+	return c.writeCommand(b)
+}
+
+// StartBinlogDumpGTID begins streaming binary log events for all transactions
+// that are not contained within a GTID set.
+func (c *Conn) StartBinlogDumpGTID(serverID uint32, set GTIDSet) error {
+	data := set.Encode()
+	b := make([]byte, 11, 27+len(data))
+	b[0] = comBinlogDumpGTID
+	binary.LittleEndian.PutUint16(b[1:], 0x04) // Through GTID
+	binary.LittleEndian.PutUint32(b[3:], serverID)
+	binary.LittleEndian.PutUint32(b[7:], 0) // Binlog name length
+	b = append(b, make([]byte, 12)...)
+	binary.LittleEndian.PutUint64(b[11:], 4)
+	binary.LittleEndian.PutUint32(b[19:], uint32(len(data)))
+	b = append(b, data...)
+	return c.writeCommand(b)
+}
+
+// ReadEvent blocks until the next binary log event is received and returns
+// its raw bytes, including the header.
+func (c *Conn) ReadEvent() ([]byte, error) {
+	b, err := c.readPacket()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty binlog packet")
+	}
+	switch b[0] {
+	case 0x00:
+		return b[1:], nil
+	case 0xff:
+		return nil, parseError(b)
+	case 0xfe:
+		return nil, io.EOF
+	}
+	return nil, fmt.Errorf("unexpected binlog packet type: %x", b[0])
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,166 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+func TestConnHandshakeAndQuery(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	scramble := []byte("abcdefghijklmnopqrst")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		netConn, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer netConn.Close()
+		c := &Conn{conn: netConn, rd: bufio.NewReader(netConn)}
+
+		check := func(err error) {
+			if err != nil {
+				t.Error(err)
+				runtime.Goexit()
+			}
+		}
+
+		hs := []byte{10}
+		hs = append(hs, "5.7.25\x00"...)
+		hs = append(hs, 1, 0, 0, 0)
+		hs = append(hs, scramble[:8]...)
+		hs = append(hs, 0, 0xff, 0xff, 45, 2, 0, 0xff, 0xff, 21)
+		hs = append(hs, make([]byte, 10)...)
+		hs = append(hs, scramble[8:]...)
+		hs = append(hs, 0)
+		hs = append(hs, "mysql_native_password\x00"...)
+		check(c.writePacket(hs))
+
+		resp, err := c.readPacket()
+		check(err)
+		r := &reader{b: resp[32:]}
+		if exp, act := "foo", r.nulString(); exp != act {
+			t.Errorf("Wrong user: %v != %v", act, exp)
+		}
+		auth := r.bytes(int(r.uint8()))
+		if exp := nativePassword("bar", scramble); !bytes.Equal(exp, auth) {
+			t.Errorf("Wrong auth response: %v != %v", auth, exp)
+		}
+		check(c.writePacket([]byte{0, 0, 0, 2, 0, 0, 0}))
+
+		query, err := c.readPacket()
+		check(err)
+		if exp, act := "SHOW MASTER STATUS", string(query[1:]); query[0] != comQuery || exp != act {
+			t.Errorf("Wrong query: %v != %v", act, exp)
+		}
+		check(c.writePacket([]byte{2}))
+		for _, name := range []string{"File", "Position"} {
+			col := []byte{3, 'd', 'e', 'f', 0, 0, 0, byte(len(name))}
+			col = append(col, name...)
+			check(c.writePacket(col))
+		}
+		check(c.writePacket([]byte{0xfe, 0, 0, 2, 0}))
+		row := []byte{16}
+		row = append(row, "mysql-bin.000003"...)
+		row = append(row, 0xfb)
+		check(c.writePacket(row))
+		check(c.writePacket([]byte{0xfe, 0, 0, 2, 0}))
+	}()
+
+	conn, err := Dial(Config{
+		Address:  ln.Addr().String(),
+		User:     "foo",
+		Password: "bar",
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if exp, act := "5.7.25", conn.ServerVersion; exp != act {
+		t.Errorf("Wrong server version: %v != %v", act, exp)
+	}
+
+	cols, rows, err := conn.Query("SHOW MASTER STATUS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 2 || cols[0] != "File" || cols[1] != "Position" {
+		t.Errorf("Wrong columns: %v", cols)
+	}
+	if len(rows) != 1 || rows[0][0] == nil || *rows[0][0] != "mysql-bin.000003" || rows[0][1] != nil {
+		t.Errorf("Wrong rows: %v", rows)
+	}
+	<-done
+}
+
+func TestConnAuthError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		netConn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer netConn.Close()
+		c := &Conn{conn: netConn, rd: bufio.NewReader(netConn)}
+		errPacket := []byte{0xff, 0x15, 0x04}
+		errPacket = append(errPacket, "#28000Access denied"...)
+		c.writePacket(errPacket)
+	}()
+
+	_, err = Dial(Config{Address: ln.Addr().String(), User: "foo", Timeout: time.Second})
+	myErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Wrong error type: %T: %v", err, err)
+	}
+	if myErr.Code != 1045 || myErr.SQLState != "28000" {
+		t.Errorf("Wrong error: %+v", myErr)
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	if exp, act := `'it\'s a \\ \"test\"\n'`, QuoteString("it's a \\ \"test\"\n"); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
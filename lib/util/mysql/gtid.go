@@ -0,0 +1,318 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mysql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// SID is the UUID of a server that originated a transaction.
+type SID [16]byte
+
+// ParseSID parses a UUID string.
+func ParseSID(s string) (SID, error) {
+	var sid SID
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil || len(b) != 16 {
+		return sid, fmt.Errorf("invalid server UUID: %v", s)
+	}
+	copy(sid[:], b)
+	return sid, nil
+}
+
+func (s SID) String() string {
+	h := hex.EncodeToString(s[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// GTID is a global transaction identifier.
+type GTID struct {
+	SID SID
+	GNO int64
+}
+
+func (g GTID) String() string {
+	return g.SID.String() + ":" + strconv.FormatInt(g.GNO, 10)
+}
+
+//------------------------------------------------------------------------------
+
+// interval is a range of transaction numbers, where the end is exclusive.
+type interval struct {
+	start, end int64
+}
+
+// GTIDSet is a set of transactions grouped by originating server.
+type GTIDSet map[SID][]interval
+
+// ParseGTIDSet parses a set of the form uuid:1-5:7,uuid:1-3.
+func ParseGTIDSet(s string) (GTIDSet, error) {
+	set := GTIDSet{}
+	s = strings.Replace(strings.TrimSpace(s), "\n", "", -1)
+	if len(s) == 0 {
+		return set, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid GTID set: %v", s)
+		}
+		sid, err := ParseSID(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields[1:] {
+			bounds := strings.Split(f, "-")
+			start, err := strconv.ParseInt(bounds[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid GTID interval: %v", f)
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil {
+					return nil, fmt.Errorf("invalid GTID interval: %v", f)
+				}
+			} else if len(bounds) > 2 {
+				return nil, fmt.Errorf("invalid GTID interval: %v", f)
+			}
+			if start < 1 || end < start {
+				return nil, fmt.Errorf("invalid GTID interval: %v", f)
+			}
+			set.addInterval(sid, interval{start, end + 1})
+		}
+	}
+	return set, nil
+}
+
+func (s GTIDSet) addInterval(sid SID, in interval) {
+	intervals := append(s[sid], in)
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start < intervals[j].start
+	})
+	merged := intervals[:1]
+	for _, next := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if next.start <= last.end {
+			if next.end > last.end {
+				last.end = next.end
+			}
+			continue
+		}
+		merged = append(merged, next)
+	}
+	s[sid] = merged
+}
+
+// Add a transaction to the set.
+func (s GTIDSet) Add(g GTID) {
+	s.addInterval(g.SID, interval{g.GNO, g.GNO + 1})
+}
+
+// Contains returns true if a transaction is within the set.
+func (s GTIDSet) Contains(g GTID) bool {
+	for _, in := range s[g.SID] {
+		if g.GNO >= in.start && g.GNO < in.end {
+			return true
+		}
+	}
+	return false
+}
+
+func (s GTIDSet) sortedSIDs() []SID {
+	sids := make([]SID, 0, len(s))
+	for sid := range s {
+		sids = append(sids, sid)
+	}
+	sort.Slice(sids, func(i, j int) bool {
+		return sids[i].String() < sids[j].String()
+	})
+	return sids
+}
+
+func (s GTIDSet) String() string {
+	var parts []string
+	for _, sid := range s.sortedSIDs() {
+		part := sid.String()
+		for _, in := range s[sid] {
+			if in.end-in.start == 1 {
+				part += fmt.Sprintf(":%v", in.start)
+			} else {
+				part += fmt.Sprintf(":%v-%v", in.start, in.end-1)
+			}
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Encode the set in the binary format used by the binlog dump command.
+func (s GTIDSet) Encode() []byte {
+	b := make([]byte, 8)
+	sids := s.sortedSIDs()
+	binary.LittleEndian.PutUint64(b, uint64(len(sids)))
+	for _, sid := range sids {
+		b = append(b, sid[:]...)
+		n := make([]byte, 8)
+		binary.LittleEndian.PutUint64(n, uint64(len(s[sid])))
+		b = append(b, n...)
+		for _, in := range s[sid] {
+			v := make([]byte, 16)
+			binary.LittleEndian.PutUint64(v, uint64(in.start))
+			binary.LittleEndian.PutUint64(v[8:], uint64(in.end))
+			b = append(b, v...)
+		}
+	}
+	return b
+}
+
+// Clone returns a copy of the set.
+func (s GTIDSet) Clone() GTIDSet {
+	c := make(GTIDSet, len(s))
+	for sid, intervals := range s {
+		c[sid] = append([]interval(nil), intervals...)
+	}
+	return c
+}
+
+//------------------------------------------------------------------------------
+
+// reader is a helper for decoding little endian protocol data.
+type reader struct {
+	b   []byte
+	err error
+}
+
+var errShortRead = errors.New("unexpected end of data")
+
+func (r *reader) need(n int) bool {
+	if r.err != nil {
+		return false
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errShortRead
+		return false
+	}
+	return true
+}
+
+func (r *reader) skip(n int) {
+	if r.need(n) {
+		r.b = r.b[n:]
+	}
+}
+
+func (r *reader) bytes(n int) []byte {
+	if !r.need(n) {
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *reader) uint8() uint8 {
+	if !r.need(1) {
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) uint16() uint16 {
+	if !r.need(2) {
+		return 0
+	}
+	v := binary.LittleEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *reader) uint32() uint32 {
+	if !r.need(4) {
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	if !r.need(8) {
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v
+}
+
+// uintN reads an unsigned little endian integer of n bytes.
+func (r *reader) uintN(n int) uint64 {
+	b := r.bytes(n)
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+func (r *reader) lenEncInt() uint64 {
+	switch first := r.uint8(); first {
+	case 0xfc:
+		return uint64(r.uint16())
+	case 0xfd:
+		return r.uintN(3)
+	case 0xfe:
+		return r.uint64()
+	default:
+		return uint64(first)
+	}
+}
+
+func (r *reader) lenEncString() string {
+	n := r.lenEncInt()
+	return string(r.bytes(int(n)))
+}
+
+func (r *reader) nulString() string {
+	if r.err != nil {
+		return ""
+	}
+	for i, c := range r.b {
+		if c == 0 {
+			v := string(r.b[:i])
+			r.b = r.b[i+1:]
+			return v
+		}
+	}
+	r.err = errShortRead
+	return ""
+}
+
+//------------------------------------------------------------------------------
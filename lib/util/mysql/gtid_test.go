@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mysql
+
+import (
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func TestGTIDSet(t *testing.T) {
+	set, err := ParseGTIDSet("3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:7,\n4e11fa47-71ca-11e1-9e33-c80aa9429562:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:7,4e11fa47-71ca-11e1-9e33-c80aa9429562:1", set.String(); exp != act {
+		t.Errorf("Wrong set: %v != %v", act, exp)
+	}
+
+	sid, _ := ParseSID("3e11fa47-71ca-11e1-9e33-c80aa9429562")
+	if !set.Contains(GTID{SID: sid, GNO: 3}) {
+		t.Error("Expected set to contain 3")
+	}
+	if set.Contains(GTID{SID: sid, GNO: 6}) {
+		t.Error("Expected set not to contain 6")
+	}
+
+	clone := set.Clone()
+	set.Add(GTID{SID: sid, GNO: 6})
+	if exp, act := "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-7,4e11fa47-71ca-11e1-9e33-c80aa9429562:1", set.String(); exp != act {
+		t.Errorf("Wrong set: %v != %v", act, exp)
+	}
+	if clone.Contains(GTID{SID: sid, GNO: 6}) {
+		t.Error("Expected clone to be unaffected")
+	}
+
+	if exp, act := 8+2*(16+8)+3*16, len(clone.Encode()); exp != act {
+		t.Errorf("Wrong encoded length: %v != %v", act, exp)
+	}
+
+	empty, err := ParseGTIDSet("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "", empty.String(); exp != act {
+		t.Errorf("Wrong set: %v != %v", act, exp)
+	}
+
+	for _, bad := range []string{
+		"nope:1",
+		"3e11fa47-71ca-11e1-9e33-c80aa9429562",
+		"3e11fa47-71ca-11e1-9e33-c80aa9429562:5-1",
+		"3e11fa47-71ca-11e1-9e33-c80aa9429562:a",
+		"3e11fa47-71ca-11e1-9e33-c80aa9429562:0",
+	} {
+		if _, err = ParseGTIDSet(bad); err == nil {
+			t.Errorf("Expected error from %v", bad)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,587 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mysql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Column types.
+const (
+	TypeDecimal    = 0x00
+	TypeTiny       = 0x01
+	TypeShort      = 0x02
+	TypeLong       = 0x03
+	TypeFloat      = 0x04
+	TypeDouble     = 0x05
+	TypeNull       = 0x06
+	TypeTimestamp  = 0x07
+	TypeLongLong   = 0x08
+	TypeInt24      = 0x09
+	TypeDate       = 0x0a
+	TypeTime       = 0x0b
+	TypeDateTime   = 0x0c
+	TypeYear       = 0x0d
+	TypeNewDate    = 0x0e
+	TypeVarchar    = 0x0f
+	TypeBit        = 0x10
+	TypeTimestamp2 = 0x11
+	TypeDateTime2  = 0x12
+	TypeTime2      = 0x13
+	TypeJSON       = 0xf5
+	TypeNewDecimal = 0xf6
+	TypeEnum       = 0xf7
+	TypeSet        = 0xf8
+	TypeTinyBlob   = 0xf9
+	TypeMediumBlob = 0xfa
+	TypeLongBlob   = 0xfb
+	TypeBlob       = 0xfc
+	TypeVarString  = 0xfd
+	TypeString     = 0xfe
+	TypeGeometry   = 0xff
+)
+
+// ToUnsigned converts a signed integer decoded from a column of an integer
+// type into its unsigned representation.
+func ToUnsigned(colType byte, v int64) uint64 {
+	switch colType {
+	case TypeTiny:
+		return uint64(uint8(v))
+	case TypeShort:
+		return uint64(uint16(v))
+	case TypeInt24:
+		return uint64(v) & 0xffffff
+	case TypeLong:
+		return uint64(uint32(v))
+	}
+	return uint64(v)
+}
+
+//------------------------------------------------------------------------------
+
+// parseColumnMeta reads the metadata block of a table map event.
+func parseColumnMeta(types []byte, b []byte) ([]uint16, error) {
+	r := &reader{b: b}
+	meta := make([]uint16, len(types))
+	for i, t := range types {
+		switch t {
+		case TypeFloat, TypeDouble, TypeBlob, TypeGeometry, TypeJSON,
+			TypeTimestamp2, TypeDateTime2, TypeTime2:
+			meta[i] = uint16(r.uint8())
+		case TypeVarchar, TypeVarString, TypeBit:
+			meta[i] = r.uint16()
+		case TypeNewDecimal, TypeString, TypeEnum, TypeSet:
+			hi := uint16(r.uint8())
+			meta[i] = hi<<8 | uint16(r.uint8())
+		}
+	}
+	return meta, r.err
+}
+
+// decodeValue decodes a single value of a row image.
+func decodeValue(r *reader, t byte, meta uint16) (interface{}, error) {
+	switch t {
+	case TypeTiny:
+		return int64(int8(r.uint8())), r.err
+	case TypeShort:
+		return int64(int16(r.uint16())), r.err
+	case TypeInt24:
+		v := int32(r.uintN(3) << 8)
+		return int64(v >> 8), r.err
+	case TypeLong:
+		return int64(int32(r.uint32())), r.err
+	case TypeLongLong:
+		return int64(r.uint64()), r.err
+	case TypeFloat:
+		return float64(math.Float32frombits(r.uint32())), r.err
+	case TypeDouble:
+		return math.Float64frombits(r.uint64()), r.err
+	case TypeYear:
+		y := int64(r.uint8())
+		if y != 0 {
+			y += 1900
+		}
+		return y, r.err
+	case TypeDate, TypeNewDate:
+		v := r.uintN(3)
+		return fmt.Sprintf("%04d-%02d-%02d", v>>9, (v>>5)&15, v&31), r.err
+	case TypeTime:
+		v := r.uintN(3)
+		return fmt.Sprintf("%02d:%02d:%02d", v/10000, (v%10000)/100, v%100), r.err
+	case TypeTimestamp:
+		v := r.uint32()
+		return time.Unix(int64(v), 0).UTC().Format("2006-01-02 15:04:05"), r.err
+	case TypeDateTime:
+		v := r.uint64()
+		d, t := v/1000000, v%1000000
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d",
+			d/10000, (d%10000)/100, d%100, t/10000, (t%10000)/100, t%100), r.err
+	case TypeTimestamp2:
+		sec := binary.BigEndian.Uint32(r.bytes(4))
+		usec, fsp := readFraction(r, int(meta))
+		s := time.Unix(int64(sec), 0).UTC().Format("2006-01-02 15:04:05")
+		return s + formatFraction(usec, fsp), r.err
+	case TypeDateTime2:
+		return decodeDateTime2(r, int(meta))
+	case TypeTime2:
+		return decodeTime2(r, int(meta))
+	case TypeNewDecimal:
+		return decodeDecimal(r, int(meta>>8), int(meta&0xff))
+	case TypeBit:
+		nbits := int(meta>>8)*8 + int(meta&0xff)
+		b := r.bytes((nbits + 7) / 8)
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, r.err
+	case TypeVarchar, TypeVarString:
+		n := 1
+		if meta >= 256 {
+			n = 2
+		}
+		return string(r.bytes(int(r.uintN(n)))), r.err
+	case TypeString, TypeEnum, TypeSet:
+		realType, length := byte(meta>>8), int(meta&0xff)
+		if meta < 256 {
+			realType, length = t, int(meta)
+		} else if realType&0x30 != 0x30 {
+			length |= (int(realType&0x30) ^ 0x30) << 4
+			realType |= 0x30
+		}
+		switch realType {
+		case TypeEnum, TypeSet:
+			return int64(r.uintN(length)), r.err
+		}
+		n := 1
+		if length >= 256 {
+			n = 2
+		}
+		return string(r.bytes(int(r.uintN(n)))), r.err
+	case TypeBlob, TypeTinyBlob, TypeMediumBlob, TypeLongBlob, TypeGeometry:
+		return string(r.bytes(int(r.uintN(int(meta))))), r.err
+	case TypeJSON:
+		b := r.bytes(int(r.uintN(int(meta))))
+		if r.err != nil {
+			return nil, r.err
+		}
+		if len(b) == 0 {
+			return nil, nil
+		}
+		v, err := decodeJSON(b[0], b[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode json value: %v", err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported column type: %v", t)
+}
+
+func readFraction(r *reader, fsp int) (int64, int) {
+	switch fsp {
+	case 1, 2:
+		return int64(r.uint8()) * 10000, fsp
+	case 3, 4:
+		b := r.bytes(2)
+		if b == nil {
+			return 0, fsp
+		}
+		return int64(binary.BigEndian.Uint16(b)) * 100, fsp
+	case 5, 6:
+		b := r.bytes(3)
+		if b == nil {
+			return 0, fsp
+		}
+		return int64(b[0])<<16 | int64(b[1])<<8 | int64(b[2]), fsp
+	}
+	return 0, 0
+}
+
+func formatFraction(usec int64, fsp int) string {
+	if fsp == 0 {
+		return ""
+	}
+	return "." + fmt.Sprintf("%06d", usec)[:fsp]
+}
+
+func readBigEndian(b []byte) int64 {
+	var v int64
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+func decodeDateTime2(r *reader, fsp int) (interface{}, error) {
+	intPart := readBigEndian(r.bytes(5)) - 0x8000000000
+	usec, fsp := readFraction(r, fsp)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if intPart == 0 {
+		return "0000-00-00 00:00:00" + formatFraction(usec, fsp), nil
+	}
+	ymd, hms := intPart>>17, intPart%(1<<17)
+	ym := ymd >> 5
+	return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d",
+		ym/13, ym%13, ymd%(1<<5), hms>>12, (hms>>6)%(1<<6), hms%(1<<6),
+	) + formatFraction(usec, fsp), nil
+}
+
+func decodeTime2(r *reader, fsp int) (interface{}, error) {
+	var tmp int64
+	switch fsp {
+	case 1, 2:
+		intPart := readBigEndian(r.bytes(3)) - 0x800000
+		frac := int64(r.uint8())
+		if intPart < 0 && frac > 0 {
+			intPart++
+			frac -= 0x100
+		}
+		tmp = intPart<<24 + frac*10000
+	case 3, 4:
+		intPart := readBigEndian(r.bytes(3)) - 0x800000
+		frac := readBigEndian(r.bytes(2))
+		if intPart < 0 && frac > 0 {
+			intPart++
+			frac -= 0x10000
+		}
+		tmp = intPart<<24 + frac*100
+	case 5, 6:
+		tmp = readBigEndian(r.bytes(6)) - 0x800000000000
+	default:
+		fsp = 0
+		tmp = (readBigEndian(r.bytes(3)) - 0x800000) << 24
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	sign := ""
+	if tmp < 0 {
+		sign, tmp = "-", -tmp
+	}
+	hms := tmp >> 24
+	return fmt.Sprintf("%v%02d:%02d:%02d", sign,
+		(hms>>12)%(1<<10), (hms>>6)%(1<<6), hms%(1<<6),
+	) + formatFraction(tmp%(1<<24), fsp), nil
+}
+
+var digitsToBytes = []int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// decodeDecimal decodes a value in the binary decimal format.
+func decodeDecimal(r *reader, precision, scale int) (interface{}, error) {
+	intg := precision - scale
+	intg0, intg0x := intg/9, intg%9
+	frac0, frac0x := scale/9, scale%9
+	size := intg0*4 + digitsToBytes[intg0x] + frac0*4 + digitsToBytes[frac0x]
+
+	raw := r.bytes(size)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if size == 0 {
+		return json.Number("0"), nil
+	}
+	b := make([]byte, size)
+	copy(b, raw)
+
+	negative := b[0]&0x80 == 0
+	b[0] ^= 0x80
+	if negative {
+		for i := range b {
+			b[i] ^= 0xff
+		}
+	}
+
+	var buf strings.Builder
+	if negative {
+		buf.WriteByte('-')
+	}
+
+	pos := 0
+	var intDigits strings.Builder
+	if n := digitsToBytes[intg0x]; n > 0 {
+		intDigits.WriteString(strconv.FormatInt(readBigEndian(b[pos:pos+n]), 10))
+		pos += n
+	}
+	for i := 0; i < intg0; i++ {
+		v := readBigEndian(b[pos : pos+4])
+		if intDigits.Len() > 0 {
+			intDigits.WriteString(fmt.Sprintf("%09d", v))
+		} else {
+			intDigits.WriteString(strconv.FormatInt(v, 10))
+		}
+		pos += 4
+	}
+	intStr := strings.TrimLeft(intDigits.String(), "0")
+	if len(intStr) == 0 {
+		intStr = "0"
+	}
+	buf.WriteString(intStr)
+
+	if scale > 0 {
+		buf.WriteByte('.')
+		for i := 0; i < frac0; i++ {
+			buf.WriteString(fmt.Sprintf("%09d", readBigEndian(b[pos:pos+4])))
+			pos += 4
+		}
+		if n := digitsToBytes[frac0x]; n > 0 {
+			buf.WriteString(fmt.Sprintf("%0*d", frac0x, readBigEndian(b[pos:pos+n])))
+		}
+	}
+	return json.Number(buf.String()), nil
+}
+
+//------------------------------------------------------------------------------
+
+// JSON binary value types.
+const (
+	jsonSmallObject = 0x00
+	jsonLargeObject = 0x01
+	jsonSmallArray  = 0x02
+	jsonLargeArray  = 0x03
+	jsonLiteral     = 0x04
+	jsonInt16       = 0x05
+	jsonUint16      = 0x06
+	jsonInt32       = 0x07
+	jsonUint32      = 0x08
+	jsonInt64       = 0x09
+	jsonUint64      = 0x0a
+	jsonDouble      = 0x0b
+	jsonString      = 0x0c
+	jsonOpaque      = 0x0f
+)
+
+var errJSONShort = errors.New("unexpected end of json value")
+
+// decodeJSON decodes a value in the MySQL binary JSON format.
+func decodeJSON(t byte, b []byte) (interface{}, error) {
+	switch t {
+	case jsonSmallObject, jsonLargeObject:
+		return decodeJSONContainer(b, t == jsonLargeObject, true)
+	case jsonSmallArray, jsonLargeArray:
+		return decodeJSONContainer(b, t == jsonLargeArray, false)
+	case jsonLiteral:
+		if len(b) < 1 {
+			return nil, errJSONShort
+		}
+		switch b[0] {
+		case 0x00:
+			return nil, nil
+		case 0x01:
+			return true, nil
+		case 0x02:
+			return false, nil
+		}
+		return nil, fmt.Errorf("unknown json literal: %v", b[0])
+	case jsonInt16, jsonUint16:
+		if len(b) < 2 {
+			return nil, errJSONShort
+		}
+		if t == jsonInt16 {
+			return int64(int16(binary.LittleEndian.Uint16(b))), nil
+		}
+		return int64(binary.LittleEndian.Uint16(b)), nil
+	case jsonInt32, jsonUint32:
+		if len(b) < 4 {
+			return nil, errJSONShort
+		}
+		if t == jsonInt32 {
+			return int64(int32(binary.LittleEndian.Uint32(b))), nil
+		}
+		return int64(binary.LittleEndian.Uint32(b)), nil
+	case jsonInt64, jsonUint64, jsonDouble:
+		if len(b) < 8 {
+			return nil, errJSONShort
+		}
+		v := binary.LittleEndian.Uint64(b)
+		switch t {
+		case jsonInt64:
+			return int64(v), nil
+		case jsonUint64:
+			return v, nil
+		}
+		return math.Float64frombits(v), nil
+	case jsonString:
+		s, _, err := decodeJSONVarBytes(b)
+		return string(s), err
+	case jsonOpaque:
+		if len(b) < 1 {
+			return nil, errJSONShort
+		}
+		data, _, err := decodeJSONVarBytes(b[1:])
+		if err != nil {
+			return nil, err
+		}
+		return decodeJSONOpaque(b[0], data)
+	}
+	return nil, fmt.Errorf("unknown json type: %v", t)
+}
+
+func decodeJSONVarBytes(b []byte) ([]byte, int, error) {
+	var length, shift uint
+	for i := 0; i < len(b) && i < 5; i++ {
+		length |= uint(b[i]&0x7f) << shift
+		shift += 7
+		if b[i]&0x80 == 0 {
+			end := i + 1 + int(length)
+			if end > len(b) {
+				return nil, 0, errJSONShort
+			}
+			return b[i+1 : end], end, nil
+		}
+	}
+	return nil, 0, errJSONShort
+}
+
+func decodeJSONContainer(b []byte, large, isObject bool) (interface{}, error) {
+	offsetSize := 2
+	if large {
+		offsetSize = 4
+	}
+	readOffset := func(pos int) (int, error) {
+		if pos+offsetSize > len(b) {
+			return 0, errJSONShort
+		}
+		if large {
+			return int(binary.LittleEndian.Uint32(b[pos:])), nil
+		}
+		return int(binary.LittleEndian.Uint16(b[pos:])), nil
+	}
+
+	count, err := readOffset(0)
+	if err != nil {
+		return nil, err
+	}
+	size, err := readOffset(offsetSize)
+	if err != nil {
+		return nil, err
+	}
+	if size > len(b) {
+		return nil, errJSONShort
+	}
+	b = b[:size]
+
+	pos := 2 * offsetSize
+	var keys []string
+	if isObject {
+		keys = make([]string, count)
+		for i := 0; i < count; i++ {
+			keyOffset, err := readOffset(pos)
+			if err != nil {
+				return nil, err
+			}
+			if pos+offsetSize+2 > len(b) {
+				return nil, errJSONShort
+			}
+			keyLen := int(binary.LittleEndian.Uint16(b[pos+offsetSize:]))
+			if keyOffset+keyLen > len(b) {
+				return nil, errJSONShort
+			}
+			keys[i] = string(b[keyOffset : keyOffset+keyLen])
+			pos += offsetSize + 2
+		}
+	}
+
+	values := make([]interface{}, count)
+	for i := 0; i < count; i++ {
+		if pos >= len(b) {
+			return nil, errJSONShort
+		}
+		t := b[pos]
+		inlined := t == jsonLiteral || t == jsonInt16 || t == jsonUint16 ||
+			(large && (t == jsonInt32 || t == jsonUint32))
+		if inlined {
+			if pos+1+offsetSize > len(b) {
+				return nil, errJSONShort
+			}
+			if values[i], err = decodeJSON(t, b[pos+1:pos+1+offsetSize]); err != nil {
+				return nil, err
+			}
+		} else {
+			offset, err := readOffset(pos + 1)
+			if err != nil {
+				return nil, err
+			}
+			if offset > len(b) {
+				return nil, errJSONShort
+			}
+			if values[i], err = decodeJSON(t, b[offset:]); err != nil {
+				return nil, err
+			}
+		}
+		pos += 1 + offsetSize
+	}
+
+	if !isObject {
+		return values, nil
+	}
+	obj := make(map[string]interface{}, count)
+	for i, k := range keys {
+		obj[k] = values[i]
+	}
+	return obj, nil
+}
+
+func decodeJSONOpaque(colType byte, data []byte) (interface{}, error) {
+	switch colType {
+	case TypeNewDecimal:
+		if len(data) < 2 {
+			return nil, errJSONShort
+		}
+		return decodeDecimal(&reader{b: data[2:]}, int(data[0]), int(data[1]))
+	case TypeDate, TypeDateTime, TypeTimestamp, TypeTime:
+		if len(data) < 8 {
+			return nil, errJSONShort
+		}
+		v := int64(binary.LittleEndian.Uint64(data))
+		sign := ""
+		if v < 0 {
+			sign, v = "-", -v
+		}
+		intPart, frac := v>>24, v%(1<<24)
+		if colType == TypeTime {
+			return fmt.Sprintf("%v%02d:%02d:%02d", sign,
+				(intPart>>12)%(1<<10), (intPart>>6)%(1<<6), intPart%(1<<6),
+			) + formatFraction(frac, 6), nil
+		}
+		ymd, hms := intPart>>17, intPart%(1<<17)
+		ym := ymd >> 5
+		date := fmt.Sprintf("%04d-%02d-%02d", ym/13, ym%13, ymd%(1<<5))
+		if colType == TypeDate {
+			return date, nil
+		}
+		return date + fmt.Sprintf(" %02d:%02d:%02d", hms>>12, (hms>>6)%(1<<6), hms%(1<<6)) +
+			formatFraction(frac, 6), nil
+	}
+	return string(data), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,236 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+var errShortData = errors.New("unexpected end of page data")
+
+// julianDayOfEpoch is the Julian day number of 1970-01-01, used for decoding
+// INT96 timestamps.
+const julianDayOfEpoch = 2440588
+
+// bitWidth returns the number of bits required to store values up to max.
+func bitWidth(max int) int {
+	w := 0
+	for ; max > 0; max >>= 1 {
+		w++
+	}
+	return w
+}
+
+// readBits reads width bits starting at the bit offset off, where values are
+// packed from the least significant bit of each byte.
+func readBits(data []byte, off, width int) uint32 {
+	var v uint32
+	for b := 0; b < width; b++ {
+		i := off + b
+		if i/8 >= len(data) {
+			break
+		}
+		if (data[i/8]>>uint(i%8))&1 == 1 {
+			v |= 1 << uint(b)
+		}
+	}
+	return v
+}
+
+// decodeRLEHybrid decodes n values from data encoded with the RLE/bit-packing
+// hybrid encoding of the given bit width.
+func decodeRLEHybrid(data []byte, width, n int) ([]int32, error) {
+	if width < 0 || width > 32 {
+		return nil, fmt.Errorf("invalid bit width: %v", width)
+	}
+	out := make([]int32, 0, n)
+	pos := 0
+	for len(out) < n {
+		header, k := binary.Uvarint(data[pos:])
+		if k <= 0 {
+			return nil, errShortData
+		}
+		pos += k
+		if header&1 == 0 {
+			// Run length encoded run.
+			count := int(header >> 1)
+			byteWidth := (width + 7) / 8
+			if pos+byteWidth > len(data) {
+				return nil, errShortData
+			}
+			v := readBits(data[pos:pos+byteWidth], 0, width)
+			pos += byteWidth
+			for i := 0; i < count && len(out) < n; i++ {
+				out = append(out, int32(v))
+			}
+			continue
+		}
+		// Bit packed run of groups of eight values.
+		groups := header >> 1
+		if groups > uint64(n) {
+			// Only n values are needed and each group contains eight.
+			groups = uint64(n)
+		}
+		count := int(groups) * 8
+		nBytes := (count * width) / 8
+		if pos+nBytes > len(data) {
+			nBytes = len(data) - pos
+		}
+		packed := data[pos : pos+nBytes]
+		pos += nBytes
+		for i := 0; i < count && len(out) < n; i++ {
+			out = append(out, int32(readBits(packed, i*width, width)))
+		}
+	}
+	return out, nil
+}
+
+// decodePlain decodes n values of a column from data encoded with the PLAIN
+// encoding, converting each into a value that can be serialised as JSON.
+func decodePlain(data []byte, col *Column, n int) ([]interface{}, error) {
+	values := make([]interface{}, 0, n)
+	pos := 0
+	fixed := func(size int) ([]byte, error) {
+		if pos+size > len(data) {
+			return nil, errShortData
+		}
+		b := data[pos : pos+size]
+		pos += size
+		return b, nil
+	}
+	for i := 0; i < n; i++ {
+		var v interface{}
+		switch col.elem.typ {
+		case typeBoolean:
+			if i/8 >= len(data) {
+				return nil, errShortData
+			}
+			v = readBits(data, i, 1) == 1
+		case typeInt32:
+			b, err := fixed(4)
+			if err != nil {
+				return nil, err
+			}
+			v = int32(binary.LittleEndian.Uint32(b))
+		case typeInt64:
+			b, err := fixed(8)
+			if err != nil {
+				return nil, err
+			}
+			v = int64(binary.LittleEndian.Uint64(b))
+		case typeInt96:
+			b, err := fixed(12)
+			if err != nil {
+				return nil, err
+			}
+			nanos := int64(binary.LittleEndian.Uint64(b))
+			days := int64(binary.LittleEndian.Uint32(b[8:]))
+			v = time.Unix((days-julianDayOfEpoch)*86400, nanos).UTC().Format(time.RFC3339Nano)
+		case typeFloat:
+			b, err := fixed(4)
+			if err != nil {
+				return nil, err
+			}
+			v = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case typeDouble:
+			b, err := fixed(8)
+			if err != nil {
+				return nil, err
+			}
+			v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case typeByteArray:
+			lBytes, err := fixed(4)
+			if err != nil {
+				return nil, err
+			}
+			b, err := fixed(int(binary.LittleEndian.Uint32(lBytes)))
+			if err != nil {
+				return nil, err
+			}
+			v = col.convertBytes(b)
+		case typeFixedLenByteArray:
+			b, err := fixed(int(col.elem.typeLength))
+			if err != nil {
+				return nil, err
+			}
+			v = col.convertBytes(b)
+		default:
+			return nil, fmt.Errorf("unsupported physical type: %v", col.elem.typ)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// decodeValues decodes n non-null values of a column from a data page.
+func decodeValues(data []byte, encoding int32, col *Column, dict []interface{}, n int) ([]interface{}, error) {
+	switch encoding {
+	case encodingPlain:
+		return decodePlain(data, col, n)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if dict == nil {
+			return nil, errors.New("dictionary encoded page without a dictionary")
+		}
+		if len(data) == 0 {
+			if n == 0 {
+				return nil, nil
+			}
+			return nil, errShortData
+		}
+		indexes, err := decodeRLEHybrid(data[1:], int(data[0]), n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i, index := range indexes {
+			if index < 0 || int(index) >= len(dict) {
+				return nil, fmt.Errorf("dictionary index %v out of bounds", index)
+			}
+			values[i] = dict[index]
+		}
+		return values, nil
+	case encodingRLE:
+		if col.elem.typ != typeBoolean {
+			break
+		}
+		if len(data) < 4 {
+			return nil, errShortData
+		}
+		bools, err := decodeRLEHybrid(data[4:], 1, n)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i, b := range bools {
+			values[i] = b == 1
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding: %v", encoding)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,367 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package parquet implements a reader and a writer for Parquet files with flat
+// schemas.
+//
+// Columns of all physical types are supported when reading with the PLAIN and
+// dictionary encodings, version one and two data pages, and uncompressed,
+// snappy or gzip compressed column chunks. Nested schemas are not supported.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"unicode/utf8"
+
+	"github.com/golang/snappy"
+)
+
+//------------------------------------------------------------------------------
+
+var magic = []byte("PAR1")
+
+// ErrNotParquet is returned when attempting to open data that is not a Parquet
+// file.
+var ErrNotParquet = errors.New("data is not a parquet file")
+
+//------------------------------------------------------------------------------
+
+// Column describes a column of a Parquet file.
+type Column struct {
+	Name     string
+	Optional bool
+
+	elem schemaElement
+}
+
+// isString returns whether byte array values of the column are annotated as
+// strings.
+func (c *Column) isString() bool {
+	if c.elem.hasConverted {
+		switch c.elem.convertedType {
+		case convertedUTF8, convertedEnum, convertedJSON:
+			return true
+		}
+	}
+	switch c.elem.logicalType {
+	case logicalString, logicalEnum, logicalJSON:
+		return true
+	}
+	return false
+}
+
+// convertBytes converts a byte array value into a string when the column is
+// annotated as containing strings, otherwise a copy of the bytes is returned,
+// which is serialised as base64 within JSON documents.
+func (c *Column) convertBytes(b []byte) interface{} {
+	if c.isString() && utf8.Valid(b) {
+		return string(b)
+	}
+	cp := make([]byte, len(b))
+	copy(cp, b)
+	return cp
+}
+
+//------------------------------------------------------------------------------
+
+// File is a Parquet file opened for reading.
+type File struct {
+	r       io.ReaderAt
+	size    int64
+	meta    *fileMetaData
+	columns []Column
+}
+
+// Open parses the metadata of a Parquet file of a given size.
+func Open(r io.ReaderAt, size int64) (*File, error) {
+	if size < int64(len(magic)*2+4) {
+		return nil, ErrNotParquet
+	}
+
+	head := make([]byte, len(magic))
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return nil, err
+	}
+	tail := make([]byte, 4+len(magic))
+	if _, err := r.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(head, magic) || !bytes.Equal(tail[4:], magic) {
+		return nil, ErrNotParquet
+	}
+
+	footerLen := int64(binary.LittleEndian.Uint32(tail))
+	if footerLen > size-int64(len(tail)+len(magic)) {
+		return nil, fmt.Errorf("invalid footer length: %v", footerLen)
+	}
+	footer := make([]byte, footerLen)
+	if _, err := r.ReadAt(footer, size-int64(len(tail))-footerLen); err != nil {
+		return nil, err
+	}
+
+	meta, err := readFileMetaData(&thriftReader{data: footer})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file metadata: %v", err)
+	}
+	if len(meta.schema) == 0 {
+		return nil, errors.New("file metadata contains no schema")
+	}
+
+	f := &File{r: r, size: size, meta: meta}
+	for _, elem := range meta.schema[1:] {
+		if elem.numChildren > 0 || !elem.hasType {
+			return nil, fmt.Errorf("column '%v' is nested, nested schemas are not supported", elem.name)
+		}
+		if elem.repetition == repetitionRepeated {
+			return nil, fmt.Errorf("column '%v' is repeated, nested schemas are not supported", elem.name)
+		}
+		f.columns = append(f.columns, Column{
+			Name:     elem.name,
+			Optional: elem.repetition == repetitionOptional,
+			elem:     elem,
+		})
+	}
+	if int(meta.schema[0].numChildren) != len(f.columns) {
+		return nil, errors.New("schema root does not match the number of columns")
+	}
+	return f, nil
+}
+
+// Columns returns the columns of the file.
+func (f *File) Columns() []Column {
+	return f.columns
+}
+
+// NumRows returns the total number of rows in the file.
+func (f *File) NumRows() int64 {
+	return f.meta.numRows
+}
+
+// NumRowGroups returns the number of row groups in the file.
+func (f *File) NumRowGroups() int {
+	return len(f.meta.rowGroups)
+}
+
+// ReadRowGroup decodes all rows of a row group, where each row is a map of
+// column names to values. Null values of optional columns are included as nil.
+func (f *File) ReadRowGroup(i int) ([]map[string]interface{}, error) {
+	if i < 0 || i >= len(f.meta.rowGroups) {
+		return nil, fmt.Errorf("row group index %v out of bounds", i)
+	}
+	rg := f.meta.rowGroups[i]
+	if len(rg.columns) != len(f.columns) {
+		return nil, fmt.Errorf("row group %v has %v columns, expected %v", i, len(rg.columns), len(f.columns))
+	}
+
+	numRows := int(rg.numRows)
+	rows := make([]map[string]interface{}, numRows)
+	for j := range rows {
+		rows[j] = make(map[string]interface{}, len(f.columns))
+	}
+	for j := range f.columns {
+		col := &f.columns[j]
+		values, err := f.readColumnChunk(rg.columns[j], col, numRows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read column '%v': %v", col.Name, err)
+		}
+		for k, v := range values {
+			rows[k][col.Name] = v
+		}
+	}
+	return rows, nil
+}
+
+//------------------------------------------------------------------------------
+
+func decompress(codec int32, data []byte) ([]byte, error) {
+	switch codec {
+	case codecUncompressed:
+		return data, nil
+	case codecSnappy:
+		return snappy.Decode(nil, data)
+	case codecGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+	return nil, fmt.Errorf("unsupported compression codec: %v", codec)
+}
+
+// readDefLevels decodes the definition levels of an optional column, returning
+// the levels and the number of non-null values.
+func readDefLevels(data []byte, n int) ([]int32, int, error) {
+	levels, err := decodeRLEHybrid(data, bitWidth(1), n)
+	if err != nil {
+		return nil, 0, err
+	}
+	nonNull := 0
+	for _, l := range levels {
+		if l == 1 {
+			nonNull++
+		}
+	}
+	return levels, nonNull, nil
+}
+
+// mergeNulls expands decoded values with nils according to definition levels.
+func mergeNulls(levels []int32, values []interface{}) []interface{} {
+	if levels == nil {
+		return values
+	}
+	merged := make([]interface{}, len(levels))
+	k := 0
+	for i, l := range levels {
+		if l == 1 {
+			merged[i] = values[k]
+			k++
+		}
+	}
+	return merged
+}
+
+func (f *File) readColumnChunk(chunk columnChunk, col *Column, numRows int) ([]interface{}, error) {
+	if len(chunk.filePath) > 0 {
+		return nil, errors.New("column chunks in external files are not supported")
+	}
+	meta := chunk.meta
+	if meta == nil {
+		return nil, errors.New("column chunk has no metadata")
+	}
+	if meta.typ != col.elem.typ {
+		return nil, fmt.Errorf("column chunk type %v does not match schema type %v", meta.typ, col.elem.typ)
+	}
+
+	start := meta.dataPageOffset
+	if meta.dictionaryPageOffset > 0 && meta.dictionaryPageOffset < start {
+		start = meta.dictionaryPageOffset
+	}
+	if meta.totalCompressedSize < 0 || start < 0 || start+meta.totalCompressedSize > f.size {
+		return nil, errors.New("invalid column chunk offsets")
+	}
+	buf := make([]byte, meta.totalCompressedSize)
+	if _, err := f.r.ReadAt(buf, start); err != nil {
+		return nil, err
+	}
+
+	var dict []interface{}
+	values := make([]interface{}, 0, numRows)
+	t := &thriftReader{data: buf}
+	for len(values) < numRows && t.pos < len(buf) {
+		h, err := readPageHeader(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse page header: %v", err)
+		}
+		size := int(h.compressedPageSize)
+		if size < 0 || t.pos+size > len(buf) {
+			return nil, errShortData
+		}
+		page := buf[t.pos : t.pos+size]
+		t.pos += size
+
+		var levels []int32
+		var pageValues []interface{}
+		switch h.typ {
+		case pageDictionary:
+			if h.dictionaryPage == nil {
+				return nil, errors.New("dictionary page is missing its header")
+			}
+			if page, err = decompress(meta.codec, page); err != nil {
+				return nil, err
+			}
+			if dict, err = decodePlain(page, col, int(h.dictionaryPage.numValues)); err != nil {
+				return nil, fmt.Errorf("failed to decode dictionary: %v", err)
+			}
+			continue
+		case pageData:
+			dh := h.dataPage
+			if dh == nil {
+				return nil, errors.New("data page is missing its header")
+			}
+			if page, err = decompress(meta.codec, page); err != nil {
+				return nil, err
+			}
+			n := int(dh.numValues)
+			nonNull := n
+			if col.Optional {
+				if dh.defLevelEncoding != encodingRLE {
+					return nil, fmt.Errorf("unsupported definition level encoding: %v", dh.defLevelEncoding)
+				}
+				if len(page) < 4 {
+					return nil, errShortData
+				}
+				l := int(binary.LittleEndian.Uint32(page))
+				if l < 0 || 4+l > len(page) {
+					return nil, errShortData
+				}
+				if levels, nonNull, err = readDefLevels(page[4:4+l], n); err != nil {
+					return nil, err
+				}
+				page = page[4+l:]
+			}
+			if pageValues, err = decodeValues(page, dh.encoding, col, dict, nonNull); err != nil {
+				return nil, err
+			}
+		case pageDataV2:
+			dh := h.dataPageV2
+			if dh == nil {
+				return nil, errors.New("data page is missing its header")
+			}
+			repLen, defLen := int(dh.repLevelsByteLength), int(dh.defLevelsByteLength)
+			if repLen < 0 || defLen < 0 || repLen+defLen > len(page) {
+				return nil, errShortData
+			}
+			n := int(dh.numValues)
+			nonNull := n
+			if col.Optional {
+				if levels, nonNull, err = readDefLevels(page[repLen:repLen+defLen], n); err != nil {
+					return nil, err
+				}
+			}
+			page = page[repLen+defLen:]
+			if dh.isCompressed {
+				if page, err = decompress(meta.codec, page); err != nil {
+					return nil, err
+				}
+			}
+			if pageValues, err = decodeValues(page, dh.encoding, col, dict, nonNull); err != nil {
+				return nil, err
+			}
+		default:
+			// Index pages and unknown page types are skipped.
+			continue
+		}
+		values = append(values, mergeNulls(levels, pageValues)...)
+	}
+	if len(values) != numRows {
+		return nil, fmt.Errorf("decoded %v values, expected %v", len(values), numRows)
+	}
+	return values, nil
+}
+
+//------------------------------------------------------------------------------
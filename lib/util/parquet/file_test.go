@@ -0,0 +1,444 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+//------------------------------------------------------------------------------
+
+type testPage struct {
+	dictionary bool
+	v2         bool
+	encoding   int32
+	numValues  int
+	numNulls   int
+	levels     []byte
+	data       []byte
+}
+
+type testColumn struct {
+	elem  schemaElement
+	codec int32
+	pages []testPage
+}
+
+func compressTest(t *testing.T, codec int32, data []byte) []byte {
+	t.Helper()
+	switch codec {
+	case codecSnappy:
+		return snappy.Encode(nil, data)
+	case codecGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+	return data
+}
+
+func rleRuns(values []int32, width int) []byte {
+	var w thriftWriter
+	for _, v := range values {
+		w.uvarint(2)
+		for i := 0; i < (width+7)/8; i++ {
+			w.buf.WriteByte(byte(v >> uint(8*i)))
+		}
+	}
+	return w.buf.Bytes()
+}
+
+func bitPacked(values []int32, width int) []byte {
+	groups := (len(values) + 7) / 8
+	var w thriftWriter
+	w.uvarint(uint64(groups<<1 | 1))
+	packed := make([]byte, groups*width)
+	for i, v := range values {
+		for b := 0; b < width; b++ {
+			if (v>>uint(b))&1 == 1 {
+				off := i*width + b
+				packed[off/8] |= 1 << uint(off%8)
+			}
+		}
+	}
+	w.buf.Write(packed)
+	return w.buf.Bytes()
+}
+
+func plainInt64s(values ...int64) []byte {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[i*8:], uint64(v))
+	}
+	return b
+}
+
+func plainDoubles(values ...float64) []byte {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[i*8:], math.Float64bits(v))
+	}
+	return b
+}
+
+func plainStrings(values ...string) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(v)))
+		buf.WriteString(v)
+	}
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, columns []testColumn, rowGroups [][]testColumn, numRows []int64) []byte {
+	t.Helper()
+
+	var file bytes.Buffer
+	file.Write(magic)
+
+	type chunkMeta struct {
+		dictOffset, dataOffset, size int64
+		numValues                    int64
+	}
+	chunkMetas := make([][]chunkMeta, len(rowGroups))
+
+	for i, rg := range rowGroups {
+		for _, col := range rg {
+			var cm chunkMeta
+			start := int64(file.Len())
+			for _, p := range col.pages {
+				data := p.data
+				var uncompressedLen int
+				if p.v2 {
+					uncompressedLen = len(p.levels) + len(data)
+					data = append(append([]byte{}, p.levels...), compressTest(t, col.codec, data)...)
+				} else {
+					if p.levels != nil {
+						l := make([]byte, 4)
+						binary.LittleEndian.PutUint32(l, uint32(len(p.levels)))
+						data = append(append(l, p.levels...), data...)
+					}
+					uncompressedLen = len(data)
+					data = compressTest(t, col.codec, data)
+				}
+
+				var w thriftWriter
+				w.begin()
+				switch {
+				case p.dictionary:
+					w.i32(1, pageDictionary)
+				case p.v2:
+					w.i32(1, pageDataV2)
+				default:
+					w.i32(1, pageData)
+				}
+				w.i32(2, int32(uncompressedLen))
+				w.i32(3, int32(len(data)))
+				switch {
+				case p.dictionary:
+					w.structField(7)
+					w.i32(1, int32(p.numValues))
+					w.i32(2, encodingPlainDictionary)
+					w.end()
+				case p.v2:
+					w.structField(8)
+					w.i32(1, int32(p.numValues))
+					w.i32(2, int32(p.numNulls))
+					w.i32(3, int32(p.numValues))
+					w.i32(4, p.encoding)
+					w.i32(5, int32(len(p.levels)))
+					w.i32(6, 0)
+					w.boolean(7, true)
+					// Statistics are skipped by the reader.
+					w.structField(8)
+					w.str(1, "max")
+					w.end()
+					w.end()
+				default:
+					w.structField(5)
+					w.i32(1, int32(p.numValues))
+					w.i32(2, p.encoding)
+					w.i32(3, encodingRLE)
+					w.i32(4, encodingRLE)
+					w.end()
+				}
+				w.end()
+
+				if p.dictionary {
+					cm.dictOffset = int64(file.Len())
+				} else if cm.dataOffset == 0 {
+					cm.dataOffset = int64(file.Len())
+					cm.numValues = int64(p.numValues)
+				} else {
+					cm.numValues += int64(p.numValues)
+				}
+				file.Write(w.buf.Bytes())
+				file.Write(data)
+			}
+			cm.size = int64(file.Len()) - start
+			chunkMetas[i] = append(chunkMetas[i], cm)
+		}
+	}
+
+	var w thriftWriter
+	w.begin()
+	w.i32(1, 1)
+	w.list(2, thriftStruct, len(columns)+1)
+	w.begin()
+	w.str(4, "schema")
+	w.i32(5, int32(len(columns)))
+	w.end()
+	for _, col := range columns {
+		w.begin()
+		if col.elem.hasType {
+			w.i32(1, col.elem.typ)
+		}
+		if col.elem.typeLength > 0 {
+			w.i32(2, col.elem.typeLength)
+		}
+		w.i32(3, col.elem.repetition)
+		w.str(4, col.elem.name)
+		if col.elem.numChildren > 0 {
+			w.i32(5, col.elem.numChildren)
+		}
+		if col.elem.hasConverted {
+			w.i32(6, col.elem.convertedType)
+		}
+		if col.elem.logicalType > 0 {
+			w.structField(10)
+			w.structField(col.elem.logicalType)
+			w.end()
+			w.end()
+		}
+		w.end()
+	}
+	var total int64
+	for _, n := range numRows {
+		total += n
+	}
+	w.i64(3, total)
+	w.list(4, thriftStruct, len(rowGroups))
+	for i, rg := range rowGroups {
+		w.begin()
+		w.list(1, thriftStruct, len(rg))
+		for j, col := range rg {
+			cm := chunkMetas[i][j]
+			w.begin()
+			w.i64(2, cm.dataOffset)
+			w.structField(3)
+			w.i32(1, col.elem.typ)
+			w.list(2, thriftI32, 2)
+			w.varint(encodingPlain)
+			w.varint(encodingRLE)
+			w.list(3, thriftBinary, 1)
+			w.uvarint(uint64(len(col.elem.name)))
+			w.buf.WriteString(col.elem.name)
+			w.i32(4, col.codec)
+			w.i64(5, cm.numValues)
+			w.i64(6, cm.size)
+			w.i64(7, cm.size)
+			w.i64(9, cm.dataOffset)
+			if cm.dictOffset > 0 {
+				w.i64(11, cm.dictOffset)
+			}
+			w.end()
+			w.end()
+		}
+		w.i64(2, 0)
+		w.i64(3, numRows[i])
+		w.end()
+	}
+	// Key value metadata is skipped by the reader.
+	w.list(5, thriftStruct, 1)
+	w.begin()
+	w.str(1, "foo")
+	w.str(2, "bar")
+	w.end()
+	w.str(6, "benthos test")
+	w.end()
+
+	file.Write(w.buf.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(w.buf.Len()))
+	file.Write(magic)
+	return file.Bytes()
+}
+
+//------------------------------------------------------------------------------
+
+func TestReadFile(t *testing.T) {
+	idCol := schemaElement{typ: typeInt64, hasType: true, repetition: repetitionRequired, name: "id"}
+	nameCol := schemaElement{typ: typeByteArray, hasType: true, repetition: repetitionOptional, name: "name", logicalType: logicalString}
+	scoreCol := schemaElement{typ: typeDouble, hasType: true, repetition: repetitionOptional, name: "score"}
+	flagCol := schemaElement{typ: typeBoolean, hasType: true, repetition: repetitionRequired, name: "flag"}
+	rawCol := schemaElement{typ: typeByteArray, hasType: true, repetition: repetitionRequired, name: "raw"}
+	tsCol := schemaElement{typ: typeInt96, hasType: true, repetition: repetitionRequired, name: "ts"}
+
+	ts := make([]byte, 24)
+	binary.LittleEndian.PutUint64(ts, uint64(3600*1e9))
+	binary.LittleEndian.PutUint32(ts[8:], julianDayOfEpoch+1)
+	binary.LittleEndian.PutUint32(ts[20:], julianDayOfEpoch)
+
+	schema := []testColumn{{elem: idCol}, {elem: nameCol}, {elem: scoreCol}, {elem: flagCol}, {elem: rawCol}, {elem: tsCol}}
+	rowGroups := [][]testColumn{
+		{
+			{elem: idCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 2, data: plainInt64s(1, 2)},
+				{encoding: encodingPlain, numValues: 1, data: plainInt64s(3)},
+			}},
+			{elem: nameCol, codec: codecSnappy, pages: []testPage{
+				{dictionary: true, numValues: 2, data: plainStrings("foo", "bar")},
+				{encoding: encodingRLEDictionary, numValues: 3, levels: rleRuns([]int32{1, 0, 1}, 1), data: append([]byte{1}, bitPacked([]int32{1, 0}, 1)...)},
+			}},
+			{elem: scoreCol, codec: codecGzip, pages: []testPage{
+				{v2: true, encoding: encodingPlain, numValues: 3, numNulls: 1, levels: bitPacked([]int32{1, 1, 0}, 1), data: plainDoubles(1.5, 2.5)},
+			}},
+			{elem: flagCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 3, data: []byte{5}},
+			}},
+			{elem: rawCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 3, data: plainStrings("a", "b", "c")},
+			}},
+			{elem: tsCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 3, data: append(append([]byte{}, ts...), ts[:12]...)},
+			}},
+		},
+		{
+			{elem: idCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 1, data: plainInt64s(4)},
+			}},
+			{elem: nameCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 1, levels: rleRuns([]int32{1}, 1), data: plainStrings("baz")},
+			}},
+			{elem: scoreCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 1, levels: rleRuns([]int32{0}, 1)},
+			}},
+			{elem: flagCol, pages: []testPage{
+				{v2: true, encoding: encodingRLE, numValues: 1, data: append([]byte{2, 0, 0, 0}, rleRuns([]int32{1}, 1)...)},
+			}},
+			{elem: rawCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 1, data: plainStrings("d")},
+			}},
+			{elem: tsCol, pages: []testPage{
+				{encoding: encodingPlain, numValues: 1, data: ts[12:]},
+			}},
+		},
+	}
+
+	data := writeTestFile(t, schema, rowGroups, []int64{3, 1})
+	f, err := Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := int64(4), f.NumRows(); exp != act {
+		t.Errorf("Wrong number of rows: %v != %v", act, exp)
+	}
+	if exp, act := 2, f.NumRowGroups(); exp != act {
+		t.Fatalf("Wrong number of row groups: %v != %v", act, exp)
+	}
+	if exp, act := 6, len(f.Columns()); exp != act {
+		t.Fatalf("Wrong number of columns: %v != %v", act, exp)
+	}
+	if !f.Columns()[1].Optional || f.Columns()[0].Optional {
+		t.Error("Wrong optional flags on columns")
+	}
+
+	exp := []string{
+		`{"flag":true,"id":1,"name":"bar","raw":"YQ==","score":1.5,"ts":"1970-01-02T01:00:00Z"}`,
+		`{"flag":false,"id":2,"name":null,"raw":"Yg==","score":2.5,"ts":"1970-01-01T00:00:00Z"}`,
+		`{"flag":true,"id":3,"name":"foo","raw":"Yw==","score":null,"ts":"1970-01-02T01:00:00Z"}`,
+		`{"flag":true,"id":4,"name":"baz","raw":"ZA==","score":null,"ts":"1970-01-01T00:00:00Z"}`,
+	}
+	var act []string
+	for i := 0; i < f.NumRowGroups(); i++ {
+		rows, err := f.ReadRowGroup(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			b, err := json.Marshal(row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			act = append(act, string(b))
+		}
+	}
+	if len(exp) != len(act) {
+		t.Fatalf("Wrong rows: %v != %v", act, exp)
+	}
+	for i := range exp {
+		if exp[i] != act[i] {
+			t.Errorf("Wrong row %v: %v != %v", i, act[i], exp[i])
+		}
+	}
+
+	if _, err = f.ReadRowGroup(2); err == nil {
+		t.Error("Expected error from out of bounds row group")
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("PAR1"),
+		[]byte("PAR1\x00\x00\x00\x00PAR2"),
+		[]byte("PAR1\xff\x00\x00\x00PAR1"),
+	} {
+		if _, err := Open(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Errorf("Expected error from data: %q", data)
+		}
+	}
+
+	nested := schemaElement{repetition: repetitionOptional, name: "nested", numChildren: 1}
+	data := writeTestFile(t, []testColumn{{elem: nested}}, nil, nil)
+	if _, err := Open(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("Expected error from nested schema")
+	}
+}
+
+func TestRLEHybrid(t *testing.T) {
+	data := append(rleRuns([]int32{5, 5}, 3), bitPacked([]int32{1, 2, 3, 4, 5, 6, 7, 0, 1}, 3)...)
+	values, err := decodeRLEHybrid(data, 3, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []int32{5, 5, 1, 2, 3, 4, 5, 6, 7, 0, 1}
+	for i := range exp {
+		if exp[i] != values[i] {
+			t.Errorf("Wrong value at %v: %v != %v", i, values[i], exp[i])
+		}
+	}
+
+	if _, err = decodeRLEHybrid([]byte{4}, 3, 2); err == nil {
+		t.Error("Expected error from truncated data")
+	}
+}
+
+//------------------------------------------------------------------------------
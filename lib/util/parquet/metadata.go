@@ -0,0 +1,375 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+//------------------------------------------------------------------------------
+
+// Physical types of Parquet columns.
+const (
+	typeBoolean           = 0
+	typeInt32             = 1
+	typeInt64             = 2
+	typeInt96             = 3
+	typeFloat             = 4
+	typeDouble            = 5
+	typeByteArray         = 6
+	typeFixedLenByteArray = 7
+)
+
+// Repetition types of Parquet schema elements.
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	repetitionRepeated = 2
+)
+
+// Converted types of Parquet schema elements that are relevant when decoding.
+const (
+	convertedUTF8 = 0
+	convertedEnum = 4
+	convertedJSON = 19
+)
+
+// Logical types of Parquet schema elements that are relevant when decoding,
+// these are the field ids of the LogicalType union.
+const (
+	logicalString = 1
+	logicalEnum   = 4
+	logicalJSON   = 7
+)
+
+// Encodings of Parquet pages.
+const (
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRLE             = 3
+	encodingBitPacked       = 4
+	encodingRLEDictionary   = 8
+)
+
+// Compression codecs of Parquet column chunks.
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+)
+
+// Types of Parquet pages.
+const (
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+//------------------------------------------------------------------------------
+
+type schemaElement struct {
+	typ           int32
+	hasType       bool
+	typeLength    int32
+	repetition    int32
+	name          string
+	numChildren   int32
+	convertedType int32
+	hasConverted  bool
+	logicalType   int16
+}
+
+type columnMetaData struct {
+	typ                  int32
+	pathInSchema         []string
+	codec                int32
+	numValues            int64
+	totalCompressedSize  int64
+	dataPageOffset       int64
+	dictionaryPageOffset int64
+}
+
+type columnChunk struct {
+	filePath string
+	meta     *columnMetaData
+}
+
+type rowGroup struct {
+	columns []columnChunk
+	numRows int64
+}
+
+type fileMetaData struct {
+	schema    []schemaElement
+	numRows   int64
+	rowGroups []rowGroup
+}
+
+type dataPageHeader struct {
+	numValues        int32
+	encoding         int32
+	defLevelEncoding int32
+}
+
+type dictionaryPageHeader struct {
+	numValues int32
+	encoding  int32
+}
+
+type dataPageHeaderV2 struct {
+	numValues           int32
+	numNulls            int32
+	encoding            int32
+	defLevelsByteLength int32
+	repLevelsByteLength int32
+	isCompressed        bool
+}
+
+type pageHeader struct {
+	typ                  int32
+	uncompressedPageSize int32
+	compressedPageSize   int32
+	dataPage             *dataPageHeader
+	dictionaryPage       *dictionaryPageHeader
+	dataPageV2           *dataPageHeaderV2
+}
+
+//------------------------------------------------------------------------------
+
+func readList(t *thriftReader, fn func() error) error {
+	_, size, err := t.readListHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < size; i++ {
+		if err = fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFileMetaData(t *thriftReader) (*fileMetaData, error) {
+	var m fileMetaData
+	err := t.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 2 && typ == thriftList:
+			err = readList(t, func() error {
+				e, lerr := readSchemaElement(t)
+				if lerr == nil {
+					m.schema = append(m.schema, e)
+				}
+				return lerr
+			})
+		case id == 3 && typ == thriftI64:
+			m.numRows, err = t.readVarint()
+		case id == 4 && typ == thriftList:
+			err = readList(t, func() error {
+				rg, lerr := readRowGroup(t)
+				if lerr == nil {
+					m.rowGroups = append(m.rowGroups, rg)
+				}
+				return lerr
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return &m, err
+}
+
+func readSchemaElement(t *thriftReader) (schemaElement, error) {
+	var e schemaElement
+	err := t.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			e.hasType = true
+			e.typ, err = t.readI32()
+		case id == 2 && typ == thriftI32:
+			e.typeLength, err = t.readI32()
+		case id == 3 && typ == thriftI32:
+			e.repetition, err = t.readI32()
+		case id == 4 && typ == thriftBinary:
+			e.name, err = t.readString()
+		case id == 5 && typ == thriftI32:
+			e.numChildren, err = t.readI32()
+		case id == 6 && typ == thriftI32:
+			e.hasConverted = true
+			e.convertedType, err = t.readI32()
+		case id == 10 && typ == thriftStruct:
+			// LogicalType is a union, so the id of the set field identifies
+			// the type.
+			err = t.readStruct(func(id int16, typ byte) (bool, error) {
+				e.logicalType = id
+				return false, nil
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return e, err
+}
+
+func readRowGroup(t *thriftReader) (rowGroup, error) {
+	var rg rowGroup
+	err := t.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftList:
+			err = readList(t, func() error {
+				c, lerr := readColumnChunk(t)
+				if lerr == nil {
+					rg.columns = append(rg.columns, c)
+				}
+				return lerr
+			})
+		case id == 3 && typ == thriftI64:
+			rg.numRows, err = t.readVarint()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return rg, err
+}
+
+func readColumnChunk(t *thriftReader) (columnChunk, error) {
+	var c columnChunk
+	err := t.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftBinary:
+			c.filePath, err = t.readString()
+		case id == 3 && typ == thriftStruct:
+			c.meta, err = readColumnMetaData(t)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return c, err
+}
+
+func readColumnMetaData(t *thriftReader) (*columnMetaData, error) {
+	var m columnMetaData
+	err := t.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			m.typ, err = t.readI32()
+		case id == 3 && typ == thriftList:
+			err = readList(t, func() error {
+				s, lerr := t.readString()
+				if lerr == nil {
+					m.pathInSchema = append(m.pathInSchema, s)
+				}
+				return lerr
+			})
+		case id == 4 && typ == thriftI32:
+			m.codec, err = t.readI32()
+		case id == 5 && typ == thriftI64:
+			m.numValues, err = t.readVarint()
+		case id == 7 && typ == thriftI64:
+			m.totalCompressedSize, err = t.readVarint()
+		case id == 9 && typ == thriftI64:
+			m.dataPageOffset, err = t.readVarint()
+		case id == 11 && typ == thriftI64:
+			m.dictionaryPageOffset, err = t.readVarint()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return &m, err
+}
+
+func readPageHeader(t *thriftReader) (*pageHeader, error) {
+	var h pageHeader
+	err := t.readStruct(func(id int16, typ byte) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			h.typ, err = t.readI32()
+		case id == 2 && typ == thriftI32:
+			h.uncompressedPageSize, err = t.readI32()
+		case id == 3 && typ == thriftI32:
+			h.compressedPageSize, err = t.readI32()
+		case id == 5 && typ == thriftStruct:
+			h.dataPage = &dataPageHeader{}
+			err = t.readStruct(func(id int16, typ byte) (bool, error) {
+				var err error
+				switch {
+				case id == 1 && typ == thriftI32:
+					h.dataPage.numValues, err = t.readI32()
+				case id == 2 && typ == thriftI32:
+					h.dataPage.encoding, err = t.readI32()
+				case id == 3 && typ == thriftI32:
+					h.dataPage.defLevelEncoding, err = t.readI32()
+				default:
+					return false, nil
+				}
+				return true, err
+			})
+		case id == 7 && typ == thriftStruct:
+			h.dictionaryPage = &dictionaryPageHeader{}
+			err = t.readStruct(func(id int16, typ byte) (bool, error) {
+				var err error
+				switch {
+				case id == 1 && typ == thriftI32:
+					h.dictionaryPage.numValues, err = t.readI32()
+				case id == 2 && typ == thriftI32:
+					h.dictionaryPage.encoding, err = t.readI32()
+				default:
+					return false, nil
+				}
+				return true, err
+			})
+		case id == 8 && typ == thriftStruct:
+			h.dataPageV2 = &dataPageHeaderV2{isCompressed: true}
+			err = t.readStruct(func(id int16, typ byte) (bool, error) {
+				var err error
+				switch {
+				case id == 1 && typ == thriftI32:
+					h.dataPageV2.numValues, err = t.readI32()
+				case id == 2 && typ == thriftI32:
+					h.dataPageV2.numNulls, err = t.readI32()
+				case id == 4 && typ == thriftI32:
+					h.dataPageV2.encoding, err = t.readI32()
+				case id == 5 && typ == thriftI32:
+					h.dataPageV2.defLevelsByteLength, err = t.readI32()
+				case id == 6 && typ == thriftI32:
+					h.dataPageV2.repLevelsByteLength, err = t.readI32()
+				case id == 7 && (typ == thriftBoolTrue || typ == thriftBoolFalse):
+					h.dataPageV2.isCompressed = t.readBool(typ)
+				default:
+					return false, nil
+				}
+				return true, err
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return &h, err
+}
+
+//------------------------------------------------------------------------------
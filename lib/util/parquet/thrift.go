@@ -0,0 +1,324 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+//------------------------------------------------------------------------------
+
+// Thrift compact protocol type identifiers.
+const (
+	thriftStop         = 0
+	thriftBoolTrue     = 1
+	thriftBoolFalse    = 2
+	thriftByte         = 3
+	thriftI16          = 4
+	thriftI32          = 5
+	thriftI64          = 6
+	thriftDouble       = 7
+	thriftBinary       = 8
+	thriftList         = 9
+	thriftSet          = 10
+	thriftMap          = 11
+	thriftStruct       = 12
+	thriftMaxNestDepth = 64
+)
+
+var errThriftEOF = errors.New("unexpected end of thrift data")
+
+// thriftReader decodes values encoded with the thrift compact protocol, which
+// is used by Parquet for all file and page metadata.
+type thriftReader struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func (t *thriftReader) readByte() (byte, error) {
+	if t.pos >= len(t.data) {
+		return 0, errThriftEOF
+	}
+	b := t.data[t.pos]
+	t.pos++
+	return b, nil
+}
+
+func (t *thriftReader) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(t.data[t.pos:])
+	if n <= 0 {
+		return 0, errThriftEOF
+	}
+	t.pos += n
+	return v, nil
+}
+
+func (t *thriftReader) readVarint() (int64, error) {
+	v, err := t.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+func (t *thriftReader) readI32() (int32, error) {
+	v, err := t.readVarint()
+	return int32(v), err
+}
+
+func (t *thriftReader) readBinary() ([]byte, error) {
+	l, err := t.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(t.data)-t.pos) < l {
+		return nil, errThriftEOF
+	}
+	b := t.data[t.pos : t.pos+int(l)]
+	t.pos += int(l)
+	return b, nil
+}
+
+func (t *thriftReader) readString() (string, error) {
+	b, err := t.readBinary()
+	return string(b), err
+}
+
+func (t *thriftReader) readDouble() (float64, error) {
+	if len(t.data)-t.pos < 8 {
+		return 0, errThriftEOF
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(t.data[t.pos:]))
+	t.pos += 8
+	return v, nil
+}
+
+// readListHeader returns the element type and size of a list or set.
+func (t *thriftReader) readListHeader() (byte, int, error) {
+	b, err := t.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := int(b >> 4)
+	if size == 15 {
+		var s uint64
+		if s, err = t.readUvarint(); err != nil {
+			return 0, 0, err
+		}
+		if s > uint64(len(t.data)) {
+			return 0, 0, fmt.Errorf("thrift list size %v exceeds data length", s)
+		}
+		size = int(s)
+	}
+	return b & 0x0f, size, nil
+}
+
+// readStruct iterates the fields of a struct, calling fn with the id and type
+// of each field. The function must either consume the field value or return
+// false in order for it to be skipped.
+func (t *thriftReader) readStruct(fn func(id int16, typ byte) (bool, error)) error {
+	if t.depth++; t.depth > thriftMaxNestDepth {
+		return errors.New("thrift structs nested too deeply")
+	}
+	defer func() {
+		t.depth--
+	}()
+
+	var lastID int16
+	for {
+		b, err := t.readByte()
+		if err != nil {
+			return err
+		}
+		if b == thriftStop {
+			return nil
+		}
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			lastID += delta
+		} else {
+			var id int64
+			if id, err = t.readVarint(); err != nil {
+				return err
+			}
+			lastID = int16(id)
+		}
+		consumed, err := fn(lastID, typ)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			if err = t.skip(typ); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readBool reads the value of a boolean field, which in the compact protocol
+// is encoded within the field type itself.
+func (t *thriftReader) readBool(typ byte) bool {
+	return typ == thriftBoolTrue
+}
+
+func (t *thriftReader) skip(typ byte) error {
+	var err error
+	switch typ {
+	case thriftBoolTrue, thriftBoolFalse:
+	case thriftByte:
+		_, err = t.readByte()
+	case thriftI16, thriftI32, thriftI64:
+		_, err = t.readVarint()
+	case thriftDouble:
+		_, err = t.readDouble()
+	case thriftBinary:
+		_, err = t.readBinary()
+	case thriftList, thriftSet:
+		var elemType byte
+		var size int
+		if elemType, size, err = t.readListHeader(); err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if err = t.skipElem(elemType); err != nil {
+				return err
+			}
+		}
+	case thriftMap:
+		var size uint64
+		if size, err = t.readUvarint(); err != nil || size == 0 {
+			return err
+		}
+		var types byte
+		if types, err = t.readByte(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < size; i++ {
+			if err = t.skipElem(types >> 4); err != nil {
+				return err
+			}
+			if err = t.skipElem(types & 0x0f); err != nil {
+				return err
+			}
+		}
+	case thriftStruct:
+		err = t.readStruct(func(int16, byte) (bool, error) {
+			return false, nil
+		})
+	default:
+		err = fmt.Errorf("unknown thrift type: %v", typ)
+	}
+	return err
+}
+
+// skipElem skips a container element, where unlike struct fields booleans are
+// encoded as a single byte.
+func (t *thriftReader) skipElem(typ byte) error {
+	if typ == thriftBoolTrue || typ == thriftBoolFalse {
+		_, err := t.readByte()
+		return err
+	}
+	return t.skip(typ)
+}
+
+//------------------------------------------------------------------------------
+
+// thriftWriter encodes values with the thrift compact protocol, where begin
+// and end must wrap the fields of each struct.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (w *thriftWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (w *thriftWriter) varint(v int64) {
+	w.uvarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta<<4) | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(thriftStop)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) structField(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftWriter) str(id int16, v string) {
+	w.field(id, thriftBinary)
+	w.uvarint(uint64(len(v)))
+	w.buf.WriteString(v)
+}
+
+func (w *thriftWriter) boolean(id int16, v bool) {
+	if v {
+		w.field(id, thriftBoolTrue)
+	} else {
+		w.field(id, thriftBoolFalse)
+	}
+}
+
+func (w *thriftWriter) list(id int16, elemType byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size<<4) | elemType)
+	} else {
+		w.buf.WriteByte(0xf0 | elemType)
+		w.uvarint(uint64(size))
+	}
+}
+
+//------------------------------------------------------------------------------
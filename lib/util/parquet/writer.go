@@ -0,0 +1,345 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/golang/snappy"
+)
+
+//------------------------------------------------------------------------------
+
+// Compression is a codec used to compress the column chunks of written files.
+type Compression int32
+
+// Compression codecs supported when writing files.
+const (
+	CompressionNone   Compression = codecUncompressed
+	CompressionSnappy Compression = codecSnappy
+	CompressionGzip   Compression = codecGzip
+)
+
+//------------------------------------------------------------------------------
+
+// WriteRows encodes rows as a Parquet file with a single row group, where each
+// row is a map of column names to values decoded from JSON.
+//
+// The schema is the union of all column names, where each column is optional
+// and rows without a value for it are written as null. Columns where all values
+// are booleans are written as booleans, columns where all values are whole
+// numbers are written as 64-bit integers and columns where all values are
+// numbers are written as doubles. All other columns are written as strings,
+// where values that are not strings are serialised as JSON.
+func WriteRows(w io.Writer, rows []map[string]interface{}, compression Compression) error {
+	switch compression {
+	case CompressionNone, CompressionSnappy, CompressionGzip:
+	default:
+		return fmt.Errorf("unsupported compression codec: %v", compression)
+	}
+
+	names := []string{}
+	seen := map[string]struct{}{}
+	for _, row := range rows {
+		for k := range row {
+			if _, exists := seen[k]; !exists {
+				seen[k] = struct{}{}
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var file bytes.Buffer
+	file.Write(magic)
+
+	elems := make([]schemaElement, len(names))
+	chunks := make([]columnChunk, len(names))
+	uncompressedSizes := make([]int64, len(names))
+	for i, name := range names {
+		values := make([]interface{}, len(rows))
+		for j, row := range rows {
+			values[j] = row[name]
+		}
+		elems[i] = schemaElement{
+			typ:        columnType(values),
+			hasType:    true,
+			repetition: repetitionOptional,
+			name:       name,
+		}
+		if elems[i].typ == typeByteArray {
+			elems[i].convertedType = convertedUTF8
+			elems[i].hasConverted = true
+			elems[i].logicalType = logicalString
+		}
+
+		offset := int64(file.Len())
+		size, uncompressedSize, err := writeColumnChunk(&file, elems[i].typ, values, compression)
+		if err != nil {
+			return fmt.Errorf("failed to write column '%v': %v", name, err)
+		}
+		chunks[i] = columnChunk{
+			meta: &columnMetaData{
+				typ:                 elems[i].typ,
+				pathInSchema:        []string{name},
+				codec:               int32(compression),
+				numValues:           int64(len(rows)),
+				totalCompressedSize: size,
+				dataPageOffset:      offset,
+			},
+		}
+		uncompressedSizes[i] = uncompressedSize
+	}
+
+	footer := writeFileMetaData(elems, chunks, uncompressedSizes, int64(len(rows)))
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.Write(magic)
+
+	_, err := file.WriteTo(w)
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// columnType returns the physical type that all non-null values of a column
+// can be written as.
+func columnType(values []interface{}) int32 {
+	typ := int32(-1)
+	for _, v := range values {
+		var vTyp int32
+		switch t := v.(type) {
+		case nil:
+			continue
+		case bool:
+			vTyp = typeBoolean
+		case float64:
+			vTyp = typeDouble
+			if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+				vTyp = typeInt64
+			}
+		default:
+			return typeByteArray
+		}
+		switch {
+		case typ == -1 || typ == vTyp:
+			typ = vTyp
+		case typ != typeBoolean && vTyp != typeBoolean:
+			typ = typeDouble
+		default:
+			return typeByteArray
+		}
+	}
+	if typ == -1 {
+		return typeByteArray
+	}
+	return typ
+}
+
+// encodeDefLevels encodes the definition levels of an optional column with the
+// RLE hybrid encoding, where a level of one indicates a value is present.
+func encodeDefLevels(values []interface{}) []byte {
+	var w thriftWriter
+	for i := 0; i < len(values); {
+		present := values[i] != nil
+		run := 1
+		for i+run < len(values) && (values[i+run] != nil) == present {
+			run++
+		}
+		w.uvarint(uint64(run) << 1)
+		if present {
+			w.buf.WriteByte(1)
+		} else {
+			w.buf.WriteByte(0)
+		}
+		i += run
+	}
+	return w.buf.Bytes()
+}
+
+// encodePlain encodes the non-null values of a column with the PLAIN encoding.
+func encodePlain(typ int32, values []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	var bits []byte
+	n := 0
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		switch typ {
+		case typeBoolean:
+			if n%8 == 0 {
+				bits = append(bits, 0)
+			}
+			if v.(bool) {
+				bits[n/8] |= 1 << uint(n%8)
+			}
+		case typeInt64:
+			binary.Write(&buf, binary.LittleEndian, int64(v.(float64)))
+		case typeDouble:
+			binary.Write(&buf, binary.LittleEndian, math.Float64bits(v.(float64)))
+		default:
+			s, isStr := v.(string)
+			if !isStr {
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				s = string(b)
+			}
+			binary.Write(&buf, binary.LittleEndian, uint32(len(s)))
+			buf.WriteString(s)
+		}
+		n++
+	}
+	if typ == typeBoolean {
+		return bits, nil
+	}
+	return buf.Bytes(), nil
+}
+
+func compress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return data, nil
+}
+
+// writeColumnChunk writes the values of a column as a single data page,
+// returning the total compressed and uncompressed sizes of the chunk.
+func writeColumnChunk(file *bytes.Buffer, typ int32, values []interface{}, compression Compression) (int64, int64, error) {
+	levels := encodeDefLevels(values)
+	plain, err := encodePlain(typ, values)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	page := make([]byte, 4, 4+len(levels)+len(plain))
+	binary.LittleEndian.PutUint32(page, uint32(len(levels)))
+	page = append(append(page, levels...), plain...)
+
+	compressed, err := compress(compression, page)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var w thriftWriter
+	w.begin()
+	w.i32(1, pageData)
+	w.i32(2, int32(len(page)))
+	w.i32(3, int32(len(compressed)))
+	w.structField(5)
+	w.i32(1, int32(len(values)))
+	w.i32(2, encodingPlain)
+	w.i32(3, encodingRLE)
+	w.i32(4, encodingRLE)
+	w.end()
+	w.end()
+
+	file.Write(w.buf.Bytes())
+	file.Write(compressed)
+	return int64(w.buf.Len() + len(compressed)), int64(w.buf.Len() + len(page)), nil
+}
+
+// writeFileMetaData encodes the footer of a file with a single row group.
+func writeFileMetaData(elems []schemaElement, chunks []columnChunk, uncompressedSizes []int64, numRows int64) []byte {
+	var w thriftWriter
+	w.begin()
+	w.i32(1, 1)
+	w.list(2, thriftStruct, len(elems)+1)
+	w.begin()
+	w.str(4, "schema")
+	w.i32(5, int32(len(elems)))
+	w.end()
+	for _, elem := range elems {
+		w.begin()
+		w.i32(1, elem.typ)
+		w.i32(3, elem.repetition)
+		w.str(4, elem.name)
+		if elem.hasConverted {
+			w.i32(6, elem.convertedType)
+		}
+		if elem.logicalType > 0 {
+			w.structField(10)
+			w.structField(elem.logicalType)
+			w.end()
+			w.end()
+		}
+		w.end()
+	}
+	w.i64(3, numRows)
+
+	var totalSize int64
+	for _, size := range uncompressedSizes {
+		totalSize += size
+	}
+
+	w.list(4, thriftStruct, 1)
+	w.begin()
+	w.list(1, thriftStruct, len(chunks))
+	for i, c := range chunks {
+		w.begin()
+		w.i64(2, c.meta.dataPageOffset)
+		w.structField(3)
+		w.i32(1, c.meta.typ)
+		w.list(2, thriftI32, 2)
+		w.varint(encodingPlain)
+		w.varint(encodingRLE)
+		w.list(3, thriftBinary, len(c.meta.pathInSchema))
+		for _, p := range c.meta.pathInSchema {
+			w.uvarint(uint64(len(p)))
+			w.buf.WriteString(p)
+		}
+		w.i32(4, c.meta.codec)
+		w.i64(5, c.meta.numValues)
+		w.i64(6, uncompressedSizes[i])
+		w.i64(7, c.meta.totalCompressedSize)
+		w.i64(9, c.meta.dataPageOffset)
+		w.end()
+		w.end()
+	}
+	w.i64(2, totalSize)
+	w.i64(3, numRows)
+	w.end()
+	w.str(6, "benthos")
+	w.end()
+	return w.buf.Bytes()
+}
+
+//------------------------------------------------------------------------------
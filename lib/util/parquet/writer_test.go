@@ -0,0 +1,90 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+func TestWriteRows(t *testing.T) {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(`[
+		{"id":1,"name":"foo","score":1.5,"ok":true,"tags":["a","b"]},
+		{"id":2,"score":2,"ok":false},
+		{"id":3,"name":"baz","mixed":"x"},
+		{"mixed":4}
+	]`), &rows); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []map[string]interface{}{
+		{"id": int64(1), "name": "foo", "score": 1.5, "ok": true, "tags": `["a","b"]`, "mixed": nil},
+		{"id": int64(2), "name": nil, "score": float64(2), "ok": false, "tags": nil, "mixed": nil},
+		{"id": int64(3), "name": "baz", "score": nil, "ok": nil, "tags": nil, "mixed": "x"},
+		{"id": nil, "name": nil, "score": nil, "ok": nil, "tags": nil, "mixed": "4"},
+	}
+
+	for _, compression := range []Compression{CompressionNone, CompressionSnappy, CompressionGzip} {
+		var buf bytes.Buffer
+		if err := WriteRows(&buf, rows, compression); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := int64(4), f.NumRows(); exp != act {
+			t.Errorf("Wrong count of rows: %v != %v", act, exp)
+		}
+		var names []string
+		for _, col := range f.Columns() {
+			if !col.Optional {
+				t.Errorf("Expected column '%v' to be optional", col.Name)
+			}
+			names = append(names, col.Name)
+		}
+		if exp := []string{"id", "mixed", "name", "ok", "score", "tags"}; !reflect.DeepEqual(exp, names) {
+			t.Errorf("Wrong columns: %v != %v", names, exp)
+		}
+
+		act, err := f.ReadRowGroup(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong rows for compression %v: %v != %v", compression, act, exp)
+		}
+	}
+}
+
+func TestWriteRowsBadCompression(t *testing.T) {
+	if err := WriteRows(&bytes.Buffer{}, nil, Compression(10)); err == nil {
+		t.Error("Expected error from unsupported compression")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,391 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+//------------------------------------------------------------------------------
+
+// EventType describes the kind of a decoded replication event.
+type EventType string
+
+// Event types.
+const (
+	EventBegin    EventType = "begin"
+	EventCommit   EventType = "commit"
+	EventInsert   EventType = "insert"
+	EventUpdate   EventType = "update"
+	EventDelete   EventType = "delete"
+	EventTruncate EventType = "truncate"
+	EventOther    EventType = "other"
+)
+
+// Event is a decoded logical replication message.
+type Event struct {
+	Type   EventType
+	XID    uint32
+	Schema string
+	Table  string
+
+	// Data contains the new row for inserts and updates.
+	Data map[string]interface{}
+
+	// Old contains the previous row (or its replica identity) for updates and
+	// deletes when it is provided by the server.
+	Old map[string]interface{}
+
+	// CommitLSN is the end LSN of a transaction for commit events.
+	CommitLSN LSN
+}
+
+// Decoder decodes the messages of a logical decoding output plugin.
+type Decoder interface {
+	Decode(data []byte) ([]Event, error)
+}
+
+//------------------------------------------------------------------------------
+
+type relationColumn struct {
+	name    string
+	typeOID uint32
+}
+
+type relation struct {
+	schema  string
+	table   string
+	columns []relationColumn
+}
+
+// PGOutputDecoder decodes messages of the pgoutput plugin, protocol version 1.
+type PGOutputDecoder struct {
+	relations map[uint32]relation
+	xid       uint32
+}
+
+// NewPGOutputDecoder creates a new decoder for the pgoutput plugin.
+func NewPGOutputDecoder() *PGOutputDecoder {
+	return &PGOutputDecoder{
+		relations: map[uint32]relation{},
+	}
+}
+
+type pgoutputReader struct {
+	b   []byte
+	err error
+}
+
+func (r *pgoutputReader) need(n int) bool {
+	if r.err != nil {
+		return false
+	}
+	if len(r.b) < n {
+		r.err = errors.New("unexpected end of pgoutput message")
+		return false
+	}
+	return true
+}
+
+func (r *pgoutputReader) byte() byte {
+	if !r.need(1) {
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *pgoutputReader) uint16() uint16 {
+	if !r.need(2) {
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *pgoutputReader) uint32() uint32 {
+	if !r.need(4) {
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *pgoutputReader) uint64() uint64 {
+	if !r.need(8) {
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.b)
+	r.b = r.b[8:]
+	return v
+}
+
+func (r *pgoutputReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	end := bytes.IndexByte(r.b, 0)
+	if end < 0 {
+		r.err = errors.New("unterminated string in pgoutput message")
+		return ""
+	}
+	v := string(r.b[:end])
+	r.b = r.b[end+1:]
+	return v
+}
+
+func (r *pgoutputReader) bytes(n int) []byte {
+	if !r.need(n) {
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *pgoutputReader) tuple(rel relation) map[string]interface{} {
+	n := int(r.uint16())
+	row := make(map[string]interface{}, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		var col relationColumn
+		if i < len(rel.columns) {
+			col = rel.columns[i]
+		} else {
+			col.name = strconv.Itoa(i)
+		}
+		switch kind := r.byte(); kind {
+		case 'n':
+			row[col.name] = nil
+		case 'u':
+			// Unchanged TOAST values are not sent and are omitted.
+		case 't':
+			l := int(r.uint32())
+			row[col.name] = convertText(col.typeOID, string(r.bytes(l)))
+		default:
+			if r.err == nil {
+				r.err = fmt.Errorf("unknown tuple data type: %c", kind)
+			}
+		}
+	}
+	return row
+}
+
+// Decode a single pgoutput message into zero or more events.
+func (d *PGOutputDecoder) Decode(data []byte) ([]Event, error) {
+	r := &pgoutputReader{b: data}
+	var events []Event
+
+	switch t := r.byte(); t {
+	case 'B':
+		r.uint64() // Final LSN
+		r.uint64() // Commit timestamp
+		d.xid = r.uint32()
+		events = append(events, Event{Type: EventBegin, XID: d.xid})
+	case 'C':
+		r.byte()   // Flags
+		r.uint64() // Commit LSN
+		end := LSN(r.uint64())
+		events = append(events, Event{Type: EventCommit, XID: d.xid, CommitLSN: end})
+	case 'R':
+		id := r.uint32()
+		rel := relation{
+			schema: r.string(),
+			table:  r.string(),
+		}
+		r.byte() // Replica identity
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			r.byte() // Flags
+			col := relationColumn{name: r.string()}
+			col.typeOID = r.uint32()
+			r.uint32() // Type modifier
+			rel.columns = append(rel.columns, col)
+		}
+		if r.err == nil {
+			d.relations[id] = rel
+		}
+	case 'I', 'U', 'D':
+		rel, exists := d.relations[r.uint32()]
+		if r.err == nil && !exists {
+			return nil, errors.New("received change for unknown relation")
+		}
+		e := Event{XID: d.xid, Schema: rel.schema, Table: rel.table}
+		switch t {
+		case 'I':
+			e.Type = EventInsert
+			if r.byte() == 'N' {
+				e.Data = r.tuple(rel)
+			}
+		case 'U':
+			e.Type = EventUpdate
+			kind := r.byte()
+			if kind == 'K' || kind == 'O' {
+				e.Old = r.tuple(rel)
+				kind = r.byte()
+			}
+			if kind == 'N' {
+				e.Data = r.tuple(rel)
+			}
+		case 'D':
+			e.Type = EventDelete
+			if kind := r.byte(); kind == 'K' || kind == 'O' {
+				e.Old = r.tuple(rel)
+			}
+		}
+		events = append(events, e)
+	case 'T':
+		n := int(r.uint32())
+		r.byte() // Options
+		for i := 0; i < n && r.err == nil; i++ {
+			rel := d.relations[r.uint32()]
+			events = append(events, Event{
+				Type:   EventTruncate,
+				XID:    d.xid,
+				Schema: rel.schema,
+				Table:  rel.table,
+			})
+		}
+	case 'Y', 'O':
+		events = append(events, Event{Type: EventOther, XID: d.xid})
+	default:
+		if r.err == nil {
+			return nil, fmt.Errorf("unknown pgoutput message type: %c", t)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return events, nil
+}
+
+// convertText converts the text representation of a value into a native type
+// where the type is known.
+func convertText(oid uint32, v string) interface{} {
+	switch oid {
+	case 16:
+		return v == "t"
+	case 20, 21, 23:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case 700, 701:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case 114, 3802:
+		if json.Valid([]byte(v)) {
+			return json.RawMessage(v)
+		}
+	}
+	return v
+}
+
+//------------------------------------------------------------------------------
+
+// Wal2JSONDecoder decodes messages of the wal2json plugin, format version 2.
+type Wal2JSONDecoder struct {
+	xid uint32
+}
+
+// NewWal2JSONDecoder creates a new decoder for the wal2json plugin.
+func NewWal2JSONDecoder() *Wal2JSONDecoder {
+	return &Wal2JSONDecoder{}
+}
+
+type wal2jsonColumn struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
+type wal2jsonMessage struct {
+	Action   string           `json:"action"`
+	XID      uint32           `json:"xid"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	NextLSN  string           `json:"nextlsn"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+func wal2jsonRow(cols []wal2jsonColumn) map[string]interface{} {
+	if cols == nil {
+		return nil
+	}
+	row := make(map[string]interface{}, len(cols))
+	for _, c := range cols {
+		var v interface{}
+		if len(c.Value) > 0 {
+			if err := json.Unmarshal(c.Value, &v); err != nil {
+				v = string(c.Value)
+			}
+		}
+		row[c.Name] = v
+	}
+	return row
+}
+
+// Decode a single wal2json message into zero or more events.
+func (d *Wal2JSONDecoder) Decode(data []byte) ([]Event, error) {
+	var m wal2jsonMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	e := Event{XID: d.xid, Schema: m.Schema, Table: m.Table}
+	switch m.Action {
+	case "B":
+		d.xid = m.XID
+		e.Type, e.XID = EventBegin, m.XID
+	case "C":
+		e.Type = EventCommit
+		if len(m.NextLSN) > 0 {
+			lsn, err := ParseLSN(m.NextLSN)
+			if err != nil {
+				return nil, err
+			}
+			e.CommitLSN = lsn
+		}
+	case "I":
+		e.Type = EventInsert
+		e.Data = wal2jsonRow(m.Columns)
+	case "U":
+		e.Type = EventUpdate
+		e.Data = wal2jsonRow(m.Columns)
+		e.Old = wal2jsonRow(m.Identity)
+	case "D":
+		e.Type = EventDelete
+		e.Old = wal2jsonRow(m.Identity)
+	case "T":
+		e.Type = EventTruncate
+	default:
+		e.Type = EventOther
+	}
+	return []Event{e}, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,211 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package postgres
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+//------------------------------------------------------------------------------
+
+// message is a buffer for building output plugin messages.
+type message []byte
+
+func (m message) string(s string) message {
+	return append(append(m, s...), 0)
+}
+
+func (m message) int32(i int32) message {
+	return append(m, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+}
+
+func (m message) int64(i int64) message {
+	return m.int32(int32(i >> 32)).int32(int32(i))
+}
+
+func (m message) bytes(b []byte) message {
+	return append(m, b...)
+}
+
+//------------------------------------------------------------------------------
+
+func TestPGOutputDecode(t *testing.T) {
+	dec := NewPGOutputDecoder()
+
+	begin := message{'B'}.int64(0x100).int64(0).int32(42)
+	events, err := dec.Decode(begin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []Event{{Type: EventBegin, XID: 42}}, events; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	rel := message{'R'}.int32(7).string("public").string("users").bytes([]byte{'d', 0, 3}).
+		bytes([]byte{1}).string("id").int32(23).int32(-1).
+		bytes([]byte{0}).string("name").int32(25).int32(-1).
+		bytes([]byte{0}).string("attrs").int32(3802).int32(-1)
+	if events, err = dec.Decode(rel); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Unexpected events: %v", events)
+	}
+
+	tuple := func(id, name string) message {
+		m := message{}.bytes([]byte{0, 3})
+		m = append(m, 't')
+		m = m.int32(int32(len(id))).bytes([]byte(id))
+		if len(name) > 0 {
+			m = append(m, 't')
+			m = m.int32(int32(len(name))).bytes([]byte(name))
+		} else {
+			m = append(m, 'n')
+		}
+		return append(m, 'u')
+	}
+
+	insert := message{'I'}.int32(7).bytes([]byte{'N'}).bytes(tuple("1", "foo"))
+	if events, err = dec.Decode(insert); err != nil {
+		t.Fatal(err)
+	}
+	exp := []Event{{
+		Type:   EventInsert,
+		XID:    42,
+		Schema: "public",
+		Table:  "users",
+		Data:   map[string]interface{}{"id": int64(1), "name": "foo"},
+	}}
+	if act := events; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	update := message{'U'}.int32(7).bytes([]byte{'K'}).bytes(tuple("1", "")).
+		bytes([]byte{'N'}).bytes(tuple("2", "bar"))
+	if events, err = dec.Decode(update); err != nil {
+		t.Fatal(err)
+	}
+	exp = []Event{{
+		Type:   EventUpdate,
+		XID:    42,
+		Schema: "public",
+		Table:  "users",
+		Data:   map[string]interface{}{"id": int64(2), "name": "bar"},
+		Old:    map[string]interface{}{"id": int64(1), "name": nil},
+	}}
+	if act := events; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	del := message{'D'}.int32(7).bytes([]byte{'K'}).bytes(tuple("2", ""))
+	if events, err = dec.Decode(del); err != nil {
+		t.Fatal(err)
+	}
+	exp = []Event{{
+		Type:   EventDelete,
+		XID:    42,
+		Schema: "public",
+		Table:  "users",
+		Old:    map[string]interface{}{"id": int64(2), "name": nil},
+	}}
+	if act := events; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	commit := message{'C', 0}.int64(0x100).int64(0x200).int64(0)
+	if events, err = dec.Decode(commit); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := []Event{{Type: EventCommit, XID: 42, CommitLSN: 0x200}}, events; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	if _, err = dec.Decode(message{'I'}.int32(8).bytes([]byte{'N'})); err == nil {
+		t.Error("Expected error from unknown relation")
+	}
+	if _, err = dec.Decode(message{'I'}.int32(7).bytes([]byte{'N', 0})); err == nil {
+		t.Error("Expected error from truncated message")
+	}
+}
+
+func TestConvertText(t *testing.T) {
+	tests := []struct {
+		oid uint32
+		in  string
+		exp interface{}
+	}{
+		{16, "t", true},
+		{16, "f", false},
+		{20, "-12", int64(-12)},
+		{701, "1.5", 1.5},
+		{114, `{"a":1}`, json.RawMessage(`{"a":1}`)},
+		{114, `nope`, "nope"},
+		{25, "hello", "hello"},
+	}
+	for _, test := range tests {
+		if act := convertText(test.oid, test.in); !reflect.DeepEqual(test.exp, act) {
+			t.Errorf("Wrong result for %v: %#v != %#v", test.in, act, test.exp)
+		}
+	}
+}
+
+func TestWal2JSONDecode(t *testing.T) {
+	dec := NewWal2JSONDecoder()
+
+	inputs := []string{
+		`{"action":"B","xid":10}`,
+		`{"action":"I","schema":"public","table":"t","columns":[{"name":"id","type":"integer","value":1}]}`,
+		`{"action":"U","schema":"public","table":"t","columns":[{"name":"id","value":2}],"identity":[{"name":"id","value":1}]}`,
+		`{"action":"D","schema":"public","table":"t","identity":[{"name":"id","value":2}]}`,
+		`{"action":"C","nextlsn":"0/16B3748"}`,
+	}
+	exp := []Event{
+		{Type: EventBegin, XID: 10},
+		{Type: EventInsert, XID: 10, Schema: "public", Table: "t", Data: map[string]interface{}{"id": float64(1)}},
+		{
+			Type: EventUpdate, XID: 10, Schema: "public", Table: "t",
+			Data: map[string]interface{}{"id": float64(2)},
+			Old:  map[string]interface{}{"id": float64(1)},
+		},
+		{Type: EventDelete, XID: 10, Schema: "public", Table: "t", Old: map[string]interface{}{"id": float64(2)}},
+		{Type: EventCommit, XID: 10, CommitLSN: 0x16B3748},
+	}
+
+	var act []Event
+	for _, in := range inputs {
+		events, err := dec.Decode([]byte(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		act = append(act, events...)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	if _, err := dec.Decode([]byte("not json")); err == nil {
+		t.Error("Expected error from invalid json")
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,51 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package pulsar provides helpers shared by the components that communicate
+// with Apache Pulsar through its WebSocket API.
+package pulsar
+
+import (
+	"fmt"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// TopicPath converts a topic name into the path segments used by the
+// WebSocket API, short topic names belong to the public/default namespace.
+func TopicPath(topic string) (string, error) {
+	domain := "persistent"
+	if i := strings.Index(topic, "://"); i >= 0 {
+		domain = topic[:i]
+		topic = topic[i+3:]
+	} else if !strings.Contains(topic, "/") {
+		topic = "public/default/" + topic
+	}
+	if domain != "persistent" && domain != "non-persistent" {
+		return "", fmt.Errorf("unrecognised topic domain: %v", domain)
+	}
+	if len(strings.Split(topic, "/")) != 3 {
+		return "", fmt.Errorf("topic must be of the form tenant/namespace/topic: %v", topic)
+	}
+	return domain + "/" + topic, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pulsar
+
+import (
+	"testing"
+)
+
+func TestTopicPath(t *testing.T) {
+	tests := map[string]string{
+		"foo":                               "persistent/public/default/foo",
+		"persistent://a/b/c":                "persistent/a/b/c",
+		"non-persistent://public/default/d": "non-persistent/public/default/d",
+	}
+	for input, exp := range tests {
+		act, err := TopicPath(input)
+		if err != nil {
+			t.Errorf("Unexpected error for '%v': %v", input, err)
+		} else if act != exp {
+			t.Errorf("Wrong result for '%v': %v != %v", input, act, exp)
+		}
+	}
+	for _, input := range []string{"a/b", "nope://a/b/c", "persistent://a/b"} {
+		if _, err := TopicPath(input); err == nil {
+			t.Errorf("Expected error from '%v'", input)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package redis provides shared configuration for components that connect to
+// Redis servers.
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-redis/redis"
+)
+
+//------------------------------------------------------------------------------
+
+// Documentation is a markdown description of how to connect to different
+// Redis topologies.
+const Documentation = `### Topologies
+
+The field ` + "`kind`" + ` selects the topology of the target Redis servers and
+can be one of ` + "`simple`" + `, ` + "`cluster`" + ` or ` + "`failover`" + `.
+
+A ` + "`simple`" + ` connection targets the single server of ` + "`url`" + `.
+
+With ` + "`cluster`" + ` the field ` + "`url`" + ` may contain a comma
+separated list of cluster nodes to seed the cluster topology from, commands are
+then routed to the nodes that own their keys.
+
+With ` + "`failover`" + ` the field ` + "`url`" + ` contains a comma separated
+list of Sentinel servers and ` + "`master`" + ` names the master set to connect
+to, the connection automatically follows the master when it fails over.
+
+Passwords are taken from the user info of the first URL, e.g.
+` + "`tcp://:password@localhost:6379`" + `.`
+
+//------------------------------------------------------------------------------
+
+// Kinds of Redis topology.
+const (
+	KindSimple   = "simple"
+	KindCluster  = "cluster"
+	KindFailover = "failover"
+)
+
+// Config contains configuration fields for connecting to Redis servers.
+type Config struct {
+	URL    string `json:"url" yaml:"url"`
+	Kind   string `json:"kind" yaml:"kind"`
+	Master string `json:"master" yaml:"master"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		URL:    "tcp://localhost:6379",
+		Kind:   KindSimple,
+		Master: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// parseURLs returns the URLs of a comma separated list.
+func (c Config) parseURLs() ([]*url.URL, error) {
+	var urls []*url.URL
+	for _, s := range strings.Split(c.URL, ",") {
+		if s = strings.TrimSpace(s); len(s) == 0 {
+			continue
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse url '%v': %v", s, err)
+		}
+		urls = append(urls, u)
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("at least one url must be specified")
+	}
+	return urls, nil
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	urls, err := c.parseURLs()
+	if err != nil {
+		return err
+	}
+	switch c.Kind {
+	case KindSimple:
+		if len(urls) > 1 {
+			return fmt.Errorf("kind %v only supports a single url", c.Kind)
+		}
+	case KindCluster:
+	case KindFailover:
+		if len(c.Master) == 0 {
+			return fmt.Errorf("kind %v requires a master name", c.Kind)
+		}
+	default:
+		return fmt.Errorf("kind not recognised: %v", c.Kind)
+	}
+	return nil
+}
+
+// Client creates a client for the configured Redis topology. The client does
+// not connect to any servers until a command is executed.
+func (c Config) Client() (redis.UniversalClient, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	urls, _ := c.parseURLs()
+
+	var pass string
+	if urls[0].User != nil {
+		pass, _ = urls[0].User.Password()
+	}
+	addrs := make([]string, len(urls))
+	for i, u := range urls {
+		addrs[i] = u.Host
+	}
+
+	switch c.Kind {
+	case KindCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    addrs,
+			Password: pass,
+		}), nil
+	case KindFailover:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    c.Master,
+			SentinelAddrs: addrs,
+			Password:      pass,
+		}), nil
+	}
+	return redis.NewClient(&redis.Options{
+		Addr:     urls[0].Host,
+		Network:  urls[0].Scheme,
+		Password: pass,
+	}), nil
+}
+
+//------------------------------------------------------------------------------
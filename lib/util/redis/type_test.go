@@ -0,0 +1,115 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/go-redis/redis"
+)
+
+func TestConfigValidate(t *testing.T) {
+	type testCase struct {
+		name string
+		conf Config
+		err  bool
+	}
+
+	tests := []testCase{
+		{
+			name: "default",
+			conf: NewConfig(),
+		},
+		{
+			name: "simple multiple urls",
+			conf: Config{URL: "tcp://foo:6379,tcp://bar:6379", Kind: KindSimple},
+			err:  true,
+		},
+		{
+			name: "cluster multiple urls",
+			conf: Config{URL: "tcp://foo:6379, tcp://bar:6379", Kind: KindCluster},
+		},
+		{
+			name: "failover without master",
+			conf: Config{URL: "tcp://foo:26379", Kind: KindFailover},
+			err:  true,
+		},
+		{
+			name: "failover",
+			conf: Config{URL: "tcp://foo:26379", Kind: KindFailover, Master: "mymaster"},
+		},
+		{
+			name: "no urls",
+			conf: Config{URL: " , ", Kind: KindSimple},
+			err:  true,
+		},
+		{
+			name: "bad url",
+			conf: Config{URL: "tcp://foo:bar:baz%", Kind: KindSimple},
+			err:  true,
+		},
+		{
+			name: "bad kind",
+			conf: Config{URL: "tcp://foo:6379", Kind: "nope"},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		err := test.conf.Validate()
+		if test.err && err == nil {
+			t.Errorf("%v: expected error", test.name)
+		} else if !test.err && err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+		}
+	}
+}
+
+func TestConfigClient(t *testing.T) {
+	conf := NewConfig()
+	conf.URL = "tcp://:foopass@foo:6379"
+	client, err := conf.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	simple, ok := client.(*redis.Client)
+	if !ok {
+		t.Fatalf("Wrong client type: %T", client)
+	}
+	if exp, act := "foo:6379", simple.Options().Addr; exp != act {
+		t.Errorf("Wrong address: %v != %v", act, exp)
+	}
+	if exp, act := "foopass", simple.Options().Password; exp != act {
+		t.Errorf("Wrong password: %v != %v", act, exp)
+	}
+
+	conf.URL = "tcp://foo:6379,tcp://bar:6379"
+	conf.Kind = KindCluster
+	if client, err = conf.Client(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, ok = client.(*redis.ClusterClient); !ok {
+		t.Errorf("Wrong client type: %T", client)
+	}
+}
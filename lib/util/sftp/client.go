@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package sftp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	psftp "github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+//------------------------------------------------------------------------------
+
+// IsNotExist returns true if the error indicates that a file does not exist.
+func IsNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// IsStatus returns true if the error is a status returned by the server, as
+// opposed to a failure of the underlying connection.
+func IsStatus(err error) bool {
+	var status *psftp.StatusError
+	return errors.As(err, &status) ||
+		errors.Is(err, os.ErrNotExist) ||
+		errors.Is(err, os.ErrPermission)
+}
+
+//------------------------------------------------------------------------------
+
+// Client is an SFTP client that owns the SSH connection it runs over.
+type Client struct {
+	*psftp.Client
+	conn io.Closer
+}
+
+// Dial opens an SSH connection and starts an SFTP session.
+func Dial(addr string, conf *ssh.ClientConfig) (*Client, error) {
+	conn, err := ssh.Dial("tcp", addr, conf)
+	if err != nil {
+		return nil, err
+	}
+	c, err := psftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Client{Client: c, conn: conn}, nil
+}
+
+// Close ends the SFTP session along with its SSH connection.
+func (c *Client) Close() error {
+	err := c.Client.Close()
+	if c.conn != nil {
+		if cErr := c.conn.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+// ReadFile reads the full contents of a file.
+func (c *Client) ReadFile(path string) ([]byte, error) {
+	f, err := c.Client.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// WriteFile writes data to a file, creating it if it does not exist and
+// truncating it otherwise.
+func (c *Client) WriteFile(path string, data []byte) error {
+	f, err := c.Client.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,96 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package sftp
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	psftp "github.com/pkg/sftp"
+)
+
+//------------------------------------------------------------------------------
+
+func newInMemClient(t *testing.T) *Client {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	server := psftp.NewRequestServer(serverConn, psftp.InMemHandler())
+	go server.Serve()
+
+	c, err := psftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{Client: c, conn: clientConn}
+}
+
+func TestClient(t *testing.T) {
+	c := newInMemClient(t)
+	defer c.Close()
+
+	if _, err := c.ReadFile("/foo.txt"); !IsNotExist(err) {
+		t.Errorf("Expected not exist error, received: %v", err)
+	}
+	if err := c.MkdirAll("/foo/bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteFile("/foo/bar/baz.txt", []byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WriteFile("/foo/bar/baz.txt", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := c.ReadFile("/foo/bar/baz.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "hello", string(data); exp != act {
+		t.Errorf("Wrong contents: %v != %v", act, exp)
+	}
+
+	infos, err := c.ReadDir("/foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, len(infos); exp != act {
+		t.Fatalf("Wrong count of files: %v != %v", act, exp)
+	}
+	if exp, act := "baz.txt", infos[0].Name(); exp != act {
+		t.Errorf("Wrong file name: %v != %v", act, exp)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	if !IsStatus(&psftp.StatusError{Code: 4}) {
+		t.Error("Expected status error")
+	}
+	if !IsStatus(os.ErrNotExist) {
+		t.Error("Expected not exist to be a status")
+	}
+	if IsStatus(io.ErrUnexpectedEOF) {
+		t.Error("Expected connection error not to be a status")
+	}
+}
+
+//------------------------------------------------------------------------------
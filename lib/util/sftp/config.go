@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+//------------------------------------------------------------------------------
+
+// SSHConfig creates an SSH client config that authenticates with a password
+// and/or a private key. When knownHostsFile is empty the host key of the
+// server is not verified.
+func SSHConfig(user, password, privateKeyFile, knownHostsFile string) (*ssh.ClientConfig, error) {
+	sshConf := &ssh.ClientConfig{
+		User: user,
+	}
+
+	if len(privateKeyFile) > 0 {
+		keyBytes, err := ioutil.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %v", err)
+		}
+		sshConf.Auth = append(sshConf.Auth, ssh.PublicKeys(signer))
+	}
+	if len(password) > 0 {
+		sshConf.Auth = append(sshConf.Auth, ssh.Password(password))
+	}
+	if len(sshConf.Auth) == 0 {
+		return nil, errors.New("either a password or a private key file must be specified")
+	}
+
+	if len(knownHostsFile) > 0 {
+		var err error
+		if sshConf.HostKeyCallback, err = knownhosts.New(knownHostsFile); err != nil {
+			return nil, fmt.Errorf("failed to read known hosts file: %v", err)
+		}
+	} else {
+		sshConf.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	}
+	return sshConf, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,285 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package syslog implements parsers for RFC 3164 and RFC 5424 syslog messages.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Format identifies a syslog message format.
+type Format string
+
+// Supported syslog formats.
+const (
+	FormatAuto    Format = "auto"
+	FormatRFC3164 Format = "rfc3164"
+	FormatRFC5424 Format = "rfc5424"
+)
+
+// Message is a parsed syslog message.
+type Message struct {
+	Facility       int                          `json:"facility"`
+	Severity       int                          `json:"severity"`
+	Version        int                          `json:"version,omitempty"`
+	Timestamp      *time.Time                   `json:"timestamp,omitempty"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	AppName        string                       `json:"app_name,omitempty"`
+	ProcID         string                       `json:"proc_id,omitempty"`
+	MsgID          string                       `json:"msg_id,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        string                       `json:"message"`
+}
+
+// ErrNoPriority is returned when a message does not begin with a valid
+// priority value.
+var ErrNoPriority = errors.New("message does not begin with a priority")
+
+//------------------------------------------------------------------------------
+
+// Parse attempts to parse a syslog message of a given format. When the format
+// is FormatAuto the format is determined from the message header.
+func Parse(b []byte, format Format) (*Message, error) {
+	b = bytes.TrimRight(b, "\r\n\x00")
+	pri, rest, err := parsePriority(b)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatRFC5424:
+		return parseRFC5424(pri, rest)
+	case FormatRFC3164:
+		return parseRFC3164(pri, rest, time.Now())
+	case FormatAuto:
+		if len(rest) > 1 && rest[0] >= '1' && rest[0] <= '9' && (rest[1] == ' ' || (rest[1] >= '0' && rest[1] <= '9')) {
+			if msg, err := parseRFC5424(pri, rest); err == nil {
+				return msg, nil
+			}
+		}
+		return parseRFC3164(pri, rest, time.Now())
+	}
+	return nil, fmt.Errorf("unrecognised syslog format: %v", format)
+}
+
+func parsePriority(b []byte) (int, []byte, error) {
+	if len(b) < 3 || b[0] != '<' {
+		return 0, nil, ErrNoPriority
+	}
+	head := b
+	if len(head) > 5 {
+		head = head[:5]
+	}
+	end := bytes.IndexByte(head, '>')
+	if end < 2 {
+		return 0, nil, ErrNoPriority
+	}
+	pri, err := strconv.Atoi(string(b[1:end]))
+	if err != nil || pri > 191 {
+		return 0, nil, ErrNoPriority
+	}
+	return pri, b[end+1:], nil
+}
+
+// nextField splits the next space delimited field from b.
+func nextField(b []byte) (string, []byte) {
+	i := bytes.IndexByte(b, ' ')
+	if i < 0 {
+		return string(b), nil
+	}
+	return string(b[:i]), b[i+1:]
+}
+
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+//------------------------------------------------------------------------------
+
+func parseRFC5424(pri int, b []byte) (*Message, error) {
+	msg := &Message{Facility: pri / 8, Severity: pri % 8}
+
+	var field string
+	var err error
+
+	field, b = nextField(b)
+	if msg.Version, err = strconv.Atoi(field); err != nil || msg.Version < 1 {
+		return nil, fmt.Errorf("invalid version: %v", field)
+	}
+
+	if field, b = nextField(b); field != "-" {
+		t, err := time.Parse(time.RFC3339Nano, field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp: %v", err)
+		}
+		msg.Timestamp = &t
+	}
+
+	field, b = nextField(b)
+	msg.Hostname = nilValue(field)
+	field, b = nextField(b)
+	msg.AppName = nilValue(field)
+	field, b = nextField(b)
+	msg.ProcID = nilValue(field)
+	field, b = nextField(b)
+	msg.MsgID = nilValue(field)
+
+	if len(b) == 0 {
+		return nil, errors.New("missing structured data")
+	}
+	if b[0] == '-' {
+		b = b[1:]
+	} else if msg.StructuredData, b, err = parseStructuredData(b); err != nil {
+		return nil, err
+	}
+
+	if len(b) > 0 {
+		if b[0] != ' ' {
+			return nil, errors.New("expected space after structured data")
+		}
+		b = bytes.TrimPrefix(b[1:], []byte("\xef\xbb\xbf"))
+	}
+	msg.Message = string(b)
+	return msg, nil
+}
+
+func parseStructuredData(b []byte) (map[string]map[string]string, []byte, error) {
+	sd := map[string]map[string]string{}
+	for len(b) > 0 && b[0] == '[' {
+		b = b[1:]
+		end := bytes.IndexAny(b, " ]")
+		if end <= 0 {
+			return nil, nil, errors.New("invalid structured data element")
+		}
+		params := map[string]string{}
+		sd[string(b[:end])] = params
+		b = b[end:]
+
+		for len(b) > 0 && b[0] == ' ' {
+			b = b[1:]
+			eq := bytes.IndexByte(b, '=')
+			if eq <= 0 || len(b) < eq+2 || b[eq+1] != '"' {
+				return nil, nil, errors.New("invalid structured data parameter")
+			}
+			name := string(b[:eq])
+			b = b[eq+2:]
+
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(b); i++ {
+				if b[i] == '\\' && i+1 < len(b) && (b[i+1] == '"' || b[i+1] == '\\' || b[i+1] == ']') {
+					value.WriteByte(b[i+1])
+					i++
+					continue
+				}
+				if b[i] == '"' {
+					b = b[i+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(b[i])
+			}
+			if !closed {
+				return nil, nil, errors.New("unterminated structured data parameter value")
+			}
+			params[name] = value.String()
+		}
+		if len(b) == 0 || b[0] != ']' {
+			return nil, nil, errors.New("unterminated structured data element")
+		}
+		b = b[1:]
+	}
+	return sd, b, nil
+}
+
+//------------------------------------------------------------------------------
+
+func parseRFC3164(pri int, b []byte, now time.Time) (*Message, error) {
+	msg := &Message{Facility: pri / 8, Severity: pri % 8}
+
+	// The timestamp has the form "Mmm dd hh:mm:ss", where single digit days
+	// are padded with a space.
+	if len(b) >= 16 && b[15] == ' ' {
+		if t, err := time.ParseInLocation(time.Stamp, string(b[:15]), now.Location()); err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			// Timestamps lack a year, so messages from the end of last year
+			// would otherwise appear to be in the future.
+			if t.After(now.AddDate(0, 1, 0)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			msg.Timestamp = &t
+			b = b[16:]
+		}
+	}
+
+	// The hostname is optional in practice, if the first field looks like a
+	// tag then it is assumed to be missing.
+	if msg.Timestamp != nil {
+		if field, rest := nextField(b); len(rest) > 0 && !isTag(field) {
+			msg.Hostname = field
+			b = rest
+		}
+	}
+
+	if end := tagEnd(b); end > 0 {
+		tag := string(b[:end])
+		b = b[end:]
+		if i := strings.IndexByte(tag, '['); i > 0 && strings.HasSuffix(tag, "]") {
+			msg.ProcID = tag[i+1 : len(tag)-1]
+			tag = tag[:i]
+		}
+		msg.AppName = tag
+		b = bytes.TrimPrefix(b, []byte(":"))
+		b = bytes.TrimPrefix(b, []byte(" "))
+	}
+
+	msg.Message = string(b)
+	return msg, nil
+}
+
+// tagEnd returns the length of the tag (including any PID) at the beginning of
+// b, or zero if b does not begin with a tag terminated with a colon.
+func tagEnd(b []byte) int {
+	for i, c := range b {
+		if c == ':' {
+			return i
+		}
+		if c == ' ' || i >= 64 {
+			return 0
+		}
+	}
+	return 0
+}
+
+func isTag(field string) bool {
+	return strings.HasSuffix(field, ":") && tagEnd([]byte(field)) == len(field)-1
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,146 @@
+// Copyright (c) 2019 Ashley Jeffs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package syslog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	msg, err := Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication" eventID="1011"][other@1] `+"\xef\xbb\xbf"+`An application event log entry...`+"\n"), FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+	exp := &Message{
+		Facility:  20,
+		Severity:  5,
+		Version:   1,
+		Timestamp: &ts,
+		Hostname:  "mymachine.example.com",
+		AppName:   "evntslog",
+		ProcID:    "1234",
+		MsgID:     "ID47",
+		StructuredData: map[string]map[string]string{
+			"exampleSDID@32473": {
+				"iut":         "3",
+				"eventSource": `App"lication`,
+				"eventID":     "1011",
+			},
+			"other@1": {},
+		},
+		Message: "An application event log entry...",
+	}
+	if !msg.Timestamp.Equal(*exp.Timestamp) {
+		t.Errorf("Wrong timestamp: %v != %v", msg.Timestamp, exp.Timestamp)
+	}
+	msg.Timestamp = exp.Timestamp
+	if !reflect.DeepEqual(exp, msg) {
+		t.Errorf("Wrong result: %+v != %+v", msg, exp)
+	}
+
+	msg, err = Parse([]byte(`<34>1 - - - - - -`), FormatRFC5424)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (&Message{Facility: 4, Severity: 2, Version: 1}); !reflect.DeepEqual(exp, msg) {
+		t.Errorf("Wrong result: %+v != %+v", msg, exp)
+	}
+
+	for _, input := range []string{
+		`<34>1 2003-10-11T22:14:15.003Z host app - - [unterminated`,
+		`<34>1 not-a-time host app - - -`,
+		`<34>x - - - - - -`,
+	} {
+		if _, err = Parse([]byte(input), FormatRFC5424); err == nil {
+			t.Errorf("Expected error from: %v", input)
+		}
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	now := time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		exp   Message
+		ts    time.Time
+	}{
+		{
+			input: `Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8`,
+			exp: Message{
+				Facility: 4,
+				Severity: 2,
+				Hostname: "mymachine",
+				AppName:  "su",
+				ProcID:   "123",
+				Message:  "'su root' failed for lonvick on /dev/pts/8",
+			},
+			ts: time.Date(2018, 10, 11, 22, 14, 15, 0, time.UTC),
+		},
+		{
+			input: `Jan  5 01:02:03 sshd: no hostname here`,
+			exp: Message{
+				Facility: 4,
+				Severity: 2,
+				AppName:  "sshd",
+				Message:  "no hostname here",
+			},
+			ts: time.Date(2019, 1, 5, 1, 2, 3, 0, time.UTC),
+		},
+		{
+			input: `just some text`,
+			exp: Message{
+				Facility: 4,
+				Severity: 2,
+				Message:  "just some text",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		msg, err := parseRFC3164(34, []byte(test.input), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.ts.IsZero() {
+			if msg.Timestamp != nil {
+				t.Errorf("Unexpected timestamp: %v", msg.Timestamp)
+			}
+		} else if msg.Timestamp == nil || !msg.Timestamp.Equal(test.ts) {
+			t.Errorf("Wrong timestamp: %v != %v", msg.Timestamp, test.ts)
+		}
+		msg.Timestamp = nil
+		if !reflect.DeepEqual(test.exp, *msg) {
+			t.Errorf("Wrong result: %+v != %+v", *msg, test.exp)
+		}
+	}
+}
+
+func TestParseBadPriority(t *testing.T) {
+	for _, input := range []string{"", "hello", "<>", "<abc>", "<192>1 - - - - - -", "<34"} {
+		if _, err := Parse([]byte(input), FormatAuto); err != ErrNoPriority {
+			t.Errorf("Wrong error from '%v': %v", input, err)
+		}
+	}
+}
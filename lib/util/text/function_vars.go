@@ -23,6 +23,8 @@ package text
 import (
 	"bytes"
 	"encoding/json"
+	"math"
+	"math/rand"
 	"os"
 	"regexp"
 	"strconv"
@@ -32,6 +34,7 @@ import (
 
 	"github.com/Jeffail/benthos/lib/types"
 	"github.com/Jeffail/gabs"
+	"github.com/gofrs/uuid"
 )
 
 //------------------------------------------------------------------------------
@@ -118,13 +121,52 @@ func contentFunction(msg Message, arg string) []byte {
 	return msg.Get(part).Get()
 }
 
+var randGen = rand.New(rand.NewSource(time.Now().UnixNano()))
+var randGenMux = &sync.Mutex{}
+
+func randomIntFunction(_ Message, arg string) []byte {
+	min, max := int64(0), int64(math.MaxInt64)
+	if len(arg) > 0 {
+		args := strings.Split(arg, ",")
+		if len(args) != 2 {
+			return []byte("")
+		}
+		var err error
+		if min, err = strconv.ParseInt(strings.TrimSpace(args[0]), 10, 64); err != nil {
+			return []byte("")
+		}
+		if max, err = strconv.ParseInt(strings.TrimSpace(args[1]), 10, 64); err != nil {
+			return []byte("")
+		}
+		if max < min || max-min < 0 || max-min == math.MaxInt64 {
+			return []byte("")
+		}
+		// The range is inclusive of the maximum.
+		max++
+	}
+
+	randGenMux.Lock()
+	v := min + randGen.Int63n(max-min)
+	randGenMux.Unlock()
+
+	return []byte(strconv.FormatInt(v, 10))
+}
+
+func uuidV4Function(_ Message, arg string) []byte {
+	u, err := uuid.NewV4()
+	if err != nil {
+		return []byte("")
+	}
+	return []byte(u.String())
+}
+
 //------------------------------------------------------------------------------
 
 var functionRegex *regexp.Regexp
 
 func init() {
 	var err error
-	functionRegex, err = regexp.Compile(`\${![a-z_]+(:[^}]+)?}`)
+	functionRegex, err = regexp.Compile(`\${![a-z0-9_]+(:[^}]+)?}`)
 	if err != nil {
 		panic(err)
 	}
@@ -183,6 +225,8 @@ var functionVars = map[string]func(msg Message, arg string) []byte{
 	"json_field":           jsonFieldFunction,
 	"metadata":             metadataFunction,
 	"metadata_json_object": metadataMapFunction,
+	"random_int":           randomIntFunction,
+	"uuid_v4":              uuidV4Function,
 }
 
 // ContainsFunctionVariables returns true if inBytes contains function variable
@@ -23,6 +23,7 @@ package text
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"testing"
 	"time"
@@ -379,3 +380,41 @@ func TestCountersFunction(t *testing.T) {
 		}
 	}
 }
+
+func TestRandomIntFunction(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		act := string(ReplaceFunctionVariables(nil, []byte("${!random_int:5,7}")))
+		v, err := strconv.ParseInt(act, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v < 5 || v > 7 {
+			t.Errorf("Random int out of range: %v", v)
+		}
+	}
+
+	act := string(ReplaceFunctionVariables(nil, []byte("${!random_int}")))
+	if v, err := strconv.ParseInt(act, 10, 64); err != nil {
+		t.Error(err)
+	} else if v < 0 {
+		t.Errorf("Random int out of range: %v", v)
+	}
+
+	for _, bad := range []string{"${!random_int:5}", "${!random_int:7,5}", "${!random_int:a,b}"} {
+		if act := string(ReplaceFunctionVariables(nil, []byte(bad))); act != "" {
+			t.Errorf("Expected empty result from %v, got %v", bad, act)
+		}
+	}
+}
+
+func TestUUIDV4Function(t *testing.T) {
+	uuidRegex := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first := string(ReplaceFunctionVariables(nil, []byte("${!uuid_v4}")))
+	second := string(ReplaceFunctionVariables(nil, []byte("${!uuid_v4}")))
+	if !uuidRegex.MatchString(first) {
+		t.Errorf("Result is not a UUID v4: %v", first)
+	}
+	if first == second {
+		t.Errorf("Expected unique UUIDs: %v == %v", first, second)
+	}
+}
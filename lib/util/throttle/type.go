@@ -21,6 +21,7 @@
 package throttle
 
 import (
+	"math/rand"
 	"sync/atomic"
 	"time"
 )
@@ -48,6 +49,9 @@ type Type struct {
 	// baseThrottlePeriod is the static duration for which our throttle lasts.
 	baseThrottlePeriod int64
 
+	// jitter is the maximum random duration added to each throttle.
+	jitter int64
+
 	// closeChan can interrupt a throttle when closed.
 	closeChan <-chan struct{}
 }
@@ -95,6 +99,14 @@ func OptThrottlePeriod(period time.Duration) func(*Type) {
 	}
 }
 
+// OptJitter sets a maximum random period of time that is added to each
+// throttle, which prevents synchronised retries from many clients.
+func OptJitter(period time.Duration) func(*Type) {
+	return func(t *Type) {
+		t.jitter = int64(period)
+	}
+}
+
 // OptCloseChan sets a read-only channel that, if closed, will interrupt a retry
 // throttle early.
 func OptCloseChan(c <-chan struct{}) func(*Type) {
@@ -112,8 +124,12 @@ func (t *Type) Retry() bool {
 	if rets := atomic.AddInt64(&t.consecutiveRetries, 1); rets <= t.unthrottledRetries {
 		return true
 	}
+	period := atomic.LoadInt64(&t.throttlePeriod)
+	if t.jitter > 0 {
+		period += rand.Int63n(t.jitter)
+	}
 	select {
-	case <-time.After(time.Duration(atomic.LoadInt64(&t.throttlePeriod))):
+	case <-time.After(time.Duration(period)):
 	case <-t.closeChan:
 		return false
 	}
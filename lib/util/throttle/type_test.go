@@ -180,3 +180,29 @@ func TestThrottleExponent(t *testing.T) {
 		t.Errorf("Unexpected retry period: %v != %v", act, exp)
 	}
 }
+
+func TestThrottleJitter(t *testing.T) {
+	t.Parallel()
+
+	base := time.Millisecond * 50
+	jitter := time.Millisecond * 50
+
+	throt := New(
+		OptMaxUnthrottledRetries(0),
+		OptThrottlePeriod(base),
+		OptJitter(jitter),
+	)
+
+	for i := 0; i < 5; i++ {
+		tBefore := time.Now()
+		throt.Retry()
+
+		act := time.Since(tBefore)
+		if act < base {
+			t.Errorf("Retry period below base: %v < %v", act, base)
+		}
+		if max := base + jitter + time.Millisecond*50; act > max {
+			t.Errorf("Retry period exceeded jitter: %v > %v", act, max)
+		}
+	}
+}